@@ -88,6 +88,7 @@ func TestModelCallLifecycleFinishesPhysicalOutcomes(t *testing.T) {
 		stream    func(context.Context, models.Request) (*models.EventStream[models.StreamPart, *models.Message], error)
 		wantErr   error
 		requestID string
+		throttled time.Duration
 		cancelled bool
 	}{
 		{name: "success", stream: func(context.Context, models.Request) (*models.EventStream[models.StreamPart, *models.Message], error) {
@@ -96,6 +97,9 @@ func TestModelCallLifecycleFinishesPhysicalOutcomes(t *testing.T) {
 		{name: "dispatch error", stream: func(context.Context, models.Request) (*models.EventStream[models.StreamPart, *models.Message], error) {
 			return nil, modelCallRequestError{err: providerErr, requestID: "request-failed"}
 		}, wantErr: providerErr, requestID: "request-failed"},
+		{name: "throttled dispatch error", stream: func(context.Context, models.Request) (*models.EventStream[models.StreamPart, *models.Message], error) {
+			return nil, &models.ThrottledError{Err: providerErr, Throttled: 800 * time.Millisecond}
+		}, wantErr: providerErr, throttled: 800 * time.Millisecond},
 		{name: "stream final error", stream: func(context.Context, models.Request) (*models.EventStream[models.StreamPart, *models.Message], error) {
 			stream := models.NewEventStream[models.StreamPart, *models.Message](0)
 			stream.Close(nil, providerErr)
@@ -148,6 +152,9 @@ func TestModelCallLifecycleFinishesPhysicalOutcomes(t *testing.T) {
 			if finish.ProviderRequestID != test.requestID {
 				t.Fatalf("provider request ID = %q, want %q", finish.ProviderRequestID, test.requestID)
 			}
+			if finish.Throttled != test.throttled {
+				t.Fatalf("throttled = %v, want %v", finish.Throttled, test.throttled)
+			}
 			if test.wantErr == nil && finish.Assistant == nil {
 				t.Fatal("successful finish has nil assistant")
 			}
@@ -305,7 +312,7 @@ func TestModelCallLifecyclePropagatesRequestIDAndFinishesBeforeTools(t *testing.
 	client := &modelCallTestClient{stream: func(context.Context, models.Request) (*models.EventStream[models.StreamPart, *models.Message], error) {
 		stream := models.NewEventStream[models.StreamPart, *models.Message](0)
 		go func() {
-			stream.Push(models.ResponseMetadataPart{Meta: map[string]any{"request_id": "provider_1"}})
+			stream.Push(models.ResponseMetadataPart{Meta: map[string]any{"request_id": "provider_1", "throttled_ms": int64(1500), "credential": "team-b"}})
 			stream.Close(&models.Message{Role: models.RoleAssistant, Content: models.Content{models.ToolCallPart{CallID: "tool_1", Name: "test", Input: map[string]any{}}}}, nil)
 		}()
 		return stream, nil
@@ -320,8 +327,8 @@ func TestModelCallLifecyclePropagatesRequestIDAndFinishesBeforeTools(t *testing.
 			return "call_1", nil
 		}, finish: func(_ context.Context, info ModelCallFinishInfo) error {
 			order = append(order, "finish")
-			if info.ProviderRequestID != "provider_1" {
-				t.Fatalf("request ID = %q", info.ProviderRequestID)
+			if info.ProviderRequestID != "provider_1" || info.Throttled != 1500*time.Millisecond || info.Credential != "team-b" {
+				t.Fatalf("request ID = %q, throttled = %v, credential = %q", info.ProviderRequestID, info.Throttled, info.Credential)
 			}
 			return nil
 		}},
@@ -12,7 +12,7 @@ import (
 
 func TestCheckPermissionDenyReturnsStructuredMetadata(t *testing.T) {
 	r := &runner{cfg: Config{Permissions: permission.Ruleset{{Action: "bash", Resource: "*", Effect: permission.EffectDeny}}}}
-	decision := r.checkPermission(ToolCall{ID: "call_1", Name: "bash", Args: map[string]any{"command": "pwd"}})
+	decision := r.checkPermission(context.Background(), ToolCall{ID: "call_1", Name: "bash", Args: map[string]any{"command": "pwd"}})
 	if decision.action != "bash" || decision.denyResource != "pwd" || len(decision.askResources) != 0 {
 		t.Fatalf("decision = %#v", decision)
 	}
@@ -20,17 +20,18 @@ func TestCheckPermissionDenyReturnsStructuredMetadata(t *testing.T) {
 
 func TestCheckPermissionCollectsAskResources(t *testing.T) {
 	r := &runner{cfg: Config{WorkDir: t.TempDir(), Permissions: permission.Ruleset{{Action: "edit", Resource: "*", Effect: permission.EffectAsk}}}}
-	decision := r.checkPermission(ToolCall{Name: "apply_patch", Args: map[string]any{"patchText": "*** Begin Patch\n*** Add File: a.go\n+x\n*** Add File: b.go\n+y\n*** End Patch"}, Tool: tool.NewApplyPatchTool()})
+	decision := r.checkPermission(context.Background(), ToolCall{Name: "apply_patch", Args: map[string]any{"patchText": "*** Begin Patch\n*** Add File: a.go\n+x\n*** Add File: b.go\n+y\n*** End Patch"}, Tool: tool.NewApplyPatchTool()})
 	if decision.action != "edit" || len(decision.askResources) != 2 || decision.askResources[0] != "a.go" || decision.askResources[1] != "b.go" {
 		t.Fatalf("decision = %#v", decision)
 	}
 }
 
 func TestPermissionTargetMapsMutatingToolsToEdit(t *testing.T) {
-	action, resources, err := permissionTarget(ToolCall{Name: "write", Args: map[string]any{"filePath": "docs/index.md"}}, "/repo")
+	target, err := permissionTarget(context.Background(), ToolCall{Name: "write", Args: map[string]any{"filePath": "docs/index.md"}}, "/repo")
 	if err != nil {
 		t.Fatal(err)
 	}
+	action, resources := target.Action, target.Resources
 	if action != "edit" || len(resources) != 1 || resources[0] != "docs/index.md" {
 		t.Fatalf("target = %s %#v", action, resources)
 	}
@@ -38,7 +39,7 @@ func TestPermissionTargetMapsMutatingToolsToEdit(t *testing.T) {
 
 func TestPermissionTargetUsesApplyPatchResources(t *testing.T) {
 	workDir := t.TempDir()
-	action, resources, err := permissionTarget(ToolCall{
+	target, err := permissionTarget(context.Background(), ToolCall{
 		Name: "apply_patch",
 		Args: map[string]any{"patchText": "*** Begin Patch\n*** Add File: docs/a.md\n+hello\n*** Update File: src/b.go\n*** Move to: src/c.go\n@@\n-old\n+new\n*** End Patch"},
 		Tool: tool.NewApplyPatchTool(),
@@ -46,6 +47,7 @@ func TestPermissionTargetUsesApplyPatchResources(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	action, resources := target.Action, target.Resources
 	if action != "edit" {
 		t.Fatalf("action = %q, want edit", action)
 	}
@@ -69,7 +71,7 @@ func TestCheckPermissionDenyApplyPatchByPath(t *testing.T) {
 			{Action: "edit", Resource: "src/*", Effect: permission.EffectDeny},
 		},
 	}}
-	decision := r.checkPermission(ToolCall{
+	decision := r.checkPermission(context.Background(), ToolCall{
 		ID:   "call_1",
 		Name: "apply_patch",
 		Args: map[string]any{"patchText": "*** Begin Patch\n*** Add File: src/main.go\n+package main\n*** End Patch"},
@@ -80,9 +82,9 @@ func TestCheckPermissionDenyApplyPatchByPath(t *testing.T) {
 	}
 }
 
-type permissionPrompterFunc func(context.Context, PermissionRequestInfo) (PermissionResponse, error)
+type permissionPrompterFunc func(context.Context, PermissionRequestInfo) (PermissionReply, error)
 
-func (f permissionPrompterFunc) Request(ctx context.Context, info PermissionRequestInfo) (PermissionResponse, error) {
+func (f permissionPrompterFunc) Request(ctx context.Context, info PermissionRequestInfo) (PermissionReply, error) {
 	return f(ctx, info)
 }
 
@@ -98,12 +100,12 @@ func TestPermissionPromptAllowsAfterReply(t *testing.T) {
 			}
 			r := &runner{cfg: Config{
 				Permissions: permission.Ruleset{{Action: "test", Resource: "*", Effect: permission.EffectAsk}}, ToolUseLifecycle: lifecycle,
-				PermissionPrompter: permissionPrompterFunc(func(_ context.Context, info PermissionRequestInfo) (PermissionResponse, error) {
+				PermissionPrompter: permissionPrompterFunc(func(_ context.Context, info PermissionRequestInfo) (PermissionReply, error) {
 					order = append(order, "prompt")
 					if info.Step != 2 || info.Ordinal != 3 || info.ToolUseID != "tlu_1" || info.CallID != "call_1" || info.MessageID != "message_1" || info.PartID != "part_1" || info.ModelCallID != "model_call_1" || info.Action != "test" || len(info.Resources) != 1 || info.Resources[0] != "*" {
 						t.Fatalf("request = %#v", info)
 					}
-					return response, nil
+					return PermissionReply{Response: response}, nil
 				}),
 			}, eventCh: make(chan Event, 4)}
 			call := permissionTestCall(func(context.Context, tool.Invocation) (tool.Result, error) {
@@ -133,11 +135,11 @@ func TestPermissionPromptDeclinesWithoutExecution(t *testing.T) {
 		prompter        PermissionPrompter
 	}{
 		{name: "nil", errorType: "permission_unavailable"},
-		{name: "reject", errorType: "permission_denied", prompter: permissionPrompterFunc(func(context.Context, PermissionRequestInfo) (PermissionResponse, error) {
-			return PermissionResponseReject, nil
+		{name: "reject", errorType: "permission_denied", prompter: permissionPrompterFunc(func(context.Context, PermissionRequestInfo) (PermissionReply, error) {
+			return PermissionReply{Response: PermissionResponseReject}, nil
 		})},
-		{name: "invalid", errorType: "permission_invalid_response", prompter: permissionPrompterFunc(func(context.Context, PermissionRequestInfo) (PermissionResponse, error) {
-			return "invalid", nil
+		{name: "invalid", errorType: "permission_invalid_response", prompter: permissionPrompterFunc(func(context.Context, PermissionRequestInfo) (PermissionReply, error) {
+			return PermissionReply{Response: "invalid"}, nil
 		})},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
@@ -163,6 +165,86 @@ func TestPermissionPromptDeclinesWithoutExecution(t *testing.T) {
 	}
 }
 
+func TestPermissionReplyEditsInput(t *testing.T) {
+	schema := tool.InputSchema{Type: "object", Properties: map[string]tool.Property{"command": {Type: "string"}}, Required: []string{"command"}}
+	newCall := func(execute func(context.Context, tool.Invocation) (tool.Result, error)) ToolCall {
+		return ToolCall{ID: "call_1", ToolUseID: "tlu_1", Name: "bash", Args: map[string]any{"command": "rm -rf build"}, Tool: tool.NewFuncTool("bash", "bash", tool.Definition{Name: "bash", InputSchema: schema}, execute)}
+	}
+	lifecycle := func(finished *ToolUseFinishInfo) ToolUseLifecycle {
+		return toolUseLifecycleFuncs{
+			propose:   func(context.Context, ToolUseProposeInfo) (string, error) { return "", nil },
+			authorize: func(context.Context, ToolUseAuthorizeInfo) error { return nil },
+			start:     func(context.Context, ToolUseStartInfo) error { return nil },
+			finish:    func(_ context.Context, info ToolUseFinishInfo) error { *finished = info; return nil },
+		}
+	}
+
+	rules := permission.Ruleset{{Action: "bash", Resource: "*", Effect: permission.EffectAsk}, {Action: "bash", Resource: "rm -rf /*", Effect: permission.EffectDeny}}
+	var finished ToolUseFinishInfo
+	var executed map[string]any
+	r := permissionTestRunner(permissionPrompterFunc(func(_ context.Context, info PermissionRequestInfo) (PermissionReply, error) {
+		if err := info.ValidateInput(map[string]any{}); err == nil {
+			t.Fatal("ValidateInput accepted input without command")
+		}
+		return PermissionReply{Response: PermissionResponseOnce, Input: map[string]any{"command": "rm -rf build/tmp"}}, nil
+	}), lifecycle(&finished))
+	r.cfg.Permissions = rules
+	res, err := r.executeOne(context.Background(), newCall(func(_ context.Context, inv tool.Invocation) (tool.Result, error) {
+		executed = inv.Input
+		return tool.Result{}, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if executed["command"] != "rm -rf build/tmp" || res.Args["command"] != "rm -rf build/tmp" || finished.Status != ToolUseStatusCompleted {
+		t.Fatalf("executed=%#v args=%#v finished=%#v", executed, res.Args, finished)
+	}
+
+	r = permissionTestRunner(permissionPrompterFunc(func(context.Context, PermissionRequestInfo) (PermissionReply, error) {
+		return PermissionReply{Response: PermissionResponseOnce, Input: map[string]any{"command": 1}}, nil
+	}), lifecycle(&finished))
+	r.cfg.Permissions = rules
+	if _, err := r.executeOne(context.Background(), newCall(func(context.Context, tool.Invocation) (tool.Result, error) {
+		t.Fatal("executed invalid edit")
+		return tool.Result{}, nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if finished.Status != ToolUseStatusDeclined || finished.ErrorType != "input_validation" {
+		t.Fatalf("invalid edit finished = %#v", finished)
+	}
+
+	r = permissionTestRunner(permissionPrompterFunc(func(context.Context, PermissionRequestInfo) (PermissionReply, error) {
+		return PermissionReply{Response: PermissionResponseOnce, Input: map[string]any{"command": "rm -rf /etc"}}, nil
+	}), lifecycle(&finished))
+	r.cfg.Permissions = rules
+	if _, err := r.executeOne(context.Background(), newCall(func(context.Context, tool.Invocation) (tool.Result, error) {
+		t.Fatal("executed denied edit")
+		return tool.Result{}, nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if finished.Status != ToolUseStatusDeclined || finished.ErrorType != "permission_denied" {
+		t.Fatalf("denied edit finished = %#v", finished)
+	}
+}
+
+func TestPermissionReplyRejectMessage(t *testing.T) {
+	r := permissionTestRunner(permissionPrompterFunc(func(context.Context, PermissionRequestInfo) (PermissionReply, error) {
+		return PermissionReply{Response: PermissionResponseReject, Message: "use the test target instead"}, nil
+	}), nil)
+	res, err := r.executeOne(context.Background(), permissionTestCall(func(context.Context, tool.Invocation) (tool.Result, error) {
+		t.Fatal("executed rejected call")
+		return tool.Result{}, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Error != "permission rejected: use the test target instead" || res.Metadata["permission"].(map[string]any)["message"] != "use the test target instead" {
+		t.Fatalf("result = %#v", res)
+	}
+}
+
 func TestPermissionPromptInterruptsOnContextEnd(t *testing.T) {
 	for _, tc := range []struct {
 		name, errorType string
@@ -174,9 +256,9 @@ func TestPermissionPromptInterruptsOnContextEnd(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			var executed int
 			var finished ToolUseFinishInfo
-			r := permissionTestRunner(permissionPrompterFunc(func(ctx context.Context, _ PermissionRequestInfo) (PermissionResponse, error) {
+			r := permissionTestRunner(permissionPrompterFunc(func(ctx context.Context, _ PermissionRequestInfo) (PermissionReply, error) {
 				<-ctx.Done()
-				return "", ctx.Err()
+				return PermissionReply{}, ctx.Err()
 			}), toolUseLifecycleFuncs{
 				propose: func(context.Context, ToolUseProposeInfo) (string, error) { return "", nil }, authorize: func(context.Context, ToolUseAuthorizeInfo) error { t.Fatal("authorized"); return nil }, start: func(context.Context, ToolUseStartInfo) error { t.Fatal("started"); return nil }, finish: func(_ context.Context, info ToolUseFinishInfo) error { finished = info; return nil },
 			})
@@ -193,9 +275,9 @@ func TestPermissionPromptInterruptsOnContextEnd(t *testing.T) {
 func TestPermissionPrompterFailureAndDeny(t *testing.T) {
 	var executed, prompted int
 	var failed ToolUseFinishInfo
-	r := permissionTestRunner(permissionPrompterFunc(func(context.Context, PermissionRequestInfo) (PermissionResponse, error) {
+	r := permissionTestRunner(permissionPrompterFunc(func(context.Context, PermissionRequestInfo) (PermissionReply, error) {
 		prompted++
-		return "", errors.New("prompt failed")
+		return PermissionReply{}, errors.New("prompt failed")
 	}), toolUseLifecycleFuncs{propose: func(context.Context, ToolUseProposeInfo) (string, error) { return "", nil }, authorize: func(context.Context, ToolUseAuthorizeInfo) error { t.Fatal("authorized"); return nil }, start: func(context.Context, ToolUseStartInfo) error { t.Fatal("started"); return nil }, finish: func(_ context.Context, info ToolUseFinishInfo) error { failed = info; return nil }})
 	if _, err := r.executeOne(context.Background(), permissionTestCall(func(context.Context, tool.Invocation) (tool.Result, error) { executed++; return tool.Result{}, nil })); err == nil {
 		t.Fatal("expected prompt error")
@@ -231,3 +313,21 @@ func timedOutContext() context.Context {
 	defer cancel()
 	return ctx
 }
+
+type alsoCheckTool struct{ tool.Tool }
+
+func (alsoCheckTool) Permission(context.Context, tool.Invocation) (tool.PermissionCheck, error) {
+	return tool.PermissionCheck{Action: "git.commit", Resources: []string{"main"}, Also: []tool.PermissionCheck{{Action: "edit", Resources: []string{"secrets"}}}}, nil
+}
+
+func TestCheckPermissionEvaluatesAdditionalChecks(t *testing.T) {
+	call := ToolCall{Name: "git_commit", Tool: alsoCheckTool{}}
+	r := &runner{cfg: Config{Permissions: permission.Ruleset{{Action: "edit", Resource: "secrets", Effect: permission.EffectDeny}}}}
+	if decision := r.checkPermission(context.Background(), call); decision.action != "edit" || decision.denyResource != "secrets" {
+		t.Fatalf("decision = %#v", decision)
+	}
+	r.cfg.Permissions = permission.Ruleset{{Action: "edit", Resource: "*", Effect: permission.EffectAsk}}
+	if decision := r.checkPermission(context.Background(), call); decision.action != "edit" || len(decision.askResources) != 1 || decision.askResources[0] != "secrets" {
+		t.Fatalf("decision = %#v", decision)
+	}
+}
@@ -5,11 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"path/filepath"
+	"maps"
 	"reflect"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/chaserensberger/wingman/models"
 	"github.com/chaserensberger/wingman/permission"
@@ -21,7 +22,9 @@ import (
 // termination conditions is reached:
 //
 //   - The assistant produces a turn with no tool calls (StopReasonEndTurn).
-//   - The MaxSteps limit is hit (StopReasonMaxSteps).
+//   - A configured budget is hit (StopReasonMaxSteps,
+//     StopReasonMaxTotalTokens, StopReasonMaxDuration, or
+//     StopReasonMaxToolCalls). Limit stops return a nil error.
 //   - The context is cancelled (StopReasonAborted; Run returns ctx.Err()).
 //   - A provider stream errors out (StopReasonError).
 //   - A hook returns an error other than ErrSkipTool (StopReasonError).
@@ -111,6 +114,12 @@ type runner struct {
 	toolDefs []models.ToolDef
 	usage    models.Usage
 
+	// toolCalls counts calls admitted against Config.MaxToolCalls;
+	// toolCallLimitHit records that one was declined for exceeding it.
+	// Parallel tool workers update both.
+	toolCalls        atomic.Int64
+	toolCallLimitHit atomic.Bool
+
 	// structuredOutput is set on the terminal turn when an active schema
 	// produced a valid JSON response.
 	structuredOutput map[string]any
@@ -122,19 +131,31 @@ type runner struct {
 	eventWG sync.WaitGroup
 }
 
+// errMaxDuration is the cancellation cause of a run that outlived
+// Config.MaxDuration. It separates the budget from caller aborts.
+var errMaxDuration = errors.New("run exceeded its maximum duration")
+
 // run is the main loop body.
 func (r *runner) run(ctx context.Context) (*Result, error) {
+	if r.cfg.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, r.cfg.MaxDuration, errMaxDuration)
+		defer cancel()
+	}
 	step := 0
 	for {
 		// Cancellation check at top of every iteration. Provider streams
 		// honor ctx independently; this catches cancellations between
 		// turns (e.g., during tool execution that ignored ctx).
 		if err := ctx.Err(); err != nil {
+			if errors.Is(context.Cause(ctx), errMaxDuration) {
+				return r.finalize(step, StopReasonMaxDuration), nil
+			}
 			return r.finalize(step, StopReasonAborted), err
 		}
 
-		if r.cfg.MaxSteps > 0 && step >= r.cfg.MaxSteps {
-			return r.finalize(step, StopReasonMaxSteps), nil
+		if reason, ok := r.limitReached(step); ok {
+			return r.finalize(step, reason), nil
 		}
 
 		step++
@@ -152,6 +173,9 @@ func (r *runner) run(ctx context.Context) (*Result, error) {
 			if !turn.StartedAt.IsZero() {
 				r.turns = append(r.turns, turn)
 			}
+			if errors.Is(context.Cause(ctx), errMaxDuration) {
+				return r.finalize(step, StopReasonMaxDuration), nil
+			}
 			r.emitError(err)
 			// Distinguish abort from generic error so callers can decide
 			// whether to retry or surface the error.
@@ -183,6 +207,30 @@ func (r *runner) run(ctx context.Context) (*Result, error) {
 	}
 }
 
+// limitReached reports the budget that stops the run before its next
+// assistant turn. MaxDuration is enforced through the run context instead.
+func (r *runner) limitReached(step int) (StopReason, bool) {
+	if r.cfg.MaxSteps > 0 && step >= r.cfg.MaxSteps {
+		return StopReasonMaxSteps, true
+	}
+	if r.cfg.MaxTotalTokens > 0 && r.totalTokens() >= r.cfg.MaxTotalTokens {
+		return StopReasonMaxTotalTokens, true
+	}
+	if r.toolCallLimitHit.Load() {
+		return StopReasonMaxToolCalls, true
+	}
+	return "", false
+}
+
+// totalTokens is the run's accumulated usage. Providers that do not report a
+// total are measured by input plus output tokens.
+func (r *runner) totalTokens() int {
+	if r.usage.TotalTokens > 0 {
+		return r.usage.TotalTokens
+	}
+	return r.usage.InputTokens + r.usage.OutputTokens
+}
+
 func firstChangedMessage(oldMsgs, newMsgs []models.Message) *models.Message {
 	limit := len(oldMsgs)
 	if len(newMsgs) < limit {
@@ -342,6 +390,7 @@ func (r *runner) runTurn(ctx context.Context, step int) (Turn, error) {
 		cancelStream()
 		turn.CompletedAt = time.Now()
 		turn.ProviderRequestID = providerRequestID(err)
+		turn.Throttled = models.ThrottledFor(err)
 		failure := fmt.Errorf("model stream: %w", err)
 		turn.Failure = failure
 		if attempt < policy.MaxAttempts && retryableProviderError(err) {
@@ -372,6 +421,8 @@ func (r *runner) runTurn(ctx context.Context, step int) (Turn, error) {
 	var turnUsage models.Usage
 	var finishReason models.FinishReason
 	var providerRequestID string
+	var throttled time.Duration
+	var credential string
 	partIndexes := make(map[string]int)
 	for part := range stream.Iter() {
 		if fp, ok := part.(models.FinishPart); ok {
@@ -382,6 +433,10 @@ func (r *runner) runTurn(ctx context.Context, step int) (Turn, error) {
 			if requestID, ok := metadata.Meta["request_id"].(string); ok {
 				providerRequestID = requestID
 			}
+			throttled += metadataMillis(metadata.Meta["throttled_ms"])
+			if name, ok := metadata.Meta["credential"].(string); ok {
+				credential = name
+			}
 		}
 		partID, changed := applyStreamPart(&assistantMsg, partIndexes, part)
 		if changed {
@@ -394,7 +449,7 @@ func (r *runner) runTurn(ctx context.Context, step int) (Turn, error) {
 				}()
 				err = r.retainFailedAssistant(ctx, step, &assistantMsg, err)
 				turn.Assistant, turn.Failure = assistantMsg, err
-				turn.CompletedAt, turn.Usage, turn.ProviderRequestID = time.Now(), turnUsage, providerRequestID
+				turn.CompletedAt, turn.Usage, turn.ProviderRequestID, turn.Throttled, turn.Credential = time.Now(), turnUsage, providerRequestID, throttled, credential
 				return turn, r.finishModelCall(ctx, turn, &assistantMsg, turnUsage, err, err)
 			}
 			if partID != "" {
@@ -410,6 +465,8 @@ func (r *runner) runTurn(ctx context.Context, step int) (Turn, error) {
 		turn.Failure = failure
 		turn.Usage = turnUsage
 		turn.ProviderRequestID = providerRequestID
+		turn.Throttled = throttled
+		turn.Credential = credential
 		failure = r.retainFailedAssistant(ctx, step, &assistantMsg, failure)
 		turn.Assistant = assistantMsg
 		return turn, r.finishModelCall(ctx, turn, &assistantMsg, turnUsage, failure, failure)
@@ -419,6 +476,8 @@ func (r *runner) runTurn(ctx context.Context, step int) (Turn, error) {
 		turn.Failure = err
 		turn.Usage = turnUsage
 		turn.ProviderRequestID = providerRequestID
+		turn.Throttled = throttled
+		turn.Credential = credential
 		err = r.retainFailedAssistant(ctx, step, &assistantMsg, err)
 		turn.Assistant, turn.Failure = assistantMsg, err
 		return turn, r.finishModelCall(ctx, turn, &assistantMsg, turnUsage, err, err)
@@ -428,6 +487,8 @@ func (r *runner) runTurn(ctx context.Context, step int) (Turn, error) {
 	}
 	turn.Usage = turnUsage
 	turn.ProviderRequestID = providerRequestID
+	turn.Throttled = throttled
+	turn.Credential = credential
 	mergeFinalAssistant(&assistantMsg, *finalMsg)
 	if finishReason != "" {
 		assistantMsg.FinishReason = finishReason
@@ -790,6 +851,8 @@ func (r *runner) settleModelCall(ctx context.Context, turn Turn, assistant *mode
 		Assistant:         assistant,
 		Usage:             usage,
 		ProviderRequestID: turn.ProviderRequestID,
+		Throttled:         turn.Throttled,
+		Credential:        turn.Credential,
 		Failure:           failure,
 	})
 }
@@ -824,6 +887,20 @@ func providerRequestID(err error) string {
 	return ""
 }
 
+// metadataMillis reads a millisecond count from response metadata, which
+// holds an integer in process and a float64 once replayed from JSON.
+func metadataMillis(value any) time.Duration {
+	switch n := value.(type) {
+	case int64:
+		return time.Duration(n) * time.Millisecond
+	case int:
+		return time.Duration(n) * time.Millisecond
+	case float64:
+		return time.Duration(n * float64(time.Millisecond))
+	}
+	return 0
+}
+
 func retryDelay(policy RetryPolicy, attempt int, err error) time.Duration {
 	var providerErr *models.ProviderError
 	if errors.As(err, &providerErr) && providerErr.RetryAfter != nil {
@@ -1005,7 +1082,7 @@ func (r *runner) executeOne(ctx context.Context, call ToolCall) (ToolResult, err
 		}
 		return r.settleToolUse(ctx, call, res, ToolUseStatusDeclined, "input_validation", nil)
 	}
-	decision := r.checkPermission(call)
+	decision := r.checkPermission(ctx, call)
 	if decision.err != nil {
 		res := ToolResult{CallID: call.ID, ToolUseID: call.ToolUseID, Name: call.Name, Args: call.Args, Error: decision.err.Error(), IsError: true}
 		return r.settleToolUse(ctx, call, res, ToolUseStatusDeclined, "permission_check", decision.err)
@@ -1021,9 +1098,11 @@ func (r *runner) executeOne(ctx context.Context, call ToolCall) (ToolResult, err
 			res.ToolUseID = call.ToolUseID
 			return r.settleToolUse(ctx, call, res, ToolUseStatusDeclined, "permission_unavailable", nil)
 		}
-		response, err := r.cfg.PermissionPrompter.Request(ctx, PermissionRequestInfo{
+		proposed := call.Tool
+		reply, err := r.cfg.PermissionPrompter.Request(ctx, PermissionRequestInfo{
 			Step: call.Step, Ordinal: call.Ordinal, ToolUseID: call.ToolUseID, CallID: call.ID, Name: call.Name, Args: call.Args,
 			MessageID: call.MessageID, PartID: call.PartID, ModelCallID: call.ModelCallID, Action: decision.action, Resources: decision.askResources,
+			ValidateInput: func(args map[string]any) error { return validateToolInput(proposed, args) },
 		})
 		if err != nil {
 			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
@@ -1045,13 +1124,36 @@ func (r *runner) executeOne(ctx context.Context, call ToolCall) (ToolResult, err
 			settled, settleErr := r.settleToolUse(ctx, call, res, ToolUseStatusFailed, "permission_prompt", err)
 			return settled, errors.Join(fmt.Errorf("permission prompt: %w", err), settleErr)
 		}
-		switch response {
+		switch reply.Response {
 		case PermissionResponseOnce, PermissionResponseAlways:
+			if reply.Input != nil {
+				// The reviewer approved edited input. Validate it like a
+				// model proposal; the approval cannot override a deny rule.
+				call.Args = reply.Input
+				if err := validateToolInput(call.Tool, call.Args); err != nil {
+					res := ToolResult{CallID: call.ID, ToolUseID: call.ToolUseID, Name: call.Name, Args: call.Args, Error: err.Error(), IsError: true}
+					return r.settleToolUse(ctx, call, res, ToolUseStatusDeclined, "input_validation", nil)
+				}
+				edited := r.checkPermission(ctx, call)
+				if edited.err != nil {
+					res := ToolResult{CallID: call.ID, ToolUseID: call.ToolUseID, Name: call.Name, Args: call.Args, Error: edited.err.Error(), IsError: true}
+					return r.settleToolUse(ctx, call, res, ToolUseStatusDeclined, "permission_check", edited.err)
+				}
+				if edited.denyResource != "" {
+					res := permissionToolResult(call, permission.EffectDeny, edited.action, edited.denyResource)
+					res.ToolUseID = call.ToolUseID
+					return r.settleToolUse(ctx, call, res, ToolUseStatusDeclined, "permission_denied", nil)
+				}
+			}
 		case PermissionResponseReject:
 			res := permissionToolResult(call, permission.EffectAsk, decision.action, decision.askResources[0])
 			res.ToolUseID = call.ToolUseID
 			res.Metadata["error_type"] = "permission_denied"
 			res.Metadata["permission"].(map[string]any)["response"] = "reject"
+			if reply.Message != "" {
+				res.Error = "permission rejected: " + reply.Message
+				res.Metadata["permission"].(map[string]any)["message"] = reply.Message
+			}
 			return r.settleToolUse(ctx, call, res, ToolUseStatusDeclined, "permission_denied", nil)
 		default:
 			res := permissionToolResult(call, permission.EffectAsk, decision.action, decision.askResources[0])
@@ -1059,6 +1161,21 @@ func (r *runner) executeOne(ctx context.Context, call ToolCall) (ToolResult, err
 			return r.settleToolUse(ctx, call, res, ToolUseStatusDeclined, "permission_invalid_response", nil)
 		}
 	}
+	// Budget: only calls admitted for execution count against
+	// MaxToolCalls. Calls past it are declined unexecuted, and the loop
+	// stops before the model sees the declined results.
+	if r.cfg.MaxToolCalls > 0 && r.toolCalls.Add(1) > int64(r.cfg.MaxToolCalls) {
+		r.toolCallLimitHit.Store(true)
+		res := ToolResult{
+			CallID: call.ID, ToolUseID: call.ToolUseID,
+			Name:    call.Name,
+			Args:    call.Args,
+			Error:   fmt.Sprintf("tool call limit of %d reached for this run", r.cfg.MaxToolCalls),
+			IsError: true,
+		}
+		return r.settleToolUse(ctx, call, res, ToolUseStatusDeclined, "tool_call_limit", nil)
+	}
+
 	if r.cfg.ToolUseLifecycle != nil {
 		authorizeInfo := toolUseAuthorizeInfo(call, time.Now())
 		if err := r.cfg.ToolUseLifecycle.Authorize(ctx, authorizeInfo); err != nil {
@@ -1089,6 +1206,8 @@ func (r *runner) executeOne(ctx context.Context, call ToolCall) (ToolResult, err
 		MessageID:   call.MessageID,
 		PartID:      call.PartID,
 		ModelCallID: call.ModelCallID,
+		Artifacts:   r.cfg.ToolOutputArtifacts,
+		Allowed:     r.allowed(),
 		Progress: tool.NewProgress(func(delta string, metadata map[string]any) {
 			r.emit(ToolExecutionProgressEvent{
 				CallID:      call.ID,
@@ -1136,9 +1255,55 @@ func (r *runner) executeOne(ctx context.Context, call ToolCall) (ToolResult, err
 			status, errorType = ToolUseStatusFailed, "result_validation"
 		}
 	}
+	res = r.spillToolOutput(ctx, call, res)
 	return r.settleToolUse(ctx, call, res, status, errorType, execErr)
 }
 
+// spillToolOutput moves output larger than MaxToolOutputBytes to an artifact
+// and keeps a preview that names it. The preview points the model at
+// read_artifact only when the run can call it. If the artifact cannot be
+// saved the output is still truncated, and the preview says why.
+func (r *runner) spillToolOutput(ctx context.Context, call ToolCall, res ToolResult) ToolResult {
+	limit := r.cfg.MaxToolOutputBytes
+	if limit <= 0 || r.cfg.ToolOutputArtifacts == nil || len(res.Output) <= limit {
+		return res
+	}
+	if _, ok := call.Tool.(tool.BoundedOutputTool); ok {
+		return res
+	}
+	size := len(res.Output)
+	preview := truncateUTF8(res.Output, limit)
+	id, err := r.cfg.ToolOutputArtifacts.SaveToolOutput(context.WithoutCancel(ctx), ToolOutputInfo{ToolUseID: res.ToolUseID, CallID: res.CallID, Name: res.Name, Output: res.Output})
+	metadata := maps.Clone(res.Metadata)
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	if err != nil {
+		res.Output = preview + fmt.Sprintf("\n\n[Output truncated: showing %d of %d bytes. The full output could not be saved: %v]", len(preview), size, err)
+		metadata["output_truncated"] = map[string]any{"bytes": size, "error": err.Error()}
+	} else if _, readable := r.registry.Get("read_artifact"); readable == nil {
+		res.Output = preview + fmt.Sprintf("\n\n[Output truncated: showing %d of %d bytes. The full output is saved as artifact %s; call read_artifact with this artifact_id to page through it.]", len(preview), size, id)
+		metadata["artifact"] = map[string]any{"id": id, "bytes": size}
+	} else {
+		res.Output = preview + fmt.Sprintf("\n\n[Output truncated: showing %d of %d bytes.]", len(preview), size)
+		metadata["artifact"] = map[string]any{"id": id, "bytes": size}
+	}
+	res.Metadata = metadata
+	return res
+}
+
+// truncateUTF8 returns the longest prefix of s that is at most n bytes and
+// does not split a UTF-8 sequence.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
 func toolUseAuthorizeInfo(call ToolCall, authorizedAt time.Time) ToolUseAuthorizeInfo {
 	return ToolUseAuthorizeInfo{Step: call.Step, Ordinal: call.Ordinal, ToolUseID: call.ToolUseID, CallID: call.ID, Name: call.Name, Args: call.Args, MessageID: call.MessageID, PartID: call.PartID, ModelCallID: call.ModelCallID, AuthorizedAt: authorizedAt}
 }
@@ -1213,31 +1378,52 @@ type permissionDecision struct {
 	err          error
 }
 
-func (r *runner) checkPermission(call ToolCall) permissionDecision {
+func (r *runner) checkPermission(ctx context.Context, call ToolCall) permissionDecision {
 	if len(r.cfg.Permissions) == 0 {
 		return permissionDecision{}
 	}
-	action, resources, err := permissionTarget(call, r.cfg.WorkDir)
+	target, err := permissionTarget(ctx, call, r.cfg.WorkDir)
 	if err != nil {
 		return permissionDecision{err: err}
 	}
-	if len(resources) == 0 {
-		resources = []string{"*"}
-	}
-	decision := permissionDecision{action: action}
-	for _, resource := range resources {
-		evaluated := permission.Evaluate(action, resource, r.cfg.Permissions, permission.EffectAllow)
-		switch evaluated.Effect {
-		case permission.EffectDeny:
-			decision.denyResource = resource
-			return decision
-		case permission.EffectAsk:
-			decision.askResources = append(decision.askResources, resource)
+	decision := permissionDecision{action: target.Action}
+	for _, check := range append([]tool.PermissionCheck{target}, target.Also...) {
+		resources := check.Resources
+		if len(resources) == 0 {
+			resources = []string{"*"}
+		}
+		var asks []string
+		for _, resource := range resources {
+			evaluated := permission.Evaluate(check.Action, resource, r.cfg.Permissions, permission.EffectAllow)
+			switch evaluated.Effect {
+			case permission.EffectDeny:
+				return permissionDecision{action: check.Action, denyResource: resource}
+			case permission.EffectAsk:
+				asks = append(asks, resource)
+			}
+		}
+		// One prompt covers the call; it names the first check that asks.
+		if len(decision.askResources) == 0 && len(asks) > 0 {
+			decision.action, decision.askResources = check.Action, asks
 		}
 	}
 	return decision
 }
 
+// allowed returns the Invocation.Allowed check for the run's rules, or nil
+// without rules. Paths are matched like the read and edit tools' paths.
+func (r *runner) allowed() func(action, resource string) bool {
+	if len(r.cfg.Permissions) == 0 {
+		return nil
+	}
+	return func(action, resource string) bool {
+		if action == "read" || action == "edit" {
+			resource = permission.PathResource(r.cfg.WorkDir, resource)
+		}
+		return permission.Evaluate(action, resource, r.cfg.Permissions, permission.EffectAllow).Effect == permission.EffectAllow
+	}
+}
+
 func permissionToolResult(call ToolCall, effect permission.Effect, action, resource string) ToolResult {
 	label := "permission denied"
 	if effect == permission.EffectAsk {
@@ -1259,33 +1445,33 @@ func permissionToolResult(call ToolCall, effect permission.Effect, action, resou
 	}
 }
 
-func permissionTarget(call ToolCall, workDir string) (string, []string, error) {
+func permissionTarget(ctx context.Context, call ToolCall, workDir string) (tool.PermissionCheck, error) {
 	if call.Tool != nil {
-		check, declared, err := tool.PermissionFor(call.Tool, tool.Invocation{Input: call.Args, WorkDir: workDir})
+		check, declared, err := tool.PermissionFor(ctx, call.Tool, tool.Invocation{Input: call.Args, WorkDir: workDir})
 		if err != nil {
-			return "", nil, err
+			return tool.PermissionCheck{}, err
 		}
 		if declared {
-			return check.Action, check.Resources, nil
+			return check, nil
 		}
 	}
 	switch call.Name {
 	case "write", "edit":
-		return "edit", pathResources(call, workDir), nil
+		return tool.PermissionCheck{Action: "edit", Resources: pathResources(call, workDir)}, nil
 	case "apply_patch":
-		return "edit", []string{"*"}, nil
+		return tool.PermissionCheck{Action: "edit", Resources: []string{"*"}}, nil
 	case "read":
-		return "read", pathResources(call, workDir), nil
+		return tool.PermissionCheck{Action: "read", Resources: pathResources(call, workDir)}, nil
 	case "bash":
-		return "bash", []string{stringArg(call.Args, "command", "*")}, nil
+		return tool.PermissionCheck{Action: "bash", Resources: []string{stringArg(call.Args, "command", "*")}}, nil
 	case "glob", "grep":
-		return call.Name, []string{stringArg(call.Args, "pattern", "*")}, nil
+		return tool.PermissionCheck{Action: call.Name, Resources: []string{stringArg(call.Args, "pattern", "*")}}, nil
 	case "webfetch":
-		return "webfetch", []string{stringArg(call.Args, "url", "*")}, nil
+		return tool.PermissionCheck{Action: "webfetch", Resources: []string{stringArg(call.Args, "url", "*")}}, nil
 	case "websearch":
-		return "websearch", []string{stringArg(call.Args, "query", "*")}, nil
+		return tool.PermissionCheck{Action: "websearch", Resources: []string{stringArg(call.Args, "query", "*")}}, nil
 	default:
-		return call.Name, []string{"*"}, nil
+		return tool.PermissionCheck{Action: call.Name, Resources: []string{"*"}}, nil
 	}
 }
 
@@ -1300,26 +1486,7 @@ func pathResources(call ToolCall, workDir string) []string {
 	if path == "" {
 		return []string{"*"}
 	}
-	if workDir != "" {
-		if rel := relativeResource(workDir, path); rel != "" {
-			return []string{rel}
-		}
-	}
-	return []string{filepath.ToSlash(filepath.Clean(path))}
-}
-
-func relativeResource(workDir, raw string) string {
-	path := raw
-	if !filepath.IsAbs(path) {
-		path = filepath.Join(workDir, path)
-	}
-	path = filepath.Clean(path)
-	workDir = filepath.Clean(workDir)
-	rel, err := filepath.Rel(workDir, path)
-	if err != nil || rel == ".." || filepath.IsAbs(rel) || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
-		return ""
-	}
-	return filepath.ToSlash(rel)
+	return []string{permission.PathResource(workDir, path)}
 }
 
 func stringArg(args map[string]any, key, fallback string) string {
@@ -1418,26 +1585,38 @@ func (r *runner) handleStructuredOutput(turn Turn) error {
 	if r.cfg.OutputSchema == nil {
 		return nil
 	}
-	text := textOf(turn.Assistant)
-	if text == "" {
-		return fmt.Errorf("loop: structured output required but assistant returned empty text")
-	}
-	var parsed map[string]any
-	if err := json.Unmarshal([]byte(text), &parsed); err != nil {
-		return fmt.Errorf("loop: structured output parse error: %w (raw: %s)", err, text)
-	}
-	if err := validateAgainstSchema(r.cfg.OutputSchema.Schema, parsed); err != nil {
-		return fmt.Errorf("loop: structured output validation error: %w (raw: %s)", err, text)
+	parsed, err := ValidateStructuredOutput(r.cfg.OutputSchema, turn.Assistant)
+	if err != nil {
+		return err
 	}
 	r.structuredOutput = parsed
 	r.emit(StructuredOutputEvent{
 		Schema:  r.cfg.OutputSchema.Name,
-		RawJSON: text,
+		RawJSON: textOf(turn.Assistant),
 		Parsed:  parsed,
 	})
 	return nil
 }
 
+// ValidateStructuredOutput parses msg's text as JSON and validates it
+// against schema, as a run does for its final assistant turn. It lets
+// callers that obtain a final message outside a run, such as provider
+// batches, apply the same contract.
+func ValidateStructuredOutput(schema *models.OutputSchema, msg models.Message) (map[string]any, error) {
+	text := textOf(msg)
+	if text == "" {
+		return nil, fmt.Errorf("loop: structured output required but assistant returned empty text")
+	}
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(text), &parsed); err != nil {
+		return nil, fmt.Errorf("loop: structured output parse error: %w (raw: %s)", err, text)
+	}
+	if err := validateAgainstSchema(schema.Schema, parsed); err != nil {
+		return nil, fmt.Errorf("loop: structured output validation error: %w (raw: %s)", err, text)
+	}
+	return parsed, nil
+}
+
 func validateAgainstSchema(schema map[string]any, value any) error {
 	c := jsonschema.NewCompiler()
 	if err := c.AddResource("schema.json", schema); err != nil {
@@ -0,0 +1,137 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chaserensberger/wingman/models"
+	"github.com/chaserensberger/wingman/tool"
+)
+
+// budgetClient requests the same tool call every turn and reports fixed
+// usage on each stream.
+type budgetClient struct {
+	usage models.Usage
+	calls int
+}
+
+func (c budgetClient) Prepare(context.Context, models.Request) (*models.PreparedRequest, error) {
+	return nil, errors.New("unexpected Prepare")
+}
+
+func (c budgetClient) Generate(context.Context, models.Request) (*models.Message, error) {
+	return nil, errors.New("unexpected Generate")
+}
+
+func (c budgetClient) Stream(context.Context, models.Request) (*models.EventStream[models.StreamPart, *models.Message], error) {
+	calls := max(c.calls, 1)
+	content := make(models.Content, 0, calls)
+	for i := range calls {
+		content = append(content, models.ToolCallPart{CallID: "call_" + string(rune('a'+i)), Name: "work", Input: map[string]any{}})
+	}
+	stream := models.NewEventStream[models.StreamPart, *models.Message](1)
+	stream.Push(models.FinishPart{Reason: models.FinishReasonToolCalls, Usage: c.usage})
+	stream.Close(&models.Message{Role: models.RoleAssistant, Content: content}, nil)
+	return stream, nil
+}
+
+func budgetTool(executed *atomic.Int32, fn func(context.Context) error) tool.Tool {
+	return tool.NewFuncTool("work", "work", tool.Definition{Name: "work", InputSchema: tool.InputSchema{Type: "object"}}, func(ctx context.Context, _ tool.Invocation) (tool.Result, error) {
+		executed.Add(1)
+		if fn != nil {
+			if err := fn(ctx); err != nil {
+				return tool.Result{}, err
+			}
+		}
+		return tool.Result{Text: "done"}, nil
+	})
+}
+
+func TestRunStopsAtLimits(t *testing.T) {
+	t.Parallel()
+	waitForCancel := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	tests := []struct {
+		name     string
+		client   budgetClient
+		tool     func(context.Context) error
+		cfg      Config
+		reason   StopReason
+		steps    int
+		executed int32
+	}{
+		{name: "steps", cfg: Config{MaxSteps: 2}, reason: StopReasonMaxSteps, steps: 2, executed: 2},
+		{name: "total tokens", client: budgetClient{usage: models.Usage{InputTokens: 60, OutputTokens: 10, TotalTokens: 70}}, cfg: Config{MaxTotalTokens: 100}, reason: StopReasonMaxTotalTokens, steps: 2, executed: 2},
+		{name: "tokens without total", client: budgetClient{usage: models.Usage{InputTokens: 40, OutputTokens: 10}}, cfg: Config{MaxTotalTokens: 50}, reason: StopReasonMaxTotalTokens, steps: 1, executed: 1},
+		{name: "tool calls", client: budgetClient{calls: 2}, cfg: Config{MaxToolCalls: 3}, reason: StopReasonMaxToolCalls, steps: 2, executed: 3},
+		{name: "duration", tool: waitForCancel, cfg: Config{MaxDuration: 20 * time.Millisecond}, reason: StopReasonMaxDuration, steps: 1, executed: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var executed atomic.Int32
+			var declined []string
+			cfg := tc.cfg
+			cfg.Client, cfg.Model = tc.client, testModel
+			cfg.Tools = []tool.Tool{budgetTool(&executed, tc.tool)}
+			cfg.Sink = SinkFunc(func(event Event) {
+				if end, ok := event.(ToolExecutionEndEvent); ok && end.Result.Status == ToolUseStatusDeclined {
+					declined = append(declined, end.Result.Error)
+				}
+			})
+			result, err := Run(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.StopReason != tc.reason || !result.StopReason.IsLimit() {
+				t.Fatalf("stop reason = %q, want %q", result.StopReason, tc.reason)
+			}
+			if result.Steps != tc.steps || executed.Load() != tc.executed {
+				t.Fatalf("steps = %d, executed = %d; want %d, %d", result.Steps, executed.Load(), tc.steps, tc.executed)
+			}
+			if tc.reason == StopReasonMaxToolCalls && (len(declined) != 1 || declined[0] != "tool call limit of 3 reached for this run") {
+				t.Fatalf("declined = %v", declined)
+			}
+		})
+	}
+}
+
+func TestRunCountsOnlyAdmittedToolCalls(t *testing.T) {
+	t.Parallel()
+	var executed, seen atomic.Int32
+	result, err := Run(context.Background(), Config{
+		Client: budgetClient{calls: 2}, Model: testModel, MaxToolCalls: 2,
+		Tools: []tool.Tool{budgetTool(&executed, nil)},
+		Hooks: Hooks{BeforeToolCall: func(_ context.Context, call ToolCall) (map[string]any, error) {
+			// Skip the first turn's calls; they must not use up the budget.
+			if seen.Add(1) <= 2 {
+				return nil, ErrSkipTool
+			}
+			return nil, nil
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.StopReason != StopReasonMaxToolCalls || result.Steps != 3 || executed.Load() != 2 {
+		t.Fatalf("stop reason = %q, steps = %d, executed = %d", result.StopReason, result.Steps, executed.Load())
+	}
+}
+
+func TestRunCallerAbortIsNotDurationLimit(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	var executed atomic.Int32
+	result, err := Run(ctx, Config{
+		Client: budgetClient{}, Model: testModel, MaxDuration: time.Minute,
+		Tools: []tool.Tool{budgetTool(&executed, func(context.Context) error { cancel(); return nil })},
+	})
+	if !errors.Is(err, context.Canceled) || result.StopReason != StopReasonAborted {
+		t.Fatalf("result = %q, error = %v", result.StopReason, err)
+	}
+}
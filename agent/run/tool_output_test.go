@@ -0,0 +1,138 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chaserensberger/wingman/models"
+	"github.com/chaserensberger/wingman/tool"
+)
+
+type memoryArtifacts struct {
+	mu    sync.Mutex
+	saved map[string]ToolOutputInfo
+	err   error
+}
+
+func (a *memoryArtifacts) SaveToolOutput(_ context.Context, info ToolOutputInfo) (string, error) {
+	if a.err != nil {
+		return "", a.err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.saved == nil {
+		a.saved = map[string]ToolOutputInfo{}
+	}
+	a.saved["art_1"] = info
+	return "art_1", nil
+}
+
+func (a *memoryArtifacts) ReadArtifact(_ context.Context, id string) ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	info, ok := a.saved[id]
+	if !ok {
+		return nil, errors.New("artifact not found")
+	}
+	return []byte(info.Output), nil
+}
+
+func TestLargeToolOutputSpillsToArtifact(t *testing.T) {
+	output := "0123456789" + strings.Repeat("é", 5) + "tail"
+	var requests []models.Request
+	client := &modelCallTestClient{stream: func(_ context.Context, req models.Request) (*models.EventStream[models.StreamPart, *models.Message], error) {
+		requests = append(requests, req)
+		switch len(requests) {
+		case 1:
+			return completedStream(models.Message{Role: models.RoleAssistant, Content: models.Content{models.ToolCallPart{CallID: "call_work", Name: "work", Input: map[string]any{}}}}), nil
+		case 2:
+			return completedStream(models.Message{Role: models.RoleAssistant, Content: models.Content{models.ToolCallPart{CallID: "call_page", Name: "read_artifact", Input: map[string]any{"artifact_id": "art_1", "offset": 10, "limit": 4}}}}), nil
+		default:
+			return completedStream(models.Message{Role: models.RoleAssistant, Content: models.Content{models.TextPart{Text: "done"}}}), nil
+		}
+	}}
+	work := tool.NewFuncTool("work", "work", tool.Definition{Name: "work", InputSchema: tool.InputSchema{Type: "object"}}, func(context.Context, tool.Invocation) (tool.Result, error) {
+		return tool.Result{Text: output}, nil
+	})
+	artifacts := &memoryArtifacts{}
+	result, err := Run(context.Background(), Config{
+		Client: client, Model: testModel, Tools: []tool.Tool{work, tool.NewReadArtifactTool()},
+		MaxToolOutputBytes: 11, ToolOutputArtifacts: artifacts,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if artifacts.saved["art_1"].Output != output || artifacts.saved["art_1"].Name != "work" {
+		t.Fatalf("saved = %#v", artifacts.saved)
+	}
+	// The preview stops before the multi-byte rune that crosses the limit.
+	spilled := result.Turns[0].Results[0]
+	if !strings.HasPrefix(spilled.Output, "0123456789\n\n[Output truncated: showing 10 of 24 bytes.") || !strings.Contains(spilled.Output, "artifact art_1") {
+		t.Fatalf("spilled output = %q", spilled.Output)
+	}
+	if artifact, _ := spilled.Metadata["artifact"].(map[string]any); artifact["id"] != "art_1" || artifact["bytes"] != len(output) {
+		t.Fatalf("metadata = %#v", spilled.Metadata)
+	}
+	if sent, ok := requests[1].Messages[len(requests[1].Messages)-1].Content[0].(models.ToolPart); !ok || sent.Output != spilled.Output {
+		t.Fatalf("model received %#v", requests[1].Messages[len(requests[1].Messages)-1].Content)
+	}
+	// Pages from read_artifact are bounded by the tool and never spill.
+	page := result.Turns[1].Results[0]
+	if page.IsError || !strings.Contains(page.Output, "<content>\néé\n") || !strings.Contains(page.Output, "Use offset=14 to continue.") {
+		t.Fatalf("page = %#v", page)
+	}
+}
+
+func TestToolOutputIsTruncatedWhenArtifactSaveFails(t *testing.T) {
+	calls := 0
+	client := &modelCallTestClient{stream: func(context.Context, models.Request) (*models.EventStream[models.StreamPart, *models.Message], error) {
+		calls++
+		if calls == 1 {
+			return completedStream(models.Message{Role: models.RoleAssistant, Content: models.Content{models.ToolCallPart{CallID: "call_work", Name: "work", Input: map[string]any{}}}}), nil
+		}
+		return completedStream(models.Message{Role: models.RoleAssistant, Content: models.Content{models.TextPart{Text: "done"}}}), nil
+	}}
+	work := tool.NewFuncTool("work", "work", tool.Definition{Name: "work", InputSchema: tool.InputSchema{Type: "object"}}, func(context.Context, tool.Invocation) (tool.Result, error) {
+		return tool.Result{Text: strings.Repeat("x", 100)}, nil
+	})
+	result, err := Run(context.Background(), Config{
+		Client: client, Model: testModel, Tools: []tool.Tool{work},
+		MaxToolOutputBytes: 8, ToolOutputArtifacts: &memoryArtifacts{err: errors.New("disk full")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := result.Turns[0].Results[0]
+	if !strings.HasPrefix(got.Output, "xxxxxxxx\n\n[Output truncated") || !strings.Contains(got.Output, "disk full") || got.Metadata["output_truncated"] == nil {
+		t.Fatalf("result = %#v", got)
+	}
+}
+
+func TestSpilledOutputOmitsReadArtifactHintWithoutTheTool(t *testing.T) {
+	calls := 0
+	client := &modelCallTestClient{stream: func(context.Context, models.Request) (*models.EventStream[models.StreamPart, *models.Message], error) {
+		calls++
+		if calls == 1 {
+			return completedStream(models.Message{Role: models.RoleAssistant, Content: models.Content{models.ToolCallPart{CallID: "call_work", Name: "work", Input: map[string]any{}}}}), nil
+		}
+		return completedStream(models.Message{Role: models.RoleAssistant, Content: models.Content{models.TextPart{Text: "done"}}}), nil
+	}}
+	work := tool.NewFuncTool("work", "work", tool.Definition{Name: "work", InputSchema: tool.InputSchema{Type: "object"}}, func(context.Context, tool.Invocation) (tool.Result, error) {
+		return tool.Result{Text: strings.Repeat("x", 100)}, nil
+	})
+	artifacts := &memoryArtifacts{}
+	result, err := Run(context.Background(), Config{
+		Client: client, Model: testModel, Tools: []tool.Tool{work},
+		MaxToolOutputBytes: 8, ToolOutputArtifacts: artifacts,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := result.Turns[0].Results[0]
+	if got.Output != "xxxxxxxx\n\n[Output truncated: showing 8 of 100 bytes.]" || artifacts.saved["art_1"].Output == "" || got.Metadata["artifact"] == nil {
+		t.Fatalf("result = %#v", got)
+	}
+}
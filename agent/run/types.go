@@ -126,6 +126,33 @@ type Config struct {
 	// explicitly.
 	MaxSteps int
 
+	// MaxTotalTokens stops the run before the next assistant turn once the
+	// accumulated usage reaches this many tokens. Zero means unlimited. The
+	// turn that crosses the budget completes, so usage can exceed it.
+	MaxTotalTokens int
+
+	// MaxDuration bounds the run's wall-clock time. When it elapses, the
+	// in-flight provider call or tool is cancelled and the run stops with
+	// StopReasonMaxDuration. Zero means unlimited.
+	MaxDuration time.Duration
+
+	// MaxToolCalls caps the tool calls the run executes. Calls skipped by
+	// hooks or declined by permissions do not count. Calls beyond the
+	// budget are declined with error type "tool_call_limit", and the run
+	// stops with StopReasonMaxToolCalls before the next assistant turn.
+	// Zero means unlimited.
+	MaxToolCalls int
+
+	// MaxToolOutputBytes bounds the tool output kept inline in the
+	// transcript and sent to the model. Larger output is saved through
+	// ToolOutputArtifacts and replaced by a preview naming the artifact.
+	// Zero, or a nil ToolOutputArtifacts, keeps all output inline.
+	MaxToolOutputBytes int
+
+	// ToolOutputArtifacts stores spilled tool output. Tools also receive it
+	// as Invocation.Artifacts so they can read artifacts back.
+	ToolOutputArtifacts ToolOutputArtifacts
+
 	// ToolExecution overrides the default per-tool sequential/parallel
 	// decision. Empty string defers to per-tool Sequential() opt-in.
 	ToolExecution ToolExecutionMode
@@ -161,6 +188,23 @@ type Config struct {
 	ToolUseLifecycle ToolUseLifecycle
 }
 
+// ToolOutputArtifacts stores tool output too large to keep inline and reads
+// stored artifacts back by ID.
+type ToolOutputArtifacts interface {
+	tool.ArtifactReader
+	// SaveToolOutput stores the full output of one tool call and returns
+	// the artifact ID.
+	SaveToolOutput(ctx context.Context, info ToolOutputInfo) (string, error)
+}
+
+// ToolOutputInfo is the full output of one tool call moved to an artifact.
+type ToolOutputInfo struct {
+	ToolUseID string
+	CallID    string
+	Name      string
+	Output    string
+}
+
 // RetryPolicy controls retryable provider dispatch failures.
 type RetryPolicy struct {
 	MaxAttempts  int
@@ -190,11 +234,25 @@ type PermissionRequestInfo struct {
 	MessageID, PartID, ModelCallID string
 	Action                         string
 	Resources                      []string
+	// ValidateInput checks replacement input against the tool's input
+	// schema, so prompters can refuse an invalid edit before replying.
+	ValidateInput func(args map[string]any) error
+}
+
+// PermissionReply is a user's answer to an authored ask permission request.
+type PermissionReply struct {
+	Response PermissionResponse
+	// Input, when set on an approval, replaces the proposed tool input. The
+	// run re-validates it and re-checks deny rules before execution.
+	Input map[string]any
+	// Message, when set on a rejection, is returned to the model as the
+	// tool result.
+	Message string
 }
 
 // PermissionPrompter resolves an authored ask permission request.
 type PermissionPrompter interface {
-	Request(ctx context.Context, info PermissionRequestInfo) (PermissionResponse, error)
+	Request(ctx context.Context, info PermissionRequestInfo) (PermissionReply, error)
 }
 
 // ToolUseStatus is a terminal durable tool-use status.
@@ -296,7 +354,13 @@ type ModelCallFinishInfo struct {
 	Assistant         *models.Message
 	Usage             models.Usage
 	ProviderRequestID string
-	Failure           error
+	// Throttled is how long a shared provider rate limiter held the call
+	// back before dispatch.
+	Throttled time.Duration
+	// Credential names the provider credential that served the call when
+	// the provider has several.
+	Credential string
+	Failure    error
 }
 
 // ToolExecutionMode selects per-call vs per-batch tool dispatch.
@@ -602,7 +666,13 @@ type Turn struct {
 	Attempt int
 	// ProviderRequestID is the provider request ID observed in response metadata.
 	ProviderRequestID string
-	Assistant         models.Message
+	// Throttled is how long the provider's rate limiter held the call
+	// back, as observed in response metadata.
+	Throttled time.Duration
+	// Credential names the provider credential that served the call, as
+	// observed in response metadata.
+	Credential string
+	Assistant  models.Message
 	// Results is in source order (the order the assistant emitted the
 	// tool calls in), regardless of execution mode. Empty if the
 	// assistant produced no tool calls.
@@ -656,6 +726,17 @@ const (
 	// assistant produced a tool-call-free turn.
 	StopReasonMaxSteps StopReason = "max_steps"
 
+	// StopReasonMaxTotalTokens: accumulated usage reached
+	// Config.MaxTotalTokens.
+	StopReasonMaxTotalTokens StopReason = "max_total_tokens"
+
+	// StopReasonMaxDuration: the run exceeded Config.MaxDuration.
+	StopReasonMaxDuration StopReason = "max_duration"
+
+	// StopReasonMaxToolCalls: the model requested more tool calls than
+	// Config.MaxToolCalls allows.
+	StopReasonMaxToolCalls StopReason = "max_tool_calls"
+
 	// StopReasonAborted: the loop's context was cancelled.
 	StopReasonAborted StopReason = "aborted"
 
@@ -664,6 +745,16 @@ const (
 	StopReasonError StopReason = "error"
 )
 
+// IsLimit reports whether the run stopped because it hit one of its
+// configured budgets rather than finishing or failing.
+func (r StopReason) IsLimit() bool {
+	switch r {
+	case StopReasonMaxSteps, StopReasonMaxTotalTokens, StopReasonMaxDuration, StopReasonMaxToolCalls:
+		return true
+	}
+	return false
+}
+
 // Sink receives loop lifecycle events. The loop serializes all
 // emissions through a single internal goroutine before delivering them
 // to OnEvent, so implementations need not be concurrent-safe even when
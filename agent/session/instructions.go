@@ -0,0 +1,151 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// InstructionFileNames are the project instruction files looked up in each
+// directory, in load order.
+var InstructionFileNames = []string{"AGENTS.md", filepath.Join(".wingman", "instructions.md")}
+
+// maxInstructionFileBytes bounds how much of one instruction file reaches
+// the system prompt.
+const maxInstructionFileBytes = 64 << 10
+
+// InstructionFile is one project instruction file merged into a run's
+// system prompt.
+type InstructionFile struct {
+	// Path is the file's absolute path.
+	Path string `json:"path"`
+	// Bytes is the size of the file on disk.
+	Bytes int `json:"bytes"`
+	// SHA256 identifies the loaded content so clients can tell when a
+	// file changed between runs.
+	SHA256 string `json:"sha256"`
+	// Truncated reports that only the first 64 KiB reached the prompt.
+	Truncated bool `json:"truncated,omitempty"`
+
+	name    string
+	content string
+}
+
+// InstructionsLoadedEvent reports the instruction files a run loaded. It is
+// delivered on RunStream as "instructions" before the first iteration.
+type InstructionsLoadedEvent struct {
+	Files []InstructionFile `json:"files"`
+}
+
+// LoadInstructions reads project instruction files from root down to workDir.
+// Files in outer directories load first so instructions closer to the working
+// directory come later and take precedence. When root is empty or does not
+// contain workDir, the nearest ancestor holding a .git entry is used, and
+// failing that only workDir itself is searched. Missing files are skipped,
+// and so are unreadable ones, with a warning on logger when it is non-nil.
+func LoadInstructions(workDir, root string, logger *slog.Logger) []InstructionFile {
+	if workDir == "" {
+		return nil
+	}
+	workDir = filepath.Clean(workDir)
+	if root != "" {
+		root = filepath.Clean(root)
+	}
+	if root == "" || !withinDir(root, workDir) {
+		root = projectRoot(workDir)
+	}
+	dirs := []string{workDir}
+	for dir := workDir; dir != root; {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+		dirs = append(dirs, dir)
+	}
+
+	var files []InstructionFile
+	for i := len(dirs) - 1; i >= 0; i-- {
+		for _, name := range InstructionFileNames {
+			file, ok, err := readInstructionFile(dirs[i], name)
+			if err != nil {
+				if logger != nil {
+					logger.Warn("skipping unreadable instruction file", "error", err)
+				}
+				continue
+			}
+			if ok {
+				rel, relErr := filepath.Rel(root, file.Path)
+				if relErr != nil {
+					rel = file.Path
+				}
+				file.name = filepath.ToSlash(rel)
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
+func readInstructionFile(dir, name string) (InstructionFile, bool, error) {
+	path := filepath.Join(dir, name)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return InstructionFile{}, false, nil
+	}
+	if err != nil {
+		return InstructionFile{}, false, fmt.Errorf("read instruction file %s: %w", path, err)
+	}
+	sum := sha256.Sum256(data)
+	file := InstructionFile{Path: path, Bytes: len(data), SHA256: hex.EncodeToString(sum[:])}
+	if len(data) > maxInstructionFileBytes {
+		// Cut on a rune boundary so the prompt stays valid UTF-8.
+		n := maxInstructionFileBytes
+		for n > 0 && !utf8.RuneStart(data[n]) {
+			n--
+		}
+		data, file.Truncated = data[:n], true
+	}
+	file.content = strings.TrimSpace(string(data))
+	return file, file.content != "", nil
+}
+
+// projectRoot returns the nearest ancestor of dir that holds a .git entry, or
+// dir itself.
+func projectRoot(dir string) string {
+	for current := dir; ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
+
+func withinDir(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// formatInstructions renders loaded files as one system prompt section.
+func formatInstructions(files []InstructionFile) string {
+	if len(files) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Project instructions follow, from the repository root down to the working directory. Later files take precedence.")
+	for _, file := range files {
+		fmt.Fprintf(&b, "\n\n<instructions path=%q>\n%s\n</instructions>", file.name, file.content)
+	}
+	return b.String()
+}
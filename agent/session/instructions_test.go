@@ -0,0 +1,139 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/chaserensberger/wingman/models"
+)
+
+func writeInstructionFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadInstructionsOrdersRootToWorkDir(t *testing.T) {
+	root := t.TempDir()
+	workDir := filepath.Join(root, "services", "api")
+	writeInstructionFile(t, filepath.Join(filepath.Dir(root), "AGENTS.md"), "outside the root")
+	writeInstructionFile(t, filepath.Join(root, "AGENTS.md"), "root rules")
+	writeInstructionFile(t, filepath.Join(root, ".wingman", "instructions.md"), "root wingman rules")
+	writeInstructionFile(t, filepath.Join(root, "services", "AGENTS.md"), "  ")
+	writeInstructionFile(t, filepath.Join(workDir, "AGENTS.md"), "api rules")
+
+	files := LoadInstructions(workDir, root, nil)
+	var names []string
+	for _, file := range files {
+		names = append(names, file.name)
+	}
+	if strings.Join(names, ",") != "AGENTS.md,.wingman/instructions.md,services/api/AGENTS.md" {
+		t.Fatalf("loaded = %v", names)
+	}
+	if files[2].Path != filepath.Join(workDir, "AGENTS.md") || files[2].Bytes != len("api rules") || files[2].SHA256 == "" {
+		t.Fatalf("file = %#v", files[2])
+	}
+
+	// Without a root, the repository root bounds the search.
+	if err := os.Mkdir(filepath.Join(root, "services", ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files = LoadInstructions(workDir, "", nil)
+	if len(files) != 1 || files[0].name != "api/AGENTS.md" {
+		t.Fatalf("files = %#v", files)
+	}
+}
+
+func TestLoadInstructionsTruncatesOnRuneBoundaryAndSkipsUnreadableFiles(t *testing.T) {
+	root := t.TempDir()
+	// A directory where a file is expected cannot be read; the other
+	// instruction file still loads.
+	if err := os.MkdirAll(filepath.Join(root, ".wingman", "instructions.md"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Repeat("a", maxInstructionFileBytes-1) + "é" + "tail"
+	writeInstructionFile(t, filepath.Join(root, "AGENTS.md"), content)
+
+	files := LoadInstructions(root, root, nil)
+	if len(files) != 1 || !files[0].Truncated {
+		t.Fatalf("files = %v", files)
+	}
+	if !utf8.ValidString(files[0].content) || len(files[0].content) != maxInstructionFileBytes-1 {
+		t.Fatalf("truncated content is %d bytes, valid UTF-8 = %v", len(files[0].content), utf8.ValidString(files[0].content))
+	}
+}
+
+func TestRunReloadsProjectInstructions(t *testing.T) {
+	workDir := t.TempDir()
+	writeInstructionFile(t, filepath.Join(workDir, "AGENTS.md"), "Use tabs.")
+	client := &requestCaptureClient{}
+	sess := New(
+		WithClient(client),
+		WithModelRef(models.ModelRef{Provider: "test", ID: "model"}, models.ModelInfo{}),
+		WithSystem("Agent prompt."),
+		WithWorkDir(workDir),
+		WithProjectInstructions(workDir),
+	)
+
+	stream, err := sess.RunStream(context.Background(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded []InstructionFile
+	for stream.Next() {
+		if event, ok := stream.Event().Data.(InstructionsLoadedEvent); ok {
+			loaded = event.Files
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 || len(stream.Result().Instructions) != 1 {
+		t.Fatalf("loaded = %#v, result = %#v", loaded, stream.Result().Instructions)
+	}
+	system := client.request.System
+	if !strings.HasPrefix(system, "Agent prompt.\n\n") || !strings.Contains(system, "<instructions path=\"AGENTS.md\">\nUse tabs.\n</instructions>\n\nCurrent date: ") {
+		t.Fatalf("system = %q", system)
+	}
+
+	writeInstructionFile(t, filepath.Join(workDir, "AGENTS.md"), "Use spaces.")
+	result, err := sess.Run(context.Background(), "again")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(client.request.System, "Use spaces.") || result.Instructions[0].SHA256 == loaded[0].SHA256 {
+		t.Fatalf("system after edit = %q", client.request.System)
+	}
+}
+
+func TestRunEvaluatesContextSections(t *testing.T) {
+	client := &requestCaptureClient{}
+	terminal := ""
+	sess := New(
+		WithClient(client),
+		WithModelRef(models.ModelRef{Provider: "test", ID: "model"}, models.ModelInfo{}),
+		WithSystem("Agent prompt."),
+		WithContextSection(func() string { return terminal }),
+	)
+	if _, err := sess.Run(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(client.request.System, "Agent prompt.\n\nCurrent date: ") {
+		t.Fatalf("system without section = %q", client.request.System)
+	}
+	terminal = "$ make test\nok"
+	if _, err := sess.Run(context.Background(), "again"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(client.request.System, "Agent prompt.\n\n$ make test\nok\n\nCurrent date: ") {
+		t.Fatalf("system with section = %q", client.request.System)
+	}
+}
@@ -99,6 +99,59 @@ func (s *Session) persistMessage(ctx context.Context, msg models.Message, idx in
 	return msg, nil
 }
 
+// RecordedTurn is a single prompt and its outcome produced outside a run
+// loop, such as a provider batch result.
+type RecordedTurn struct {
+	SessionID string
+	RunID     string
+	AgentID   string
+	Model     models.ModelRef
+	ModelInfo models.ModelInfo
+	Prompt    string
+	// Assistant is the final message. It is nil when Failure is set.
+	Assistant        *models.Message
+	StructuredOutput map[string]any
+	// ProviderRequestID identifies the upstream request, when reported.
+	ProviderRequestID string
+	StartedAt         time.Time
+	CompletedAt       time.Time
+	Failure           error
+}
+
+// RecordTurn appends turn's user prompt and assistant message to the
+// session history and records its model call, exactly as a one-step run
+// would. The caller owns the run lifecycle around it.
+func RecordTurn(ctx context.Context, st store.Store, turn RecordedTurn) error {
+	existing, err := st.ListMessages(ctx, turn.SessionID)
+	if err != nil {
+		return fmt.Errorf("list messages: %w", err)
+	}
+	s := &Session{store: st, id: turn.SessionID, runID: turn.RunID}
+	idx := len(existing)
+	prompt := models.Message{Role: models.RoleUser, Content: models.Content{models.TextPart{Text: turn.Prompt}}}
+	if _, err := s.persistMessage(ctx, prompt, idx); err != nil {
+		return err
+	}
+	record := run.Turn{
+		Step:              1,
+		Attempt:           1,
+		ProviderRequestID: turn.ProviderRequestID,
+		StartedAt:         turn.StartedAt,
+		CompletedAt:       turn.CompletedAt,
+		Failure:           turn.Failure,
+	}
+	var msgID, stopReason string
+	if turn.Assistant != nil {
+		assistant, err := s.persistMessage(ctx, *turn.Assistant, idx+1)
+		if err != nil {
+			return err
+		}
+		record.Assistant = assistant
+		msgID, stopReason = assistant.ID, string(run.StopReasonEndTurn)
+	}
+	return s.persistModelCall(ctx, msgID, record, turn.Model, turn.ModelInfo, turn.RunID, turn.AgentID, stopReason, turn.StructuredOutput)
+}
+
 // storedMessageFromModel serializes a complete message snapshot. Existing
 // part timestamps and opaque payloads are retained when supplied by base.
 func storedMessageFromModel(msg models.Message, base store.StoredMessage) (models.Message, store.StoredMessage, error) {
@@ -374,6 +427,33 @@ func (r *toolUseRecorder) Finish(ctx context.Context, info run.ToolUseFinishInfo
 	return nil
 }
 
+// artifactRecorder stores spilled tool output as artifacts of the session and
+// reads the session's artifacts back for tools.
+type artifactRecorder struct {
+	store     store.Store
+	sessionID string
+	runID     string
+}
+
+func (r *artifactRecorder) SaveToolOutput(ctx context.Context, info run.ToolOutputInfo) (string, error) {
+	artifact := &store.Artifact{
+		SessionID: r.sessionID, RunID: r.runID, ToolUseID: info.ToolUseID, CallID: info.CallID,
+		Name: info.Name, MediaType: "text/plain; charset=utf-8", Content: []byte(info.Output),
+	}
+	if err := r.store.SaveArtifact(ctx, artifact); err != nil {
+		return "", fmt.Errorf("save tool output artifact: %w", err)
+	}
+	return artifact.ID, nil
+}
+
+func (r *artifactRecorder) ReadArtifact(ctx context.Context, id string) ([]byte, error) {
+	artifact, err := r.store.GetArtifact(ctx, r.sessionID, id)
+	if err != nil {
+		return nil, err
+	}
+	return artifact.Content, nil
+}
+
 func (r *toolUseRecorder) logToolUse(phase string, use store.ToolUse) {
 	if r.logger == nil {
 		return
@@ -448,6 +528,8 @@ func (r *modelCallRecorder) Finish(ctx context.Context, info run.ModelCallFinish
 		Step:              info.Step,
 		Attempt:           info.Attempt,
 		ProviderRequestID: info.ProviderRequestID,
+		Throttled:         info.Throttled,
+		Credential:        info.Credential,
 		Usage:             info.Usage,
 		StartedAt:         info.StartedAt,
 		CompletedAt:       info.CompletedAt,
@@ -533,6 +615,8 @@ func modelCallRecord(sessionID, runID, agentID string, model models.ModelRef, in
 		ContextWindow:     info.ContextWindow,
 		ContextPercent:    usage.ContextPercent(info.ContextWindow),
 		Cost:              estimatedCost(usage, info),
+		ThrottledMS:       turn.Throttled.Milliseconds(),
+		Credential:        turn.Credential,
 		StartedAt:         turn.StartedAt.UTC(),
 		CompletedAt:       turn.CompletedAt.UTC(),
 		CreatedAt:         now,
@@ -55,9 +55,23 @@ type Session struct {
 	permissions permission.Ruleset
 	prompter    run.PermissionPrompter
 	retry       run.RetryPolicy
+	limits      Limits
 	logger      *slog.Logger
-	agentID     string
-	runID       string
+
+	// maxToolOutputBytes spills larger tool output to session artifacts
+	// when the session has a store.
+	maxToolOutputBytes int
+
+	// projectInstructions enables loading instruction files from workDir
+	// up to instructionRoot at the start of every run.
+	projectInstructions bool
+	instructionRoot     string
+	agentID             string
+	runID               string
+
+	// contextSections are evaluated at the start of every run and appended
+	// to the system prompt after project instructions.
+	contextSections []func() string
 
 	// Plugins installed via WithPlugin. Composed into Built at Run
 	// time so the session sees the model that was set most recently
@@ -229,6 +243,52 @@ func WithRetryPolicy(policy run.RetryPolicy) Option {
 	return func(s *Session) { s.retry = policy }
 }
 
+// Limits bounds each run of a session. Zero fields are unlimited. A run
+// that reaches a limit stops cleanly with the matching run.StopReason.
+type Limits struct {
+	MaxSteps       int
+	MaxTotalTokens int
+	MaxDuration    time.Duration
+	MaxToolCalls   int
+}
+
+// WithLimits bounds every run of this session. See run.Config for how each
+// limit is enforced.
+func WithLimits(limits Limits) Option {
+	return func(s *Session) { s.limits = limits }
+}
+
+// WithMaxToolOutputBytes keeps at most n bytes of each tool result inline.
+// Larger output is stored as a session artifact, and the model sees a
+// preview with the artifact ID. It has no effect without WithStore. Zero
+// keeps all output inline.
+func WithMaxToolOutputBytes(n int) Option {
+	return func(s *Session) { s.maxToolOutputBytes = n }
+}
+
+// WithProjectInstructions merges project instruction files into the system
+// prompt after the session's own prompt. Files are re-read at the start of
+// every run, so edits between runs take effect. root bounds the upward search
+// from the working directory; see LoadInstructions for the empty-root
+// fallback. Sessions without a working directory load nothing.
+func WithProjectInstructions(root string) Option {
+	return func(s *Session) {
+		s.projectInstructions = true
+		s.instructionRoot = root
+	}
+}
+
+// WithContextSection appends the text returned by section to the system
+// prompt, after project instructions. section is called at the start of every
+// run; an empty result adds nothing.
+func WithContextSection(section func() string) Option {
+	return func(s *Session) {
+		if section != nil {
+			s.contextSections = append(s.contextSections, section)
+		}
+	}
+}
+
 // WithLogger enables structured runtime logs for this session. The logger is
 // expected to already carry request/session attributes supplied by the caller.
 func WithLogger(logger *slog.Logger) Option {
@@ -528,6 +588,10 @@ type Result struct {
 	// StructuredOutput is populated when the run had an active OutputSchema
 	// and the model returned a parseable, schema-valid final message.
 	StructuredOutput map[string]any
+
+	// Instructions lists the project instruction files merged into the
+	// system prompt for this run.
+	Instructions []InstructionFile
 }
 
 // ToolCallResult is a serialization-friendly view of one tool call.
@@ -557,16 +621,28 @@ var (
 // Result is always non-nil even when err is non-nil, so callers can
 // persist partial state.
 func (s *Session) Run(ctx context.Context, message string) (*Result, error) {
-	return s.runWith(ctx, message, nil)
+	return s.runWith(ctx, message, nil, nil)
 }
 
 // runWith is the shared core for Run and RunStream. extraSink, if
 // non-nil, is invoked for every loop event in addition to the session's
 // internal sink. The session's own sink collects ToolCallResults and
 // keeps the running history in sync.
-func (s *Session) runWith(ctx context.Context, message string, extraSink run.Sink) (*Result, error) {
+//
+// loaded, if non-nil, receives the project instruction files before the loop
+// starts.
+func (s *Session) runWith(ctx context.Context, message string, extraSink run.Sink, loaded func([]InstructionFile)) (*Result, error) {
 	s.runMu.Lock()
 	defer s.runMu.Unlock()
+	// Instruction files are read without s.mu so slow file systems do not
+	// block the session's accessors.
+	s.mu.Lock()
+	loadProjectInstructions, instructionDir, instructionRoot, instructionLogger := s.projectInstructions, s.workDir, s.instructionRoot, s.logger
+	s.mu.Unlock()
+	var instructions []InstructionFile
+	if loadProjectInstructions {
+		instructions = LoadInstructions(instructionDir, instructionRoot, instructionLogger)
+	}
 	s.mu.Lock()
 	if s.closed {
 		s.mu.Unlock()
@@ -580,17 +656,27 @@ func (s *Session) runWith(ctx context.Context, message string, extraSink run.Sin
 	client := s.client
 	model := s.model
 	modelInfo := s.modelInfo
+	sections := []string{formatInstructions(instructions)}
+	for _, section := range s.contextSections {
+		sections = append(sections, section())
+	}
+	sections = append(sections, "Current date: "+time.Now().Format(time.DateOnly)+".")
 	system := s.system
-	currentDate := "Current date: " + time.Now().Format(time.DateOnly) + "."
-	if system == "" {
-		system = currentDate
-	} else {
-		system += "\n\n" + currentDate
+	for _, section := range sections {
+		if section == "" {
+			continue
+		}
+		if system != "" {
+			system += "\n\n"
+		}
+		system += section
 	}
 	tools := append([]tool.Tool(nil), s.tools...)
 	permissions := append(permission.Ruleset(nil), s.permissions...)
 	prompter := s.prompter
 	retry := s.retry
+	limits := s.limits
+	maxToolOutputBytes := s.maxToolOutputBytes
 	workDir := s.workDir
 	logger := s.logger
 	rawTransformHistory := s.transformHistory
@@ -663,6 +749,9 @@ func (s *Session) runWith(ctx context.Context, message string, extraSink run.Sin
 	s.history[userMsgIdx] = userMsg
 	historySnap := append([]models.Message(nil), s.history...)
 	s.mu.Unlock()
+	if loaded != nil && len(instructions) > 0 {
+		loaded(instructions)
+	}
 
 	// Inject the session's own in-memory history as the final
 	// BeforeRun contribution. Plugin BeforeRun hooks run first;
@@ -746,6 +835,10 @@ func (s *Session) runWith(ctx context.Context, message string, extraSink run.Sin
 		Permissions:        permissions,
 		PermissionPrompter: prompter,
 		Retry:              retry,
+		MaxSteps:           limits.MaxSteps,
+		MaxTotalTokens:     limits.MaxTotalTokens,
+		MaxDuration:        limits.MaxDuration,
+		MaxToolCalls:       limits.MaxToolCalls,
 		Sink:               internal,
 		OutputSchema:       outputSchema,
 		Hooks: run.Hooks{
@@ -776,6 +869,8 @@ func (s *Session) runWith(ctx context.Context, message string, extraSink run.Sin
 			runID:     runID,
 			logger:    logger,
 		}
+		cfg.ToolOutputArtifacts = &artifactRecorder{store: s.store, sessionID: s.id, runID: runID}
+		cfg.MaxToolOutputBytes = maxToolOutputBytes
 	}
 
 	start := time.Now()
@@ -815,7 +910,8 @@ func (s *Session) runWith(ctx context.Context, message string, extraSink run.Sin
 	}
 
 	out := &Result{
-		ToolCalls: toolCalls,
+		ToolCalls:    toolCalls,
+		Instructions: instructions,
 	}
 	if res != nil {
 		out.Usage = res.Usage
@@ -51,6 +51,7 @@ type SessionStream struct {
 //   - "context_transformed": Data is run.ContextTransformedEvent (other transforms)
 //   - "error":               Data is run.ErrorEvent
 //   - "structured_output":   Data is run.StructuredOutputEvent
+//   - "instructions":        Data is InstructionsLoadedEvent, before the first iteration
 //
 // Consumers that want the loop's typed events simply type-assert on Data.
 type StreamEvent struct {
@@ -111,7 +112,12 @@ func (s *Session) RunStream(ctx context.Context, message string) (*SessionStream
 
 	go func() {
 		defer close(ss.events)
-		res, err := s.runWith(ctx, message, sink)
+		res, err := s.runWith(ctx, message, sink, func(files []InstructionFile) {
+			select {
+			case ss.events <- newStreamEvent("instructions", InstructionsLoadedEvent{Files: files}):
+			case <-ctx.Done():
+			}
+		})
 		ss.resultC <- streamResult{res: res, err: err}
 	}()
 
@@ -122,9 +128,14 @@ func (s *Session) RunStream(ctx context.Context, message string) (*SessionStream
 // Adding a new loop event variant requires updating classify; the
 // default branch surfaces the raw event under an "unknown" type so logs
 // catch the omission. Version is stamped centrally so call sites stay
-// uniform — never construct a StreamEvent without using this.
+// uniform — never construct a StreamEvent without using newStreamEvent.
 func toStreamEvent(e run.Event) StreamEvent {
-	t, data := classify(e)
+	return newStreamEvent(classify(e))
+}
+
+// newStreamEvent stamps the envelope for session-level events that do not
+// come from the loop.
+func newStreamEvent(t string, data any) StreamEvent {
 	return StreamEvent{Type: t, Version: EnvelopeVersion, Data: data}
 }
 
@@ -9,8 +9,8 @@ import (
 
 type testPermissionPrompter struct{}
 
-func (testPermissionPrompter) Request(context.Context, run.PermissionRequestInfo) (run.PermissionResponse, error) {
-	return run.PermissionResponseOnce, nil
+func (testPermissionPrompter) Request(context.Context, run.PermissionRequestInfo) (run.PermissionReply, error) {
+	return run.PermissionReply{Response: run.PermissionResponseOnce}, nil
 }
 
 func TestWithPermissionPrompter(t *testing.T) {
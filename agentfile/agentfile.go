@@ -0,0 +1,179 @@
+// Package agentfile loads agent definitions kept as Markdown files in a
+// project's .wingman/agents directory, so teams can version agents in git.
+//
+// Each file has optional YAML frontmatter and a Markdown body:
+//
+//	---
+//	name: Reviewer
+//	model_ref: anthropic/claude-sonnet-4-5
+//	tools: [read, grep, glob]
+//	permissions:
+//	  bash: deny
+//	---
+//	Review the change for correctness and test coverage.
+//
+// The body becomes the agent's instructions. The file name without its .md
+// extension names the agent's ID, which is IDPrefix followed by the stem.
+package agentfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
+	"github.com/chaserensberger/wingman/frontmatter"
+	"github.com/chaserensberger/wingman/permission"
+	"github.com/chaserensberger/wingman/store"
+)
+
+// IDPrefix marks agent IDs that resolve to agent files rather than the store.
+const IDPrefix = "file:"
+
+var stemPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// agentFrontmatter is the accepted frontmatter shape. Unknown keys are
+// rejected so typos surface as load errors instead of silently dropped
+// settings.
+type agentFrontmatter struct {
+	Name         string             `json:"name"`
+	Tools        []string           `json:"tools"`
+	Permissions  permission.Ruleset `json:"permissions"`
+	ModelRef     string             `json:"model_ref"`
+	Options      map[string]any     `json:"options"`
+	OutputSchema map[string]any     `json:"output_schema"`
+	Limits       *store.RunLimits   `json:"limits"`
+}
+
+// Dir returns the project-local agent file directory for workDir.
+func Dir(workDir string) string {
+	if workDir == "" {
+		return ""
+	}
+	return filepath.Join(workDir, ".wingman", "agents")
+}
+
+// IsFileID reports whether id names an agent file.
+func IsFileID(id string) bool {
+	return strings.HasPrefix(id, IDPrefix)
+}
+
+// ID returns the agent ID for the agent file at path.
+func ID(path string) string {
+	return IDPrefix + strings.TrimSuffix(filepath.Base(path), ".md")
+}
+
+// Discover loads every *.md file in workDir's agent directory, sorted by ID.
+// Files that fail to load are reported as load errors and skipped. A missing
+// directory yields no agents.
+func Discover(workDir string) ([]*store.Agent, []frontmatter.LoadError) {
+	dir := Dir(workDir)
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, []frontmatter.LoadError{{Path: dir, Error: err.Error()}}
+	}
+	var agents []*store.Agent
+	var errs []frontmatter.LoadError
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".md" {
+			continue
+		}
+		agent, err := Load(filepath.Join(dir, name))
+		if err != nil {
+			errs = append(errs, frontmatter.LoadError{Path: filepath.Join(dir, name), Error: err.Error()})
+			continue
+		}
+		agents = append(agents, agent)
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+	return agents, errs
+}
+
+// Load reads and validates one agent file.
+func Load(path string) (*store.Agent, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	agent, err := Parse(path, data)
+	if err != nil {
+		return nil, err
+	}
+	modified := info.ModTime().UTC().Format(time.RFC3339Nano)
+	agent.CreatedAt, agent.UpdatedAt = modified, modified
+	return agent, nil
+}
+
+// Parse decodes an agent file's contents. path names the agent and is
+// recorded on the returned agent; it is not read.
+func Parse(path string, data []byte) (*store.Agent, error) {
+	stem := strings.TrimSuffix(filepath.Base(path), ".md")
+	if !stemPattern.MatchString(stem) {
+		return nil, fmt.Errorf("agent file name %q must start with a letter or digit and contain only letters, digits, '.', '_', or '-'", filepath.Base(path))
+	}
+	var meta agentFrontmatter
+	body, err := frontmatter.Parse(data, &meta)
+	if err != nil {
+		return nil, err
+	}
+	instructions := strings.TrimSpace(body)
+	if instructions == "" {
+		return nil, errors.New("agent instructions are required in the file body")
+	}
+	if err := validate(meta); err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(meta.Name)
+	if name == "" {
+		name = stem
+	}
+	return &store.Agent{
+		ID: ID(path), Name: name, Instructions: instructions,
+		Tools: meta.Tools, Permissions: meta.Permissions, ModelRef: meta.ModelRef,
+		Options: meta.Options, OutputSchema: meta.OutputSchema, Limits: meta.Limits.Tighten(nil),
+		Source: store.AgentSourceFile, Path: path,
+	}, nil
+}
+
+func validate(meta agentFrontmatter) error {
+	seen := make(map[string]struct{}, len(meta.Tools))
+	for _, name := range meta.Tools {
+		if strings.TrimSpace(name) == "" {
+			return errors.New("frontmatter: tool names cannot be empty")
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("frontmatter: tool %q is listed more than once", name)
+		}
+		seen[name] = struct{}{}
+	}
+	if limits := meta.Limits; limits != nil && (limits.MaxSteps < 0 || limits.MaxTotalTokens < 0 || limits.MaxDurationMS < 0 || limits.MaxToolCalls < 0) {
+		return errors.New("frontmatter: limits cannot be negative")
+	}
+	if meta.OutputSchema != nil {
+		compiler := jsonschema.NewCompiler()
+		if err := compiler.AddResource("output_schema.json", meta.OutputSchema); err != nil {
+			return fmt.Errorf("frontmatter: output_schema: %w", err)
+		}
+		if _, err := compiler.Compile("output_schema.json"); err != nil {
+			return fmt.Errorf("frontmatter: output_schema: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package agentfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chaserensberger/wingman/permission"
+)
+
+func TestParseReadsFrontmatterAndBody(t *testing.T) {
+	data := "---\r\nname: Reviewer\r\nmodel_ref: anthropic/claude-sonnet-5\r\ntools: [read, grep]\r\npermissions:\r\n  bash:\r\n    \"*\": deny\r\n    \"git diff *\": allow\r\noutput_schema:\r\n  type: object\r\nlimits:\r\n  max_steps: 5\r\n---\r\n\r\nReview the change.\r\n"
+	agent, err := Parse("/repo/.wingman/agents/reviewer.md", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agent.ID != "file:reviewer" || agent.Name != "Reviewer" || agent.Instructions != "Review the change." || agent.ModelRef != "anthropic/claude-sonnet-5" {
+		t.Fatalf("agent = %#v", agent)
+	}
+	if strings.Join(agent.Tools, ",") != "read,grep" || agent.OutputSchema["type"] != "object" || agent.Limits.MaxSteps != 5 {
+		t.Fatalf("agent = %#v", agent)
+	}
+	// Permission rules keep file order because later rules win.
+	want := permission.Ruleset{{Action: "bash", Resource: "*", Effect: permission.EffectDeny}, {Action: "bash", Resource: "git diff *", Effect: permission.EffectAllow}}
+	if len(agent.Permissions) != 2 || agent.Permissions[0] != want[0] || agent.Permissions[1] != want[1] {
+		t.Fatalf("permissions = %#v", agent.Permissions)
+	}
+
+	agent, err = Parse("helper.md", []byte("Just instructions."))
+	if err != nil || agent.Name != "helper" || agent.Instructions != "Just instructions." {
+		t.Fatalf("agent = %#v, error = %v", agent, err)
+	}
+}
+
+func TestParseRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name, path, data, want string
+	}{
+		{"unknown field", "a.md", "---\nmodel: x\n---\nbody", `unknown field "model"`},
+		{"unclosed frontmatter", "a.md", "---\nname: a\nbody", "missing its closing --- line"},
+		{"empty body", "a.md", "---\nname: a\n---\n", "instructions are required"},
+		{"bad yaml", "a.md", "---\ntools: [read\n---\nbody", "parse frontmatter"},
+		{"bad permission", "a.md", "---\npermissions: maybe\n---\nbody", `unknown permission effect "maybe"`},
+		{"duplicate tool", "a.md", "---\ntools: [read, read]\n---\nbody", `tool "read" is listed more than once`},
+		{"negative limit", "a.md", "---\nlimits: {max_steps: -1}\n---\nbody", "cannot be negative"},
+		{"bad schema", "a.md", "---\noutput_schema: {type: 7}\n---\nbody", "output_schema"},
+		{"bad name", "my agent.md", "body", "agent file name"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(tc.path, []byte(tc.data))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestDiscoverReportsLoadErrors(t *testing.T) {
+	workDir := t.TempDir()
+	dir := Dir(workDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"b.md": "Second.", "a.md": "First.", "broken.md": "---\nname: x\n", "notes.txt": "ignored"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	agents, errs := Discover(workDir)
+	if len(agents) != 2 || agents[0].ID != "file:a" || agents[1].ID != "file:b" || agents[0].UpdatedAt == "" {
+		t.Fatalf("agents = %#v", agents)
+	}
+	if len(errs) != 1 || errs[0].Path != filepath.Join(dir, "broken.md") || ID(errs[0].Path) != "file:broken" {
+		t.Fatalf("errors = %#v", errs)
+	}
+	if agents, errs := Discover(t.TempDir()); agents != nil || errs != nil {
+		t.Fatalf("missing directory = %#v, %#v", agents, errs)
+	}
+}
@@ -39,6 +39,7 @@ const (
 	SessionEventPermissionRequested       SessionEventType = "session.permission.requested"
 	SessionEventPermissionResolved        SessionEventType = "session.permission.resolved"
 	SessionEventStructuredOutputCompleted SessionEventType = "session.structured_output.completed"
+	SessionEventInstructionsLoaded        SessionEventType = "session.instructions.loaded"
 	SessionEventEventsSynchronized        SessionEventType = "session.events.synchronized"
 	SessionEventEventsResyncRequired      SessionEventType = "session.events.resync_required"
 )
@@ -116,7 +117,8 @@ type sessionEventData struct{}
 
 func (sessionEventData) isSessionEventData() {}
 
-// RunEventData describes admission and run lifecycle events.
+// RunEventData describes admission and run lifecycle events. StopReason names
+// the exhausted budget when ErrorType is limit_exceeded.
 type RunEventData struct {
 	sessionEventData
 	RunID        string        `json:"run_id"`
@@ -124,6 +126,7 @@ type RunEventData struct {
 	Message      string        `json:"message,omitempty"`
 	ErrorType    string        `json:"error_type,omitempty"`
 	ErrorMessage string        `json:"error_message,omitempty"`
+	StopReason   string        `json:"stop_reason,omitempty"`
 	Usage        *models.Usage `json:"usage,omitempty"`
 	Steps        int           `json:"steps,omitempty"`
 	StartedAt    string        `json:"started_at,omitempty"`
@@ -210,6 +213,22 @@ type StructuredOutputEventData struct {
 	Parsed  map[string]any `json:"parsed"`
 }
 
+// InstructionsEventData lists the project instruction files a run merged
+// into its system prompt.
+type InstructionsEventData struct {
+	sessionEventData
+	RunID string            `json:"run_id"`
+	Files []InstructionFile `json:"files"`
+}
+
+// InstructionFile is one project instruction file loaded for a run.
+type InstructionFile struct {
+	Path      string `json:"path"`
+	Bytes     int    `json:"bytes"`
+	SHA256    string `json:"sha256"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
 // EventsSynchronizedEventData marks the durable/live stream boundary.
 type EventsSynchronizedEventData struct {
 	sessionEventData
@@ -274,6 +293,8 @@ func DecodeSessionEventDataForSchemaVersion(schemaVersion int, eventType Session
 		data = &PermissionEventData{}
 	case SessionEventStructuredOutputCompleted:
 		data = &StructuredOutputEventData{}
+	case SessionEventInstructionsLoaded:
+		data = &InstructionsEventData{}
 	case SessionEventEventsSynchronized:
 		data = &EventsSynchronizedEventData{}
 	case SessionEventEventsResyncRequired:
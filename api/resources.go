@@ -18,8 +18,107 @@ type Agent struct {
 	ModelRef     string             `json:"model_ref,omitempty"`
 	Options      map[string]any     `json:"options,omitempty"`
 	OutputSchema map[string]any     `json:"output_schema,omitempty"`
-	CreatedAt    string             `json:"created_at"`
-	UpdatedAt    string             `json:"updated_at"`
+	Limits       *RunLimits         `json:"limits,omitempty"`
+	// Revision is the stored agent's current immutable revision. File
+	// agents have no revisions.
+	Revision int64 `json:"revision,omitempty"`
+	// Source is "store" for agents managed through /agents and "file" for
+	// agents loaded from a project's .wingman/agents directory. File agents
+	// are read-only through the API.
+	Source string `json:"source"`
+	// Path is the agent file a file agent was loaded from.
+	Path      string `json:"path,omitempty"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// AgentRevision is one immutable snapshot of a stored agent.
+type AgentRevision struct {
+	Revision  int64  `json:"revision"`
+	Agent     Agent  `json:"agent"`
+	CreatedAt string `json:"created_at"`
+}
+
+// AgentFileList reports the agent files discovered for a working directory
+// and the files that failed to load.
+type AgentFileList struct {
+	Agents []Agent         `json:"agents"`
+	Errors []FileLoadError `json:"errors"`
+}
+
+// FileLoadError reports an agent or command file that could not be loaded.
+type FileLoadError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// Command is a saved prompt template with named arguments.
+type Command struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Template is the prompt text. {{name}} placeholders are replaced by
+	// the command's arguments when it runs.
+	Template  string            `json:"template"`
+	Arguments []CommandArgument `json:"arguments,omitempty"`
+	// AgentID and ModelRef are defaults used when a command request does
+	// not name its own agent or model.
+	AgentID  string `json:"agent_id,omitempty"`
+	ModelRef string `json:"model_ref,omitempty"`
+	// Source is "store" for commands managed through /commands and "file"
+	// for commands loaded from a project's .wingman/commands directory.
+	// File commands are read-only through the API.
+	Source string `json:"source"`
+	// Path is the command file a file command was loaded from.
+	Path      string `json:"path,omitempty"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// CommandArgument declares one named argument of a command template.
+type CommandArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	// Default replaces an omitted optional argument.
+	Default string `json:"default,omitempty"`
+}
+
+// CreateCommandRequest creates a saved command.
+type CreateCommandRequest struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Template    string            `json:"template"`
+	Arguments   []CommandArgument `json:"arguments,omitempty"`
+	AgentID     string            `json:"agent_id,omitempty"`
+	ModelRef    string            `json:"model_ref,omitempty"`
+}
+
+// UpdateCommandRequest updates fields present in a saved command.
+type UpdateCommandRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Template    *string `json:"template,omitempty"`
+	// Arguments replaces the declared arguments. An empty list clears them.
+	Arguments []CommandArgument `json:"arguments,omitempty"`
+	AgentID   *string           `json:"agent_id,omitempty"`
+	ModelRef  *string           `json:"model_ref,omitempty"`
+}
+
+// CommandFileList reports the command files discovered for a working
+// directory and the files that failed to load.
+type CommandFileList struct {
+	Commands []Command       `json:"commands"`
+	Errors   []FileLoadError `json:"errors"`
+}
+
+// RunLimits bounds one run. Zero or omitted fields are unlimited. A run that
+// reaches a limit fails with error type limit_exceeded.
+type RunLimits struct {
+	MaxSteps       int   `json:"max_steps,omitempty"`
+	MaxTotalTokens int   `json:"max_total_tokens,omitempty"`
+	MaxDurationMS  int64 `json:"max_duration_ms,omitempty"`
+	MaxToolCalls   int   `json:"max_tool_calls,omitempty"`
 }
 
 // CreateAgentRequest creates an agent definition.
@@ -32,6 +131,7 @@ type CreateAgentRequest struct {
 	ModelRoute   *models.ModelInfo  `json:"model_route,omitempty"`
 	Options      map[string]any     `json:"options,omitempty"`
 	OutputSchema map[string]any     `json:"output_schema,omitempty"`
+	Limits       *RunLimits         `json:"limits,omitempty"`
 }
 
 // UpdateAgentRequest updates fields present in an agent definition.
@@ -44,6 +144,9 @@ type UpdateAgentRequest struct {
 	ModelRoute   *models.ModelInfo  `json:"model_route,omitempty"`
 	Options      map[string]any     `json:"options,omitempty"`
 	OutputSchema map[string]any     `json:"output_schema,omitempty"`
+	// Limits replaces the agent's default run limits. An empty object
+	// clears them.
+	Limits *RunLimits `json:"limits,omitempty"`
 }
 
 // Client identifies an application consuming the Wingman API.
@@ -66,24 +169,30 @@ type CreateClientResponse struct {
 
 // Workspace is one client-owned saved context.
 type Workspace struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Path      string `json:"path"`
-	ClientID  string `json:"client_id,omitempty"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	ClientID string `json:"client_id,omitempty"`
+	// Credentials maps provider IDs to the named credential sessions in
+	// the workspace use.
+	Credentials map[string]string `json:"credentials,omitempty"`
+	CreatedAt   string            `json:"created_at"`
+	UpdatedAt   string            `json:"updated_at"`
 }
 
 // CreateWorkspaceRequest creates a saved context.
 type CreateWorkspaceRequest struct {
-	Name string `json:"name"`
-	Path string `json:"path"`
+	Name        string            `json:"name"`
+	Path        string            `json:"path"`
+	Credentials map[string]string `json:"credentials,omitempty"`
 }
 
-// UpdateWorkspaceRequest updates fields present in a saved context.
+// UpdateWorkspaceRequest updates fields present in a saved context. A
+// present credentials object replaces the workspace's selections.
 type UpdateWorkspaceRequest struct {
-	Name *string `json:"name,omitempty"`
-	Path *string `json:"path,omitempty"`
+	Name        *string           `json:"name,omitempty"`
+	Path        *string           `json:"path,omitempty"`
+	Credentials map[string]string `json:"credentials,omitempty"`
 }
 
 // Session is the summary returned by list, create, and metadata commands.
@@ -127,36 +236,42 @@ type MoveSessionRequest struct {
 
 // ModelCall describes one physical upstream model request.
 type ModelCall struct {
-	ID                 string          `json:"id"`
-	SessionID          string          `json:"session_id"`
-	RunID              string          `json:"run_id,omitempty"`
-	AssistantMessageID string          `json:"assistant_message_id,omitempty"`
-	Step               int             `json:"step"`
-	Attempt            int             `json:"attempt"`
-	Status             string          `json:"status"`
-	AgentID            string          `json:"agent_id,omitempty"`
-	ModelRef           string          `json:"model_ref,omitempty"`
-	Provider           string          `json:"provider,omitempty"`
-	ProviderRequestID  string          `json:"provider_request_id,omitempty"`
-	API                string          `json:"api,omitempty"`
-	ModelID            string          `json:"model_id,omitempty"`
-	FinishReason       string          `json:"finish_reason,omitempty"`
-	StopReason         string          `json:"stop_reason,omitempty"`
-	ErrorType          string          `json:"error_type,omitempty"`
-	ErrorMessage       string          `json:"error_message,omitempty"`
-	InputTokens        int             `json:"input_tokens"`
-	OutputTokens       int             `json:"output_tokens"`
-	ReasoningTokens    int             `json:"reasoning_tokens,omitempty"`
-	CachedInputTokens  int             `json:"cached_input_tokens,omitempty"`
-	CacheWriteTokens   int             `json:"cache_write_tokens,omitempty"`
-	TotalTokens        int             `json:"total_tokens"`
-	ContextTokens      int             `json:"context_tokens"`
-	ContextWindow      int             `json:"context_window,omitempty"`
-	ContextPercent     float64         `json:"context_percent,omitempty"`
-	Cost               *float64        `json:"cost,omitempty"`
-	Trace              json.RawMessage `json:"trace,omitempty"`
-	StartedAt          time.Time       `json:"started_at"`
-	CompletedAt        time.Time       `json:"completed_at,omitempty"`
+	ID                 string   `json:"id"`
+	SessionID          string   `json:"session_id"`
+	RunID              string   `json:"run_id,omitempty"`
+	AssistantMessageID string   `json:"assistant_message_id,omitempty"`
+	Step               int      `json:"step"`
+	Attempt            int      `json:"attempt"`
+	Status             string   `json:"status"`
+	AgentID            string   `json:"agent_id,omitempty"`
+	ModelRef           string   `json:"model_ref,omitempty"`
+	Provider           string   `json:"provider,omitempty"`
+	ProviderRequestID  string   `json:"provider_request_id,omitempty"`
+	API                string   `json:"api,omitempty"`
+	ModelID            string   `json:"model_id,omitempty"`
+	FinishReason       string   `json:"finish_reason,omitempty"`
+	StopReason         string   `json:"stop_reason,omitempty"`
+	ErrorType          string   `json:"error_type,omitempty"`
+	ErrorMessage       string   `json:"error_message,omitempty"`
+	InputTokens        int      `json:"input_tokens"`
+	OutputTokens       int      `json:"output_tokens"`
+	ReasoningTokens    int      `json:"reasoning_tokens,omitempty"`
+	CachedInputTokens  int      `json:"cached_input_tokens,omitempty"`
+	CacheWriteTokens   int      `json:"cache_write_tokens,omitempty"`
+	TotalTokens        int      `json:"total_tokens"`
+	ContextTokens      int      `json:"context_tokens"`
+	ContextWindow      int      `json:"context_window,omitempty"`
+	ContextPercent     float64  `json:"context_percent,omitempty"`
+	Cost               *float64 `json:"cost,omitempty"`
+	// ThrottledMS is how long the provider rate limiter held the call back
+	// before dispatch.
+	ThrottledMS int64 `json:"throttled_ms,omitempty"`
+	// Credential names the provider credential that served the call when
+	// the provider has several.
+	Credential  string          `json:"credential,omitempty"`
+	Trace       json.RawMessage `json:"trace,omitempty"`
+	StartedAt   time.Time       `json:"started_at"`
+	CompletedAt time.Time       `json:"completed_at,omitempty"`
 }
 
 // StatusResponse reports a completed command without a resource body.
@@ -192,4 +307,70 @@ type DiagnosticsResponse struct {
 	PluginsDegraded      int   `json:"plugins_degraded"`
 	PluginsFailed        int   `json:"plugins_failed"`
 	PluginLoadErrors     int   `json:"plugin_load_errors"`
+	// Scheduler reports the run slots shared by all sessions.
+	Scheduler RunSchedulerDiagnostics `json:"scheduler"`
+	// RateLimits reports the shared provider rate limiters that have a
+	// budget or have seen rate limiting.
+	RateLimits []ProviderRateLimitDiagnostics `json:"rate_limits"`
+	// Embeddings sums the embedding calls made with each model since the
+	// daemon started.
+	Embeddings []EmbeddingUsageDiagnostics `json:"embeddings"`
+}
+
+// EmbeddingUsageDiagnostics reports one embedding model's usage. Cost is
+// estimated from catalog pricing.
+type EmbeddingUsageDiagnostics struct {
+	Provider    string    `json:"provider"`
+	Model       string    `json:"model"`
+	Requests    int64     `json:"requests"`
+	Inputs      int64     `json:"inputs"`
+	Failures    int64     `json:"failures"`
+	InputTokens int64     `json:"input_tokens"`
+	TotalTokens int64     `json:"total_tokens"`
+	Cost        float64   `json:"cost,omitempty"`
+	LastUsedAt  time.Time `json:"last_used_at,omitzero"`
+}
+
+// ProviderRateLimitDiagnostics reports one provider's shared rate limiter,
+// or the limiter of one of its named credentials. Zero budgets are unset;
+// remaining counts are -1 when the provider has not reported them.
+type ProviderRateLimitDiagnostics struct {
+	Provider          string    `json:"provider"`
+	Credential        string    `json:"credential,omitempty"`
+	RequestsPerMinute int       `json:"requests_per_minute"`
+	TokensPerMinute   int       `json:"tokens_per_minute"`
+	RemainingRequests int       `json:"remaining_requests"`
+	RemainingTokens   int       `json:"remaining_tokens"`
+	BlockedUntil      time.Time `json:"blocked_until,omitempty"`
+	WaitingRequests   int       `json:"waiting_requests"`
+	// ThrottledRequests counts requests that waited, for ThrottledMS in
+	// total.
+	ThrottledRequests    int64 `json:"throttled_requests"`
+	ThrottledMS          int64 `json:"throttled_ms"`
+	RateLimitedResponses int64 `json:"rate_limited_responses"`
+}
+
+// RunSchedulerDiagnostics reports run slot limits, the runs holding slots,
+// and the runs waiting for one. Zero limits are unset.
+type RunSchedulerDiagnostics struct {
+	MaxConcurrentRuns  int            `json:"max_concurrent_runs"`
+	MaxRunsPerClient   int            `json:"max_runs_per_client"`
+	MaxRunsPerProvider map[string]int `json:"max_runs_per_provider,omitempty"`
+	RunningRuns        int            `json:"running_runs"`
+	RunningByClient    map[string]int `json:"running_by_client,omitempty"`
+	RunningByProvider  map[string]int `json:"running_by_provider,omitempty"`
+	WaitingRuns        int            `json:"waiting_runs"`
+	// Queue lists the first waiting runs in the order they would start.
+	Queue []RunQueueEntry `json:"queue"`
+}
+
+// RunQueueEntry is one run waiting for a run slot.
+type RunQueueEntry struct {
+	Position  int       `json:"position"`
+	SessionID string    `json:"session_id"`
+	RunID     string    `json:"run_id"`
+	ClientID  string    `json:"client_id,omitempty"`
+	Provider  string    `json:"provider,omitempty"`
+	Priority  int       `json:"priority"`
+	QueuedAt  time.Time `json:"queued_at"`
 }
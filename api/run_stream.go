@@ -24,6 +24,7 @@ const (
 	RunStreamEventContextTransformed RunStreamEventType = "context_transformed"
 	RunStreamEventError              RunStreamEventType = "error"
 	RunStreamEventStructuredOutput   RunStreamEventType = "structured_output"
+	RunStreamEventInstructions       RunStreamEventType = "instructions"
 	RunStreamEventDone               RunStreamEventType = "done"
 )
 
@@ -160,6 +161,12 @@ type RunStructuredOutputEventData struct {
 	Parsed  map[string]any `json:"parsed"`
 }
 
+// RunInstructionsEventData lists the project instruction files merged into
+// the run's system prompt.
+type RunInstructionsEventData struct {
+	Files []InstructionFile `json:"files"`
+}
+
 // RunDoneEventData summarizes a successful one-shot run.
 type RunDoneEventData struct {
 	Usage models.Usage `json:"usage"`
@@ -183,6 +190,7 @@ func (RunStreamPartEventData) isRunStreamEventData()         {}
 func (RunContextTransformedEventData) isRunStreamEventData() {}
 func (RunErrorEventData) isRunStreamEventData()              {}
 func (RunStructuredOutputEventData) isRunStreamEventData()   {}
+func (RunInstructionsEventData) isRunStreamEventData()       {}
 func (RunDoneEventData) isRunStreamEventData()               {}
 func (UnknownRunStreamEventData) isRunStreamEventData()      {}
 
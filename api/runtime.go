@@ -16,12 +16,52 @@ type OutputSchema struct {
 
 // MessageSessionRequest admits one message to a persistent session.
 type MessageSessionRequest struct {
-	RequestID    string            `json:"request_id,omitempty"`
-	AgentID      string            `json:"agent_id"`
-	ModelRef     string            `json:"model_ref,omitempty"`
-	ModelRoute   *models.ModelInfo `json:"model_route,omitempty"`
-	Message      string            `json:"message"`
-	OutputSchema *OutputSchema     `json:"output_schema,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	AgentID   string `json:"agent_id"`
+	// AgentRevision pins the run to an earlier revision of a stored agent
+	// instead of its current one.
+	AgentRevision int64             `json:"agent_revision,omitempty"`
+	ModelRef      string            `json:"model_ref,omitempty"`
+	ModelRoute    *models.ModelInfo `json:"model_route,omitempty"`
+	Message       string            `json:"message"`
+	OutputSchema  *OutputSchema     `json:"output_schema,omitempty"`
+	// Resources are MCP resources read at admission and attached to the
+	// message as context.
+	Resources []MCPResourceRef `json:"resources,omitempty"`
+	// Limits bound this run. They combine with the agent's limits; the
+	// stricter value of each field applies.
+	Limits *RunLimits `json:"limits,omitempty"`
+	// Priority orders the run against other sessions' runs when the daemon
+	// is at its run limits. Higher runs first; the default is 0 and values
+	// range from -100 to 100. Runs in one session still run in order.
+	Priority int `json:"priority,omitempty"`
+}
+
+// CommandSessionRequest renders a saved command and admits its output as a
+// session message.
+type CommandSessionRequest struct {
+	RequestID string `json:"request_id,omitempty"`
+	CommandID string `json:"command_id"`
+	// Arguments fill the command's {{name}} placeholders.
+	Arguments map[string]string `json:"arguments,omitempty"`
+	// AgentID and ModelRef override the command's bound agent and model.
+	AgentID       string            `json:"agent_id,omitempty"`
+	AgentRevision int64             `json:"agent_revision,omitempty"`
+	ModelRef      string            `json:"model_ref,omitempty"`
+	ModelRoute    *models.ModelInfo `json:"model_route,omitempty"`
+	// OutputSchema and Resources apply to the rendered message as they do
+	// on MessageSessionRequest.
+	OutputSchema *OutputSchema    `json:"output_schema,omitempty"`
+	Resources    []MCPResourceRef `json:"resources,omitempty"`
+	Limits       *RunLimits       `json:"limits,omitempty"`
+	// Priority orders the run as MessageSessionRequest.Priority does.
+	Priority int `json:"priority,omitempty"`
+}
+
+// MCPResourceRef names one resource on a configured MCP server.
+type MCPResourceRef struct {
+	Server string `json:"server"`
+	URI    string `json:"uri"`
 }
 
 // MessageSessionResponse identifies an admitted persistent run.
@@ -63,23 +103,218 @@ type AbortSessionResponse struct {
 
 // SessionRun is one durably admitted session input and its execution state.
 type SessionRun struct {
-	ID              string    `json:"id"`
-	SessionID       string    `json:"session_id"`
-	RequestID       string    `json:"request_id,omitempty"`
-	AdmittedVersion int64     `json:"admitted_version"`
-	WorkDir         string    `json:"work_dir,omitempty"`
-	WorkspaceID     string    `json:"workspace_id,omitempty"`
-	ClientID        string    `json:"client_id,omitempty"`
-	Sequence        int       `json:"sequence"`
-	Status          string    `json:"status"`
-	Message         string    `json:"message"`
-	Agent           Agent     `json:"agent"`
-	ErrorType       string    `json:"error_type,omitempty"`
-	ErrorMessage    string    `json:"error_message,omitempty"`
-	CreatedAt       time.Time `json:"created_at"`
-	StartedAt       time.Time `json:"started_at,omitempty"`
-	CompletedAt     time.Time `json:"completed_at,omitempty"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID              string `json:"id"`
+	SessionID       string `json:"session_id"`
+	RequestID       string `json:"request_id,omitempty"`
+	AdmittedVersion int64  `json:"admitted_version"`
+	WorkDir         string `json:"work_dir,omitempty"`
+	WorkspaceID     string `json:"workspace_id,omitempty"`
+	ClientID        string `json:"client_id,omitempty"`
+	Sequence        int    `json:"sequence"`
+	Priority        int    `json:"priority,omitempty"`
+	// QueuePosition is the run's 1-based place among runs waiting for a
+	// run slot. It is zero once the run starts and while it waits behind
+	// an earlier run of its own session.
+	QueuePosition int       `json:"queue_position,omitempty"`
+	Status        string    `json:"status"`
+	Message       string    `json:"message"`
+	Agent         Agent     `json:"agent"`
+	ErrorType     string    `json:"error_type,omitempty"`
+	ErrorMessage  string    `json:"error_message,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	StartedAt     time.Time `json:"started_at,omitempty"`
+	CompletedAt   time.Time `json:"completed_at,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Artifact is one blob stored with a session. Tool output larger than the
+// daemon's inline limit is stored as an artifact, and the transcript keeps a
+// preview whose metadata names it.
+type Artifact struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"session_id"`
+	RunID     string    `json:"run_id,omitempty"`
+	ToolUseID string    `json:"tool_use_id,omitempty"`
+	CallID    string    `json:"call_id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	MediaType string    `json:"media_type"`
+	Size      int64     `json:"size"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Process is one background process an agent started with process_start.
+// Processes live in the session's execution scope and are not persisted.
+type Process struct {
+	ID          string    `json:"id"`
+	SessionID   string    `json:"session_id"`
+	RunID       string    `json:"run_id,omitempty"`
+	Command     string    `json:"command"`
+	WorkDir     string    `json:"work_dir"`
+	PID         int       `json:"pid"`
+	Status      string    `json:"status"`
+	ExitCode    *int      `json:"exit_code,omitempty"`
+	OutputBytes int64     `json:"output_bytes"`
+	StartedAt   time.Time `json:"started_at"`
+	ExitedAt    time.Time `json:"exited_at,omitempty"`
+}
+
+// CreateBatchRequest submits prompts to the provider batch endpoint of an
+// agent's model. The agent must define an output schema; each prompt runs
+// as one tool-free turn whose result is validated against it.
+type CreateBatchRequest struct {
+	AgentID  string   `json:"agent_id"`
+	ModelRef string   `json:"model_ref,omitempty"`
+	Prompts  []string `json:"prompts"`
+}
+
+// Batch is a set of prompts processed through a provider batch endpoint.
+// Each item's result is stored as a completed or failed run of its own
+// session once the provider finishes.
+type Batch struct {
+	ID              string      `json:"id"`
+	AgentID         string      `json:"agent_id"`
+	ModelRef        string      `json:"model_ref"`
+	ProviderBatchID string      `json:"provider_batch_id"`
+	Status          string      `json:"status"`
+	ErrorMessage    string      `json:"error_message,omitempty"`
+	Counts          BatchCounts `json:"counts"`
+	// Items is omitted when batches are listed.
+	Items       []BatchItem `json:"items,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	CompletedAt time.Time   `json:"completed_at,omitempty"`
+}
+
+// BatchCounts tallies a batch's items by status.
+type BatchCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// BatchItem is one prompt of a batch and, once imported, the session and
+// run holding its result.
+type BatchItem struct {
+	Index        int    `json:"index"`
+	Prompt       string `json:"prompt"`
+	SessionID    string `json:"session_id,omitempty"`
+	RunID        string `json:"run_id,omitempty"`
+	Status       string `json:"status"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// CreateEvalRequest runs an agent over dataset cases. AgentRevision and
+// ModelRef select what is being evaluated; JudgeModelRef is the default
+// model for judge checks and falls back to the evaluated model.
+type CreateEvalRequest struct {
+	Name          string `json:"name,omitempty"`
+	AgentID       string `json:"agent_id"`
+	AgentRevision int64  `json:"agent_revision,omitempty"`
+	ModelRef      string `json:"model_ref,omitempty"`
+	JudgeModelRef string `json:"judge_model_ref,omitempty"`
+	// WorkingDirectory resolves file agents and relative fixtures. Fixtures
+	// must lie inside it. Cases run in their own fixture copies.
+	WorkingDirectory string `json:"working_directory,omitempty"`
+	// Concurrency bounds how many cases run at once. Zero uses the
+	// default of 4.
+	Concurrency int        `json:"concurrency,omitempty"`
+	Cases       []EvalCase `json:"cases"`
+}
+
+// EvalCase is one dataset case: a prompt, an optional fixture directory
+// copied into a fresh working directory, the expected output, and the
+// checks that grade the answer.
+type EvalCase struct {
+	ID       string          `json:"id"`
+	Input    string          `json:"input"`
+	Fixture  string          `json:"fixture,omitempty"`
+	Expected json.RawMessage `json:"expected,omitempty"`
+	Checks   []EvalCheck     `json:"checks,omitempty"`
+}
+
+// EvalCheck grades an answer by exact match, JSON schema, a shell command
+// run in the case's working directory, or an LLM judge.
+type EvalCheck struct {
+	Type      string         `json:"type"`
+	Schema    map[string]any `json:"schema,omitempty"`
+	Command   string         `json:"command,omitempty"`
+	TimeoutMS int            `json:"timeout_ms,omitempty"`
+	Criteria  string         `json:"criteria,omitempty"`
+	ModelRef  string         `json:"model_ref,omitempty"`
+}
+
+// Eval is one run of an agent over a dataset.
+type Eval struct {
+	ID            string      `json:"id"`
+	Name          string      `json:"name,omitempty"`
+	AgentID       string      `json:"agent_id"`
+	AgentRevision int64       `json:"agent_revision,omitempty"`
+	ModelRef      string      `json:"model_ref"`
+	JudgeModelRef string      `json:"judge_model_ref,omitempty"`
+	Status        string      `json:"status"`
+	ErrorMessage  string      `json:"error_message,omitempty"`
+	Summary       EvalSummary `json:"summary"`
+	// Cases is omitted when evals are listed.
+	Cases       []EvalCaseResult `json:"cases,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	CompletedAt time.Time        `json:"completed_at,omitempty"`
+}
+
+// EvalSummary aggregates an eval's cases. Score is the mean case score.
+type EvalSummary struct {
+	Total       int     `json:"total"`
+	Passed      int     `json:"passed"`
+	Failed      int     `json:"failed"`
+	Errored     int     `json:"errored"`
+	Score       float64 `json:"score"`
+	TotalTokens int     `json:"total_tokens"`
+	Cost        float64 `json:"cost"`
+}
+
+// EvalCaseResult is one case of an eval and its outcome. SessionID holds
+// the case's transcript.
+type EvalCaseResult struct {
+	Index        int               `json:"index"`
+	Case         EvalCase          `json:"case"`
+	SessionID    string            `json:"session_id,omitempty"`
+	RunID        string            `json:"run_id,omitempty"`
+	Status       string            `json:"status"`
+	Score        float64           `json:"score"`
+	Checks       []EvalCheckResult `json:"checks,omitempty"`
+	ErrorMessage string            `json:"error_message,omitempty"`
+	TotalTokens  int               `json:"total_tokens"`
+	Cost         float64           `json:"cost"`
+}
+
+// EvalCheckResult is the outcome of one check. Score is in [0, 1].
+type EvalCheckResult struct {
+	Type   string  `json:"type"`
+	Passed bool    `json:"passed"`
+	Score  float64 `json:"score"`
+	Detail string  `json:"detail,omitempty"`
+}
+
+// Terminal message types exchanged as WebSocket text frames on a session
+// terminal. Binary frames carry raw terminal input and output.
+const (
+	TerminalMessageInput  = "input"
+	TerminalMessageResize = "resize"
+	TerminalMessageShare  = "share"
+	TerminalMessageExit   = "exit"
+)
+
+// TerminalMessage is a control message on a session terminal. Clients send
+// input, resize, and share messages; the server sends exit when the shell
+// ends.
+type TerminalMessage struct {
+	Type     string `json:"type"`
+	Data     string `json:"data,omitempty"`
+	Cols     int    `json:"cols,omitempty"`
+	Rows     int    `json:"rows,omitempty"`
+	Shared   *bool  `json:"shared,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"`
 }
 
 // ToolUse is one durable tool invocation lifecycle.
@@ -111,20 +346,24 @@ type ToolUse struct {
 
 // PermissionRequest is one interactive authorization decision.
 type PermissionRequest struct {
-	ID           string    `json:"id"`
-	SessionID    string    `json:"session_id"`
-	RunID        string    `json:"run_id,omitempty"`
-	ToolUseID    string    `json:"tool_use_id,omitempty"`
-	CallID       string    `json:"call_id,omitempty"`
-	Action       string    `json:"action"`
-	Resources    []string  `json:"resources"`
-	Status       string    `json:"status"`
-	Response     string    `json:"response,omitempty"`
-	ErrorType    string    `json:"error_type,omitempty"`
-	ErrorMessage string    `json:"error_message,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	ResolvedAt   time.Time `json:"resolved_at,omitempty"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           string   `json:"id"`
+	SessionID    string   `json:"session_id"`
+	RunID        string   `json:"run_id,omitempty"`
+	ToolUseID    string   `json:"tool_use_id,omitempty"`
+	CallID       string   `json:"call_id,omitempty"`
+	Action       string   `json:"action"`
+	Resources    []string `json:"resources"`
+	Status       string   `json:"status"`
+	Response     string   `json:"response,omitempty"`
+	ErrorType    string   `json:"error_type,omitempty"`
+	ErrorMessage string   `json:"error_message,omitempty"`
+	// EditedInput is the reviewer-edited tool input that was approved.
+	EditedInput json.RawMessage `json:"edited_input,omitempty"`
+	// Message is the reviewer's rejection explanation shown to the model.
+	Message    string    `json:"message,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	ResolvedAt time.Time `json:"resolved_at,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
 
 // PermissionGrant is a session-scoped remembered authorization.
@@ -136,7 +375,66 @@ type PermissionGrant struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// PermissionRuleset is a persisted workspace or agent permission ruleset.
+type PermissionRuleset struct {
+	Scope     string             `json:"scope"`
+	ScopeID   string             `json:"scope_id"`
+	Rules     permission.Ruleset `json:"rules"`
+	Version   int64              `json:"version"`
+	UpdatedAt time.Time          `json:"updated_at,omitempty"`
+}
+
+// PermissionRulesetUpdateRequest replaces a persisted permission ruleset.
+type PermissionRulesetUpdateRequest struct {
+	Rules           permission.Ruleset `json:"rules"`
+	ExpectedVersion *int64             `json:"expected_version,omitempty"`
+}
+
+// PermissionRuleChange is one audited change to a persisted ruleset.
+type PermissionRuleChange struct {
+	ID        string             `json:"id"`
+	Scope     string             `json:"scope"`
+	ScopeID   string             `json:"scope_id"`
+	Version   int64              `json:"version"`
+	Operation string             `json:"operation"`
+	ClientID  string             `json:"client_id,omitempty"`
+	SessionID string             `json:"session_id,omitempty"`
+	GrantID   string             `json:"grant_id,omitempty"`
+	Before    permission.Ruleset `json:"before"`
+	After     permission.Ruleset `json:"after"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// PermissionGrantPromoteRequest copies a session grant into a persisted ruleset.
+type PermissionGrantPromoteRequest struct {
+	Scope           string `json:"scope"`
+	AgentID         string `json:"agent_id,omitempty"`
+	ExpectedVersion *int64 `json:"expected_version,omitempty"`
+}
+
+// PermissionEvaluateRequest asks how an action/resource pair would be decided.
+type PermissionEvaluateRequest struct {
+	Action      string `json:"action"`
+	Resource    string `json:"resource"`
+	AgentID     string `json:"agent_id,omitempty"`
+	WorkspaceID string `json:"workspace_id,omitempty"`
+	SessionID   string `json:"session_id,omitempty"`
+}
+
+// PermissionEvaluation is a dry-run permission decision and its matching rule.
+type PermissionEvaluation struct {
+	Effect permission.Effect `json:"effect"`
+	Rule   *permission.Rule  `json:"rule,omitempty"`
+	Source string            `json:"source,omitempty"`
+}
+
 // PermissionReplyRequest resolves a pending permission request.
 type PermissionReplyRequest struct {
 	Response string `json:"response"`
+	// Input replaces the proposed tool input. It requires response "once"
+	// and must satisfy the tool's input schema.
+	Input map[string]any `json:"input,omitempty"`
+	// Message explains a rejection. It requires response "reject" and is
+	// returned to the model as the tool result.
+	Message string `json:"message,omitempty"`
 }
@@ -9,16 +9,19 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/chaserensberger/wingman/execution"
 	"github.com/chaserensberger/wingman/internal/observability"
 	wingmcp "github.com/chaserensberger/wingman/mcp"
+	"github.com/chaserensberger/wingman/models"
 	provider "github.com/chaserensberger/wingman/models/providers"
 	_ "github.com/chaserensberger/wingman/models/providers/anthropic"
 	_ "github.com/chaserensberger/wingman/models/providers/deepseek"
 	_ "github.com/chaserensberger/wingman/models/providers/google"
+	_ "github.com/chaserensberger/wingman/models/providers/mock"
 	_ "github.com/chaserensberger/wingman/models/providers/openai"
 	_ "github.com/chaserensberger/wingman/models/providers/openaicompat"
 	_ "github.com/chaserensberger/wingman/models/providers/opencode"
@@ -26,6 +29,7 @@ import (
 	_ "github.com/chaserensberger/wingman/models/providers/openrouter"
 	"github.com/chaserensberger/wingman/permission"
 	"github.com/chaserensberger/wingman/pluginhost"
+	"github.com/chaserensberger/wingman/search"
 	"github.com/chaserensberger/wingman/server"
 	"github.com/chaserensberger/wingman/store"
 )
@@ -54,6 +58,22 @@ type Config struct {
 	Username          string
 	InstanceID        string
 	Version           string
+
+	// MaxToolOutputBytes bounds inline tool output; see server.Config.
+	MaxToolOutputBytes int
+	// SearchModel is the embedding model ref behind codebase_search. Its
+	// index is stored in search.db next to the daemon database, or in
+	// memory for ephemeral daemons. Empty disables the tool.
+	SearchModel string
+	// MaxConcurrentRuns, MaxRunsPerClient, and MaxRunsPerProvider bound
+	// the session runs executing at once; see server.SchedulerConfig.
+	MaxConcurrentRuns  int
+	MaxRunsPerClient   int
+	MaxRunsPerProvider map[string]int
+	// CredentialKeys, when set, encrypts stored provider and MCP
+	// credentials. Credentials stored in plain form or under an older key
+	// are resealed with the current key at startup.
+	CredentialKeys store.CredentialKeys
 }
 
 type lifecycleServer interface {
@@ -88,6 +108,7 @@ type App struct {
 	logger *slog.Logger
 	logs   *observability.LogBuffer
 	store  storeResource
+	search *search.Store
 	scopes scopeResource
 
 	closeMu   sync.Mutex
@@ -136,21 +157,53 @@ func newWithFactories(ctx context.Context, cfg Config, f factories) (*App, error
 		return fail(fmt.Errorf("validate MCP config: %w", err))
 	}
 
+	dbPath := ""
 	if !cfg.Ephemeral {
-		path := cfg.DBPath
-		if path == "" {
+		dbPath = cfg.DBPath
+		if dbPath == "" {
 			var err error
-			path, err = store.DefaultDBPath()
+			dbPath, err = store.DefaultDBPath()
 			if err != nil {
 				return fail(fmt.Errorf("resolve database path: %w", err))
 			}
 		}
-		resource, err := f.openStore(path)
+		resource, err := f.openStore(dbPath)
 		if err != nil {
 			return fail(fmt.Errorf("initialize storage: %w", err))
 		}
 		a.store = resource
 		rollback = append(rollback, resource.close)
+		if cfg.CredentialKeys != nil {
+			sealer, ok := resource.store.(interface {
+				UseCredentialKeys(store.CredentialKeys) (bool, error)
+			})
+			if !ok {
+				return fail(errors.New("initialize storage: credential encryption is not supported by this store"))
+			}
+			resealed, err := sealer.UseCredentialKeys(cfg.CredentialKeys)
+			if err != nil {
+				return fail(fmt.Errorf("initialize credential encryption: %w", err))
+			}
+			if resealed {
+				a.logger.Info("stored credentials resealed with the current credential key")
+			}
+		}
+	}
+	var searchModel models.ModelRef
+	if cfg.SearchModel != "" {
+		var ok bool
+		if searchModel, ok = models.ParseModelRef(cfg.SearchModel); !ok {
+			return fail(fmt.Errorf("invalid search model %q", cfg.SearchModel))
+		}
+		searchPath := ""
+		if dbPath != "" {
+			searchPath = filepath.Join(filepath.Dir(dbPath), "search.db")
+		}
+		a.search, err = search.OpenStore(searchPath)
+		if err != nil {
+			return fail(fmt.Errorf("initialize search index: %w", err))
+		}
+		rollback = append(rollback, a.search.Close)
 	}
 
 	dirs := append([]string(nil), cfg.PluginDirs...)
@@ -166,7 +219,8 @@ func newWithFactories(ctx context.Context, cfg Config, f factories) (*App, error
 	}
 	a.scopes, err = f.newScopes(execution.Config{
 		RootContext: root, PluginDirs: dirs, DisablePlugins: cfg.DisablePlugins,
-		MCP: cfg.MCP, Providers: providers, NativeTools: execution.BuiltinTools(),
+		MCP: cfg.MCP, MCPTokens: server.MCPTokenStore(a.store.store), Providers: providers, NativeTools: execution.BuiltinTools(),
+		SearchModel: searchModel, SearchStore: a.search, Embedder: server.ProviderEmbedder(a.store.store, providers),
 	})
 	if err != nil {
 		return fail(fmt.Errorf("initialize execution scopes: %w", err))
@@ -177,6 +231,10 @@ func newWithFactories(ctx context.Context, cfg Config, f factories) (*App, error
 		Logger: a.logger, Logs: a.logs, Scopes: a.scopes.manager, Permissions: cfg.Permissions,
 		AgentPermissions: cfg.AgentPermissions, PermissionTimeout: cfg.PermissionTimeout,
 		Password: cfg.Password, Username: cfg.Username, InstanceID: cfg.InstanceID, Version: cfg.Version,
+		MaxToolOutputBytes: cfg.MaxToolOutputBytes,
+		Scheduler: server.SchedulerConfig{
+			MaxConcurrentRuns: cfg.MaxConcurrentRuns, MaxRunsPerClient: cfg.MaxRunsPerClient, MaxRunsPerProvider: cfg.MaxRunsPerProvider,
+		},
 	})
 	rollback = append(rollback, func() error { return a.server.Close(context.Background()) })
 	if err := a.server.Start(ctx); err != nil {
@@ -299,6 +357,9 @@ func (a *App) close(ctx context.Context) error {
 	if a.scopes.close != nil {
 		errs = append(errs, a.scopes.close(ctx))
 	}
+	if a.search != nil {
+		errs = append(errs, a.search.Close())
+	}
 	if a.store.close != nil {
 		errs = append(errs, a.store.close())
 	}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v3"
+
+	daemonconfig "github.com/chaserensberger/wingman/internal/config"
+	"github.com/chaserensberger/wingman/internal/credentialkeys"
+	"github.com/chaserensberger/wingman/store"
+)
+
+func authCommand(cfg daemonconfig.Config) *cli.Command {
+	dbFlag := &cli.StringFlag{
+		Name:  "db",
+		Value: cfg.Server.DB,
+		Usage: "Database path (default: ~/.local/share/wingman/wingman.db)",
+	}
+	return &cli.Command{Name: "auth", Usage: "Manage stored credentials", Commands: []*cli.Command{
+		{Name: "migrate", Usage: "Encrypt stored credentials with the configured credential key",
+			Flags: []cli.Flag{dbFlag}, Action: runAuthMigrate(cfg)},
+		{Name: "rotate-key", Usage: "Add a new credential key and reseal stored credentials with it",
+			Flags: []cli.Flag{dbFlag}, Action: runAuthRotateKey(cfg)},
+	}}
+}
+
+// openCredentialKeys returns the configured credential keys, or nil when
+// credential encryption is off. It never creates keys: a keyset that went
+// missing must not be replaced while sealed credentials still need it.
+func openCredentialKeys(cfg daemonconfig.Config) (*credentialkeys.Keys, error) {
+	backend, err := credentialBackend(cfg)
+	if err != nil || backend == nil {
+		return nil, err
+	}
+	keys, err := credentialkeys.Open(backend)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s has no credential keys; run `wingman auth migrate` to create them", backend.Describe())
+	}
+	return keys, err
+}
+
+func credentialBackend(cfg daemonconfig.Config) (credentialkeys.Backend, error) {
+	return credentialkeys.NewBackend(cfg.Credentials.Encryption, cfg.Credentials.KeyFile)
+}
+
+var errCredentialEncryptionOff = errors.New(`credential encryption is off; set credentials.encryption to "keyring" or "file" in the config`)
+
+func runAuthMigrate(cfg daemonconfig.Config) cli.ActionFunc {
+	return func(ctx context.Context, cmd *cli.Command) error {
+		backend, err := credentialBackend(cfg)
+		if err != nil {
+			return err
+		}
+		if backend == nil {
+			return errCredentialEncryptionOff
+		}
+		db, err := openAuthStore(cmd.String("db"))
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		keys, err := credentialkeys.Open(backend)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			// New keys could not open credentials sealed with the lost ones.
+			if _, err := db.GetAuth(); errors.Is(err, store.ErrCredentialsSealed) {
+				return fmt.Errorf("stored credentials are encrypted but %s has no credential keys; restore the keys that encrypted them", backend.Describe())
+			} else if err != nil {
+				return err
+			}
+			if keys, err = credentialkeys.Create(backend); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			// Saving wraps a plain key file when a passphrase is set.
+			if err := keys.Save(); err != nil {
+				return err
+			}
+		}
+		resealed, err := db.UseCredentialKeys(keys)
+		if err != nil {
+			return err
+		}
+		current, _, _ := keys.Current()
+		if resealed {
+			fmt.Fprintf(commandWriter(cmd), "Encrypted stored credentials with key %s\n", current)
+		} else {
+			fmt.Fprintf(commandWriter(cmd), "Stored credentials already use key %s\n", current)
+		}
+		return nil
+	}
+}
+
+func runAuthRotateKey(cfg daemonconfig.Config) cli.ActionFunc {
+	return func(ctx context.Context, cmd *cli.Command) error {
+		keys, err := openCredentialKeys(cfg)
+		if err != nil {
+			return err
+		}
+		if keys == nil {
+			return errCredentialEncryptionOff
+		}
+		id, err := keys.Rotate()
+		if err != nil {
+			return err
+		}
+		db, err := openAuthStore(cmd.String("db"))
+		if err == nil {
+			defer db.Close()
+			_, err = db.UseCredentialKeys(keys)
+		}
+		if err != nil {
+			return fmt.Errorf("added credential key %s but could not reseal stored credentials: %w", id, err)
+		}
+		fmt.Fprintf(commandWriter(cmd), "Rotated credential key to %s\n", id)
+		return nil
+	}
+}
+
+// openAuthStore opens the database whose stored credentials the auth
+// commands rewrite. A running daemon keeps reading them: it reloads the
+// keyset when it meets an unknown key ID.
+func openAuthStore(dbPath string) (*store.SQLiteStore, error) {
+	if dbPath == "" {
+		var err error
+		if dbPath, err = store.DefaultDBPath(); err != nil {
+			return nil, err
+		}
+	}
+	db, err := store.NewSQLiteStore(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	daemonconfig "github.com/chaserensberger/wingman/internal/config"
+	"github.com/chaserensberger/wingman/store"
+)
+
+func TestAuthMigrateAndRotateKeyEncryptStoredCredentials(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "wingman.db")
+	data, err := store.NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := data.SetAuth(&store.Auth{Providers: map[string]store.AuthCredential{"openai": {Type: "api", Key: "sk-test"}}}); err != nil {
+		t.Fatal(err)
+	}
+	_ = data.Close()
+
+	if err := newCommand(daemonconfig.Config{}).Run(context.Background(), []string{"wingman", "auth", "migrate", "--db", dbPath}); err == nil {
+		t.Fatal("migrate without credential encryption succeeded")
+	}
+
+	cfg := daemonconfig.Config{Credentials: daemonconfig.CredentialConfig{Encryption: "file", KeyFile: filepath.Join(dir, "credentials.key")}}
+	var output bytes.Buffer
+	cmd := newCommand(cfg)
+	cmd.Writer = &output
+	if err := cmd.Run(context.Background(), []string{"wingman", "auth", "migrate", "--db", dbPath}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Run(context.Background(), []string{"wingman", "auth", "rotate-key", "--db", dbPath}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output.String(), "Encrypted stored credentials") || !strings.Contains(output.String(), "Rotated credential key") {
+		t.Fatalf("output = %q", output.String())
+	}
+
+	data, err = store.NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	if _, err := data.GetAuth(); !errors.Is(err, store.ErrCredentialsSealed) {
+		t.Fatalf("read migrated credentials without keys: error = %v", err)
+	}
+	keys, err := openCredentialKeys(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resealed, err := data.UseCredentialKeys(keys); err != nil || resealed {
+		t.Fatalf("credentials after rotate: resealed = %v, error = %v", resealed, err)
+	}
+	auth, err := data.GetAuth()
+	if err != nil || auth.Providers["openai"].Key != "sk-test" {
+		t.Fatalf("auth = %#v, error = %v", auth, err)
+	}
+
+	// Lost keys are never replaced while sealed credentials need them.
+	if err := os.Remove(cfg.Credentials.KeyFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openCredentialKeys(cfg); err == nil || !strings.Contains(err.Error(), "wingman auth migrate") {
+		t.Fatalf("open missing keys: error = %v", err)
+	}
+	if err := cmd.Run(context.Background(), []string{"wingman", "auth", "migrate", "--db", dbPath}); err == nil || !strings.Contains(err.Error(), "restore the keys") {
+		t.Fatalf("migrate without the sealing keys: error = %v", err)
+	}
+	if _, err := os.Stat(cfg.Credentials.KeyFile); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("migrate created a key file: error = %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/eval"
+)
+
+const evalPollInterval = 2 * time.Second
+
+func evalCommand() *cli.Command {
+	return &cli.Command{Name: "eval", Usage: "Evaluate agents against datasets", Commands: []*cli.Command{
+		{Name: "run", Usage: "Run an agent over a JSONL dataset and score the answers", ArgsUsage: "<dataset.jsonl>", Flags: []cli.Flag{
+			&cli.StringFlag{Name: "agent", Usage: "Agent ID to evaluate", Required: true},
+			&cli.Int64Flag{Name: "revision", Usage: "Agent revision to evaluate instead of the current one"},
+			&cli.StringFlag{Name: "model", Usage: "Model ref to evaluate instead of the agent's model"},
+			&cli.StringFlag{Name: "judge-model", Usage: "Model ref for judge checks; defaults to the evaluated model"},
+			&cli.StringFlag{Name: "name", Usage: "Eval name"},
+			&cli.IntFlag{Name: "concurrency", Usage: "Cases to run at once (default 4)"},
+			&cli.BoolFlag{Name: "no-wait", Usage: "Print the eval ID and return without waiting for results"},
+		}, Action: runEvalRun},
+		{Name: "list", Usage: "List evals", Flags: []cli.Flag{
+			&cli.StringFlag{Name: "agent", Usage: "Only list evals of this agent"},
+		}, Action: runEvalList},
+		{Name: "show", Usage: "Show an eval's case results", ArgsUsage: "<eval-id>", Action: runEvalShow},
+	}}
+}
+
+func runEvalRun(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return errors.New("expected one dataset path")
+	}
+	cases, err := eval.LoadDataset(cmd.Args().First())
+	if err != nil {
+		return err
+	}
+	workDir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	req := api.CreateEvalRequest{
+		Name: cmd.String("name"), AgentID: cmd.String("agent"), AgentRevision: cmd.Int64("revision"),
+		ModelRef: cmd.String("model"), JudgeModelRef: cmd.String("judge-model"), WorkingDirectory: workDir,
+		Concurrency: cmd.Int("concurrency"), Cases: apiEvalCases(cases),
+	}
+	client, err := discoverManagedDaemon(ctx)
+	if err != nil {
+		return err
+	}
+	var created api.Eval
+	if err := client.DoJSON(ctx, "POST", "/evals", req, &created); err != nil {
+		return err
+	}
+	out := commandWriter(cmd)
+	if cmd.Bool("no-wait") {
+		fmt.Fprintln(out, created.ID)
+		return nil
+	}
+	fmt.Fprintf(out, "Eval %s: %d cases with %s\n", created.ID, created.Summary.Total, created.ModelRef)
+	finished, err := awaitEval(ctx, client, created.ID)
+	if err != nil {
+		return err
+	}
+	printEval(out, finished)
+	return evalOutcome(finished)
+}
+
+func runEvalList(ctx context.Context, cmd *cli.Command) error {
+	client, err := discoverManagedDaemon(ctx)
+	if err != nil {
+		return err
+	}
+	path := "/evals"
+	if agentID := cmd.String("agent"); agentID != "" {
+		path += "?agent_id=" + url.QueryEscape(agentID)
+	}
+	var evals []api.Eval
+	if err := client.DoJSON(ctx, "GET", path, nil, &evals); err != nil {
+		return err
+	}
+	out := commandWriter(cmd)
+	for _, value := range evals {
+		fmt.Fprintf(out, "%s  %-9s  %s  %s  %d/%d passed  score %.2f\n", value.ID, value.Status, evalTarget(value), value.CreatedAt.Local().Format(time.DateTime), value.Summary.Passed, value.Summary.Total, value.Summary.Score)
+	}
+	return nil
+}
+
+func runEvalShow(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return errors.New("expected one eval ID")
+	}
+	client, err := discoverManagedDaemon(ctx)
+	if err != nil {
+		return err
+	}
+	var value api.Eval
+	if err := client.DoJSON(ctx, "GET", "/evals/"+url.PathEscape(cmd.Args().First()), nil, &value); err != nil {
+		return err
+	}
+	printEval(commandWriter(cmd), value)
+	return nil
+}
+
+type jsonDaemonClient interface {
+	DoJSON(ctx context.Context, method, path string, requestBody, responseBody any) error
+}
+
+func awaitEval(ctx context.Context, client jsonDaemonClient, id string) (api.Eval, error) {
+	ticker := time.NewTicker(evalPollInterval)
+	defer ticker.Stop()
+	for {
+		var value api.Eval
+		if err := client.DoJSON(ctx, "GET", "/evals/"+url.PathEscape(id), nil, &value); err != nil {
+			return api.Eval{}, err
+		}
+		if value.Status != "running" {
+			return value, nil
+		}
+		select {
+		case <-ctx.Done():
+			return api.Eval{}, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func printEval(w io.Writer, value api.Eval) {
+	fmt.Fprintf(w, "Eval %s (%s) %s\n", value.ID, evalTarget(value), value.Status)
+	for _, c := range value.Cases {
+		fmt.Fprintf(w, "  %-7s %.2f  %s", strings.ToUpper(c.Status), c.Score, c.Case.ID)
+		if c.SessionID != "" {
+			fmt.Fprintf(w, "  session %s", c.SessionID)
+		}
+		fmt.Fprintln(w)
+		if c.ErrorMessage != "" {
+			fmt.Fprintf(w, "          %s\n", firstLine(c.ErrorMessage))
+		}
+		for _, check := range c.Checks {
+			if !check.Passed {
+				fmt.Fprintf(w, "          %s: %s\n", check.Type, firstLine(check.Detail))
+			}
+		}
+	}
+	summary := value.Summary
+	fmt.Fprintf(w, "%d/%d passed, %d failed, %d errored, score %.2f, %d tokens, $%.4f\n", summary.Passed, summary.Total, summary.Failed, summary.Errored, summary.Score, summary.TotalTokens, summary.Cost)
+	if value.ErrorMessage != "" {
+		fmt.Fprintln(w, value.ErrorMessage)
+	}
+}
+
+// evalOutcome fails the command unless every case passed, so scripts can
+// gate on an eval.
+func evalOutcome(value api.Eval) error {
+	if value.ErrorMessage != "" {
+		return errors.New(value.ErrorMessage)
+	}
+	if missed := value.Summary.Total - value.Summary.Passed; missed > 0 {
+		return fmt.Errorf("%d of %d cases did not pass", missed, value.Summary.Total)
+	}
+	return nil
+}
+
+func evalTarget(value api.Eval) string {
+	target := value.AgentID
+	if value.AgentRevision > 0 {
+		target += fmt.Sprintf("@%d", value.AgentRevision)
+	}
+	return target + " " + value.ModelRef
+}
+
+func apiEvalCases(cases []eval.Case) []api.EvalCase {
+	result := make([]api.EvalCase, len(cases))
+	for i, c := range cases {
+		result[i] = api.EvalCase{ID: c.ID, Input: c.Input, Fixture: c.Fixture, Expected: c.Expected}
+		for _, check := range c.Checks {
+			result[i].Checks = append(result[i].Checks, api.EvalCheck(check))
+		}
+	}
+	return result
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/chaserensberger/wingman/api"
+	daemonconfig "github.com/chaserensberger/wingman/internal/config"
+)
+
+type scriptedJSONClient struct {
+	paths     []string
+	responses []string
+}
+
+func (c *scriptedJSONClient) DoJSON(_ context.Context, _, path string, _, responseBody any) error {
+	c.paths = append(c.paths, path)
+	response := c.responses[0]
+	c.responses = c.responses[1:]
+	return json.Unmarshal([]byte(response), responseBody)
+}
+
+func TestEvalCommandHierarchy(t *testing.T) {
+	eval := newCommand(daemonconfig.Config{}).Command("eval")
+	if eval == nil {
+		t.Fatal("eval command is missing")
+	}
+	for _, name := range []string{"run", "list", "show"} {
+		if eval.Command(name) == nil {
+			t.Errorf("eval %s command is missing", name)
+		}
+	}
+}
+
+func TestAwaitEvalPrintsCasesAndFailsOnMisses(t *testing.T) {
+	client := &scriptedJSONClient{responses: []string{
+		`{"id":"evl_1","status":"running"}`,
+		`{"id":"evl_1","agent_id":"agt_a","agent_revision":3,"model_ref":"test/model","status":"completed","summary":{"total":2,"passed":1,"failed":1,"score":0.5,"total_tokens":42},
+		 "cases":[{"index":0,"case":{"id":"sum","input":"2+2"},"session_id":"ses_1","status":"passed","score":1},
+		          {"index":1,"case":{"id":"build","input":"fix"},"session_id":"ses_2","status":"failed","score":0,"checks":[{"type":"command","passed":false,"detail":"exit status 1\nFAIL main_test.go"}]}]}`,
+	}}
+	value, err := awaitEval(context.Background(), client, "evl_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(client.paths) != 2 || client.paths[1] != "/evals/evl_1" {
+		t.Fatalf("paths = %v", client.paths)
+	}
+	var out bytes.Buffer
+	printEval(&out, value)
+	for _, want := range []string{"agt_a@3 test/model", "PASSED  1.00  sum  session ses_1", "command: exit status 1\n", "1/2 passed, 1 failed, 0 errored, score 0.50, 42 tokens"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if err := evalOutcome(value); err == nil || err.Error() != "1 of 2 cases did not pass" {
+		t.Fatalf("outcome = %v", err)
+	}
+	if err := evalOutcome(api.Eval{Summary: api.EvalSummary{Total: 1, Passed: 1}}); err != nil {
+		t.Fatalf("passing outcome = %v", err)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/chaserensberger/wingman/app"
 	daemonconfig "github.com/chaserensberger/wingman/internal/config"
 	"github.com/chaserensberger/wingman/internal/daemonstate"
+	"github.com/chaserensberger/wingman/store"
 )
 
 var (
@@ -115,7 +116,10 @@ func newCommand(cfg daemonconfig.Config) *cli.Command {
 				Usage:  "Show server pairing information",
 				Action: runPair(cfg),
 			},
+			authCommand(cfg),
 			clientsCommand(),
+			evalCommand(),
+			mcpCommand(),
 			{
 				Name:   "console",
 				Usage:  "Open the managed daemon console",
@@ -232,13 +236,26 @@ func runServe(cfg daemonconfig.Config) cli.ActionFunc {
 			}
 			defer func() { _, _ = state.RemoveRegistration(instanceID) }()
 		}
+		var credentialKeys store.CredentialKeys
+		if !cmd.Bool("ephemeral") {
+			keys, err := openCredentialKeys(effective)
+			if err != nil {
+				_ = listener.Close()
+				return fmt.Errorf("open credential keys: %w", err)
+			}
+			if keys != nil {
+				credentialKeys = keys
+			}
+		}
 		application, err := app.New(sigCtx, app.Config{
 			Ephemeral: cmd.Bool("ephemeral"), DBPath: effective.Server.DB,
 			ConsoleDevURL: cmd.String("console-dev-url"), LogFormat: effective.Server.LogFormat, LogLevel: effective.Server.LogLevel,
 			PluginDirs: effective.Plugins.Dirs, DefaultPluginDir: effective.Plugins.DefaultDir, DisablePlugins: cmd.Bool("no-plugins"),
 			MCP: effective.MCP, Providers: effective.Provider,
-			Permissions: effective.Permissions, AgentPermissions: effective.AgentPermissions,
-			Password: password, Username: username, InstanceID: instanceID, Version: version,
+			Permissions: effective.Permissions, AgentPermissions: effective.AgentPermissions, MaxToolOutputBytes: effective.Tools.MaxOutputBytes,
+			SearchModel: effective.Tools.SearchModel, Password: password, Username: username, InstanceID: instanceID, Version: version,
+			MaxConcurrentRuns: effective.Runs.MaxConcurrent, MaxRunsPerClient: effective.Runs.MaxPerClient, MaxRunsPerProvider: effective.Runs.MaxPerProvider,
+			CredentialKeys: credentialKeys,
 		})
 		if err != nil {
 			_ = listener.Close()
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/urfave/cli/v3"
+)
+
+func mcpCommand() *cli.Command {
+	return &cli.Command{Name: "mcp", Usage: "Use Wingman from MCP hosts", Commands: []*cli.Command{
+		{Name: "serve", Usage: "Serve Wingman agents, sessions, and prompts over MCP on stdio", Flags: []cli.Flag{
+			&cli.StringFlag{Name: "client", Usage: "Client ID that owns sessions created over MCP"},
+		}, Action: runMCPServe},
+	}}
+}
+
+// runMCPServe relays stdio to the managed daemon's MCP endpoint so that
+// sessions, runs, and permission requests live in the daemon.
+func runMCPServe(ctx context.Context, cmd *cli.Command) error {
+	client, err := discoverManagedDaemon(ctx)
+	if err != nil {
+		return err
+	}
+	endpoint, err := resolveURL(client.URL(), "/mcp-server")
+	if err != nil {
+		return err
+	}
+	headers := make(http.Header)
+	if clientID := cmd.String("client"); clientID != "" {
+		headers.Set("X-Wingman-Client", clientID)
+	}
+	remote := &mcpsdk.StreamableClientTransport{Endpoint: endpoint, HTTPClient: client.HTTPClient(headers)}
+	return bridgeMCP(ctx, &mcpsdk.StdioTransport{}, remote)
+}
+
+// bridgeMCP relays JSON-RPC messages between two MCP transports until the
+// local side closes. Calls the remote side rejects are answered locally so
+// the host is not left waiting.
+func bridgeMCP(ctx context.Context, local, remote mcpsdk.Transport) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	localConn, err := local.Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("connect local MCP transport: %w", err)
+	}
+	defer localConn.Close()
+	remoteConn, err := remote.Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("connect Wingman MCP endpoint: %w", err)
+	}
+	defer remoteConn.Close()
+
+	errs := make(chan error, 2)
+	go func() {
+		for {
+			msg, err := localConn.Read(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if err := remoteConn.Write(ctx, msg); err != nil {
+				request, ok := msg.(*jsonrpc.Request)
+				if !ok || !request.IsCall() {
+					errs <- err
+					return
+				}
+				if err := localConn.Write(ctx, &jsonrpc.Response{ID: request.ID, Error: &jsonrpc.Error{Code: jsonrpc.CodeInternalError, Message: err.Error()}}); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}
+	}()
+	go func() {
+		for {
+			msg, err := remoteConn.Read(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if err := localConn.Write(ctx, msg); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	err = <-errs
+	if errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestBridgeMCPRelaysHostToDaemonEndpoint(t *testing.T) {
+	daemon := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "wingman", Version: "test"}, nil)
+	daemon.AddTool(&mcpsdk.Tool{Name: "ask_build", InputSchema: map[string]any{"type": "object"}}, func(context.Context, *mcpsdk.CallToolRequest) (*mcpsdk.CallToolResult, error) {
+		return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: "built"}}}, nil
+	})
+	endpoint := httptest.NewServer(mcpsdk.NewStreamableHTTPHandler(func(*http.Request) *mcpsdk.Server { return daemon }, nil))
+	defer endpoint.Close()
+
+	hostTransport, bridgeTransport := mcpsdk.NewInMemoryTransports()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bridged := make(chan error, 1)
+	go func() {
+		bridged <- bridgeMCP(ctx, bridgeTransport, &mcpsdk.StreamableClientTransport{Endpoint: endpoint.URL, HTTPClient: endpoint.Client()})
+	}()
+
+	host := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "editor", Version: "test"}, nil)
+	session, err := host.Connect(ctx, hostTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := session.CallTool(ctx, &mcpsdk.CallToolParams{Name: "ask_build"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := result.Content[0].(*mcpsdk.TextContent).Text; text != "built" {
+		t.Fatalf("tool result = %q", text)
+	}
+	if err := session.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-bridged:
+		if err != nil {
+			t.Fatalf("bridge error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("bridge did not stop after the host closed")
+	}
+}
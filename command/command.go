@@ -0,0 +1,205 @@
+// Package command validates and renders saved prompt commands and loads
+// them from a project's .wingman/commands directory.
+//
+// A command template names its arguments as {{name}} placeholders. Command
+// files use the same layout as agent files: optional YAML frontmatter
+// followed by the template body.
+//
+//	---
+//	description: Review the working tree for security issues
+//	arguments:
+//	  - name: focus
+//	    default: injection and authentication
+//	agent_id: file:reviewer
+//	---
+//	Review this diff for security issues, focusing on {{focus}}.
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chaserensberger/wingman/frontmatter"
+	"github.com/chaserensberger/wingman/store"
+)
+
+// IDPrefix marks command IDs that resolve to command files rather than the
+// store.
+const IDPrefix = "file:"
+
+var (
+	placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*\}\}`)
+	argumentPattern    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+	stemPattern        = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+type commandFrontmatter struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Arguments   []store.CommandArgument `json:"arguments"`
+	AgentID     string                  `json:"agent_id"`
+	ModelRef    string                  `json:"model_ref"`
+}
+
+// Validate checks a command's name, template, and argument declarations.
+// Every placeholder in the template must name a declared argument.
+func Validate(c *store.Command) error {
+	if strings.TrimSpace(c.Name) == "" {
+		return errors.New("name is required")
+	}
+	if strings.TrimSpace(c.Template) == "" {
+		return errors.New("template is required")
+	}
+	declared := make(map[string]struct{}, len(c.Arguments))
+	for _, argument := range c.Arguments {
+		if !argumentPattern.MatchString(argument.Name) {
+			return fmt.Errorf("argument name %q must start with a letter or underscore and contain only letters, digits, '_', or '-'", argument.Name)
+		}
+		if _, ok := declared[argument.Name]; ok {
+			return fmt.Errorf("argument %q is declared more than once", argument.Name)
+		}
+		if argument.Required && argument.Default != "" {
+			return fmt.Errorf("argument %q cannot be required and have a default", argument.Name)
+		}
+		declared[argument.Name] = struct{}{}
+	}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(c.Template, -1) {
+		if _, ok := declared[match[1]]; !ok {
+			return fmt.Errorf("template uses undeclared argument %q", match[1])
+		}
+	}
+	return nil
+}
+
+// Render substitutes args into the command template. Required arguments must
+// be present, omitted optional arguments use their defaults, and unknown
+// arguments are rejected.
+func Render(c *store.Command, args map[string]string) (string, error) {
+	values := make(map[string]string, len(c.Arguments))
+	for _, argument := range c.Arguments {
+		value, ok := args[argument.Name]
+		if !ok || value == "" {
+			if argument.Required {
+				return "", fmt.Errorf("argument %q is required", argument.Name)
+			}
+			value = argument.Default
+		}
+		values[argument.Name] = value
+	}
+	var unknown []string
+	for name := range args {
+		if _, ok := values[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return "", fmt.Errorf("unknown arguments: %s", strings.Join(unknown, ", "))
+	}
+	return placeholderPattern.ReplaceAllStringFunc(c.Template, func(placeholder string) string {
+		return values[placeholderPattern.FindStringSubmatch(placeholder)[1]]
+	}), nil
+}
+
+// Dir returns the project-local command file directory for workDir.
+func Dir(workDir string) string {
+	if workDir == "" {
+		return ""
+	}
+	return filepath.Join(workDir, ".wingman", "commands")
+}
+
+// IsFileID reports whether id names a command file.
+func IsFileID(id string) bool {
+	return strings.HasPrefix(id, IDPrefix)
+}
+
+// ID returns the command ID for the command file at path.
+func ID(path string) string {
+	return IDPrefix + strings.TrimSuffix(filepath.Base(path), ".md")
+}
+
+// Discover loads every *.md file in workDir's command directory, sorted by
+// ID. Files that fail to load are reported as load errors and skipped.
+func Discover(workDir string) ([]*store.Command, []frontmatter.LoadError) {
+	dir := Dir(workDir)
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, []frontmatter.LoadError{{Path: dir, Error: err.Error()}}
+	}
+	var commands []*store.Command
+	var errs []frontmatter.LoadError
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".md" {
+			continue
+		}
+		command, err := Load(filepath.Join(dir, name))
+		if err != nil {
+			errs = append(errs, frontmatter.LoadError{Path: filepath.Join(dir, name), Error: err.Error()})
+			continue
+		}
+		commands = append(commands, command)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].ID < commands[j].ID })
+	return commands, errs
+}
+
+// Load reads and validates one command file.
+func Load(path string) (*store.Command, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	command, err := Parse(path, data)
+	if err != nil {
+		return nil, err
+	}
+	modified := info.ModTime().UTC().Format(time.RFC3339Nano)
+	command.CreatedAt, command.UpdatedAt = modified, modified
+	return command, nil
+}
+
+// Parse decodes a command file's contents. The file name without .md is the
+// default name; path is recorded on the command and is not read.
+func Parse(path string, data []byte) (*store.Command, error) {
+	stem := strings.TrimSuffix(filepath.Base(path), ".md")
+	if !stemPattern.MatchString(stem) {
+		return nil, fmt.Errorf("command file name %q must start with a letter or digit and contain only letters, digits, '.', '_', or '-'", filepath.Base(path))
+	}
+	var meta commandFrontmatter
+	body, err := frontmatter.Parse(data, &meta)
+	if err != nil {
+		return nil, err
+	}
+	command := &store.Command{
+		ID: ID(path), Name: strings.TrimSpace(meta.Name), Description: meta.Description,
+		Template: strings.TrimSpace(body), Arguments: meta.Arguments,
+		AgentID: meta.AgentID, ModelRef: meta.ModelRef,
+		Source: store.AgentSourceFile, Path: path,
+	}
+	if command.Name == "" {
+		command.Name = stem
+	}
+	if err := Validate(command); err != nil {
+		return nil, err
+	}
+	return command, nil
+}
@@ -0,0 +1,82 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chaserensberger/wingman/store"
+)
+
+func TestRenderAppliesArgumentsAndDefaults(t *testing.T) {
+	c := &store.Command{
+		Name:     "review",
+		Template: "Review {{ path }} for {{focus}}. Again: {{path}}.",
+		Arguments: []store.CommandArgument{
+			{Name: "path", Required: true},
+			{Name: "focus", Default: "bugs"},
+		},
+	}
+	if err := Validate(c); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Render(c, map[string]string{"path": "main.go"})
+	if err != nil || got != "Review main.go for bugs. Again: main.go." {
+		t.Fatalf("render = %q, error = %v", got, err)
+	}
+	if _, err := Render(c, nil); err == nil || !strings.Contains(err.Error(), `argument "path" is required`) {
+		t.Fatalf("missing argument error = %v", err)
+	}
+	if _, err := Render(c, map[string]string{"path": "a", "zeta": "1", "alpha": "2"}); err == nil || err.Error() != "unknown arguments: alpha, zeta" {
+		t.Fatalf("unknown argument error = %v", err)
+	}
+}
+
+func TestValidateRejectsInvalidCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		command store.Command
+		want    string
+	}{
+		{"no template", store.Command{Name: "a"}, "template is required"},
+		{"undeclared", store.Command{Name: "a", Template: "{{x}}"}, `undeclared argument "x"`},
+		{"duplicate", store.Command{Name: "a", Template: "t", Arguments: []store.CommandArgument{{Name: "x"}, {Name: "x"}}}, "more than once"},
+		{"bad name", store.Command{Name: "a", Template: "t", Arguments: []store.CommandArgument{{Name: "1x"}}}, "argument name"},
+		{"required default", store.Command{Name: "a", Template: "t", Arguments: []store.CommandArgument{{Name: "x", Required: true, Default: "d"}}}, "cannot be required"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := Validate(&tc.command); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestDiscoverLoadsCommandFiles(t *testing.T) {
+	workDir := t.TempDir()
+	dir := Dir(workDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"review.md": "---\ndescription: Review a file\narguments:\n  - name: path\n    required: true\nagent_id: file:reviewer\n---\nReview {{path}}.",
+		"plain.md":  "Summarize the repository.",
+		"broken.md": "Use {{missing}}.",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commands, errs := Discover(workDir)
+	if len(commands) != 2 || commands[0].ID != "file:plain" || commands[1].Name != "review" || commands[1].AgentID != "file:reviewer" || commands[1].Source != store.AgentSourceFile {
+		t.Fatalf("commands = %#v", commands)
+	}
+	if len(commands[1].Arguments) != 1 || !commands[1].Arguments[0].Required {
+		t.Fatalf("arguments = %#v", commands[1].Arguments)
+	}
+	if len(errs) != 1 || ID(errs[0].Path) != "file:broken" || !strings.Contains(errs[0].Error, "undeclared argument") {
+		t.Fatalf("errors = %#v", errs)
+	}
+}
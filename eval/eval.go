@@ -0,0 +1,306 @@
+// Package eval scores agent answers against a dataset of cases. A dataset
+// is JSONL: each line is one Case with the prompt, an optional fixture
+// directory the agent works in, the expected output, and the checks that
+// grade the answer.
+package eval
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chaserensberger/wingman/agent/run"
+	"github.com/chaserensberger/wingman/models"
+)
+
+// Check types.
+const (
+	CheckExact   = "exact"
+	CheckSchema  = "schema"
+	CheckCommand = "command"
+	CheckJudge   = "judge"
+)
+
+const (
+	defaultCommandTimeout = 2 * time.Minute
+	maxCheckDetail        = 2048
+	maxDatasetLine        = 16 << 20
+)
+
+// Case is one dataset entry.
+type Case struct {
+	ID    string `json:"id,omitempty"`
+	Input string `json:"input"`
+	// Fixture is a directory copied into a fresh working directory for the
+	// case. Relative paths are resolved against the dataset file.
+	Fixture string `json:"fixture,omitempty"`
+	// Expected is the answer exact checks compare against: a JSON string
+	// compares text, any other JSON value compares the parsed answer.
+	Expected json.RawMessage `json:"expected,omitempty"`
+	// Checks grade the answer. A case without checks but with Expected
+	// uses a single exact check.
+	Checks []Check `json:"checks,omitempty"`
+}
+
+// Check is one way of grading an answer.
+type Check struct {
+	Type string `json:"type"`
+	// Schema is the JSON schema a schema check validates the answer
+	// against.
+	Schema map[string]any `json:"schema,omitempty"`
+	// Command runs with bash in the case's working directory after the
+	// agent finishes. It passes when it exits zero.
+	Command   string `json:"command,omitempty"`
+	TimeoutMS int    `json:"timeout_ms,omitempty"`
+	// Criteria tells an LLM judge what a good answer looks like.
+	Criteria string `json:"criteria,omitempty"`
+	// ModelRef overrides the eval's judge model for this check.
+	ModelRef string `json:"model_ref,omitempty"`
+}
+
+// Result is the outcome of one check. Score is in [0, 1]; checks other than
+// judge score 0 or 1.
+type Result struct {
+	Type   string  `json:"type"`
+	Passed bool    `json:"passed"`
+	Score  float64 `json:"score"`
+	Detail string  `json:"detail,omitempty"`
+}
+
+// LoadDataset reads a JSONL dataset file and resolves relative fixture
+// paths against its directory.
+func LoadDataset(path string) ([]Case, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cases, err := ParseDataset(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	for i := range cases {
+		if cases[i].Fixture != "" && !filepath.IsAbs(cases[i].Fixture) {
+			cases[i].Fixture = filepath.Join(dir, cases[i].Fixture)
+		}
+	}
+	return cases, nil
+}
+
+// ParseDataset decodes JSONL cases, skipping blank lines. Cases without an
+// ID are named after their line number.
+func ParseDataset(r io.Reader) ([]Case, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxDatasetLine)
+	var cases []Case
+	seen := map[string]bool{}
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var c Case
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if c.ID == "" {
+			c.ID = "line-" + strconv.Itoa(line)
+		}
+		if seen[c.ID] {
+			return nil, fmt.Errorf("line %d: duplicate case id %q", line, c.ID)
+		}
+		seen[c.ID] = true
+		if err := c.Validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, errors.New("dataset has no cases")
+	}
+	return cases, nil
+}
+
+// Validate reports whether the case can be run and scored.
+func (c Case) Validate() error {
+	if strings.TrimSpace(c.Input) == "" {
+		return fmt.Errorf("case %s: input is required", c.ID)
+	}
+	checks := c.EffectiveChecks()
+	if len(checks) == 0 {
+		return fmt.Errorf("case %s: needs expected output or at least one check", c.ID)
+	}
+	for i, check := range checks {
+		var err error
+		switch check.Type {
+		case CheckExact:
+			if len(c.Expected) == 0 {
+				err = errors.New("exact check requires expected output")
+			} else if !json.Valid(c.Expected) {
+				err = errors.New("expected output is not valid JSON")
+			}
+		case CheckSchema:
+			if len(check.Schema) == 0 {
+				err = errors.New("schema check requires a schema")
+			}
+		case CheckCommand:
+			if strings.TrimSpace(check.Command) == "" {
+				err = errors.New("command check requires a command")
+			}
+		case CheckJudge:
+			if strings.TrimSpace(check.Criteria) == "" {
+				err = errors.New("judge check requires criteria")
+			}
+		default:
+			err = fmt.Errorf("unknown check type %q", check.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("case %s: checks[%d]: %w", c.ID, i, err)
+		}
+	}
+	return nil
+}
+
+// EffectiveChecks returns the case's checks, defaulting to one exact check
+// when only Expected is set.
+func (c Case) EffectiveChecks() []Check {
+	if len(c.Checks) == 0 && len(c.Expected) > 0 {
+		return []Check{{Type: CheckExact}}
+	}
+	return c.Checks
+}
+
+// JudgeResolver returns the judge for a check's model ref. An empty ref
+// selects the eval's default judge model.
+type JudgeResolver func(modelRef string) (*Judge, error)
+
+// Score grades answer, the agent's final text, with every check of the
+// case. workDir is the directory command checks run in.
+func Score(ctx context.Context, c Case, answer, workDir string, judges JudgeResolver) []Result {
+	checks := c.EffectiveChecks()
+	results := make([]Result, len(checks))
+	for i, check := range checks {
+		var result Result
+		switch check.Type {
+		case CheckExact:
+			result = scoreExact(c.Expected, answer)
+		case CheckSchema:
+			result = scoreSchema(check.Schema, answer)
+		case CheckCommand:
+			result = scoreCommand(ctx, check, workDir)
+		case CheckJudge:
+			result = scoreJudge(ctx, check, c, answer, judges)
+		}
+		result.Type = check.Type
+		results[i] = result
+	}
+	return results
+}
+
+// Summarize averages check scores into a case score. A case passes when
+// every check passes.
+func Summarize(results []Result) (score float64, passed bool) {
+	if len(results) == 0 {
+		return 0, false
+	}
+	passed = true
+	for _, result := range results {
+		score += result.Score
+		passed = passed && result.Passed
+	}
+	return score / float64(len(results)), passed
+}
+
+func scoreExact(expected json.RawMessage, answer string) Result {
+	var want any
+	if err := json.Unmarshal(expected, &want); err != nil {
+		return Result{Detail: "expected output is not valid JSON"}
+	}
+	if text, ok := want.(string); ok {
+		if strings.TrimSpace(answer) == strings.TrimSpace(text) {
+			return Result{Passed: true, Score: 1}
+		}
+		return Result{Detail: "got " + truncate(strconv.Quote(strings.TrimSpace(answer)))}
+	}
+	var got any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(answer)), &got); err != nil {
+		return Result{Detail: "answer is not JSON: " + truncate(answer)}
+	}
+	if reflect.DeepEqual(want, got) {
+		return Result{Passed: true, Score: 1}
+	}
+	return Result{Detail: "got " + truncate(strings.TrimSpace(answer))}
+}
+
+func scoreSchema(schema map[string]any, answer string) Result {
+	if _, err := run.ValidateStructuredOutput(&models.OutputSchema{Schema: schema}, models.NewAssistantText(strings.TrimSpace(answer))); err != nil {
+		return Result{Detail: truncate(strings.TrimPrefix(err.Error(), "loop: "))}
+	}
+	return Result{Passed: true, Score: 1}
+}
+
+func scoreCommand(ctx context.Context, check Check, workDir string) Result {
+	timeout := defaultCommandTimeout
+	if check.TimeoutMS > 0 {
+		timeout = time.Duration(check.TimeoutMS) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "bash", "-c", check.Command)
+	cmd.Dir = workDir
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return Result{Passed: true, Score: 1}
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return Result{Detail: fmt.Sprintf("timed out after %s", timeout)}
+	}
+	detail := err.Error()
+	if tail := strings.TrimSpace(string(output)); tail != "" {
+		if len(tail) > maxCheckDetail {
+			tail = tail[len(tail)-maxCheckDetail:]
+		}
+		detail += "\n" + tail
+	}
+	return Result{Detail: detail}
+}
+
+func scoreJudge(ctx context.Context, check Check, c Case, answer string, judges JudgeResolver) Result {
+	if judges == nil {
+		return Result{Detail: "no judge model configured"}
+	}
+	judge, err := judges(check.ModelRef)
+	if err != nil {
+		return Result{Detail: err.Error()}
+	}
+	result, err := judge.Grade(ctx, check.Criteria, c, answer)
+	if err != nil {
+		return Result{Detail: "judge: " + err.Error()}
+	}
+	return result
+}
+
+func truncate(s string) string {
+	if len(s) <= maxCheckDetail {
+		return s
+	}
+	return s[:maxCheckDetail] + "..."
+}
@@ -0,0 +1,85 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadDatasetResolvesFixturesAndDefaults(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cases.jsonl")
+	dataset := `{"id":"sum","input":"2+2","expected":"4"}
+
+{"input":"fix the build","fixture":"fixtures/build","checks":[{"type":"command","command":"go build ./..."}]}
+`
+	if err := os.WriteFile(path, []byte(dataset), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cases, err := LoadDataset(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) != 2 || cases[1].ID != "line-3" || cases[1].Fixture != filepath.Join(dir, "fixtures", "build") {
+		t.Fatalf("cases = %#v", cases)
+	}
+	if checks := cases[0].EffectiveChecks(); len(checks) != 1 || checks[0].Type != CheckExact {
+		t.Fatalf("default checks = %#v", checks)
+	}
+
+	for input, want := range map[string]string{
+		`{"id":"a","input":"x","expected":"y"}` + "\n" + `{"id":"a","input":"x","expected":"y"}`: "duplicate case id",
+		`{"id":"a","input":"x"}`:                             "needs expected output",
+		`{"id":"a","input":"x","checks":[{"type":"judge"}]}`: "judge check requires criteria",
+		``: "no cases",
+	} {
+		if _, err := ParseDataset(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseDataset(%q) error = %v, want %q", input, err, want)
+		}
+	}
+}
+
+func TestScoreChecks(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "done"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := Case{ID: "c", Input: "x", Expected: json.RawMessage(`{"a":1,"b":[true]}`), Checks: []Check{
+		{Type: CheckExact},
+		{Type: CheckSchema, Schema: map[string]any{"type": "object", "required": []any{"a"}, "properties": map[string]any{"a": map[string]any{"type": "integer"}}}},
+		{Type: CheckCommand, Command: "test -f done"},
+		{Type: CheckCommand, Command: "echo missing >&2; test -f missing"},
+		{Type: CheckJudge, Criteria: "anything"},
+	}}
+	results := Score(context.Background(), c, " {\"b\":[true],\"a\":1}\n", dir, nil)
+	var passed []bool
+	for _, result := range results {
+		passed = append(passed, result.Passed)
+	}
+	if want := []bool{true, true, true, false, false}; !slices.Equal(passed, want) {
+		t.Fatalf("passed = %v, results = %#v", passed, results)
+	}
+	if !strings.Contains(results[3].Detail, "missing") || results[4].Detail != "no judge model configured" {
+		t.Fatalf("details = %#v", results[3:])
+	}
+	if score, ok := Summarize(results); ok || score != 0.6 {
+		t.Fatalf("Summarize = %v, %v", score, ok)
+	}
+	if result := scoreExact(json.RawMessage(`"4"`), " 4\n"); !result.Passed {
+		t.Fatalf("text exact = %#v", result)
+	}
+}
+
+func TestParseVerdict(t *testing.T) {
+	v, err := parseVerdict("Here you go:\n```json\n{\"pass\":true,\"score\":0.8,\"reason\":\"ok\"}\n```")
+	if err != nil || !v.Pass || v.Score != 0.8 || v.Reason != "ok" {
+		t.Fatalf("verdict = %#v, error = %v", v, err)
+	}
+	if _, err := parseVerdict("yes"); err == nil {
+		t.Fatal("prose verdict parsed")
+	}
+}
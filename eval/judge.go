@@ -0,0 +1,86 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/chaserensberger/wingman/models"
+)
+
+const judgeSystem = `You grade answers produced by an AI agent. Judge the answer only against the criteria. Reply with a JSON object: "pass" is true when the answer meets the criteria, "score" is a number from 0 to 1, and "reason" briefly explains the grade.`
+
+var judgeSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"pass":   map[string]any{"type": "boolean"},
+		"score":  map[string]any{"type": "number", "minimum": 0, "maximum": 1},
+		"reason": map[string]any{"type": "string"},
+	},
+	"required":             []any{"pass", "score", "reason"},
+	"additionalProperties": false,
+}
+
+// Judge grades answers with a model.
+type Judge struct {
+	Client models.Client
+	Model  models.ModelRef
+	// StructuredOutput asks the model for a schema-constrained verdict
+	// instead of parsing JSON out of free text.
+	StructuredOutput bool
+}
+
+type verdict struct {
+	Pass   bool    `json:"pass"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+// Grade asks the model whether answer to c meets criteria.
+func (j *Judge) Grade(ctx context.Context, criteria string, c Case, answer string) (Result, error) {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Criteria:\n%s\n\nTask given to the agent:\n%s\n\n", criteria, c.Input)
+	if len(c.Expected) > 0 {
+		fmt.Fprintf(&prompt, "Reference answer:\n%s\n\n", c.Expected)
+	}
+	fmt.Fprintf(&prompt, "Agent's answer:\n%s", answer)
+	req := models.Request{
+		Model:    j.Model,
+		System:   judgeSystem,
+		Messages: []models.Message{models.NewUserText(prompt.String())},
+	}
+	if j.StructuredOutput {
+		req.OutputSchema = &models.OutputSchema{Name: "verdict", Schema: judgeSchema, Strict: true}
+	}
+	msg, err := models.Generate(ctx, j.Client, req)
+	if err != nil {
+		return Result{}, err
+	}
+	var text string
+	for _, part := range msg.Content {
+		if t, ok := part.(models.TextPart); ok {
+			text += t.Text
+		}
+	}
+	v, err := parseVerdict(text)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Passed: v.Pass, Score: min(max(v.Score, 0), 1), Detail: truncate(v.Reason)}, nil
+}
+
+// parseVerdict reads the outermost JSON object of text, tolerating models
+// that wrap it in prose or a code fence.
+func parseVerdict(text string) (verdict, error) {
+	start, end := strings.IndexByte(text, '{'), strings.LastIndexByte(text, '}')
+	if start < 0 || end < start {
+		return verdict{}, errors.New("verdict is not JSON: " + truncate(text))
+	}
+	var v verdict
+	if err := json.Unmarshal([]byte(text[start:end+1]), &v); err != nil {
+		return verdict{}, fmt.Errorf("decode verdict: %w", err)
+	}
+	return v, nil
+}
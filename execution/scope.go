@@ -11,9 +11,16 @@ import (
 	"sync"
 	"time"
 
+	"github.com/chaserensberger/wingman/agentfile"
+	"github.com/chaserensberger/wingman/frontmatter"
 	wingmcp "github.com/chaserensberger/wingman/mcp"
+	"github.com/chaserensberger/wingman/models"
 	provider "github.com/chaserensberger/wingman/models/providers"
 	"github.com/chaserensberger/wingman/pluginhost"
+	"github.com/chaserensberger/wingman/process"
+	"github.com/chaserensberger/wingman/search"
+	"github.com/chaserensberger/wingman/store"
+	"github.com/chaserensberger/wingman/symbols"
 	"github.com/chaserensberger/wingman/tool"
 )
 
@@ -26,9 +33,16 @@ type Config struct {
 	PluginDirs     []string
 	DisablePlugins bool
 	MCP            map[string]wingmcp.ServerConfig
+	MCPTokens      wingmcp.TokenStore
 	Providers      *provider.Registry
 	NativeTools    []tool.Tool
 	IdleTimeout    time.Duration
+
+	// SearchModel is the embedding model behind codebase_search. Scopes
+	// offer the tool only when it, SearchStore, and Embedder are all set.
+	SearchModel models.ModelRef
+	SearchStore *search.Store
+	Embedder    models.Embedder
 }
 
 // BuiltinTools returns a fresh deterministic set of Wingman's native tools.
@@ -37,6 +51,8 @@ func BuiltinTools() []tool.Tool {
 		tool.NewApplyPatchTool(), tool.NewBashTool(), tool.NewReadTool(),
 		tool.NewWriteTool(), tool.NewEditTool(), tool.NewGlobTool(),
 		tool.NewGrepTool(), tool.NewWebFetchTool(), tool.NewWebSearchTool(),
+		tool.NewReadArtifactTool(), tool.NewGitStatusTool(), tool.NewGitDiffTool(),
+		tool.NewGitLogTool(), tool.NewGitCommitTool(), tool.NewGitBranchTool(),
 	}
 	sort.Slice(tools, func(i, j int) bool { return tools[i].Name() < tools[j].Name() })
 	return tools
@@ -84,6 +100,9 @@ type Scope struct {
 	native    []tool.Tool
 	plugins   *pluginhost.Manager
 	mcp       *wingmcp.Manager
+	processes *process.Manager
+	symbols   *symbols.Index
+	search    *search.Index
 	cancel    context.CancelFunc
 
 	closeOnce sync.Once
@@ -191,6 +210,22 @@ func (m *Manager) Acquire(ctx context.Context, workDir string) (*Lease, error) {
 	return &Lease{manager: m, owned: owned}, nil
 }
 
+// Lookup returns workDir's scope if it is already constructed. It neither
+// constructs nor pins the scope, so callers must not rely on it staying open.
+func (m *Manager) Lookup(workDir string) (*Scope, bool) {
+	id, _, err := canonicalScope(workDir)
+	if err != nil {
+		return nil, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	owned := m.scopes[id]
+	if owned == nil || owned.scope == nil {
+		return nil, false
+	}
+	return owned.scope, true
+}
+
 // Providers returns the immutable provider generation shared by current scopes.
 func (m *Manager) Providers() *provider.Registry { return m.cfg.Providers }
 
@@ -288,7 +323,10 @@ func (m *Manager) CloseContext(ctx context.Context) error {
 }
 
 func (m *Manager) construct(ctx context.Context, cancel context.CancelFunc, id, workDir string) (*Scope, error) {
-	s := &Scope{id: id, workDir: workDir, providers: m.cfg.Providers, native: append([]tool.Tool(nil), m.cfg.NativeTools...), cancel: cancel}
+	s := &Scope{id: id, workDir: workDir, providers: m.cfg.Providers, native: append([]tool.Tool(nil), m.cfg.NativeTools...), processes: process.NewManager(), symbols: symbols.NewIndex(workDir), cancel: cancel}
+	if m.cfg.SearchModel.Ref() != "" && m.cfg.SearchStore != nil && m.cfg.Embedder != nil {
+		s.search = search.NewIndex(workDir, m.cfg.SearchStore, m.cfg.Embedder, m.cfg.SearchModel)
+	}
 	if !m.cfg.DisablePlugins {
 		dirs := append([]string(nil), m.cfg.PluginDirs...)
 		if local := pluginhost.LocalPluginDir(workDir); local != "" {
@@ -301,7 +339,7 @@ func (m *Manager) construct(ctx context.Context, cancel context.CancelFunc, id,
 		}
 		s.plugins = plugins
 	}
-	s.mcp = m.f.newMCP(ctx, wingmcp.Config{Servers: cloneMCP(m.cfg.MCP)})
+	s.mcp = m.f.newMCP(ctx, wingmcp.Config{Servers: cloneMCP(m.cfg.MCP), Tokens: m.cfg.MCPTokens})
 	if _, err := s.ToolCatalog(); err != nil {
 		_ = closeScope(s)
 		return nil, err
@@ -334,6 +372,12 @@ func (m *Manager) evict(owned *ownedScope) {
 		m.mu.Unlock()
 		return
 	}
+	// Background processes keep an idle scope alive until they exit.
+	if owned.scope.processes.Running() > 0 {
+		owned.timer = time.AfterFunc(m.cfg.IdleTimeout, func() { m.evict(owned) })
+		m.mu.Unlock()
+		return
+	}
 	delete(m.scopes, owned.scope.id)
 	m.mu.Unlock()
 	_ = closeScope(owned.scope)
@@ -363,9 +407,61 @@ func (s *Scope) Plugins() *pluginhost.Manager { return s.plugins }
 // MCP returns the scope-owned MCP manager.
 func (s *Scope) MCP() *wingmcp.Manager { return s.mcp }
 
+// Processes returns the scope-owned background process manager.
+func (s *Scope) Processes() *process.Manager { return s.processes }
+
+// Symbols returns the scope-owned symbol index of the working directory.
+func (s *Scope) Symbols() *symbols.Index { return s.symbols }
+
+// Search returns the scope-owned semantic search index, or nil when no
+// search model is configured.
+func (s *Scope) Search() *search.Index { return s.search }
+
+// Agents discovers the agent files in this scope's .wingman/agents directory.
+// Files are re-read on every call so edits apply to the next run. Agents that
+// name tools missing from the scope's catalog are reported as load errors.
+func (s *Scope) Agents() ([]*store.Agent, []frontmatter.LoadError) {
+	agents, errs := agentfile.Discover(s.workDir)
+	if len(agents) == 0 {
+		return agents, errs
+	}
+	registry, err := s.ToolCatalog()
+	if err != nil {
+		return nil, append(errs, frontmatter.LoadError{Path: agentfile.Dir(s.workDir), Error: err.Error()})
+	}
+	loaded := agents[:0]
+	for _, agent := range agents {
+		if missing := missingTool(registry, agent.Tools); missing != "" {
+			errs = append(errs, frontmatter.LoadError{Path: agent.Path, Error: fmt.Sprintf("tool %q is not available in this scope", missing)})
+			continue
+		}
+		loaded = append(loaded, agent)
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	return loaded, errs
+}
+
+func missingTool(registry *tool.Registry, names []string) string {
+	for _, name := range names {
+		if _, err := registry.Get(name); err != nil {
+			return name
+		}
+	}
+	return ""
+}
+
 // ToolCatalog composes one immutable tool catalog from current owned generations.
 func (s *Scope) ToolCatalog() (*tool.Registry, error) {
 	tools := append([]tool.Tool(nil), s.native...)
+	if s.processes != nil {
+		tools = append(tools, s.processes.Tools()...)
+	}
+	if s.symbols != nil {
+		tools = append(tools, s.symbols.Tools()...)
+	}
+	if s.search != nil {
+		tools = append(tools, s.search.Tools()...)
+	}
 	if s.plugins != nil {
 		tools = append(tools, s.plugins.Tools()...)
 	}
@@ -383,6 +479,9 @@ func (s *Scope) close(ctx context.Context) error {
 	s.closeOnce.Do(func() {
 		s.cancel()
 		var errs []error
+		if s.processes != nil {
+			errs = append(errs, s.processes.CloseContext(ctx))
+		}
 		if s.mcp != nil {
 			errs = append(errs, s.mcp.CloseContext(ctx))
 		}
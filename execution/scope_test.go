@@ -11,8 +11,12 @@ import (
 	"time"
 
 	wingmcp "github.com/chaserensberger/wingman/mcp"
+	"github.com/chaserensberger/wingman/models"
 	provider "github.com/chaserensberger/wingman/models/providers"
 	"github.com/chaserensberger/wingman/pluginhost"
+	"github.com/chaserensberger/wingman/process"
+	"github.com/chaserensberger/wingman/search"
+	"github.com/chaserensberger/wingman/symbols"
 	"github.com/chaserensberger/wingman/tool"
 )
 
@@ -115,6 +119,51 @@ func TestManagerKeepsDirectorylessScopePinned(t *testing.T) {
 	}
 }
 
+func TestRunningProcessesKeepScopeUntilClose(t *testing.T) {
+	registry, err := provider.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(Config{Providers: registry, DisablePlugins: true, IdleTimeout: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	lease, err := m.Acquire(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := lease.Scope()
+	catalog, err := scope.ToolCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := catalog.Get(process.StartToolName); err != nil {
+		t.Fatalf("process tools missing from catalog: %v", err)
+	}
+	if _, err := catalog.Get(symbols.DefinitionToolName); err != nil || scope.Symbols().Root() != scope.WorkDir() {
+		t.Fatalf("symbol tools missing from catalog: %v", err)
+	}
+	if _, err := catalog.Get(search.ToolName); err == nil || scope.Search() != nil {
+		t.Fatal("codebase search offered without a search model")
+	}
+	p, err := scope.Processes().Start(process.StartOptions{SessionID: "ses_scope", Command: "sleep 60", WorkDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = lease.Close(context.Background())
+	time.Sleep(20 * time.Millisecond)
+	if got, ok := m.Lookup(dir); !ok || got != scope {
+		t.Fatal("scope with a running process was evicted")
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Running() {
+		t.Fatal("closing the scope did not kill its process")
+	}
+}
+
 func TestManagerRejectsInvalidDirectories(t *testing.T) {
 	registry, err := provider.NewRegistry(nil)
 	if err != nil {
@@ -350,3 +399,36 @@ func TestCanceledWaiterDoesNotAbortSharedConstruction(t *testing.T) {
 	}
 	_ = second.lease.Close(context.Background())
 }
+
+func TestScopesOfferCodebaseSearchWithSearchModel(t *testing.T) {
+	registry, err := provider.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := search.OpenStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+	m, err := NewManager(Config{
+		Providers: registry, DisablePlugins: true,
+		SearchModel: models.ModelRef{Provider: "openai", ID: "text-embedding-3-small"}, SearchStore: index, Embedder: registry.NewClient(nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	lease, err := m.Acquire(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Close(context.Background())
+	scope := lease.Scope()
+	catalog, err := scope.ToolCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := catalog.Get(search.ToolName); err != nil || scope.Search().Root() != scope.WorkDir() {
+		t.Fatalf("codebase search missing from catalog: %v", err)
+	}
+}
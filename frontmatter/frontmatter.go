@@ -0,0 +1,127 @@
+// Package frontmatter parses Markdown files that open with YAML frontmatter,
+// the format of Wingman's project agent and command files.
+package frontmatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// LoadError reports a project agent or command file that could not be
+// loaded.
+type LoadError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// Parse separates data's frontmatter from its body and decodes the
+// frontmatter into v through v's JSON shape, so fields accept the same forms
+// as the HTTP API. Unknown keys are rejected. Files without an opening ---
+// line are all body.
+func Parse(data []byte, v any) (string, error) {
+	header, body, err := split(data)
+	if err != nil {
+		return "", err
+	}
+	if len(bytes.TrimSpace(header)) > 0 {
+		if err := decode(header, v); err != nil {
+			return "", err
+		}
+	}
+	return string(body), nil
+}
+
+// split separates leading "---" delimited frontmatter from the body. Files
+// without an opening delimiter are all body.
+func split(data []byte) ([]byte, []byte, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	normalized := bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(normalized, []byte("---\n")) {
+		return nil, normalized, nil
+	}
+	lines := bytes.SplitAfter(normalized[len("---\n"):], []byte("\n"))
+	for i, line := range lines {
+		if string(bytes.TrimRight(line, " \t\n")) == "---" {
+			return bytes.Join(lines[:i], nil), bytes.Join(lines[i+1:], nil), nil
+		}
+	}
+	return nil, nil, errors.New("frontmatter is missing its closing --- line")
+}
+
+// decode parses YAML and decodes it into v through v's JSON shape.
+func decode(header []byte, v any) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(header, &doc); err != nil {
+		return fmt.Errorf("parse frontmatter: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return errors.New("frontmatter must be a mapping")
+	}
+	encoded, err := nodeJSON(doc.Content[0])
+	if err != nil {
+		return fmt.Errorf("parse frontmatter: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("frontmatter: %s", strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
+
+// nodeJSON encodes a YAML node as JSON, keeping mapping keys in file order
+// because later permission rules take precedence.
+func nodeJSON(node *yaml.Node) ([]byte, error) {
+	var b bytes.Buffer
+	switch node.Kind {
+	case yaml.AliasNode:
+		return nodeJSON(node.Alias)
+	case yaml.MappingNode:
+		b.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key, err := json.Marshal(node.Content[i].Value)
+			if err != nil {
+				return nil, err
+			}
+			value, err := nodeJSON(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			b.Write(key)
+			b.WriteByte(':')
+			b.Write(value)
+		}
+		b.WriteByte('}')
+	case yaml.SequenceNode:
+		b.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			value, err := nodeJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			b.Write(value)
+		}
+		b.WriteByte(']')
+	default:
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return json.Marshal(value)
+	}
+	return b.Bytes(), nil
+}
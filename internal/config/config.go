@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -20,6 +21,9 @@ import (
 type Config struct {
 	Server           ServerConfig                       `json:"server"`
 	Plugins          PluginConfig                       `json:"plugins"`
+	Tools            ToolConfig                         `json:"tools"`
+	Runs             RunConfig                          `json:"runs"`
+	Credentials      CredentialConfig                   `json:"credentials"`
 	Permissions      permission.Ruleset                 `json:"permissions"`
 	AgentPermissions map[string]permission.Ruleset      `json:"agent_permissions"`
 	Provider         map[string]provider.ProviderConfig `json:"provider"`
@@ -41,6 +45,40 @@ type PluginConfig struct {
 	DefaultDir string   `json:"-"`
 }
 
+// ToolConfig contains daemon-wide tool execution settings.
+type ToolConfig struct {
+	// MaxOutputBytes bounds the tool output kept inline in a session
+	// transcript. Larger output is stored as a session artifact. Zero
+	// keeps all output inline.
+	MaxOutputBytes int `json:"max_output_bytes"`
+	// SearchModel is the embedding model ref codebase_search indexes
+	// working directories with, such as openai/text-embedding-3-small.
+	// Empty disables the tool.
+	SearchModel string `json:"search_model"`
+}
+
+// RunConfig bounds how many session runs the daemon executes at once. Zero
+// leaves a limit unset.
+type RunConfig struct {
+	// MaxConcurrent bounds runs across all sessions.
+	MaxConcurrent int `json:"max_concurrent"`
+	// MaxPerClient bounds the runs of any one API client.
+	MaxPerClient int `json:"max_per_client"`
+	// MaxPerProvider bounds runs by the provider of their model, keyed by
+	// provider ID.
+	MaxPerProvider map[string]int `json:"max_per_provider"`
+}
+
+// CredentialConfig selects how stored provider and MCP credentials are
+// encrypted at rest.
+type CredentialConfig struct {
+	// Encryption is none, keyring, or file.
+	Encryption string `json:"encryption"`
+	// KeyFile is the key file for file encryption. It defaults to
+	// credentials.key next to the default database.
+	KeyFile string `json:"key_file"`
+}
+
 // Default returns the default daemon configuration.
 func Default() Config {
 	return Config{
@@ -50,6 +88,8 @@ func Default() Config {
 			LogLevel:  "info",
 			LogFormat: "json",
 		},
+		Tools:       ToolConfig{MaxOutputBytes: 64 * 1024},
+		Credentials: CredentialConfig{Encryption: "none"},
 	}
 }
 
@@ -99,6 +139,31 @@ func (c Config) Validate() error {
 			return fmt.Errorf("plugins.dirs[%d] must not be empty", i)
 		}
 	}
+	if c.Tools.MaxOutputBytes < 0 {
+		return fmt.Errorf("tools.max_output_bytes must not be negative")
+	}
+	if c.Tools.SearchModel != "" {
+		if _, ok := models.ParseModelRef(c.Tools.SearchModel); !ok {
+			return fmt.Errorf("tools.search_model must be a provider/model ref")
+		}
+	}
+	if c.Runs.MaxConcurrent < 0 {
+		return fmt.Errorf("runs.max_concurrent must not be negative")
+	}
+	if c.Runs.MaxPerClient < 0 {
+		return fmt.Errorf("runs.max_per_client must not be negative")
+	}
+	if err := validateMapKeys("runs.max_per_provider", c.Runs.MaxPerProvider); err != nil {
+		return err
+	}
+	for id, limit := range c.Runs.MaxPerProvider {
+		if limit < 0 {
+			return fmt.Errorf("runs.max_per_provider.%s must not be negative", id)
+		}
+	}
+	if !oneOf(c.Credentials.Encryption, "", "none", "keyring", "file") {
+		return fmt.Errorf("credentials.encryption must be none, keyring, or file")
+	}
 	if err := validateMapKeys("agent_permissions", c.AgentPermissions); err != nil {
 		return err
 	}
@@ -122,6 +187,13 @@ func (c Config) Normalize(home string) (Config, error) {
 	if out.Server.DB, err = expandHome(dbPath, home); err != nil {
 		return Config{}, fmt.Errorf("normalize server.db: %w", err)
 	}
+	keyFile := c.Credentials.KeyFile
+	if keyFile == "" && c.Credentials.Encryption == "file" && home != "" {
+		keyFile = filepath.Join(home, ".local", "share", "wingman", "credentials.key")
+	}
+	if out.Credentials.KeyFile, err = expandHome(keyFile, home); err != nil {
+		return Config{}, fmt.Errorf("normalize credentials.key_file: %w", err)
+	}
 	out.Plugins.Dirs = make([]string, len(c.Plugins.Dirs))
 	out.Plugins.DefaultDir = DefaultPluginDir(home)
 	for i, dir := range c.Plugins.Dirs {
@@ -129,9 +201,17 @@ func (c Config) Normalize(home string) (Config, error) {
 			return Config{}, fmt.Errorf("normalize plugins.dirs[%d]: %w", i, err)
 		}
 	}
+	out.Runs.MaxPerProvider = maps.Clone(c.Runs.MaxPerProvider)
 	out.Permissions = append(permission.Ruleset(nil), c.Permissions...)
 	out.AgentPermissions = cloneAgentPermissions(c.AgentPermissions)
 	out.Provider = cloneProviders(c.Provider)
+	for id, cfg := range out.Provider {
+		if cfg.Options.Cassette != nil {
+			if cfg.Options.Cassette.Path, err = expandHome(cfg.Options.Cassette.Path, home); err != nil {
+				return Config{}, fmt.Errorf("normalize provider.%s.options.cassette.path: %w", id, err)
+			}
+		}
+	}
 	out.MCP = cloneMCP(c.MCP)
 	for name, server := range out.MCP {
 		if server.CWD != "" {
@@ -275,6 +355,14 @@ func cloneProviders(in map[string]provider.ProviderConfig) map[string]provider.P
 			}
 			value.Options.Query = query
 		}
+		if value.Options.Cassette != nil {
+			cassette := *value.Options.Cassette
+			value.Options.Cassette = &cassette
+		}
+		if value.Options.RateLimit != nil {
+			budget := *value.Options.RateLimit
+			value.Options.RateLimit = &budget
+		}
 		if value.Models != nil {
 			models := make(map[string]models.ModelInfo, len(value.Models))
 			for modelKey, model := range value.Models {
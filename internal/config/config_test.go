@@ -31,13 +31,14 @@ func TestLoad(t *testing.T) {
 			contents: `{
 				"server":{"host":"0.0.0.0","port":8080,"db":"~/wingman.db","log_level":"debug","log_format":"text"},
 				"plugins":{"dirs":["~/plugins"]},
+				"tools":{"max_output_bytes":4096,"search_model":"openai/text-embedding-3-small"},
 				"permissions":{"bash":"ask"},
 				"agent_permissions":{"research":{"read":"allow"}},
 				"provider":{"custom":{"name":"Custom","options":{"baseURL":"https://example.test","query":{"version":"1"}}}},
 				"mcp":{"filesystem":{"type":"local","command":["mcp-filesystem"],"cwd":"~/project","environment":{"HOME":"/tmp"},"discovery_timeout":1000,"execution_timeout":2000}}
 			}`,
 			check: func(t *testing.T, cfg Config) {
-				if cfg.Server.Port != 8080 {
+				if cfg.Server.Port != 8080 || cfg.Tools.MaxOutputBytes != 4096 || cfg.Tools.SearchModel != "openai/text-embedding-3-small" {
 					t.Fatalf("decoded config = %#v", cfg)
 				}
 				if got := cfg.Provider["custom"].Options.BaseURL; got != "https://example.test" {
@@ -62,6 +63,8 @@ func TestLoad(t *testing.T) {
 		{name: "invalid port", contents: `{"server":{"port":0}}`, wantErr: "server.port"},
 		{name: "invalid log level", contents: `{"server":{"log_level":"trace"}}`, wantErr: "server.log_level"},
 		{name: "invalid log format", contents: `{"server":{"log_format":"pretty"}}`, wantErr: "server.log_format"},
+		{name: "negative tool output limit", contents: `{"tools":{"max_output_bytes":-1}}`, wantErr: "tools.max_output_bytes"},
+		{name: "invalid search model", contents: `{"tools":{"search_model":"text-embedding-3-small"}}`, wantErr: "tools.search_model"},
 		{name: "empty plugin directory", contents: `{"plugins":{"dirs":[""]}}`, wantErr: "plugins.dirs[0]"},
 		{name: "empty provider key", contents: `{"provider":{"":{}}}`, wantErr: "provider has an empty key"},
 		{name: "empty agent permission key", contents: `{"agent_permissions":{" ":"allow"}}`, wantErr: "agent_permissions has an empty key"},
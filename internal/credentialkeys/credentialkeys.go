@@ -0,0 +1,203 @@
+// Package credentialkeys loads the keys that encrypt stored provider and MCP
+// credentials at rest. A keyset lives in the OS keyring or in a key file,
+// optionally wrapped with a passphrase. The newest key seals new writes;
+// older keys stay in the set so credentials sealed before a rotation still
+// open.
+package credentialkeys
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/chaserensberger/wingman/store"
+)
+
+// Source modes.
+const (
+	ModeNone    = "none"
+	ModeKeyring = "keyring"
+	ModeFile    = "file"
+)
+
+// PassphraseEnv names the passphrase that wraps a key file.
+const PassphraseEnv = "WINGMAN_CREDENTIAL_PASSPHRASE"
+
+const keySize = 32
+
+// Key is one AES-256 credential key.
+type Key struct {
+	ID        string    `json:"id"`
+	Secret    []byte    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Keyset is an ordered set of keys, newest first.
+type Keyset struct {
+	Keys []Key `json:"keys"`
+}
+
+func (k *Keyset) validate() error {
+	if len(k.Keys) == 0 {
+		return errors.New("credential keyset is empty")
+	}
+	seen := map[string]bool{}
+	for _, key := range k.Keys {
+		if key.ID == "" || len(key.Secret) != keySize {
+			return fmt.Errorf("credential key %q is invalid", key.ID)
+		}
+		if seen[key.ID] {
+			return fmt.Errorf("credential key %q is duplicated", key.ID)
+		}
+		seen[key.ID] = true
+	}
+	return nil
+}
+
+// Backend persists a keyset.
+type Backend interface {
+	// Load returns the stored keyset, or an error wrapping os.ErrNotExist
+	// when there is none yet.
+	Load() (*Keyset, error)
+	Save(*Keyset) error
+	// Describe names the backend for messages, such as the key file path.
+	Describe() string
+}
+
+// Keys serves a backend's keyset as store.CredentialKeys. Lookups of an
+// unknown key ID reload the keyset, so a daemon opens credentials resealed
+// by `wingman auth rotate-key` without a restart.
+type Keys struct {
+	backend Backend
+
+	mu     sync.Mutex
+	keyset *Keyset
+}
+
+var _ store.CredentialKeys = (*Keys)(nil)
+
+// Open loads the backend's keyset. It fails with an error wrapping
+// os.ErrNotExist when the backend has none; only Create makes one, so a
+// missing keyset is never silently replaced.
+func Open(backend Backend) (*Keys, error) {
+	keyset, err := backend.Load()
+	if err != nil {
+		return nil, fmt.Errorf("load credential keys from %s: %w", backend.Describe(), err)
+	}
+	if err := keyset.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", backend.Describe(), err)
+	}
+	return &Keys{backend: backend, keyset: keyset}, nil
+}
+
+// Create saves a keyset with one fresh key to backend, replacing any keyset
+// it holds.
+func Create(backend Backend) (*Keys, error) {
+	keyset := &Keyset{}
+	if _, err := keyset.add(); err != nil {
+		return nil, err
+	}
+	if err := backend.Save(keyset); err != nil {
+		return nil, fmt.Errorf("save credential keys to %s: %w", backend.Describe(), err)
+	}
+	return &Keys{backend: backend, keyset: keyset}, nil
+}
+
+// Save writes the keyset back to its backend. A plain key file loaded with
+// a passphrase is wrapped with it.
+func (k *Keys) Save() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err := k.backend.Save(k.keyset); err != nil {
+		return fmt.Errorf("save credential keys to %s: %w", k.backend.Describe(), err)
+	}
+	return nil
+}
+
+// Current returns the newest key.
+func (k *Keys) Current() (string, []byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key := k.keyset.Keys[0]
+	return key.ID, key.Secret, nil
+}
+
+// Lookup returns the key with id.
+func (k *Keys) Lookup(id string) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if secret, ok := k.find(id); ok {
+		return secret, nil
+	}
+	if keyset, err := k.backend.Load(); err == nil && keyset.validate() == nil {
+		k.keyset = keyset
+		if secret, ok := k.find(id); ok {
+			return secret, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s in %s", store.ErrCredentialKeyNotFound, id, k.backend.Describe())
+}
+
+func (k *Keys) find(id string) ([]byte, bool) {
+	for _, key := range k.keyset.Keys {
+		if key.ID == id {
+			return key.Secret, true
+		}
+	}
+	return nil, false
+}
+
+// Rotate adds a new current key and saves the keyset. Older keys remain
+// for reading until the stored credentials are resealed.
+func (k *Keys) Rotate() (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	keyset := &Keyset{Keys: append([]Key(nil), k.keyset.Keys...)}
+	id, err := keyset.add()
+	if err != nil {
+		return "", err
+	}
+	if err := k.backend.Save(keyset); err != nil {
+		return "", fmt.Errorf("save credential keys to %s: %w", k.backend.Describe(), err)
+	}
+	k.keyset = keyset
+	return id, nil
+}
+
+// add prepends a random key and returns its ID.
+func (k *Keyset) add() (string, error) {
+	secret := make([]byte, keySize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	key := Key{ID: now.Format("20060102") + "-" + hex.EncodeToString(suffix), Secret: secret, CreatedAt: now}
+	k.Keys = append([]Key{key}, k.Keys...)
+	return key.ID, nil
+}
+
+// NewBackend returns the backend for a config mode, or nil for ModeNone. A
+// key file is wrapped with the passphrase in PassphraseEnv when it is set.
+func NewBackend(mode, keyFile string) (Backend, error) {
+	switch mode {
+	case "", ModeNone:
+		return nil, nil
+	case ModeKeyring:
+		return Keyring{}, nil
+	case ModeFile:
+		if keyFile == "" {
+			return nil, errors.New("credential key file path is required")
+		}
+		return File{Path: keyFile, Passphrase: os.Getenv(PassphraseEnv)}, nil
+	default:
+		return nil, fmt.Errorf("unknown credential encryption %q", mode)
+	}
+}
@@ -0,0 +1,177 @@
+package credentialkeys
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/chaserensberger/wingman/store"
+)
+
+func TestFileKeysWrapWithPassphraseAndRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.key")
+	if _, err := Open(File{Path: path}); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("open missing key file: error = %v", err)
+	}
+	plain, err := Create(File{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, secret, _ := plain.Current()
+
+	wrapped := File{Path: path, Passphrase: "correct horse"}
+	keys, err := Open(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, got, _ := keys.Current(); id != first || string(got) != string(secret) {
+		t.Fatalf("current key with passphrase = %q", id)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), kdfPBKDF2SHA256) {
+		t.Fatalf("loading wrapped the key file:\n%s", data)
+	}
+	if err := keys.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), base64.StdEncoding.EncodeToString(secret)) || !strings.Contains(string(data), kdfPBKDF2SHA256) {
+		t.Fatalf("key file was not wrapped:\n%s", data)
+	}
+	if runtime.GOOS != "windows" {
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != keyFileMode {
+			t.Fatalf("key file mode = %v, error = %v", info.Mode().Perm(), err)
+		}
+	}
+	if _, err := Open(File{Path: path, Passphrase: "wrong"}); err == nil {
+		t.Fatal("opened a wrapped key file with the wrong passphrase")
+	}
+	if _, err := Open(File{Path: path}); err == nil || !strings.Contains(err.Error(), PassphraseEnv) {
+		t.Fatalf("open without passphrase: error = %v", err)
+	}
+
+	stale, err := Open(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := keys.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _, _ := keys.Current(); id != second || second == first {
+		t.Fatalf("current key after rotate = %q, previous %q", id, first)
+	}
+	if _, err := keys.Lookup(first); err != nil {
+		t.Fatalf("previous key after rotate: %v", err)
+	}
+	if _, err := stale.Lookup(second); err != nil {
+		t.Fatalf("stale keys did not reload the rotated keyset: %v", err)
+	}
+	if _, err := stale.Lookup("missing"); !errors.Is(err, store.ErrCredentialKeyNotFound) {
+		t.Fatalf("unknown key: error = %v", err)
+	}
+}
+
+func TestKeyringStoresKeysetWithSystemTool(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake keyring tools exit through sh")
+	}
+	originalOS, originalRun := keyringOS, runCommand
+	t.Cleanup(func() { keyringOS, runCommand = originalOS, originalRun })
+	for _, goos := range []string{"linux", "darwin"} {
+		t.Run(goos, func(t *testing.T) {
+			keyringOS = goos
+			var stored string
+			var argv []string
+			runCommand = func(name string, stdin []byte, args ...string) ([]byte, []byte, error) {
+				argv = append(argv, args...)
+				switch {
+				case name == "secret-tool" && args[0] == "store":
+					stored = string(stdin)
+				case name == "security" && args[0] == "-i":
+					fields := strings.Fields(string(stdin))
+					if fields[0] != "add-generic-password" || fields[len(fields)-2] != "-w" {
+						t.Fatalf("security command = %q", stdin)
+					}
+					stored = fields[len(fields)-1]
+				case stored == "":
+					code := "exit 1"
+					if name == "security" {
+						code = "exit 44"
+					}
+					return nil, nil, exec.Command("sh", "-c", code).Run()
+				default:
+					return []byte(stored + "\n"), nil, nil
+				}
+				return nil, nil, nil
+			}
+
+			if _, err := Open(Keyring{}); !errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("open empty keyring: error = %v", err)
+			}
+			keys, err := Create(Keyring{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored == "" {
+				t.Fatal("a new keyset was not saved to the keyring")
+			}
+			if slices.Contains(argv, stored) {
+				t.Fatalf("keyset passed on the command line: %q", argv)
+			}
+			id, _, _ := keys.Current()
+			reopened, err := Open(Keyring{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _, _ := reopened.Current(); got != id {
+				t.Fatalf("reopened current key = %q, want %q", got, id)
+			}
+		})
+	}
+}
+
+func TestKeyringLookupFailureIsNotAMissingEntry(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake keyring tool exits through sh")
+	}
+	originalOS, originalRun := keyringOS, runCommand
+	t.Cleanup(func() { keyringOS, runCommand = originalOS, originalRun })
+	keyringOS = "linux"
+	runCommand = func(string, []byte, ...string) ([]byte, []byte, error) {
+		return nil, []byte("Cannot autolaunch D-Bus without X11 $DISPLAY\n"), exec.Command("sh", "-c", "exit 1").Run()
+	}
+	_, err := Keyring{}.Load()
+	if err == nil || errors.Is(err, os.ErrNotExist) || !strings.Contains(err.Error(), "D-Bus") {
+		t.Fatalf("load error = %v, want the tool's failure", err)
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	t.Setenv(PassphraseEnv, "secret")
+	if backend, err := NewBackend(ModeNone, ""); backend != nil || err != nil {
+		t.Fatalf("none = %v, %v", backend, err)
+	}
+	backend, err := NewBackend(ModeFile, "/tmp/keys")
+	if file, ok := backend.(File); err != nil || !ok || file.Passphrase != "secret" {
+		t.Fatalf("file = %#v, %v", backend, err)
+	}
+	if _, err := NewBackend(ModeFile, ""); err == nil {
+		t.Fatal("file mode without a path")
+	}
+	if _, err := NewBackend("vault", ""); err == nil {
+		t.Fatal("unknown mode")
+	}
+}
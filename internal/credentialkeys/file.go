@@ -0,0 +1,138 @@
+package credentialkeys
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	kdfPBKDF2SHA256 = "pbkdf2-sha256"
+	kdfIterations   = 600000
+	keyFileMode     = 0o600
+)
+
+// File stores a keyset in a key file. With a passphrase, the keyset is
+// encrypted with a key derived from it. Loading a plain key file with a
+// passphrase leaves it plain until the keyset is saved.
+type File struct {
+	Path       string
+	Passphrase string
+}
+
+// keyFile is the on-disk form. Plain files set Keys; wrapped files set the
+// KDF fields and Sealed.
+type keyFile struct {
+	Keys       []Key  `json:"keys,omitempty"`
+	KDF        string `json:"kdf,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       []byte `json:"salt,omitempty"`
+	Sealed     []byte `json:"sealed,omitempty"`
+}
+
+func (f File) Describe() string { return "key file " + f.Path }
+
+func (f File) Load() (*Keyset, error) {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	if file.KDF == "" {
+		return &Keyset{Keys: file.Keys}, nil
+	}
+	if file.KDF != kdfPBKDF2SHA256 {
+		return nil, fmt.Errorf("unsupported kdf %q", file.KDF)
+	}
+	if f.Passphrase == "" {
+		return nil, fmt.Errorf("key file is passphrase protected; set %s", PassphraseEnv)
+	}
+	aead, err := passphraseAEAD(f.Passphrase, file.Salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Sealed) < aead.NonceSize() {
+		return nil, errors.New("malformed sealed keyset")
+	}
+	plain, err := aead.Open(nil, file.Sealed[:aead.NonceSize()], file.Sealed[aead.NonceSize():], []byte(kdfPBKDF2SHA256))
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupt key file")
+	}
+	var keyset Keyset
+	if err := json.Unmarshal(plain, &keyset); err != nil {
+		return nil, fmt.Errorf("decode keyset: %w", err)
+	}
+	return &keyset, nil
+}
+
+func (f File) Save(keyset *Keyset) error {
+	file := keyFile{Keys: keyset.Keys}
+	if f.Passphrase != "" {
+		plain, err := json.Marshal(keyset)
+		if err != nil {
+			return err
+		}
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		aead, err := passphraseAEAD(f.Passphrase, salt, kdfIterations)
+		if err != nil {
+			return err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return err
+		}
+		file = keyFile{KDF: kdfPBKDF2SHA256, Iterations: kdfIterations, Salt: salt, Sealed: aead.Seal(nonce, nonce, plain, []byte(kdfPBKDF2SHA256))}
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), filepath.Base(f.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(keyFileMode); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+func passphraseAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	if len(salt) == 0 || iterations <= 0 {
+		return nil, errors.New("malformed key file")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
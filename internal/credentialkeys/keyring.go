@@ -0,0 +1,134 @@
+package credentialkeys
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const (
+	keyringService = "wingman"
+	keyringAccount = "credential-keys"
+)
+
+// Keyring stores a keyset in the OS keyring: the Secret Service through
+// secret-tool on Linux, and the login keychain through security on macOS.
+type Keyring struct{}
+
+// keyringOS selects the keyring tool. Tests replace it.
+var keyringOS = runtime.GOOS
+
+// runCommand runs a keyring tool and returns its stdout and stderr. Tests
+// replace it.
+var runCommand = func(name string, stdin []byte, args ...string) ([]byte, []byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	return out, stderr.Bytes(), err
+}
+
+// runKeyringTool runs a keyring tool and adds its stderr to a failure.
+func runKeyringTool(name string, stdin []byte, args ...string) ([]byte, error) {
+	out, stderr, err := runCommand(name, stdin, args...)
+	return out, withStderr(err, stderr)
+}
+
+func withStderr(err error, stderr []byte) error {
+	if message := strings.TrimSpace(string(stderr)); err != nil && message != "" {
+		return fmt.Errorf("%w: %s", err, message)
+	}
+	return err
+}
+
+func (Keyring) Describe() string { return "OS keyring" }
+
+func (Keyring) Load() (*Keyset, error) {
+	var out []byte
+	var err error
+	switch keyringOS {
+	case "linux":
+		var stderr []byte
+		out, stderr, err = runCommand("secret-tool", nil, "lookup", "service", keyringService, "account", keyringAccount)
+		// secret-tool exits 1 silently when nothing matches. Anything on
+		// stderr, such as a locked or unreachable Secret Service, is a
+		// failure rather than a missing entry.
+		if exitCode(err) == 1 && len(bytes.TrimSpace(out)) == 0 && len(bytes.TrimSpace(stderr)) == 0 {
+			return nil, os.ErrNotExist
+		}
+		err = withStderr(err, stderr)
+	case "darwin":
+		out, err = runKeyringTool("security", nil, "find-generic-password", "-s", keyringService, "-a", keyringAccount, "-w")
+		// errSecItemNotFound.
+		if exitCode(err) == 44 {
+			return nil, os.ErrNotExist
+		}
+	default:
+		return nil, errUnsupportedKeyring
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+	if err != nil {
+		return nil, fmt.Errorf("decode keyring entry: %w", err)
+	}
+	var keyset Keyset
+	if err := json.Unmarshal(data, &keyset); err != nil {
+		return nil, fmt.Errorf("decode keyring entry: %w", err)
+	}
+	return &keyset, nil
+}
+
+func (Keyring) Save(keyset *Keyset) error {
+	data, err := json.Marshal(keyset)
+	if err != nil {
+		return err
+	}
+	value := base64.StdEncoding.EncodeToString(data)
+	switch keyringOS {
+	case "linux":
+		_, err = runKeyringTool("secret-tool", []byte(value), "store", "--label=Wingman credential keys", "service", keyringService, "account", keyringAccount)
+	case "darwin":
+		return saveKeychain(value)
+	default:
+		return errUnsupportedKeyring
+	}
+	return err
+}
+
+// saveKeychain stores value in the login keychain. security reads the
+// command from stdin in interactive mode, which keeps the keyset out of the
+// process list. Interactive mode does not reliably report a failed command
+// in its exit status, so the entry is read back to confirm it.
+func saveKeychain(value string) error {
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", keyringService, keyringAccount, value)
+	if _, err := runKeyringTool("security", []byte(command), "-i"); err != nil {
+		return err
+	}
+	out, err := runKeyringTool("security", nil, "find-generic-password", "-s", keyringService, "-a", keyringAccount, "-w")
+	if err != nil {
+		return fmt.Errorf("confirm keyring entry: %w", err)
+	}
+	if strings.TrimSpace(string(out)) != value {
+		return errors.New("confirm keyring entry: the login keychain did not store the keyset")
+	}
+	return nil
+}
+
+var errUnsupportedKeyring = errors.New("the OS keyring is supported on Linux and macOS only; use a key file")
+
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return 0
+}
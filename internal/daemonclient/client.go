@@ -165,6 +165,38 @@ func (c *Client) Do(ctx context.Context, method, path string, body io.Reader, he
 	return response, nil
 }
 
+// HTTPClient returns an HTTP client for protocols layered on the daemon API,
+// such as MCP. Requests are authenticated, carry headers, and may only target
+// the managed daemon.
+func (c *Client) HTTPClient(headers http.Header) *http.Client {
+	base := c.httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &http.Client{
+		Transport:     &daemonTransport{client: c, headers: headers.Clone(), base: base},
+		CheckRedirect: c.httpClient.CheckRedirect,
+	}
+}
+
+type daemonTransport struct {
+	client  *Client
+	headers http.Header
+	base    http.RoundTripper
+}
+
+func (t *daemonTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.URL.Host != t.client.baseURL.Host || request.URL.Scheme != t.client.baseURL.Scheme {
+		return nil, errors.New("daemon request must target the managed daemon")
+	}
+	request = request.Clone(request.Context())
+	for name, values := range t.headers {
+		request.Header[name] = append([]string(nil), values...)
+	}
+	request.SetBasicAuth(t.client.username, t.client.password)
+	return t.base.RoundTrip(request)
+}
+
 func unavailableError(result Result) error {
 	switch result.Status {
 	case StatusMissing:
@@ -255,3 +255,31 @@ func TestInspectDoesNotFollowRedirects(t *testing.T) {
 		t.Fatalf("redirect target requests = %d", redirected.Load())
 	}
 }
+
+func TestClientHTTPClientAuthenticatesAndStaysOnDaemon(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "wingman" || password != "root-password" || r.Header.Get("X-Wingman-Client") != "cli_editor" {
+			t.Errorf("basic auth = %q, %q, %t, client = %q", username, password, ok, r.Header.Get("X-Wingman-Client"))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	baseURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{baseURL: baseURL, username: "wingman", password: "root-password", httpClient: server.Client()}
+	httpClient := client.HTTPClient(http.Header{"X-Wingman-Client": {"cli_editor"}})
+	response, err := httpClient.Get(server.URL + "/mcp-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNoContent {
+		t.Fatalf("status = %d", response.StatusCode)
+	}
+	if _, err := httpClient.Get("http://example.invalid/mcp-server"); err == nil {
+		t.Fatal("request to another origin succeeded")
+	}
+}
@@ -11,6 +11,10 @@ import (
 // Config is the daemon-level MCP configuration loaded from wingman.json.
 type Config struct {
 	Servers map[string]ServerConfig `json:"servers,omitempty"`
+
+	// Tokens supplies stored OAuth tokens for remote servers. It is nil when
+	// the daemon has no durable credential store.
+	Tokens TokenStore `json:"-"`
 }
 
 // ServerConfig declares one MCP server. Local servers run over stdio; remote
@@ -35,7 +39,7 @@ func (c Config) normalized() Config {
 }
 
 func cloneConfig(c Config) Config {
-	out := Config{Servers: make(map[string]ServerConfig, len(c.Servers))}
+	out := Config{Servers: make(map[string]ServerConfig, len(c.Servers)), Tokens: c.Tokens}
 	for name, server := range c.Servers {
 		server.Command = append([]string(nil), server.Command...)
 		server.Environment = cloneStringMap(server.Environment)
@@ -25,6 +25,13 @@ const retirementTimeout = 5 * time.Second
 
 var sanitizeRE = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
 
+// ErrServerNotFound reports a server name absent from the configuration.
+var ErrServerNotFound = errors.New("MCP server not found")
+
+// ErrServerUnavailable reports a configured server that is disconnected or
+// lacks the requested capability.
+var ErrServerUnavailable = errors.New("MCP server unavailable")
+
 // Status is the runtime state of one configured MCP server.
 type Status struct {
 	Name      string   `json:"name"`
@@ -56,6 +63,11 @@ type Manager struct {
 	servers     map[string]*serverState
 	closed      bool
 	connect     connector
+
+	subscriptions map[*resourceSubscription]struct{}
+	// subscribeLocks serialize each server's subscribe and unsubscribe
+	// requests with the local subscriber count they depend on.
+	subscribeLocks map[string]*sync.Mutex
 }
 
 type serverState struct {
@@ -74,8 +86,9 @@ type connection interface {
 type connector func(context.Context, string, ServerConfig) (connection, []*mcpsdk.Tool, error)
 
 type connectionGeneration struct {
-	connection connection
-	tools      []*mcpsdk.Tool
+	connection   connection
+	tools        []*mcpsdk.Tool
+	capabilities *mcpsdk.ServerCapabilities
 
 	mu               sync.Mutex
 	accepting        bool
@@ -85,13 +98,16 @@ type connectionGeneration struct {
 
 // New creates a manager and connects all enabled configured servers.
 func New(ctx context.Context, cfg Config) *Manager {
-	m := newManager(cfg, connectServer)
+	m := newManager(cfg, nil)
+	m.connect = func(ctx context.Context, name string, cfg ServerConfig) (connection, []*mcpsdk.Tool, error) {
+		return connectServer(ctx, name, cfg, m.cfg.Tokens, m.publishResourceUpdate)
+	}
 	m.ConnectEnabled(ctx)
 	return m
 }
 
 func newManager(cfg Config, connect connector) *Manager {
-	m := &Manager{cfg: cloneConfig(cfg).normalized(), servers: map[string]*serverState{}, connect: connect}
+	m := &Manager{cfg: cloneConfig(cfg).normalized(), servers: map[string]*serverState{}, connect: connect, subscriptions: map[*resourceSubscription]struct{}{}, subscribeLocks: map[string]*sync.Mutex{}}
 	for name, serverCfg := range m.cfg.Servers {
 		m.servers[name] = &serverState{name: name, cfg: serverCfg, status: "disabled"}
 	}
@@ -140,6 +156,7 @@ func (m *Manager) CloseContext(ctx context.Context) error {
 		state.status = "disabled"
 		state.err = ""
 	}
+	m.closeSubscriptionsLocked("")
 	m.mu.Unlock()
 
 	return retireAll(ctx, retired)
@@ -191,12 +208,18 @@ func (m *Manager) Tools() []tool.Tool {
 			out = append(out, &mcpTool{generation: state.generation, server: state.name, remoteName: def.Name, def: def})
 		}
 	}
+	if servers := m.resourceServersLocked(); len(servers) > 0 {
+		out = append(out, &resourceTool{manager: m, servers: servers})
+	}
 	sort.Slice(out, func(i, j int) bool {
 		if out[i].Name() != out[j].Name() {
 			return out[i].Name() < out[j].Name()
 		}
-		left := out[i].(*mcpTool)
-		right := out[j].(*mcpTool)
+		left, lok := out[i].(*mcpTool)
+		right, rok := out[j].(*mcpTool)
+		if !lok || !rok {
+			return !lok
+		}
 		if left.server != right.server {
 			return left.server < right.server
 		}
@@ -249,7 +272,7 @@ func (m *Manager) Connect(ctx context.Context, name string) error {
 	closed := m.closed
 	m.mu.RUnlock()
 	if state == nil {
-		return fmt.Errorf("MCP server not found: %s", name)
+		return fmt.Errorf("%w: %s", ErrServerNotFound, name)
 	}
 	if closed {
 		return errors.New("MCP manager is closed")
@@ -272,7 +295,7 @@ func (m *Manager) Connect(ctx context.Context, name string) error {
 		if conn != nil {
 			_ = conn.Close()
 		}
-		return fmt.Errorf("MCP server not found: %s", name)
+		return fmt.Errorf("%w: %s", ErrServerNotFound, name)
 	}
 	if err != nil {
 		if state.generation == nil {
@@ -285,7 +308,7 @@ func (m *Manager) Connect(ctx context.Context, name string) error {
 		return err
 	}
 
-	newGeneration := &connectionGeneration{connection: conn, tools: tools, executionTimeout: executionTimeout(state.cfg), accepting: true}
+	newGeneration := &connectionGeneration{connection: conn, tools: tools, capabilities: connectionCapabilities(conn), executionTimeout: executionTimeout(state.cfg), accepting: true}
 	oldGeneration := state.generation
 	if oldGeneration != nil {
 		retireLocked(oldGeneration)
@@ -295,6 +318,13 @@ func (m *Manager) Connect(ctx context.Context, name string) error {
 	state.err = ""
 	m.mu.Unlock()
 
+	if err := m.resubscribe(ctx, name, newGeneration); err != nil {
+		m.mu.Lock()
+		if state.generation == newGeneration {
+			state.err = err.Error()
+		}
+		m.mu.Unlock()
+	}
 	return retireWithTimeout(oldGeneration)
 }
 
@@ -310,7 +340,7 @@ func (m *Manager) disconnectLocked(name string) error {
 	state := m.servers[name]
 	if state == nil {
 		m.mu.Unlock()
-		return fmt.Errorf("MCP server not found: %s", name)
+		return fmt.Errorf("%w: %s", ErrServerNotFound, name)
 	}
 	oldGeneration := state.generation
 	if oldGeneration != nil {
@@ -319,19 +349,29 @@ func (m *Manager) disconnectLocked(name string) error {
 	state.generation = nil
 	state.status = "disabled"
 	state.err = ""
+	m.closeSubscriptionsLocked(name)
 	m.mu.Unlock()
 	return retireWithTimeout(oldGeneration)
 }
 
-func (g *connectionGeneration) callTool(ctx context.Context, remoteName string, args map[string]any) (*mcpsdk.CallToolResult, error) {
+// begin registers one in-flight request so retirement waits for it. The
+// returned func must be called when the request finishes.
+func (g *connectionGeneration) begin() (func(), error) {
 	g.mu.Lock()
+	defer g.mu.Unlock()
 	if !g.accepting {
-		g.mu.Unlock()
 		return nil, errors.New("MCP connection has been retired")
 	}
 	g.calls.Add(1)
-	g.mu.Unlock()
-	defer g.calls.Done()
+	return g.calls.Done, nil
+}
+
+func (g *connectionGeneration) callTool(ctx context.Context, remoteName string, args map[string]any) (*mcpsdk.CallToolResult, error) {
+	done, err := g.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
 	ctx, cancel := context.WithTimeout(ctx, g.executionTimeout)
 	defer cancel()
 	return g.connection.CallTool(ctx, &mcpsdk.CallToolParams{Name: remoteName, Arguments: args})
@@ -378,23 +418,34 @@ func retireAll(ctx context.Context, generations []*connectionGeneration) error {
 	return errors.Join(joined...)
 }
 
-func connectServer(ctx context.Context, name string, cfg ServerConfig) (connection, []*mcpsdk.Tool, error) {
+func connectServer(ctx context.Context, name string, cfg ServerConfig, tokens TokenStore, onUpdate func(server, uri string)) (connection, []*mcpsdk.Tool, error) {
 	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout(cfg))
 	defer cancel()
-	client := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "wingman", Version: "dev"}, &mcpsdk.ClientOptions{Capabilities: &mcpsdk.ClientCapabilities{}})
-
-	transport, err := transportFor(cfg)
+	opts := clientOptions(name, onUpdate)
+	client := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "wingman", Version: "dev"}, opts)
+
+	var remote *http.Client
+	if cfg.Type == "remote" {
+		var err error
+		if remote, err = remoteHTTPClient(ctx, name, cfg, tokens); err != nil {
+			return nil, nil, fmt.Errorf("connect %s: %w", name, err)
+		}
+	}
+	transport, err := transportFor(cfg, remote)
 	if err != nil {
 		return nil, nil, err
 	}
 	session, err := client.Connect(ctx, transport, nil)
 	if err != nil && cfg.Type == "remote" {
-		session, err = connectSSE(ctx, cfg)
+		session, err = connectSSE(ctx, cfg, remote, opts)
 	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("connect %s: %w", name, err)
 	}
-	tools, err := listTools(ctx, session)
+	var tools []*mcpsdk.Tool
+	if res := session.InitializeResult(); res == nil || res.Capabilities == nil || res.Capabilities.Tools != nil {
+		tools, err = listTools(ctx, session)
+	}
 	if err != nil {
 		_ = session.Close()
 		return nil, nil, err
@@ -402,12 +453,24 @@ func connectServer(ctx context.Context, name string, cfg ServerConfig) (connecti
 	return session, tools, nil
 }
 
-func connectSSE(ctx context.Context, cfg ServerConfig) (*mcpsdk.ClientSession, error) {
-	client := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "wingman", Version: "dev"}, &mcpsdk.ClientOptions{Capabilities: &mcpsdk.ClientCapabilities{}})
-	return client.Connect(ctx, &mcpsdk.SSEClientTransport{Endpoint: cfg.URL, HTTPClient: httpClient(cfg.Headers)}, nil)
+func clientOptions(name string, onUpdate func(server, uri string)) *mcpsdk.ClientOptions {
+	opts := &mcpsdk.ClientOptions{Capabilities: &mcpsdk.ClientCapabilities{}}
+	if onUpdate != nil {
+		opts.ResourceUpdatedHandler = func(_ context.Context, req *mcpsdk.ResourceUpdatedNotificationRequest) {
+			if req != nil && req.Params != nil {
+				onUpdate(name, req.Params.URI)
+			}
+		}
+	}
+	return opts
+}
+
+func connectSSE(ctx context.Context, cfg ServerConfig, remote *http.Client, opts *mcpsdk.ClientOptions) (*mcpsdk.ClientSession, error) {
+	client := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "wingman", Version: "dev"}, opts)
+	return client.Connect(ctx, &mcpsdk.SSEClientTransport{Endpoint: cfg.URL, HTTPClient: remote}, nil)
 }
 
-func transportFor(cfg ServerConfig) (mcpsdk.Transport, error) {
+func transportFor(cfg ServerConfig, remote *http.Client) (mcpsdk.Transport, error) {
 	switch cfg.Type {
 	case "local":
 		if len(cfg.Command) == 0 || cfg.Command[0] == "" {
@@ -426,32 +489,20 @@ func transportFor(cfg ServerConfig) (mcpsdk.Transport, error) {
 		if cfg.URL == "" {
 			return nil, fmt.Errorf("remote MCP server url is required")
 		}
-		return &mcpsdk.StreamableClientTransport{Endpoint: cfg.URL, HTTPClient: httpClient(cfg.Headers)}, nil
+		return &mcpsdk.StreamableClientTransport{Endpoint: cfg.URL, HTTPClient: remote}, nil
 	default:
 		return nil, fmt.Errorf("unsupported MCP server type %q", cfg.Type)
 	}
 }
 
 func listTools(ctx context.Context, session *mcpsdk.ClientSession) ([]*mcpsdk.Tool, error) {
-	var out []*mcpsdk.Tool
-	var cursor string
-	seen := map[string]struct{}{}
-	for page := 0; page < 1000; page++ {
+	return listPages(ctx, "tools", func(cursor string) ([]*mcpsdk.Tool, string, error) {
 		res, err := session.ListTools(ctx, &mcpsdk.ListToolsParams{Cursor: cursor})
 		if err != nil {
-			return nil, fmt.Errorf("list MCP tools: %w", err)
-		}
-		out = append(out, res.Tools...)
-		if res.NextCursor == "" {
-			return out, nil
+			return nil, "", err
 		}
-		if _, ok := seen[res.NextCursor]; ok {
-			return nil, fmt.Errorf("list MCP tools returned duplicate cursor %q", res.NextCursor)
-		}
-		seen[res.NextCursor] = struct{}{}
-		cursor = res.NextCursor
-	}
-	return nil, fmt.Errorf("list MCP tools exceeded page limit")
+		return res.Tools, res.NextCursor, nil
+	})
 }
 
 func discoveryTimeout(cfg ServerConfig) time.Duration {
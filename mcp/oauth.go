@@ -0,0 +1,401 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/modelcontextprotocol/go-sdk/oauthex"
+)
+
+// tokenRefreshSkew refreshes access tokens shortly before they expire so a
+// request never leaves with a token that lapses in flight.
+const tokenRefreshSkew = 30 * time.Second
+
+// oauthTimeout bounds each discovery, registration and token request so an
+// unresponsive authorization server cannot stall a connect indefinitely.
+const oauthTimeout = 30 * time.Second
+
+// oauthClient is used for authorization server requests, which carry no
+// server-configured headers.
+var oauthClient = &http.Client{Timeout: oauthTimeout}
+
+// refreshLocks serializes refreshes of one server's token across managers:
+// every execution scope has its own manager, and rotating refresh tokens may
+// only be spent once. Servers refresh independently of each other.
+var (
+	refreshLocksMu sync.Mutex
+	refreshLocks   = map[string]*sync.Mutex{}
+)
+
+func refreshLock(server string) *sync.Mutex {
+	refreshLocksMu.Lock()
+	defer refreshLocksMu.Unlock()
+	lock := refreshLocks[server]
+	if lock == nil {
+		lock = &sync.Mutex{}
+		refreshLocks[server] = lock
+	}
+	return lock
+}
+
+// ErrServerNotRemote reports an OAuth request for a local stdio server.
+var ErrServerNotRemote = errors.New("MCP server is not remote")
+
+// Token is the stored OAuth credential for one remote server, including the
+// registered client and token endpoint needed to refresh it.
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    int64
+	ClientID     string
+	ClientSecret string
+	TokenURL     string
+	Resource     string
+	Scope        string
+}
+
+func (t Token) fresh() bool {
+	return t.AccessToken != "" && (t.ExpiresAt == 0 || time.Now().Add(tokenRefreshSkew).Unix() < t.ExpiresAt)
+}
+
+// TokenStore persists OAuth tokens for remote servers by server name.
+type TokenStore interface {
+	LoadToken(server string) (Token, bool, error)
+	SaveToken(server string, token Token) error
+}
+
+// Authorization is the discovered OAuth configuration of a remote server
+// together with the client Wingman registered for one loopback redirect URI.
+type Authorization struct {
+	Resource              string
+	AuthorizationEndpoint string
+	TokenEndpoint         string
+	Scopes                []string
+	ClientID              string
+	ClientSecret          string
+	RedirectURI           string
+}
+
+// Authorize discovers the authorization server protecting the named remote
+// server from its protected-resource metadata and dynamically registers
+// Wingman as a public client that redirects to redirectURI.
+func (m *Manager) Authorize(ctx context.Context, name, redirectURI string) (*Authorization, error) {
+	m.mu.RLock()
+	state := m.servers[name]
+	m.mu.RUnlock()
+	if state == nil {
+		return nil, fmt.Errorf("%w: %s", ErrServerNotFound, name)
+	}
+	if state.cfg.Type != "remote" {
+		return nil, fmt.Errorf("%w: %s", ErrServerNotRemote, name)
+	}
+	return discoverAuthorization(ctx, state.cfg, redirectURI)
+}
+
+func discoverAuthorization(ctx context.Context, cfg ServerConfig, redirectURI string) (*Authorization, error) {
+	challenges := probeChallenges(ctx, cfg)
+	prm, err := protectedResourceMetadata(ctx, challenges, cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	issuer := prm.AuthorizationServers[0]
+	asm, err := auth.GetAuthServerMetadata(ctx, issuer, oauthClient)
+	if err != nil {
+		return nil, fmt.Errorf("discover authorization server metadata: %w", err)
+	}
+	if asm == nil {
+		// Servers predating metadata discovery serve the default endpoints.
+		asm = &oauthex.AuthServerMeta{
+			Issuer:                issuer,
+			AuthorizationEndpoint: issuer + "/authorize",
+			TokenEndpoint:         issuer + "/token",
+			RegistrationEndpoint:  issuer + "/register",
+		}
+	}
+	if asm.RegistrationEndpoint == "" {
+		return nil, fmt.Errorf("authorization server %s does not support dynamic client registration", issuer)
+	}
+	scopes := challengeScopes(challenges)
+	if len(scopes) == 0 {
+		scopes = prm.ScopesSupported
+	}
+	registration, err := oauthex.RegisterClient(ctx, asm.RegistrationEndpoint, &oauthex.ClientRegistrationMetadata{
+		RedirectURIs:            []string{redirectURI},
+		TokenEndpointAuthMethod: "none",
+		GrantTypes:              []string{"authorization_code", "refresh_token"},
+		ResponseTypes:           []string{"code"},
+		ClientName:              "Wingman",
+		Scope:                   strings.Join(scopes, " "),
+	}, oauthClient)
+	if err != nil {
+		return nil, fmt.Errorf("register OAuth client: %w", err)
+	}
+	return &Authorization{
+		Resource:              prm.Resource,
+		AuthorizationEndpoint: asm.AuthorizationEndpoint,
+		TokenEndpoint:         asm.TokenEndpoint,
+		Scopes:                scopes,
+		ClientID:              registration.ClientID,
+		ClientSecret:          registration.ClientSecret,
+		RedirectURI:           redirectURI,
+	}, nil
+}
+
+// probeChallenges requests the server without credentials and returns the
+// WWW-Authenticate challenges of its 401 or 403 response, if any.
+func probeChallenges(ctx context.Context, cfg ServerConfig) []oauthex.Challenge {
+	ctx, cancel := context.WithTimeout(ctx, oauthTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.URL, nil)
+	if err != nil {
+		return nil
+	}
+	request.Header.Set("accept", "application/json, text/event-stream")
+	response, err := httpClient(cfg.Headers).Do(request)
+	if err != nil {
+		return nil
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 1<<16))
+	if response.StatusCode != http.StatusUnauthorized && response.StatusCode != http.StatusForbidden {
+		return nil
+	}
+	challenges, err := oauthex.ParseWWWAuthenticate(response.Header.Values("WWW-Authenticate"))
+	if err != nil {
+		return nil
+	}
+	return challenges
+}
+
+// protectedResourceMetadata tries the metadata URL advertised by the server,
+// then the path-specific and root well-known locations. Servers without
+// metadata are treated as their own authorization server.
+func protectedResourceMetadata(ctx context.Context, challenges []oauthex.Challenge, serverURL string) (*oauthex.ProtectedResourceMetadata, error) {
+	endpoint, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse MCP server URL: %w", err)
+	}
+	type candidate struct{ metadata, resource string }
+	var candidates []candidate
+	for _, challenge := range challenges {
+		if metadata := challenge.Params["resource_metadata"]; metadata != "" {
+			candidates = append(candidates, candidate{metadata, serverURL})
+			break
+		}
+	}
+	root := *endpoint
+	root.Path, root.RawPath, root.RawQuery, root.Fragment = "", "", "", ""
+	pathMetadata := root
+	pathMetadata.Path = "/.well-known/oauth-protected-resource/" + strings.TrimLeft(endpoint.Path, "/")
+	rootMetadata := root
+	rootMetadata.Path = "/.well-known/oauth-protected-resource"
+	candidates = append(candidates, candidate{pathMetadata.String(), serverURL}, candidate{rootMetadata.String(), root.String()})
+
+	for _, c := range candidates {
+		prm, err := oauthex.GetProtectedResourceMetadata(ctx, c.metadata, c.resource, oauthClient)
+		if err != nil || prm == nil {
+			continue
+		}
+		if len(prm.AuthorizationServers) == 0 {
+			return nil, fmt.Errorf("protected resource metadata for %s lists no authorization servers", serverURL)
+		}
+		return prm, nil
+	}
+	return &oauthex.ProtectedResourceMetadata{Resource: serverURL, AuthorizationServers: []string{root.String()}}, nil
+}
+
+func challengeScopes(challenges []oauthex.Challenge) []string {
+	for _, challenge := range challenges {
+		if challenge.Scheme == "bearer" && challenge.Params["scope"] != "" {
+			return strings.Fields(challenge.Params["scope"])
+		}
+	}
+	return nil
+}
+
+// AuthCodeURL returns the PKCE authorization URL the user opens in a browser.
+func (a *Authorization) AuthCodeURL(state, verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {a.ClientID},
+		"redirect_uri":          {a.RedirectURI},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+		"state":                 {state},
+		"resource":              {a.Resource},
+	}
+	if len(a.Scopes) > 0 {
+		params.Set("scope", strings.Join(a.Scopes, " "))
+	}
+	separator := "?"
+	if strings.Contains(a.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return a.AuthorizationEndpoint + separator + params.Encode()
+}
+
+// Exchange trades an authorization code for a token that can later be
+// refreshed without the Authorization.
+func (a *Authorization) Exchange(ctx context.Context, code, verifier string) (Token, error) {
+	prior := Token{ClientID: a.ClientID, ClientSecret: a.ClientSecret, TokenURL: a.TokenEndpoint, Resource: a.Resource, Scope: strings.Join(a.Scopes, " ")}
+	values := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {a.RedirectURI},
+		"code_verifier": {verifier},
+	}
+	return tokenRequest(ctx, values, prior)
+}
+
+func refreshToken(ctx context.Context, token Token) (Token, error) {
+	values := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {token.RefreshToken}}
+	return tokenRequest(ctx, values, token)
+}
+
+func tokenRequest(ctx context.Context, values url.Values, prior Token) (Token, error) {
+	if prior.Resource != "" {
+		values.Set("resource", prior.Resource)
+	}
+	if prior.ClientSecret == "" {
+		values.Set("client_id", prior.ClientID)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, prior.TokenURL, strings.NewReader(values.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+	request.Header.Set("content-type", "application/x-www-form-urlencoded")
+	request.Header.Set("accept", "application/json")
+	if prior.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(prior.ClientID), url.QueryEscape(prior.ClientSecret))
+	}
+	response, err := oauthClient.Do(request)
+	if err != nil {
+		return Token{}, fmt.Errorf("request MCP OAuth token: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 8192))
+		return Token{}, fmt.Errorf("MCP OAuth token request: HTTP %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var tokens struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+		Scope        string `json:"scope"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&tokens); err != nil {
+		return Token{}, fmt.Errorf("decode MCP OAuth token response: %w", err)
+	}
+	if tokens.AccessToken == "" {
+		return Token{}, errors.New("MCP OAuth token response is missing an access token")
+	}
+	next := prior
+	next.AccessToken = tokens.AccessToken
+	if tokens.RefreshToken != "" {
+		next.RefreshToken = tokens.RefreshToken
+	}
+	next.ExpiresAt = 0
+	if tokens.ExpiresIn > 0 {
+		next.ExpiresAt = time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second).Unix()
+	}
+	if tokens.Scope != "" {
+		next.Scope = tokens.Scope
+	}
+	return next, nil
+}
+
+// remoteHTTPClient returns the client for a remote server. When a token is
+// stored for it, the token is refreshed now if needed and attached to every
+// request, refreshing again as it nears expiry.
+func remoteHTTPClient(ctx context.Context, name string, cfg ServerConfig, tokens TokenStore) (*http.Client, error) {
+	client := httpClient(cfg.Headers)
+	if tokens == nil {
+		return client, nil
+	}
+	token, ok, err := tokens.LoadToken(name)
+	if err != nil {
+		return nil, fmt.Errorf("load OAuth token: %w", err)
+	}
+	if !ok {
+		return client, nil
+	}
+	source := &tokenSource{server: name, store: tokens, token: token}
+	if _, err := source.accessToken(ctx); err != nil {
+		return nil, err
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &http.Client{Transport: bearerTransport{base: base, source: source}}, nil
+}
+
+type tokenSource struct {
+	server string
+	store  TokenStore
+
+	mu    sync.Mutex
+	token Token
+}
+
+func (s *tokenSource) accessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token.fresh() {
+		return s.token.AccessToken, nil
+	}
+	lock := refreshLock(s.server)
+	lock.Lock()
+	defer lock.Unlock()
+	// Another manager may already have refreshed the stored token.
+	stored, ok, err := s.store.LoadToken(s.server)
+	if err != nil {
+		return "", fmt.Errorf("load OAuth token: %w", err)
+	}
+	if !ok {
+		return "", fmt.Errorf("OAuth token for MCP server %q was removed; authorize it again", s.server)
+	}
+	if stored.fresh() {
+		s.token = stored
+		return stored.AccessToken, nil
+	}
+	if stored.RefreshToken == "" || stored.TokenURL == "" {
+		return "", fmt.Errorf("OAuth token for MCP server %q expired; authorize it again", s.server)
+	}
+	fresh, err := refreshToken(ctx, stored)
+	if err != nil {
+		return "", fmt.Errorf("refresh OAuth token: %w", err)
+	}
+	if err := s.store.SaveToken(s.server, fresh); err != nil {
+		return "", fmt.Errorf("save refreshed OAuth token: %w", err)
+	}
+	s.token = fresh
+	return fresh.AccessToken, nil
+}
+
+type bearerTransport struct {
+	base   http.RoundTripper
+	source *tokenSource
+}
+
+func (t bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.accessToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(clone)
+}
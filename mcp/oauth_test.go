@@ -0,0 +1,166 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type memoryTokens struct {
+	mu     sync.Mutex
+	tokens map[string]Token
+}
+
+func (s *memoryTokens) LoadToken(server string) (Token, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens[server]
+	return token, ok, nil
+}
+
+func (s *memoryTokens) SaveToken(server string, token Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[server] = token
+	return nil
+}
+
+func TestAuthorizeDiscoversRegistersAndRefreshesBeforeConnect(t *testing.T) {
+	var challenge string
+	authServer := httptest.NewServer(nil)
+	defer authServer.Close()
+	mcpServer := httptest.NewServer(nil)
+	defer mcpServer.Close()
+	resource := mcpServer.URL + "/mcp"
+
+	authMux := http.NewServeMux()
+	authMux.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, http.StatusOK, map[string]any{
+			"issuer":                           authServer.URL,
+			"authorization_endpoint":           authServer.URL + "/authorize",
+			"token_endpoint":                   authServer.URL + "/token",
+			"registration_endpoint":            authServer.URL + "/register",
+			"response_types_supported":         []string{"code"},
+			"code_challenge_methods_supported": []string{"S256"},
+		})
+	})
+	authMux.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
+		var metadata struct {
+			RedirectURIs []string `json:"redirect_uris"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&metadata)
+		if len(metadata.RedirectURIs) != 1 || metadata.RedirectURIs[0] != "http://127.0.0.1:9/callback" {
+			t.Errorf("redirect URIs = %v", metadata.RedirectURIs)
+		}
+		writeTestJSON(w, http.StatusCreated, map[string]any{"client_id": "wingman-client"})
+	})
+	authMux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Form.Get("client_id") != "wingman-client" || r.Form.Get("resource") != resource {
+			http.Error(w, "bad client", http.StatusBadRequest)
+			return
+		}
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if r.Form.Get("code") != "code" || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+				http.Error(w, "bad code", http.StatusBadRequest)
+				return
+			}
+			// Expires inside the refresh skew so the next connect refreshes.
+			writeTestJSON(w, http.StatusOK, map[string]any{"access_token": "stale", "refresh_token": "refresh", "expires_in": 1})
+		case "refresh_token":
+			if r.Form.Get("refresh_token") != "refresh" {
+				http.Error(w, "bad refresh token", http.StatusBadRequest)
+				return
+			}
+			writeTestJSON(w, http.StatusOK, map[string]any{"access_token": "fresh", "expires_in": 3600})
+		}
+	})
+	authServer.Config.Handler = authMux
+
+	protected := mcpsdk.NewStreamableHTTPHandler(func(*http.Request) *mcpsdk.Server {
+		return mcpsdk.NewServer(&mcpsdk.Implementation{Name: "docs", Version: "test"}, nil)
+	}, nil)
+	mcpMux := http.NewServeMux()
+	mcpMux.HandleFunc("/.well-known/oauth-protected-resource/mcp", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, http.StatusOK, map[string]any{"resource": resource, "authorization_servers": []string{authServer.URL}, "scopes_supported": []string{"docs.read"}})
+	})
+	mcpMux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.Header().Set("WWW-Authenticate", `Bearer resource_metadata="`+mcpServer.URL+`/.well-known/oauth-protected-resource/mcp"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		protected.ServeHTTP(w, r)
+	})
+	mcpServer.Config.Handler = mcpMux
+
+	tokens := &memoryTokens{tokens: map[string]Token{}}
+	ctx := context.Background()
+	m := New(ctx, Config{Servers: map[string]ServerConfig{"docs": {Type: "remote", URL: resource}}, Tokens: tokens})
+	defer m.Close()
+	if status := m.Status()[0]; status.Status != "failed" {
+		t.Fatalf("status before authorization = %#v", status)
+	}
+
+	authorization, err := m.Authorize(ctx, "docs", "http://127.0.0.1:9/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := url.Parse(authorization.AuthCodeURL("state", "verifier-verifier-verifier-verifier-verifier"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := authURL.Query()
+	if authURL.Path != "/authorize" || query.Get("client_id") != "wingman-client" || query.Get("resource") != resource || query.Get("scope") != "docs.read" || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("authorization URL = %s", authURL)
+	}
+	challenge = query.Get("code_challenge")
+	token, err := authorization.Exchange(ctx, "code", "verifier-verifier-verifier-verifier-verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.SaveToken("docs", token); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Connect(ctx, "docs"); err != nil {
+		t.Fatal(err)
+	}
+	if status := m.Status()[0]; status.Status != "connected" {
+		t.Fatalf("status after authorization = %#v", status)
+	}
+	stored, _, _ := tokens.LoadToken("docs")
+	if stored.AccessToken != "fresh" || stored.RefreshToken != "refresh" || stored.TokenURL != authServer.URL+"/token" {
+		t.Fatalf("stored token = %#v", stored)
+	}
+}
+
+func writeTestJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func TestRefreshLocksArePerServer(t *testing.T) {
+	if refreshLock("docs") != refreshLock("docs") {
+		t.Fatal("refreshLock returned different locks for one server")
+	}
+	first := refreshLock("docs")
+	first.Lock()
+	defer first.Unlock()
+	other := refreshLock("tickets")
+	if !other.TryLock() {
+		t.Fatal("refreshing one server blocked another")
+	}
+	other.Unlock()
+}
@@ -0,0 +1,65 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chaserensberger/wingman/tool"
+)
+
+// ResourceToolName is the built-in tool that reads MCP resources.
+const ResourceToolName = "read_mcp_resource"
+
+// resourceTool lets agents read resources from any connected server that
+// advertises them. Servers are resolved at call time, so a reconnect does not
+// strand the tool on a retired generation.
+type resourceTool struct {
+	manager *Manager
+	servers []string
+}
+
+func (t *resourceTool) Name() string { return ResourceToolName }
+
+func (t *resourceTool) Description() string {
+	return "Read a resource exposed by a connected MCP server. Provide the server name and the resource URI."
+}
+
+func (t *resourceTool) Definition() tool.Definition {
+	return tool.Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: tool.InputSchema{
+			Type: "object",
+			Properties: map[string]tool.Property{
+				"server": {
+					Type:        "string",
+					Description: "The MCP server that owns the resource",
+					Enum:        append([]string(nil), t.servers...),
+				},
+				"uri": {
+					Type:        "string",
+					Description: "The resource URI to read",
+				},
+			},
+			Required: []string{"server", "uri"},
+		},
+	}
+}
+
+func (t *resourceTool) Execute(ctx context.Context, inv tool.Invocation) (tool.Result, error) {
+	server, _ := inv.Input["server"].(string)
+	uri, _ := inv.Input["uri"].(string)
+	if strings.TrimSpace(server) == "" {
+		return tool.Result{}, fmt.Errorf("server is required")
+	}
+	read, err := t.manager.ReadResource(ctx, server, uri)
+	if err != nil {
+		return tool.Result{}, err
+	}
+	return tool.Result{Text: ResourceText(read), Metadata: map[string]any{
+		"source": "mcp",
+		"server": server,
+		"uri":    uri,
+	}}, nil
+}
@@ -0,0 +1,516 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// subscriptionBuffer bounds undelivered resource updates per subscriber. A
+// slow subscriber drops updates rather than stalling the MCP session.
+const subscriptionBuffer = 16
+
+// Resource describes one concrete resource an MCP server can read.
+type Resource struct {
+	Server      string `json:"server"`
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mime_type,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+}
+
+// ResourceTemplate describes a parameterized family of resources.
+type ResourceTemplate struct {
+	Server      string `json:"server"`
+	URITemplate string `json:"uri_template"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mime_type,omitempty"`
+}
+
+// ResourceList is the resource catalog of one MCP server.
+type ResourceList struct {
+	Resources []Resource         `json:"resources"`
+	Templates []ResourceTemplate `json:"templates"`
+}
+
+// ResourceContent is one part of a read resource. Exactly one of Text and
+// Blob is normally set.
+type ResourceContent struct {
+	URI      string `json:"uri"`
+	MIMEType string `json:"mime_type,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     []byte `json:"blob,omitempty"`
+}
+
+// ResourceRead is the result of reading one resource URI.
+type ResourceRead struct {
+	Server   string            `json:"server"`
+	URI      string            `json:"uri"`
+	Contents []ResourceContent `json:"contents"`
+}
+
+// ResourceUpdate reports that a subscribed resource changed. URI may name a
+// sub-resource of the subscribed URI.
+type ResourceUpdate struct {
+	Server string `json:"server"`
+	URI    string `json:"uri"`
+}
+
+// Prompt describes one prompt template an MCP server offers.
+type Prompt struct {
+	Server      string           `json:"server"`
+	Name        string           `json:"name"`
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// PromptArgument describes one prompt template argument.
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptMessage is one rendered prompt message. Non-text content is rendered
+// as its JSON representation.
+type PromptMessage struct {
+	Role string `json:"role"`
+	Text string `json:"text"`
+}
+
+// RenderedPrompt is a prompt template expanded with arguments.
+type RenderedPrompt struct {
+	Server      string          `json:"server"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// catalogConnection is implemented by connections that can serve resources
+// and prompts in addition to tools. The SDK client session implements it.
+type catalogConnection interface {
+	connection
+	ListResources(context.Context, *mcpsdk.ListResourcesParams) (*mcpsdk.ListResourcesResult, error)
+	ListResourceTemplates(context.Context, *mcpsdk.ListResourceTemplatesParams) (*mcpsdk.ListResourceTemplatesResult, error)
+	ReadResource(context.Context, *mcpsdk.ReadResourceParams) (*mcpsdk.ReadResourceResult, error)
+	Subscribe(context.Context, *mcpsdk.SubscribeParams) error
+	Unsubscribe(context.Context, *mcpsdk.UnsubscribeParams) error
+	ListPrompts(context.Context, *mcpsdk.ListPromptsParams) (*mcpsdk.ListPromptsResult, error)
+	GetPrompt(context.Context, *mcpsdk.GetPromptParams) (*mcpsdk.GetPromptResult, error)
+}
+
+type resourceSubscription struct {
+	server  string
+	uri     string
+	updates chan ResourceUpdate
+}
+
+// Resources lists the resources and resource templates of a connected server.
+func (m *Manager) Resources(ctx context.Context, server string) (ResourceList, error) {
+	g, err := m.catalogGeneration(server, func(c *mcpsdk.ServerCapabilities) bool { return c.Resources != nil }, "resources")
+	if err != nil {
+		return ResourceList{}, err
+	}
+	out := ResourceList{Resources: []Resource{}, Templates: []ResourceTemplate{}}
+	err = g.withCatalog(ctx, func(ctx context.Context, conn catalogConnection) error {
+		resources, err := listPages(ctx, "resources", func(cursor string) ([]*mcpsdk.Resource, string, error) {
+			res, err := conn.ListResources(ctx, &mcpsdk.ListResourcesParams{Cursor: cursor})
+			if err != nil {
+				return nil, "", err
+			}
+			return res.Resources, res.NextCursor, nil
+		})
+		if err != nil {
+			return err
+		}
+		templates, err := listPages(ctx, "resource templates", func(cursor string) ([]*mcpsdk.ResourceTemplate, string, error) {
+			res, err := conn.ListResourceTemplates(ctx, &mcpsdk.ListResourceTemplatesParams{Cursor: cursor})
+			if err != nil {
+				return nil, "", err
+			}
+			return res.ResourceTemplates, res.NextCursor, nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, r := range resources {
+			out.Resources = append(out.Resources, Resource{Server: server, URI: r.URI, Name: r.Name, Title: r.Title, Description: r.Description, MIMEType: r.MIMEType, Size: r.Size})
+		}
+		for _, t := range templates {
+			out.Templates = append(out.Templates, ResourceTemplate{Server: server, URITemplate: t.URITemplate, Name: t.Name, Title: t.Title, Description: t.Description, MIMEType: t.MIMEType})
+		}
+		return nil
+	})
+	if err != nil {
+		return ResourceList{}, err
+	}
+	sort.Slice(out.Resources, func(i, j int) bool { return out.Resources[i].URI < out.Resources[j].URI })
+	sort.Slice(out.Templates, func(i, j int) bool { return out.Templates[i].URITemplate < out.Templates[j].URITemplate })
+	return out, nil
+}
+
+// ReadResource reads one resource URI from a connected server.
+func (m *Manager) ReadResource(ctx context.Context, server, uri string) (ResourceRead, error) {
+	if strings.TrimSpace(uri) == "" {
+		return ResourceRead{}, errors.New("MCP resource uri is required")
+	}
+	g, err := m.catalogGeneration(server, func(c *mcpsdk.ServerCapabilities) bool { return c.Resources != nil }, "resources")
+	if err != nil {
+		return ResourceRead{}, err
+	}
+	out := ResourceRead{Server: server, URI: uri, Contents: []ResourceContent{}}
+	err = g.withCatalog(ctx, func(ctx context.Context, conn catalogConnection) error {
+		res, err := conn.ReadResource(ctx, &mcpsdk.ReadResourceParams{URI: uri})
+		if err != nil {
+			return fmt.Errorf("read MCP resource %q: %w", uri, err)
+		}
+		for _, content := range res.Contents {
+			if content == nil {
+				continue
+			}
+			out.Contents = append(out.Contents, ResourceContent{URI: content.URI, MIMEType: content.MIMEType, Text: content.Text, Blob: append([]byte(nil), content.Blob...)})
+		}
+		return nil
+	})
+	if err != nil {
+		return ResourceRead{}, err
+	}
+	return out, nil
+}
+
+// SubscribeResource asks a connected server for change notifications on uri
+// and the URIs below it by path segment. Updates are delivered until cancel is called or the server is disconnected,
+// at which point the channel is closed. Active subscriptions are renewed when
+// the server reconnects.
+func (m *Manager) SubscribeResource(ctx context.Context, server, uri string) (<-chan ResourceUpdate, func(), error) {
+	if strings.TrimSpace(uri) == "" {
+		return nil, nil, errors.New("MCP resource uri is required")
+	}
+	g, err := m.catalogGeneration(server, func(c *mcpsdk.ServerCapabilities) bool { return c.Resources != nil && c.Resources.Subscribe }, "resource subscriptions")
+	if err != nil {
+		return nil, nil, err
+	}
+	sub := &resourceSubscription{server: server, uri: uri, updates: make(chan ResourceUpdate, subscriptionBuffer)}
+
+	// Holding the server's subscribe lock until the server answers keeps a
+	// concurrent last unsubscribe from cancelling this subscription, and
+	// makes a second subscriber wait until the first is really subscribed.
+	lock := m.subscribeLock(server)
+	lock.Lock()
+	defer lock.Unlock()
+	m.mu.Lock()
+	first := m.subscriberCountLocked(server, uri) == 0
+	m.subscriptions[sub] = struct{}{}
+	m.mu.Unlock()
+
+	if first {
+		err := g.withCatalog(ctx, func(ctx context.Context, conn catalogConnection) error {
+			return conn.Subscribe(ctx, &mcpsdk.SubscribeParams{URI: uri})
+		})
+		if err != nil {
+			m.removeSubscription(sub, false)
+			return nil, nil, fmt.Errorf("subscribe to MCP resource %q: %w", uri, err)
+		}
+	}
+	return sub.updates, func() { m.removeSubscription(sub, true) }, nil
+}
+
+// Prompts lists the prompt templates of a connected server.
+func (m *Manager) Prompts(ctx context.Context, server string) ([]Prompt, error) {
+	g, err := m.catalogGeneration(server, func(c *mcpsdk.ServerCapabilities) bool { return c.Prompts != nil }, "prompts")
+	if err != nil {
+		return nil, err
+	}
+	out := []Prompt{}
+	err = g.withCatalog(ctx, func(ctx context.Context, conn catalogConnection) error {
+		prompts, err := listPages(ctx, "prompts", func(cursor string) ([]*mcpsdk.Prompt, string, error) {
+			res, err := conn.ListPrompts(ctx, &mcpsdk.ListPromptsParams{Cursor: cursor})
+			if err != nil {
+				return nil, "", err
+			}
+			return res.Prompts, res.NextCursor, nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, p := range prompts {
+			prompt := Prompt{Server: server, Name: p.Name, Title: p.Title, Description: p.Description}
+			for _, arg := range p.Arguments {
+				if arg != nil {
+					prompt.Arguments = append(prompt.Arguments, PromptArgument{Name: arg.Name, Title: arg.Title, Description: arg.Description, Required: arg.Required})
+				}
+			}
+			out = append(out, prompt)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// RenderPrompt expands a prompt template with arguments.
+func (m *Manager) RenderPrompt(ctx context.Context, server, name string, args map[string]string) (RenderedPrompt, error) {
+	if strings.TrimSpace(name) == "" {
+		return RenderedPrompt{}, errors.New("MCP prompt name is required")
+	}
+	g, err := m.catalogGeneration(server, func(c *mcpsdk.ServerCapabilities) bool { return c.Prompts != nil }, "prompts")
+	if err != nil {
+		return RenderedPrompt{}, err
+	}
+	out := RenderedPrompt{Server: server, Name: name, Messages: []PromptMessage{}}
+	err = g.withCatalog(ctx, func(ctx context.Context, conn catalogConnection) error {
+		res, err := conn.GetPrompt(ctx, &mcpsdk.GetPromptParams{Name: name, Arguments: args})
+		if err != nil {
+			return fmt.Errorf("render MCP prompt %q: %w", name, err)
+		}
+		out.Description = res.Description
+		for _, message := range res.Messages {
+			if message == nil {
+				continue
+			}
+			out.Messages = append(out.Messages, PromptMessage{Role: string(message.Role), Text: contentText(message.Content)})
+		}
+		return nil
+	})
+	if err != nil {
+		return RenderedPrompt{}, err
+	}
+	return out, nil
+}
+
+// ResourceText renders read resource contents for a model. Binary parts are
+// summarized because they cannot be shown as text.
+func ResourceText(read ResourceRead) string {
+	parts := make([]string, 0, len(read.Contents))
+	for _, content := range read.Contents {
+		switch {
+		case content.Text != "":
+			parts = append(parts, content.Text)
+		case len(content.Blob) > 0:
+			mime := content.MIMEType
+			if mime == "" {
+				mime = "application/octet-stream"
+			}
+			parts = append(parts, fmt.Sprintf("[binary resource %s: %s, %d bytes]", content.URI, mime, len(content.Blob)))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// catalogGeneration returns the published generation of a connected server
+// after checking that the server advertised the requested capability.
+func (m *Manager) catalogGeneration(server string, supports func(*mcpsdk.ServerCapabilities) bool, feature string) (*connectionGeneration, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	state := m.servers[server]
+	if state == nil {
+		return nil, fmt.Errorf("%w: %s", ErrServerNotFound, server)
+	}
+	if state.generation == nil || state.status != "connected" {
+		return nil, fmt.Errorf("%w: %s is not connected", ErrServerUnavailable, server)
+	}
+	if _, ok := state.generation.connection.(catalogConnection); !ok || !supports(state.generation.capabilities) {
+		return nil, fmt.Errorf("%w: %s does not support %s", ErrServerUnavailable, server, feature)
+	}
+	return state.generation, nil
+}
+
+// resourceServersLocked returns connected servers that serve resources.
+func (m *Manager) resourceServersLocked() []string {
+	var out []string
+	for name, state := range m.servers {
+		if state.generation == nil || state.status != "connected" || state.generation.capabilities.Resources == nil {
+			continue
+		}
+		if _, ok := state.generation.connection.(catalogConnection); ok {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (m *Manager) subscribeLock(server string) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock := m.subscribeLocks[server]
+	if lock == nil {
+		lock = &sync.Mutex{}
+		m.subscribeLocks[server] = lock
+	}
+	return lock
+}
+
+func (m *Manager) subscriberCountLocked(server, uri string) int {
+	n := 0
+	for sub := range m.subscriptions {
+		if sub.server == server && sub.uri == uri {
+			n++
+		}
+	}
+	return n
+}
+
+// removeSubscription closes sub. When it was the last local subscriber for
+// its URI and unsubscribe is set, the server is told to stop notifying.
+func (m *Manager) removeSubscription(sub *resourceSubscription, unsubscribe bool) {
+	if unsubscribe {
+		lock := m.subscribeLock(sub.server)
+		lock.Lock()
+		defer lock.Unlock()
+	}
+	m.mu.Lock()
+	if _, ok := m.subscriptions[sub]; !ok {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.subscriptions, sub)
+	close(sub.updates)
+	last := m.subscriberCountLocked(sub.server, sub.uri) == 0
+	var g *connectionGeneration
+	if state := m.servers[sub.server]; state != nil && state.status == "connected" {
+		g = state.generation
+	}
+	m.mu.Unlock()
+
+	if !unsubscribe || !last || g == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), retirementTimeout)
+	defer cancel()
+	_ = g.withCatalog(ctx, func(ctx context.Context, conn catalogConnection) error {
+		return conn.Unsubscribe(ctx, &mcpsdk.UnsubscribeParams{URI: sub.uri})
+	})
+}
+
+// closeSubscriptionsLocked closes every subscription for server, or all
+// subscriptions when server is empty.
+func (m *Manager) closeSubscriptionsLocked(server string) {
+	for sub := range m.subscriptions {
+		if server == "" || sub.server == server {
+			delete(m.subscriptions, sub)
+			close(sub.updates)
+		}
+	}
+}
+
+// resubscribe renews the server's active subscriptions on a new generation.
+func (m *Manager) resubscribe(ctx context.Context, server string, g *connectionGeneration) error {
+	m.mu.RLock()
+	seen := map[string]struct{}{}
+	var uris []string
+	for sub := range m.subscriptions {
+		if _, ok := seen[sub.uri]; sub.server == server && !ok {
+			seen[sub.uri] = struct{}{}
+			uris = append(uris, sub.uri)
+		}
+	}
+	m.mu.RUnlock()
+	if len(uris) == 0 {
+		return nil
+	}
+	sort.Strings(uris)
+	var errs []error
+	for _, uri := range uris {
+		err := g.withCatalog(ctx, func(ctx context.Context, conn catalogConnection) error {
+			return conn.Subscribe(ctx, &mcpsdk.SubscribeParams{URI: uri})
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resubscribe to MCP resource %q: %w", uri, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// publishResourceUpdate fans one server notification out to matching
+// subscribers without blocking the MCP session.
+func (m *Manager) publishResourceUpdate(server, uri string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for sub := range m.subscriptions {
+		if sub.server != server || !resourceURIMatches(sub.uri, uri) {
+			continue
+		}
+		select {
+		case sub.updates <- ResourceUpdate{Server: server, URI: uri}:
+		default:
+		}
+	}
+}
+
+// resourceURIMatches reports whether an update for uri concerns a
+// subscription to subscribed: the same URI or one below it by path segment.
+func resourceURIMatches(subscribed, uri string) bool {
+	if uri == subscribed {
+		return true
+	}
+	if !strings.HasPrefix(uri, subscribed) {
+		return false
+	}
+	return strings.HasSuffix(subscribed, "/") || uri[len(subscribed)] == '/'
+}
+
+func (g *connectionGeneration) withCatalog(ctx context.Context, fn func(context.Context, catalogConnection) error) error {
+	done, err := g.begin()
+	if err != nil {
+		return err
+	}
+	defer done()
+	conn, ok := g.connection.(catalogConnection)
+	if !ok {
+		return errors.New("MCP connection does not support resources or prompts")
+	}
+	ctx, cancel := context.WithTimeout(ctx, g.executionTimeout)
+	defer cancel()
+	return fn(ctx, conn)
+}
+
+func connectionCapabilities(conn connection) *mcpsdk.ServerCapabilities {
+	if initialized, ok := conn.(interface {
+		InitializeResult() *mcpsdk.InitializeResult
+	}); ok {
+		if res := initialized.InitializeResult(); res != nil && res.Capabilities != nil {
+			return res.Capabilities
+		}
+	}
+	return &mcpsdk.ServerCapabilities{}
+}
+
+func listPages[T any](ctx context.Context, kind string, list func(cursor string) ([]T, string, error)) ([]T, error) {
+	var out []T
+	var cursor string
+	seen := map[string]struct{}{}
+	for page := 0; page < 1000; page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		items, next, err := list(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("list MCP %s: %w", kind, err)
+		}
+		out = append(out, items...)
+		if next == "" {
+			return out, nil
+		}
+		if _, ok := seen[next]; ok {
+			return nil, fmt.Errorf("list MCP %s returned duplicate cursor %q", kind, next)
+		}
+		seen[next] = struct{}{}
+		cursor = next
+	}
+	return nil, fmt.Errorf("list MCP %s exceeded page limit", kind)
+}
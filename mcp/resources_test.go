@@ -0,0 +1,219 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/chaserensberger/wingman/tool"
+)
+
+func resourceServer() *mcpsdk.Server {
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "docs", Version: "test"}, &mcpsdk.ServerOptions{
+		SubscribeHandler:   func(context.Context, *mcpsdk.SubscribeRequest) error { return nil },
+		UnsubscribeHandler: func(context.Context, *mcpsdk.UnsubscribeRequest) error { return nil },
+	})
+	server.AddResource(&mcpsdk.Resource{URI: "file:///readme.md", Name: "readme", MIMEType: "text/markdown"}, func(_ context.Context, req *mcpsdk.ReadResourceRequest) (*mcpsdk.ReadResourceResult, error) {
+		return &mcpsdk.ReadResourceResult{Contents: []*mcpsdk.ResourceContents{{URI: req.Params.URI, MIMEType: "text/markdown", Text: "# Readme"}}}, nil
+	})
+	server.AddResourceTemplate(&mcpsdk.ResourceTemplate{URITemplate: "file:///docs/{page}", Name: "docs"}, func(_ context.Context, req *mcpsdk.ReadResourceRequest) (*mcpsdk.ReadResourceResult, error) {
+		return &mcpsdk.ReadResourceResult{Contents: []*mcpsdk.ResourceContents{{URI: req.Params.URI, Blob: []byte{1, 2, 3}, MIMEType: "image/png"}}}, nil
+	})
+	server.AddPrompt(&mcpsdk.Prompt{Name: "review", Arguments: []*mcpsdk.PromptArgument{{Name: "focus", Required: true}}}, func(_ context.Context, req *mcpsdk.GetPromptRequest) (*mcpsdk.GetPromptResult, error) {
+		return &mcpsdk.GetPromptResult{Description: "Code review", Messages: []*mcpsdk.PromptMessage{
+			{Role: "user", Content: &mcpsdk.TextContent{Text: "Review for " + req.Params.Arguments["focus"]}},
+		}}, nil
+	})
+	return server
+}
+
+func inMemoryManager(t *testing.T, server *mcpsdk.Server) *Manager {
+	t.Helper()
+	m := testManager(nil)
+	m.connect = func(ctx context.Context, name string, _ ServerConfig) (connection, []*mcpsdk.Tool, error) {
+		clientTransport, serverTransport := mcpsdk.NewInMemoryTransports()
+		if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+			return nil, nil, err
+		}
+		client := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "wingman", Version: "test"}, clientOptions(name, m.publishResourceUpdate))
+		session, err := client.Connect(ctx, clientTransport, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		return session, nil, nil
+	}
+	if err := m.Connect(context.Background(), "server"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = m.Close() })
+	return m
+}
+
+func TestResourcesAndPromptsRoundTrip(t *testing.T) {
+	m := inMemoryManager(t, resourceServer())
+	ctx := context.Background()
+
+	resources, err := m.Resources(ctx, "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources.Resources) != 1 || resources.Resources[0].URI != "file:///readme.md" || resources.Resources[0].Server != "server" {
+		t.Fatalf("resources = %#v", resources.Resources)
+	}
+	if len(resources.Templates) != 1 || resources.Templates[0].URITemplate != "file:///docs/{page}" {
+		t.Fatalf("templates = %#v", resources.Templates)
+	}
+
+	read, err := m.ReadResource(ctx, "server", "file:///readme.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ResourceText(read); got != "# Readme" {
+		t.Fatalf("resource text = %q", got)
+	}
+	binary, err := m.ReadResource(ctx, "server", "file:///docs/logo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ResourceText(binary); got != "[binary resource file:///docs/logo: image/png, 3 bytes]" {
+		t.Fatalf("binary resource text = %q", got)
+	}
+
+	prompts, err := m.Prompts(ctx, "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prompts) != 1 || prompts[0].Name != "review" || len(prompts[0].Arguments) != 1 || !prompts[0].Arguments[0].Required {
+		t.Fatalf("prompts = %#v", prompts)
+	}
+	rendered, err := m.RenderPrompt(ctx, "server", "review", map[string]string{"focus": "races"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered.Description != "Code review" || len(rendered.Messages) != 1 || rendered.Messages[0].Role != "user" || rendered.Messages[0].Text != "Review for races" {
+		t.Fatalf("rendered = %#v", rendered)
+	}
+}
+
+func TestResourceToolReadsConnectedResources(t *testing.T) {
+	m := inMemoryManager(t, resourceServer())
+	tools := m.Tools()
+	if len(tools) != 1 || tools[0].Name() != ResourceToolName {
+		t.Fatalf("tools = %#v", tools)
+	}
+	if enum := tools[0].Definition().InputSchema.Properties["server"].Enum; len(enum) != 1 || enum[0] != "server" {
+		t.Fatalf("server enum = %#v", enum)
+	}
+	res, err := tools[0].Execute(context.Background(), tool.Invocation{Input: map[string]any{"server": "server", "uri": "file:///readme.md"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Text != "# Readme" || res.Metadata["uri"] != "file:///readme.md" {
+		t.Fatalf("result = %#v", res)
+	}
+	if _, err := tools[0].Execute(context.Background(), tool.Invocation{Input: map[string]any{"server": "missing", "uri": "file:///readme.md"}}); !errors.Is(err, ErrServerNotFound) {
+		t.Fatalf("missing server error = %v", err)
+	}
+}
+
+func TestResourceSubscriptionDeliversUpdatesUntilDisconnect(t *testing.T) {
+	server := resourceServer()
+	m := inMemoryManager(t, server)
+	ctx := context.Background()
+
+	updates, cancel, err := m.SubscribeResource(ctx, "server", "file:///readme.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.ResourceUpdated(ctx, &mcpsdk.ResourceUpdatedNotificationParams{URI: "file:///readme.md"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case update := <-updates:
+		if update.Server != "server" || update.URI != "file:///readme.md" {
+			t.Fatalf("update = %#v", update)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("resource update was not delivered")
+	}
+
+	// Reconnecting renews the subscription on the new session.
+	if err := m.Connect(ctx, "server"); err != nil {
+		t.Fatal(err)
+	}
+	if status := m.Status()[0]; status.Error != "" {
+		t.Fatalf("status after reconnect = %#v", status)
+	}
+	if err := server.ResourceUpdated(ctx, &mcpsdk.ResourceUpdatedNotificationParams{URI: "file:///readme.md"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-updates:
+	case <-time.After(time.Second):
+		t.Fatal("resource update was not delivered after reconnect")
+	}
+
+	if err := m.Disconnect("server"); err != nil {
+		t.Fatal(err)
+	}
+	if _, open := <-updates; open {
+		t.Fatal("subscription stayed open after disconnect")
+	}
+	cancel()
+}
+
+func TestResourceUpdatesMatchWholePathSegments(t *testing.T) {
+	m := inMemoryManager(t, resourceServer())
+	updates, cancel, err := m.SubscribeResource(context.Background(), "server", "file:///docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+	for _, uri := range []string{"file:///docsite", "file:///docs", "file:///docs/logo"} {
+		m.publishResourceUpdate("server", uri)
+	}
+	for _, want := range []string{"file:///docs", "file:///docs/logo"} {
+		select {
+		case update := <-updates:
+			if update.URI != want {
+				t.Fatalf("update = %#v, want %s", update, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("update for %s was not delivered", want)
+		}
+	}
+	select {
+	case update := <-updates:
+		t.Fatalf("unexpected update %#v", update)
+	default:
+	}
+}
+
+func TestCatalogRequiresAdvertisedCapability(t *testing.T) {
+	m := testManager(func(context.Context, string, ServerConfig) (connection, []*mcpsdk.Tool, error) {
+		return &fakeConnection{}, testTools("tools only"), nil
+	})
+	if _, err := m.Resources(context.Background(), "server"); !errors.Is(err, ErrServerUnavailable) {
+		t.Fatalf("disconnected resources error = %v", err)
+	}
+	if err := m.Connect(context.Background(), "server"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Prompts(context.Background(), "server"); !errors.Is(err, ErrServerUnavailable) {
+		t.Fatalf("prompts error = %v", err)
+	}
+	if _, _, err := m.SubscribeResource(context.Background(), "server", "file:///x"); !errors.Is(err, ErrServerUnavailable) {
+		t.Fatalf("subscribe error = %v", err)
+	}
+	if _, err := m.ReadResource(context.Background(), "missing", "file:///x"); !errors.Is(err, ErrServerNotFound) {
+		t.Fatalf("missing server error = %v", err)
+	}
+	for _, candidate := range m.Tools() {
+		if candidate.Name() == ResourceToolName {
+			t.Fatal("resource tool published without a resource server")
+		}
+	}
+}
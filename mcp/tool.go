@@ -58,20 +58,26 @@ func resultText(res *mcpsdk.CallToolResult) string {
 	}
 	var parts []string
 	for _, content := range res.Content {
-		switch item := content.(type) {
-		case *mcpsdk.TextContent:
-			if strings.TrimSpace(item.Text) != "" {
-				parts = append(parts, item.Text)
-			}
-		default:
-			if b, err := json.Marshal(item); err == nil && len(b) > 0 {
-				parts = append(parts, string(b))
-			}
+		if text := contentText(content); strings.TrimSpace(text) != "" {
+			parts = append(parts, text)
 		}
 	}
 	return strings.Join(parts, "\n\n")
 }
 
+// contentText renders one MCP content item. Text is returned verbatim; other
+// content kinds fall back to their JSON representation.
+func contentText(content mcpsdk.Content) string {
+	if item, ok := content.(*mcpsdk.TextContent); ok {
+		return item.Text
+	}
+	b, err := json.Marshal(content)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
 func resultMetadata(server, remoteName string, res *mcpsdk.CallToolResult) map[string]any {
 	meta := map[string]any{
 		"source":      "mcp",
@@ -0,0 +1,70 @@
+package models
+
+import "context"
+
+// BatchStatus is the provider-neutral state of a batch.
+type BatchStatus string
+
+const (
+	// BatchInProgress covers every state before the provider finishes:
+	// validating, queued, running, finalizing, and cancelling.
+	BatchInProgress BatchStatus = "in_progress"
+	BatchCompleted  BatchStatus = "completed"
+	BatchFailed     BatchStatus = "failed"
+	BatchExpired    BatchStatus = "expired"
+	BatchCancelled  BatchStatus = "cancelled"
+)
+
+// Done reports whether the provider has stopped working on the batch.
+// Expired and cancelled batches still return results for the requests that
+// finished.
+func (s BatchStatus) Done() bool { return s != BatchInProgress }
+
+// BatchRequest is one request of a batch. CustomID matches it to its
+// result and must be unique within the batch.
+type BatchRequest struct {
+	CustomID string  `json:"custom_id"`
+	Request  Request `json:"request"`
+}
+
+// BatchCounts reports a batch's progress by request.
+type BatchCounts struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// Batch is a provider's view of a submitted batch.
+type Batch struct {
+	ID     string      `json:"id"`
+	Status BatchStatus `json:"status"`
+	Counts BatchCounts `json:"counts"`
+	// Error explains a failed batch. Per-request failures are reported on
+	// each BatchResult instead.
+	Error string `json:"error,omitempty"`
+}
+
+// BatchResult is the outcome of one batch request. Exactly one of Message
+// and Error is set. Message carries the request's usage.
+type BatchResult struct {
+	CustomID  string   `json:"custom_id"`
+	Message   *Message `json:"message,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	RequestID string   `json:"request_id,omitempty"`
+}
+
+// BatchCostFactor scales a model's per-token prices for batched requests.
+// Every provider with a batch endpoint bills it at half the regular price.
+const BatchCostFactor = 0.5
+
+// Batcher is implemented by clients that can submit requests to a
+// provider's asynchronous batch endpoint. Batched requests are billed at a
+// discount and complete within the provider's window, typically 24 hours.
+// Every request of a batch must target model.
+type Batcher interface {
+	SubmitBatch(ctx context.Context, model ModelRef, requests []BatchRequest) (*Batch, error)
+	GetBatch(ctx context.Context, model ModelRef, id string) (*Batch, error)
+	// BatchResults returns the results of a finished batch in no
+	// particular order. Requests without a result are omitted.
+	BatchResults(ctx context.Context, model ModelRef, id string) ([]BatchResult, error)
+}
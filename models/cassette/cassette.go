@@ -0,0 +1,232 @@
+// Package cassette records model calls to a file and replays them, so code
+// that drives agent runs can be tested offline and deterministically.
+//
+// Calls are keyed by a hash of their CallTrace. A trace describes a call's
+// structure, not its text, so calls with the same key replay in the order
+// they were recorded.
+package cassette
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/chaserensberger/wingman/models"
+)
+
+// Modes.
+const (
+	ModeRecord = "record"
+	ModeReplay = "replay"
+)
+
+const fileVersion = 1
+
+// ErrNoRecording is returned by a replaying client when the cassette has no
+// unplayed interaction for a call.
+var ErrNoRecording = errors.New("cassette: no recording for call")
+
+// currentDate matches the date sentence sessions add to system prompts so a
+// recording keeps replaying on later days.
+var currentDate = regexp.MustCompile(`Current date: \d{4}-\d{2}-\d{2}\.`)
+
+// Interaction is one recorded model call.
+type Interaction struct {
+	Key   string           `json:"key"`
+	Trace models.CallTrace `json:"trace"`
+	// Request is the provider-native request without headers, kept to
+	// explain a recording. Replay does not compare it.
+	Request *models.PreparedRequest `json:"request,omitempty"`
+	Parts   []json.RawMessage       `json:"parts"`
+	Final   *models.Message         `json:"final,omitempty"`
+	Error   string                  `json:"error,omitempty"`
+}
+
+type file struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Cassette is one cassette file. It is safe for concurrent use.
+type Cassette struct {
+	path string
+	mode string
+
+	mu           sync.Mutex
+	interactions []Interaction
+	played       map[string]int
+}
+
+// Open opens the cassette at path. Replay loads the recorded interactions;
+// record starts empty and rewrites the file after every call.
+func Open(path, mode string) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode, played: map[string]int{}}
+	switch mode {
+	case ModeRecord:
+		return c, nil
+	case ModeReplay:
+	default:
+		return nil, fmt.Errorf("cassette: unknown mode %q", mode)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", path, err)
+	}
+	if f.Version != fileVersion {
+		return nil, fmt.Errorf("cassette %s: unsupported version %d", path, f.Version)
+	}
+	c.interactions = f.Interactions
+	return c, nil
+}
+
+// Path returns the cassette file path.
+func (c *Cassette) Path() string { return c.path }
+
+// Mode returns ModeRecord or ModeReplay.
+func (c *Cassette) Mode() string { return c.mode }
+
+// Key returns the hash of req's CallTrace that interactions are keyed by.
+// The model's route and the current date are left out so a recording made
+// against one endpoint or day replays against another.
+func Key(req models.Request) string {
+	req.System = currentDate.ReplaceAllString(req.System, "")
+	trace := models.NewCallTrace(req, models.LoweredOptions{})
+	trace.Model = models.ModelRef{Provider: req.Model.Provider, ID: req.Model.ID, API: req.Model.API}
+	data, _ := json.Marshal(trace)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Wrap returns a client that records next's calls to the cassette or, when
+// replaying, serves them from it without calling next for streams.
+func (c *Cassette) Wrap(next models.Client) models.Client {
+	return &client{cassette: c, next: next}
+}
+
+type client struct {
+	cassette *Cassette
+	next     models.Client
+}
+
+func (cl *client) Prepare(ctx context.Context, req models.Request) (*models.PreparedRequest, error) {
+	return cl.next.Prepare(ctx, req)
+}
+
+func (cl *client) Generate(ctx context.Context, req models.Request) (*models.Message, error) {
+	return models.Generate(ctx, cl, req)
+}
+
+func (cl *client) Stream(ctx context.Context, req models.Request) (*models.EventStream[models.StreamPart, *models.Message], error) {
+	if cl.cassette.mode == ModeReplay {
+		return cl.cassette.replay(req)
+	}
+	return cl.cassette.record(ctx, cl.next, req)
+}
+
+func (c *Cassette) replay(req models.Request) (*models.EventStream[models.StreamPart, *models.Message], error) {
+	key := Key(req)
+	c.mu.Lock()
+	var found *Interaction
+	seen := 0
+	for i := range c.interactions {
+		if c.interactions[i].Key != key {
+			continue
+		}
+		if seen == c.played[key] {
+			found = &c.interactions[i]
+			c.played[key]++
+			break
+		}
+		seen++
+	}
+	c.mu.Unlock()
+	if found == nil {
+		return nil, fmt.Errorf("%w %s (%s %s, %d messages) in %s", ErrNoRecording, key, req.Model.Provider, req.Model.ID, len(req.Messages), c.path)
+	}
+	parts := make([]models.StreamPart, len(found.Parts))
+	for i, raw := range found.Parts {
+		part, err := models.UnmarshalStreamPart(raw)
+		if err != nil {
+			return nil, fmt.Errorf("cassette %s: interaction %s: %w", c.path, key, err)
+		}
+		parts[i] = part
+	}
+	var streamErr error
+	if found.Error != "" {
+		streamErr = errors.New(found.Error)
+	}
+	stream := models.NewEventStream[models.StreamPart, *models.Message](len(parts))
+	for _, part := range parts {
+		stream.Push(part)
+	}
+	stream.Close(found.Final, streamErr)
+	return stream, nil
+}
+
+// record streams the call from next and appends it to the cassette once the
+// stream ends. Calls that fail before streaming are not recorded, so retries
+// replay as the attempt that succeeded.
+func (c *Cassette) record(ctx context.Context, next models.Client, req models.Request) (*models.EventStream[models.StreamPart, *models.Message], error) {
+	prepared, err := next.Prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	upstream, err := next.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	prepared.Headers = nil
+	interaction := Interaction{Key: Key(req), Trace: models.NewCallTrace(req, models.LoweredOptions{}), Request: prepared}
+	stream := models.NewEventStream[models.StreamPart, *models.Message](64)
+	stream.BindContext(ctx)
+	go func() {
+		for part := range upstream.Iter() {
+			if raw, err := models.MarshalStreamPart(part); err == nil {
+				interaction.Parts = append(interaction.Parts, raw)
+			}
+			stream.Push(part)
+		}
+		final, err := upstream.Final()
+		interaction.Final = final
+		if err != nil {
+			interaction.Error = err.Error()
+		}
+		if saveErr := c.append(interaction); saveErr != nil && err == nil {
+			err = saveErr
+		}
+		stream.Close(final, err)
+	}()
+	return stream, nil
+}
+
+func (c *Cassette) append(interaction Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, interaction)
+	data, err := json.MarshalIndent(file{Version: fileVersion, Interactions: c.interactions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	return nil
+}
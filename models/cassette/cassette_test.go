@@ -0,0 +1,103 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/chaserensberger/wingman/models"
+)
+
+// scriptedClient answers each stream with the next reply.
+type scriptedClient struct {
+	replies []string
+	calls   int
+}
+
+func (c *scriptedClient) Prepare(_ context.Context, req models.Request) (*models.PreparedRequest, error) {
+	return &models.PreparedRequest{Model: req.Model, URL: "https://example.test/v1/chat", Headers: map[string]string{"authorization": "Bearer secret"}}, nil
+}
+
+func (c *scriptedClient) Stream(context.Context, models.Request) (*models.EventStream[models.StreamPart, *models.Message], error) {
+	text := c.replies[c.calls]
+	c.calls++
+	msg := models.NewAssistantText(text)
+	stream := models.NewEventStream[models.StreamPart, *models.Message](4)
+	stream.Push(models.StreamStartPart{})
+	stream.Push(models.TextDeltaPart{ID: "t", Delta: text})
+	stream.Push(models.FinishPart{Reason: models.FinishReasonStop, Message: &msg})
+	stream.Close(&msg, nil)
+	return stream, nil
+}
+
+func (c *scriptedClient) Generate(ctx context.Context, req models.Request) (*models.Message, error) {
+	return models.Generate(ctx, c, req)
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "session.json")
+	model := models.ModelRef{Provider: "test", ID: "model", BaseURL: "http://127.0.0.1:1234"}
+	first := models.Request{Model: model, System: "Be brief. Current date: 2026-01-02.", Messages: []models.Message{models.NewUserText("hi")}}
+	followUp := models.Request{Model: model, System: first.System, Messages: []models.Message{models.NewUserText("hi"), models.NewAssistantText("hello"), models.NewUserText("again")}}
+
+	recorder, err := Open(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream := &scriptedClient{replies: []string{"hello", "hello again", "bye"}}
+	client := recorder.Wrap(upstream)
+	for _, req := range []models.Request{first, followUp, first} {
+		if _, err := client.Generate(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replayer, err := Open(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayer.interactions) != 3 || replayer.interactions[0].Request.Headers != nil || replayer.interactions[0].Request.URL == "" {
+		t.Fatalf("interactions = %#v", replayer.interactions)
+	}
+	client = replayer.Wrap(&scriptedClient{})
+	// A later day and another endpoint replay the same recording, and calls
+	// with the same trace replay in recorded order.
+	first.System = "Be brief. Current date: 2027-05-06."
+	first.Model.BaseURL = "http://127.0.0.1:5678"
+	for _, tc := range []struct {
+		req  models.Request
+		want string
+	}{{first, "hello"}, {first, "bye"}, {followUp, "hello again"}} {
+		stream, err := client.Stream(context.Background(), tc.req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var deltas string
+		for part := range stream.Iter() {
+			if delta, ok := part.(models.TextDeltaPart); ok {
+				deltas += delta.Delta
+			}
+		}
+		msg, err := stream.Final()
+		if err != nil || deltas != tc.want || msg.Content[0].(models.TextPart).Text != tc.want {
+			t.Fatalf("replay = %q, %#v, %v; want %q", deltas, msg, err, tc.want)
+		}
+	}
+	if _, err := client.Stream(context.Background(), first); !errors.Is(err, ErrNoRecording) {
+		t.Fatalf("exhausted replay error = %v", err)
+	}
+	first.System = "Be verbose."
+	if Key(first) == Key(followUp) || Key(first) == Key(models.Request{Model: model, System: "Be brief.", Messages: first.Messages}) {
+		t.Fatal("keys ignore system prompt or messages")
+	}
+}
+
+func TestOpenRejectsUnknownModeAndMissingFile(t *testing.T) {
+	if _, err := Open("x.json", "rewind"); err == nil {
+		t.Fatal("unknown mode opened")
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.json"), ModeReplay); err == nil {
+		t.Fatal("missing cassette opened for replay")
+	}
+}
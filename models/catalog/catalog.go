@@ -36,6 +36,11 @@ type modelFile struct {
 		Reasoning        bool `toml:"reasoning"`
 		StructuredOutput bool `toml:"structured_output"`
 	} `toml:"capabilities"`
+	Embedding struct {
+		Dimensions     int `toml:"dimensions"`
+		MaxInputTokens int `toml:"max_input_tokens"`
+		MaxBatchSize   int `toml:"max_batch_size"`
+	} `toml:"embedding"`
 }
 type providerFile struct {
 	Name    string   `toml:"name"`
@@ -170,12 +175,16 @@ func loadEmbedded(c *Catalog) error {
 			if src.ID == "" || src.API == "" {
 				return fmt.Errorf("%s: id and api are required", path)
 			}
+			embedding := src.API == string(models.APIOpenAIEmbeddings) || src.API == string(models.APIGeminiEmbed)
+			if embedding != (src.Embedding.Dimensions > 0) {
+				return fmt.Errorf("%s: embedding.dimensions is required for embedding APIs only", path)
+			}
 			if src.BaseModel != "" {
 				if _, ok := c.canonicalModels[src.BaseModel]; !ok {
 					return fmt.Errorf("%s: unknown base_model %q", path, src.BaseModel)
 				}
 			}
-			info := models.ModelInfo{Provider: src.Provider, ID: src.ID, API: models.API(src.API), BaseURL: src.BaseURL, Env: append([]string(nil), src.Env...), ContextWindow: src.ContextWindow, MaxOutput: src.MaxOutput, InputCostPerMTok: src.InputCost, OutputCostPerMTok: src.OutputCost, Capabilities: models.ModelCapabilities{Tools: src.Capabilities.Tools, Images: src.Capabilities.Images, Reasoning: src.Capabilities.Reasoning, StructuredOutput: src.Capabilities.StructuredOutput}}
+			info := models.ModelInfo{Provider: src.Provider, ID: src.ID, API: models.API(src.API), BaseURL: src.BaseURL, Env: append([]string(nil), src.Env...), ContextWindow: src.ContextWindow, MaxOutput: src.MaxOutput, InputCostPerMTok: src.InputCost, OutputCostPerMTok: src.OutputCost, Capabilities: models.ModelCapabilities{Tools: src.Capabilities.Tools, Images: src.Capabilities.Images, Reasoning: src.Capabilities.Reasoning, StructuredOutput: src.Capabilities.StructuredOutput}, Embedding: models.EmbeddingInfo{Dimensions: src.Embedding.Dimensions, MaxInputTokens: src.Embedding.MaxInputTokens, MaxBatchSize: src.Embedding.MaxBatchSize}}
 			c.addRoute(info, src.BaseModel)
 		}
 	}
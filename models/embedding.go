@@ -0,0 +1,48 @@
+package models
+
+import (
+	"context"
+	"fmt"
+)
+
+// EmbeddingInfo describes an embedding model's output and input limits.
+type EmbeddingInfo struct {
+	// Dimensions is the length of the model's default output vector.
+	Dimensions int `json:"dimensions"`
+	// MaxInputTokens is the longest single input the model accepts.
+	MaxInputTokens int `json:"max_input_tokens,omitempty"`
+	// MaxBatchSize is how many inputs one provider request may carry.
+	// Clients split larger requests into batches of this size.
+	MaxBatchSize int `json:"max_batch_size,omitempty"`
+}
+
+// EmbeddingRequest asks an embedding model to embed each input.
+type EmbeddingRequest struct {
+	Model ModelRef `json:"model"`
+	Input []string `json:"input"`
+	// Dimensions shortens the output vectors on models that support it.
+	Dimensions int `json:"dimensions,omitempty"`
+}
+
+// EmbeddingResponse holds one vector per request input, in input order.
+type EmbeddingResponse struct {
+	Model      ModelRef    `json:"model"`
+	Embeddings [][]float32 `json:"embeddings"`
+	// Usage sums the tokens the provider reported across every batch.
+	// Providers that do not report embedding usage leave it empty.
+	Usage Usage `json:"usage"`
+}
+
+// Embedder is implemented by clients that can embed text.
+type Embedder interface {
+	Embed(context.Context, EmbeddingRequest) (*EmbeddingResponse, error)
+}
+
+// Embed embeds req.Input with c, failing when c does not support embeddings.
+func Embed(ctx context.Context, c Client, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	embedder, ok := c.(Embedder)
+	if !ok {
+		return nil, &ProviderError{Category: ErrorInvalidRequest, Provider: req.Model.Provider, Message: fmt.Sprintf("client does not support embeddings for %s", req.Model.Ref())}
+	}
+	return embedder.Embed(ctx, req)
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"time"
 )
@@ -58,3 +59,26 @@ func (e *ProviderError) Unwrap() error { return e.Cause }
 
 // ProviderRequestID returns the provider request ID when one was supplied.
 func (e *ProviderError) ProviderRequestID() string { return e.RequestID }
+
+// ThrottledError wraps the failure of a request that a rate limiter held
+// back first, so callers can still account for the time it waited.
+type ThrottledError struct {
+	Err       error
+	Throttled time.Duration
+}
+
+// Error returns the wrapped error's message.
+func (e *ThrottledError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the wrapped error.
+func (e *ThrottledError) Unwrap() error { return e.Err }
+
+// ThrottledFor returns how long a rate limiter held the request that failed
+// with err, or zero when it was not held.
+func ThrottledFor(err error) time.Duration {
+	var throttled *ThrottledError
+	if errors.As(err, &throttled) {
+		return throttled.Throttled
+	}
+	return 0
+}
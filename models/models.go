@@ -63,6 +63,10 @@ const (
 	APIOpenAICompatible  API = "openai_compatible_chat"
 	APIAnthropicMessages API = "anthropic_messages"
 	APIGeminiGenerate    API = "gemini_generate"
+	APIOpenAIEmbeddings  API = "openai_embeddings"
+	APIGeminiEmbed       API = "gemini_embed"
+	// APIMock is served in process by the mock provider.
+	APIMock API = "mock"
 )
 
 // ------------------------------------------------------------------
@@ -830,6 +834,9 @@ type ModelInfo struct {
 	Capabilities      ModelCapabilities `json:"capabilities"`
 	InputCostPerMTok  float64           `json:"input_cost_per_mtok,omitempty"`
 	OutputCostPerMTok float64           `json:"output_cost_per_mtok,omitempty"`
+
+	// Embedding describes the vectors of models with an embedding API.
+	Embedding EmbeddingInfo `json:"embedding,omitzero"`
 }
 
 type ModelCapabilities struct {
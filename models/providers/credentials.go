@@ -0,0 +1,218 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chaserensberger/wingman/models"
+	"github.com/chaserensberger/wingman/models/providers/internal/httpmodel"
+)
+
+// Credential strategies choose which credential in a provider's set a
+// request tries first. Either way a request that the provider rejects with
+// 401 or 429 is sent again with the next credential.
+const (
+	// CredentialFailover starts every request with the first credential.
+	CredentialFailover = "failover"
+	// CredentialRoundRobin starts each request with the next credential.
+	CredentialRoundRobin = "round_robin"
+)
+
+// DefaultCredential names a provider's single stored credential when it
+// joins a set of named credentials.
+const DefaultCredential = "default"
+
+// CredentialOptions configure how requests use a provider's credential set.
+type CredentialOptions struct {
+	Strategy string `json:"strategy,omitempty"`
+}
+
+// CredentialUsage reports how requests have used one credential in a set
+// since the registry was created. It is not persisted.
+type CredentialUsage struct {
+	Provider string
+	Name     string
+	// Requests counts requests sent with the credential.
+	Requests int64
+	// Unauthorized and RateLimited count requests the provider rejected
+	// with 401 and 429.
+	Unauthorized int64
+	RateLimited  int64
+	LastUsed     time.Time
+	// BlockedUntil is when the credential's rate limiter lets requests
+	// resume, if later than now.
+	BlockedUntil time.Time
+}
+
+type credentialKey struct{ provider, name string }
+
+// credentialState is a registry's mutable credential bookkeeping: the
+// round-robin position of each provider, and the rate limiter and usage of
+// each named credential.
+type credentialState struct {
+	mu       sync.Mutex
+	next     map[string]int
+	limiters map[credentialKey]*httpmodel.Limiter
+	usage    map[credentialKey]*CredentialUsage
+}
+
+func newCredentialState() *credentialState {
+	return &credentialState{
+		next:     map[string]int{},
+		limiters: map[credentialKey]*httpmodel.Limiter{},
+		usage:    map[credentialKey]*CredentialUsage{},
+	}
+}
+
+// NewClientWithCredentialSets creates a client whose providers may hold
+// several named credentials. Each set lists a provider's credentials in
+// failover order. A provider without a set falls back to its single
+// credential in credentials.
+func (r *Registry) NewClientWithCredentialSets(credentials map[string]Credential, sets map[string][]Credential, refresh func(context.Context, string, Credential) (Credential, error)) *Client {
+	client := r.NewClientWithCredentials(credentials, refresh)
+	client.CredentialSets = sets
+	return client
+}
+
+// CredentialUsage reports a provider's named credentials that requests
+// have used, in name order.
+func (r *Registry) CredentialUsage(providerID string) []CredentialUsage {
+	r.credentials.mu.Lock()
+	defer r.credentials.mu.Unlock()
+	out := []CredentialUsage{}
+	for key, usage := range r.credentials.usage {
+		if key.provider != providerID {
+			continue
+		}
+		report := *usage
+		if status := r.credentialLimiterLocked(key).Status(); !status.BlockedUntil.IsZero() {
+			report.BlockedUntil = status.BlockedUntil
+		}
+		out = append(out, report)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// credentialLimiter returns the rate limiter of one credential. The default
+// credential shares the provider's limiter; every other credential has its
+// own with the provider's budget, since providers limit each key or
+// organization separately.
+func (r *Registry) credentialLimiter(providerID, name string) *httpmodel.Limiter {
+	r.credentials.mu.Lock()
+	defer r.credentials.mu.Unlock()
+	return r.credentialLimiterLocked(credentialKey{providerID, name})
+}
+
+func (r *Registry) credentialLimiterLocked(key credentialKey) *httpmodel.Limiter {
+	if key.name == "" || key.name == DefaultCredential {
+		return r.limiters[key.provider]
+	}
+	limiter := r.credentials.limiters[key]
+	if limiter == nil {
+		var budget RateLimitOptions
+		if opts := r.configs[key.provider].Options.RateLimit; opts != nil {
+			budget = *opts
+		}
+		limiter = httpmodel.NewLimiter(budget.RequestsPerMinute, budget.TokensPerMinute)
+		r.credentials.limiters[key] = limiter
+	}
+	return limiter
+}
+
+// recordCredential counts one request sent with a named credential.
+func (r *Registry) recordCredential(providerID, name string, err error) {
+	r.credentials.mu.Lock()
+	defer r.credentials.mu.Unlock()
+	key := credentialKey{providerID, name}
+	usage := r.credentials.usage[key]
+	if usage == nil {
+		usage = &CredentialUsage{Provider: providerID, Name: name}
+		r.credentials.usage[key] = usage
+	}
+	usage.Requests++
+	usage.LastUsed = time.Now()
+	var providerErr *models.ProviderError
+	if errors.As(err, &providerErr) {
+		switch providerErr.Category {
+		case models.ErrorAuthentication:
+			usage.Unauthorized++
+		case models.ErrorRateLimit:
+			usage.RateLimited++
+		}
+	}
+}
+
+// credentialOrder returns the credentials a request to providerID tries, in
+// order: the set rotated by the provider's strategy, with credentials whose
+// rate limiter is blocked moved last.
+func (c *Client) credentialOrder(providerID string) []Credential {
+	set := c.CredentialSets[providerID]
+	if len(set) < 2 {
+		return set
+	}
+	order := make([]Credential, 0, len(set))
+	start := 0
+	if opts := c.registry.configs[providerID].Options.Credentials; opts != nil && opts.Strategy == CredentialRoundRobin {
+		c.registry.credentials.mu.Lock()
+		start = c.registry.credentials.next[providerID] % len(set)
+		c.registry.credentials.next[providerID] = start + 1
+		c.registry.credentials.mu.Unlock()
+	}
+	order = append(order, set[start:]...)
+	order = append(order, set[:start]...)
+	blocked := make(map[string]bool, len(order))
+	for _, credential := range order {
+		blocked[credential.Name] = !c.registry.credentialLimiter(providerID, credential.Name).Status().BlockedUntil.IsZero()
+	}
+	sort.SliceStable(order, func(i, j int) bool { return !blocked[order[i].Name] && blocked[order[j].Name] })
+	return order
+}
+
+// withCredentials calls call with the model route for ref, once per
+// credential in the provider's set until one is not rejected with 401 or
+// 429. A provider without a set uses its single credential.
+func (c *Client) withCredentials(ref models.ModelRef, call func(*httpmodel.Model) error) error {
+	info, err := resolveModelInfo(c.registry.catalog, ref)
+	if err != nil {
+		return err
+	}
+	order := c.credentialOrder(info.Provider)
+	if len(order) == 0 {
+		m, err := c.model(ref)
+		if err != nil {
+			return err
+		}
+		return call(m)
+	}
+	for i, credential := range order {
+		m, err := c.modelWithCredential(ref, &credential)
+		if err != nil {
+			return err
+		}
+		m.Credential = credential.Name
+		m.Limiter = c.registry.credentialLimiter(info.Provider, credential.Name)
+		m.FailFast = i < len(order)-1
+		err = call(m)
+		c.registry.recordCredential(info.Provider, credential.Name, err)
+		if err == nil || !credentialRejected(err) {
+			return err
+		}
+		if i == len(order)-1 {
+			return fmt.Errorf("all %d %s credentials were rejected: %w", len(order), info.Provider, err)
+		}
+	}
+	return nil
+}
+
+// credentialRejected reports whether err is a 401 or 429 that another
+// credential may not hit.
+func credentialRejected(err error) bool {
+	var providerErr *models.ProviderError
+	return errors.As(err, &providerErr) &&
+		(providerErr.Category == models.ErrorAuthentication || providerErr.Category == models.ErrorRateLimit)
+}
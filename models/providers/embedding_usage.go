@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chaserensberger/wingman/models"
+)
+
+// EmbeddingUsage sums the embedding calls made with one model since the
+// registry was created.
+type EmbeddingUsage struct {
+	Provider string
+	Model    string
+	// Requests counts Embed calls and Inputs the texts they embedded.
+	Requests int64
+	Inputs   int64
+	// Failures counts calls that returned an error.
+	Failures    int64
+	InputTokens int64
+	TotalTokens int64
+	// Cost is estimated from catalog pricing, and zero when the catalog has
+	// none.
+	Cost     float64
+	LastUsed time.Time
+}
+
+type embeddingModel struct{ provider, id string }
+
+type embeddingUsageState struct {
+	mu    sync.Mutex
+	usage map[embeddingModel]*EmbeddingUsage
+}
+
+// EmbeddingUsage reports embedding usage by provider and model.
+func (r *Registry) EmbeddingUsage() []EmbeddingUsage {
+	r.embeddings.mu.Lock()
+	defer r.embeddings.mu.Unlock()
+	out := make([]EmbeddingUsage, 0, len(r.embeddings.usage))
+	for _, usage := range r.embeddings.usage {
+		out = append(out, *usage)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		return out[i].Model < out[j].Model
+	})
+	return out
+}
+
+// recordEmbedding adds one Embed call to the model's usage.
+func (r *Registry) recordEmbedding(req models.EmbeddingRequest, resp *models.EmbeddingResponse, err error) {
+	key := embeddingModel{req.Model.Provider, req.Model.ID}
+	r.embeddings.mu.Lock()
+	defer r.embeddings.mu.Unlock()
+	if r.embeddings.usage == nil {
+		r.embeddings.usage = map[embeddingModel]*EmbeddingUsage{}
+	}
+	usage := r.embeddings.usage[key]
+	if usage == nil {
+		usage = &EmbeddingUsage{Provider: key.provider, Model: key.id}
+		r.embeddings.usage[key] = usage
+	}
+	usage.Requests++
+	usage.Inputs += int64(len(req.Input))
+	usage.LastUsed = time.Now()
+	if err != nil || resp == nil {
+		usage.Failures++
+		return
+	}
+	usage.InputTokens += int64(resp.Usage.InputTokens)
+	usage.TotalTokens += int64(resp.Usage.TotalTokens)
+	if info, ok := r.catalog.Get(key.provider, key.id); ok {
+		usage.Cost += float64(resp.Usage.InputTokens) * info.InputCostPerMTok / 1e6
+	}
+}
@@ -64,7 +64,7 @@ func defaultAuth(protocol Protocol, apiKey string) Auth {
 	if protocol == AnthropicMessages {
 		return HeaderAuth("x-api-key", apiKey)
 	}
-	if protocol == GeminiGenerate {
+	if protocol == GeminiGenerate || protocol == GeminiEmbed {
 		return HeaderAuth("x-goog-api-key", apiKey)
 	}
 	return BearerAuth(apiKey)
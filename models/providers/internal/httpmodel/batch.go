@@ -0,0 +1,551 @@
+package httpmodel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/chaserensberger/wingman/models"
+)
+
+const (
+	// maxBatchResponse bounds one decoded batch metadata response.
+	maxBatchResponse = 16 << 20
+	// maxBatchLine bounds one line of a batch results file.
+	maxBatchLine = 64 << 20
+)
+
+// Batches reports whether p has a provider batch endpoint.
+func (p Protocol) Batches() bool {
+	return p == OpenAIResponses || p == OpenAIChat || p == AnthropicMessages
+}
+
+// SubmitBatch lowers each request as Stream would, without streaming, and
+// submits them as one provider batch. OpenAI batches are uploaded as a JSONL
+// file; Anthropic batches are sent inline.
+func (m *Model) SubmitBatch(ctx context.Context, requests []models.BatchRequest) (*models.Batch, error) {
+	if !m.Protocol.Batches() {
+		return nil, m.batchRequestError(fmt.Sprintf("%s does not support batch requests", m.Info_.ID))
+	}
+	if len(requests) == 0 {
+		return nil, m.batchRequestError("batch requires at least one request")
+	}
+	seen := make(map[string]bool, len(requests))
+	bodies := make([]map[string]any, len(requests))
+	for i, request := range requests {
+		if request.CustomID == "" || seen[request.CustomID] {
+			return nil, m.batchRequestError(fmt.Sprintf("batch request %d needs a unique custom_id", i))
+		}
+		seen[request.CustomID] = true
+		body, err := m.body(request.Request)
+		if err != nil {
+			return nil, err
+		}
+		delete(body, "stream")
+		delete(body, "stream_options")
+		bodies[i] = body
+	}
+	if m.Protocol == AnthropicMessages {
+		return m.submitAnthropicBatch(ctx, requests, bodies)
+	}
+	return m.submitOpenAIBatch(ctx, requests, bodies)
+}
+
+// GetBatch returns the provider's current view of batch id.
+func (m *Model) GetBatch(ctx context.Context, id string) (*models.Batch, error) {
+	if !m.Protocol.Batches() {
+		return nil, m.batchRequestError(fmt.Sprintf("%s does not support batch requests", m.Info_.ID))
+	}
+	if m.Protocol == AnthropicMessages {
+		var batch anthropicBatch
+		if err := m.batchCall(ctx, http.MethodGet, "/messages/batches/"+url.PathEscape(id), "", nil, &batch); err != nil {
+			return nil, err
+		}
+		return batch.batch(), nil
+	}
+	var batch openAIBatch
+	if err := m.batchCall(ctx, http.MethodGet, "/batches/"+url.PathEscape(id), "", nil, &batch); err != nil {
+		return nil, err
+	}
+	return batch.batch(), nil
+}
+
+// BatchResults downloads the results of a finished batch. Successful
+// responses are parsed into assistant messages with their usage.
+func (m *Model) BatchResults(ctx context.Context, id string) ([]models.BatchResult, error) {
+	if !m.Protocol.Batches() {
+		return nil, m.batchRequestError(fmt.Sprintf("%s does not support batch requests", m.Info_.ID))
+	}
+	if m.Protocol == AnthropicMessages {
+		return m.anthropicBatchResults(ctx, id)
+	}
+	return m.openAIBatchResults(ctx, id)
+}
+
+func (m *Model) batchRequestError(message string) *models.ProviderError {
+	return &models.ProviderError{Category: models.ErrorInvalidRequest, Provider: m.Info_.Provider, Message: message}
+}
+
+// openAIBatchEndpoint is the endpoint every line of an OpenAI batch file
+// targets.
+func (m *Model) openAIBatchEndpoint() string {
+	if m.Protocol == OpenAIChat {
+		return "/v1/chat/completions"
+	}
+	return "/v1/responses"
+}
+
+func (m *Model) submitOpenAIBatch(ctx context.Context, requests []models.BatchRequest, bodies []map[string]any) (*models.Batch, error) {
+	endpoint := m.openAIBatchEndpoint()
+	var lines bytes.Buffer
+	encoder := json.NewEncoder(&lines)
+	for i, request := range requests {
+		line := map[string]any{"custom_id": request.CustomID, "method": http.MethodPost, "url": endpoint, "body": bodies[i]}
+		if err := encoder.Encode(line); err != nil {
+			return nil, fmt.Errorf("marshal %s batch request: %w", m.Info_.Provider, err)
+		}
+	}
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	if err := writer.WriteField("purpose", "batch"); err != nil {
+		return nil, err
+	}
+	file, err := writer.CreateFormFile("file", "batch.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(lines.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	var uploaded struct {
+		ID string `json:"id"`
+	}
+	if err := m.batchCall(ctx, http.MethodPost, "/files", writer.FormDataContentType(), form.Bytes(), &uploaded); err != nil {
+		return nil, err
+	}
+	if uploaded.ID == "" {
+		return nil, decodingError(m.Info_.Provider, "provider returned no batch file id", nil)
+	}
+	body, err := json.Marshal(map[string]any{"input_file_id": uploaded.ID, "endpoint": endpoint, "completion_window": "24h"})
+	if err != nil {
+		return nil, err
+	}
+	var batch openAIBatch
+	if err := m.batchCall(ctx, http.MethodPost, "/batches", "application/json", body, &batch); err != nil {
+		return nil, err
+	}
+	if batch.ID == "" {
+		return nil, decodingError(m.Info_.Provider, "provider returned no batch id", nil)
+	}
+	return batch.batch(), nil
+}
+
+func (m *Model) openAIBatchResults(ctx context.Context, id string) ([]models.BatchResult, error) {
+	var batch openAIBatch
+	if err := m.batchCall(ctx, http.MethodGet, "/batches/"+url.PathEscape(id), "", nil, &batch); err != nil {
+		return nil, err
+	}
+	var results []models.BatchResult
+	// Successful responses are in the output file and failed ones in the
+	// error file; either may be absent.
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		err := m.batchLines(ctx, "/files/"+url.PathEscape(fileID)+"/content", func(raw []byte) error {
+			var line openAIBatchLine
+			if err := json.Unmarshal(raw, &line); err != nil {
+				return decodingError(m.Info_.Provider, "invalid batch result line", err)
+			}
+			result := models.BatchResult{CustomID: line.CustomID}
+			switch {
+			case line.Error != nil && line.Error.Message != "":
+				result.Error = line.Error.Message
+			case line.Response == nil:
+				result.Error = "provider returned no response"
+			case line.Response.StatusCode < 200 || line.Response.StatusCode >= 300:
+				result.RequestID = line.Response.RequestID
+				result.Error = openAIErrorMessage(line.Response.StatusCode, line.Response.Body)
+			default:
+				result.RequestID = line.Response.RequestID
+				msg, err := m.parseBatchMessage(line.Response.Body)
+				if err != nil {
+					result.Error = err.Error()
+				} else {
+					result.Message = msg
+				}
+			}
+			results = append(results, result)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (m *Model) submitAnthropicBatch(ctx context.Context, requests []models.BatchRequest, bodies []map[string]any) (*models.Batch, error) {
+	items := make([]map[string]any, len(requests))
+	for i, request := range requests {
+		items[i] = map[string]any{"custom_id": request.CustomID, "params": bodies[i]}
+	}
+	body, err := json.Marshal(map[string]any{"requests": items})
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s batch request: %w", m.Info_.Provider, err)
+	}
+	var batch anthropicBatch
+	if err := m.batchCall(ctx, http.MethodPost, "/messages/batches", "application/json", body, &batch); err != nil {
+		return nil, err
+	}
+	if batch.ID == "" {
+		return nil, decodingError(m.Info_.Provider, "provider returned no batch id", nil)
+	}
+	return batch.batch(), nil
+}
+
+func (m *Model) anthropicBatchResults(ctx context.Context, id string) ([]models.BatchResult, error) {
+	// The results path is fixed, so the batch's results_url is not trusted
+	// with the API key.
+	var results []models.BatchResult
+	err := m.batchLines(ctx, "/messages/batches/"+url.PathEscape(id)+"/results", func(raw []byte) error {
+		var line anthropicBatchLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			return decodingError(m.Info_.Provider, "invalid batch result line", err)
+		}
+		result := models.BatchResult{CustomID: line.CustomID}
+		switch line.Result.Type {
+		case "succeeded":
+			msg, err := m.parseBatchMessage(line.Result.Message)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Message = msg
+			}
+		case "errored":
+			result.Error = line.Result.Error.Error.Message
+			if result.Error == "" {
+				result.Error = "request failed"
+			}
+		case "canceled":
+			result.Error = "request cancelled"
+		case "expired":
+			result.Error = "request expired"
+		default:
+			result.Error = fmt.Sprintf("unknown result type %q", line.Result.Type)
+		}
+		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// batchCall sends one batch API request and decodes its JSON response
+// into out.
+func (m *Model) batchCall(ctx context.Context, method, path, contentType string, body []byte, out any) error {
+	resp, err := m.batchSend(ctx, method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxBatchResponse))
+	if err != nil {
+		return transportError(m.Info_.Provider, err)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return decodingError(m.Info_.Provider, "invalid batch response", err)
+	}
+	return nil
+}
+
+// batchLines downloads a JSONL results file and calls fn with each
+// non-empty line.
+func (m *Model) batchLines(ctx context.Context, path string, fn func([]byte) error) error {
+	resp, err := m.batchSend(ctx, http.MethodGet, path, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBatchLine)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if isTransportError(err) || ctx.Err() != nil {
+			return transportError(m.Info_.Provider, err)
+		}
+		return decodingError(m.Info_.Provider, "invalid batch results file", err)
+	}
+	return nil
+}
+
+func (m *Model) batchSend(ctx context.Context, method, path, contentType string, body []byte) (*http.Response, error) {
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	route := m.route(models.Request{})
+	return (streamTransport{client: client}).send(ctx, m.Info_.Provider, route, method, route.resourceURL(path), contentType, nil, body)
+}
+
+type openAIBatch struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	OutputFileID  string `json:"output_file_id"`
+	ErrorFileID   string `json:"error_file_id"`
+	RequestCounts struct {
+		Total     int `json:"total"`
+		Completed int `json:"completed"`
+		Failed    int `json:"failed"`
+	} `json:"request_counts"`
+	Errors struct {
+		Data []struct {
+			Message string `json:"message"`
+		} `json:"data"`
+	} `json:"errors"`
+}
+
+func (b openAIBatch) batch() *models.Batch {
+	status := models.BatchInProgress
+	switch b.Status {
+	case "completed":
+		status = models.BatchCompleted
+	case "failed":
+		status = models.BatchFailed
+	case "expired":
+		status = models.BatchExpired
+	case "cancelled":
+		status = models.BatchCancelled
+	}
+	out := &models.Batch{ID: b.ID, Status: status, Counts: models.BatchCounts{Total: b.RequestCounts.Total, Succeeded: b.RequestCounts.Completed, Failed: b.RequestCounts.Failed}}
+	messages := make([]string, 0, len(b.Errors.Data))
+	for _, e := range b.Errors.Data {
+		if e.Message != "" {
+			messages = append(messages, e.Message)
+		}
+	}
+	out.Error = strings.Join(messages, "; ")
+	return out
+}
+
+type openAIBatchLine struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		RequestID  string          `json:"request_id"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// openAIErrorMessage returns the error message of a failed response body.
+func openAIErrorMessage(status int, body json.RawMessage) string {
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &resp) == nil && resp.Error.Message != "" {
+		return resp.Error.Message
+	}
+	return fmt.Sprintf("provider returned status %d", status)
+}
+
+type anthropicBatch struct {
+	ID                string `json:"id"`
+	ProcessingStatus  string `json:"processing_status"`
+	CancelInitiatedAt string `json:"cancel_initiated_at"`
+	RequestCounts     struct {
+		Processing int `json:"processing"`
+		Succeeded  int `json:"succeeded"`
+		Errored    int `json:"errored"`
+		Canceled   int `json:"canceled"`
+		Expired    int `json:"expired"`
+	} `json:"request_counts"`
+}
+
+func (b anthropicBatch) batch() *models.Batch {
+	counts := b.RequestCounts
+	status := models.BatchInProgress
+	if b.ProcessingStatus == "ended" {
+		// Anthropic ends every batch; a cancelled one keeps the results of
+		// requests that finished first.
+		status = models.BatchCompleted
+		if b.CancelInitiatedAt != "" {
+			status = models.BatchCancelled
+		}
+	}
+	return &models.Batch{ID: b.ID, Status: status, Counts: models.BatchCounts{
+		Total:     counts.Processing + counts.Succeeded + counts.Errored + counts.Canceled + counts.Expired,
+		Succeeded: counts.Succeeded,
+		Failed:    counts.Errored + counts.Canceled + counts.Expired,
+	}}
+}
+
+type anthropicBatchLine struct {
+	CustomID string `json:"custom_id"`
+	Result   struct {
+		Type    string          `json:"type"`
+		Message json.RawMessage `json:"message"`
+		Error   struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"error"`
+	} `json:"result"`
+}
+
+// parseBatchMessage parses one non-streaming response body into the
+// assistant message Stream would have produced.
+func (m *Model) parseBatchMessage(raw json.RawMessage) (*models.Message, error) {
+	state := parseState{provider: m.Info_.Provider, api: m.Info_.API, model: m.Info_.ID, finish: models.FinishReasonStop}
+	var err error
+	switch m.Protocol {
+	case OpenAIResponses:
+		err = parseOpenAIResponsesBody(raw, &state)
+	case OpenAIChat:
+		err = parseOpenAIChatBody(raw, &state)
+	case AnthropicMessages:
+		err = parseAnthropicBody(raw, &state)
+	}
+	if err != nil {
+		return nil, err
+	}
+	msg := state.message()
+	if !state.usage.Empty() {
+		usage := state.usage
+		msg.Usage = &usage
+	}
+	return msg, nil
+}
+
+func parseOpenAIResponsesBody(raw json.RawMessage, state *parseState) error {
+	var resp struct {
+		Output []struct {
+			openAIResponsesOutputItem
+			Content []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"content"`
+			Summary []struct {
+				Text string `json:"text"`
+			} `json:"summary"`
+		} `json:"output"`
+		openAIResponsesResponse
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return decodingError(state.provider, "invalid OpenAI Responses body", err)
+	}
+	for _, item := range resp.Output {
+		switch item.Type {
+		case "message":
+			for _, content := range item.Content {
+				if content.Type == "output_text" {
+					state.text.WriteString(content.Text)
+				}
+			}
+		case "reasoning":
+			state.reasonID = item.ID
+			state.sig = item.EncryptedContent
+			for _, summary := range item.Summary {
+				state.reason.WriteString(summary.Text)
+			}
+		case "function_call":
+			input, err := decodeArgs(item.Arguments)
+			if err != nil {
+				return decodingError(state.provider, "invalid tool arguments", err)
+			}
+			state.tools = append(state.tools, models.ToolCallPart{CallID: item.CallID, Name: item.Name, Input: input})
+		}
+	}
+	state.finish = finishReason(resp.IncompleteDetails.Reason, len(state.tools) > 0)
+	state.usage = openAIResponsesUsage(resp.Usage)
+	return nil
+}
+
+func parseOpenAIChatBody(raw json.RawMessage, state *parseState) error {
+	var resp struct {
+		Choices []struct {
+			Message      openAIChatDelta `json:"message"`
+			FinishReason string          `json:"finish_reason"`
+		} `json:"choices"`
+		Usage *openAIChatUsageEvent `json:"usage"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return decodingError(state.provider, "invalid OpenAI Chat body", err)
+	}
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		state.text.WriteString(choice.Message.Content)
+		state.reason.WriteString(choice.Message.ReasoningContent)
+		for _, call := range choice.Message.ToolCalls {
+			input, err := decodeArgs(call.Function.Arguments)
+			if err != nil {
+				return decodingError(state.provider, "invalid tool arguments", err)
+			}
+			state.tools = append(state.tools, models.ToolCallPart{CallID: call.ID, Name: call.Function.Name, Input: input})
+		}
+		state.finish = finishReason(choice.FinishReason, len(state.tools) > 0)
+	}
+	state.usage = openAIChatUsage(resp.Usage)
+	return nil
+}
+
+func parseAnthropicBody(raw json.RawMessage, state *parseState) error {
+	var resp struct {
+		Content []struct {
+			Type      string         `json:"type"`
+			Text      string         `json:"text"`
+			Thinking  string         `json:"thinking"`
+			Signature string         `json:"signature"`
+			ID        string         `json:"id"`
+			Name      string         `json:"name"`
+			Input     map[string]any `json:"input"`
+		} `json:"content"`
+		StopReason string              `json:"stop_reason"`
+		Usage      anthropicUsageEvent `json:"usage"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return decodingError(state.provider, "invalid Anthropic body", err)
+	}
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			state.text.WriteString(block.Text)
+		case "thinking":
+			state.reason.WriteString(block.Thinking)
+			state.sig = block.Signature
+		case "tool_use":
+			input := block.Input
+			if input == nil {
+				input = map[string]any{}
+			}
+			state.tools = append(state.tools, models.ToolCallPart{CallID: block.ID, Name: block.Name, Input: input})
+		}
+	}
+	state.finish = finishReason(resp.StopReason, len(state.tools) > 0)
+	state.usage = anthropicUsage(resp.Usage)
+	return nil
+}
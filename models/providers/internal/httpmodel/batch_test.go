@@ -0,0 +1,184 @@
+package httpmodel
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/chaserensberger/wingman/models"
+)
+
+func batchRequests(prompts ...string) []models.BatchRequest {
+	requests := make([]models.BatchRequest, len(prompts))
+	for i, prompt := range prompts {
+		requests[i] = models.BatchRequest{CustomID: "item_" + string(rune('a'+i)), Request: models.Request{
+			Messages:     []models.Message{{Role: models.RoleUser, Content: models.Content{models.TextPart{Text: prompt}}}},
+			OutputSchema: &models.OutputSchema{Name: "label", Schema: map[string]any{"type": "object"}},
+		}}
+	}
+	return requests
+}
+
+func TestOpenAIBatchUploadsJSONLAndParsesResults(t *testing.T) {
+	var uploaded []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("authorization") != "Bearer key" {
+			t.Errorf("%s %s auth = %q", r.Method, r.URL.Path, r.Header.Get("authorization"))
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v1/files":
+			if r.FormValue("purpose") != "batch" {
+				t.Errorf("purpose = %q", r.FormValue("purpose"))
+			}
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw, _ := io.ReadAll(file)
+			for _, line := range strings.Split(strings.TrimSpace(string(raw)), "\n") {
+				var entry map[string]any
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatal(err)
+				}
+				uploaded = append(uploaded, entry)
+			}
+			_, _ = w.Write([]byte(`{"id":"file_in"}`))
+		case "POST /v1/batches":
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["input_file_id"] != "file_in" || body["endpoint"] != "/v1/responses" || body["completion_window"] != "24h" {
+				t.Errorf("create body = %#v", body)
+			}
+			_, _ = w.Write([]byte(`{"id":"batch_1","status":"validating","request_counts":{"total":2}}`))
+		case "GET /v1/batches/batch_1":
+			_, _ = w.Write([]byte(`{"id":"batch_1","status":"completed","output_file_id":"file_out","error_file_id":"file_err","request_counts":{"total":2,"completed":1,"failed":1}}`))
+		case "GET /v1/files/file_out/content":
+			_, _ = w.Write([]byte(`{"custom_id":"item_a","response":{"status_code":200,"request_id":"req_1","body":{"output":[{"type":"message","content":[{"type":"output_text","text":"{\"label\":\"bug\"}"}]}],"usage":{"input_tokens":10,"output_tokens":4,"total_tokens":14}}}}` + "\n"))
+		case "GET /v1/files/file_err/content":
+			_, _ = w.Write([]byte(`{"custom_id":"item_b","response":{"status_code":400,"body":{"error":{"message":"bad schema"}}}}` + "\n"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	model := &Model{Info_: models.ModelInfo{Provider: "openai", ID: "gpt", API: models.APIOpenAIResponses}, Protocol: OpenAIResponses, BaseURL: server.URL + "/v1", APIKey: "key"}
+	ctx := context.Background()
+
+	batch, err := model.SubmitBatch(ctx, batchRequests("first", "second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.ID != "batch_1" || batch.Status != models.BatchInProgress || batch.Counts.Total != 2 {
+		t.Fatalf("batch = %#v", batch)
+	}
+	if len(uploaded) != 2 || uploaded[0]["custom_id"] != "item_a" || uploaded[0]["url"] != "/v1/responses" {
+		t.Fatalf("uploaded = %#v", uploaded)
+	}
+	body := uploaded[0]["body"].(map[string]any)
+	if _, ok := body["stream"]; ok || body["model"] != "gpt" || body["text"] == nil {
+		t.Fatalf("line body = %#v", body)
+	}
+
+	batch, err = model.GetBatch(ctx, "batch_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Status != models.BatchCompleted || batch.Counts.Succeeded != 1 || batch.Counts.Failed != 1 {
+		t.Fatalf("polled batch = %#v", batch)
+	}
+	results, err := model.BatchResults(ctx, "batch_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].CustomID != "item_a" || results[0].RequestID != "req_1" || results[1].Error != "bad schema" {
+		t.Fatalf("results = %#v", results)
+	}
+	msg := results[0].Message
+	if text := joinText(msg.Content); text != `{"label":"bug"}` || msg.Usage == nil || msg.Usage.TotalTokens != 14 || msg.FinishReason != models.FinishReasonStop {
+		t.Fatalf("message = %#v", msg)
+	}
+}
+
+func TestAnthropicBatchSendsParamsAndParsesResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "key" || r.Header.Get("anthropic-version") == "" {
+			t.Errorf("%s %s headers = %v", r.Method, r.URL.Path, r.Header)
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v1/messages/batches":
+			var body struct {
+				Requests []struct {
+					CustomID string         `json:"custom_id"`
+					Params   map[string]any `json:"params"`
+				} `json:"requests"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if len(body.Requests) != 3 || body.Requests[1].CustomID != "item_b" || body.Requests[1].Params["model"] != "claude" {
+				t.Errorf("requests = %#v", body.Requests)
+			}
+			if _, ok := body.Requests[0].Params["stream"]; ok {
+				t.Error("batch params ask to stream")
+			}
+			_, _ = w.Write([]byte(`{"id":"msgbatch_1","processing_status":"in_progress","request_counts":{"processing":3}}`))
+		case "GET /v1/messages/batches/msgbatch_1":
+			_, _ = w.Write([]byte(`{"id":"msgbatch_1","processing_status":"ended","request_counts":{"succeeded":1,"errored":1,"expired":1}}`))
+		case "GET /v1/messages/batches/msgbatch_1/results":
+			_, _ = w.Write([]byte(strings.Join([]string{
+				`{"custom_id":"item_a","result":{"type":"succeeded","message":{"content":[{"type":"text","text":"{\"label\":\"docs\"}"}],"stop_reason":"end_turn","usage":{"input_tokens":8,"output_tokens":3}}}}`,
+				`{"custom_id":"item_b","result":{"type":"errored","error":{"type":"error","error":{"type":"invalid_request_error","message":"too long"}}}}`,
+				`{"custom_id":"item_c","result":{"type":"expired"}}`,
+			}, "\n")))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	model := &Model{Info_: models.ModelInfo{Provider: "anthropic", ID: "claude", API: models.APIAnthropicMessages, MaxOutput: 1024}, Protocol: AnthropicMessages, BaseURL: server.URL + "/v1", APIKey: "key"}
+	ctx := context.Background()
+
+	batch, err := model.SubmitBatch(ctx, batchRequests("a", "b", "c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.ID != "msgbatch_1" || batch.Status != models.BatchInProgress || batch.Counts.Total != 3 {
+		t.Fatalf("batch = %#v", batch)
+	}
+	batch, err = model.GetBatch(ctx, "msgbatch_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Status != models.BatchCompleted || batch.Counts.Succeeded != 1 || batch.Counts.Failed != 2 {
+		t.Fatalf("polled batch = %#v", batch)
+	}
+	results, err := model.BatchResults(ctx, "msgbatch_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].CustomID < results[j].CustomID })
+	if len(results) != 3 || results[1].Error != "too long" || results[2].Error != "request expired" {
+		t.Fatalf("results = %#v", results)
+	}
+	msg := results[0].Message
+	if joinText(msg.Content) != `{"label":"docs"}` || msg.Usage == nil || msg.Usage.InputTokens != 8 || msg.Usage.OutputTokens != 3 {
+		t.Fatalf("message = %#v", msg)
+	}
+}
+
+func TestBatchRejectsUnsupportedProtocolsAndDuplicateIDs(t *testing.T) {
+	gemini := &Model{Info_: models.ModelInfo{Provider: "google", ID: "gemini"}, Protocol: GeminiGenerate}
+	if _, err := gemini.SubmitBatch(context.Background(), batchRequests("a")); err == nil {
+		t.Fatal("gemini batch succeeded")
+	}
+	openai := &Model{Info_: models.ModelInfo{Provider: "openai", ID: "gpt"}, Protocol: OpenAIChat, BaseURL: "http://127.0.0.1:1"}
+	requests := append(batchRequests("a"), batchRequests("b")...)
+	if _, err := openai.SubmitBatch(context.Background(), requests); err == nil || !strings.Contains(err.Error(), "unique custom_id") {
+		t.Fatalf("duplicate custom ids error = %v", err)
+	}
+}
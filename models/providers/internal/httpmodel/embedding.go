@@ -0,0 +1,167 @@
+package httpmodel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/chaserensberger/wingman/models"
+)
+
+// Embedding protocols.
+const (
+	OpenAIEmbeddings Protocol = "openai_embeddings"
+	GeminiEmbed      Protocol = "gemini_embed"
+)
+
+// Default batch sizes for models whose catalog entry does not set one.
+const (
+	openAIEmbeddingBatch = 2048
+	geminiEmbeddingBatch = 100
+)
+
+// maxEmbeddingResponse bounds one decoded embedding response.
+const maxEmbeddingResponse = 256 << 20
+
+// Embeddings reports whether p is an embedding protocol.
+func (p Protocol) Embeddings() bool { return p == OpenAIEmbeddings || p == GeminiEmbed }
+
+// Embed embeds req.Input, splitting it into batches the model accepts and
+// summing the usage each batch reports.
+func (m *Model) Embed(ctx context.Context, req models.EmbeddingRequest) (*models.EmbeddingResponse, error) {
+	if !m.Protocol.Embeddings() {
+		return nil, &models.ProviderError{Category: models.ErrorInvalidRequest, Provider: m.Info_.Provider, Message: fmt.Sprintf("%s is not an embedding model", m.Info_.ID)}
+	}
+	if len(req.Input) == 0 {
+		return nil, &models.ProviderError{Category: models.ErrorInvalidRequest, Provider: m.Info_.Provider, Message: "embedding input is required"}
+	}
+	batch := m.embeddingBatchSize()
+	out := &models.EmbeddingResponse{
+		Model:      models.ModelRef{Provider: m.Info_.Provider, ID: m.Info_.ID, API: m.Info_.API, BaseURL: m.BaseURL},
+		Embeddings: make([][]float32, 0, len(req.Input)),
+	}
+	for start := 0; start < len(req.Input); start += batch {
+		inputs := req.Input[start:min(start+batch, len(req.Input))]
+		vectors, usage, err := m.embedBatch(ctx, inputs, req.Dimensions)
+		if err != nil {
+			return nil, err
+		}
+		if len(vectors) != len(inputs) {
+			return nil, decodingError(m.Info_.Provider, fmt.Sprintf("provider returned %d embeddings for %d inputs", len(vectors), len(inputs)), nil)
+		}
+		out.Embeddings = append(out.Embeddings, vectors...)
+		out.Usage.InputTokens += usage.InputTokens
+		out.Usage.TotalTokens += usage.TotalTokens
+	}
+	return out, nil
+}
+
+func (m *Model) embeddingBatchSize() int {
+	if m.Info_.Embedding.MaxBatchSize > 0 {
+		return m.Info_.Embedding.MaxBatchSize
+	}
+	if m.Protocol == GeminiEmbed {
+		return geminiEmbeddingBatch
+	}
+	return openAIEmbeddingBatch
+}
+
+func (m *Model) embedBatch(ctx context.Context, inputs []string, dimensions int) ([][]float32, models.Usage, error) {
+	var body map[string]any
+	if m.Protocol == GeminiEmbed {
+		body = m.geminiEmbedBody(inputs, dimensions)
+	} else {
+		body = m.openAIEmbeddingsBody(inputs, dimensions)
+	}
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, models.Usage{}, fmt.Errorf("marshal %s embedding request: %w", m.Info_.Provider, err)
+	}
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, _, err := (streamTransport{client: client, limiter: m.Limiter, failFast: m.FailFast}).open(ctx, m.Info_.Provider, m.route(models.Request{}), nil, bodyBytes)
+	if err != nil {
+		return nil, models.Usage{}, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxEmbeddingResponse))
+	if err != nil {
+		return nil, models.Usage{}, transportError(m.Info_.Provider, err)
+	}
+	if m.Protocol == GeminiEmbed {
+		return parseGeminiEmbeddings(m.Info_.Provider, raw)
+	}
+	return parseOpenAIEmbeddings(m.Info_.Provider, raw, len(inputs))
+}
+
+func (m *Model) openAIEmbeddingsBody(inputs []string, dimensions int) map[string]any {
+	body := map[string]any{"model": m.Info_.ID, "input": inputs, "encoding_format": "float"}
+	if dimensions > 0 {
+		body["dimensions"] = dimensions
+	}
+	return body
+}
+
+func (m *Model) geminiEmbedBody(inputs []string, dimensions int) map[string]any {
+	requests := make([]map[string]any, len(inputs))
+	for i, input := range inputs {
+		request := map[string]any{
+			"model":   "models/" + m.Info_.ID,
+			"content": map[string]any{"parts": []map[string]any{{"text": input}}},
+		}
+		if dimensions > 0 {
+			request["outputDimensionality"] = dimensions
+		}
+		requests[i] = request
+	}
+	return map[string]any{"requests": requests}
+}
+
+func parseOpenAIEmbeddings(provider string, raw []byte, count int) ([][]float32, models.Usage, error) {
+	var resp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+			TotalTokens  int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, models.Usage{}, decodingError(provider, "invalid embedding response", err)
+	}
+	if len(resp.Data) != count {
+		return nil, models.Usage{}, decodingError(provider, fmt.Sprintf("provider returned %d embeddings for %d inputs", len(resp.Data), count), nil)
+	}
+	// Entries carry their input index; order by it rather than trusting the
+	// response order.
+	vectors := make([][]float32, count)
+	for _, item := range resp.Data {
+		if item.Index < 0 || item.Index >= count || vectors[item.Index] != nil {
+			return nil, models.Usage{}, decodingError(provider, fmt.Sprintf("invalid embedding index %d", item.Index), nil)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, models.Usage{InputTokens: resp.Usage.PromptTokens, TotalTokens: resp.Usage.TotalTokens}, nil
+}
+
+func parseGeminiEmbeddings(provider string, raw []byte) ([][]float32, models.Usage, error) {
+	var resp struct {
+		Embeddings []struct {
+			Values []float32 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, models.Usage{}, decodingError(provider, "invalid embedding response", err)
+	}
+	vectors := make([][]float32, len(resp.Embeddings))
+	for i, embedding := range resp.Embeddings {
+		vectors[i] = embedding.Values
+	}
+	return vectors, models.Usage{}, nil
+}
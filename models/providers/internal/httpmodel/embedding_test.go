@@ -0,0 +1,114 @@
+package httpmodel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chaserensberger/wingman/models"
+)
+
+func TestOpenAIEmbedBatchesInputsAndSumsUsage(t *testing.T) {
+	var batches [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("authorization") != "Bearer key" {
+			t.Errorf("request = %s %s, auth %q", r.Method, r.URL.Path, r.Header.Get("authorization"))
+		}
+		var body struct {
+			Model      string   `json:"model"`
+			Input      []string `json:"input"`
+			Dimensions int      `json:"dimensions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Model != "embed" || body.Dimensions != 2 {
+			t.Errorf("body = %#v, %v", body, err)
+		}
+		batches = append(batches, body.Input)
+		// Reverse the entries to check that index, not position, orders them.
+		data := make([]map[string]any, len(body.Input))
+		for i := range body.Input {
+			data[len(body.Input)-1-i] = map[string]any{"index": i, "embedding": []float32{float32(len(batches)), float32(i)}}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data, "usage": map[string]int{"prompt_tokens": len(body.Input), "total_tokens": len(body.Input)}})
+	}))
+	defer server.Close()
+	model := &Model{
+		Info_:    models.ModelInfo{Provider: "test", ID: "embed", Embedding: models.EmbeddingInfo{Dimensions: 4, MaxBatchSize: 2}},
+		Protocol: OpenAIEmbeddings,
+		BaseURL:  server.URL + "/v1",
+		APIKey:   "key",
+	}
+
+	resp, err := model.Embed(context.Background(), models.EmbeddingRequest{Input: []string{"a", "b", "c"}, Dimensions: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 || len(batches[0]) != 2 || batches[1][0] != "c" {
+		t.Fatalf("batches = %#v", batches)
+	}
+	want := [][]float32{{1, 0}, {1, 1}, {2, 0}}
+	for i, vector := range resp.Embeddings {
+		if vector[0] != want[i][0] || vector[1] != want[i][1] {
+			t.Fatalf("embeddings = %#v", resp.Embeddings)
+		}
+	}
+	if resp.Usage.InputTokens != 3 || resp.Usage.TotalTokens != 3 || resp.Model.ID != "embed" {
+		t.Fatalf("response = %#v", resp)
+	}
+
+	if _, err := model.Stream(context.Background(), models.Request{}); err == nil {
+		t.Fatal("embedding model streamed a chat response")
+	}
+}
+
+func TestGeminiEmbedUsesBatchEmbedContents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-embed:batchEmbedContents" || r.Header.Get("x-goog-api-key") != "key" || r.URL.Query().Get("alt") != "" {
+			t.Errorf("request = %s, key %q", r.URL, r.Header.Get("x-goog-api-key"))
+		}
+		var body struct {
+			Requests []struct {
+				Model                string `json:"model"`
+				OutputDimensionality int    `json:"outputDimensionality"`
+				Content              struct {
+					Parts []struct {
+						Text string `json:"text"`
+					} `json:"parts"`
+				} `json:"content"`
+			} `json:"requests"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Requests) != 2 || body.Requests[1].Model != "models/gemini-embed" || body.Requests[1].Content.Parts[0].Text != "second" || body.Requests[0].OutputDimensionality != 0 {
+			t.Errorf("body = %#v, %v", body, err)
+		}
+		_, _ = w.Write([]byte(`{"embeddings":[{"values":[0.5]},{"values":[0.25]}]}`))
+	}))
+	defer server.Close()
+	model := &Model{Info_: models.ModelInfo{Provider: "google", ID: "gemini-embed"}, Protocol: GeminiEmbed, BaseURL: server.URL + "/v1beta", APIKey: "key"}
+
+	resp, err := model.Embed(context.Background(), models.EmbeddingRequest{Input: []string{"first", "second"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Embeddings) != 2 || resp.Embeddings[1][0] != 0.25 || !resp.Usage.Empty() {
+		t.Fatalf("response = %#v", resp)
+	}
+}
+
+func TestEmbedRejectsChatModelsAndShortResponses(t *testing.T) {
+	chat := &Model{Info_: models.ModelInfo{Provider: "test", ID: "chat"}, Protocol: OpenAIChat}
+	var providerErr *models.ProviderError
+	if _, err := chat.Embed(context.Background(), models.EmbeddingRequest{Input: []string{"a"}}); !errors.As(err, &providerErr) || providerErr.Category != models.ErrorInvalidRequest {
+		t.Fatalf("chat embed error = %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":[{"index":0,"embedding":[1]}]}`))
+	}))
+	defer server.Close()
+	model := &Model{Info_: models.ModelInfo{Provider: "test", ID: "embed"}, Protocol: OpenAIEmbeddings, BaseURL: server.URL}
+	if _, err := model.Embed(context.Background(), models.EmbeddingRequest{Input: []string{"a", "b"}}); !errors.As(err, &providerErr) || providerErr.Category != models.ErrorDecoding {
+		t.Fatalf("short response error = %v", err)
+	}
+}
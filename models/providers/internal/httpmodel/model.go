@@ -29,6 +29,14 @@ type Model struct {
 	ForceStoreFalse bool
 	Route           *Route
 	Client          *http.Client
+	// Limiter, when set, paces requests with the provider's other callers.
+	Limiter *Limiter
+	// Credential names the stored credential the request uses, reported in
+	// response metadata.
+	Credential string
+	// FailFast returns a 429 at once instead of waiting it out, so the
+	// caller can try another credential.
+	FailFast bool
 }
 
 // Stream sends a streaming request and parses provider SSE into WingModels parts.
@@ -46,8 +54,11 @@ func (m *Model) Stream(ctx context.Context, req models.Request) (*models.EventSt
 	if client == nil {
 		client = http.DefaultClient
 	}
-	resp, err := (streamTransport{client: client}).open(ctx, m.Info_.Provider, route, req.HTTP.Headers, bodyBytes)
+	resp, throttled, err := (streamTransport{client: client, limiter: m.Limiter, failFast: m.FailFast}).open(ctx, m.Info_.Provider, route, req.HTTP.Headers, bodyBytes)
 	if err != nil {
+		if throttled > 0 {
+			return nil, &models.ThrottledError{Err: err, Throttled: throttled}
+		}
 		return nil, err
 	}
 
@@ -57,8 +68,18 @@ func (m *Model) Stream(ctx context.Context, req models.Request) (*models.EventSt
 	go func() {
 		defer resp.Body.Close()
 		stream.Push(models.StreamStartPart{})
+		meta := map[string]any{}
 		if requestID != "" {
-			stream.Push(models.ResponseMetadataPart{Meta: map[string]any{"request_id": requestID}})
+			meta["request_id"] = requestID
+		}
+		if throttled > 0 {
+			meta["throttled_ms"] = throttled.Milliseconds()
+		}
+		if m.Credential != "" {
+			meta["credential"] = m.Credential
+		}
+		if len(meta) > 0 {
+			stream.Push(models.ResponseMetadataPart{Meta: meta})
 		}
 		msg, usage, reason, err := m.readSSE(ctx, resp.Body, stream)
 		if msg != nil && !usage.Empty() {
@@ -209,6 +230,8 @@ func (m *Model) body(req models.Request) (map[string]any, error) {
 		return m.anthropicBody(req)
 	case GeminiGenerate:
 		return m.geminiBody(req)
+	case OpenAIEmbeddings, GeminiEmbed:
+		return nil, &models.ProviderError{Category: models.ErrorInvalidRequest, Provider: m.Info_.Provider, Message: fmt.Sprintf("%s is an embedding model and cannot generate messages", m.Info_.ID)}
 	default:
 		return nil, fmt.Errorf("unsupported protocol %q", m.Protocol)
 	}
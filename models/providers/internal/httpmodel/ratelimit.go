@@ -0,0 +1,286 @@
+package httpmodel
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// rateLimitAttempts bounds how many times a limited model request is
+	// sent when the provider answers 429.
+	rateLimitAttempts = 3
+	// rateLimitBackoff holds dispatch after a 429 that says nothing about
+	// when to retry.
+	rateLimitBackoff = time.Second
+	rateLimitWindow  = time.Minute
+)
+
+// Limiter paces the requests sent to one provider. A daemon shares one per
+// provider across every session, so model calls wait for capacity instead
+// of each failing into the same rate limit. Capacity comes from configured
+// requests and tokens per minute and from the rate-limit headers and
+// Retry-After the provider returns. A nil Limiter never waits.
+type Limiter struct {
+	requestsPerMinute int
+	tokensPerMinute   int
+
+	mu           sync.Mutex
+	requests     []time.Time
+	tokens       []tokenUse
+	blockedUntil time.Time
+	// remaining counts are -1 until a response reports them.
+	remainingRequests int
+	requestsReset     time.Time
+	remainingTokens   int
+	tokensReset       time.Time
+	waiting           int
+	throttled         int64
+	throttledFor      time.Duration
+	rateLimited       int64
+}
+
+type tokenUse struct {
+	at     time.Time
+	tokens int
+}
+
+// LimiterStatus is a snapshot of a Limiter.
+type LimiterStatus struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+	// RemainingRequests and RemainingTokens are the provider's last report,
+	// or -1 when unknown or past their reset.
+	RemainingRequests int
+	RemainingTokens   int
+	BlockedUntil      time.Time
+	Waiting           int
+	// Throttled counts requests that waited, for Throttle in total.
+	Throttled int64
+	Throttle  time.Duration
+	// RateLimited counts 429 responses.
+	RateLimited int64
+}
+
+// NewLimiter returns a limiter for the given budgets. Zero leaves a budget
+// unset; the limiter still honors provider headers.
+func NewLimiter(requestsPerMinute, tokensPerMinute int) *Limiter {
+	return &Limiter{
+		requestsPerMinute: max(requestsPerMinute, 0),
+		tokensPerMinute:   max(tokensPerMinute, 0),
+		remainingRequests: -1,
+		remainingTokens:   -1,
+	}
+}
+
+// Wait blocks until a request of about tokens may be sent, reserves its
+// capacity, and returns how long it waited.
+func (l *Limiter) Wait(ctx context.Context, tokens int) (time.Duration, error) {
+	if l == nil {
+		return 0, ctx.Err()
+	}
+	start := time.Now()
+	queued := false
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		delay := l.delayLocked(now, tokens)
+		if delay <= 0 {
+			l.reserveLocked(now, tokens)
+			waited := time.Duration(0)
+			if queued {
+				waited = now.Sub(start)
+				l.waiting--
+				l.throttled++
+				l.throttledFor += waited
+			}
+			l.mu.Unlock()
+			return waited, nil
+		}
+		if !queued {
+			queued = true
+			l.waiting++
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			l.mu.Lock()
+			l.waiting--
+			l.mu.Unlock()
+			return time.Since(start), ctx.Err()
+		}
+	}
+}
+
+// delayLocked returns how long a request of tokens must wait at now.
+func (l *Limiter) delayLocked(now time.Time, tokens int) time.Duration {
+	l.pruneLocked(now)
+	until := l.blockedUntil
+	later := func(t time.Time) {
+		if t.After(until) {
+			until = t
+		}
+	}
+	if l.remainingRequests == 0 && now.Before(l.requestsReset) {
+		later(l.requestsReset)
+	}
+	if l.remainingTokens >= 0 && l.remainingTokens < tokens && now.Before(l.tokensReset) {
+		later(l.tokensReset)
+	}
+	if limit := l.requestsPerMinute; limit > 0 && len(l.requests) >= limit {
+		later(l.requests[len(l.requests)-limit].Add(rateLimitWindow))
+	}
+	if limit := l.tokensPerMinute; limit > 0 {
+		// A request larger than the whole budget waits for an empty window
+		// rather than forever.
+		need := min(tokens, limit)
+		used := 0
+		for _, use := range l.tokens {
+			used += use.tokens
+		}
+		for _, use := range l.tokens {
+			if used+need <= limit {
+				break
+			}
+			used -= use.tokens
+			later(use.at.Add(rateLimitWindow))
+		}
+	}
+	return until.Sub(now)
+}
+
+func (l *Limiter) reserveLocked(now time.Time, tokens int) {
+	if l.requestsPerMinute > 0 {
+		l.requests = append(l.requests, now)
+	}
+	if l.tokensPerMinute > 0 && tokens > 0 {
+		l.tokens = append(l.tokens, tokenUse{at: now, tokens: tokens})
+	}
+	if l.remainingRequests > 0 {
+		l.remainingRequests--
+	}
+	if l.remainingTokens > 0 {
+		l.remainingTokens = max(l.remainingTokens-tokens, 0)
+	}
+}
+
+func (l *Limiter) pruneLocked(now time.Time) {
+	cutoff := now.Add(-rateLimitWindow)
+	i := 0
+	for i < len(l.requests) && !l.requests[i].After(cutoff) {
+		i++
+	}
+	l.requests = l.requests[i:]
+	j := 0
+	for j < len(l.tokens) && !l.tokens[j].at.After(cutoff) {
+		j++
+	}
+	l.tokens = l.tokens[j:]
+}
+
+// Observe records the rate-limit state a provider response reports: the
+// OpenAI x-ratelimit-* and Anthropic anthropic-ratelimit-* headers, and
+// Retry-After on 429 and 503 responses.
+func (l *Limiter) Observe(resp *http.Response) {
+	if l == nil || resp == nil {
+		return
+	}
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, names := range [][3]string{
+		{"x-ratelimit-remaining-requests", "x-ratelimit-reset-requests", "requests"},
+		{"anthropic-ratelimit-requests-remaining", "anthropic-ratelimit-requests-reset", "requests"},
+		{"x-ratelimit-remaining-tokens", "x-ratelimit-reset-tokens", "tokens"},
+		{"anthropic-ratelimit-tokens-remaining", "anthropic-ratelimit-tokens-reset", "tokens"},
+	} {
+		remaining, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get(names[0])))
+		if err != nil {
+			continue
+		}
+		reset, ok := parseReset(resp.Header.Get(names[1]), now)
+		if !ok {
+			continue
+		}
+		if names[2] == "requests" {
+			l.remainingRequests, l.requestsReset = remaining, reset
+		} else {
+			l.remainingTokens, l.tokensReset = remaining, reset
+		}
+	}
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		l.rateLimited++
+	}
+	wait := retryAfter(resp.Header)
+	if wait == nil {
+		if resp.StatusCode != http.StatusTooManyRequests || l.delayLocked(now, 0) > 0 {
+			return
+		}
+		backoff := rateLimitBackoff
+		wait = &backoff
+	}
+	if until := now.Add(*wait); until.After(l.blockedUntil) {
+		l.blockedUntil = until
+	}
+}
+
+// Status returns a snapshot of the limiter.
+func (l *Limiter) Status() LimiterStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	status := LimiterStatus{
+		RequestsPerMinute: l.requestsPerMinute,
+		TokensPerMinute:   l.tokensPerMinute,
+		RemainingRequests: -1,
+		RemainingTokens:   -1,
+		Waiting:           l.waiting,
+		Throttled:         l.throttled,
+		Throttle:          l.throttledFor,
+		RateLimited:       l.rateLimited,
+	}
+	if now.Before(l.requestsReset) {
+		status.RemainingRequests = l.remainingRequests
+	}
+	if now.Before(l.tokensReset) {
+		status.RemainingTokens = l.remainingTokens
+	}
+	if now.Before(l.blockedUntil) {
+		status.BlockedUntil = l.blockedUntil
+	}
+	return status
+}
+
+// parseReset reads a reset header as a duration ("6m0s", OpenAI), an RFC
+// 3339 time (Anthropic), or seconds.
+func parseReset(raw string, now time.Time) (time.Time, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, false
+	}
+	if d, err := time.ParseDuration(raw); err == nil {
+		return now.Add(d), true
+	}
+	if when, err := time.Parse(time.RFC3339, raw); err == nil {
+		return when, true
+	}
+	if seconds, err := strconv.ParseFloat(raw, 64); err == nil {
+		return now.Add(time.Duration(seconds * float64(time.Second))), true
+	}
+	return time.Time{}, false
+}
+
+// estimateTokens roughly sizes a request body at four bytes per token.
+func estimateTokens(body []byte) int {
+	return max(len(body)/4, 1)
+}
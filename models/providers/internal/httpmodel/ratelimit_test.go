@@ -0,0 +1,162 @@
+package httpmodel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chaserensberger/wingman/models"
+)
+
+func TestStreamWaitsOutRetryAfterAndReportsThrottle(t *testing.T) {
+	limiter := NewLimiter(0, 0)
+	calls := 0
+	model := &Model{
+		Info_:    models.ModelInfo{Provider: "test", ID: "test"},
+		Protocol: OpenAIChat,
+		BaseURL:  "https://example.com",
+		Limiter:  limiter,
+		Client: &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			calls++
+			if calls == 1 {
+				return &http.Response{
+					StatusCode: http.StatusTooManyRequests,
+					Header:     http.Header{"Retry-After": {"1"}},
+					Body:       io.NopCloser(strings.NewReader("rate limited")),
+				}, nil
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"X-Request-Id": {"request-ok"}},
+				Body:       io.NopCloser(strings.NewReader("data: [DONE]\n\n")),
+			}, nil
+		})},
+	}
+
+	stream, err := model.Stream(context.Background(), models.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta map[string]any
+	for part := range stream.Iter() {
+		if metadata, ok := part.(models.ResponseMetadataPart); ok {
+			meta = metadata.Meta
+		}
+	}
+	throttled, _ := meta["throttled_ms"].(int64)
+	if calls != 2 || meta["request_id"] != "request-ok" || throttled < 900 {
+		t.Fatalf("calls = %d, metadata = %#v", calls, meta)
+	}
+	status := limiter.Status()
+	if status.RateLimited != 1 || status.Throttled != 1 || status.Throttle < 900*time.Millisecond || status.Waiting != 0 {
+		t.Fatalf("status = %#v", status)
+	}
+}
+
+func TestStreamGivesUpAfterRepeatedRateLimits(t *testing.T) {
+	calls := 0
+	model := &Model{
+		Info_:    models.ModelInfo{Provider: "test", ID: "test"},
+		Protocol: OpenAIChat,
+		BaseURL:  "https://example.com",
+		Limiter:  NewLimiter(0, 0),
+		Client: &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			calls++
+			return &http.Response{
+				StatusCode: http.StatusTooManyRequests,
+				Header:     http.Header{"Retry-After": {"0"}},
+				Body:       io.NopCloser(strings.NewReader("rate limited")),
+			}, nil
+		})},
+	}
+	_, err := model.Stream(context.Background(), models.Request{})
+	var providerErr *models.ProviderError
+	if calls != rateLimitAttempts || !errors.As(err, &providerErr) || providerErr.Category != models.ErrorRateLimit {
+		t.Fatalf("calls = %d, error = %v", calls, err)
+	}
+}
+
+func TestStreamReportsThrottleWhenWaitingFails(t *testing.T) {
+	limiter := NewLimiter(0, 0)
+	limiter.Observe(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"30"}}})
+	model := &Model{
+		Info_:    models.ModelInfo{Provider: "test", ID: "test"},
+		Protocol: OpenAIChat,
+		BaseURL:  "https://example.com",
+		Limiter:  limiter,
+		Client: &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			t.Fatal("request sent while the limiter was blocked")
+			return nil, nil
+		})},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := model.Stream(ctx, models.Request{})
+	if !errors.Is(err, context.DeadlineExceeded) || models.ThrottledFor(err) < 40*time.Millisecond {
+		t.Fatalf("error = %v, throttled = %v", err, models.ThrottledFor(err))
+	}
+}
+
+func TestLimiterBudgets(t *testing.T) {
+	limiter := NewLimiter(2, 100)
+	ctx := context.Background()
+	for range 2 {
+		if waited, err := limiter.Wait(ctx, 10); err != nil || waited != 0 {
+			t.Fatalf("waited %v, error = %v", waited, err)
+		}
+	}
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Wait(short, 10); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("request over the per-minute budget: error = %v", err)
+	}
+
+	tokens := NewLimiter(0, 100)
+	if _, err := tokens.Wait(ctx, 500); err != nil {
+		t.Fatalf("oversized request into an empty window: %v", err)
+	}
+	short, cancel = context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := tokens.Wait(short, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("request over the token budget: error = %v", err)
+	}
+	if status := tokens.Status(); status.Waiting != 0 || status.Throttled != 0 {
+		t.Fatalf("status = %#v", status)
+	}
+}
+
+func TestLimiterObservesProviderHeaders(t *testing.T) {
+	limiter := NewLimiter(0, 0)
+	limiter.Observe(&http.Response{StatusCode: http.StatusOK, Header: http.Header{
+		"X-Ratelimit-Remaining-Requests": {"0"},
+		"X-Ratelimit-Reset-Requests":     {"50ms"},
+	}})
+	waited, err := limiter.Wait(context.Background(), 1)
+	if err != nil || waited < 40*time.Millisecond {
+		t.Fatalf("waited %v, error = %v", waited, err)
+	}
+
+	reset := time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+	limiter.Observe(&http.Response{StatusCode: http.StatusOK, Header: http.Header{
+		"Anthropic-Ratelimit-Requests-Remaining": {"7"},
+		"Anthropic-Ratelimit-Requests-Reset":     {reset},
+		"Anthropic-Ratelimit-Tokens-Remaining":   {"4000"},
+		"Anthropic-Ratelimit-Tokens-Reset":       {reset},
+	}})
+	if _, err := limiter.Wait(context.Background(), 1000); err != nil {
+		t.Fatal(err)
+	}
+	status := limiter.Status()
+	if status.RemainingRequests != 6 || status.RemainingTokens != 3000 || status.Throttled != 1 {
+		t.Fatalf("status = %#v", status)
+	}
+
+	limiter.Observe(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"30"}}})
+	if status := limiter.Status(); status.RateLimited != 1 || time.Until(status.BlockedUntil) < 29*time.Second {
+		t.Fatalf("status after 429 = %#v", status)
+	}
+}
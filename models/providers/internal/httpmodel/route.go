@@ -25,7 +25,6 @@ type Route struct {
 }
 
 func (r Route) URL() string {
-	base := strings.TrimRight(r.Endpoint.BaseURL, "/")
 	path := ""
 	switch r.Protocol {
 	case OpenAIResponses:
@@ -36,8 +35,18 @@ func (r Route) URL() string {
 		path = "/messages"
 	case GeminiGenerate:
 		path = "/models/" + url.PathEscape(r.Endpoint.ModelID) + ":streamGenerateContent"
+	case OpenAIEmbeddings:
+		path = "/embeddings"
+	case GeminiEmbed:
+		path = "/models/" + url.PathEscape(r.Endpoint.ModelID) + ":batchEmbedContents"
 	}
-	raw := base + path
+	return r.resourceURL(path)
+}
+
+// resourceURL joins path to the endpoint base URL and appends the endpoint
+// query.
+func (r Route) resourceURL(path string) string {
+	raw := strings.TrimRight(r.Endpoint.BaseURL, "/") + path
 	if len(r.Endpoint.Query) == 0 {
 		return raw
 	}
@@ -3,22 +3,54 @@ package httpmodel
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/chaserensberger/wingman/models"
 )
 
 type streamTransport struct {
-	client *http.Client
+	client  *http.Client
+	limiter *Limiter
+	// failFast returns the first 429 instead of sending the request again.
+	failFast bool
 }
 
-func (t streamTransport) open(ctx context.Context, provider string, route Route, headers map[string]string, body []byte) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, route.URL(), bytes.NewReader(body))
+// open posts a model request once the limiter has capacity for it. A 429 is
+// sent again, after the wait it asks for, up to rateLimitAttempts times
+// unless failFast is set. It returns how long the request was held back.
+func (t streamTransport) open(ctx context.Context, provider string, route Route, headers map[string]string, body []byte) (*http.Response, time.Duration, error) {
+	var throttled time.Duration
+	for attempt := 1; ; attempt++ {
+		waited, err := t.limiter.Wait(ctx, estimateTokens(body))
+		throttled += waited
+		if err != nil {
+			return nil, throttled, err
+		}
+		resp, err := t.send(ctx, provider, route, http.MethodPost, route.URL(), "application/json", headers, body)
+		var providerErr *models.ProviderError
+		if err == nil || t.limiter == nil || t.failFast || attempt == rateLimitAttempts || !errors.As(err, &providerErr) || providerErr.Status != http.StatusTooManyRequests {
+			return resp, throttled, err
+		}
+	}
+}
+
+// send issues one authenticated request to target and returns the 2xx
+// response. A nil body sends no content type.
+func (t streamTransport) send(ctx context.Context, provider string, route Route, method, target, contentType string, headers map[string]string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
 	if err != nil {
 		return nil, &models.ProviderError{Category: models.ErrorInvalidRequest, Provider: provider, Message: "invalid provider request", Cause: err}
 	}
-	req.Header.Set("content-type", "application/json")
+	if body != nil {
+		req.Header.Set("content-type", contentType)
+	}
 	for name, value := range headers {
 		req.Header.Set(name, value)
 	}
@@ -29,6 +61,7 @@ func (t streamTransport) open(ctx context.Context, provider string, route Route,
 	if err != nil {
 		return nil, transportError(provider, err)
 	}
+	t.limiter.Observe(resp)
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 		return resp, nil
 	}
@@ -0,0 +1,296 @@
+// Package mock registers the mock provider. Its models answer in process,
+// without network access or credentials, by playing scripted steps: text,
+// reasoning, tool calls, errors, delays, and usage. Client developers use it
+// to drive every session event from the REST API.
+//
+// A model call plays one turn. Turns come from @mock directives in the
+// latest user message, from a script file, or, without either, from an echo
+// of the user's text. See Parse for the directive syntax and Script for the
+// file format.
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chaserensberger/wingman/models"
+	provider "github.com/chaserensberger/wingman/models/providers"
+)
+
+// ID is the provider ID for mock model refs.
+const ID = "mock"
+
+// ScriptEnv names a script file played when a message has no directives.
+const ScriptEnv = "WINGMAN_MOCK_SCRIPT"
+
+// Model returns a mock model ref.
+func Model(id string) models.ModelRef {
+	return models.ModelRef{Provider: ID, ID: id, API: models.APIMock}
+}
+
+func init() {
+	capabilities := models.ModelCapabilities{Tools: true, Images: true, Reasoning: true, StructuredOutput: true}
+	provider.Register(provider.ProviderMeta{
+		ID:      ID,
+		Name:    "Mock",
+		BaseURL: "mock://local",
+		Models: map[string]models.ModelInfo{
+			"scripted": {
+				Provider: ID, ID: "scripted", API: models.APIMock, ContextWindow: 200000, MaxOutput: 32000,
+				Capabilities: capabilities, InputCostPerMTok: 1, OutputCostPerMTok: 4,
+			},
+		},
+		Local: func(info models.ModelInfo) models.Client { return &Client{Info: info} },
+	})
+}
+
+// Client plays scripted turns for one mock model.
+type Client struct {
+	Info models.ModelInfo
+}
+
+// Prepare returns the turn the call would play as the request body.
+func (c *Client) Prepare(_ context.Context, req models.Request) (*models.PreparedRequest, error) {
+	turn, err := c.turn(req)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PreparedRequest{
+		Model: req.Model,
+		API:   models.APIMock,
+		URL:   "mock://local/" + c.Info.ID,
+		Body:  map[string]any{"steps": turn},
+	}, nil
+}
+
+// Generate drains Stream and returns the final assistant message.
+func (c *Client) Generate(ctx context.Context, req models.Request) (*models.Message, error) {
+	return models.Generate(ctx, c, req)
+}
+
+// Stream plays the request's turn. An error step before any output fails
+// the call; later, it ends the stream.
+func (c *Client) Stream(ctx context.Context, req models.Request) (*models.EventStream[models.StreamPart, *models.Message], error) {
+	turn, err := c.turn(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(turn) > 0 && turn[0].Type == StepError {
+		return nil, turn[0].providerError()
+	}
+	stream := models.NewEventStream[models.StreamPart, *models.Message](64)
+	stream.BindContext(ctx)
+	go c.play(ctx, req, turn, stream)
+	return stream, nil
+}
+
+func (c *Client) play(ctx context.Context, req models.Request, turn []Step, stream *models.EventStream[models.StreamPart, *models.Message]) {
+	p := player{ctx: ctx, stream: stream, usage: estimateUsage(req)}
+	stream.Push(models.StreamStartPart{})
+	stream.Push(models.ResponseMetadataPart{Meta: map[string]any{"request_id": "mock_" + strconv.FormatInt(time.Now().UnixNano(), 36)}})
+	var err error
+	for _, step := range turn {
+		if err = p.play(step); err != nil {
+			break
+		}
+	}
+	finish := models.FinishReasonStop
+	if len(p.tools) > 0 {
+		finish = models.FinishReasonToolCalls
+	}
+	msg := p.message(c.Info, finish)
+	if err != nil {
+		stream.Push(models.ErrorPart{Error: err.Error()})
+		stream.Close(msg, err)
+		return
+	}
+	stream.Push(models.FinishPart{Reason: finish, Usage: *msg.Usage, Message: msg})
+	stream.Close(msg, nil)
+}
+
+// turn selects the steps for req. The turn index counts the assistant
+// messages after the latest user message, so a turn that calls tools is
+// followed by the next turn once the results come back.
+func (c *Client) turn(req models.Request) ([]Step, error) {
+	text, index := latestUserText(req.Messages)
+	script, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if script == nil {
+		if path := os.Getenv(ScriptEnv); path != "" {
+			if script, err = LoadScript(path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if script == nil {
+		script = &Script{Turns: [][]Step{{{Type: StepText, Text: echo(text)}}}}
+	}
+	if index < len(script.Turns) {
+		return script.Turns[index], nil
+	}
+	return []Step{{Type: StepText, Text: "Done."}}, nil
+}
+
+func latestUserText(messages []models.Message) (string, int) {
+	after := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Role == models.RoleAssistant {
+			after++
+			continue
+		}
+		if msg.Role != models.RoleUser {
+			continue
+		}
+		var text strings.Builder
+		for _, part := range msg.Content {
+			if t, ok := part.(models.TextPart); ok {
+				text.WriteString(t.Text)
+			}
+		}
+		return text.String(), after
+	}
+	return "", after
+}
+
+func echo(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "Hello from the mock provider."
+	}
+	return "You said: " + text
+}
+
+// estimateUsage counts roughly four characters per token so usage and cost
+// are non-zero without a usage step.
+func estimateUsage(req models.Request) models.Usage {
+	chars := len(req.System)
+	for _, msg := range req.Messages {
+		data, _ := json.Marshal(msg.Content)
+		chars += len(data)
+	}
+	return models.Usage{InputTokens: max(chars/4, 1)}
+}
+
+type player struct {
+	ctx       context.Context
+	stream    *models.EventStream[models.StreamPart, *models.Message]
+	pace      time.Duration
+	text      strings.Builder
+	reasoning strings.Builder
+	tools     []models.ToolCallPart
+	usage     models.Usage
+	usageSet  bool
+	parts     int
+}
+
+func (p *player) play(step Step) error {
+	switch step.Type {
+	case StepText:
+		return p.emit(step.Text, &p.text, func(id string) models.StreamPart { return models.TextStartPart{ID: id} },
+			func(id, delta string) models.StreamPart { return models.TextDeltaPart{ID: id, Delta: delta} },
+			func(id string) models.StreamPart { return models.TextEndPart{ID: id} })
+	case StepReasoning:
+		return p.emit(step.Text, &p.reasoning, func(id string) models.StreamPart { return models.ReasoningStartPart{ID: id} },
+			func(id, delta string) models.StreamPart { return models.ReasoningDeltaPart{ID: id, Delta: delta} },
+			func(id string) models.StreamPart { return models.ReasoningEndPart{ID: id} })
+	case StepToolCall:
+		input := step.Input
+		if input == nil {
+			input = map[string]any{}
+		}
+		call := models.ToolCallPart{CallID: fmt.Sprintf("call_mock_%d", len(p.tools)+1), Name: step.Name, Input: input}
+		raw, _ := json.Marshal(input)
+		p.stream.Push(models.ToolInputStartPart{ID: call.CallID, ToolName: call.Name})
+		for _, chunk := range chunks(string(raw)) {
+			if err := p.wait(p.pace); err != nil {
+				return err
+			}
+			p.stream.Push(models.ToolInputDeltaPart{ID: call.CallID, Delta: chunk})
+		}
+		p.stream.Push(models.ToolInputEndPart{ID: call.CallID})
+		p.stream.Push(models.ToolCallPart_{ID: call.CallID, ToolName: call.Name, Input: call.Input})
+		p.tools = append(p.tools, call)
+	case StepDelay:
+		return p.wait(step.Duration.Duration)
+	case StepPace:
+		p.pace = step.Duration.Duration
+	case StepUsage:
+		p.usage, p.usageSet = step.Usage, true
+	case StepError:
+		return step.providerError()
+	}
+	return nil
+}
+
+// emit streams text as word-sized deltas of one block.
+func (p *player) emit(text string, into *strings.Builder, start func(string) models.StreamPart, delta func(string, string) models.StreamPart, end func(string) models.StreamPart) error {
+	p.parts++
+	id := fmt.Sprintf("mock_%d", p.parts)
+	if into.Len() > 0 {
+		into.WriteString("\n\n")
+	}
+	p.stream.Push(start(id))
+	for _, chunk := range chunks(text) {
+		if err := p.wait(p.pace); err != nil {
+			return err
+		}
+		into.WriteString(chunk)
+		p.stream.Push(delta(id, chunk))
+	}
+	p.stream.Push(end(id))
+	return nil
+}
+
+func (p *player) wait(d time.Duration) error {
+	if d <= 0 {
+		return p.ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (p *player) message(info models.ModelInfo, finish models.FinishReason) *models.Message {
+	content := models.Content{}
+	if p.text.Len() > 0 {
+		content = append(content, models.TextPart{Text: p.text.String()})
+	}
+	if p.reasoning.Len() > 0 {
+		content = append(content, models.ReasoningPart{Reasoning: p.reasoning.String()})
+	}
+	for _, call := range p.tools {
+		content = append(content, call)
+	}
+	usage := p.usage
+	if !p.usageSet {
+		usage.OutputTokens = max((p.text.Len()+p.reasoning.Len())/4, 1)
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	}
+	return &models.Message{
+		Role: models.RoleAssistant, Content: content, FinishReason: finish, Usage: &usage,
+		Origin: &models.MessageOrigin{Provider: info.Provider, API: models.APIMock, ModelID: info.ID},
+	}
+}
+
+// chunks splits s after each space so deltas arrive word by word.
+func chunks(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.SplitAfter(s, " ")
+}
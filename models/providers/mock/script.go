@@ -0,0 +1,232 @@
+package mock
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chaserensberger/wingman/models"
+)
+
+// Step types.
+const (
+	StepText      = "text"
+	StepReasoning = "reasoning"
+	StepToolCall  = "tool_call"
+	StepDelay     = "delay"
+	StepPace      = "pace"
+	StepUsage     = "usage"
+	StepError     = "error"
+)
+
+const directivePrefix = "@mock"
+
+// Script is a sequence of turns. Each model call plays one turn.
+//
+// A script file is JSON:
+//
+//	{"turns": [
+//	  [{"type": "reasoning", "text": "Look first."}, {"type": "tool_call", "name": "bash", "input": {"command": "ls"}}],
+//	  [{"type": "pace", "duration": "50ms"}, {"type": "text", "text": "Two files."}]
+//	]}
+type Script struct {
+	Turns [][]Step `json:"turns"`
+}
+
+// Step is one scripted behavior.
+type Step struct {
+	Type string `json:"type"`
+	// Text is the content of text and reasoning steps.
+	Text string `json:"text,omitempty"`
+	// Name and Input describe a tool call.
+	Name  string         `json:"name,omitempty"`
+	Input map[string]any `json:"input,omitempty"`
+	// Duration is the pause of a delay step, or the pause between deltas
+	// that a pace step sets for the rest of the turn.
+	Duration Duration `json:"duration,omitzero"`
+	// Usage replaces the estimated usage of the turn.
+	Usage models.Usage `json:"usage,omitzero"`
+	// Category, Message, and Retryable describe an error. Rate limit,
+	// unavailable, and timeout errors are retryable unless Retryable is
+	// false.
+	Category  models.ErrorCategory `json:"category,omitempty"`
+	Message   string               `json:"message,omitempty"`
+	Retryable *bool                `json:"retryable,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "250ms".
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) { return json.Marshal(d.String()) }
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// LoadScript reads and validates a script file.
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("mock script: %w", err)
+	}
+	var script Script
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("mock script %s: %w", path, err)
+	}
+	for i, turn := range script.Turns {
+		for j, step := range turn {
+			if err := step.validate(); err != nil {
+				return nil, fmt.Errorf("mock script %s: turns[%d][%d]: %w", path, i, j, err)
+			}
+		}
+	}
+	return &script, nil
+}
+
+// Parse reads @mock directives, one per line, from a user message. It
+// returns nil when the message has none. Other lines are ignored.
+//
+//	@mock text <text>                 stream text
+//	@mock reasoning <text>            stream reasoning
+//	@mock tool <name> [json input]    call a tool
+//	@mock delay <duration>            pause, such as 2s
+//	@mock pace <duration>             pause between later deltas
+//	@mock usage <input> <output> [reasoning] [cached input]
+//	@mock error <category> [message]  fail with a provider error
+//	@mock next                        start the turn played after tool results
+//	@mock script <path>               play a script file instead
+func Parse(message string) (*Script, error) {
+	var script *Script
+	turn := []Step{}
+	scanner := bufio.NewScanner(strings.NewReader(message))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.TrimSpace(scanner.Text())
+		rest, ok := strings.CutPrefix(fields, directivePrefix)
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		if script == nil {
+			script = &Script{}
+		}
+		name, arg, _ := strings.Cut(strings.TrimSpace(rest), " ")
+		arg = strings.TrimSpace(arg)
+		step, err := parseDirective(name, arg)
+		if err != nil {
+			return nil, fmt.Errorf("mock directive on line %d: %w", line, err)
+		}
+		switch name {
+		case "script":
+			return LoadScript(arg)
+		case "next":
+			script.Turns = append(script.Turns, turn)
+			turn = []Step{}
+		default:
+			turn = append(turn, step)
+		}
+	}
+	if script != nil {
+		script.Turns = append(script.Turns, turn)
+	}
+	return script, nil
+}
+
+func parseDirective(name, arg string) (Step, error) {
+	var step Step
+	switch name {
+	case "text", "reasoning":
+		step = Step{Type: name, Text: arg}
+	case "tool":
+		toolName, input, _ := strings.Cut(arg, " ")
+		step = Step{Type: StepToolCall, Name: toolName}
+		if input = strings.TrimSpace(input); input != "" {
+			if err := json.Unmarshal([]byte(input), &step.Input); err != nil {
+				return Step{}, fmt.Errorf("tool input: %w", err)
+			}
+		}
+	case "delay", "pace":
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return Step{}, err
+		}
+		step = Step{Type: name, Duration: Duration{d}}
+	case "usage":
+		fields := strings.Fields(arg)
+		counts := make([]int, 4)
+		if len(fields) < 2 || len(fields) > len(counts) {
+			return Step{}, fmt.Errorf("usage needs 2 to 4 token counts")
+		}
+		for i, field := range fields {
+			n, err := strconv.Atoi(field)
+			if err != nil {
+				return Step{}, fmt.Errorf("usage: %w", err)
+			}
+			counts[i] = n
+		}
+		step = Step{Type: StepUsage, Usage: models.Usage{InputTokens: counts[0], OutputTokens: counts[1], ReasoningTokens: counts[2], CachedInputTokens: counts[3]}}
+	case "error":
+		category, message, _ := strings.Cut(arg, " ")
+		step = Step{Type: StepError, Category: models.ErrorCategory(category), Message: strings.TrimSpace(message)}
+	case "next":
+		return Step{}, nil
+	case "script":
+		if arg == "" {
+			return Step{}, fmt.Errorf("script needs a path")
+		}
+		return Step{}, nil
+	default:
+		return Step{}, fmt.Errorf("unknown directive %q", name)
+	}
+	return step, step.validate()
+}
+
+func (s Step) validate() error {
+	switch s.Type {
+	case StepText, StepReasoning:
+		if s.Text == "" {
+			return fmt.Errorf("%s step needs text", s.Type)
+		}
+	case StepToolCall:
+		if s.Name == "" {
+			return fmt.Errorf("tool_call step needs a name")
+		}
+	case StepDelay, StepPace:
+		if s.Duration.Duration < 0 {
+			return fmt.Errorf("%s step duration cannot be negative", s.Type)
+		}
+	case StepUsage:
+	case StepError:
+		if s.Category == "" {
+			return fmt.Errorf("error step needs a category")
+		}
+	default:
+		return fmt.Errorf("unknown step type %q", s.Type)
+	}
+	return nil
+}
+
+func (s Step) providerError() error {
+	retryable := s.Category == models.ErrorRateLimit || s.Category == models.ErrorUnavailable || s.Category == models.ErrorTimeout
+	if s.Retryable != nil {
+		retryable = *s.Retryable
+	}
+	message := s.Message
+	if message == "" {
+		message = "scripted " + string(s.Category) + " error"
+	}
+	return &models.ProviderError{Category: s.Category, Provider: ID, Retryable: retryable, Message: message}
+}
@@ -4,6 +4,7 @@ package provider
 import (
 	"context"
 	"fmt"
+	"maps"
 	"net/http"
 	"os"
 	"sort"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"github.com/chaserensberger/wingman/models"
+	"github.com/chaserensberger/wingman/models/cassette"
 	"github.com/chaserensberger/wingman/models/catalog"
 	"github.com/chaserensberger/wingman/models/providers/internal/httpmodel"
 )
@@ -27,6 +29,11 @@ type ProviderMeta struct {
 	Name      string     `json:"name"`
 	BaseURL   string     `json:"base_url,omitempty"`
 	AuthTypes []AuthType `json:"auth_types,omitempty"`
+	// Models adds catalog models for a provider the catalog data does not
+	// cover.
+	Models map[string]models.ModelInfo `json:"-"`
+	// Local serves the provider's models in process instead of over HTTP.
+	Local func(models.ModelInfo) models.Client `json:"-"`
 }
 
 var (
@@ -67,8 +74,11 @@ func IsValid(id string) bool {
 type Client struct {
 	Auth        map[string]string
 	Credentials map[string]Credential
-	Refresh     func(context.Context, string, Credential) (Credential, error)
-	registry    *Registry
+	// CredentialSets hold the named credentials of providers that have
+	// several, in failover order. They take precedence over Credentials.
+	CredentialSets map[string][]Credential
+	Refresh        func(context.Context, string, Credential) (Credential, error)
+	registry       *Registry
 }
 
 // Registry is an immutable provider and catalog generation.
@@ -76,10 +86,18 @@ type Registry struct {
 	providers map[string]ProviderMeta
 	catalog   *catalog.Catalog
 	configs   map[string]ProviderConfig
+	cassettes map[string]*cassette.Cassette
+	limiters  map[string]*httpmodel.Limiter
+	// credentials tracks credential sets; see credentials.go.
+	credentials *credentialState
+	embeddings  *embeddingUsageState
 }
 
 // Credential is one provider credential resolved by a caller-owned auth store.
 type Credential struct {
+	// Name identifies the credential within its provider's set. It is empty
+	// for a provider's single credential.
+	Name      string
 	Type      string
 	Key       string
 	Access    string
@@ -103,6 +121,52 @@ type ProviderOptions struct {
 	AuthHeader string            `json:"authHeader,omitempty"`
 	AuthScheme string            `json:"authScheme,omitempty"`
 	Query      map[string]string `json:"query,omitempty"`
+	// Cassette records the provider's model streams to a file or replays
+	// them from it instead of calling the provider.
+	Cassette *CassetteOptions `json:"cassette,omitempty"`
+	// RateLimit budgets the requests sent to the provider across the daemon.
+	RateLimit *RateLimitOptions `json:"rateLimit,omitempty"`
+	// Credentials choose how requests use the provider's named credentials.
+	Credentials *CredentialOptions `json:"credentials,omitempty"`
+}
+
+// RateLimitOptions set a provider's request budget. Zero leaves a budget
+// unset; rate-limit headers and Retry-After from the provider are honored
+// either way.
+type RateLimitOptions struct {
+	RequestsPerMinute int `json:"requestsPerMinute,omitempty"`
+	TokensPerMinute   int `json:"tokensPerMinute,omitempty"`
+}
+
+// RateLimitStatus reports the shared rate limiter of one provider.
+type RateLimitStatus struct {
+	Provider string
+	// Credential names the credential the limiter paces. It is empty for
+	// the provider's default credential.
+	Credential        string
+	RequestsPerMinute int
+	TokensPerMinute   int
+	// RemainingRequests and RemainingTokens are the provider's last
+	// report, or -1 when unknown.
+	RemainingRequests int
+	RemainingTokens   int
+	// BlockedUntil is when a Retry-After lets requests resume, if later
+	// than now.
+	BlockedUntil time.Time
+	// Waiting counts requests held back now.
+	Waiting int
+	// ThrottledRequests counts requests that waited, for Throttled in total.
+	ThrottledRequests int64
+	Throttled         time.Duration
+	// RateLimitedResponses counts 429 responses.
+	RateLimitedResponses int64
+}
+
+// CassetteOptions select a cassette file and whether it is recorded or
+// replayed.
+type CassetteOptions struct {
+	Path string `json:"path"`
+	Mode string `json:"mode"`
 }
 
 // NewRegistry creates an immutable generation from built-in providers and config.
@@ -117,8 +181,8 @@ func NewRegistry(configs map[string]ProviderConfig) (*Registry, error) {
 
 	overlays := map[string]catalog.ProviderOverlay{}
 	for id, meta := range metas {
-		if meta.BaseURL != "" {
-			overlays[id] = catalog.ProviderOverlay{BaseURL: meta.BaseURL}
+		if meta.BaseURL != "" || len(meta.Models) > 0 {
+			overlays[id] = catalog.ProviderOverlay{BaseURL: meta.BaseURL, Models: meta.Models}
 		}
 	}
 	ids := make([]string, 0, len(configs))
@@ -127,6 +191,7 @@ func NewRegistry(configs map[string]ProviderConfig) (*Registry, error) {
 	}
 	sort.Strings(ids)
 	snapshot := make(map[string]ProviderConfig, len(configs))
+	cassettes := map[string]*cassette.Cassette{}
 	for _, id := range ids {
 		if id == "" {
 			return nil, fmt.Errorf("provider ID is required")
@@ -179,21 +244,84 @@ func NewRegistry(configs map[string]ProviderConfig) (*Registry, error) {
 			}
 			cfg.Models[modelID] = info
 		}
+		if opts := cfg.Options.RateLimit; opts != nil && (opts.RequestsPerMinute < 0 || opts.TokensPerMinute < 0) {
+			return nil, fmt.Errorf("provider %q: rate limits cannot be negative", id)
+		}
+		if opts := cfg.Options.Credentials; opts != nil && opts.Strategy != "" && opts.Strategy != CredentialFailover && opts.Strategy != CredentialRoundRobin {
+			return nil, fmt.Errorf("provider %q: credential strategy must be %s or %s", id, CredentialFailover, CredentialRoundRobin)
+		}
+		if opts := cfg.Options.Cassette; opts != nil {
+			if opts.Path == "" {
+				return nil, fmt.Errorf("provider %q: cassette path is required", id)
+			}
+			tape, err := cassette.Open(opts.Path, opts.Mode)
+			if err != nil {
+				return nil, fmt.Errorf("provider %q: %w", id, err)
+			}
+			cassettes[id] = tape
+		}
 		meta.BaseURL = baseURL
 		metas[id] = meta
-		overlays[id] = catalog.ProviderOverlay{BaseURL: baseURL, Models: cfg.Models}
+		builtinModels := make(map[string]models.ModelInfo, len(meta.Models)+len(cfg.Models))
+		for modelID, info := range meta.Models {
+			info.BaseURL = baseURL
+			builtinModels[modelID] = info
+		}
+		maps.Copy(builtinModels, cfg.Models)
+		overlays[id] = catalog.ProviderOverlay{BaseURL: baseURL, Models: builtinModels}
 		snapshot[id] = cfg
 	}
 	c, err := catalog.New(overlays)
 	if err != nil {
 		return nil, err
 	}
-	return &Registry{providers: metas, catalog: c, configs: snapshot}, nil
+	limiters := make(map[string]*httpmodel.Limiter, len(metas))
+	for id := range metas {
+		var budget RateLimitOptions
+		if opts := snapshot[id].Options.RateLimit; opts != nil {
+			budget = *opts
+		}
+		limiters[id] = httpmodel.NewLimiter(budget.RequestsPerMinute, budget.TokensPerMinute)
+	}
+	return &Registry{providers: metas, catalog: c, configs: snapshot, cassettes: cassettes, limiters: limiters, credentials: newCredentialState(), embeddings: &embeddingUsageState{}}, nil
 }
 
 // Catalog returns this generation's immutable catalog snapshot.
 func (r *Registry) Catalog() *catalog.Catalog { return r.catalog }
 
+// RateLimits reports, in provider ID and credential order, the limiters
+// that have a configured budget or have seen rate limiting.
+func (r *Registry) RateLimits() []RateLimitStatus {
+	limiters := make(map[credentialKey]*httpmodel.Limiter, len(r.limiters))
+	for id, limiter := range r.limiters {
+		limiters[credentialKey{provider: id}] = limiter
+	}
+	r.credentials.mu.Lock()
+	maps.Copy(limiters, r.credentials.limiters)
+	r.credentials.mu.Unlock()
+	out := []RateLimitStatus{}
+	for key, limiter := range limiters {
+		status := limiter.Status()
+		if status.RequestsPerMinute == 0 && status.TokensPerMinute == 0 && status.RemainingRequests < 0 && status.RemainingTokens < 0 &&
+			status.Throttled == 0 && status.RateLimited == 0 && status.Waiting == 0 && status.BlockedUntil.IsZero() {
+			continue
+		}
+		out = append(out, RateLimitStatus{
+			Provider: key.provider, Credential: key.name, RequestsPerMinute: status.RequestsPerMinute, TokensPerMinute: status.TokensPerMinute,
+			RemainingRequests: status.RemainingRequests, RemainingTokens: status.RemainingTokens,
+			BlockedUntil: status.BlockedUntil, Waiting: status.Waiting,
+			ThrottledRequests: status.Throttled, Throttled: status.Throttle, RateLimitedResponses: status.RateLimited,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		return out[i].Credential < out[j].Credential
+	})
+	return out
+}
+
 // List returns generation providers in deterministic ID order.
 func (r *Registry) List() []ProviderMeta {
 	out := make([]ProviderMeta, 0, len(r.providers))
@@ -253,7 +381,7 @@ func NewClientWithCredentials(credentials map[string]Credential, refresh func(co
 
 // Prepare lowers a request into provider-native JSON without sending it.
 func (c *Client) Prepare(ctx context.Context, req models.Request) (*models.PreparedRequest, error) {
-	m, err := c.model(req.Model)
+	m, err := c.route(req.Model)
 	if err != nil {
 		return nil, err
 	}
@@ -269,13 +397,32 @@ func (c *Client) LoweredOptions(ctx context.Context, req models.Request) models.
 	return m.LoweredOptions(ctx, req)
 }
 
-// Stream sends the request to the selected provider route.
+// Stream sends the request to the selected provider route, or to the
+// provider's cassette when one is configured.
 func (c *Client) Stream(ctx context.Context, req models.Request) (*models.EventStream[models.StreamPart, *models.Message], error) {
-	m, err := c.model(req.Model)
+	m, err := c.route(req.Model)
 	if err != nil {
 		return nil, err
 	}
-	return m.Stream(ctx, req)
+	tape := c.registry.cassettes[req.Model.Provider]
+	if _, ok := m.(*httpmodel.Model); !ok {
+		// In-process models take no credentials.
+		if tape != nil {
+			return tape.Wrap(m).Stream(ctx, req)
+		}
+		return m.Stream(ctx, req)
+	}
+	var stream *models.EventStream[models.StreamPart, *models.Message]
+	err = c.withCredentials(req.Model, func(m *httpmodel.Model) error {
+		var err error
+		if tape != nil {
+			stream, err = tape.Wrap(m).Stream(ctx, req)
+		} else {
+			stream, err = m.Stream(ctx, req)
+		}
+		return err
+	})
+	return stream, err
 }
 
 // Generate drains Stream and returns the final assistant message.
@@ -283,7 +430,78 @@ func (c *Client) Generate(ctx context.Context, req models.Request) (*models.Mess
 	return models.Generate(ctx, c, req)
 }
 
+// Embed embeds req.Input with the selected embedding model route.
+func (c *Client) Embed(ctx context.Context, req models.EmbeddingRequest) (*models.EmbeddingResponse, error) {
+	var resp *models.EmbeddingResponse
+	err := c.withCredentials(req.Model, func(m *httpmodel.Model) error {
+		var err error
+		resp, err = m.Embed(ctx, req)
+		return err
+	})
+	c.registry.recordEmbedding(req, resp, err)
+	return resp, err
+}
+
+// SubmitBatch submits requests to the batch endpoint of model's provider.
+func (c *Client) SubmitBatch(ctx context.Context, model models.ModelRef, requests []models.BatchRequest) (*models.Batch, error) {
+	m, err := c.batchModel(model)
+	if err != nil {
+		return nil, err
+	}
+	return m.SubmitBatch(ctx, requests)
+}
+
+// GetBatch returns the provider's current view of a submitted batch.
+func (c *Client) GetBatch(ctx context.Context, model models.ModelRef, id string) (*models.Batch, error) {
+	m, err := c.batchModel(model)
+	if err != nil {
+		return nil, err
+	}
+	return m.GetBatch(ctx, id)
+}
+
+// BatchResults downloads the results of a finished batch.
+func (c *Client) BatchResults(ctx context.Context, model models.ModelRef, id string) ([]models.BatchResult, error) {
+	m, err := c.batchModel(model)
+	if err != nil {
+		return nil, err
+	}
+	return m.BatchResults(ctx, id)
+}
+
+// batchModel resolves a model route for the batch API. Subscription OAuth
+// routes have no batch endpoint.
+func (c *Client) batchModel(ref models.ModelRef) (*httpmodel.Model, error) {
+	m, err := c.model(ref)
+	if err != nil {
+		return nil, err
+	}
+	if c.Credentials[m.Info_.Provider].Type == "oauth" {
+		return nil, &models.ProviderError{Category: models.ErrorInvalidRequest, Provider: m.Info_.Provider, Message: "batch requests require an API key"}
+	}
+	return m, nil
+}
+
+// route returns the client serving ref: the provider's in-process models or
+// an HTTP route.
+func (c *Client) route(ref models.ModelRef) (models.Client, error) {
+	info, err := resolveModelInfo(c.registry.catalog, ref)
+	if err != nil {
+		return nil, err
+	}
+	if local := c.registry.providers[info.Provider].Local; local != nil {
+		return local(info), nil
+	}
+	return c.model(ref)
+}
+
 func (c *Client) model(ref models.ModelRef) (*httpmodel.Model, error) {
+	return c.modelWithCredential(ref, nil)
+}
+
+// modelWithCredential resolves an HTTP route authenticated with credential,
+// or with the provider's single credential when credential is nil.
+func (c *Client) modelWithCredential(ref models.ModelRef, credential *Credential) (*httpmodel.Model, error) {
 	info, err := resolveModelInfo(c.registry.catalog, ref)
 	if err != nil {
 		return nil, err
@@ -297,7 +515,13 @@ func (c *Client) model(ref models.ModelRef) (*httpmodel.Model, error) {
 		return nil, err
 	}
 	apiKey := ""
-	credential := c.Credentials[info.Provider]
+	if credential == nil {
+		single := c.Credentials[info.Provider]
+		if set := c.CredentialSets[info.Provider]; len(set) > 0 {
+			single = set[0]
+		}
+		credential = &single
+	}
 	useAuth := true
 	if cfg.Options.Auth != nil {
 		useAuth = *cfg.Options.Auth
@@ -333,12 +557,13 @@ func (c *Client) model(ref models.ModelRef) (*httpmodel.Model, error) {
 		BaseURL:         info.BaseURL,
 		APIKey:          apiKey,
 		ForceStoreFalse: credential.Type == "oauth" && info.Provider == "openai",
+		Limiter:         c.registry.limiters[info.Provider],
 		Route: &httpmodel.Route{
 			ID:       string(protocol),
 			Protocol: protocol,
 			Endpoint: httpmodel.Endpoint{BaseURL: info.BaseURL, Query: query, ModelID: info.ID},
-			Auth:     c.routeAuth(protocol, info.Provider, apiKey, credential, cfg.Options),
-			Headers:  routeHeaders(protocol, credential),
+			Auth:     c.routeAuth(protocol, info.Provider, apiKey, *credential, cfg.Options),
+			Headers:  routeHeaders(protocol, *credential),
 		},
 	}, nil
 }
@@ -377,7 +602,7 @@ func (c *Client) routeAuth(protocol httpmodel.Protocol, providerID, apiKey strin
 	if header == "" && protocol == httpmodel.AnthropicMessages {
 		header = "x-api-key"
 	}
-	if header == "" && protocol == httpmodel.GeminiGenerate {
+	if header == "" && (protocol == httpmodel.GeminiGenerate || protocol == httpmodel.GeminiEmbed) {
 		header = "x-goog-api-key"
 	}
 	if header != "" {
@@ -427,6 +652,7 @@ func resolveModelInfo(c *catalog.Catalog, ref models.ModelRef) (models.ModelInfo
 
 func cloneMeta(meta ProviderMeta) ProviderMeta {
 	meta.AuthTypes = append([]AuthType(nil), meta.AuthTypes...)
+	meta.Models = maps.Clone(meta.Models)
 	return meta
 }
 
@@ -437,6 +663,18 @@ func cloneConfig(cfg ProviderConfig) ProviderConfig {
 		auth := *cfg.Options.Auth
 		cfg.Options.Auth = &auth
 	}
+	if cfg.Options.Cassette != nil {
+		tape := *cfg.Options.Cassette
+		cfg.Options.Cassette = &tape
+	}
+	if cfg.Options.RateLimit != nil {
+		budget := *cfg.Options.RateLimit
+		cfg.Options.RateLimit = &budget
+	}
+	if cfg.Options.Credentials != nil {
+		credentials := *cfg.Options.Credentials
+		cfg.Options.Credentials = &credentials
+	}
 	modelsByID := cfg.Models
 	cfg.Models = make(map[string]models.ModelInfo, len(modelsByID))
 	for id, info := range modelsByID {
@@ -469,6 +707,10 @@ func protocolFor(api models.API) (httpmodel.Protocol, error) {
 		return httpmodel.AnthropicMessages, nil
 	case models.APIGeminiGenerate:
 		return httpmodel.GeminiGenerate, nil
+	case models.APIOpenAIEmbeddings:
+		return httpmodel.OpenAIEmbeddings, nil
+	case models.APIGeminiEmbed:
+		return httpmodel.GeminiEmbed, nil
 	default:
 		return "", fmt.Errorf("unsupported model API: %s", api)
 	}
@@ -2,10 +2,20 @@ package provider_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/chaserensberger/wingman/models"
+	"github.com/chaserensberger/wingman/models/cassette"
 	provider "github.com/chaserensberger/wingman/models/providers"
+	"github.com/chaserensberger/wingman/models/providers/mock"
 	"github.com/chaserensberger/wingman/models/providers/openai"
 	"github.com/chaserensberger/wingman/models/providers/opencodego"
 )
@@ -163,3 +173,237 @@ func TestRegistryProviderBaseURLOverridesBuiltInModelRoutes(t *testing.T) {
 		t.Fatalf("prepared URL = %q", prepared.URL)
 	}
 }
+
+func TestClientEmbedsWithConfiguredModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("authorization") != "Bearer key" {
+			t.Errorf("request = %s, auth %q", r.URL.Path, r.Header.Get("authorization"))
+		}
+		_, _ = w.Write([]byte(`{"data":[{"index":0,"embedding":[0.1,0.2]}],"usage":{"prompt_tokens":3,"total_tokens":3}}`))
+	}))
+	defer server.Close()
+	registry, err := provider.NewRegistry(map[string]provider.ProviderConfig{
+		"local": {
+			Options: provider.ProviderOptions{BaseURL: server.URL + "/v1"},
+			Models:  map[string]models.ModelInfo{"embed": {API: models.APIOpenAIEmbeddings, Embedding: models.EmbeddingInfo{Dimensions: 2}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := registry.NewClient(map[string]string{"local": "key"})
+	ref := models.ModelRef{Provider: "local", ID: "embed"}
+
+	resp, err := models.Embed(context.Background(), client, models.EmbeddingRequest{Model: ref, Input: []string{"hello"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Embeddings) != 1 || len(resp.Embeddings[0]) != 2 || resp.Usage.InputTokens != 3 || resp.Model.Ref() != "local/embed" {
+		t.Fatalf("response = %#v", resp)
+	}
+	if usage := registry.EmbeddingUsage(); len(usage) != 1 || usage[0].Model != "embed" || usage[0].Requests != 1 || usage[0].Inputs != 1 || usage[0].InputTokens != 3 {
+		t.Fatalf("embedding usage = %#v", usage)
+	}
+	if _, err := client.Prepare(context.Background(), models.Request{Model: ref, Messages: []models.Message{models.NewUserText("hello")}}); err == nil {
+		t.Fatal("embedding model prepared a chat request")
+	}
+
+	small, ok := registry.Catalog().Get(openai.ID, "text-embedding-3-small")
+	if !ok || small.API != models.APIOpenAIEmbeddings || small.Embedding.Dimensions != 1536 || small.Embedding.MaxBatchSize != 2048 {
+		t.Fatalf("catalog embedding model = %#v", small)
+	}
+}
+
+func TestClientRecordsAndReplaysCassette(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"recorded\"}}]}\n\ndata: {\"choices\":[{\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n"))
+	}))
+	path := filepath.Join(t.TempDir(), "local.json")
+	config := func(mode string) map[string]provider.ProviderConfig {
+		return map[string]provider.ProviderConfig{"local": {
+			Options: provider.ProviderOptions{BaseURL: server.URL, Cassette: &provider.CassetteOptions{Path: path, Mode: mode}},
+			Models:  map[string]models.ModelInfo{"chat": {API: models.APIOpenAICompatible}},
+		}}
+	}
+	req := models.Request{Model: models.ModelRef{Provider: "local", ID: "chat"}, Messages: []models.Message{models.NewUserText("hi")}}
+
+	recording, err := provider.NewRegistry(config(cassette.ModeRecord))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := recording.NewClient(nil).Generate(context.Background(), req); err != nil || msg.Content[0].(models.TextPart).Text != "recorded" {
+		t.Fatalf("recorded message = %#v, error = %v", msg, err)
+	}
+	server.Close()
+
+	replaying, err := provider.NewRegistry(config(cassette.ModeReplay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := replaying.NewClient(nil).Generate(context.Background(), req); err != nil || msg.Content[0].(models.TextPart).Text != "recorded" {
+		t.Fatalf("replayed message = %#v, error = %v", msg, err)
+	}
+	if _, err := provider.NewRegistry(map[string]provider.ProviderConfig{"local": {Options: provider.ProviderOptions{Cassette: &provider.CassetteOptions{Mode: cassette.ModeReplay}}}}); err == nil {
+		t.Fatal("cassette without a path was accepted")
+	}
+}
+
+func TestRegistrySharesProviderRateLimitAcrossClients(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"ok\"}}]}\n\ndata: {\"choices\":[{\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n"))
+	}))
+	defer server.Close()
+	registry, err := provider.NewRegistry(map[string]provider.ProviderConfig{"local": {
+		Options: provider.ProviderOptions{BaseURL: server.URL, RateLimit: &provider.RateLimitOptions{RequestsPerMinute: 1}},
+		Models:  map[string]models.ModelInfo{"chat": {API: models.APIOpenAICompatible}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := models.Request{Model: models.ModelRef{Provider: "local", ID: "chat"}, Messages: []models.Message{models.NewUserText("hi")}}
+	if _, err := registry.NewClient(nil).Generate(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := registry.NewClient(nil).Generate(ctx, req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second client over the shared budget: error = %v", err)
+	}
+	limits := registry.RateLimits()
+	if len(limits) != 1 || limits[0].Provider != "local" || limits[0].RequestsPerMinute != 1 || limits[0].Waiting != 0 {
+		t.Fatalf("rate limits = %#v", limits)
+	}
+	if _, err := provider.NewRegistry(map[string]provider.ProviderConfig{"local": {Options: provider.ProviderOptions{RateLimit: &provider.RateLimitOptions{TokensPerMinute: -1}}}}); err == nil {
+		t.Fatal("negative rate limit was accepted")
+	}
+}
+
+func TestClientFailsOverAndRotatesCredentialSet(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		keys = append(keys, key)
+		switch key {
+		case "revoked":
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		case "limited":
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"ok\"}}]}\n\ndata: [DONE]\n\n"))
+	}))
+	defer server.Close()
+	registry, err := provider.NewRegistry(map[string]provider.ProviderConfig{"local": {
+		Options: provider.ProviderOptions{BaseURL: server.URL, Credentials: &provider.CredentialOptions{Strategy: provider.CredentialRoundRobin}},
+		Models:  map[string]models.ModelInfo{"chat": {API: models.APIOpenAICompatible}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := []provider.Credential{
+		{Name: "team-a", Type: "api_key", Key: "team-a"},
+		{Name: "revoked", Type: "api_key", Key: "revoked"},
+		{Name: "limited", Type: "api_key", Key: "limited"},
+		{Name: "team-b", Type: "api_key", Key: "team-b"},
+	}
+	client := registry.NewClientWithCredentialSets(nil, map[string][]provider.Credential{"local": set}, nil)
+	req := models.Request{Model: models.ModelRef{Provider: "local", ID: "chat"}, Messages: []models.Message{models.NewUserText("hi")}}
+	var used []string
+	for range 3 {
+		stream, err := client.Stream(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for part := range stream.Iter() {
+			if metadata, ok := part.(models.ResponseMetadataPart); ok {
+				used = append(used, fmt.Sprint(metadata.Meta["credential"]))
+			}
+		}
+	}
+	// The second request starts at revoked and fails over past limited to
+	// team-b. The third starts at limited, which is still blocked after its
+	// 429, so it is tried last.
+	if want := []string{"team-a", "team-b", "team-b"}; !slices.Equal(used, want) {
+		t.Fatalf("credentials used = %v, want %v (sent %v)", used, want, keys)
+	}
+	if want := []string{"team-a", "revoked", "limited", "team-b", "team-b"}; !slices.Equal(keys, want) {
+		t.Fatalf("keys sent = %v, want %v", keys, want)
+	}
+	usage := registry.CredentialUsage("local")
+	if len(usage) != 4 || usage[0].Name != "limited" || usage[0].RateLimited != 1 || usage[0].BlockedUntil.IsZero() ||
+		usage[1].Name != "revoked" || usage[1].Unauthorized != 1 || usage[3].Name != "team-b" || usage[3].Requests != 2 {
+		t.Fatalf("usage = %#v", usage)
+	}
+	if limits := registry.RateLimits(); len(limits) != 1 || limits[0].Credential != "limited" {
+		t.Fatalf("rate limits = %#v", limits)
+	}
+
+	pinned := registry.NewClientWithCredentialSets(nil, map[string][]provider.Credential{"local": set[1:2]}, nil)
+	if _, err := pinned.Generate(context.Background(), req); err == nil {
+		t.Fatal("a pinned revoked credential failed over")
+	}
+	if _, err := provider.NewRegistry(map[string]provider.ProviderConfig{"local": {Options: provider.ProviderOptions{Credentials: &provider.CredentialOptions{Strategy: "random"}}}}); err == nil {
+		t.Fatal("unknown credential strategy was accepted")
+	}
+}
+
+func TestMockProviderPlaysDirectivesAcrossTurns(t *testing.T) {
+	registry, err := provider.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := registry.NewClient(nil)
+	prompt := models.NewUserText("List files.\n@mock reasoning Look first.\n@mock tool bash {\"command\":\"ls\"}\n@mock usage 10 5\n@mock next\n@mock text Two files.")
+	req := models.Request{Model: mock.Model("scripted"), Messages: []models.Message{prompt}}
+
+	stream, err := client.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts []models.StreamPart
+	for part := range stream.Iter() {
+		parts = append(parts, part)
+	}
+	first, err := stream.Final()
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, ok := first.Content[1].(models.ToolCallPart)
+	if first.FinishReason != models.FinishReasonToolCalls || first.Content[0].(models.ReasoningPart).Reasoning != "Look first." || !ok || call.Name != "bash" || call.Input["command"] != "ls" {
+		t.Fatalf("first turn = %#v", first)
+	}
+	if first.Usage.InputTokens != 10 || first.Usage.OutputTokens != 5 || first.Usage.TotalTokens != 15 || first.Origin.API != models.APIMock {
+		t.Fatalf("first turn usage = %#v, origin = %#v", first.Usage, first.Origin)
+	}
+	if _, ok := parts[0].(models.StreamStartPart); !ok {
+		t.Fatalf("parts = %#v", parts)
+	}
+	if _, ok := parts[len(parts)-1].(models.FinishPart); !ok {
+		t.Fatalf("parts = %#v", parts)
+	}
+
+	req.Messages = append(req.Messages, *first, models.Message{Role: models.RoleTool, Content: models.Content{models.ToolResultPart{CallID: call.CallID, Output: []models.Part{models.TextPart{Text: "a b"}}}}})
+	second, err := client.Generate(context.Background(), req)
+	if err != nil || second.Content[0].(models.TextPart).Text != "Two files." || second.FinishReason != models.FinishReasonStop {
+		t.Fatalf("second turn = %#v, error = %v", second, err)
+	}
+
+	echo, err := client.Generate(context.Background(), models.Request{Model: mock.Model("scripted"), Messages: []models.Message{models.NewUserText("hello")}})
+	if err != nil || echo.Content[0].(models.TextPart).Text != "You said: hello" {
+		t.Fatalf("echo = %#v, error = %v", echo, err)
+	}
+
+	_, err = client.Stream(context.Background(), models.Request{Model: mock.Model("scripted"), Messages: []models.Message{models.NewUserText("@mock error rate_limit slow down")}})
+	var providerErr *models.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Category != models.ErrorRateLimit || !providerErr.Retryable || providerErr.Message != "slow down" {
+		t.Fatalf("error = %#v", err)
+	}
+	if _, err := client.Stream(context.Background(), models.Request{Model: mock.Model("scripted"), Messages: []models.Message{models.NewUserText("@mock dance")}}); err == nil {
+		t.Fatal("unknown directive was accepted")
+	}
+}
@@ -260,3 +260,20 @@ func ExpandHome(pattern string) string {
 }
 
 var getenvHome = func() string { return os.Getenv("HOME") }
+
+// PathResource normalizes a file path the way runs check read and edit
+// calls: a path inside workDir becomes slash-separated and relative to it,
+// and any other path is only cleaned.
+func PathResource(workDir, path string) string {
+	if workDir != "" {
+		abs := path
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(workDir, abs)
+		}
+		rel, err := filepath.Rel(filepath.Clean(workDir), filepath.Clean(abs))
+		if err == nil && rel != ".." && !filepath.IsAbs(rel) && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
@@ -2,6 +2,7 @@ package permission
 
 import (
 	"encoding/json"
+	"path/filepath"
 	"testing"
 )
 
@@ -85,3 +86,16 @@ func TestUnmarshalArraySyntaxValidatesEffects(t *testing.T) {
 		t.Fatal("expected invalid effect error")
 	}
 }
+
+func TestPathResourceRelativizesPathsInsideWorkDir(t *testing.T) {
+	workDir := filepath.Join(string(filepath.Separator)+"work", "repo")
+	for _, test := range []struct{ path, want string }{
+		{filepath.Join(workDir, "src", "main.go"), "src/main.go"},
+		{"src/../README.md", "README.md"},
+		{"../other/file", filepath.ToSlash(filepath.Clean("../other/file"))},
+	} {
+		if got := PathResource(workDir, test.path); got != test.want {
+			t.Fatalf("PathResource(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
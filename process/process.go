@@ -0,0 +1,461 @@
+// Package process owns background processes that agents start in one
+// execution scope. A process outlives the tool call that started it and is
+// killed when its manager closes.
+package process
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chaserensberger/wingman/store"
+)
+
+// Process statuses.
+const (
+	StatusRunning = "running"
+	StatusExited  = "exited"
+)
+
+// maxRetainedOutput is the combined output kept for each process. Older
+// output is discarded once twice this much accumulates; offsets keep
+// counting from the first byte written.
+const maxRetainedOutput = 1 << 20
+
+// Exited processes stay readable for exitedRetention, and a manager keeps at
+// most maxExitedProcesses of them; older ones are pruned when processes start
+// or are listed.
+const (
+	exitedRetention    = 15 * time.Minute
+	maxExitedProcesses = 16
+)
+
+// waitDelay bounds how long an exited process's output pipes may be held open
+// by children that outlive it.
+const waitDelay = 2 * time.Second
+
+// ErrNotFound reports a process ID unknown to the manager or owned by another
+// session.
+var ErrNotFound = errors.New("process not found")
+
+// ErrClosed reports a manager that no longer starts processes.
+var ErrClosed = errors.New("process manager is closed")
+
+// Info is a snapshot of one background process.
+type Info struct {
+	ID          string
+	SessionID   string
+	RunID       string
+	Command     string
+	WorkDir     string
+	PID         int
+	Status      string
+	ExitCode    int
+	OutputBytes int64
+	StartedAt   time.Time
+	ExitedAt    time.Time
+}
+
+// Output is a range of a process's combined stdout and stderr.
+type Output struct {
+	Data string
+	// Offset is the offset of Data's first byte and Next the offset that
+	// continues after it. Total counts every byte written so far.
+	Offset int64
+	Next   int64
+	Total  int64
+	// Skipped counts requested bytes that were already discarded.
+	Skipped int64
+}
+
+// StartOptions describes a process to start.
+type StartOptions struct {
+	SessionID string
+	RunID     string
+	Command   string
+	WorkDir   string
+}
+
+// Manager owns the background processes started in one execution scope.
+type Manager struct {
+	mu     sync.Mutex
+	procs  map[string]*Process
+	closed bool
+}
+
+// NewManager returns an empty manager.
+func NewManager() *Manager {
+	return &Manager{procs: map[string]*Process{}}
+}
+
+// Process is one background command run with bash -c.
+type Process struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+
+	// stdinLock serialises writers to stdin. It is separate from mu because
+	// a write blocks until the process reads, and the process may need mu
+	// to record the output it produces first.
+	stdinLock chan struct{}
+	stdin     *os.File
+	stdinEOF  bool
+
+	mu      sync.Mutex
+	info    Info
+	output  []byte
+	dropped int64
+	changed chan struct{}
+}
+
+// Start runs opts.Command in opts.WorkDir. The process is not tied to any
+// request context; it runs until it exits, is signalled, or the manager
+// closes.
+func (m *Manager) Start(opts StartOptions) (*Process, error) {
+	if strings.TrimSpace(opts.Command) == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+	cmd := exec.Command("bash", "-c", opts.Command)
+	cmd.Dir = opts.WorkDir
+	cmd.WaitDelay = waitDelay
+	setProcessGroup(cmd)
+	p := &Process{
+		cmd:       cmd,
+		done:      make(chan struct{}),
+		stdinLock: make(chan struct{}, 1),
+		changed:   make(chan struct{}),
+		info: Info{
+			ID:        store.NewID(store.PrefixProcess),
+			SessionID: opts.SessionID,
+			RunID:     opts.RunID,
+			Command:   opts.Command,
+			WorkDir:   opts.WorkDir,
+			Status:    StatusRunning,
+		},
+	}
+	cmd.Stdout = (*outputWriter)(p)
+	cmd.Stderr = (*outputWriter)(p)
+	// An os.Pipe rather than cmd.StdinPipe, so writes can be interrupted
+	// with a deadline.
+	stdinReader, stdin, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("start process: %w", err)
+	}
+	defer stdinReader.Close()
+	cmd.Stdin = stdinReader
+	p.stdin = stdin
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		_ = stdin.Close()
+		return nil, ErrClosed
+	}
+	if err := cmd.Start(); err != nil {
+		_ = stdin.Close()
+		return nil, fmt.Errorf("start process: %w", err)
+	}
+	p.info.PID = cmd.Process.Pid
+	p.info.StartedAt = time.Now().UTC()
+	m.pruneLocked()
+	m.procs[p.info.ID] = p
+	go p.wait()
+	return p, nil
+}
+
+// pruneLocked forgets exited processes past exitedRetention, then the oldest
+// exited processes beyond maxExitedProcesses, so their output is released.
+func (m *Manager) pruneLocked() {
+	var exited []Info
+	for _, p := range m.procs {
+		if info := p.Info(); info.Status == StatusExited {
+			exited = append(exited, info)
+		}
+	}
+	sort.Slice(exited, func(i, j int) bool { return exited[i].ExitedAt.After(exited[j].ExitedAt) })
+	for i, info := range exited {
+		if i >= maxExitedProcesses || time.Since(info.ExitedAt) > exitedRetention {
+			delete(m.procs, info.ID)
+		}
+	}
+}
+
+// Get returns a process started by sessionID.
+func (m *Manager) Get(sessionID, id string) (*Process, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p := m.procs[id]
+	if p == nil || p.info.SessionID != sessionID {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return p, nil
+}
+
+// List returns the processes started by sessionID in start order, including
+// processes that have exited and not yet been pruned.
+func (m *Manager) List(sessionID string) []Info {
+	m.mu.Lock()
+	m.pruneLocked()
+	procs := make([]*Process, 0, len(m.procs))
+	for _, p := range m.procs {
+		if p.info.SessionID == sessionID {
+			procs = append(procs, p)
+		}
+	}
+	m.mu.Unlock()
+	out := make([]Info, 0, len(procs))
+	for _, p := range procs {
+		out = append(out, p.Info())
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].StartedAt.Equal(out[j].StartedAt) {
+			return out[i].StartedAt.Before(out[j].StartedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Running reports the number of processes that have not exited.
+func (m *Manager) Running() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	running := 0
+	for _, p := range m.procs {
+		if p.Running() {
+			running++
+		}
+	}
+	return running
+}
+
+// CloseSession kills the processes sessionID started and forgets them, so a
+// deleted session leaves nothing running.
+func (m *Manager) CloseSession(sessionID string) {
+	m.mu.Lock()
+	var procs []*Process
+	for id, p := range m.procs {
+		if p.info.SessionID == sessionID {
+			procs = append(procs, p)
+			delete(m.procs, id)
+		}
+	}
+	m.mu.Unlock()
+	for _, p := range procs {
+		if p.Running() {
+			_ = signalProcess(p.cmd.Process, os.Kill)
+		}
+	}
+}
+
+// CloseContext kills every running process and waits for them to exit until
+// ctx is done. Later starts fail with ErrClosed.
+func (m *Manager) CloseContext(ctx context.Context) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	procs := make([]*Process, 0, len(m.procs))
+	for _, p := range m.procs {
+		procs = append(procs, p)
+	}
+	m.mu.Unlock()
+	for _, p := range procs {
+		if p.Running() {
+			_ = signalProcess(p.cmd.Process, os.Kill)
+		}
+	}
+	var errs []error
+	for _, p := range procs {
+		select {
+		case <-p.done:
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("process %s did not exit: %w", p.info.ID, ctx.Err()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ID returns the process ID.
+func (p *Process) ID() string { return p.info.ID }
+
+// Info returns a snapshot of the process.
+func (p *Process) Info() Info {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info := p.info
+	info.OutputBytes = p.dropped + int64(len(p.output))
+	return info
+}
+
+// Running reports whether the process has not exited.
+func (p *Process) Running() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Done is closed when the process exits and its output is complete.
+func (p *Process) Done() <-chan struct{} { return p.done }
+
+// Read returns at most limit bytes of output starting at offset. Output that
+// was discarded is skipped, and both ends are aligned to UTF-8 boundaries.
+func (p *Process) Read(offset int64, limit int) (Output, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	total := p.dropped + int64(len(p.output))
+	if offset < 0 || offset > total {
+		return Output{}, fmt.Errorf("offset %d is out of range for this process (%d bytes)", offset, total)
+	}
+	out := Output{Total: total}
+	if offset < p.dropped {
+		out.Skipped = p.dropped - offset
+		offset = p.dropped
+	}
+	buf := p.output
+	start := int(offset - p.dropped)
+	for start < len(buf) && !utf8.RuneStart(buf[start]) {
+		start++
+	}
+	end := min(start+limit, len(buf))
+	for end < len(buf) && end > start && !utf8.RuneStart(buf[end]) {
+		end--
+	}
+	out.Data = string(buf[start:end])
+	out.Offset = p.dropped + int64(start)
+	out.Next = p.dropped + int64(end)
+	return out, nil
+}
+
+// Wait blocks until output past offset is available, the process exits, or
+// ctx is done.
+func (p *Process) Wait(ctx context.Context, offset int64) error {
+	for {
+		p.mu.Lock()
+		ready := p.dropped+int64(len(p.output)) > offset
+		changed := p.changed
+		p.mu.Unlock()
+		if ready || !p.Running() {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-p.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Write sends input to the process's stdin and, if closeStdin is set, closes
+// stdin afterwards. Writes wait for the process to read its input; when ctx
+// is done first, Write stops and reports how much was written. Pipes on
+// Windows have no deadlines, so there a write waits until the process reads
+// or exits.
+func (p *Process) Write(ctx context.Context, input string, closeStdin bool) error {
+	if !p.Running() {
+		return fmt.Errorf("process %s has already exited", p.info.ID)
+	}
+	select {
+	case p.stdinLock <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-p.stdinLock }()
+	if p.stdinEOF {
+		return fmt.Errorf("stdin of process %s is closed", p.info.ID)
+	}
+	if input != "" {
+		stop := context.AfterFunc(ctx, func() { _ = p.stdin.SetWriteDeadline(time.Unix(1, 0)) })
+		n, err := io.WriteString(p.stdin, input)
+		if !stop() {
+			_ = p.stdin.SetWriteDeadline(time.Time{})
+		}
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) && ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			return fmt.Errorf("write to process %s after %d of %d bytes: %w", p.info.ID, n, len(input), err)
+		}
+	}
+	if closeStdin {
+		p.stdinEOF = true
+		if err := p.stdin.Close(); err != nil {
+			return fmt.Errorf("close stdin of process %s: %w", p.info.ID, err)
+		}
+	}
+	return nil
+}
+
+// Signal sends a signal, named like SIGTERM or TERM, to the process and the
+// children in its process group.
+func (p *Process) Signal(name string) error {
+	sig, err := ParseSignal(name)
+	if err != nil {
+		return err
+	}
+	if !p.Running() {
+		return fmt.Errorf("process %s has already exited", p.info.ID)
+	}
+	if err := signalProcess(p.cmd.Process, sig); err != nil {
+		return fmt.Errorf("signal process %s: %w", p.info.ID, err)
+	}
+	return nil
+}
+
+// ParseSignal resolves a signal name. The SIG prefix is optional.
+func ParseSignal(name string) (os.Signal, error) {
+	key := strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(key, "SIG") {
+		key = "SIG" + key
+	}
+	sig, ok := signals[key]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signal %q", name)
+	}
+	return sig, nil
+}
+
+func (p *Process) wait() {
+	_ = p.cmd.Wait()
+	// Unblock a writer the process will never read from.
+	_ = p.stdin.Close()
+	p.mu.Lock()
+	p.info.Status = StatusExited
+	p.info.ExitedAt = time.Now().UTC()
+	// ExitCode is -1 for a process terminated by a signal.
+	p.info.ExitCode = p.cmd.ProcessState.ExitCode()
+	p.mu.Unlock()
+	close(p.done)
+}
+
+// outputWriter appends combined output to its process, trimming it back to
+// the newest maxRetainedOutput bytes when it grows past twice that.
+type outputWriter Process
+
+func (w *outputWriter) Write(b []byte) (int, error) {
+	p := (*Process)(w)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.output = append(p.output, b...)
+	if len(p.output) > 2*maxRetainedOutput {
+		over := len(p.output) - maxRetainedOutput
+		p.output = append(p.output[:0:0], p.output[over:]...)
+		p.dropped += int64(over)
+	}
+	close(p.changed)
+	p.changed = make(chan struct{})
+	return len(b), nil
+}
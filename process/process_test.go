@@ -0,0 +1,237 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chaserensberger/wingman/permission"
+	"github.com/chaserensberger/wingman/tool"
+)
+
+func execute(t *testing.T, m *Manager, name string, inv tool.Invocation) tool.Result {
+	t.Helper()
+	for _, candidate := range m.Tools() {
+		if candidate.Name() == name {
+			if inv.SessionID == "" {
+				inv.SessionID = "ses_process"
+			}
+			result, err := candidate.Execute(context.Background(), inv)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			return result
+		}
+	}
+	t.Fatalf("tool %s not found", name)
+	return tool.Result{}
+}
+
+func TestProcessToolsStartReadWriteAndSignal(t *testing.T) {
+	m := NewManager()
+	t.Cleanup(func() { _ = m.CloseContext(context.Background()) })
+
+	var mu sync.Mutex
+	var streamed strings.Builder
+	progress := tool.NewProgress(func(delta string, _ map[string]any) {
+		mu.Lock()
+		defer mu.Unlock()
+		streamed.WriteString(delta)
+	})
+	started := execute(t, m, StartToolName, tool.Invocation{
+		Input:    map[string]any{"command": "echo ready; while read line; do echo \"got $line\"; done", "wait": "300ms"},
+		WorkDir:  t.TempDir(),
+		Progress: progress,
+	})
+	id, _ := started.Metadata["process_id"].(string)
+	if !strings.Contains(started.Text, "ready") || started.Metadata["status"] != StatusRunning {
+		t.Fatalf("start = %#v", started)
+	}
+	mu.Lock()
+	if !strings.HasPrefix(streamed.String(), "ready") {
+		t.Fatalf("streamed = %q", streamed.String())
+	}
+	mu.Unlock()
+
+	execute(t, m, WriteToolName, tool.Invocation{Input: map[string]any{"process_id": id, "input": "ping\n"}})
+	read := execute(t, m, ReadToolName, tool.Invocation{Input: map[string]any{"process_id": id, "offset": 6, "wait": "5s"}})
+	if !strings.Contains(read.Text, "got ping") || read.Metadata["offset"] != int64(6) {
+		t.Fatalf("read = %#v", read)
+	}
+	if list := execute(t, m, ListToolName, tool.Invocation{}); !strings.Contains(list.Text, id) || !strings.Contains(list.Text, "running") {
+		t.Fatalf("list = %q", list.Text)
+	}
+	if list := execute(t, m, ListToolName, tool.Invocation{SessionID: "ses_other"}); list.Text != "No background processes." {
+		t.Fatalf("other session list = %q", list.Text)
+	}
+
+	signalled := execute(t, m, SignalToolName, tool.Invocation{Input: map[string]any{"process_id": id, "signal": "TERM"}})
+	if signalled.Metadata["status"] != StatusExited || m.Running() != 0 {
+		t.Fatalf("signal = %#v", signalled)
+	}
+	final := execute(t, m, ReadToolName, tool.Invocation{Input: map[string]any{"process_id": id}})
+	if !strings.Contains(final.Text, "End of output") {
+		t.Fatalf("final read = %q", final.Text)
+	}
+}
+
+func TestProcessReadPagesOutput(t *testing.T) {
+	m := NewManager()
+	t.Cleanup(func() { _ = m.CloseContext(context.Background()) })
+	p, err := m.Start(StartOptions{SessionID: "ses_process", Command: "printf 'abcdefghij'", WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-p.Done()
+	page := execute(t, m, ReadToolName, tool.Invocation{Input: map[string]any{"process_id": p.ID(), "offset": 2, "limit": 3}})
+	if !strings.Contains(page.Text, "<output>\ncde\n") || !strings.Contains(page.Text, "Use offset=5 to continue.") || !strings.Contains(page.Text, "exited (code 0)") {
+		t.Fatalf("page = %q", page.Text)
+	}
+	if _, err := p.Read(11, 1); err == nil {
+		t.Fatal("out of range offset returned no error")
+	}
+	if _, err := m.Get("ses_other", p.ID()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("other session error = %v", err)
+	}
+}
+
+func TestCloseKillsRunningProcesses(t *testing.T) {
+	m := NewManager()
+	p, err := m.Start(StartOptions{SessionID: "ses_process", Command: "sleep 60 & wait", WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := m.CloseContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if p.Running() || p.Info().Status != StatusExited {
+		t.Fatalf("process still running: %#v", p.Info())
+	}
+	if _, err := m.Start(StartOptions{Command: "true", WorkDir: t.TempDir()}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("start after close error = %v", err)
+	}
+}
+
+func TestCloseSessionKillsOnlyThatSessionsProcesses(t *testing.T) {
+	m := NewManager()
+	t.Cleanup(func() { _ = m.CloseContext(context.Background()) })
+	closed, err := m.Start(StartOptions{SessionID: "ses_closed", Command: "sleep 60 & wait", WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := m.Start(StartOptions{SessionID: "ses_kept", Command: "sleep 60 & wait", WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.CloseSession("ses_closed")
+	select {
+	case <-closed.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("closed session's process is still running")
+	}
+	if len(m.List("ses_closed")) != 0 || !kept.Running() {
+		t.Fatalf("closed = %v, kept running = %v", m.List("ses_closed"), kept.Running())
+	}
+}
+
+func TestWriteLargerThanPipeBufferLeavesProcessReadable(t *testing.T) {
+	m := NewManager()
+	t.Cleanup(func() { _ = m.CloseContext(context.Background()) })
+	p, err := m.Start(StartOptions{SessionID: "ses_process", Command: "cat", WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := strings.Repeat("0123456789abcdef", 256<<10)
+	written := make(chan error, 1)
+	go func() { written <- p.Write(context.Background(), input, true) }()
+
+	infos := make(chan Info, 1)
+	go func() {
+		_ = m.List("ses_process")
+		_, _ = p.Read(0, 16)
+		infos <- p.Info()
+	}()
+	select {
+	case <-infos:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Info blocked behind a stdin write")
+	}
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("write to cat did not finish")
+	}
+	<-p.Done()
+	if got := p.Info().OutputBytes; got != int64(len(input)) {
+		t.Fatalf("output bytes = %d, want %d", got, len(input))
+	}
+}
+
+func TestWriteStopsWhenContextIsDone(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("pipes have no write deadlines on Windows")
+	}
+	m := NewManager()
+	t.Cleanup(func() { _ = m.CloseContext(context.Background()) })
+	p, err := m.Start(StartOptions{SessionID: "ses_process", Command: "sleep 60", WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := p.Write(ctx, strings.Repeat("x", 4<<20), false); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("write error = %v", err)
+	}
+	if err := p.Write(context.Background(), "", true); err != nil {
+		t.Fatalf("close after an interrupted write: %v", err)
+	}
+}
+
+func TestProcessWriteIsCheckedAgainstBashRules(t *testing.T) {
+	m := NewManager()
+	var write tool.Tool
+	for _, candidate := range m.Tools() {
+		if candidate.Name() == WriteToolName {
+			write = candidate
+		}
+	}
+	rules := permission.Ruleset{{Action: "bash", Resource: "rm *", Effect: permission.EffectDeny}}
+	check, declared, err := tool.PermissionFor(context.Background(), write, tool.Invocation{Input: map[string]any{"process_id": "proc_1", "input": "ls\nrm -rf /tmp/x\n"}})
+	if err != nil || !declared || check.Action != "bash" {
+		t.Fatalf("check = %#v, declared = %v, error = %v", check, declared, err)
+	}
+	denied := false
+	for _, resource := range check.Resources {
+		if permission.Evaluate(check.Action, resource, rules, permission.EffectAllow).Effect == permission.EffectDeny {
+			denied = true
+		}
+	}
+	if !denied || !slices.Equal(check.Resources, []string{"ls", "rm -rf /tmp/x"}) {
+		t.Fatalf("resources = %q, denied = %v", check.Resources, denied)
+	}
+}
+
+func TestManagerPrunesExitedProcesses(t *testing.T) {
+	m := NewManager()
+	t.Cleanup(func() { _ = m.CloseContext(context.Background()) })
+	for range maxExitedProcesses + 2 {
+		p, err := m.Start(StartOptions{SessionID: "ses_process", Command: "true", WorkDir: t.TempDir()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-p.Done()
+	}
+	if got := len(m.List("ses_process")); got != maxExitedProcesses {
+		t.Fatalf("listed %d processes, want %d", got, maxExitedProcesses)
+	}
+}
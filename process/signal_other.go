@@ -0,0 +1,22 @@
+//go:build !unix
+
+package process
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Without process groups only killing and interrupting are supported, and
+// SIGTERM kills.
+var signals = map[string]os.Signal{
+	"SIGINT":  os.Interrupt,
+	"SIGKILL": os.Kill,
+	"SIGTERM": os.Kill,
+}
+
+func setProcessGroup(*exec.Cmd) {}
+
+func signalProcess(p *os.Process, sig os.Signal) error {
+	return p.Signal(sig)
+}
@@ -0,0 +1,417 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chaserensberger/wingman/tool"
+)
+
+// Tool names.
+const (
+	StartToolName  = "process_start"
+	ListToolName   = "process_list"
+	ReadToolName   = "process_read"
+	WriteToolName  = "process_write"
+	SignalToolName = "process_signal"
+)
+
+const (
+	defaultReadLimit  = 16 * 1024
+	maxReadLimit      = 64 * 1024
+	defaultStartWait  = 2 * time.Second
+	defaultSignalWait = 2 * time.Second
+	maxWait           = 2 * time.Minute
+)
+
+// Tools returns the process tools bound to m. Each tool only sees the
+// processes started by the invoking session.
+func (m *Manager) Tools() []tool.Tool {
+	return []tool.Tool{
+		&listTool{manager: m},
+		&readTool{manager: m},
+		&signalTool{manager: m},
+		&startTool{manager: m},
+		&writeTool{manager: m},
+	}
+}
+
+type startTool struct{ manager *Manager }
+
+func (t *startTool) Name() string { return StartToolName }
+
+func (t *startTool) Description() string {
+	return "Start a long-running bash command, such as a dev server or file watcher, in the background and return its process ID. Output produced while waiting is returned; use process_read to follow later output and process_signal to stop it."
+}
+
+func (t *startTool) Definition() tool.Definition {
+	return tool.Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: tool.InputSchema{
+			Type: "object",
+			Properties: map[string]tool.Property{
+				"command": {
+					Type:        "string",
+					Description: "The bash command to run in the background",
+				},
+				"wait": {
+					Type:        "string",
+					Description: "How long to collect initial output (e.g., '5s'). Defaults to 2s; at most 2m. Returns early if the process exits.",
+				},
+			},
+			Required: []string{"command"},
+		},
+		Permission: &tool.PermissionTarget{Action: "bash", ResourceFields: []string{"command"}},
+	}
+}
+
+func (t *startTool) DirectoryScoped() {}
+
+func (t *startTool) BoundedOutput() {}
+
+func (t *startTool) Execute(ctx context.Context, inv tool.Invocation) (tool.Result, error) {
+	command, _ := inv.Input["command"].(string)
+	if strings.TrimSpace(command) == "" {
+		return tool.Result{}, fmt.Errorf("command is required")
+	}
+	if inv.WorkDir == "" {
+		return tool.Result{}, fmt.Errorf("workDir is required for process_start tool")
+	}
+	p, err := t.manager.Start(StartOptions{SessionID: inv.SessionID, RunID: inv.RunID, Command: command, WorkDir: inv.WorkDir})
+	if err != nil {
+		return tool.Result{}, err
+	}
+	inv.Progress.Report("", map[string]any{"process_id": p.ID(), "pid": p.Info().PID})
+	out, err := collect(ctx, p, defaultReadLimit, durationParam(inv.Input["wait"], defaultStartWait), inv.Progress)
+	if err != nil {
+		return tool.Result{}, err
+	}
+	return outputResult(p.Info(), out), nil
+}
+
+type listTool struct{ manager *Manager }
+
+func (t *listTool) Name() string { return ListToolName }
+
+func (t *listTool) Description() string {
+	return "List the background processes started in this session with their status and output size."
+}
+
+func (t *listTool) Definition() tool.Definition {
+	return tool.Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: tool.InputSchema{Type: "object", Properties: map[string]tool.Property{}},
+	}
+}
+
+func (t *listTool) Execute(_ context.Context, inv tool.Invocation) (tool.Result, error) {
+	infos := t.manager.List(inv.SessionID)
+	if len(infos) == 0 {
+		return tool.Result{Text: "No background processes.", Metadata: map[string]any{"count": 0}}, nil
+	}
+	var b strings.Builder
+	for _, info := range infos {
+		fmt.Fprintf(&b, "%s\t%s\tpid %d\t%d bytes\t%s\n", info.ID, statusText(info), info.PID, info.OutputBytes, info.Command)
+	}
+	return tool.Result{Text: strings.TrimSuffix(b.String(), "\n"), Metadata: map[string]any{"count": len(infos)}}, nil
+}
+
+type readTool struct{ manager *Manager }
+
+func (t *readTool) Name() string { return ReadToolName }
+
+func (t *readTool) Description() string {
+	return "Read a background process's combined stdout and stderr from a byte offset. Use the next offset reported at the end of each read to continue; set wait to block until new output arrives."
+}
+
+func (t *readTool) Definition() tool.Definition {
+	return tool.Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: tool.InputSchema{
+			Type: "object",
+			Properties: map[string]tool.Property{
+				"process_id": {
+					Type:        "string",
+					Description: "The process ID returned by process_start",
+				},
+				"offset": {
+					Type:        "number",
+					Description: "The byte offset to start reading from (defaults to 0)",
+				},
+				"limit": {
+					Type:        "number",
+					Description: "The maximum number of bytes to read (defaults to 16384, at most 65536)",
+				},
+				"wait": {
+					Type:        "string",
+					Description: "How long to wait when no output is available yet (e.g., '10s'). Defaults to 0; at most 2m. Returns as soon as output arrives or the process exits.",
+				},
+			},
+			Required: []string{"process_id"},
+		},
+	}
+}
+
+func (t *readTool) BoundedOutput() {}
+
+func (t *readTool) Execute(ctx context.Context, inv tool.Invocation) (tool.Result, error) {
+	p, err := t.manager.Get(inv.SessionID, stringParam(inv.Input["process_id"]))
+	if err != nil {
+		return tool.Result{}, err
+	}
+	offset := intParam(inv.Input["offset"], 0)
+	limit := intParam(inv.Input["limit"], defaultReadLimit)
+	if limit < 1 {
+		return tool.Result{}, fmt.Errorf("limit must be >= 1")
+	}
+	limit = min(limit, maxReadLimit)
+	out, err := p.Read(int64(offset), limit)
+	if err != nil {
+		return tool.Result{}, err
+	}
+	if wait := durationParam(inv.Input["wait"], 0); out.Data == "" && wait > 0 {
+		waitCtx, cancel := context.WithTimeout(ctx, min(wait, maxWait))
+		err := p.Wait(waitCtx, out.Next)
+		cancel()
+		if err == nil {
+			if out, err = p.Read(out.Next, limit); err != nil {
+				return tool.Result{}, err
+			}
+		}
+	}
+	if out.Data != "" {
+		inv.Progress.Report(out.Data, nil)
+	}
+	return outputResult(p.Info(), out), nil
+}
+
+type writeTool struct{ manager *Manager }
+
+func (t *writeTool) Name() string { return WriteToolName }
+
+func (t *writeTool) Description() string {
+	return "Send input to a background process's stdin. Include a trailing newline to submit a line. Set close_stdin to send end-of-file."
+}
+
+func (t *writeTool) Definition() tool.Definition {
+	return tool.Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: tool.InputSchema{
+			Type: "object",
+			Properties: map[string]tool.Property{
+				"process_id": {
+					Type:        "string",
+					Description: "The process ID returned by process_start",
+				},
+				"input": {
+					Type:        "string",
+					Description: "The text to write to stdin",
+				},
+				"close_stdin": {
+					Type:        "boolean",
+					Description: "Close stdin after writing the input",
+				},
+			},
+			Required: []string{"process_id"},
+		},
+	}
+}
+
+// Permission checks each line written to the process as a bash command, so
+// a shell or REPL started with process_start cannot run what the bash rules
+// deny.
+func (t *writeTool) Permission(_ context.Context, inv tool.Invocation) (tool.PermissionCheck, error) {
+	var lines []string
+	for line := range strings.Lines(stringParam(inv.Input["input"])) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		lines = []string{"*"}
+	}
+	return tool.PermissionCheck{Action: "bash", Resources: lines}, nil
+}
+
+func (t *writeTool) Execute(ctx context.Context, inv tool.Invocation) (tool.Result, error) {
+	p, err := t.manager.Get(inv.SessionID, stringParam(inv.Input["process_id"]))
+	if err != nil {
+		return tool.Result{}, err
+	}
+	input := stringParam(inv.Input["input"])
+	closeStdin, _ := inv.Input["close_stdin"].(bool)
+	if input == "" && !closeStdin {
+		return tool.Result{}, fmt.Errorf("input or close_stdin is required")
+	}
+	if err := p.Write(ctx, input, closeStdin); err != nil {
+		return tool.Result{}, err
+	}
+	text := fmt.Sprintf("Wrote %d bytes to process %s.", len(input), p.ID())
+	if closeStdin {
+		text += " Closed stdin."
+	}
+	return tool.Result{Text: text, Metadata: map[string]any{"process_id": p.ID(), "bytes": len(input), "closed_stdin": closeStdin}}, nil
+}
+
+type signalTool struct{ manager *Manager }
+
+func (t *signalTool) Name() string { return SignalToolName }
+
+func (t *signalTool) Description() string {
+	return "Send a signal to a background process and the children it started. Defaults to SIGTERM; use SIGKILL if the process does not exit."
+}
+
+func (t *signalTool) Definition() tool.Definition {
+	return tool.Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: tool.InputSchema{
+			Type: "object",
+			Properties: map[string]tool.Property{
+				"process_id": {
+					Type:        "string",
+					Description: "The process ID returned by process_start",
+				},
+				"signal": {
+					Type:        "string",
+					Description: "The signal to send",
+					Enum:        []string{"SIGTERM", "SIGINT", "SIGHUP", "SIGQUIT", "SIGKILL"},
+				},
+			},
+			Required: []string{"process_id"},
+		},
+	}
+}
+
+func (t *signalTool) Execute(ctx context.Context, inv tool.Invocation) (tool.Result, error) {
+	p, err := t.manager.Get(inv.SessionID, stringParam(inv.Input["process_id"]))
+	if err != nil {
+		return tool.Result{}, err
+	}
+	name := stringParam(inv.Input["signal"])
+	if name == "" {
+		name = "SIGTERM"
+	}
+	if err := p.Signal(name); err != nil {
+		return tool.Result{}, err
+	}
+	timer := time.NewTimer(defaultSignalWait)
+	defer timer.Stop()
+	select {
+	case <-p.Done():
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	info := p.Info()
+	return tool.Result{
+		Text:     fmt.Sprintf("Sent %s to process %s. Status: %s.", strings.ToUpper(name), info.ID, statusText(info)),
+		Metadata: infoMetadata(info),
+	}, nil
+}
+
+// collect reads a new process's output for up to wait, stopping early once
+// limit bytes are read or the process exits. Each chunk is streamed through
+// progress as it arrives.
+func collect(ctx context.Context, p *Process, limit int, wait time.Duration, progress *tool.Progress) (Output, error) {
+	out, err := p.Read(0, limit)
+	if err != nil {
+		return Output{}, err
+	}
+	if out.Data != "" {
+		progress.Report(out.Data, nil)
+	}
+	if wait <= 0 {
+		return out, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, min(wait, maxWait))
+	defer cancel()
+	for len(out.Data) < limit && p.Running() {
+		if err := p.Wait(ctx, out.Next); err != nil {
+			break
+		}
+		more, err := p.Read(out.Next, limit-len(out.Data))
+		if err != nil {
+			return Output{}, err
+		}
+		if more.Data != "" {
+			progress.Report(more.Data, nil)
+		}
+		out.Data += more.Data
+		out.Next, out.Total = more.Next, more.Total
+		out.Skipped += more.Skipped
+	}
+	return out, nil
+}
+
+// outputResult formats out with info, a snapshot taken after the read, so
+// output that arrived since the read is reported as remaining.
+func outputResult(info Info, out Output) tool.Result {
+	total := max(out.Total, info.OutputBytes)
+	var b strings.Builder
+	fmt.Fprintf(&b, "<process>%s</process>\n<status>%s</status>\n<output>\n", info.ID, statusText(info))
+	if out.Skipped > 0 {
+		fmt.Fprintf(&b, "(%d earlier bytes were discarded)\n", out.Skipped)
+	}
+	b.WriteString(out.Data)
+	switch {
+	case out.Next < total:
+		fmt.Fprintf(&b, "\n\n(Showing bytes %d-%d of %d. Use offset=%d to continue.)", out.Offset, out.Next, total, out.Next)
+	case info.Status == StatusRunning:
+		fmt.Fprintf(&b, "\n\n(No more output yet. Use offset=%d to read new output.)", out.Next)
+	default:
+		fmt.Fprintf(&b, "\n\n(End of output - total %d bytes)", total)
+	}
+	b.WriteString("\n</output>")
+	metadata := infoMetadata(info)
+	metadata["offset"] = out.Offset
+	metadata["next"] = out.Next
+	return tool.Result{Text: b.String(), Metadata: metadata}
+}
+
+func infoMetadata(info Info) map[string]any {
+	metadata := map[string]any{"process_id": info.ID, "pid": info.PID, "status": info.Status, "bytes": info.OutputBytes}
+	if info.Status == StatusExited {
+		metadata["exit_code"] = info.ExitCode
+	}
+	return metadata
+}
+
+func statusText(info Info) string {
+	if info.Status == StatusExited {
+		return fmt.Sprintf("exited (code %d)", info.ExitCode)
+	}
+	return info.Status
+}
+
+func stringParam(value any) string {
+	s, _ := value.(string)
+	return s
+}
+
+func intParam(value any, fallback int) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	default:
+		return fallback
+	}
+}
+
+func durationParam(value any, fallback time.Duration) time.Duration {
+	if s, ok := value.(string); ok && s != "" {
+		if d, err := time.ParseDuration(s); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,139 @@
+package search
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is one pattern line from a .gitignore file.
+type ignoreRule struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreRules holds the .gitignore rules of each directory visited during a
+// walk, keyed by slash-separated path relative to the root ("" for the root).
+type ignoreRules map[string][]ignoreRule
+
+// load reads dir's .gitignore, plus .git/info/exclude for the root.
+func (r ignoreRules) load(root, rel string) {
+	dir := filepath.Join(root, filepath.FromSlash(rel))
+	var rules []ignoreRule
+	if rel == "" {
+		rules = append(rules, readIgnoreFile(filepath.Join(dir, ".git", "info", "exclude"))...)
+	}
+	rules = append(rules, readIgnoreFile(filepath.Join(dir, ".gitignore"))...)
+	if len(rules) > 0 {
+		r[rel] = rules
+	}
+}
+
+// ignored reports whether rel is excluded. Rules of deeper directories
+// override shallower ones, and later lines override earlier ones.
+func (r ignoreRules) ignored(rel string, isDir bool) bool {
+	ignored := false
+	dirs := []string{""}
+	for i := range len(rel) {
+		if rel[i] == '/' {
+			dirs = append(dirs, rel[:i])
+		}
+	}
+	for _, dir := range dirs {
+		local := rel
+		if dir != "" {
+			local = rel[len(dir)+1:]
+		}
+		for _, rule := range r[dir] {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.matches(local) {
+				ignored = !rule.negate
+			}
+		}
+	}
+	return ignored
+}
+
+func (rule ignoreRule) matches(rel string) bool {
+	if !rule.anchored {
+		return globMatch(rule.pattern, path.Base(rel))
+	}
+	return globMatch(rule.pattern, rel)
+}
+
+func readIgnoreFile(name string) []ignoreRule {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	// A slash anywhere but the end anchors the pattern to its directory.
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	rule.pattern = line
+	return rule, true
+}
+
+// globMatch matches a slash-separated name against a gitignore glob, where
+// a "**" segment matches any number of path segments.
+func globMatch(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := len(name); i >= 0; i-- {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,380 @@
+// Package search embeds the text files of a working directory into a local
+// vector index and exposes natural-language lookups as the codebase_search
+// tool.
+package search
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/chaserensberger/wingman/models"
+	"github.com/chaserensberger/wingman/tool"
+)
+
+const (
+	// maxFileSize skips generated or data files too large to be useful.
+	maxFileSize = 256 << 10
+	// maxFiles bounds how many files one index tracks.
+	maxFiles = 20000
+	// chunkLines and chunkBytes bound one embedded span of a file.
+	chunkLines = 40
+	chunkBytes = 2000
+	// embedGroup is how many chunks are embedded before they are written,
+	// so an interrupted first index keeps its progress.
+	embedGroup = 256
+)
+
+// ErrNoWorkDir is returned by searches on an index without a root.
+var ErrNoWorkDir = errors.New("codebase search requires a working directory")
+
+// Result is one ranked span of a file.
+type Result struct {
+	Path      string  `json:"path"`
+	StartLine int     `json:"start_line"`
+	EndLine   int     `json:"end_line"`
+	Score     float64 `json:"score"`
+	Content   string  `json:"content"`
+}
+
+// Index embeds one working directory with one embedding model. Vectors are
+// kept in a Store shared by every index of the daemon.
+type Index struct {
+	root     string
+	store    *Store
+	embedder models.Embedder
+	model    models.ModelRef
+
+	// mu guards claimed. It is not held while files are embedded.
+	mu sync.Mutex
+	// claimed holds the files a refresh is embedding, so concurrent
+	// searches embed each change once.
+	claimed map[string]bool
+}
+
+// NewIndex returns an index of root that embeds with model through embedder.
+// Nothing is read until the first search.
+func NewIndex(root string, store *Store, embedder models.Embedder, model models.ModelRef) *Index {
+	return &Index{root: root, store: store, embedder: embedder, model: model, claimed: map[string]bool{}}
+}
+
+// Root returns the indexed working directory.
+func (ix *Index) Root() string { return ix.root }
+
+// Model returns the embedding model of the index.
+func (ix *Index) Model() models.ModelRef { return ix.model }
+
+// Search re-indexes files changed since the last search, then returns the
+// limit spans under dir most similar to query, best first. allow, when set,
+// excludes spans of files it rejects. progress, when set, is called as
+// changed files are embedded.
+func (ix *Index) Search(ctx context.Context, query, dir string, limit int, allow func(path string) bool, progress func(done, total int)) ([]Result, error) {
+	prefix := ""
+	if dir != "" {
+		rel, err := ix.resolve(dir)
+		if err != nil {
+			return nil, err
+		}
+		prefix = rel
+	} else if ix.root == "" {
+		return nil, ErrNoWorkDir
+	}
+	if err := ix.refresh(ctx, progress); err != nil {
+		return nil, err
+	}
+	vectors, err := ix.embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	target := vectors[0]
+	var results []Result
+	allowed := map[string]bool{}
+	err = ix.store.each(ctx, ix.root, ix.model.Ref(), prefix, func(c chunk) {
+		if len(c.vector) != len(target) {
+			return
+		}
+		if allow != nil {
+			ok, seen := allowed[c.path]
+			if !seen {
+				ok = allow(c.path)
+				allowed[c.path] = ok
+			}
+			if !ok {
+				return
+			}
+		}
+		score := dot(target, c.vector)
+		if len(results) == limit && score <= results[limit-1].Score {
+			return
+		}
+		i := sort.Search(len(results), func(i int) bool { return results[i].Score < score })
+		if len(results) < limit {
+			results = append(results, Result{})
+		}
+		copy(results[i+1:], results[i:])
+		results[i] = Result{Path: c.path, StartLine: c.startLine, EndLine: c.endLine, Score: score, Content: c.content}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// resolve returns path relative to the root, rejecting paths outside it.
+func (ix *Index) resolve(path string) (string, error) {
+	if ix.root == "" {
+		return "", ErrNoWorkDir
+	}
+	abs := path
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(ix.root, abs)
+	}
+	rel, err := filepath.Rel(ix.root, filepath.Clean(abs))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q is outside the working directory", path)
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// refresh brings the stored chunks in line with the files on disk: deleted
+// files are dropped and new or changed files are re-embedded. Files whose
+// size and modification time match the store are not read. Changed files
+// are claimed under ix.mu and embedded without it; files another refresh
+// has claimed are left to that refresh.
+func (ix *Index) refresh(ctx context.Context, progress func(done, total int)) error {
+	model := ix.model.Ref()
+	stored, current, changed, err := ix.claimChanges(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ix.mu.Lock()
+		for _, rel := range changed {
+			delete(ix.claimed, rel)
+		}
+		ix.mu.Unlock()
+	}()
+
+	pending := map[string]fileState{}
+	var chunks []chunk
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if len(chunks) > 0 {
+			inputs := make([]string, len(chunks))
+			for i, c := range chunks {
+				// The path helps queries that name a package or feature.
+				inputs[i] = c.path + "\n" + c.content
+			}
+			vectors, err := ix.embed(ctx, inputs)
+			if err != nil {
+				return err
+			}
+			for i := range chunks {
+				chunks[i].vector = vectors[i]
+			}
+		}
+		if err := ix.store.replace(ctx, ix.root, model, pending, chunks); err != nil {
+			return fmt.Errorf("update search index: %w", err)
+		}
+		pending, chunks = map[string]fileState{}, nil
+		return nil
+	}
+	for i, rel := range changed {
+		info := current[rel]
+		src, err := os.ReadFile(filepath.Join(ix.root, filepath.FromSlash(rel)))
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(src)
+		state := fileState{size: info.Size(), modTime: info.ModTime().UnixNano(), hash: hex.EncodeToString(sum[:])}
+		if old, ok := stored[rel]; ok && old.hash == state.hash {
+			if err := ix.store.touch(ctx, ix.root, model, rel, state); err != nil {
+				return fmt.Errorf("update search index: %w", err)
+			}
+			continue
+		}
+		pending[rel] = state
+		// Binary files are recorded without chunks so they are not re-read.
+		if utf8.Valid(src) && bytes.IndexByte(src, 0) < 0 {
+			chunks = append(chunks, split(rel, string(src))...)
+		}
+		if len(chunks) >= embedGroup {
+			if err := flush(); err != nil {
+				return err
+			}
+			if progress != nil {
+				progress(i+1, len(changed))
+			}
+		}
+	}
+	return flush()
+}
+
+// claimChanges drops deleted files from the store and claims the new and
+// changed files no other refresh is embedding. It returns the stored and
+// current file states alongside the claimed paths in sorted order.
+func (ix *Index) claimChanges(ctx context.Context) (map[string]fileState, map[string]os.FileInfo, []string, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	model := ix.model.Ref()
+	stored, err := ix.store.files(ctx, ix.root, model)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	current, err := ix.walk(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var removed, changed []string
+	for rel := range stored {
+		if _, ok := current[rel]; !ok && !ix.claimed[rel] {
+			removed = append(removed, rel)
+		}
+	}
+	for rel, info := range current {
+		if state, ok := stored[rel]; (!ok || state.size != info.Size() || state.modTime != info.ModTime().UnixNano()) && !ix.claimed[rel] {
+			changed = append(changed, rel)
+		}
+	}
+	if len(removed) > 0 {
+		if err := ix.store.remove(ctx, ix.root, model, removed); err != nil {
+			return nil, nil, nil, fmt.Errorf("update search index: %w", err)
+		}
+	}
+	sort.Strings(changed)
+	for _, rel := range changed {
+		ix.claimed[rel] = true
+	}
+	return stored, current, changed, nil
+}
+
+// walk returns the searchable files under the root by relative path. It
+// applies grep's directory and file-type rules plus .gitignore files.
+func (ix *Index) walk(ctx context.Context) (map[string]os.FileInfo, error) {
+	rules := ignoreRules{}
+	files := map[string]os.FileInfo{}
+	err := filepath.WalkDir(ix.root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(ix.root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel == "." {
+				rules.load(ix.root, "")
+				return nil
+			}
+			if tool.SkipDir(d.Name()) || rules.ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			rules.load(ix.root, rel)
+			return nil
+		}
+		if !d.Type().IsRegular() || !tool.IsTextFile(d.Name()) || secretFile(d.Name()) || rules.ignored(rel, false) {
+			return nil
+		}
+		if len(files) == maxFiles {
+			return filepath.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxFileSize {
+			return nil
+		}
+		files[rel] = info
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// secretFile reports dotenv files, which grep reads but which must not be
+// sent to an embedding provider.
+func secretFile(name string) bool {
+	return name == ".env" || strings.HasPrefix(name, ".env.")
+}
+
+// embed returns one unit-length vector per input.
+func (ix *Index) embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	resp, err := ix.embedder.Embed(ctx, models.EmbeddingRequest{Model: ix.model, Input: inputs})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("embedding model returned %d vectors for %d inputs", len(resp.Embeddings), len(inputs))
+	}
+	for _, v := range resp.Embeddings {
+		normalize(v)
+	}
+	return resp.Embeddings, nil
+}
+
+// split cuts src into spans of at most chunkLines lines and roughly
+// chunkBytes bytes, skipping blank spans. A single longer line is truncated.
+func split(rel, src string) []chunk {
+	lines := strings.SplitAfter(src, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var out []chunk
+	for start := 0; start < len(lines); {
+		end, size := start, 0
+		for end < len(lines) && end-start < chunkLines && (end == start || size+len(lines[end]) <= chunkBytes) {
+			size += len(lines[end])
+			end++
+		}
+		content := strings.Join(lines[start:end], "")
+		if len(content) > chunkBytes {
+			content = strings.ToValidUTF8(content[:chunkBytes], "")
+		}
+		if content = strings.TrimRight(content, "\n"); strings.TrimSpace(content) != "" {
+			out = append(out, chunk{path: rel, startLine: start + 1, endLine: end, content: content})
+		}
+		start = end
+	}
+	return out
+}
+
+func normalize(v []float32) {
+	var sum float64
+	for _, f := range v {
+		sum += float64(f) * float64(f)
+	}
+	if sum == 0 {
+		return
+	}
+	scale := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= scale
+	}
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -0,0 +1,171 @@
+package search
+
+import (
+	"context"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chaserensberger/wingman/models"
+)
+
+// wordEmbedder embeds text as a bag of hashed words and records each input.
+type wordEmbedder struct{ inputs []string }
+
+func (e *wordEmbedder) Embed(_ context.Context, req models.EmbeddingRequest) (*models.EmbeddingResponse, error) {
+	e.inputs = append(e.inputs, req.Input...)
+	out := &models.EmbeddingResponse{Model: req.Model}
+	for _, input := range req.Input {
+		vector := make([]float32, 64)
+		for _, word := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool { return r < 'a' || r > 'z' }) {
+			h := fnv.New32a()
+			h.Write([]byte(word))
+			vector[h.Sum32()%64]++
+		}
+		out.Embeddings = append(out.Embeddings, vector)
+	}
+	return out, nil
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSearchRanksSpansAndReindexesChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"retry.go":      "package app\n\n// retry the provider request after a rate limit error\nfunc retry() {}\n",
+		"render.go":     "package app\n\n// render the console page template\nfunc render() {}\n",
+		"docs/guide.md": "# Guide\n\nInstall the daemon and open the console.\n",
+	})
+	store, err := OpenStore(filepath.Join(t.TempDir(), "search.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	embedder := &wordEmbedder{}
+	ix := NewIndex(root, store, embedder, models.ModelRef{Provider: "test", ID: "words"})
+	ctx := context.Background()
+
+	results, err := ix.Search(ctx, "retry after rate limit error", "", 2, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Path != "retry.go" || results[0].StartLine != 1 || results[0].EndLine != 4 {
+		t.Fatalf("results = %#v", results)
+	}
+	if len(embedder.inputs) != 4 {
+		t.Fatalf("first search embedded %d inputs, want 3 chunks and the query", len(embedder.inputs))
+	}
+
+	embedder.inputs = nil
+	if _, err := ix.Search(ctx, "console", "", 5, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(embedder.inputs) != 1 {
+		t.Fatalf("unchanged files were re-embedded: %q", embedder.inputs)
+	}
+	filtered, err := ix.Search(ctx, "retry after rate limit error", "", 5, func(path string) bool { return path != "retry.go" }, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range filtered {
+		if result.Path == "retry.go" {
+			t.Fatalf("filtered results = %#v", filtered)
+		}
+	}
+
+	embedder.inputs = nil
+	writeFiles(t, root, map[string]string{"render.go": "package app\n\n// render the console page and retry a rate limit error\nfunc render() {}\n"})
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(root, "render.go"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root, "retry.go")); err != nil {
+		t.Fatal(err)
+	}
+	results, err = ix.Search(ctx, "retry after rate limit error", "", 5, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embedder.inputs) != 2 || !strings.HasPrefix(embedder.inputs[0], "render.go\n") {
+		t.Fatalf("changed file inputs = %q", embedder.inputs)
+	}
+	if len(results) != 2 || results[0].Path != "render.go" {
+		t.Fatalf("results after edit = %#v", results)
+	}
+
+	results, err = ix.Search(ctx, "console", "docs", 5, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Path != "docs/guide.md" {
+		t.Fatalf("results under docs = %#v", results)
+	}
+	if _, err := ix.Search(ctx, "console", "../outside", 5, nil, nil); err == nil {
+		t.Fatal("search outside the working directory succeeded")
+	}
+}
+
+func TestWalkAppliesGitignoreAndGrepSkipRules(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":              "*.json\n!keep.json\n/generated/\ndocs/**/*.txt\n",
+		"main.go":                 "package main\n",
+		"debug.json":              "ignored\n",
+		"keep.json":               "kept\n",
+		"generated/out.go":        "package generated\n",
+		"pkg/generated/in.go":     "package generated\n",
+		"docs/a/b/notes.txt":      "ignored\n",
+		"docs/readme.md":          "kept\n",
+		"web/.gitignore":          "cache/\n",
+		"web/cache/page.html":     "ignored\n",
+		"web/app.ts":              "export {}\n",
+		"node_modules/dep/dep.js": "ignored\n",
+		".env":                    "SECRET=1\n",
+		"image.png":               "binary\n",
+	})
+	ix := NewIndex(root, nil, nil, models.ModelRef{})
+	files, err := ix.walk(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for rel := range files {
+		got = append(got, rel)
+	}
+	sort.Strings(got)
+	want := []string{".gitignore", "docs/readme.md", "keep.json", "main.go", "pkg/generated/in.go", "web/.gitignore", "web/app.ts"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("walked files = %q, want %q", got, want)
+	}
+}
+
+func TestSplitBoundsChunks(t *testing.T) {
+	var b strings.Builder
+	for range 100 {
+		b.WriteString("line\n")
+	}
+	b.WriteString("\n\n")
+	chunks := split("a.txt", b.String())
+	if len(chunks) != 3 || chunks[0].startLine != 1 || chunks[0].endLine != chunkLines || chunks[2].startLine != 2*chunkLines+1 {
+		t.Fatalf("chunks = %#v", chunks)
+	}
+	if long := split("min.js", strings.Repeat("x", 3*chunkBytes)); len(long) != 1 || len(long[0].content) != chunkBytes {
+		t.Fatalf("long line chunks = %d", len(long))
+	}
+}
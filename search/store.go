@@ -0,0 +1,236 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+// schemaVersion is stored in PRAGMA user_version. The index is a cache, so
+// a store with another version is dropped and rebuilt rather than migrated.
+const schemaVersion = 1
+
+const schema = `
+CREATE TABLE files (
+    root     TEXT NOT NULL,
+    model    TEXT NOT NULL,
+    path     TEXT NOT NULL,
+    size     INTEGER NOT NULL,
+    mod_time INTEGER NOT NULL,
+    hash     TEXT NOT NULL,
+    PRIMARY KEY (root, model, path)
+);
+
+CREATE TABLE chunks (
+    root       TEXT NOT NULL,
+    model      TEXT NOT NULL,
+    path       TEXT NOT NULL,
+    start_line INTEGER NOT NULL,
+    end_line   INTEGER NOT NULL,
+    content    TEXT NOT NULL,
+    vector     BLOB NOT NULL
+);
+
+CREATE INDEX idx_chunks_file ON chunks(root, model, path);`
+
+// Store persists embedded chunks for every indexed working directory and
+// embedding model.
+type Store struct {
+	db *sql.DB
+}
+
+// fileState is what the store remembers about one indexed file.
+type fileState struct {
+	size    int64
+	modTime int64
+	hash    string
+}
+
+// chunk is one embedded span of a file.
+type chunk struct {
+	path      string
+	startLine int
+	endLine   int
+	content   string
+	vector    []float32
+}
+
+// OpenStore opens or creates the index database at path. An empty path
+// keeps the index in memory for the life of the Store.
+func OpenStore(path string) (*Store, error) {
+	dsn := "file::memory:"
+	if path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, fmt.Errorf("create search index directory: %w", err)
+		}
+		// The index holds source snippets; keep it private like the daemon DB.
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("create search index: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			return nil, fmt.Errorf("create search index: %w", err)
+		}
+		dsn = path
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open search index: %w", err)
+	}
+	// One connection keeps an in-memory database alive and serializes
+	// writers without busy retries.
+	db.SetMaxOpenConns(1)
+	s := &Store{db: db}
+	if err := s.init(path != ""); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the index database.
+func (s *Store) Close() error { return s.db.Close() }
+
+func (s *Store) init(onDisk bool) error {
+	if onDisk {
+		if _, err := s.db.Exec(`PRAGMA journal_mode = WAL`); err != nil {
+			return fmt.Errorf("configure search index: %w", err)
+		}
+	}
+	var version int
+	if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("read search index version: %w", err)
+	}
+	if version == schemaVersion {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, stmt := range []string{`DROP TABLE IF EXISTS chunks`, `DROP TABLE IF EXISTS files`, schema, fmt.Sprintf(`PRAGMA user_version = %d`, schemaVersion)} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("create search index: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// files returns the indexed files of root for model, keyed by relative path.
+func (s *Store) files(ctx context.Context, root, model string) (map[string]fileState, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT path, size, mod_time, hash FROM files WHERE root = ? AND model = ?`, root, model)
+	if err != nil {
+		return nil, fmt.Errorf("list indexed files: %w", err)
+	}
+	defer rows.Close()
+	out := map[string]fileState{}
+	for rows.Next() {
+		var path string
+		var state fileState
+		if err := rows.Scan(&path, &state.size, &state.modTime, &state.hash); err != nil {
+			return nil, fmt.Errorf("list indexed files: %w", err)
+		}
+		out[path] = state
+	}
+	return out, rows.Err()
+}
+
+// touch records a file whose content is unchanged since it was embedded.
+func (s *Store) touch(ctx context.Context, root, model, path string, state fileState) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE files SET size = ?, mod_time = ? WHERE root = ? AND model = ? AND path = ?`, state.size, state.modTime, root, model, path)
+	return err
+}
+
+// replace swaps the chunks of each file in files for its new chunks in
+// one transaction.
+func (s *Store) replace(ctx context.Context, root, model string, files map[string]fileState, chunks []chunk) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for path, state := range files {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM chunks WHERE root = ? AND model = ? AND path = ?`, root, model, path); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO files (root, model, path, size, mod_time, hash) VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (root, model, path) DO UPDATE SET size = excluded.size, mod_time = excluded.mod_time, hash = excluded.hash`,
+			root, model, path, state.size, state.modTime, state.hash); err != nil {
+			return err
+		}
+	}
+	for _, c := range chunks {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO chunks (root, model, path, start_line, end_line, content, vector) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			root, model, c.path, c.startLine, c.endLine, c.content, encodeVector(c.vector)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// remove drops deleted files and their chunks.
+func (s *Store) remove(ctx context.Context, root, model string, paths []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, path := range paths {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM chunks WHERE root = ? AND model = ? AND path = ?`, root, model, path); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM files WHERE root = ? AND model = ? AND path = ?`, root, model, path); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// each calls fn with every chunk of root for model under prefix.
+func (s *Store) each(ctx context.Context, root, model, prefix string, fn func(chunk)) error {
+	query := `SELECT path, start_line, end_line, content, vector FROM chunks WHERE root = ? AND model = ?`
+	args := []any{root, model}
+	if prefix != "" {
+		query += ` AND (path = ? OR substr(path, 1, ?) = ?)`
+		args = append(args, prefix, len(prefix)+1, prefix+"/")
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("read search index: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c chunk
+		var vector []byte
+		if err := rows.Scan(&c.path, &c.startLine, &c.endLine, &c.content, &vector); err != nil {
+			return fmt.Errorf("read search index: %w", err)
+		}
+		c.vector = decodeVector(vector)
+		fn(c)
+	}
+	return rows.Err()
+}
+
+// encodeVector stores v as little-endian float32s.
+func encodeVector(v []float32) []byte {
+	out := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(out[4*i:], math.Float32bits(f))
+	}
+	return out
+}
+
+func decodeVector(b []byte) []float32 {
+	out := make([]float32, len(b)/4)
+	for i := range out {
+		out[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return out
+}
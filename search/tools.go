@@ -0,0 +1,132 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/chaserensberger/wingman/tool"
+)
+
+// ToolName is the name of the semantic search tool.
+const ToolName = "codebase_search"
+
+const (
+	defaultLimit = 10
+	maxLimit     = 50
+	// snippetLines bounds each result in the text output. The structured
+	// result carries the whole span.
+	snippetLines = 15
+)
+
+// Results is the structured result of codebase_search.
+type Results struct {
+	Query   string   `json:"query"`
+	Results []Result `json:"results"`
+}
+
+// Tools returns the search tool bound to ix.
+func (ix *Index) Tools() []tool.Tool {
+	return []tool.Tool{&searchTool{index: ix}}
+}
+
+type searchTool struct{ index *Index }
+
+func (t *searchTool) Name() string { return ToolName }
+
+func (t *searchTool) Description() string {
+	return "Search the working directory by meaning rather than exact text. Describe what the code does (e.g., 'where session runs are retried after a provider error') and get the most relevant file spans with line numbers. Use grep for exact identifiers or strings. The first search in a directory indexes it, which can take a while."
+}
+
+func (t *searchTool) Definition() tool.Definition {
+	return tool.Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: tool.InputSchema{
+			Type: "object",
+			Properties: map[string]tool.Property{
+				"query": {
+					Type:        "string",
+					Description: "A natural-language description of the code to find",
+				},
+				"path": {
+					Type:        "string",
+					Description: "Only search files under this directory or in this file (optional, defaults to working directory)",
+				},
+				"limit": {
+					Type:        "integer",
+					Description: fmt.Sprintf("Maximum number of results (optional, default %d, at most %d)", defaultLimit, maxLimit),
+				},
+			},
+			Required: []string{"query"},
+		},
+		// A distinct action, since the search sends file contents to the
+		// embedding provider.
+		Permission: &tool.PermissionTarget{Action: ToolName, ResourceFields: []string{"path"}},
+	}
+}
+
+func (t *searchTool) DirectoryScoped() {}
+
+func (t *searchTool) BoundedOutput() {}
+
+func (t *searchTool) Execute(ctx context.Context, inv tool.Invocation) (tool.Result, error) {
+	query, _ := inv.Input["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return tool.Result{}, fmt.Errorf("query is required")
+	}
+	path, _ := inv.Input["path"].(string)
+	limit := min(intParam(inv.Input["limit"], defaultLimit), maxLimit)
+	if limit < 1 {
+		limit = defaultLimit
+	}
+	// Results carry file contents, so they are limited to files the read
+	// rules allow.
+	var allow func(string) bool
+	if inv.Allowed != nil {
+		allow = func(rel string) bool {
+			return inv.Allowed("read", filepath.Join(t.index.Root(), filepath.FromSlash(rel)))
+		}
+	}
+	results, err := t.index.Search(ctx, query, path, limit, allow, func(done, total int) {
+		inv.Progress.Report("", map[string]any{"indexed": done, "changed": total})
+	})
+	if err != nil {
+		return tool.Result{}, err
+	}
+	found := Results{Query: query, Results: results}
+	if found.Results == nil {
+		found.Results = []Result{}
+	}
+	if len(results) == 0 {
+		return tool.Result{Text: "No indexed files matched.", Structured: found, Metadata: map[string]any{"count": 0}}, nil
+	}
+	var b strings.Builder
+	for i, r := range results {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%s:%d-%d (score %.3f)\n", r.Path, r.StartLine, r.EndLine, r.Score)
+		lines := strings.Split(r.Content, "\n")
+		if len(lines) > snippetLines {
+			lines = append(lines[:snippetLines], fmt.Sprintf("... (%d more lines)", len(lines)-snippetLines))
+		}
+		b.WriteString(strings.Join(lines, "\n"))
+		b.WriteByte('\n')
+	}
+	return tool.Result{Text: strings.TrimSuffix(b.String(), "\n"), Structured: found, Metadata: map[string]any{"count": len(results)}}, nil
+}
+
+func intParam(value any, fallback int) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	default:
+		return fallback
+	}
+}
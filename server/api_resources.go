@@ -7,20 +7,109 @@ import (
 	"slices"
 	"time"
 
+	"github.com/chaserensberger/wingman/agent/session"
 	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/frontmatter"
 	"github.com/chaserensberger/wingman/models"
+	"github.com/chaserensberger/wingman/process"
 	"github.com/chaserensberger/wingman/store"
 )
 
 func apiAgent(value *store.Agent) api.Agent {
+	source := value.Source
+	if source == "" {
+		source = store.AgentSourceStore
+	}
 	return api.Agent{
 		ID: value.ID, Name: value.Name, Instructions: value.Instructions,
 		Tools: slices.Clone(value.Tools), Permissions: slices.Clone(value.Permissions),
 		ModelRef: value.ModelRef, Options: maps.Clone(value.Options), OutputSchema: maps.Clone(value.OutputSchema),
+		Limits: apiRunLimits(value.Limits), Revision: value.Revision, Source: source, Path: value.Path,
+		CreatedAt: value.CreatedAt, UpdatedAt: value.UpdatedAt,
+	}
+}
+
+func apiAgentRevisions(values []store.AgentRevision) []api.AgentRevision {
+	result := make([]api.AgentRevision, len(values))
+	for i, value := range values {
+		result[i] = api.AgentRevision{Revision: value.Revision, Agent: apiAgent(&value.Agent), CreatedAt: value.CreatedAt}
+	}
+	return result
+}
+
+func apiFileLoadErrors(values []frontmatter.LoadError) []api.FileLoadError {
+	result := make([]api.FileLoadError, len(values))
+	for i, value := range values {
+		result[i] = api.FileLoadError{Path: value.Path, Error: value.Error}
+	}
+	return result
+}
+
+func apiCommand(value *store.Command) api.Command {
+	source := value.Source
+	if source == "" {
+		source = store.AgentSourceStore
+	}
+	arguments := make([]api.CommandArgument, len(value.Arguments))
+	for i, argument := range value.Arguments {
+		arguments[i] = api.CommandArgument{Name: argument.Name, Description: argument.Description, Required: argument.Required, Default: argument.Default}
+	}
+	return api.Command{
+		ID: value.ID, Name: value.Name, Description: value.Description, Template: value.Template,
+		Arguments: arguments, AgentID: value.AgentID, ModelRef: value.ModelRef, Source: source, Path: value.Path,
 		CreatedAt: value.CreatedAt, UpdatedAt: value.UpdatedAt,
 	}
 }
 
+func apiCommands(values []*store.Command) []api.Command {
+	result := make([]api.Command, len(values))
+	for i, value := range values {
+		result[i] = apiCommand(value)
+	}
+	return result
+}
+
+func storeCommandArguments(values []api.CommandArgument) []store.CommandArgument {
+	if values == nil {
+		return nil
+	}
+	result := make([]store.CommandArgument, len(values))
+	for i, value := range values {
+		result[i] = store.CommandArgument{Name: value.Name, Description: value.Description, Required: value.Required, Default: value.Default}
+	}
+	return result
+}
+
+func apiInstructionFiles(values []session.InstructionFile) []api.InstructionFile {
+	result := make([]api.InstructionFile, len(values))
+	for i, value := range values {
+		result[i] = api.InstructionFile{Path: value.Path, Bytes: value.Bytes, SHA256: value.SHA256, Truncated: value.Truncated}
+	}
+	return result
+}
+
+func apiRunLimits(value *store.RunLimits) *api.RunLimits {
+	if value == nil {
+		return nil
+	}
+	return &api.RunLimits{MaxSteps: value.MaxSteps, MaxTotalTokens: value.MaxTotalTokens, MaxDurationMS: value.MaxDurationMS, MaxToolCalls: value.MaxToolCalls}
+}
+
+// storeRunLimits validates and converts request limits. Empty limits are nil.
+func storeRunLimits(value *api.RunLimits) (*store.RunLimits, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if value.MaxSteps < 0 || value.MaxTotalTokens < 0 || value.MaxDurationMS < 0 || value.MaxToolCalls < 0 {
+		return nil, fmt.Errorf("limits cannot be negative")
+	}
+	limits := store.RunLimits{MaxSteps: value.MaxSteps, MaxTotalTokens: value.MaxTotalTokens, MaxDurationMS: value.MaxDurationMS, MaxToolCalls: value.MaxToolCalls}
+	if limits == (store.RunLimits{}) {
+		return nil, nil
+	}
+	return &limits, nil
+}
+
 func storeAgent(value *api.AgentSpec) *store.Agent {
 	return &store.Agent{
 		ID: value.ID, Name: value.Name, Instructions: value.Instructions,
@@ -52,7 +141,7 @@ func apiClients(values []*store.Client) []api.Client {
 func apiWorkspace(value *store.Workspace) api.Workspace {
 	return api.Workspace{
 		ID: value.ID, Name: value.Name, Path: value.Path, ClientID: value.ClientID,
-		CreatedAt: value.CreatedAt, UpdatedAt: value.UpdatedAt,
+		Credentials: value.Credentials, CreatedAt: value.CreatedAt, UpdatedAt: value.UpdatedAt,
 	}
 }
 
@@ -105,7 +194,7 @@ func apiModelCall(value store.ModelCall) api.ModelCall {
 		InputTokens: value.InputTokens, OutputTokens: value.OutputTokens, ReasoningTokens: value.ReasoningTokens,
 		CachedInputTokens: value.CachedInputTokens, CacheWriteTokens: value.CacheWriteTokens,
 		TotalTokens: value.TotalTokens, ContextTokens: value.ContextTokens,
-		ContextWindow: value.ContextWindow, ContextPercent: value.ContextPercent, Cost: cost,
+		ContextWindow: value.ContextWindow, ContextPercent: value.ContextPercent, Cost: cost, ThrottledMS: value.ThrottledMS, Credential: value.Credential,
 		Trace: append(json.RawMessage(nil), value.Trace...), StartedAt: value.StartedAt, CompletedAt: value.CompletedAt,
 	}
 }
@@ -118,11 +207,76 @@ func apiModelCalls(values []store.ModelCall) []api.ModelCall {
 	return result
 }
 
+func apiArtifact(value *store.Artifact) api.Artifact {
+	return api.Artifact{
+		ID: value.ID, SessionID: value.SessionID, RunID: value.RunID, ToolUseID: value.ToolUseID, CallID: value.CallID,
+		Name: value.Name, MediaType: value.MediaType, Size: value.Size, Content: string(value.Content), CreatedAt: value.CreatedAt,
+	}
+}
+
+func apiBatch(value *store.Batch) api.Batch {
+	var items []api.BatchItem
+	if value.Items != nil {
+		items = make([]api.BatchItem, len(value.Items))
+		for i, item := range value.Items {
+			items[i] = api.BatchItem{Index: item.Index, Prompt: item.Prompt, SessionID: item.SessionID, RunID: item.RunID, Status: item.Status, ErrorMessage: item.ErrorMessage}
+		}
+	}
+	return api.Batch{
+		ID: value.ID, AgentID: value.Agent.ID, ModelRef: value.Agent.ModelRef, ProviderBatchID: value.ProviderBatchID,
+		Status: value.Status, ErrorMessage: value.ErrorMessage,
+		Counts: api.BatchCounts{Total: value.Counts.Total, Completed: value.Counts.Completed, Failed: value.Counts.Failed},
+		Items:  items, CreatedAt: value.CreatedAt, UpdatedAt: value.UpdatedAt, CompletedAt: value.CompletedAt,
+	}
+}
+
+func apiEval(value *store.Eval) api.Eval {
+	var cases []api.EvalCaseResult
+	if value.Cases != nil {
+		cases = make([]api.EvalCaseResult, len(value.Cases))
+		for i, c := range value.Cases {
+			cases[i] = api.EvalCaseResult{
+				Index: c.Index, SessionID: c.SessionID, RunID: c.RunID, Status: c.Status, Score: c.Score,
+				ErrorMessage: c.ErrorMessage, TotalTokens: c.TotalTokens, Cost: c.Cost,
+			}
+			// Both columns are written by the server from these shapes.
+			_ = json.Unmarshal(c.DefinitionJSON, &cases[i].Case)
+			if len(c.ResultsJSON) > 0 {
+				_ = json.Unmarshal(c.ResultsJSON, &cases[i].Checks)
+			}
+		}
+	}
+	summary := value.Summary
+	return api.Eval{
+		ID: value.ID, Name: value.Name, AgentID: value.Agent.ID, AgentRevision: value.Agent.Revision,
+		ModelRef: value.Agent.ModelRef, JudgeModelRef: value.JudgeModelRef, Status: value.Status, ErrorMessage: value.ErrorMessage,
+		Summary: api.EvalSummary{
+			Total: summary.Total, Passed: summary.Passed, Failed: summary.Failed, Errored: summary.Errored,
+			Score: summary.Score, TotalTokens: summary.TotalTokens, Cost: summary.Cost,
+		},
+		Cases: cases, CreatedAt: value.CreatedAt, UpdatedAt: value.UpdatedAt, CompletedAt: value.CompletedAt,
+	}
+}
+
+func apiProcesses(values []process.Info) []api.Process {
+	result := make([]api.Process, len(values))
+	for i, value := range values {
+		result[i] = api.Process{
+			ID: value.ID, SessionID: value.SessionID, RunID: value.RunID, Command: value.Command, WorkDir: value.WorkDir,
+			PID: value.PID, Status: value.Status, OutputBytes: value.OutputBytes, StartedAt: value.StartedAt, ExitedAt: value.ExitedAt,
+		}
+		if value.Status == process.StatusExited {
+			result[i].ExitCode = &value.ExitCode
+		}
+	}
+	return result
+}
+
 func apiSessionRun(value store.SessionRun) api.SessionRun {
 	return api.SessionRun{
 		ID: value.ID, SessionID: value.SessionID, RequestID: value.RequestID,
 		AdmittedVersion: value.AdmittedVersion, WorkDir: value.WorkDir, WorkspaceID: value.WorkspaceID,
-		ClientID: value.ClientID, Sequence: value.Sequence, Status: value.Status, Message: value.Message,
+		ClientID: value.ClientID, Sequence: value.Sequence, Priority: value.Priority, Status: value.Status, Message: value.Message,
 		Agent: apiAgent(&value.Agent), ErrorType: value.ErrorType, ErrorMessage: value.ErrorMessage,
 		CreatedAt: value.CreatedAt, StartedAt: value.StartedAt, CompletedAt: value.CompletedAt, UpdatedAt: value.UpdatedAt,
 	}
@@ -162,6 +316,7 @@ func apiPermissionRequest(value store.PermissionRequest) api.PermissionRequest {
 		ID: value.ID, SessionID: value.SessionID, RunID: value.RunID, ToolUseID: value.ToolUseID,
 		CallID: value.CallID, Action: value.Action, Resources: slices.Clone(value.Resources), Status: value.Status,
 		Response: value.Response, ErrorType: value.ErrorType, ErrorMessage: value.ErrorMessage,
+		EditedInput: slices.Clone(value.EditedInput), Message: value.Message,
 		CreatedAt: value.CreatedAt, ResolvedAt: value.ResolvedAt, UpdatedAt: value.UpdatedAt,
 	}
 }
@@ -186,6 +341,22 @@ func apiPermissionGrants(values []store.PermissionGrant) []api.PermissionGrant {
 	return result
 }
 
+func apiPermissionRuleset(value store.PermissionRuleset) api.PermissionRuleset {
+	return api.PermissionRuleset{Scope: value.Scope, ScopeID: value.ScopeID, Rules: slices.Clone(value.Rules), Version: value.Version, UpdatedAt: value.UpdatedAt}
+}
+
+func apiPermissionRuleChanges(values []store.PermissionRuleChange) []api.PermissionRuleChange {
+	result := make([]api.PermissionRuleChange, len(values))
+	for i, value := range values {
+		result[i] = api.PermissionRuleChange{
+			ID: value.ID, Scope: value.Scope, ScopeID: value.ScopeID, Version: value.Version, Operation: value.Operation,
+			ClientID: value.ClientID, SessionID: value.SessionID, GrantID: value.GrantID,
+			Before: slices.Clone(value.Before), After: slices.Clone(value.After), CreatedAt: value.CreatedAt,
+		}
+	}
+	return result
+}
+
 func apiSessionEvent(value store.SessionEvent) (api.SessionEvent, error) {
 	schemaVersion := value.SchemaVersion
 	if schemaVersion == 0 {
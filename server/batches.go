@@ -0,0 +1,394 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/chaserensberger/wingman/agent/run"
+	"github.com/chaserensberger/wingman/agent/session"
+	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/models"
+	"github.com/chaserensberger/wingman/store"
+)
+
+const (
+	defaultBatchPollInterval = 30 * time.Second
+	// maxBatchPrompts stays well below the smallest provider limit so one
+	// request body remains a reasonable size.
+	maxBatchPrompts     = 10000
+	batchCustomIDPrefix = "item_"
+	// batchSubmitTimeout bounds a submission, which outlives the request
+	// so a client disconnect cannot abandon a batch the provider accepted.
+	batchSubmitTimeout = 5 * time.Minute
+)
+
+func (s *Server) handleCreateBatch(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	var req api.CreateBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.AgentID == "" {
+		s.writeError(w, http.StatusBadRequest, "agent_id is required")
+		return
+	}
+	if len(req.Prompts) == 0 || len(req.Prompts) > maxBatchPrompts {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("prompts must contain between 1 and %d entries", maxBatchPrompts))
+		return
+	}
+	for i, prompt := range req.Prompts {
+		if strings.TrimSpace(prompt) == "" {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("prompts[%d] is empty", i))
+			return
+		}
+	}
+	clientID, err := s.resolveClientID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	stored, status, err := s.lookupAgent(r.Context(), "", req.AgentID)
+	if err != nil {
+		s.writeError(w, status, err.Error())
+		return
+	}
+	agent := s.agentWithRequestModel(stored, req.ModelRef, nil)
+	if len(agent.OutputSchema) == 0 {
+		s.writeError(w, http.StatusBadRequest, "batch agent requires an output_schema")
+		return
+	}
+	ref, info, batcher, err := s.batchClient(agent)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !info.Capabilities.StructuredOutput {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("model %s does not support structured output", ref.Ref()))
+		return
+	}
+	requests := make([]models.BatchRequest, len(req.Prompts))
+	items := make([]store.BatchItem, len(req.Prompts))
+	for i, prompt := range req.Prompts {
+		requests[i] = models.BatchRequest{CustomID: batchCustomID(i), Request: models.Request{
+			Model:        ref,
+			System:       agent.Instructions,
+			Messages:     []models.Message{{Role: models.RoleUser, Content: models.Content{models.TextPart{Text: prompt}}}},
+			OutputSchema: batchOutputSchema(agent),
+		}}
+		items[i] = store.BatchItem{Prompt: prompt}
+	}
+	// The batch is stored before it is submitted so a paid provider batch
+	// never goes unrecorded when the store fails afterwards.
+	batch := &store.Batch{ClientID: clientID, Agent: *agent, Status: store.BatchStatusSubmitting, Items: items}
+	if err := s.store.CreateBatch(r.Context(), batch); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	submitCtx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), batchSubmitTimeout)
+	defer cancel()
+	remote, err := batcher.SubmitBatch(submitCtx, ref, requests)
+	if err != nil {
+		batch.Status, batch.ErrorMessage = store.BatchStatusFailed, err.Error()
+		if updateErr := s.store.UpdateBatch(context.WithoutCancel(r.Context()), batch); updateErr != nil {
+			s.logger.Error("record failed batch submission", "batch_id", batch.ID, "error", updateErr)
+		}
+		s.writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	batch.ProviderBatchID, batch.Status = remote.ID, store.BatchStatusInProgress
+	if err := s.store.UpdateBatch(context.WithoutCancel(r.Context()), batch); err != nil {
+		s.logger.Error("record submitted batch", "batch_id", batch.ID, "provider_batch_id", remote.ID, "error", err)
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.logger.Info("batch submitted", "batch_id", batch.ID, "provider_batch_id", remote.ID, "agent_id", agent.ID, "model_ref", ref.Ref(), "items", len(items))
+	writeJSON(w, http.StatusCreated, apiBatch(batch))
+}
+
+func (s *Server) handleListBatches(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	clientID, err := s.resolveClientID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	batches, err := s.store.ListBatchesByClient(r.Context(), clientID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	result := make([]api.Batch, 0, len(batches))
+	for _, batch := range batches {
+		result = append(result, apiBatch(batch))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleGetBatch(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	clientID, err := s.resolveClientID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	batch, err := s.store.GetBatch(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, store.ErrBatchNotFound) {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if batch.ClientID != clientID {
+		s.writeError(w, http.StatusForbidden, "batch belongs to another client")
+		return
+	}
+	writeJSON(w, http.StatusOK, apiBatch(batch))
+}
+
+// batchClient resolves the agent's model to a client that can submit
+// provider batches.
+func (s *Server) batchClient(agent *store.Agent) (models.ModelRef, models.ModelInfo, models.Batcher, error) {
+	ref, info, client, err := s.buildModelClient(agent, "", s.providers)
+	if err != nil {
+		return models.ModelRef{}, models.ModelInfo{}, nil, err
+	}
+	batcher, ok := client.(models.Batcher)
+	if !ok {
+		return models.ModelRef{}, models.ModelInfo{}, nil, fmt.Errorf("model %s does not support batches", ref.Ref())
+	}
+	return ref, info, batcher, nil
+}
+
+func batchOutputSchema(agent *store.Agent) *models.OutputSchema {
+	return &models.OutputSchema{Name: agent.ID, Schema: agent.OutputSchema, Strict: true}
+}
+
+func batchCustomID(index int) string { return batchCustomIDPrefix + strconv.Itoa(index) }
+
+// failInterruptedBatches fails batches a previous process stored but never
+// finished submitting. Whether the provider accepted them is unknown.
+func (s *Server) failInterruptedBatches(ctx context.Context) error {
+	batches, err := s.store.ListBatchesByStatus(ctx, store.BatchStatusSubmitting)
+	if err != nil {
+		return err
+	}
+	for _, batch := range batches {
+		batch.Status, batch.ErrorMessage = store.BatchStatusFailed, "process interrupted while submitting the batch"
+		if err := s.store.UpdateBatch(ctx, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startBatchPoller polls in-progress batches until the server closes.
+func (s *Server) startBatchPoller() {
+	done := s.trackInflight()
+	go func() {
+		defer done()
+		ticker := time.NewTicker(s.batchPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.shutdownCtx.Done():
+				return
+			case <-ticker.C:
+				s.pollBatches(s.shutdownCtx)
+			}
+		}
+	}()
+}
+
+// pollBatches advances every in-progress batch. Failures are logged and
+// retried on the next poll.
+func (s *Server) pollBatches(ctx context.Context) {
+	batches, err := s.store.ListBatchesByStatus(ctx, store.BatchStatusInProgress)
+	if err != nil {
+		s.logger.Error("list batches", "error", err)
+		return
+	}
+	for _, batch := range batches {
+		if err := s.advanceBatch(ctx, batch.ID); err != nil && ctx.Err() == nil {
+			s.logger.Error("poll batch", "batch_id", batch.ID, "error", err)
+		}
+	}
+}
+
+// advanceBatch checks a batch with its provider and, once the provider is
+// done, imports every result that is not imported yet.
+func (s *Server) advanceBatch(ctx context.Context, id string) error {
+	batch, err := s.store.GetBatch(ctx, id)
+	if err != nil {
+		return err
+	}
+	ref, info, batcher, err := s.batchClient(&batch.Agent)
+	if err != nil {
+		return err
+	}
+	remote, err := batcher.GetBatch(ctx, ref, batch.ProviderBatchID)
+	if err != nil {
+		return err
+	}
+	if !remote.Status.Done() {
+		return nil
+	}
+	results := map[string]models.BatchResult{}
+	if remote.Status != models.BatchFailed {
+		list, err := batcher.BatchResults(ctx, ref, batch.ProviderBatchID)
+		if err != nil {
+			return err
+		}
+		for _, result := range list {
+			results[result.CustomID] = result
+		}
+	}
+	for _, item := range batch.Items {
+		if item.Status != store.BatchItemStatusPending {
+			continue
+		}
+		result, ok := results[batchCustomID(item.Index)]
+		if !ok {
+			result.Error = "provider returned no result"
+			if remote.Error != "" {
+				result.Error = remote.Error
+			}
+		}
+		if err := s.importBatchItem(ctx, batch, item, ref, info, result); err != nil {
+			return fmt.Errorf("import item %d: %w", item.Index, err)
+		}
+	}
+	batch.Status, batch.ErrorMessage = string(remote.Status), remote.Error
+	if err := s.store.UpdateBatch(ctx, batch); err != nil {
+		return err
+	}
+	s.logger.Info("batch finished", "batch_id", batch.ID, "status", batch.Status)
+	return nil
+}
+
+// importBatchItem stores one result as a run of the item's own session. The
+// run is admitted under a request ID derived from the item, so an import
+// interrupted by a restart resumes with the same run.
+func (s *Server) importBatchItem(ctx context.Context, batch *store.Batch, item store.BatchItem, ref models.ModelRef, info models.ModelInfo, result models.BatchResult) error {
+	if item.SessionID == "" {
+		sess := &store.Session{Title: fmt.Sprintf("%s batch #%d", batch.Agent.Name, item.Index+1), ClientID: batch.ClientID}
+		if err := s.store.CreateSession(sess); err != nil {
+			return err
+		}
+		item.SessionID = sess.ID
+		if err := s.store.UpdateBatchItem(ctx, batch.ID, item); err != nil {
+			return err
+		}
+	}
+	release, ok := s.runs.hold(item.SessionID)
+	if !ok {
+		return fmt.Errorf("session %s is busy", item.SessionID)
+	}
+	defer release()
+
+	admission, err := s.store.AdmitSessionRun(ctx, store.SessionRun{SessionID: item.SessionID, RequestID: batch.ID + "/" + batchCustomID(item.Index), Message: item.Prompt, Agent: batch.Agent})
+	if err != nil {
+		return err
+	}
+	if admission.Created {
+		s.events.publish(admission.QueuedEvent)
+	}
+	item.RunID = admission.Run.ID
+	status := admission.Run.Status
+	if status == store.SessionRunStatusQueued {
+		transition, err := s.store.ClaimNextSessionRun(ctx, item.SessionID)
+		if err != nil {
+			return err
+		}
+		if transition.Changed {
+			s.events.publish(transition.Event)
+		}
+		if transition.Run.ID != admission.Run.ID {
+			return fmt.Errorf("claimed run %s instead of %s", transition.Run.ID, admission.Run.ID)
+		}
+		status = store.SessionRunStatusRunning
+	}
+	// A run an earlier import claimed but never settled is still running;
+	// the session hold makes this import its only owner.
+	if status == store.SessionRunStatusRunning {
+		if !s.settleBatchRun(ctx, batch, item, ref, info, result) {
+			return ctx.Err()
+		}
+	}
+	settled, err := s.store.GetSessionRun(ctx, item.SessionID, item.RunID)
+	if err != nil {
+		return err
+	}
+	switch settled.Status {
+	case store.SessionRunStatusCompleted:
+		item.Status, item.ErrorMessage = store.BatchItemStatusCompleted, ""
+	case store.SessionRunStatusFailed, store.SessionRunStatusAborted:
+		item.Status, item.ErrorMessage = store.BatchItemStatusFailed, settled.ErrorMessage
+		if item.ErrorMessage == "" {
+			item.ErrorMessage = "run " + settled.Status
+		}
+	default:
+		return fmt.Errorf("run %s is still %s", settled.ID, settled.Status)
+	}
+	return s.store.UpdateBatchItem(ctx, batch.ID, item)
+}
+
+// settleBatchRun records the result's turn on the claimed run and settles
+// it. A provider error or an answer that does not match the agent's output
+// schema fails the run.
+func (s *Server) settleBatchRun(ctx context.Context, batch *store.Batch, item store.BatchItem, ref models.ModelRef, info models.ModelInfo, result models.BatchResult) bool {
+	now := time.Now().UTC()
+	info.InputCostPerMTok *= models.BatchCostFactor
+	info.OutputCostPerMTok *= models.BatchCostFactor
+	turn := session.RecordedTurn{
+		SessionID: item.SessionID, RunID: item.RunID, AgentID: batch.Agent.ID, Model: ref, ModelInfo: info, Prompt: item.Prompt,
+		ProviderRequestID: result.RequestID, StartedAt: batch.CreatedAt, CompletedAt: now,
+	}
+	errorType := "batch_failed"
+	var failure error
+	if result.Message == nil {
+		failure = errors.New(result.Error)
+		turn.Failure = failure
+	} else {
+		turn.Assistant = result.Message
+		turn.StructuredOutput, failure = run.ValidateStructuredOutput(batchOutputSchema(&batch.Agent), *result.Message)
+		if failure != nil {
+			errorType = "run_failed"
+		}
+	}
+	if err := session.RecordTurn(ctx, s.store, turn); err != nil {
+		failure, errorType = err, "run_failed"
+	}
+	settlement := store.SessionRunSettlement{ID: item.RunID, ExpectedStatus: store.SessionRunStatusRunning, Status: store.SessionRunStatusCompleted}
+	if failure != nil {
+		message := failure.Error()
+		settlement.Status, settlement.ErrorType, settlement.ErrorMessage = store.SessionRunStatusFailed, errorType, message
+		settlement.EventData = map[string]any{"error_type": errorType, "error_message": message}
+	} else {
+		var usage models.Usage
+		if result.Message.Usage != nil {
+			usage = *result.Message.Usage
+		}
+		settlement.EventData = map[string]any{"usage": usage, "steps": 1}
+	}
+	return s.runs.settle(ctx, settlement)
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/models"
+	"github.com/chaserensberger/wingman/store"
+	"github.com/chaserensberger/wingman/store/memory"
+)
+
+func TestBatchImportsResultsAsSessionRuns(t *testing.T) {
+	var ended atomic.Bool
+	var polls atomic.Int32
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /files":
+			_, _ = w.Write([]byte(`{"id":"file_in"}`))
+		case "POST /batches":
+			_, _ = w.Write([]byte(`{"id":"batch_1","status":"validating"}`))
+		case "GET /batches/batch_1":
+			polls.Add(1)
+			if !ended.Load() {
+				_, _ = w.Write([]byte(`{"id":"batch_1","status":"in_progress"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"batch_1","status":"completed","output_file_id":"file_out","error_file_id":"file_err"}`))
+		case "GET /files/file_out/content":
+			_, _ = w.Write([]byte(strings.Join([]string{
+				`{"custom_id":"item_0","response":{"status_code":200,"request_id":"req_0","body":{"choices":[{"message":{"role":"assistant","content":"{\"label\":\"bug\"}"},"finish_reason":"stop"}],"usage":{"prompt_tokens":9,"completion_tokens":4,"total_tokens":13}}}}`,
+				`{"custom_id":"item_1","response":{"status_code":200,"body":{"choices":[{"message":{"role":"assistant","content":"not json"},"finish_reason":"stop"}]}}}`,
+			}, "\n")))
+		case "GET /files/file_err/content":
+			_, _ = w.Write([]byte(`{"custom_id":"item_2","response":{"status_code":400,"body":{"error":{"message":"prompt too long"}}}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer provider.Close()
+
+	data := memory.NewStore()
+	if _, err := data.EnsureDefaultClient(); err != nil {
+		t.Fatal(err)
+	}
+	agent := &store.Agent{
+		ID: "agt_batch", Name: "Labeler", Instructions: "Label the issue.", ModelRef: "test/model",
+		OutputSchema: map[string]any{"type": "object", "required": []any{"label"}, "properties": map[string]any{"label": map[string]any{"type": "string"}}},
+		Options: map[string]any{agentOptionModelRoute: models.ModelInfo{
+			Provider: "test", ID: "model", API: models.APIOpenAICompatible, BaseURL: provider.URL,
+			Capabilities: models.ModelCapabilities{StructuredOutput: true}, InputCostPerMTok: 200_000, OutputCostPerMTok: 500_000,
+		}},
+	}
+	if err := data.CreateAgent(agent); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.CreateAgent(&store.Agent{ID: "agt_plain", Name: "Plain", ModelRef: "test/model"}); err != nil {
+		t.Fatal(err)
+	}
+	s := New(Config{Store: data})
+	t.Cleanup(func() { _ = s.Close(context.Background()) })
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		s.router.ServeHTTP(response, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return response
+	}
+
+	if response := serve(http.MethodPost, "/batches", `{"agent_id":"agt_plain","prompts":["a"]}`); response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "output_schema") {
+		t.Fatalf("schemaless agent = %d: %s", response.Code, response.Body.String())
+	}
+	response := serve(http.MethodPost, "/batches", `{"agent_id":"agt_batch","prompts":["crash on save","typo in docs","huge log"]}`)
+	if response.Code != http.StatusCreated {
+		t.Fatalf("create = %d: %s", response.Code, response.Body.String())
+	}
+	var created api.Batch
+	if err := json.Unmarshal(response.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.ProviderBatchID != "batch_1" || created.Status != store.BatchStatusInProgress || created.Counts.Total != 3 {
+		t.Fatalf("created = %#v", created)
+	}
+
+	ctx := context.Background()
+	s.pollBatches(ctx)
+	if batch, err := data.GetBatch(ctx, created.ID); err != nil || batch.Status != store.BatchStatusInProgress || batch.Items[0].SessionID != "" {
+		t.Fatalf("batch after first poll = %#v, error = %v", batch, err)
+	}
+	ended.Store(true)
+	s.pollBatches(ctx)
+
+	var got api.Batch
+	if err := json.Unmarshal(serve(http.MethodGet, "/batches/"+created.ID, "").Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != store.BatchStatusCompleted || got.Counts != (api.BatchCounts{Total: 3, Completed: 1, Failed: 2}) || got.CompletedAt.IsZero() {
+		t.Fatalf("batch = %#v", got)
+	}
+	for _, item := range got.Items {
+		if item.SessionID == "" || item.RunID == "" {
+			t.Fatalf("item = %#v", item)
+		}
+	}
+	if got.Items[1].Status != store.BatchItemStatusFailed || !strings.Contains(got.Items[1].ErrorMessage, "structured output") || got.Items[2].ErrorMessage != "prompt too long" {
+		t.Fatalf("failed items = %#v", got.Items)
+	}
+
+	first := got.Items[0]
+	run, err := data.GetSessionRun(ctx, first.SessionID, first.RunID)
+	if err != nil || run.Status != store.SessionRunStatusCompleted || run.Message != "crash on save" || run.Agent.ID != "agt_batch" {
+		t.Fatalf("run = %#v, error = %v", run, err)
+	}
+	messages, err := data.ListMessages(ctx, first.SessionID)
+	if err != nil || len(messages) != 2 || messages[0].Role != string(models.RoleUser) || messages[1].Role != string(models.RoleAssistant) || messages[1].RunID != first.RunID {
+		t.Fatalf("messages = %#v, error = %v", messages, err)
+	}
+	calls, err := data.ListModelCalls(ctx, first.SessionID)
+	if err != nil || len(calls) != 1 || string(calls[0].StructuredOutputJSON) != `{"label":"bug"}` || calls[0].TotalTokens != 13 || calls[0].ProviderRequestID != "req_0" {
+		t.Fatalf("model calls = %#v, error = %v", calls, err)
+	}
+	// Half of 9 input tokens at 0.2 and 4 output tokens at 0.5.
+	if cost := calls[0].Cost; cost == nil || *cost < 1.89 || *cost > 1.91 {
+		t.Fatalf("batch cost = %v, want the discounted 1.9", cost)
+	}
+	events, err := data.ListSessionEvents(ctx, first.SessionID, 0, 10)
+	if err != nil || len(events) != 3 || events[2].Type != "session.run.completed" {
+		t.Fatalf("events = %#v, error = %v", events, err)
+	}
+	failed, err := data.GetSessionRun(ctx, got.Items[2].SessionID, got.Items[2].RunID)
+	if err != nil || failed.Status != store.SessionRunStatusFailed || failed.ErrorType != "batch_failed" {
+		t.Fatalf("failed run = %#v, error = %v", failed, err)
+	}
+
+	before := polls.Load()
+	s.pollBatches(ctx)
+	if polls.Load() != before {
+		t.Fatal("finished batch was polled again")
+	}
+	var list []api.Batch
+	if err := json.Unmarshal(serve(http.MethodGet, "/batches", "").Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != created.ID || list[0].Items != nil {
+		t.Fatalf("list = %#v", list)
+	}
+}
+
+func newBatchTestServer(t *testing.T, providerURL string) (*Server, *memory.Store) {
+	t.Helper()
+	data := memory.NewStore()
+	if _, err := data.EnsureDefaultClient(); err != nil {
+		t.Fatal(err)
+	}
+	agent := &store.Agent{
+		ID: "agt_batch", Name: "Labeler", ModelRef: "test/model",
+		OutputSchema: map[string]any{"type": "object", "required": []any{"label"}, "properties": map[string]any{"label": map[string]any{"type": "string"}}},
+		Options: map[string]any{agentOptionModelRoute: models.ModelInfo{
+			Provider: "test", ID: "model", API: models.APIOpenAICompatible, BaseURL: providerURL,
+			Capabilities: models.ModelCapabilities{StructuredOutput: true}, InputCostPerMTok: 200_000, OutputCostPerMTok: 500_000,
+		}},
+	}
+	if err := data.CreateAgent(agent); err != nil {
+		t.Fatal(err)
+	}
+	s := New(Config{Store: data})
+	t.Cleanup(func() { _ = s.Close(context.Background()) })
+	return s, data
+}
+
+func TestBatchIsRecordedWhenSubmissionFails(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /files":
+			_, _ = w.Write([]byte(`{"id":"file_in"}`))
+		case "POST /batches":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":{"message":"batch queue full"}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer provider.Close()
+	s, data := newBatchTestServer(t, provider.URL)
+	ctx := context.Background()
+
+	response := httptest.NewRecorder()
+	s.router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/batches", strings.NewReader(`{"agent_id":"agt_batch","prompts":["a"]}`)))
+	if response.Code != http.StatusBadGateway {
+		t.Fatalf("create = %d: %s", response.Code, response.Body.String())
+	}
+	batches, err := data.ListBatches(ctx)
+	if err != nil || len(batches) != 1 || batches[0].Status != store.BatchStatusFailed || !strings.Contains(batches[0].ErrorMessage, "HTTP 500") || batches[0].CompletedAt.IsZero() {
+		t.Fatalf("batches = %#v, error = %v", batches, err)
+	}
+
+	interrupted := &store.Batch{Agent: batches[0].Agent, Status: store.BatchStatusSubmitting, Items: []store.BatchItem{{Prompt: "b"}}}
+	if err := data.CreateBatch(ctx, interrupted); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got, err := data.GetBatch(ctx, interrupted.ID)
+	if err != nil || got.Status != store.BatchStatusFailed || got.ErrorMessage != "process interrupted while submitting the batch" {
+		t.Fatalf("interrupted batch = %#v, error = %v", got, err)
+	}
+}
+
+func TestBatchImportSettlesRunLeftRunning(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /files":
+			_, _ = w.Write([]byte(`{"id":"file_in"}`))
+		case "POST /batches":
+			_, _ = w.Write([]byte(`{"id":"batch_1","status":"validating"}`))
+		case "GET /batches/batch_1":
+			_, _ = w.Write([]byte(`{"id":"batch_1","status":"completed","output_file_id":"file_out"}`))
+		case "GET /files/file_out/content":
+			_, _ = w.Write([]byte(`{"custom_id":"item_0","response":{"status_code":200,"body":{"choices":[{"message":{"role":"assistant","content":"{\"label\":\"bug\"}"},"finish_reason":"stop"}]}}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer provider.Close()
+	s, data := newBatchTestServer(t, provider.URL)
+	ctx := context.Background()
+
+	response := httptest.NewRecorder()
+	s.router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/batches", strings.NewReader(`{"agent_id":"agt_batch","prompts":["crash on save"]}`)))
+	if response.Code != http.StatusCreated {
+		t.Fatalf("create = %d: %s", response.Code, response.Body.String())
+	}
+	var created api.Batch
+	if err := json.Unmarshal(response.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	batch, err := data.GetBatch(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An earlier import claimed the item's run and stopped before settling it.
+	sess := &store.Session{Title: "Labeler batch #1"}
+	if err := data.CreateSession(sess); err != nil {
+		t.Fatal(err)
+	}
+	item := batch.Items[0]
+	item.SessionID = sess.ID
+	if err := data.UpdateBatchItem(ctx, batch.ID, item); err != nil {
+		t.Fatal(err)
+	}
+	admission, err := data.AdmitSessionRun(ctx, store.SessionRun{SessionID: sess.ID, RequestID: batch.ID + "/" + batchCustomID(0), Message: item.Prompt, Agent: batch.Agent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := data.ClaimNextSessionRun(ctx, sess.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	s.pollBatches(ctx)
+	got, err := data.GetBatch(ctx, batch.ID)
+	if err != nil || got.Status != store.BatchStatusCompleted || got.Items[0].Status != store.BatchItemStatusCompleted || got.Items[0].RunID != admission.Run.ID {
+		t.Fatalf("batch = %#v, error = %v", got, err)
+	}
+	run, err := data.GetSessionRun(ctx, sess.ID, admission.Run.ID)
+	if err != nil || run.Status != store.SessionRunStatusCompleted {
+		t.Fatalf("run = %#v, error = %v", run, err)
+	}
+}
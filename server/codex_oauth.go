@@ -73,18 +73,25 @@ func newOAuthManager(root context.Context, data store.Store) *oauthManager {
 	return &oauthManager{store: data, root: ctx, rootCancel: cancel, attempts: map[string]*oauthAttempt{}}
 }
 
-func (m *oauthManager) start(providerID, method string) (oauthAttemptDTO, error) {
+func (m *oauthManager) open() error {
 	m.lifecycleMu.Lock()
 	closing := m.closing
 	m.lifecycleMu.Unlock()
 	if closing {
-		return oauthAttemptDTO{}, fmt.Errorf("OAuth manager is closing")
+		return fmt.Errorf("OAuth manager is closing")
 	}
 	select {
 	case <-m.root.Done():
-		return oauthAttemptDTO{}, fmt.Errorf("OAuth manager is closing")
+		return fmt.Errorf("OAuth manager is closing")
 	default:
 	}
+	return nil
+}
+
+func (m *oauthManager) start(providerID, method string) (oauthAttemptDTO, error) {
+	if err := m.open(); err != nil {
+		return oauthAttemptDTO{}, err
+	}
 	if providerID != "openai" {
 		return oauthAttemptDTO{}, fmt.Errorf("OAuth is not supported for provider: %s", providerID)
 	}
@@ -135,8 +142,23 @@ func (m *oauthManager) startBrowser(ctx context.Context, attempt *oauthAttempt)
 	attempt.instructions = "Complete authorization in your browser. This window will close automatically."
 	m.mu.Unlock()
 
-	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/auth/callback" {
+	h := m.callbackHandler(attempt, "/auth/callback", state, "OpenAI", func(ctx context.Context, code string) (func() error, error) {
+		credential, err := exchangeCodexCode(ctx, code, codexCallback, verifier)
+		if err != nil {
+			return nil, err
+		}
+		return m.saveProvider("openai", credential), nil
+	}, nil)
+	return m.serveCallback(ctx, attempt, listener, h)
+}
+
+// callbackHandler answers a browser attempt's loopback redirect. exchange
+// trades the authorization code and returns the save that completes the
+// attempt; connected, when set, runs in the background after the credential
+// is saved.
+func (m *oauthManager) callbackHandler(attempt *oauthAttempt, path, state, display string, exchange func(context.Context, string) (func() error, error), connected func()) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
 			http.NotFound(w, r)
 			return
 		}
@@ -152,7 +174,7 @@ func (m *oauthManager) startBrowser(ctx context.Context, attempt *oauthAttempt)
 		}
 		if r.URL.Query().Get("error") != "" {
 			m.finish(attempt.id, "failed", fmt.Errorf("OAuth authorization failed"))
-			writeOAuthPage(w, http.StatusBadRequest, "Authorization failed", "OpenAI declined authorization.")
+			writeOAuthPage(w, http.StatusBadRequest, "Authorization failed", display+" declined authorization.")
 			return
 		}
 		code := r.URL.Query().Get("code")
@@ -161,13 +183,13 @@ func (m *oauthManager) startBrowser(ctx context.Context, attempt *oauthAttempt)
 			writeOAuthPage(w, http.StatusBadRequest, "Authorization failed", "Missing authorization code.")
 			return
 		}
-		credential, err := exchangeCodexCode(r.Context(), code, codexCallback, verifier)
+		save, err := exchange(r.Context(), code)
 		if err != nil {
 			m.finish(attempt.id, "failed", err)
 			writeOAuthPage(w, http.StatusBadRequest, "Authorization failed", "Token exchange failed. Return to Wingman and try again.")
 			return
 		}
-		if err := m.complete(attempt.id, credential); err != nil {
+		if err := m.complete(attempt.id, save); err != nil {
 			if errors.Is(err, errOAuthAttemptInactive) {
 				writeOAuthPage(w, http.StatusConflict, "Authorization cancelled", "This authorization attempt is no longer active.")
 				return
@@ -176,8 +198,15 @@ func (m *oauthManager) startBrowser(ctx context.Context, attempt *oauthAttempt)
 			writeOAuthPage(w, http.StatusInternalServerError, "Authorization failed", "Wingman could not save the credential.")
 			return
 		}
-		writeOAuthPage(w, http.StatusOK, "OpenAI connected", "You can close this window and return to Wingman.")
+		if connected != nil {
+			m.startWorker(connected)
+		}
+		writeOAuthPage(w, http.StatusOK, display+" connected", "You can close this window and return to Wingman.")
 	})
+}
+
+// serveCallback serves h on listener until the attempt ends.
+func (m *oauthManager) serveCallback(ctx context.Context, attempt *oauthAttempt, listener net.Listener, h http.Handler) error {
 	server := &http.Server{Handler: h}
 	if !m.startWorker(func() {
 		serveDone := make(chan struct{})
@@ -329,7 +358,7 @@ func (m *oauthManager) pollDevice(ctx context.Context, attemptID, deviceAuthID,
 			m.finish(attemptID, "failed", err)
 			return
 		}
-		if err := m.complete(attemptID, credential); err != nil {
+		if err := m.complete(attemptID, m.saveProvider("openai", credential)); err != nil {
 			if errors.Is(err, errOAuthAttemptInactive) {
 				return
 			}
@@ -350,37 +379,49 @@ func (m *oauthManager) refresh(ctx context.Context, providerID string, stale pro
 	if err != nil {
 		return provider.Credential{}, err
 	}
-	cred, ok := auth.Providers[providerID]
+	// A named credential refreshes in place in the provider's set; the
+	// default one is the provider's single stored credential.
+	credentials := auth.Providers
+	if stale.Name != "" && stale.Name != provider.DefaultCredential {
+		credentials = auth.ProviderCredentials[providerID]
+	}
+	cred, ok := credentials[storedCredentialKey(providerID, stale.Name)]
 	if !ok || cred.Type != "oauth" || cred.Refresh == "" {
 		return provider.Credential{}, fmt.Errorf("OpenAI OAuth credential is missing; reconnect the provider")
 	}
 	if cred.Access != "" && cred.ExpiresAt > time.Now().Unix() {
-		return providerCredential(cred), nil
+		return namedProviderCredential(stale.Name, cred), nil
 	}
 	fresh, err := refreshCodexCredential(ctx, cred)
 	if err != nil {
 		return provider.Credential{}, err
 	}
-	auth.Providers[providerID] = fresh
+	credentials[storedCredentialKey(providerID, stale.Name)] = fresh
 	if err := m.store.SetAuth(auth); err != nil {
 		return provider.Credential{}, fmt.Errorf("save refreshed OpenAI OAuth credential: %w", err)
 	}
-	return providerCredential(fresh), nil
+	return namedProviderCredential(stale.Name, fresh), nil
 }
 
-func (m *oauthManager) complete(id string, credential store.AuthCredential) error {
+// storedCredentialKey returns the key of a provider credential in the map
+// that holds it: the provider ID for the default credential, or the
+// credential's name within the provider's named set.
+func storedCredentialKey(providerID, name string) string {
+	if name == "" || name == provider.DefaultCredential {
+		return providerID
+	}
+	return name
+}
+
+// complete saves a pending attempt's credential and marks it completed.
+func (m *oauthManager) complete(id string, save func() error) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	attempt, ok := m.attempts[id]
 	if !ok || attempt.status != "pending" {
 		return errOAuthAttemptInactive
 	}
-	auth, err := m.store.GetAuth()
-	if err != nil {
-		return err
-	}
-	auth.Providers["openai"] = credential
-	if err := m.store.SetAuth(auth); err != nil {
+	if err := save(); err != nil {
 		return err
 	}
 	attempt.status = "completed"
@@ -388,6 +429,17 @@ func (m *oauthManager) complete(id string, credential store.AuthCredential) erro
 	return nil
 }
 
+func (m *oauthManager) saveProvider(providerID string, credential store.AuthCredential) func() error {
+	return func() error {
+		auth, err := m.store.GetAuth()
+		if err != nil {
+			return err
+		}
+		auth.Providers[providerID] = credential
+		return m.store.SetAuth(auth)
+	}
+}
+
 func (m *oauthManager) status(id string) (oauthAttemptDTO, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -398,6 +450,14 @@ func (m *oauthManager) status(id string) (oauthAttemptDTO, error) {
 	return oauthAttemptDTO{ID: attempt.id, Method: attempt.method, Status: attempt.status, URL: attempt.url, Instructions: attempt.instructions, Error: attempt.err}, nil
 }
 
+// owns reports whether attempt id authorizes the given provider key.
+func (m *oauthManager) owns(id, provider string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	attempt, ok := m.attempts[id]
+	return ok && attempt.provider == provider
+}
+
 func (m *oauthManager) cancel(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -44,7 +44,7 @@ func TestOAuthCompleteDoesNotPersistCancelledAttempt(t *testing.T) {
 	if err := manager.cancel("attempt"); err != nil {
 		t.Fatal(err)
 	}
-	if err := manager.complete("attempt", store.AuthCredential{Type: "oauth", Access: "new-access", Refresh: "new-refresh"}); !errors.Is(err, errOAuthAttemptInactive) {
+	if err := manager.complete("attempt", manager.saveProvider("openai", store.AuthCredential{Type: "oauth", Access: "new-access", Refresh: "new-refresh"})); !errors.Is(err, errOAuthAttemptInactive) {
 		t.Fatalf("complete error = %v, want inactive attempt", err)
 	}
 	if ctx.Err() == nil {
@@ -67,7 +67,7 @@ func TestOAuthCompletePersistsPendingAttempt(t *testing.T) {
 	manager := newOAuthManager(context.Background(), data)
 	manager.attempts["attempt"] = &oauthAttempt{id: "attempt", provider: "openai", status: "pending", cancel: cancel}
 	credential := store.AuthCredential{Type: "oauth", Access: "access", Refresh: "refresh", AccountID: "account"}
-	if err := manager.complete("attempt", credential); err != nil {
+	if err := manager.complete("attempt", manager.saveProvider("openai", credential)); err != nil {
 		t.Fatal(err)
 	}
 
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"context"
+
+	"github.com/chaserensberger/wingman/models"
+	provider "github.com/chaserensberger/wingman/models/providers"
+	"github.com/chaserensberger/wingman/store"
+)
+
+// ProviderEmbedder returns an embedder that authenticates with data's stored
+// provider credentials, falling back to environment variables. Credentials
+// are read on every call, so keys saved after startup apply immediately.
+func ProviderEmbedder(data store.Store, providers *provider.Registry) models.Embedder {
+	return providerEmbedder{store: data, providers: providers}
+}
+
+type providerEmbedder struct {
+	store     store.Store
+	providers *provider.Registry
+}
+
+func (e providerEmbedder) Embed(ctx context.Context, req models.EmbeddingRequest) (*models.EmbeddingResponse, error) {
+	credentials, sets, err := providerCredentials(e.store, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Embedding providers authenticate with API keys, so there is no
+	// OAuth refresh to wire.
+	return e.providers.NewClientWithCredentialSets(credentials, sets, nil).Embed(ctx, req)
+}
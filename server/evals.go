@@ -0,0 +1,486 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/eval"
+	"github.com/chaserensberger/wingman/models"
+	"github.com/chaserensberger/wingman/permission"
+	"github.com/chaserensberger/wingman/store"
+)
+
+const (
+	defaultEvalConcurrency = 4
+	maxEvalConcurrency     = 16
+	maxEvalCases           = 10000
+	// evalCaseTimeout bounds one case's run, including time spent waiting
+	// on permission requests nobody answers.
+	evalCaseTimeout  = 20 * time.Minute
+	evalPollInterval = time.Second
+)
+
+func (s *Server) handleCreateEval(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	var req api.CreateEvalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.AgentID == "" {
+		s.writeError(w, http.StatusBadRequest, "agent_id is required")
+		return
+	}
+	if len(req.Cases) == 0 || len(req.Cases) > maxEvalCases {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("cases must contain between 1 and %d entries", maxEvalCases))
+		return
+	}
+	if req.Concurrency < 0 || req.Concurrency > maxEvalConcurrency {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("concurrency must be between 0 and %d", maxEvalConcurrency))
+		return
+	}
+	if req.AgentRevision < 0 {
+		s.writeError(w, http.StatusBadRequest, "agent_revision cannot be negative")
+		return
+	}
+	clientID, err := s.resolveClientID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	workDir, workspaceID := "", ""
+	if req.WorkingDirectory != "" {
+		if workDir, workspaceID, err = s.resolveSessionLocation(clientID, req.WorkingDirectory, ""); err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	cases := make([]store.EvalCase, len(req.Cases))
+	commands := map[string][]string{}
+	seen := map[string]bool{}
+	for i, value := range req.Cases {
+		c := evalCase(value)
+		if c.ID == "" {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("cases[%d]: id is required", i))
+			return
+		}
+		if seen[c.ID] {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("cases[%d]: duplicate case id %q", i, c.ID))
+			return
+		}
+		seen[c.ID] = true
+		if err := c.Validate(); err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if c.Fixture != "" {
+			if c.Fixture, err = evalFixture(workDir, c.Fixture); err != nil {
+				s.writeError(w, http.StatusBadRequest, fmt.Sprintf("case %s: %v", c.ID, err))
+				return
+			}
+		}
+		definition, err := json.Marshal(c)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		cases[i] = store.EvalCase{CaseID: c.ID, DefinitionJSON: definition}
+		for _, check := range c.Checks {
+			if check.Type == eval.CheckCommand {
+				commands[c.ID] = append(commands[c.ID], check.Command)
+			}
+		}
+	}
+
+	stored, status, err := s.lookupAgent(r.Context(), workDir, req.AgentID)
+	if err != nil {
+		s.writeError(w, status, err.Error())
+		return
+	}
+	if req.AgentRevision > 0 && req.AgentRevision != stored.Revision {
+		if stored.Source == store.AgentSourceFile {
+			s.writeError(w, http.StatusBadRequest, "agent_revision is not supported for file agents")
+			return
+		}
+		pinned, err := s.store.GetAgentRevision(req.AgentID, req.AgentRevision)
+		if err != nil {
+			s.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		stored = &pinned.Agent
+	}
+	agent := s.agentWithRequestModel(stored, req.ModelRef, nil)
+	if _, _, _, err := s.buildModelClient(agent, "", s.providers); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Command checks run outside the agent's run, so they are held to the
+	// rules its bash tool would be.
+	layers, err := s.permissionLayers(r.Context(), agent, workspaceID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, c := range cases {
+		for _, command := range commands[c.CaseID] {
+			// Nobody answers a prompt during an eval, so ask is refused too.
+			if evaluation := evaluatePermissionLayers("bash", command, layers); evaluation.Effect != permission.EffectAllow {
+				s.writeError(w, http.StatusForbidden, fmt.Sprintf("case %s: command check %q is denied by %s permission rules (%s)", c.CaseID, command, evaluation.Source, evaluation.Effect))
+				return
+			}
+		}
+	}
+	if req.JudgeModelRef != "" {
+		if _, ok := models.ParseModelRef(req.JudgeModelRef); !ok {
+			s.writeError(w, http.StatusBadRequest, "invalid judge_model_ref: "+req.JudgeModelRef)
+			return
+		}
+	}
+
+	record := &store.Eval{ClientID: clientID, Name: req.Name, Agent: *agent, JudgeModelRef: req.JudgeModelRef, Cases: cases}
+	if err := s.store.CreateEval(r.Context(), record); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	concurrency := req.Concurrency
+	if concurrency == 0 {
+		concurrency = defaultEvalConcurrency
+	}
+	s.logger.Info("eval started", "eval_id", record.ID, "agent_id", agent.ID, "agent_revision", agent.Revision, "model_ref", agent.ModelRef, "cases", len(cases))
+	response := apiEval(record)
+	done := s.trackInflight()
+	go func() {
+		defer done()
+		s.runEval(s.shutdownCtx, record, concurrency)
+	}()
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+func (s *Server) handleListEvals(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	clientID, err := s.resolveClientID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	evals, err := s.store.ListEvals(r.Context())
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	agentID := r.URL.Query().Get("agent_id")
+	result := []api.Eval{}
+	for _, value := range evals {
+		if value.ClientID == clientID && (agentID == "" || value.Agent.ID == agentID) {
+			result = append(result, apiEval(value))
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleGetEval(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	clientID, err := s.resolveClientID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	value, err := s.store.GetEval(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, store.ErrEvalNotFound) {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if value.ClientID != clientID {
+		s.writeError(w, http.StatusForbidden, "eval belongs to another client")
+		return
+	}
+	writeJSON(w, http.StatusOK, apiEval(value))
+}
+
+// runEval runs every case of record with at most concurrency cases at once
+// and marks the eval completed. A case that fails to run is recorded as an
+// error without stopping the others.
+func (s *Server) runEval(ctx context.Context, record *store.Eval, concurrency int) {
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+cases:
+	for _, c := range record.Cases {
+		select {
+		case <-ctx.Done():
+			break cases
+		case slots <- struct{}{}:
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-slots; wg.Done() }()
+			s.runEvalCase(ctx, record, c)
+		}()
+	}
+	wg.Wait()
+	record.Status = store.EvalStatusCompleted
+	if ctx.Err() != nil {
+		record.Status, record.ErrorMessage = store.EvalStatusFailed, "server shut down during eval"
+	}
+	// The eval's own context may be done, but its outcome still has to be
+	// recorded.
+	if err := s.store.UpdateEval(context.WithoutCancel(ctx), record); err != nil {
+		s.logger.Error("finish eval", "eval_id", record.ID, "error", err)
+		return
+	}
+	s.logger.Info("eval finished", "eval_id", record.ID, "status", record.Status)
+}
+
+// runEvalCase runs one case in a fresh copy of its fixture, scores the
+// answer, and records the outcome.
+func (s *Server) runEvalCase(ctx context.Context, record *store.Eval, c store.EvalCase) {
+	var definition eval.Case
+	err := json.Unmarshal(c.DefinitionJSON, &definition)
+	if err == nil {
+		err = s.scoreEvalCase(ctx, record, definition, &c)
+	}
+	if err != nil {
+		c.Status, c.ErrorMessage = store.EvalCaseStatusError, err.Error()
+	}
+	if err := s.store.UpdateEvalCase(context.WithoutCancel(ctx), record.ID, c); err != nil {
+		s.logger.Error("record eval case", "eval_id", record.ID, "case_id", c.CaseID, "error", err)
+	}
+}
+
+func (s *Server) scoreEvalCase(ctx context.Context, record *store.Eval, definition eval.Case, c *store.EvalCase) error {
+	workDir, err := os.MkdirTemp("", "wingman-eval-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+	if definition.Fixture != "" {
+		if err := os.CopyFS(workDir, os.DirFS(definition.Fixture)); err != nil {
+			return fmt.Errorf("copy fixture: %w", err)
+		}
+	}
+	sess := &store.Session{Title: fmt.Sprintf("%s eval %s", evalName(record), definition.ID), WorkDir: workDir, ClientID: record.ClientID}
+	if err := s.store.CreateSession(sess); err != nil {
+		return err
+	}
+	defer s.detachEvalSession(context.WithoutCancel(ctx), sess.ID, workDir)
+	c.SessionID, c.Status = sess.ID, store.EvalCaseStatusRunning
+	admission, err := s.store.AdmitSessionRun(ctx, store.SessionRun{SessionID: sess.ID, RequestID: record.ID + "/" + definition.ID, Message: definition.Input, Agent: record.Agent})
+	if err != nil {
+		return err
+	}
+	c.RunID = admission.Run.ID
+	if err := s.store.UpdateEvalCase(ctx, record.ID, *c); err != nil {
+		return err
+	}
+	if admission.Created {
+		s.events.publish(admission.QueuedEvent)
+	}
+	s.runs.wake(sess.ID)
+
+	finished, err := s.awaitEvalRun(ctx, sess.ID, c.RunID)
+	c.TotalTokens, c.Cost = s.sessionUsage(context.WithoutCancel(ctx), sess.ID)
+	if err != nil {
+		return err
+	}
+	if finished.Status != store.SessionRunStatusCompleted {
+		message := finished.ErrorMessage
+		if message == "" {
+			message = "run " + finished.Status
+		}
+		return errors.New(message)
+	}
+	answer, err := s.runReply(ctx, sess.ID, c.RunID)
+	if err != nil {
+		return err
+	}
+	results := eval.Score(ctx, definition, answer, workDir, s.evalJudges(record))
+	if c.ResultsJSON, err = json.Marshal(results); err != nil {
+		return err
+	}
+	score, passed := eval.Summarize(results)
+	c.Score, c.Status = score, store.EvalCaseStatusFailed
+	if passed {
+		c.Status = store.EvalCaseStatusPassed
+	}
+	return nil
+}
+
+// detachEvalSession stops what a case's session still runs in its working
+// directory and clears the directory from the session before it is removed.
+// The transcript stays readable.
+func (s *Server) detachEvalSession(ctx context.Context, sessionID, workDir string) {
+	s.terminals.CloseSession(sessionID)
+	if s.scopes != nil {
+		if scope, ok := s.scopes.Lookup(workDir); ok {
+			scope.Processes().CloseSession(sessionID)
+		}
+	}
+	sess, err := s.store.GetSession(sessionID)
+	if err == nil {
+		_, err = s.store.MoveSession(ctx, sessionID, "", "", sess.AggregateVersion)
+	}
+	if err != nil {
+		s.logger.Error("detach eval session", "session_id", sessionID, "error", err)
+	}
+}
+
+// evalFixture resolves a case's fixture against the eval's working
+// directory. Fixtures must lie inside it, so an eval cannot copy arbitrary
+// server directories.
+func evalFixture(workDir, fixture string) (string, error) {
+	if workDir == "" {
+		return "", errors.New("fixture requires a working_directory")
+	}
+	if !filepath.IsAbs(fixture) {
+		fixture = filepath.Join(workDir, fixture)
+	}
+	resolved, err := filepath.EvalSymlinks(fixture)
+	if err != nil {
+		return "", fmt.Errorf("fixture is not a directory: %s", fixture)
+	}
+	if info, err := os.Stat(resolved); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("fixture is not a directory: %s", fixture)
+	}
+	root, err := filepath.EvalSymlinks(workDir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("fixture is outside the working directory: %s", fixture)
+	}
+	return resolved, nil
+}
+
+// awaitEvalRun waits for a case's run to settle, aborting it when the case
+// times out.
+func (s *Server) awaitEvalRun(ctx context.Context, sessionID, runID string) (*store.SessionRun, error) {
+	ctx, cancel := context.WithTimeout(ctx, evalCaseTimeout)
+	defer cancel()
+	sub, unsubscribe := s.events.subscribe(sessionID)
+	defer unsubscribe()
+	ticker := time.NewTicker(evalPollInterval)
+	defer ticker.Stop()
+	overflow := sub.overflow
+	for {
+		current, err := s.store.GetSessionRun(ctx, sessionID, runID)
+		if err != nil {
+			return nil, err
+		}
+		switch current.Status {
+		case store.SessionRunStatusCompleted, store.SessionRunStatusFailed, store.SessionRunStatusAborted:
+			return current, nil
+		}
+		select {
+		case <-ctx.Done():
+			s.runs.abort(sessionID)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("run timed out after %s", evalCaseTimeout)
+			}
+			return nil, ctx.Err()
+		case <-sub.done:
+			return nil, fmt.Errorf("session closed: %s", sessionID)
+		case <-overflow:
+			overflow = nil
+		case <-sub.events:
+		case <-ticker.C:
+		}
+	}
+}
+
+// evalJudges resolves judge checks to the check's model, then the eval's
+// judge model, then the evaluated agent's own model.
+func (s *Server) evalJudges(record *store.Eval) eval.JudgeResolver {
+	return func(modelRef string) (*eval.Judge, error) {
+		judge := record.Agent
+		judge.Options = nil
+		switch {
+		case modelRef != "":
+			judge.ModelRef = modelRef
+		case record.JudgeModelRef != "":
+			judge.ModelRef = record.JudgeModelRef
+		default:
+			judge.Options = record.Agent.Options
+		}
+		ref, info, client, err := s.buildModelClient(&judge, "", s.providers)
+		if err != nil {
+			return nil, err
+		}
+		return &eval.Judge{Client: client, Model: ref, StructuredOutput: info.Capabilities.StructuredOutput}, nil
+	}
+}
+
+// sessionUsage sums the tokens and cost of a session's model calls.
+func (s *Server) sessionUsage(ctx context.Context, sessionID string) (int, float64) {
+	calls, err := s.store.ListModelCalls(ctx, sessionID)
+	if err != nil {
+		s.logger.Error("list model calls", "session_id", sessionID, "error", err)
+		return 0, 0
+	}
+	var tokens int
+	var cost float64
+	for _, call := range calls {
+		tokens += call.TotalTokens
+		if call.Cost != nil {
+			cost += *call.Cost
+		}
+	}
+	return tokens, cost
+}
+
+// failInterruptedEvals marks evals left running by a previous process as
+// failed. Their unfinished case runs were aborted with the process.
+func (s *Server) failInterruptedEvals(ctx context.Context) error {
+	evals, err := s.store.ListEvals(ctx)
+	if err != nil {
+		return err
+	}
+	for _, value := range evals {
+		if value.Status != store.EvalStatusRunning {
+			continue
+		}
+		value.Status, value.ErrorMessage = store.EvalStatusFailed, "process interrupted during eval"
+		if err := s.store.UpdateEval(ctx, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func evalName(record *store.Eval) string {
+	if record.Name != "" {
+		return record.Name
+	}
+	return record.Agent.Name
+}
+
+func evalCase(value api.EvalCase) eval.Case {
+	c := eval.Case{ID: value.ID, Input: value.Input, Fixture: value.Fixture, Expected: value.Expected}
+	for _, check := range value.Checks {
+		c.Checks = append(c.Checks, eval.Check(check))
+	}
+	return c
+}
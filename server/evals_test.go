@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/models"
+	"github.com/chaserensberger/wingman/permission"
+	"github.com/chaserensberger/wingman/store"
+	"github.com/chaserensberger/wingman/store/memory"
+)
+
+func TestEvalRunsAndScoresCases(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		content := "4"
+		if strings.Contains(string(body), "Criteria:") {
+			content = `{"pass":true,"score":0.5,"reason":"close enough"}`
+		}
+		chunk, _ := json.Marshal(map[string]any{"choices": []any{map[string]any{"delta": map[string]any{"content": content}}}})
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: "+string(chunk)+"\n\ndata: {\"choices\":[{\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+	}))
+	defer provider.Close()
+	root := t.TempDir()
+	fixture := filepath.Join(root, "fixture")
+	if err := os.Mkdir(fixture, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(fixture, "input.txt"), []byte("2 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	data := memory.NewStore()
+	if _, err := data.EnsureDefaultClient(); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.CreateAgent(&store.Agent{ID: "agt_eval", Name: "Adder", ModelRef: "test/model", Options: map[string]any{
+		agentOptionModelRoute: models.ModelInfo{Provider: "test", ID: "model", API: models.APIOpenAICompatible, BaseURL: provider.URL},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	s := New(Config{Store: data})
+	t.Cleanup(func() { _ = s.Close(context.Background()) })
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		s.router.ServeHTTP(response, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return response
+	}
+
+	if response := serve(http.MethodPost, "/evals", `{"agent_id":"agt_eval","cases":[{"id":"a","input":"x","checks":[{"type":"regex"}]}]}`); response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "unknown check type") {
+		t.Fatalf("invalid check = %d: %s", response.Code, response.Body.String())
+	}
+	outside, err := json.Marshal(api.CreateEvalRequest{AgentID: "agt_eval", WorkingDirectory: fixture, Cases: []api.EvalCase{{ID: "a", Input: "x", Fixture: root, Expected: json.RawMessage(`"4"`)}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response := serve(http.MethodPost, "/evals", string(outside)); response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "outside the working directory") {
+		t.Fatalf("outside fixture = %d: %s", response.Code, response.Body.String())
+	}
+	request, err := json.Marshal(api.CreateEvalRequest{Name: "arithmetic", AgentID: "agt_eval", WorkingDirectory: root, Cases: []api.EvalCase{
+		{ID: "sum", Input: "What is 2+2?", Expected: json.RawMessage(`"4"`)},
+		{ID: "file", Input: "Add the numbers in input.txt", Fixture: "fixture", Checks: []api.EvalCheck{
+			{Type: "command", Command: "test -f input.txt"},
+			{Type: "judge", Criteria: "The answer is the sum."},
+		}},
+		{ID: "wrong", Input: "What is 2+3?", Expected: json.RawMessage(`"5"`)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := serve(http.MethodPost, "/evals", string(request))
+	if response.Code != http.StatusAccepted {
+		t.Fatalf("create = %d: %s", response.Code, response.Body.String())
+	}
+	var created api.Eval
+	if err := json.Unmarshal(response.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Status != store.EvalStatusRunning || created.Summary.Total != 3 || created.ModelRef != "test/model" {
+		t.Fatalf("created = %#v", created)
+	}
+
+	var got api.Eval
+	deadline := time.Now().Add(10 * time.Second)
+	for got.Status != store.EvalStatusCompleted {
+		if time.Now().After(deadline) {
+			t.Fatalf("eval did not finish: %#v", got)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if err := json.Unmarshal(serve(http.MethodGet, "/evals/"+created.ID, "").Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got.Summary.Passed != 2 || got.Summary.Failed != 1 || got.Summary.Errored != 0 || math.Abs(got.Summary.Score-1.75/3) > 1e-9 || got.CompletedAt.IsZero() {
+		t.Fatalf("summary = %#v", got.Summary)
+	}
+	file := got.Cases[1]
+	if file.Case.ID != "file" || file.Status != store.EvalCaseStatusPassed || file.Score != 0.75 || len(file.Checks) != 2 || file.Checks[1].Detail != "close enough" {
+		t.Fatalf("file case = %#v", file)
+	}
+	if wrong := got.Cases[2]; wrong.Status != store.EvalCaseStatusFailed || !strings.Contains(wrong.Checks[0].Detail, `"4"`) {
+		t.Fatalf("wrong case = %#v", wrong)
+	}
+	messages, err := data.ListMessages(context.Background(), file.SessionID)
+	if err != nil || len(messages) != 2 || messages[1].RunID != file.RunID {
+		t.Fatalf("transcript = %#v, error = %v", messages, err)
+	}
+	if sess, err := data.GetSession(file.SessionID); err != nil || sess.WorkDir != "" {
+		t.Fatalf("case session = %#v, error = %v", sess, err)
+	}
+
+	var list []api.Eval
+	if err := json.Unmarshal(serve(http.MethodGet, "/evals?agent_id=agt_eval", "").Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != created.ID || list[0].Cases != nil {
+		t.Fatalf("list = %#v", list)
+	}
+	if err := json.Unmarshal(serve(http.MethodGet, "/evals?agent_id=agt_other", "").Body.Bytes(), &list); err != nil || len(list) != 0 {
+		t.Fatalf("filtered list = %#v, error = %v", list, err)
+	}
+}
+
+func TestEvalRejectsCommandChecksDeniedForTheAgent(t *testing.T) {
+	data := memory.NewStore()
+	if _, err := data.EnsureDefaultClient(); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.CreateAgent(&store.Agent{ID: "agt_eval", Name: "Adder", ModelRef: "test/model",
+		Permissions: []permission.Rule{{Action: "bash", Resource: "rm *", Effect: permission.EffectDeny}, {Action: "bash", Resource: "git push*", Effect: permission.EffectAsk}},
+		Options: map[string]any{
+			agentOptionModelRoute: models.ModelInfo{Provider: "test", ID: "model", API: models.APIOpenAICompatible, BaseURL: "http://127.0.0.1:1"},
+		}}); err != nil {
+		t.Fatal(err)
+	}
+	s := New(Config{Store: data})
+	t.Cleanup(func() { _ = s.Close(context.Background()) })
+
+	response := httptest.NewRecorder()
+	s.router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/evals", strings.NewReader(`{"agent_id":"agt_eval","cases":[{"id":"ok","input":"x","checks":[{"type":"command","command":"true"}]},{"id":"wipe","input":"x","checks":[{"type":"command","command":"rm -rf /tmp/x"}]}]}`)))
+	if response.Code != http.StatusForbidden || !strings.Contains(response.Body.String(), "case wipe") || !strings.Contains(response.Body.String(), "agent permission rules") {
+		t.Fatalf("create = %d: %s", response.Code, response.Body.String())
+	}
+
+	response = httptest.NewRecorder()
+	s.router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/evals", strings.NewReader(`{"agent_id":"agt_eval","cases":[{"id":"push","input":"x","checks":[{"type":"command","command":"git push"}]}]}`)))
+	if response.Code != http.StatusForbidden || !strings.Contains(response.Body.String(), "case push") || !strings.Contains(response.Body.String(), "(ask)") {
+		t.Fatalf("ask = %d: %s", response.Code, response.Body.String())
+	}
+	if evals, err := data.ListEvals(context.Background()); err != nil || len(evals) != 0 {
+		t.Fatalf("evals = %#v, error = %v", evals, err)
+	}
+}
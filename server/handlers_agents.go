@@ -3,16 +3,27 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/chaserensberger/wingman/agent/session"
+	"github.com/chaserensberger/wingman/agentfile"
 	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/frontmatter"
 	"github.com/chaserensberger/wingman/models"
 	"github.com/chaserensberger/wingman/store"
 )
 
-const agentOptionModelRoute = "model_route"
+const (
+	agentOptionModelRoute = "model_route"
+	// agentOptionCredentials maps provider IDs to the named credential the
+	// agent uses.
+	agentOptionCredentials = "credentials"
+)
 
 func (s *Server) handleCreateAgent(w http.ResponseWriter, r *http.Request) {
 	if s.Ephemeral() {
@@ -33,6 +44,15 @@ func (s *Server) handleCreateAgent(w http.ResponseWriter, r *http.Request) {
 		s.writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := s.validateAgentCredentials(req.Options); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limits, err := storeRunLimits(req.Limits)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	a := &store.Agent{
 		Name:         req.Name,
@@ -42,6 +62,7 @@ func (s *Server) handleCreateAgent(w http.ResponseWriter, r *http.Request) {
 		ModelRef:     req.ModelRef,
 		Options:      req.Options,
 		OutputSchema: req.OutputSchema,
+		Limits:       limits,
 	}
 	setAgentModelRoute(a, req.ModelRoute)
 
@@ -63,7 +84,39 @@ func (s *Server) handleListAgents(w http.ResponseWriter, r *http.Request) {
 		s.writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, apiAgents(agents))
+	workDir, ok := s.agentWorkDir(w, r)
+	if !ok {
+		return
+	}
+	files, _, err := s.fileAgents(r.Context(), workDir)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, apiAgents(append(agents, files...)))
+}
+
+// handleListAgentFiles reports the agent files discovered for a working
+// directory, including files that failed to load.
+func (s *Server) handleListAgentFiles(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	workDir, ok := s.agentWorkDir(w, r)
+	if !ok {
+		return
+	}
+	if workDir == "" {
+		s.writeError(w, http.StatusBadRequest, "working_directory is required")
+		return
+	}
+	agents, errs, err := s.fileAgents(r.Context(), workDir)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, api.AgentFileList{Agents: apiAgents(agents), Errors: apiFileLoadErrors(errs)})
 }
 
 func (s *Server) handleGetAgent(w http.ResponseWriter, r *http.Request) {
@@ -72,6 +125,19 @@ func (s *Server) handleGetAgent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := chi.URLParam(r, "id")
+	if agentfile.IsFileID(id) {
+		workDir, ok := s.agentWorkDir(w, r)
+		if !ok {
+			return
+		}
+		a, status, err := s.lookupAgent(r.Context(), workDir, id)
+		if err != nil {
+			s.writeError(w, status, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, apiAgent(a))
+		return
+	}
 
 	a, err := s.store.GetAgent(id)
 	if err != nil {
@@ -88,6 +154,10 @@ func (s *Server) handleUpdateAgent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := chi.URLParam(r, "id")
+	if agentfile.IsFileID(id) {
+		s.writeError(w, http.StatusConflict, fileAgentReadOnly(id))
+		return
+	}
 
 	a, err := s.store.GetAgent(id)
 	if err != nil {
@@ -121,12 +191,23 @@ func (s *Server) handleUpdateAgent(w http.ResponseWriter, r *http.Request) {
 		a.ModelRef = *req.ModelRef
 	}
 	if req.Options != nil {
+		if err := s.validateAgentCredentials(req.Options); err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		a.Options = req.Options
 	}
 	setAgentModelRoute(a, req.ModelRoute)
 	if req.OutputSchema != nil {
 		a.OutputSchema = req.OutputSchema
 	}
+	if req.Limits != nil {
+		a.Limits, err = storeRunLimits(req.Limits)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
 	if err := s.store.UpdateAgent(a); err != nil {
 		s.writeError(w, http.StatusInternalServerError, err.Error())
@@ -136,6 +217,16 @@ func (s *Server) handleUpdateAgent(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, apiAgent(a))
 }
 
+// validateAgentCredentials checks the credential selection in an agent's
+// options as workspace selections are checked.
+func (s *Server) validateAgentCredentials(options map[string]any) error {
+	selected, err := credentialsFromOptions(options)
+	if err != nil {
+		return err
+	}
+	return s.validateCredentialSelection(selected)
+}
+
 func (s *Server) validateAgentTools(ctx context.Context, names []string) error {
 	scope, release, err := s.executionScope(ctx, "")
 	if err != nil {
@@ -146,6 +237,149 @@ func (s *Server) validateAgentTools(ctx context.Context, names []string) error {
 	return err
 }
 
+func (s *Server) handleListAgentRevisions(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	revisions, err := s.store.ListAgentRevisions(chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, apiAgentRevisions(revisions))
+}
+
+func (s *Server) handleGetAgentRevision(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	number, ok := s.agentRevisionParam(w, r)
+	if !ok {
+		return
+	}
+	revision, err := s.store.GetAgentRevision(chi.URLParam(r, "id"), number)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, apiAgentRevisions([]store.AgentRevision{*revision})[0])
+}
+
+// handleRollbackAgent restores an earlier revision's definition as the
+// agent's next revision. History is never rewritten.
+func (s *Server) handleRollbackAgent(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if agentfile.IsFileID(id) {
+		s.writeError(w, http.StatusConflict, fileAgentReadOnly(id))
+		return
+	}
+	number, ok := s.agentRevisionParam(w, r)
+	if !ok {
+		return
+	}
+	current, err := s.store.GetAgent(id)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	target, err := s.store.GetAgentRevision(id, number)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err := s.validateAgentTools(r.Context(), target.Agent.Tools); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	restored := target.Agent
+	restored.ID, restored.CreatedAt = current.ID, current.CreatedAt
+	if err := s.store.UpdateAgent(&restored); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, apiAgent(&restored))
+}
+
+func (s *Server) agentRevisionParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	revision, err := strconv.ParseInt(chi.URLParam(r, "revision"), 10, 64)
+	if err != nil || revision < 1 {
+		s.writeError(w, http.StatusBadRequest, "revision must be a positive integer")
+		return 0, false
+	}
+	return revision, true
+}
+
+// agentWorkDir resolves the optional working_directory query parameter that
+// selects which project's agent files to include.
+func (s *Server) agentWorkDir(w http.ResponseWriter, r *http.Request) (string, bool) {
+	dir := r.URL.Query().Get("working_directory")
+	if dir == "" {
+		return "", true
+	}
+	workDir, err := session.ResolveWorkDir(dir)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return "", false
+	}
+	return workDir, true
+}
+
+// fileAgents discovers the agent files in workDir's execution scope.
+func (s *Server) fileAgents(ctx context.Context, workDir string) ([]*store.Agent, []frontmatter.LoadError, error) {
+	if workDir == "" {
+		return nil, nil, nil
+	}
+	scope, release, err := s.executionScope(ctx, workDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+	if scope == nil {
+		agents, errs := agentfile.Discover(workDir)
+		return agents, errs, nil
+	}
+	agents, errs := scope.Agents()
+	return agents, errs, nil
+}
+
+// lookupAgent resolves a stored agent, or for a file: ID the agent file
+// discovered in workDir. An agent file that fails to load is a bad request
+// that reports the load error.
+func (s *Server) lookupAgent(ctx context.Context, workDir, id string) (*store.Agent, int, error) {
+	if !agentfile.IsFileID(id) {
+		a, err := s.store.GetAgent(id)
+		if err != nil {
+			return nil, http.StatusNotFound, errors.New("agent not found: " + id)
+		}
+		return a, http.StatusOK, nil
+	}
+	agents, errs, err := s.fileAgents(ctx, workDir)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	for _, a := range agents {
+		if a.ID == id {
+			return a, http.StatusOK, nil
+		}
+	}
+	for _, loadErr := range errs {
+		if agentfile.ID(loadErr.Path) == id {
+			return nil, http.StatusBadRequest, fmt.Errorf("agent file %s: %s", loadErr.Path, loadErr.Error)
+		}
+	}
+	return nil, http.StatusNotFound, errors.New("agent not found: " + id)
+}
+
+func fileAgentReadOnly(id string) string {
+	return fmt.Sprintf("agent %s is defined by a file in .wingman/agents; edit the file instead", id)
+}
+
 func setAgentModelRoute(a *store.Agent, route *models.ModelInfo) {
 	if route == nil {
 		return
@@ -162,6 +396,10 @@ func (s *Server) handleDeleteAgent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := chi.URLParam(r, "id")
+	if agentfile.IsFileID(id) {
+		s.writeError(w, http.StatusConflict, fileAgentReadOnly(id))
+		return
+	}
 
 	if err := s.store.DeleteAgent(id); err != nil {
 		s.writeError(w, http.StatusNotFound, err.Error())
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/execution"
+	"github.com/chaserensberger/wingman/models"
+	provider "github.com/chaserensberger/wingman/models/providers"
+	"github.com/chaserensberger/wingman/store"
+	"github.com/chaserensberger/wingman/store/memory"
+)
+
+func TestAgentFilesMergeWithStoredAgents(t *testing.T) {
+	workDir := t.TempDir()
+	dir := filepath.Join(workDir, ".wingman", "agents")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"reviewer.md": "---\nname: Reviewer\ntools: [read]\n---\nReview the change.",
+		"missing.md":  "---\ntools: [nonexistent]\n---\nUse a missing tool.",
+		"typo.md":     "---\ntool: [read]\n---\nMisspelled key.",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data := memory.NewStore()
+	if err := data.CreateAgent(&store.Agent{ID: "agt_stored", Name: "stored"}); err != nil {
+		t.Fatal(err)
+	}
+	registry, err := provider.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scopes, err := execution.NewManager(execution.Config{Providers: registry, DisablePlugins: true, NativeTools: execution.BuiltinTools()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = scopes.Close() })
+	s := New(Config{Store: data, Scopes: scopes})
+	query := "?working_directory=" + url.QueryEscape(workDir)
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		s.router.ServeHTTP(response, httptest.NewRequest(method, target, strings.NewReader(`{"name":"renamed"}`)))
+		return response
+	}
+
+	response := serve(http.MethodGet, "/agents"+query)
+	var agents []api.Agent
+	if err := json.Unmarshal(response.Body.Bytes(), &agents); err != nil {
+		t.Fatal(err)
+	}
+	if len(agents) != 2 || agents[0].Source != "store" || agents[1].ID != "file:reviewer" || agents[1].Source != "file" || agents[1].Path != filepath.Join(dir, "reviewer.md") {
+		t.Fatalf("agents = %#v", agents)
+	}
+	if response := serve(http.MethodGet, "/agents"); !strings.Contains(response.Body.String(), "agt_stored") || strings.Contains(response.Body.String(), "file:") {
+		t.Fatalf("agents without working directory = %s", response.Body.String())
+	}
+
+	response = serve(http.MethodGet, "/agents/files"+query)
+	var files api.AgentFileList
+	if err := json.Unmarshal(response.Body.Bytes(), &files); err != nil {
+		t.Fatal(err)
+	}
+	if len(files.Agents) != 1 || len(files.Errors) != 2 || !strings.Contains(files.Errors[0].Error, `tool "nonexistent" is not available`) || !strings.Contains(files.Errors[1].Error, `unknown field "tool"`) {
+		t.Fatalf("files = %#v", files)
+	}
+
+	if response := serve(http.MethodGet, "/agents/file:reviewer"+query); response.Code != http.StatusOK || !strings.Contains(response.Body.String(), "Review the change.") {
+		t.Fatalf("get = %d: %s", response.Code, response.Body.String())
+	}
+	if response := serve(http.MethodGet, "/agents/file:typo"+query); response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "typo.md") {
+		t.Fatalf("get invalid = %d: %s", response.Code, response.Body.String())
+	}
+	if response := serve(http.MethodPut, "/agents/file:reviewer"); response.Code != http.StatusConflict {
+		t.Fatalf("update = %d: %s", response.Code, response.Body.String())
+	}
+}
+
+func TestAgentRevisionsRollbackAndPinRuns(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer provider.Close()
+	data := memory.NewStore()
+	client, err := data.EnsureDefaultClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := data.CreateSession(&store.Session{ID: "ses_revisions", ClientID: client.ID}); err != nil {
+		t.Fatal(err)
+	}
+	agent := &store.Agent{ID: "agt_revisions", Name: "Writer", Instructions: "v1", ModelRef: "test/model", Options: map[string]any{
+		agentOptionModelRoute: models.ModelInfo{Provider: "test", ID: "model", API: models.APIOpenAICompatible, BaseURL: provider.URL},
+	}}
+	if err := data.CreateAgent(agent); err != nil {
+		t.Fatal(err)
+	}
+	s := New(Config{Store: data})
+	t.Cleanup(func() { _ = s.Close(context.Background()) })
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		s.router.ServeHTTP(response, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return response
+	}
+
+	if response := serve(http.MethodPut, "/agents/agt_revisions", `{"instructions":"v2"}`); response.Code != http.StatusOK || !strings.Contains(response.Body.String(), `"revision":2`) {
+		t.Fatalf("update = %d: %s", response.Code, response.Body.String())
+	}
+	response := serve(http.MethodPost, "/agents/agt_revisions/revisions/1/rollback", "")
+	var restored api.Agent
+	if err := json.Unmarshal(response.Body.Bytes(), &restored); err != nil {
+		t.Fatal(err)
+	}
+	if response.Code != http.StatusOK || restored.Revision != 3 || restored.Instructions != "v1" {
+		t.Fatalf("rollback = %d: %s", response.Code, response.Body.String())
+	}
+	var revisions []api.AgentRevision
+	if err := json.Unmarshal(serve(http.MethodGet, "/agents/agt_revisions/revisions", "").Body.Bytes(), &revisions); err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 3 || revisions[1].Agent.Instructions != "v2" || revisions[2].Agent.Instructions != "v1" {
+		t.Fatalf("revisions = %#v", revisions)
+	}
+	if response := serve(http.MethodGet, "/agents/agt_revisions/revisions/9", ""); response.Code != http.StatusNotFound {
+		t.Fatalf("missing revision = %d", response.Code)
+	}
+	if response := serve(http.MethodPost, "/agents/agt_revisions/revisions/0/rollback", ""); response.Code != http.StatusBadRequest {
+		t.Fatalf("invalid revision = %d", response.Code)
+	}
+
+	for _, tc := range []struct {
+		body     string
+		revision int64
+		want     string
+	}{
+		{body: `{"agent_id":"agt_revisions","message":"hi"}`, revision: 3, want: "v1"},
+		{body: `{"agent_id":"agt_revisions","agent_revision":2,"message":"hi"}`, revision: 2, want: "v2"},
+	} {
+		response := serve(http.MethodPost, "/sessions/ses_revisions/message", tc.body)
+		var admitted api.MessageSessionResponse
+		if err := json.Unmarshal(response.Body.Bytes(), &admitted); err != nil || response.Code != http.StatusAccepted {
+			t.Fatalf("message = %d: %s", response.Code, response.Body.String())
+		}
+		run, err := data.GetSessionRun(context.Background(), "ses_revisions", admitted.RunID)
+		if err != nil || run.Agent.Revision != tc.revision || run.Agent.Instructions != tc.want {
+			t.Fatalf("run agent = %#v, error = %v", run.Agent, err)
+		}
+	}
+	if response := serve(http.MethodPost, "/sessions/ses_revisions/message", `{"agent_id":"agt_revisions","agent_revision":7,"message":"hi"}`); response.Code != http.StatusNotFound {
+		t.Fatalf("unknown pinned revision = %d: %s", response.Code, response.Body.String())
+	}
+}
@@ -0,0 +1,274 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/chaserensberger/wingman/agentfile"
+	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/command"
+	"github.com/chaserensberger/wingman/store"
+)
+
+func (s *Server) handleCreateCommand(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	var req api.CreateCommandRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	c := &store.Command{
+		Name:        req.Name,
+		Description: req.Description,
+		Template:    req.Template,
+		Arguments:   storeCommandArguments(req.Arguments),
+		AgentID:     req.AgentID,
+		ModelRef:    req.ModelRef,
+	}
+	if err := s.validateCommand(c); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.store.CreateCommand(c); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiCommand(c))
+}
+
+func (s *Server) handleListCommands(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	commands, err := s.store.ListCommands()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	workDir, ok := s.agentWorkDir(w, r)
+	if !ok {
+		return
+	}
+	files, _ := command.Discover(workDir)
+	writeJSON(w, http.StatusOK, apiCommands(append(commands, files...)))
+}
+
+// handleListCommandFiles reports the command files discovered for a working
+// directory, including files that failed to load.
+func (s *Server) handleListCommandFiles(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	workDir, ok := s.agentWorkDir(w, r)
+	if !ok {
+		return
+	}
+	if workDir == "" {
+		s.writeError(w, http.StatusBadRequest, "working_directory is required")
+		return
+	}
+	commands, errs := command.Discover(workDir)
+	writeJSON(w, http.StatusOK, api.CommandFileList{Commands: apiCommands(commands), Errors: apiFileLoadErrors(errs)})
+}
+
+func (s *Server) handleGetCommand(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	workDir, ok := s.agentWorkDir(w, r)
+	if !ok {
+		return
+	}
+	c, status, err := s.lookupCommand(workDir, chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, apiCommand(c))
+}
+
+func (s *Server) handleUpdateCommand(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if command.IsFileID(id) {
+		s.writeError(w, http.StatusConflict, fileCommandReadOnly(id))
+		return
+	}
+	c, err := s.store.GetCommand(id)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	var req api.UpdateCommandRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Name != nil {
+		c.Name = *req.Name
+	}
+	if req.Description != nil {
+		c.Description = *req.Description
+	}
+	if req.Template != nil {
+		c.Template = *req.Template
+	}
+	if req.Arguments != nil {
+		c.Arguments = storeCommandArguments(req.Arguments)
+	}
+	if req.AgentID != nil {
+		c.AgentID = *req.AgentID
+	}
+	if req.ModelRef != nil {
+		c.ModelRef = *req.ModelRef
+	}
+	if err := s.validateCommand(c); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.store.UpdateCommand(c); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, apiCommand(c))
+}
+
+func (s *Server) handleDeleteCommand(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if command.IsFileID(id) {
+		s.writeError(w, http.StatusConflict, fileCommandReadOnly(id))
+		return
+	}
+	if err := s.store.DeleteCommand(id); err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, api.StatusResponse{Status: "deleted"})
+}
+
+// handleCommandSession renders a command with the request's arguments and
+// admits the result exactly as POST /sessions/{id}/message would.
+func (s *Server) handleCommandSession(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.writeError(w, http.StatusNotImplemented, "persistence is disabled; use POST /run for ephemeral runs")
+		return
+	}
+	sess, err := s.store.GetSession(chi.URLParam(r, "id"))
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	clientID, err := s.resolveClientID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if sess.ClientID != clientID {
+		s.writeError(w, http.StatusForbidden, "session belongs to another client")
+		return
+	}
+
+	var req api.CommandSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.CommandID == "" {
+		s.writeError(w, http.StatusBadRequest, "command_id is required")
+		return
+	}
+	c, status, err := s.lookupCommand(sess.WorkDir, req.CommandID)
+	if err != nil {
+		s.writeError(w, status, err.Error())
+		return
+	}
+	rendered, err := command.Render(c, req.Arguments)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("command %s: %s", c.ID, err))
+		return
+	}
+	message := api.MessageSessionRequest{
+		RequestID:     req.RequestID,
+		AgentID:       req.AgentID,
+		AgentRevision: req.AgentRevision,
+		ModelRef:      req.ModelRef,
+		ModelRoute:    req.ModelRoute,
+		Message:       rendered,
+		OutputSchema:  req.OutputSchema,
+		Resources:     req.Resources,
+		Limits:        req.Limits,
+		Priority:      req.Priority,
+	}
+	if message.AgentID == "" {
+		message.AgentID = c.AgentID
+	}
+	if message.ModelRef == "" {
+		message.ModelRef = c.ModelRef
+	}
+	admission, status, err := s.admitSessionMessage(r.Context(), sess, message)
+	if err != nil {
+		s.writeError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, api.MessageSessionResponse{RunID: admission.Run.ID, Status: admission.Run.Status, SessionVersion: admission.SessionVersion})
+}
+
+// validateCommand checks a stored command's template and, when it binds a
+// stored agent, that the agent exists. File agents are resolved per session.
+func (s *Server) validateCommand(c *store.Command) error {
+	if err := command.Validate(c); err != nil {
+		return err
+	}
+	if c.AgentID != "" && !agentfile.IsFileID(c.AgentID) {
+		if _, err := s.store.GetAgent(c.AgentID); err != nil {
+			return errors.New("agent not found: " + c.AgentID)
+		}
+	}
+	return nil
+}
+
+// lookupCommand resolves a stored command, or for a file: ID the command
+// file in workDir. A command file that fails to load is a bad request that
+// reports the load error.
+func (s *Server) lookupCommand(workDir, id string) (*store.Command, int, error) {
+	if !command.IsFileID(id) {
+		c, err := s.store.GetCommand(id)
+		if err != nil {
+			return nil, http.StatusNotFound, errors.New("command not found: " + id)
+		}
+		return c, http.StatusOK, nil
+	}
+	commands, errs := command.Discover(workDir)
+	for _, c := range commands {
+		if c.ID == id {
+			return c, http.StatusOK, nil
+		}
+	}
+	for _, loadErr := range errs {
+		if command.ID(loadErr.Path) == id {
+			return nil, http.StatusBadRequest, fmt.Errorf("command file %s: %s", loadErr.Path, loadErr.Error)
+		}
+	}
+	return nil, http.StatusNotFound, errors.New("command not found: " + id)
+}
+
+func fileCommandReadOnly(id string) string {
+	return fmt.Sprintf("command %s is defined by a file in .wingman/commands; edit the file instead", id)
+}
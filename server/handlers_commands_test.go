@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/models"
+	"github.com/chaserensberger/wingman/store"
+	"github.com/chaserensberger/wingman/store/memory"
+)
+
+func TestCommandSessionRendersAndAdmitsRun(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer provider.Close()
+	workDir := t.TempDir()
+	dir := filepath.Join(workDir, ".wingman", "commands")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "fix.md"), []byte("---\narguments:\n  - name: issue\n    required: true\nagent_id: agt_commands\n---\nFix issue {{issue}}."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data := memory.NewStore()
+	client, err := data.EnsureDefaultClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := data.CreateSession(&store.Session{ID: "ses_commands", ClientID: client.ID, WorkDir: workDir}); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.CreateAgent(&store.Agent{ID: "agt_commands", Name: "Fixer", ModelRef: "test/model", Options: map[string]any{
+		agentOptionModelRoute: models.ModelInfo{Provider: "test", ID: "model", API: models.APIOpenAICompatible, BaseURL: provider.URL},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	s := New(Config{Store: data})
+	t.Cleanup(func() { _ = s.Close(context.Background()) })
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		s.router.ServeHTTP(response, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return response
+	}
+
+	response := serve(http.MethodPost, "/commands", `{"name":"review","template":"Review {{path}}.","arguments":[{"name":"path","default":"."}],"agent_id":"agt_commands"}`)
+	var stored api.Command
+	if err := json.Unmarshal(response.Body.Bytes(), &stored); err != nil || response.Code != http.StatusCreated || stored.Source != "store" {
+		t.Fatalf("create = %d: %s", response.Code, response.Body.String())
+	}
+	if response := serve(http.MethodPost, "/commands", `{"name":"bad","template":"{{missing}}"}`); response.Code != http.StatusBadRequest {
+		t.Fatalf("invalid create = %d: %s", response.Code, response.Body.String())
+	}
+	if response := serve(http.MethodPut, "/commands/file:fix", `{"name":"x"}`); response.Code != http.StatusConflict {
+		t.Fatalf("update file command = %d", response.Code)
+	}
+
+	for _, tc := range []struct {
+		body, want string
+		priority   int
+		schema     bool
+	}{
+		{body: `{"command_id":"` + stored.ID + `"}`, want: "Review .."},
+		{body: `{"command_id":"file:fix","arguments":{"issue":"42"},"priority":5,"output_schema":{"schema":{"type":"object"}}}`, want: "Fix issue 42.", priority: 5, schema: true},
+	} {
+		response := serve(http.MethodPost, "/sessions/ses_commands/command", tc.body)
+		var admitted api.MessageSessionResponse
+		if err := json.Unmarshal(response.Body.Bytes(), &admitted); err != nil || response.Code != http.StatusAccepted {
+			t.Fatalf("command = %d: %s", response.Code, response.Body.String())
+		}
+		run, err := data.GetSessionRun(context.Background(), "ses_commands", admitted.RunID)
+		if err != nil || run.Message != tc.want || run.Agent.ID != "agt_commands" || run.Priority != tc.priority || (len(run.OutputSchemaJSON) > 0) != tc.schema {
+			t.Fatalf("run = %#v, error = %v", run, err)
+		}
+	}
+	if response := serve(http.MethodPost, "/sessions/ses_commands/command", `{"command_id":"file:fix"}`); response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), `argument \"issue\" is required`) {
+		t.Fatalf("missing argument = %d: %s", response.Code, response.Body.String())
+	}
+	if response := serve(http.MethodPost, "/sessions/ses_commands/command", `{"command_id":"file:nope"}`); response.Code != http.StatusNotFound {
+		t.Fatalf("unknown command = %d", response.Code)
+	}
+}
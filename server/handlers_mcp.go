@@ -1,9 +1,18 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+
+	"github.com/chaserensberger/wingman/api"
+	wingmcp "github.com/chaserensberger/wingman/mcp"
 )
 
 type mcpResponse struct {
@@ -62,10 +71,210 @@ func (s *Server) handleDisconnectMCP(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, mcpResponse{Servers: scope.MCP().Status()})
 }
 
-func (s *Server) handleAuthMCP(w http.ResponseWriter, r *http.Request) {
-	s.writeError(w, http.StatusNotImplemented, "MCP OAuth is not implemented yet")
+type mcpPromptRenderRequest struct {
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// mcpManager resolves the directoryless scope's MCP manager. It writes the
+// error response and returns ok=false when MCP is unavailable.
+func (s *Server) mcpManager(w http.ResponseWriter, r *http.Request) (*wingmcp.Manager, func(), bool) {
+	scope, release, err := s.executionScope(r.Context(), "")
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, nil, false
+	}
+	if scope == nil || scope.MCP() == nil {
+		release()
+		s.writeError(w, http.StatusNotFound, "MCP is not configured")
+		return nil, nil, false
+	}
+	return scope.MCP(), release, true
+}
+
+func mcpErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, wingmcp.ErrServerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, wingmcp.ErrServerUnavailable):
+		return http.StatusConflict
+	case errors.Is(err, wingmcp.ErrServerNotRemote):
+		return http.StatusBadRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func (s *Server) handleListMCPResources(w http.ResponseWriter, r *http.Request) {
+	manager, release, ok := s.mcpManager(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+	resources, err := manager.Resources(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		s.writeError(w, mcpErrorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resources)
+}
+
+func (s *Server) handleReadMCPResource(w http.ResponseWriter, r *http.Request) {
+	uri := r.URL.Query().Get("uri")
+	if uri == "" {
+		s.writeError(w, http.StatusBadRequest, "uri is required")
+		return
+	}
+	manager, release, ok := s.mcpManager(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+	read, err := manager.ReadResource(r.Context(), chi.URLParam(r, "name"), uri)
+	if err != nil {
+		s.writeError(w, mcpErrorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, read)
 }
 
-func (s *Server) handleLogoutMCP(w http.ResponseWriter, r *http.Request) {
-	s.writeError(w, http.StatusNotImplemented, "MCP OAuth is not implemented yet")
+func (s *Server) handleSubscribeMCPResource(w http.ResponseWriter, r *http.Request) {
+	uri := r.URL.Query().Get("uri")
+	if uri == "" {
+		s.writeError(w, http.StatusBadRequest, "uri is required")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	manager, release, ok := s.mcpManager(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	done := s.trackInflight()
+	defer done()
+	go func() {
+		select {
+		case <-s.ShutdownCtx().Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	updates, unsubscribe, err := manager.SubscribeResource(ctx, chi.URLParam(r, "name"), uri)
+	if err != nil {
+		s.writeError(w, mcpErrorStatus(err), err.Error())
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache, no-transform")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case update, open := <-updates:
+			if !open {
+				return
+			}
+			b, err := json.Marshal(update)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: mcp.resource.updated\ndata: %s\n\n", b); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Server) handleListMCPPrompts(w http.ResponseWriter, r *http.Request) {
+	manager, release, ok := s.mcpManager(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+	prompts, err := manager.Prompts(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		s.writeError(w, mcpErrorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, prompts)
+}
+
+func (s *Server) handleRenderMCPPrompt(w http.ResponseWriter, r *http.Request) {
+	var req mcpPromptRenderRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	manager, release, ok := s.mcpManager(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+	rendered, err := manager.RenderPrompt(r.Context(), chi.URLParam(r, "name"), chi.URLParam(r, "prompt"), req.Arguments)
+	if err != nil {
+		s.writeError(w, mcpErrorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, rendered)
+}
+
+// errMCPAttachment marks a malformed or unsatisfiable resource attachment.
+var errMCPAttachment = errors.New("invalid MCP resource attachment")
+
+// attachMCPResources reads each referenced resource in the session's execution
+// scope and prepends the contents to message. The snapshot is stored with the
+// admitted run, so later resource changes do not alter its input.
+func (s *Server) attachMCPResources(ctx context.Context, workDir, message string, refs []api.MCPResourceRef) (string, error) {
+	scope, release, err := s.executionScope(ctx, workDir)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	if scope == nil || scope.MCP() == nil {
+		return "", fmt.Errorf("%w: MCP is not configured", errMCPAttachment)
+	}
+	var b strings.Builder
+	for i, ref := range refs {
+		if strings.TrimSpace(ref.Server) == "" || strings.TrimSpace(ref.URI) == "" {
+			return "", fmt.Errorf("%w: resources[%d] requires server and uri", errMCPAttachment, i)
+		}
+		read, err := scope.MCP().ReadResource(ctx, ref.Server, ref.URI)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "<mcp_resource server=%q uri=%q>\n%s\n</mcp_resource>\n\n", ref.Server, ref.URI, wingmcp.ResourceText(read))
+	}
+	b.WriteString(message)
+	return b.String(), nil
+}
+
+func mcpAttachmentStatus(err error) int {
+	if errors.Is(err, errMCPAttachment) {
+		return http.StatusBadRequest
+	}
+	return mcpErrorStatus(err)
 }
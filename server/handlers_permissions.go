@@ -0,0 +1,388 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/permission"
+	"github.com/chaserensberger/wingman/store"
+)
+
+// Permission sources, in evaluation order. Later sources win.
+const (
+	permissionSourceAgent        = "agent"
+	permissionSourceConfig       = "config"
+	permissionSourceConfigAgent  = "config_agent"
+	permissionSourceAgentRules   = "agent_ruleset"
+	permissionSourceWorkspace    = "workspace_ruleset"
+	permissionSourceSessionGrant = "session_grant"
+)
+
+// permissionLayer is one named ruleset in the effective permission stack.
+type permissionLayer struct {
+	source string
+	rules  permission.Ruleset
+}
+
+// permissionLayers returns the rulesets that govern agent runs in workspaceID:
+// the agent definition, daemon config, per-agent config, then the persisted
+// agent and workspace rulesets managed through the API.
+func (s *Server) permissionLayers(ctx context.Context, agent *store.Agent, workspaceID string) ([]permissionLayer, error) {
+	layers := []permissionLayer{}
+	if agent != nil {
+		layers = append(layers, permissionLayer{permissionSourceAgent, agent.Permissions})
+	}
+	layers = append(layers, permissionLayer{permissionSourceConfig, s.permissions})
+	if agent != nil {
+		layers = append(layers,
+			permissionLayer{permissionSourceConfigAgent, s.agentPermissions[agent.Name]},
+			permissionLayer{permissionSourceConfigAgent, s.agentPermissions[agent.ID]},
+		)
+	}
+	if s.store == nil {
+		return layers, nil
+	}
+	if agent != nil && agent.ID != "" {
+		ruleset, err := s.store.GetPermissionRuleset(ctx, store.PermissionScopeAgent, agent.ID)
+		if err != nil {
+			return nil, fmt.Errorf("load agent permission rules: %w", err)
+		}
+		layers = append(layers, permissionLayer{permissionSourceAgentRules, ruleset.Rules})
+	}
+	if workspaceID != "" {
+		ruleset, err := s.store.GetPermissionRuleset(ctx, store.PermissionScopeWorkspace, workspaceID)
+		if err != nil {
+			return nil, fmt.Errorf("load workspace permission rules: %w", err)
+		}
+		layers = append(layers, permissionLayer{permissionSourceWorkspace, ruleset.Rules})
+	}
+	return layers, nil
+}
+
+// evaluatePermissionLayers evaluates layers as one merged ruleset and reports
+// which layer produced the decision. An empty stack allows everything, as the
+// run loop does when no permissions are configured.
+func evaluatePermissionLayers(action, resource string, layers []permissionLayer) api.PermissionEvaluation {
+	for i := len(layers) - 1; i >= 0; i-- {
+		decision := permission.Evaluate(action, resource, layers[i].rules, "")
+		if decision.Rule != nil {
+			return api.PermissionEvaluation{Effect: decision.Effect, Rule: decision.Rule, Source: layers[i].source}
+		}
+	}
+	return api.PermissionEvaluation{Effect: permission.EffectAllow}
+}
+
+func (s *Server) handleGetWorkspacePermissions(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	workspace, ok := s.authorizeWorkspaceForRequest(w, r, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+	s.writePermissionRuleset(w, r, store.PermissionScopeWorkspace, workspace.ID)
+}
+
+func (s *Server) handleUpdateWorkspacePermissions(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	workspace, ok := s.authorizeWorkspaceForRequest(w, r, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+	s.replacePermissionRuleset(w, r, store.PermissionScopeWorkspace, workspace.ID)
+}
+
+func (s *Server) handleDeleteWorkspacePermissions(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	workspace, ok := s.authorizeWorkspaceForRequest(w, r, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+	s.clearPermissionRuleset(w, r, store.PermissionScopeWorkspace, workspace.ID)
+}
+
+func (s *Server) handleListWorkspacePermissionHistory(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	workspace, ok := s.authorizeWorkspaceForRequest(w, r, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+	s.writePermissionHistory(w, r, store.PermissionScopeWorkspace, workspace.ID)
+}
+
+func (s *Server) handleGetAgentPermissions(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	agent, ok := s.agentForPermissions(w, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+	s.writePermissionRuleset(w, r, store.PermissionScopeAgent, agent.ID)
+}
+
+func (s *Server) handleUpdateAgentPermissions(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	agent, ok := s.agentForPermissions(w, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+	s.replacePermissionRuleset(w, r, store.PermissionScopeAgent, agent.ID)
+}
+
+func (s *Server) handleDeleteAgentPermissions(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	agent, ok := s.agentForPermissions(w, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+	s.clearPermissionRuleset(w, r, store.PermissionScopeAgent, agent.ID)
+}
+
+func (s *Server) handleListAgentPermissionHistory(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	agent, ok := s.agentForPermissions(w, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+	s.writePermissionHistory(w, r, store.PermissionScopeAgent, agent.ID)
+}
+
+func (s *Server) agentForPermissions(w http.ResponseWriter, agentID string) (*store.Agent, bool) {
+	agent, err := s.store.GetAgent(agentID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	return agent, true
+}
+
+func (s *Server) writePermissionRuleset(w http.ResponseWriter, r *http.Request, scope, scopeID string) {
+	ruleset, err := s.store.GetPermissionRuleset(r.Context(), scope, scopeID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, apiPermissionRuleset(*ruleset))
+}
+
+func (s *Server) writePermissionHistory(w http.ResponseWriter, r *http.Request, scope, scopeID string) {
+	changes, err := s.store.ListPermissionRuleChanges(r.Context(), scope, scopeID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, apiPermissionRuleChanges(changes))
+}
+
+func (s *Server) replacePermissionRuleset(w http.ResponseWriter, r *http.Request, scope, scopeID string) {
+	var req api.PermissionRulesetUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	s.updatePermissionRuleset(w, r, store.PermissionRulesetUpdate{
+		Scope: scope, ScopeID: scopeID, ExpectedVersion: req.ExpectedVersion, Rules: req.Rules, Operation: store.PermissionRuleChangeReplace,
+	})
+}
+
+func (s *Server) clearPermissionRuleset(w http.ResponseWriter, r *http.Request, scope, scopeID string) {
+	s.updatePermissionRuleset(w, r, store.PermissionRulesetUpdate{Scope: scope, ScopeID: scopeID, Operation: store.PermissionRuleChangeDelete})
+}
+
+func (s *Server) updatePermissionRuleset(w http.ResponseWriter, r *http.Request, update store.PermissionRulesetUpdate) {
+	clientID, err := s.resolveClientID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	update.ClientID = clientID
+	transition, err := s.store.UpdatePermissionRuleset(r.Context(), update)
+	if errors.Is(err, store.ErrPermissionRulesetVersionConflict) {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if transition.Changed {
+		s.logger.Info("permission rules changed", "scope", update.Scope, "scope_id", update.ScopeID, "operation", update.Operation, "version", transition.Ruleset.Version, "client_id", clientID)
+	}
+	writeJSON(w, http.StatusOK, apiPermissionRuleset(transition.Ruleset))
+}
+
+// handlePromotePermissionGrant copies a session "always" grant into the
+// session's workspace ruleset or an agent ruleset so later sessions inherit it.
+func (s *Server) handlePromotePermissionGrant(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	sessionID, grantID := chi.URLParam(r, "id"), chi.URLParam(r, "grantID")
+	sess, ok := s.authorizeSessionForRequest(w, r, sessionID)
+	if !ok {
+		return
+	}
+	var req api.PermissionGrantPromoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	grants, err := s.store.ListPermissionGrants(r.Context(), sessionID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	index := slices.IndexFunc(grants, func(grant store.PermissionGrant) bool { return grant.ID == grantID })
+	if index < 0 {
+		s.writeError(w, http.StatusNotFound, "permission grant not found: "+grantID)
+		return
+	}
+	grant := grants[index]
+
+	var scopeID string
+	switch req.Scope {
+	case store.PermissionScopeWorkspace:
+		if sess.WorkspaceID == "" {
+			s.writeError(w, http.StatusBadRequest, "session has no workspace")
+			return
+		}
+		scopeID = sess.WorkspaceID
+	case store.PermissionScopeAgent:
+		if req.AgentID == "" {
+			s.writeError(w, http.StatusBadRequest, "agent_id is required for agent scope")
+			return
+		}
+		agent, ok := s.agentForPermissions(w, req.AgentID)
+		if !ok {
+			return
+		}
+		scopeID = agent.ID
+	default:
+		s.writeError(w, http.StatusBadRequest, "scope must be workspace or agent")
+		return
+	}
+
+	current, err := s.store.GetPermissionRuleset(r.Context(), req.Scope, scopeID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if req.ExpectedVersion != nil && *req.ExpectedVersion != current.Version {
+		s.writeError(w, http.StatusConflict, (&store.PermissionRulesetVersionConflict{Scope: req.Scope, ScopeID: scopeID, Expected: *req.ExpectedVersion, Current: current.Version}).Error())
+		return
+	}
+	if decision := permission.Evaluate(grant.Action, grant.Resource, current.Rules, ""); decision.Effect == permission.EffectAllow {
+		writeJSON(w, http.StatusOK, apiPermissionRuleset(*current))
+		return
+	}
+	expected := current.Version
+	rules := append(slices.Clone(current.Rules), permission.Rule{Action: grant.Action, Resource: grant.Resource, Effect: permission.EffectAllow})
+	s.updatePermissionRuleset(w, r, store.PermissionRulesetUpdate{
+		Scope: req.Scope, ScopeID: scopeID, ExpectedVersion: &expected, Rules: rules,
+		Operation: store.PermissionRuleChangePromote, SessionID: sessionID, GrantID: grant.ID,
+	})
+}
+
+// handleEvaluatePermission is a dry run of the decision a tool call would
+// receive, without creating a permission request.
+func (s *Server) handleEvaluatePermission(w http.ResponseWriter, r *http.Request) {
+	var req api.PermissionEvaluateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Action == "" || req.Resource == "" {
+		s.writeError(w, http.StatusBadRequest, "action and resource are required")
+		return
+	}
+	resource := permission.ExpandHome(req.Resource)
+
+	var agent *store.Agent
+	var sess *store.Session
+	workspaceID, workDir := req.WorkspaceID, ""
+	if !s.Ephemeral() {
+		if req.SessionID != "" {
+			var ok bool
+			if sess, ok = s.authorizeSessionForRequest(w, r, req.SessionID); !ok {
+				return
+			}
+			if workspaceID == "" {
+				workspaceID = sess.WorkspaceID
+			}
+			workDir = sess.WorkDir
+		}
+		if workspaceID != "" {
+			workspace, ok := s.authorizeWorkspaceForRequest(w, r, workspaceID)
+			if !ok {
+				return
+			}
+			if workDir == "" {
+				workDir = workspace.Path
+			}
+		}
+		if req.AgentID != "" {
+			var ok bool
+			if agent, ok = s.agentForPermissions(w, req.AgentID); !ok {
+				return
+			}
+		}
+	}
+
+	// Runs check read and edit paths relative to the working directory.
+	if req.Action == "read" || req.Action == "edit" {
+		resource = permission.PathResource(workDir, resource)
+	}
+	layers, err := s.permissionLayers(r.Context(), agent, workspaceID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	evaluation := evaluatePermissionLayers(req.Action, resource, layers)
+	if evaluation.Effect == permission.EffectAsk && sess != nil {
+		grants, err := s.store.ListPermissionGrants(r.Context(), sess.ID)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, grant := range grants {
+			if grant.Action == req.Action && grant.Resource == resource {
+				evaluation = api.PermissionEvaluation{
+					Effect: permission.EffectAllow,
+					Rule:   &permission.Rule{Action: grant.Action, Resource: grant.Resource, Effect: permission.EffectAllow},
+					Source: permissionSourceSessionGrant,
+				}
+				break
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, evaluation)
+}
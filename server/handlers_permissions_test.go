@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/permission"
+	"github.com/chaserensberger/wingman/store"
+	"github.com/chaserensberger/wingman/store/memory"
+)
+
+func TestPermissionRulesetLifecycle(t *testing.T) {
+	ctx, data := context.Background(), memory.NewStore()
+	client, err := data.EnsureDefaultClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	workspace := &store.Workspace{Name: "rules", Path: t.TempDir(), ClientID: client.ID}
+	if err := data.CreateWorkspace(workspace); err != nil {
+		t.Fatal(err)
+	}
+	sess := &store.Session{ID: "ses_rules", WorkspaceID: workspace.ID, ClientID: client.ID}
+	if err := data.CreateSession(sess); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := data.CreatePermissionRequest(ctx, store.PermissionRequest{ID: "prq_rules", SessionID: sess.ID, Action: "shell.exec", Resources: []string{"make test"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := data.ResolvePermissionRequest(ctx, store.PermissionRequestResolution{SessionID: sess.ID, RequestID: "prq_rules", Status: store.PermissionRequestStatusApproved, Response: store.PermissionResponseAlways}); err != nil {
+		t.Fatal(err)
+	}
+	grants, err := data.ListPermissionGrants(ctx, sess.ID)
+	if err != nil || len(grants) != 1 {
+		t.Fatalf("grants = %#v, %v", grants, err)
+	}
+	server := New(Config{Store: data, Permissions: permission.Ruleset{{Action: "shell.exec", Resource: "*", Effect: permission.EffectAsk}}})
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		response := httptest.NewRecorder()
+		server.router.ServeHTTP(response, request)
+		return response
+	}
+	evaluate := func(body string) api.PermissionEvaluation {
+		t.Helper()
+		response := serve(http.MethodPost, "/permissions/evaluate", body)
+		if response.Code != http.StatusOK {
+			t.Fatalf("evaluate status = %d: %s", response.Code, response.Body.String())
+		}
+		var evaluation api.PermissionEvaluation
+		if err := json.NewDecoder(response.Body).Decode(&evaluation); err != nil {
+			t.Fatal(err)
+		}
+		return evaluation
+	}
+
+	if got := evaluate(`{"action":"shell.exec","resource":"git status","workspace_id":"` + workspace.ID + `"}`); got.Effect != permission.EffectAsk || got.Source != permissionSourceConfig {
+		t.Fatalf("config evaluation = %#v", got)
+	}
+	response := serve(http.MethodPut, "/workspaces/"+workspace.ID+"/permissions", `{"rules":{"shell.exec":{"git *":"allow"}},"expected_version":0}`)
+	if response.Code != http.StatusOK {
+		t.Fatalf("replace status = %d: %s", response.Code, response.Body.String())
+	}
+	if response := serve(http.MethodPut, "/workspaces/"+workspace.ID+"/permissions", `{"rules":[],"expected_version":0}`); response.Code != http.StatusConflict {
+		t.Fatalf("stale replace status = %d: %s", response.Code, response.Body.String())
+	}
+	got := evaluate(`{"action":"shell.exec","resource":"git status","workspace_id":"` + workspace.ID + `"}`)
+	if got.Effect != permission.EffectAllow || got.Source != permissionSourceWorkspace || got.Rule == nil || got.Rule.Resource != "git *" {
+		t.Fatalf("workspace evaluation = %#v", got)
+	}
+	if got := evaluate(`{"action":"shell.exec","resource":"make test","session_id":"` + sess.ID + `"}`); got.Effect != permission.EffectAllow || got.Source != permissionSourceSessionGrant {
+		t.Fatalf("session grant evaluation = %#v", got)
+	}
+
+	response = serve(http.MethodPost, "/sessions/"+sess.ID+"/permission-grants/"+grants[0].ID+"/promote", `{"scope":"workspace"}`)
+	if response.Code != http.StatusOK {
+		t.Fatalf("promote status = %d: %s", response.Code, response.Body.String())
+	}
+	var promoted api.PermissionRuleset
+	if err := json.NewDecoder(response.Body).Decode(&promoted); err != nil {
+		t.Fatal(err)
+	}
+	if promoted.Version != 2 || len(promoted.Rules) != 2 || promoted.Rules[1].Resource != "make test" {
+		t.Fatalf("promoted = %#v", promoted)
+	}
+	if got := evaluate(`{"action":"shell.exec","resource":"make test","workspace_id":"` + workspace.ID + `"}`); got.Effect != permission.EffectAllow || got.Source != permissionSourceWorkspace {
+		t.Fatalf("promoted evaluation = %#v", got)
+	}
+	if response := serve(http.MethodPost, "/sessions/"+sess.ID+"/permission-grants/"+grants[0].ID+"/promote", `{"scope":"workspace"}`); response.Code != http.StatusOK || !strings.Contains(response.Body.String(), `"version":2`) {
+		t.Fatalf("repeat promote = %d: %s", response.Code, response.Body.String())
+	}
+
+	response = serve(http.MethodGet, "/workspaces/"+workspace.ID+"/permissions/history", "")
+	var history []api.PermissionRuleChange
+	if err := json.NewDecoder(response.Body).Decode(&history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].ClientID != client.ID || history[1].Operation != store.PermissionRuleChangePromote || history[1].GrantID != grants[0].ID {
+		t.Fatalf("history = %#v", history)
+	}
+	if response := serve(http.MethodDelete, "/workspaces/"+workspace.ID+"/permissions", ""); response.Code != http.StatusOK || !strings.Contains(response.Body.String(), `"rules":[]`) {
+		t.Fatalf("delete = %d: %s", response.Code, response.Body.String())
+	}
+}
+
+func TestEvaluatePermissionRelativizesPathsLikeRuns(t *testing.T) {
+	data := memory.NewStore()
+	client, err := data.EnsureDefaultClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	workspace := &store.Workspace{Name: "paths", Path: t.TempDir(), ClientID: client.ID}
+	if err := data.CreateWorkspace(workspace); err != nil {
+		t.Fatal(err)
+	}
+	server := New(Config{Store: data, Permissions: permission.Ruleset{{Action: "edit", Resource: "src/*", Effect: permission.EffectDeny}}})
+	for _, resource := range []string{filepath.Join(workspace.Path, "src", "main.go"), "src/../src/main.go"} {
+		body, _ := json.Marshal(api.PermissionEvaluateRequest{Action: "edit", Resource: resource, WorkspaceID: workspace.ID})
+		response := httptest.NewRecorder()
+		server.router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/permissions/evaluate", strings.NewReader(string(body))))
+		var got api.PermissionEvaluation
+		if err := json.NewDecoder(response.Body).Decode(&got); err != nil || got.Effect != permission.EffectDeny {
+			t.Fatalf("evaluate %s = %#v, %v", resource, got, err)
+		}
+	}
+}
+
+func TestEffectivePermissionsLayersPersistedRules(t *testing.T) {
+	ctx, data := context.Background(), memory.NewStore()
+	agent := &store.Agent{ID: "agt_rules", Name: "rules", Permissions: permission.Ruleset{{Action: "*", Resource: "*", Effect: permission.EffectDeny}}}
+	if err := data.CreateAgent(agent); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := data.UpdatePermissionRuleset(ctx, store.PermissionRulesetUpdate{Scope: store.PermissionScopeAgent, ScopeID: agent.ID, Rules: permission.Ruleset{{Action: "filesystem.read", Resource: "*", Effect: permission.EffectAllow}}, Operation: store.PermissionRuleChangeReplace}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := data.UpdatePermissionRuleset(ctx, store.PermissionRulesetUpdate{Scope: store.PermissionScopeWorkspace, ScopeID: "wsp_rules", Rules: permission.Ruleset{{Action: "filesystem.read", Resource: "/secret/*", Effect: permission.EffectAsk}}, Operation: store.PermissionRuleChangeReplace}); err != nil {
+		t.Fatal(err)
+	}
+	server := New(Config{Store: data})
+	rules, err := server.effectivePermissions(ctx, agent, "wsp_rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for resource, want := range map[string]permission.Effect{"/src/main.go": permission.EffectAllow, "/secret/key": permission.EffectAsk} {
+		if got := permission.Evaluate("filesystem.read", resource, rules, permission.EffectAllow).Effect; got != want {
+			t.Fatalf("%s effect = %q, want %q", resource, got, want)
+		}
+	}
+	if got := permission.Evaluate("shell.exec", "ls", rules, permission.EffectAllow).Effect; got != permission.EffectDeny {
+		t.Fatalf("shell effect = %q, want deny", got)
+	}
+}
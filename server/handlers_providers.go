@@ -2,8 +2,12 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
+	"maps"
 	"net/http"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
@@ -222,6 +226,188 @@ func (s *Server) handleDeleteProviderAuth(w http.ResponseWriter, r *http.Request
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
+// ProviderCredentialsResponse lists a provider's credential set and the
+// strategy requests use to pick from it.
+type ProviderCredentialsResponse struct {
+	Provider    string                   `json:"provider"`
+	Strategy    string                   `json:"strategy"`
+	Credentials []ProviderCredentialInfo `json:"credentials"`
+}
+
+// ProviderCredentialInfo describes one credential without its secrets. The
+// default credential is the one /provider/auth manages.
+type ProviderCredentialInfo struct {
+	Name       string                  `json:"name"`
+	Type       string                  `json:"type"`
+	Configured bool                    `json:"configured"`
+	Usage      ProviderCredentialUsage `json:"usage"`
+}
+
+// ProviderCredentialUsage counts the requests sent with a credential since
+// the daemon started. The counts live in memory and reset on restart; a
+// model call's Credential is the durable record.
+type ProviderCredentialUsage struct {
+	Requests     int64     `json:"requests"`
+	Unauthorized int64     `json:"unauthorized"`
+	RateLimited  int64     `json:"rate_limited"`
+	LastUsedAt   time.Time `json:"last_used_at,omitzero"`
+	BlockedUntil time.Time `json:"blocked_until,omitzero"`
+}
+
+func (s *Server) handleListProviderCredentials(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	providerID := chi.URLParam(r, "name")
+	if !s.providers.IsValid(providerID) {
+		s.writeError(w, http.StatusNotFound, "unknown provider: "+providerID)
+		return
+	}
+	auth, err := s.store.GetAuth()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := ProviderCredentialsResponse{Provider: providerID, Strategy: provider.CredentialFailover, Credentials: []ProviderCredentialInfo{}}
+	if cfg, ok := s.providers.Config(providerID); ok && cfg.Options.Credentials != nil && cfg.Options.Credentials.Strategy != "" {
+		resp.Strategy = cfg.Options.Credentials.Strategy
+	}
+	usage := map[string]provider.CredentialUsage{}
+	for _, u := range s.providers.CredentialUsage(providerID) {
+		usage[u.Name] = u
+	}
+	add := func(name string, cred store.AuthCredential) {
+		u := usage[name]
+		resp.Credentials = append(resp.Credentials, ProviderCredentialInfo{
+			Name: name, Type: cred.Type, Configured: credentialConfigured(cred),
+			Usage: ProviderCredentialUsage{
+				Requests: u.Requests, Unauthorized: u.Unauthorized, RateLimited: u.RateLimited,
+				LastUsedAt: u.LastUsed, BlockedUntil: u.BlockedUntil,
+			},
+		})
+	}
+	if cred, ok := auth.Providers[providerID]; ok {
+		add(provider.DefaultCredential, cred)
+	}
+	named := auth.ProviderCredentials[providerID]
+	for _, name := range slices.Sorted(maps.Keys(named)) {
+		add(name, named[name])
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleSetProviderCredential(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	providerID, name := chi.URLParam(r, "name"), chi.URLParam(r, "credential")
+	if !s.providers.IsValid(providerID) {
+		s.writeError(w, http.StatusBadRequest, "unknown provider: "+providerID)
+		return
+	}
+	if !validCredentialName(name) {
+		s.writeError(w, http.StatusBadRequest, "invalid credential name: "+name)
+		return
+	}
+	var cred store.AuthCredential
+	if err := json.NewDecoder(r.Body).Decode(&cred); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	auth, err := s.store.GetAuth()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if name == provider.DefaultCredential {
+		auth.Providers[providerID] = cred
+	} else {
+		if auth.ProviderCredentials == nil {
+			auth.ProviderCredentials = map[string]map[string]store.AuthCredential{}
+		}
+		if auth.ProviderCredentials[providerID] == nil {
+			auth.ProviderCredentials[providerID] = map[string]store.AuthCredential{}
+		}
+		auth.ProviderCredentials[providerID][name] = cred
+	}
+	if err := s.store.SetAuth(auth); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleDeleteProviderCredential(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	providerID, name := chi.URLParam(r, "name"), chi.URLParam(r, "credential")
+	if !s.providers.IsValid(providerID) {
+		s.writeError(w, http.StatusBadRequest, "unknown provider: "+providerID)
+		return
+	}
+
+	auth, err := s.store.GetAuth()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	credentials, key := auth.Providers, providerID
+	if name != provider.DefaultCredential {
+		credentials, key = auth.ProviderCredentials[providerID], name
+	}
+	if _, exists := credentials[key]; !exists {
+		s.writeError(w, http.StatusNotFound, "credential not configured: "+providerID+"/"+name)
+		return
+	}
+	delete(credentials, key)
+	if len(auth.ProviderCredentials[providerID]) == 0 {
+		delete(auth.ProviderCredentials, providerID)
+	}
+
+	if err := s.store.SetAuth(auth); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// validCredentialName reports whether name can name a provider credential:
+// lowercase letters, digits, '_', and '-'.
+func validCredentialName(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && r != '-' && (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateCredentialSelection checks a map of provider IDs to credential
+// names. Whether each credential exists is checked when a run starts, since
+// credentials may be added after the selection.
+func (s *Server) validateCredentialSelection(selected map[string]string) error {
+	for providerID, name := range selected {
+		if !s.providers.IsValid(providerID) {
+			return fmt.Errorf("unknown provider: %s", providerID)
+		}
+		if !validCredentialName(name) {
+			return fmt.Errorf("invalid credential name for %s: %q", providerID, name)
+		}
+	}
+	return nil
+}
+
 // ModelDTO is the API response shape for a single model. It exposes the
 // normalized models.ModelInfo fields rather than the raw catalog schema,
 // which changes frequently and contains internal pricing/limit details that
@@ -237,6 +423,8 @@ type ModelDTO struct {
 	StructuredOutput  bool    `json:"structured_output"`
 	InputCostPerMTok  float64 `json:"input_cost_per_mtok,omitempty"`
 	OutputCostPerMTok float64 `json:"output_cost_per_mtok,omitempty"`
+
+	Embedding models.EmbeddingInfo `json:"embedding,omitzero"`
 }
 
 func modelToDTO(info models.ModelInfo) ModelDTO {
@@ -251,6 +439,7 @@ func modelToDTO(info models.ModelInfo) ModelDTO {
 		StructuredOutput:  info.Capabilities.StructuredOutput,
 		InputCostPerMTok:  info.InputCostPerMTok,
 		OutputCostPerMTok: info.OutputCostPerMTok,
+		Embedding:         info.Embedding,
 	}
 }
 
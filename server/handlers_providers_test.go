@@ -4,10 +4,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/chaserensberger/wingman/api"
 	"github.com/chaserensberger/wingman/execution"
 	provider "github.com/chaserensberger/wingman/models/providers"
+	"github.com/chaserensberger/wingman/store"
+	"github.com/chaserensberger/wingman/store/memory"
 )
 
 func TestListProviderModelsReturnsEmptyMapForValidProviderWithoutModels(t *testing.T) {
@@ -39,3 +43,154 @@ func TestListProviderModelsReturnsEmptyMapForValidProviderWithoutModels(t *testi
 		t.Fatalf("models = %#v, want empty map", models)
 	}
 }
+
+func TestProviderAuthResponseNeverIncludesSecrets(t *testing.T) {
+	t.Parallel()
+
+	registry, err := provider.NewRegistry(map[string]provider.ProviderConfig{
+		"openai-compatible": {Name: "OpenAI Compatible"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scopes, err := execution.NewManager(execution.Config{Providers: registry, DisablePlugins: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = scopes.Close() })
+	server := New(Config{Store: memory.NewStore(), Scopes: scopes})
+
+	body := `{"providers":{"openai-compatible":{"type":"oauth","access":"hidden-access","refresh":"hidden-refresh","key":"hidden-key"}}}`
+	response := httptest.NewRecorder()
+	server.router.ServeHTTP(response, httptest.NewRequest(http.MethodPut, "/provider/auth", strings.NewReader(body)))
+	if response.Code != http.StatusOK {
+		t.Fatalf("set status = %d: %s", response.Code, response.Body.String())
+	}
+	response = httptest.NewRecorder()
+	server.router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/provider/auth", nil))
+	if response.Code != http.StatusOK {
+		t.Fatalf("get status = %d: %s", response.Code, response.Body.String())
+	}
+	if strings.Contains(response.Body.String(), "hidden") {
+		t.Fatalf("provider auth response leaked a secret: %s", response.Body.String())
+	}
+	var auth ProvidersAuthResponse
+	if err := json.NewDecoder(response.Body).Decode(&auth); err != nil {
+		t.Fatal(err)
+	}
+	if got := auth.Providers["openai-compatible"]; got.Type != "oauth" || !got.Configured {
+		t.Fatalf("provider auth = %#v", auth)
+	}
+}
+
+func TestProviderCredentialSetsAndSelection(t *testing.T) {
+	t.Parallel()
+
+	registry, err := provider.NewRegistry(map[string]provider.ProviderConfig{
+		"openai-compatible": {Name: "OpenAI Compatible"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scopes, err := execution.NewManager(execution.Config{Providers: registry, DisablePlugins: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = scopes.Close() })
+	data := memory.NewStore()
+	server := New(Config{Store: data, Scopes: scopes})
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		server.router.ServeHTTP(response, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return response
+	}
+
+	if response := serve(http.MethodPut, "/provider/auth", `{"providers":{"openai-compatible":{"type":"api","key":"hidden-default"}}}`); response.Code != http.StatusOK {
+		t.Fatalf("set default status = %d: %s", response.Code, response.Body.String())
+	}
+	for _, name := range []string{"team-b", "team-a"} {
+		if response := serve(http.MethodPut, "/provider/openai-compatible/credentials/"+name, `{"type":"api","key":"hidden-`+name+`"}`); response.Code != http.StatusOK {
+			t.Fatalf("set %s status = %d: %s", name, response.Code, response.Body.String())
+		}
+	}
+	if response := serve(http.MethodPut, "/provider/openai-compatible/credentials/Team%20C", `{"type":"api","key":"k"}`); response.Code != http.StatusBadRequest {
+		t.Fatalf("invalid name status = %d", response.Code)
+	}
+
+	response := serve(http.MethodGet, "/provider/auth", "")
+	var auth ProvidersAuthResponse
+	if err := json.NewDecoder(response.Body).Decode(&auth); err != nil {
+		t.Fatal(err)
+	}
+	if len(auth.Providers) != 1 || !auth.Providers["openai-compatible"].Configured {
+		t.Fatalf("provider auth = %#v", auth)
+	}
+
+	response = serve(http.MethodGet, "/provider/openai-compatible/credentials", "")
+	if response.Code != http.StatusOK || strings.Contains(response.Body.String(), "hidden") {
+		t.Fatalf("credentials status = %d: %s", response.Code, response.Body.String())
+	}
+	var listed ProviderCredentialsResponse
+	if err := json.NewDecoder(response.Body).Decode(&listed); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, credential := range listed.Credentials {
+		names = append(names, credential.Name)
+	}
+	if listed.Strategy != provider.CredentialFailover || strings.Join(names, ",") != "default,team-a,team-b" {
+		t.Fatalf("credentials = %#v", listed)
+	}
+
+	_, sets, err := providerCredentials(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, credential := range sets["openai-compatible"] {
+		keys = append(keys, credential.Key)
+	}
+	if strings.Join(keys, ",") != "hidden-default,hidden-team-a,hidden-team-b" {
+		t.Fatalf("set keys = %v", keys)
+	}
+
+	workspace := &store.Workspace{Name: "team", Credentials: map[string]string{"openai-compatible": "team-a"}}
+	if err := data.CreateWorkspace(workspace); err != nil {
+		t.Fatal(err)
+	}
+	agent := &store.Agent{}
+	selected, err := server.credentialSelection(agent, workspace.ID)
+	if err != nil || selected["openai-compatible"] != "team-a" {
+		t.Fatalf("workspace selection = %v, %v", selected, err)
+	}
+	agent.Options = map[string]any{agentOptionCredentials: map[string]any{"openai-compatible": "team-b"}}
+	selected, err = server.credentialSelection(agent, workspace.ID)
+	if err != nil || selected["openai-compatible"] != "team-b" {
+		t.Fatalf("agent selection = %v, %v", selected, err)
+	}
+	_, sets, err = providerCredentials(data, selected)
+	if err != nil || len(sets["openai-compatible"]) != 1 || sets["openai-compatible"][0].Key != "hidden-team-b" {
+		t.Fatalf("pinned set = %v, %v", sets, err)
+	}
+	if _, _, err := providerCredentials(data, map[string]string{"openai-compatible": "missing"}); err == nil {
+		t.Fatal("pinned an unknown credential")
+	}
+	if response := serve(http.MethodPost, "/agents", `{"name":"pinned","options":{"credentials":{"nope":"team-a"}}}`); response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "unknown provider") {
+		t.Fatalf("create agent with unknown provider = %d: %s", response.Code, response.Body.String())
+	}
+	response = serve(http.MethodPost, "/agents", `{"name":"pinned","options":{"credentials":{"openai-compatible":"team-a"}}}`)
+	var created api.Agent
+	if response.Code != http.StatusCreated || json.NewDecoder(response.Body).Decode(&created) != nil {
+		t.Fatalf("create agent = %d: %s", response.Code, response.Body.String())
+	}
+	if response := serve(http.MethodPut, "/agents/"+created.ID, `{"options":{"credentials":{"openai-compatible":"Team A"}}}`); response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "invalid credential name") {
+		t.Fatalf("update agent with invalid name = %d: %s", response.Code, response.Body.String())
+	}
+
+	if response := serve(http.MethodDelete, "/provider/openai-compatible/credentials/team-a", ""); response.Code != http.StatusOK {
+		t.Fatalf("delete status = %d: %s", response.Code, response.Body.String())
+	}
+	if response := serve(http.MethodDelete, "/provider/openai-compatible/credentials/team-a", ""); response.Code != http.StatusNotFound {
+		t.Fatalf("second delete status = %d", response.Code)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -20,6 +22,7 @@ import (
 	"github.com/chaserensberger/wingman/models/catalog"
 	provider "github.com/chaserensberger/wingman/models/providers"
 	"github.com/chaserensberger/wingman/permission"
+	"github.com/chaserensberger/wingman/process"
 	"github.com/chaserensberger/wingman/store"
 	"github.com/chaserensberger/wingman/tool"
 )
@@ -259,10 +262,16 @@ func (s *Server) handleMoveSession(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	previous := *sess
 	sess, err = s.store.MoveSession(r.Context(), id, workDir, resolvedWorkspaceID, req.ExpectedVersion)
 	if s.writeSessionCommandError(w, err) {
 		return
 	}
+	// An open shell would keep running in the old directory under the old
+	// workspace's rules.
+	if sess.WorkDir != previous.WorkDir || sess.WorkspaceID != previous.WorkspaceID {
+		s.terminals.CloseSession(id)
+	}
 	writeJSON(w, http.StatusOK, apiSession(sess))
 }
 
@@ -306,7 +315,8 @@ func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := chi.URLParam(r, "id")
-	if _, ok := s.authorizeSessionForRequest(w, r, id); !ok {
+	sess, ok := s.authorizeSessionForRequest(w, r, id)
+	if !ok {
 		return
 	}
 	expectedVersion, err := strconv.ParseInt(r.URL.Query().Get("expected_version"), 10, 64)
@@ -319,6 +329,12 @@ func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	s.events.closeSession(id)
+	s.terminals.CloseSession(id)
+	if s.scopes != nil {
+		if scope, ok := s.scopes.Lookup(sess.WorkDir); ok {
+			scope.Processes().CloseSession(id)
+		}
+	}
 	if err := s.runs.stopAndWait(r.Context(), id); err != nil {
 		s.logger.Warn("wait for purged session worker", "session_id", id, "error", err)
 		return
@@ -353,79 +369,115 @@ func (s *Server) handleMessageSession(w http.ResponseWriter, r *http.Request) {
 		s.writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
+	admission, status, err := s.admitSessionMessage(r.Context(), sess, req)
+	if err != nil {
+		s.writeError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, api.MessageSessionResponse{RunID: admission.Run.ID, Status: admission.Run.Status, SessionVersion: admission.SessionVersion})
+}
 
+// admitSessionMessage validates req against sess, durably queues the run, and
+// wakes the session worker. On failure it returns the HTTP status to report.
+// The REST message endpoint and the MCP agent tools share it.
+func (s *Server) admitSessionMessage(ctx context.Context, sess *store.Session, req api.MessageSessionRequest) (store.SessionRunAdmission, int, error) {
 	if req.Message == "" {
-		s.writeError(w, http.StatusBadRequest, "message is required")
-		return
+		return store.SessionRunAdmission{}, http.StatusBadRequest, errors.New("message is required")
 	}
 	if req.RequestID != "" {
 		if strings.TrimSpace(req.RequestID) == "" {
-			s.writeError(w, http.StatusBadRequest, "request_id cannot be blank")
-			return
+			return store.SessionRunAdmission{}, http.StatusBadRequest, errors.New("request_id cannot be blank")
 		}
 		if len(req.RequestID) > 200 {
-			s.writeError(w, http.StatusBadRequest, "request_id must be 200 bytes or fewer")
-			return
+			return store.SessionRunAdmission{}, http.StatusBadRequest, errors.New("request_id must be 200 bytes or fewer")
 		}
 	}
 	if req.AgentID == "" {
-		s.writeError(w, http.StatusBadRequest, "agent_id is required")
-		return
+		return store.SessionRunAdmission{}, http.StatusBadRequest, errors.New("agent_id is required")
+	}
+	if req.Priority < -maxRunPriority || req.Priority > maxRunPriority {
+		return store.SessionRunAdmission{}, http.StatusBadRequest, fmt.Errorf("priority must be between %d and %d", -maxRunPriority, maxRunPriority)
 	}
 
-	storedAgent, err := s.store.GetAgent(req.AgentID)
+	limits, err := storeRunLimits(req.Limits)
 	if err != nil {
-		s.writeError(w, http.StatusNotFound, "agent not found: "+req.AgentID)
-		return
+		return store.SessionRunAdmission{}, http.StatusBadRequest, err
+	}
+
+	storedAgent, status, err := s.lookupAgent(ctx, sess.WorkDir, req.AgentID)
+	if err != nil {
+		return store.SessionRunAdmission{}, status, err
+	}
+	if req.AgentRevision < 0 {
+		return store.SessionRunAdmission{}, http.StatusBadRequest, errors.New("agent_revision cannot be negative")
+	}
+	if req.AgentRevision > 0 && req.AgentRevision != storedAgent.Revision {
+		if storedAgent.Source == store.AgentSourceFile {
+			return store.SessionRunAdmission{}, http.StatusBadRequest, errors.New("agent_revision is not supported for file agents")
+		}
+		pinned, err := s.store.GetAgentRevision(req.AgentID, req.AgentRevision)
+		if err != nil {
+			return store.SessionRunAdmission{}, http.StatusNotFound, err
+		}
+		storedAgent = &pinned.Agent
 	}
 
 	effectiveAgent := s.agentWithRequestModel(storedAgent, req.ModelRef, req.ModelRoute)
-	validationSession, err := s.buildSession(r.Context(), effectiveAgent, sess)
+	if limits != nil {
+		// The run snapshot carries the effective limits, so a retried
+		// request with different limits is an admission conflict.
+		limited := *effectiveAgent
+		limited.Limits = effectiveAgent.Limits.Tighten(limits)
+		effectiveAgent = &limited
+	}
+	validationSession, err := s.buildSession(ctx, effectiveAgent, sess)
 	if err != nil {
-		s.writeError(w, http.StatusBadRequest, err.Error())
-		return
+		return store.SessionRunAdmission{}, http.StatusBadRequest, err
 	}
-	validationCtx, validationCancel := context.WithTimeout(r.Context(), 5*time.Second)
+	validationCtx, validationCancel := context.WithTimeout(ctx, 5*time.Second)
 	defer validationCancel()
 	if err := validationSession.Close(validationCtx); err != nil {
 		s.logger.Error("close admission validation session", "session_id", sess.ID, "error", err)
-		s.writeError(w, http.StatusInternalServerError, "close admission validation session")
-		return
+		return store.SessionRunAdmission{}, http.StatusInternalServerError, errors.New("close admission validation session")
+	}
+	message := req.Message
+	if len(req.Resources) > 0 {
+		message, err = s.attachMCPResources(ctx, sess.WorkDir, req.Message, req.Resources)
+		if err != nil {
+			return store.SessionRunAdmission{}, mcpAttachmentStatus(err), err
+		}
 	}
 	var outputSchemaJSON []byte
 	if req.OutputSchema != nil {
 		outputSchemaJSON, err = json.Marshal(req.OutputSchema)
 		if err != nil {
-			s.writeError(w, http.StatusBadRequest, "invalid output schema")
-			return
+			return store.SessionRunAdmission{}, http.StatusBadRequest, errors.New("invalid output schema")
 		}
 	}
-	admission, err := s.store.AdmitSessionRun(r.Context(), store.SessionRun{
-		SessionID:        id,
+	admission, err := s.store.AdmitSessionRun(ctx, store.SessionRun{
+		SessionID:        sess.ID,
 		RequestID:        req.RequestID,
-		Message:          req.Message,
+		Message:          message,
+		Priority:         req.Priority,
 		Agent:            *effectiveAgent,
 		OutputSchemaJSON: outputSchemaJSON,
 	})
 	if err != nil {
 		if errors.Is(err, store.ErrSessionRunAdmissionConflict) {
-			s.writeError(w, http.StatusConflict, err.Error())
-			return
+			return store.SessionRunAdmission{}, http.StatusConflict, err
 		}
 		if errors.Is(err, store.ErrSessionNotFound) {
-			s.writeError(w, http.StatusNotFound, err.Error())
-			return
+			return store.SessionRunAdmission{}, http.StatusNotFound, err
 		}
-		s.writeError(w, http.StatusInternalServerError, err.Error())
-		return
+		return store.SessionRunAdmission{}, http.StatusInternalServerError, err
 	}
 	if admission.Created {
 		s.events.publish(admission.QueuedEvent)
 	}
 	if admission.Run.Status == store.SessionRunStatusQueued {
-		s.runs.wake(id)
+		s.runs.wake(sess.ID)
 	}
-	writeJSON(w, http.StatusAccepted, api.MessageSessionResponse{RunID: admission.Run.ID, Status: admission.Run.Status, SessionVersion: admission.SessionVersion})
+	return admission, http.StatusAccepted, nil
 }
 
 func (s *Server) handleAbortSession(w http.ResponseWriter, r *http.Request) {
@@ -455,7 +507,7 @@ func (s *Server) handleListSessionRuns(w http.ResponseWriter, r *http.Request) {
 		s.writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, apiSessionRuns(runs))
+	writeJSON(w, http.StatusOK, s.withQueuePositions(apiSessionRuns(runs)))
 }
 
 func (s *Server) handleGetSessionRun(w http.ResponseWriter, r *http.Request) {
@@ -476,7 +528,58 @@ func (s *Server) handleGetSessionRun(w http.ResponseWriter, r *http.Request) {
 		s.writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, apiSessionRun(*run))
+	writeJSON(w, http.StatusOK, s.withQueuePositions([]api.SessionRun{apiSessionRun(*run)})[0])
+}
+
+// withQueuePositions sets the queue position of runs waiting for a run slot.
+func (s *Server) withQueuePositions(runs []api.SessionRun) []api.SessionRun {
+	positions := s.scheduler.positions()
+	for i := range runs {
+		runs[i].QueuePosition = positions[runs[i].ID]
+	}
+	return runs
+}
+
+func (s *Server) handleGetSessionArtifact(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if _, ok := s.authorizeSessionForRequest(w, r, id); !ok {
+		return
+	}
+	artifact, err := s.store.GetArtifact(r.Context(), id, chi.URLParam(r, "artifactID"))
+	if errors.Is(err, store.ErrArtifactNotFound) {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, apiArtifact(artifact))
+}
+
+// handleListSessionProcesses reports the background processes the session
+// started in its execution scope. A scope that is not loaded has none.
+func (s *Server) handleListSessionProcesses(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	sess, ok := s.authorizeSessionForRequest(w, r, id)
+	if !ok {
+		return
+	}
+	var infos []process.Info
+	if s.scopes != nil {
+		if scope, ok := s.scopes.Lookup(sess.WorkDir); ok {
+			infos = scope.Processes().List(id)
+		}
+	}
+	writeJSON(w, http.StatusOK, apiProcesses(infos))
 }
 
 func (s *Server) handleAbortSessionRun(w http.ResponseWriter, r *http.Request) {
@@ -539,15 +642,21 @@ func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	workDir, err := session.ResolveWorkDir(req.WorkingDirectory)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	var storedAgent *store.Agent
 	if req.AgentID != "" {
 		if s.Ephemeral() {
 			s.writeError(w, http.StatusBadRequest, "agent_id is not supported in ephemeral mode; provide an inline agent spec")
 			return
 		}
-		a, err := s.store.GetAgent(req.AgentID)
+		a, status, err := s.lookupAgent(r.Context(), workDir, req.AgentID)
 		if err != nil {
-			s.writeError(w, http.StatusNotFound, "agent not found: "+req.AgentID)
+			s.writeError(w, status, err.Error())
 			return
 		}
 		storedAgent = a
@@ -564,12 +673,6 @@ func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	workDir, err := session.ResolveWorkDir(req.WorkingDirectory)
-	if err != nil {
-		s.writeError(w, http.StatusBadRequest, err.Error())
-		return
-	}
-
 	sess := &store.Session{
 		ID:      store.NewID("eph_"),
 		Title:   "ephemeral",
@@ -684,6 +787,8 @@ func canonicalRunStreamEvent(event session.StreamEvent, requestID string) (api.R
 		result.Data = api.RunStreamPartEventData{Step: data.Step, MessageID: data.MessageID, PartID: data.PartID, Revision: data.Revision, Part: part}
 	case run.ContextTransformedEvent:
 		result.Data = api.RunContextTransformedEventData{Step: data.Step, Phase: data.Phase, OriginalCount: data.OriginalCount, NewCount: data.NewCount, Head: data.Head}
+	case session.InstructionsLoadedEvent:
+		result.Data = api.RunInstructionsEventData{Files: apiInstructionFiles(data.Files)}
 	case map[string]string:
 		message := data["error"]
 		if message == "" {
@@ -801,7 +906,11 @@ func (s *Server) buildSessionWithStore(ctx context.Context, stored *store.Agent,
 		workDir = executionScope.WorkDir()
 	}
 
-	modelRef, modelInfo, client, err := s.buildModelClient(stored, providers)
+	modelRef, modelInfo, client, err := s.buildModelClient(stored, sess.WorkspaceID, providers)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := s.effectivePermissions(ctx, stored, sess.WorkspaceID)
 	if err != nil {
 		return nil, err
 	}
@@ -819,14 +928,24 @@ func (s *Server) buildSessionWithStore(ctx context.Context, stored *store.Agent,
 		session.WithModelRef(modelRef, modelInfo),
 		session.WithSystem(stored.Instructions),
 		session.WithWorkDir(workDir),
-		session.WithPermissions(s.effectivePermissions(stored)),
+		session.WithPermissions(permissions),
 		session.WithPermissionPrompter(prompter),
 		session.WithLogger(logger),
 		session.WithAgentID(stored.ID),
+		session.WithMaxToolOutputBytes(s.maxToolOutputBytes),
 	}
 	if st != nil {
 		opts = append(opts, session.WithStore(st))
 	}
+	if workDir != "" {
+		opts = append(opts, session.WithProjectInstructions(s.instructionRoot(sess)))
+		sessionID := sess.ID
+		opts = append(opts, session.WithContextSection(func() string { return s.terminals.Context(sessionID) }))
+	}
+	if executionScope != nil && workDir != "" {
+		// Keep the symbol index current as the agent's file tools edit.
+		opts = append(opts, session.WithPlugin(executionScope.Symbols().Plugin()))
+	}
 	if runID != "" {
 		opts = append(opts, session.WithRunID(runID))
 	}
@@ -842,6 +961,14 @@ func (s *Server) buildSessionWithStore(ctx context.Context, stored *store.Agent,
 	if len(tools) > 0 {
 		opts = append(opts, session.WithTools(tools...))
 	}
+	if stored.Limits != nil {
+		opts = append(opts, session.WithLimits(session.Limits{
+			MaxSteps:       stored.Limits.MaxSteps,
+			MaxTotalTokens: stored.Limits.MaxTotalTokens,
+			MaxDuration:    time.Duration(stored.Limits.MaxDurationMS) * time.Millisecond,
+			MaxToolCalls:   stored.Limits.MaxToolCalls,
+		}))
+	}
 	if len(stored.OutputSchema) > 0 {
 		opts = append(opts, session.WithOutputSchema(&models.OutputSchema{
 			Name:   stored.ID,
@@ -858,20 +985,35 @@ func (s *Server) buildSessionWithStore(ctx context.Context, stored *store.Agent,
 	return session.New(opts...), nil
 }
 
-func (s *Server) effectivePermissions(agent *store.Agent) permission.Ruleset {
-	if agent == nil {
-		return s.permissions
+// instructionRoot bounds project instruction discovery at the session's
+// workspace root. Sessions outside a workspace use the repository root.
+func (s *Server) instructionRoot(sess *store.Session) string {
+	if sess.WorkspaceID == "" || s.store == nil {
+		return ""
+	}
+	workspace, err := s.store.GetWorkspace(sess.WorkspaceID)
+	if err != nil {
+		return ""
 	}
-	return permission.Merge(
-		agent.Permissions,
-		s.permissions,
-		s.agentPermissions[agent.Name],
-		s.agentPermissions[agent.ID],
-	)
+	return workspace.Path
 }
 
-// buildModelClient resolves a model ref and returns a route-backed model client.
-func (s *Server) buildModelClient(stored *store.Agent, providers *provider.Registry) (models.ModelRef, models.ModelInfo, models.Client, error) {
+func (s *Server) effectivePermissions(ctx context.Context, agent *store.Agent, workspaceID string) (permission.Ruleset, error) {
+	layers, err := s.permissionLayers(ctx, agent, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	sets := make([]permission.Ruleset, 0, len(layers))
+	for _, layer := range layers {
+		sets = append(sets, layer.rules)
+	}
+	return permission.Merge(sets...), nil
+}
+
+// buildModelClient resolves a model ref and returns a route-backed model
+// client. The agent's credentials option, over the workspace's selections,
+// pins providers to one named credential.
+func (s *Server) buildModelClient(stored *store.Agent, workspaceID string, providers *provider.Registry) (models.ModelRef, models.ModelInfo, models.Client, error) {
 	ref, ok := models.ParseModelRef(stored.ModelRef)
 	if !ok {
 		return models.ModelRef{}, models.ModelInfo{}, nil, fmt.Errorf("invalid model_ref: %s", stored.ModelRef)
@@ -881,24 +1023,91 @@ func (s *Server) buildModelClient(stored *store.Agent, providers *provider.Regis
 		return models.ModelRef{}, models.ModelInfo{}, nil, err
 	}
 	ref = modelRefWithInfo(ref, info)
-	var auth *store.Auth
-	if s.store != nil {
-		var err error
-		auth, err = s.store.GetAuth()
+	selected, err := s.credentialSelection(stored, workspaceID)
+	if err != nil {
+		return models.ModelRef{}, models.ModelInfo{}, nil, err
+	}
+	credentials, sets, err := providerCredentials(s.store, selected)
+	if err != nil {
+		return models.ModelRef{}, models.ModelInfo{}, nil, err
+	}
+	return ref, info, providers.NewClientWithCredentialSets(credentials, sets, s.refreshProviderCredential), nil
+}
+
+// credentialSelection merges the workspace's credential selections with the
+// agent's, which win.
+func (s *Server) credentialSelection(stored *store.Agent, workspaceID string) (map[string]string, error) {
+	selected := map[string]string{}
+	if workspaceID != "" && s.store != nil {
+		workspace, err := s.store.GetWorkspace(workspaceID)
 		if err != nil {
-			return models.ModelRef{}, models.ModelInfo{}, nil, fmt.Errorf("failed to load auth: %w", err)
+			return nil, err
 		}
-	} else {
-		auth = &store.Auth{Providers: make(map[string]store.AuthCredential)}
+		maps.Copy(selected, workspace.Credentials)
+	}
+	agentSelected, err := credentialsFromOptions(stored.Options)
+	if err != nil {
+		return nil, err
 	}
+	maps.Copy(selected, agentSelected)
+	return selected, nil
+}
+
+// providerCredentials loads the stored provider credentials. A nil store has
+// none, leaving clients to environment variables. A provider with named
+// credentials also gets a set: its default credential first, then the named
+// ones in name order. selected pins a provider's set to one credential.
+func providerCredentials(data store.Store, selected map[string]string) (map[string]provider.Credential, map[string][]provider.Credential, error) {
 	credentials := map[string]provider.Credential{}
+	sets := map[string][]provider.Credential{}
+	if data == nil {
+		return credentials, sets, nil
+	}
+	auth, err := data.GetAuth()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load auth: %w", err)
+	}
 	for id, cred := range auth.Providers {
-		credentials[id] = provider.Credential{
-			Type: cred.Type, Key: cred.Key, Access: cred.Access, Refresh: cred.Refresh,
-			ExpiresAt: cred.ExpiresAt, AccountID: cred.AccountID,
+		credentials[id] = providerCredential(cred)
+	}
+	for id, named := range auth.ProviderCredentials {
+		var set []provider.Credential
+		if cred, ok := auth.Providers[id]; ok && credentialConfigured(cred) {
+			set = append(set, namedProviderCredential(provider.DefaultCredential, cred))
+		}
+		for _, name := range slices.Sorted(maps.Keys(named)) {
+			if credentialConfigured(named[name]) {
+				set = append(set, namedProviderCredential(name, named[name]))
+			}
+		}
+		if len(set) > 0 {
+			sets[id] = set
 		}
 	}
-	return ref, info, providers.NewClientWithCredentials(credentials, s.refreshProviderCredential), nil
+	for id, name := range selected {
+		var pinned []provider.Credential
+		for _, credential := range sets[id] {
+			if credential.Name == name {
+				pinned = append(pinned, credential)
+			}
+		}
+		if len(pinned) == 0 && name == provider.DefaultCredential {
+			if cred, ok := auth.Providers[id]; ok && credentialConfigured(cred) {
+				pinned = append(pinned, namedProviderCredential(name, cred))
+			}
+		}
+		if len(pinned) == 0 {
+			return nil, nil, fmt.Errorf("credential %q is not configured for provider %s", name, id)
+		}
+		sets[id] = pinned
+	}
+	return credentials, sets, nil
+}
+
+func namedProviderCredential(name string, cred store.AuthCredential) provider.Credential {
+	credential := providerCredential(cred)
+	credential.Name = name
+	return credential
 }
 
 func (s *Server) resolveModelInfo(modelCatalog *catalog.Catalog, ref models.ModelRef, options map[string]any) (models.ModelInfo, error) {
@@ -927,6 +1136,22 @@ func (s *Server) resolveModelInfo(modelCatalog *catalog.Catalog, ref models.Mode
 	return info, nil
 }
 
+func credentialsFromOptions(options map[string]any) (map[string]string, error) {
+	raw, ok := options[agentOptionCredentials]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials: %w", err)
+	}
+	var selected map[string]string
+	if err := json.Unmarshal(b, &selected); err != nil {
+		return nil, fmt.Errorf("invalid credentials: %w", err)
+	}
+	return selected, nil
+}
+
 func modelRouteFromOptions(options map[string]any) (models.ModelInfo, bool, error) {
 	raw, ok := options[agentOptionModelRoute]
 	if !ok || raw == nil {
@@ -16,7 +16,10 @@ import (
 	"github.com/chaserensberger/wingman/agent/run"
 	"github.com/chaserensberger/wingman/agent/session"
 	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/execution"
 	"github.com/chaserensberger/wingman/models"
+	provider "github.com/chaserensberger/wingman/models/providers"
+	"github.com/chaserensberger/wingman/process"
 	"github.com/chaserensberger/wingman/store"
 	"github.com/chaserensberger/wingman/store/memory"
 	"github.com/chaserensberger/wingman/tool"
@@ -493,6 +496,94 @@ func TestListSessionModelCallsNotFound(t *testing.T) {
 	}
 }
 
+func TestGetSessionArtifact(t *testing.T) {
+	t.Parallel()
+
+	data := memory.NewStore()
+	client, err := data.EnsureDefaultClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"ses_artifact", "ses_other"} {
+		if err := data.CreateSession(&store.Session{ID: id, ClientID: client.ID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	artifact := &store.Artifact{SessionID: "ses_artifact", CallID: "call_1", Name: "bash", Content: []byte("full output")}
+	if err := data.SaveArtifact(context.Background(), artifact); err != nil {
+		t.Fatal(err)
+	}
+
+	server := New(Config{Store: data})
+	response := httptest.NewRecorder()
+	server.router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/sessions/ses_artifact/artifacts/"+artifact.ID, nil))
+	var got api.Artifact
+	if err := json.Unmarshal(response.Body.Bytes(), &got); err != nil || response.Code != http.StatusOK {
+		t.Fatalf("get = %d: %s", response.Code, response.Body.String())
+	}
+	if got.Content != "full output" || got.Size != 11 || got.Name != "bash" {
+		t.Fatalf("artifact = %#v", got)
+	}
+	response = httptest.NewRecorder()
+	server.router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/sessions/ses_other/artifacts/"+artifact.ID, nil))
+	if response.Code != http.StatusNotFound {
+		t.Fatalf("other session status = %d, want %d", response.Code, http.StatusNotFound)
+	}
+}
+
+func TestListSessionProcesses(t *testing.T) {
+	data := memory.NewStore()
+	client, err := data.EnsureDefaultClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	workDir := t.TempDir()
+	if err := data.CreateSession(&store.Session{ID: "ses_processes", ClientID: client.ID, WorkDir: workDir}); err != nil {
+		t.Fatal(err)
+	}
+	registry, err := provider.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scopes, err := execution.NewManager(execution.Config{Providers: registry, DisablePlugins: true, NativeTools: execution.BuiltinTools()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = scopes.Close() })
+	server := New(Config{Store: data, Scopes: scopes})
+	list := func() []api.Process {
+		t.Helper()
+		response := httptest.NewRecorder()
+		server.router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/sessions/ses_processes/processes", nil))
+		var processes []api.Process
+		if err := json.Unmarshal(response.Body.Bytes(), &processes); err != nil || response.Code != http.StatusOK {
+			t.Fatalf("list = %d: %s", response.Code, response.Body.String())
+		}
+		return processes
+	}
+	if processes := list(); len(processes) != 0 {
+		t.Fatalf("processes before scope load = %#v", processes)
+	}
+
+	scope, release, err := server.executionScope(context.Background(), workDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	p, err := scope.Processes().Start(process.StartOptions{SessionID: "ses_processes", Command: "echo hi", WorkDir: workDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-p.Done()
+	if _, err := scope.Processes().Start(process.StartOptions{SessionID: "ses_other", Command: "true", WorkDir: workDir}); err != nil {
+		t.Fatal(err)
+	}
+	processes := list()
+	if len(processes) != 1 || processes[0].ID != p.ID() || processes[0].Status != "exited" || processes[0].ExitCode == nil || *processes[0].ExitCode != 0 || processes[0].OutputBytes != 3 {
+		t.Fatalf("processes = %#v", processes)
+	}
+}
+
 func TestListSessionToolUses(t *testing.T) {
 	data := memory.NewStore()
 	owner, err := data.EnsureDefaultClient()
@@ -892,11 +983,11 @@ func TestPermissionRequestEndpointsAuthorizeReplyAndPublishOnce(t *testing.T) {
 		err      error
 	}, 1)
 	go func() {
-		response, err := server.permissionRequests.prompter("ses_permission_retry_notify", "").Request(context.Background(), run.PermissionRequestInfo{Action: "edit", Resources: []string{"b.go"}})
+		reply, err := server.permissionRequests.prompter("ses_permission_retry_notify", "").Request(context.Background(), run.PermissionRequestInfo{Action: "edit", Resources: []string{"b.go"}})
 		result <- struct {
 			response run.PermissionResponse
 			err      error
-		}{response, err}
+		}{reply.Response, err}
 	}()
 	lostNotification := waitForPermissionRequest(t, data, "ses_permission_retry_notify")
 	if _, err := data.ResolvePermissionRequest(context.Background(), store.PermissionRequestResolution{SessionID: lostNotification.SessionID, RequestID: lostNotification.ID, Status: store.PermissionRequestStatusApproved, Response: store.PermissionResponseOnce}); err != nil {
@@ -911,6 +1002,90 @@ func TestPermissionRequestEndpointsAuthorizeReplyAndPublishOnce(t *testing.T) {
 	}
 }
 
+func TestPermissionReplyEditsInputAndExplainsRejection(t *testing.T) {
+	data := memory.NewStore()
+	owner, err := data.EnsureDefaultClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := data.CreateSession(&store.Session{ID: "ses_permission_edit", ClientID: owner.ID}); err != nil {
+		t.Fatal(err)
+	}
+	server := New(Config{Store: data})
+	call := func(path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		r.Header.Set("X-Wingman-Client", owner.ID)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, r)
+		return w
+	}
+	validate := func(args map[string]any) error {
+		if _, ok := args["command"].(string); !ok {
+			return errors.New("command is required")
+		}
+		return nil
+	}
+	replies := make(chan run.PermissionReply, 1)
+	go func() {
+		reply, _ := server.permissionRequests.prompter("ses_permission_edit", "").Request(context.Background(), run.PermissionRequestInfo{Action: "bash", Resources: []string{"rm -rf build"}, ValidateInput: validate})
+		replies <- reply
+	}()
+	pending := waitForPermissionRequest(t, data, "ses_permission_edit")
+	reply := "/sessions/ses_permission_edit/permission-requests/" + pending.ID + "/reply"
+	if response := call(reply, `{"response":"always","input":{"command":"ls"}}`); response.Code != http.StatusBadRequest {
+		t.Fatalf("always with input status = %d", response.Code)
+	}
+	if response := call(reply, `{"response":"once","message":"no"}`); response.Code != http.StatusBadRequest {
+		t.Fatalf("approve with message status = %d", response.Code)
+	}
+	if response := call(reply, `{"response":"once","input":{"command":1}}`); response.Code != http.StatusBadRequest {
+		t.Fatalf("invalid input status = %d", response.Code)
+	}
+	response := call(reply, `{"response":"once","input":{"command":"rm -rf build/tmp"}}`)
+	if response.Code != http.StatusOK {
+		t.Fatalf("edited reply status = %d: %s", response.Code, response.Body.String())
+	}
+	var resolved store.PermissionRequest
+	if err := json.NewDecoder(response.Body).Decode(&resolved); err != nil || string(resolved.EditedInput) != `{"command":"rm -rf build/tmp"}` {
+		t.Fatalf("resolved = %#v, %v", resolved, err)
+	}
+	if got := <-replies; got.Response != run.PermissionResponseOnce || got.Input["command"] != "rm -rf build/tmp" {
+		t.Fatalf("edited reply = %#v", got)
+	}
+
+	go func() {
+		reply, _ := server.permissionRequests.prompter("ses_permission_edit", "").Request(context.Background(), run.PermissionRequestInfo{Action: "bash", Resources: []string{"git push"}})
+		replies <- reply
+	}()
+	var rejected store.PermissionRequest
+	deadline := time.Now().Add(time.Second)
+	for rejected.ID == "" && time.Now().Before(deadline) {
+		requests, err := data.ListPermissionRequests(context.Background(), "ses_permission_edit")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, request := range requests {
+			if request.Status == store.PermissionRequestStatusPending {
+				rejected = request
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if rejected.ID == "" {
+		t.Fatal("second permission request was not created")
+	}
+	response = call("/sessions/ses_permission_edit/permission-requests/"+rejected.ID+"/reply", `{"response":"reject","message":"push from CI instead"}`)
+	if response.Code != http.StatusOK {
+		t.Fatalf("reject reply status = %d: %s", response.Code, response.Body.String())
+	}
+	if err := json.NewDecoder(response.Body).Decode(&resolved); err != nil || resolved.Message != "push from CI instead" {
+		t.Fatalf("rejected = %#v, %v", resolved, err)
+	}
+	if got := <-replies; got.Response != run.PermissionResponseReject || got.Message != "push from CI instead" {
+		t.Fatalf("reject reply = %#v", got)
+	}
+}
+
 func TestRecoverStartupSettlesRunningRunAfterChildState(t *testing.T) {
 	data := memory.NewStore()
 	ctx := context.Background()
@@ -1231,3 +1406,89 @@ func TestSessionRunWorkerPublishesOnlyCommittedTransitions(t *testing.T) {
 		t.Fatalf("run = %#v", run)
 	}
 }
+
+func TestMessageSessionLimitsFailRunWithLimitExceeded(t *testing.T) {
+	t.Parallel()
+
+	// The provider never answers, so only the duration budget ends the run.
+	release := make(chan struct{})
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer provider.Close()
+	defer close(release)
+	data := memory.NewStore()
+	client, err := data.EnsureDefaultClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := data.CreateSession(&store.Session{ID: "ses_limits", ClientID: client.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.CreateAgent(&store.Agent{
+		ID:       "agt_limits",
+		Name:     "Limited",
+		ModelRef: "test/model",
+		Limits:   &store.RunLimits{MaxSteps: 4, MaxDurationMS: 60_000},
+		Options: map[string]any{agentOptionModelRoute: models.ModelInfo{
+			Provider: "test",
+			ID:       "model",
+			API:      models.APIOpenAICompatible,
+			BaseURL:  provider.URL,
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	server := New(Config{Store: data})
+	t.Cleanup(func() { _ = server.Close(context.Background()) })
+
+	invalid := httptest.NewRequest(http.MethodPost, "/sessions/ses_limits/message", strings.NewReader(`{"agent_id":"agt_limits","message":"hello","limits":{"max_steps":-1}}`))
+	response := httptest.NewRecorder()
+	server.router.ServeHTTP(response, invalid)
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("negative limit status = %d: %s", response.Code, response.Body.String())
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/sessions/ses_limits/message", strings.NewReader(`{"agent_id":"agt_limits","message":"hello","limits":{"max_steps":10,"max_duration_ms":50,"max_tool_calls":3}}`))
+	response = httptest.NewRecorder()
+	server.router.ServeHTTP(response, request)
+	if response.Code != http.StatusAccepted {
+		t.Fatalf("status = %d: %s", response.Code, response.Body.String())
+	}
+	var admitted api.MessageSessionResponse
+	if err := json.NewDecoder(response.Body).Decode(&admitted); err != nil {
+		t.Fatal(err)
+	}
+
+	var run *store.SessionRun
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		run, err = data.GetSessionRun(context.Background(), "ses_limits", admitted.RunID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.Status != store.SessionRunStatusQueued && run.Status != store.SessionRunStatusRunning {
+			break
+		}
+	}
+	if want := (store.RunLimits{MaxSteps: 4, MaxDurationMS: 50, MaxToolCalls: 3}); run.Agent.Limits == nil || *run.Agent.Limits != want {
+		t.Fatalf("effective limits = %#v, want %#v", run.Agent.Limits, want)
+	}
+	if run.Status != store.SessionRunStatusFailed || run.ErrorType != "limit_exceeded" {
+		t.Fatalf("run = %s/%s: %s", run.Status, run.ErrorType, run.ErrorMessage)
+	}
+	events, err := data.ListSessionEvents(context.Background(), "ses_limits", 0, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := events[len(events)-1]
+	decoded, err := api.DecodeSessionEventData(api.SessionEventType(last.Type), last.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := decoded.(*api.RunEventData); !ok || last.Type != "session.run.failed" || data.StopReason != "max_duration" || data.ErrorType != "limit_exceeded" {
+		t.Fatalf("terminal event = %s %s", last.Type, last.Data)
+	}
+}
@@ -0,0 +1,215 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/coder/websocket"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/permission"
+	"github.com/chaserensberger/wingman/store"
+	"github.com/chaserensberger/wingman/terminal"
+)
+
+// handleSessionTerminal upgrades to a WebSocket attached to the session's
+// terminal, starting a shell in the session's workdir if none is running.
+// Binary frames carry raw input and output; text frames carry
+// api.TerminalMessage control messages.
+func (s *Server) handleSessionTerminal(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	sess, ok := s.authorizeSessionForRequest(w, r, id)
+	if !ok {
+		return
+	}
+	if sess.WorkDir == "" {
+		s.writeError(w, http.StatusBadRequest, "session has no working directory")
+		return
+	}
+	query := r.URL.Query()
+	cols, err := terminalDimension(query.Get("cols"), terminal.DefaultCols)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "cols "+err.Error())
+		return
+	}
+	rows, err := terminalDimension(query.Get("rows"), terminal.DefaultRows)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "rows "+err.Error())
+		return
+	}
+	var share *bool
+	if raw := query.Get("share_with_agent"); raw != "" {
+		shared, err := strconv.ParseBool(raw)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "share_with_agent must be a boolean")
+			return
+		}
+		share = &shared
+	}
+	// The session's own agent always applies; a client cannot pick a more
+	// permissive one.
+	agent, err := s.sessionAgent(r.Context(), sess)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if agentID := query.Get("agent_id"); agentID != "" && (agent == nil || agent.ID != agentID) {
+		s.writeError(w, http.StatusBadRequest, "agent_id does not match the session's agent")
+		return
+	}
+	// The shell runs anything, so it needs the same standing as an agent's
+	// unrestricted bash command. Nothing prompts for the commands typed into
+	// it, so ask rules forbid it like deny rules do.
+	layers, err := s.permissionLayers(r.Context(), agent, sess.WorkspaceID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if evaluation := evaluatePermissionLayers("bash", "*", layers); evaluation.Effect != permission.EffectAllow {
+		s.writeError(w, http.StatusForbidden, fmt.Sprintf("terminal denied by %s permission rules for bash (%s)", evaluation.Source, evaluation.Effect))
+		return
+	}
+
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		// Accept has already written the response.
+		return
+	}
+	defer conn.CloseNow()
+	term, err := s.terminals.Open(terminal.Options{SessionID: id, WorkDir: sess.WorkDir, Cols: cols, Rows: rows})
+	if err != nil {
+		s.logger.Warn("open session terminal", "session_id", id, "error", err)
+		_ = conn.Close(websocket.StatusInternalError, "open terminal failed")
+		return
+	}
+	if share != nil {
+		term.SetShared(*share)
+	}
+	s.serveTerminal(r.Context(), conn, term)
+}
+
+// sessionAgent returns the agent of the session's latest run, with its
+// current definition when it still exists, or nil before the first run.
+func (s *Server) sessionAgent(ctx context.Context, sess *store.Session) (*store.Agent, error) {
+	runs, err := s.store.ListSessionRuns(ctx, sess.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, nil
+	}
+	snapshot := runs[len(runs)-1].Agent
+	if snapshot.ID != "" {
+		if current, _, err := s.lookupAgent(ctx, sess.WorkDir, snapshot.ID); err == nil {
+			return current, nil
+		}
+	}
+	return &snapshot, nil
+}
+
+func (s *Server) serveTerminal(ctx context.Context, conn *websocket.Conn, term *terminal.Terminal) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(s.shutdownCtx, cancel)
+	defer stop()
+
+	viewer, scrollback := term.Attach()
+	defer viewer.Detach()
+	go func() {
+		defer cancel()
+		for {
+			typ, data, err := conn.Read(ctx)
+			if err != nil {
+				return
+			}
+			if err := handleTerminalInput(term, typ, data); err != nil {
+				_ = conn.Close(websocket.StatusPolicyViolation, err.Error())
+				return
+			}
+		}
+	}()
+
+	if len(scrollback) > 0 {
+		if err := conn.Write(ctx, websocket.MessageBinary, scrollback); err != nil {
+			return
+		}
+	}
+	for {
+		select {
+		case chunk, ok := <-viewer.Output():
+			if !ok {
+				s.closeTerminalConn(ctx, conn, term)
+				return
+			}
+			if err := conn.Write(ctx, websocket.MessageBinary, chunk); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			_ = conn.Close(websocket.StatusGoingAway, "terminal detached")
+			return
+		}
+	}
+}
+
+// closeTerminalConn ends a viewer whose output stopped, reporting the exit
+// code when the shell exited rather than the viewer falling behind.
+func (s *Server) closeTerminalConn(ctx context.Context, conn *websocket.Conn, term *terminal.Terminal) {
+	if term.Running() {
+		_ = conn.Close(websocket.StatusTryAgainLater, "terminal output fell behind")
+		return
+	}
+	code := term.ExitCode()
+	msg, _ := json.Marshal(api.TerminalMessage{Type: api.TerminalMessageExit, ExitCode: &code})
+	_ = conn.Write(ctx, websocket.MessageText, msg)
+	_ = conn.Close(websocket.StatusNormalClosure, "terminal exited")
+}
+
+func handleTerminalInput(term *terminal.Terminal, typ websocket.MessageType, data []byte) error {
+	if typ == websocket.MessageBinary {
+		return term.Write(data)
+	}
+	var msg api.TerminalMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return errors.New("invalid terminal message")
+	}
+	switch msg.Type {
+	case api.TerminalMessageInput:
+		return term.Write([]byte(msg.Data))
+	case api.TerminalMessageResize:
+		if msg.Cols <= 0 || msg.Rows <= 0 || msg.Cols > maxTerminalDimension || msg.Rows > maxTerminalDimension {
+			return fmt.Errorf("terminal size must be between 1 and %d", maxTerminalDimension)
+		}
+		return term.Resize(uint16(msg.Cols), uint16(msg.Rows))
+	case api.TerminalMessageShare:
+		if msg.Shared == nil {
+			return errors.New("share message requires shared")
+		}
+		term.SetShared(*msg.Shared)
+		return nil
+	default:
+		return fmt.Errorf("unsupported terminal message type %q", msg.Type)
+	}
+}
+
+// maxTerminalDimension bounds the columns and rows a client may request.
+const maxTerminalDimension = 1000
+
+func terminalDimension(raw string, fallback uint16) (uint16, error) {
+	if raw == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 || n > maxTerminalDimension {
+		return 0, fmt.Errorf("must be an integer between 1 and %d", maxTerminalDimension)
+	}
+	return uint16(n), nil
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/permission"
+	"github.com/chaserensberger/wingman/store"
+	"github.com/chaserensberger/wingman/store/memory"
+	"github.com/chaserensberger/wingman/terminal"
+)
+
+func newTerminalTestServer(t *testing.T, permissions permission.Ruleset) *Server {
+	t.Helper()
+	t.Setenv("SHELL", "sh")
+	data := memory.NewStore()
+	client, err := data.EnsureDefaultClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := data.CreateSession(&store.Session{ID: "ses_terminal", ClientID: client.ID, WorkDir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+	server := New(Config{Store: data, Permissions: permissions})
+	t.Cleanup(func() { _ = server.Close(context.Background()) })
+	return server
+}
+
+func readTerminal(ctx context.Context, t *testing.T, conn *websocket.Conn, want string) {
+	t.Helper()
+	var got strings.Builder
+	for !strings.Contains(got.String(), want) {
+		typ, data, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatalf("read waiting for %q: %v; got %q", want, err, got.String())
+		}
+		if typ == websocket.MessageBinary {
+			got.Write(data)
+		}
+	}
+}
+
+func TestSessionTerminalWebSocket(t *testing.T) {
+	server := newTerminalTestServer(t, nil)
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/sessions/ses_terminal/terminal?cols=100&rows=40&share_with_agent=true"
+
+	first, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.CloseNow()
+	if err := first.Write(ctx, websocket.MessageBinary, []byte("echo size=$(stty size)\n")); err != nil {
+		t.Fatal(err)
+	}
+	readTerminal(ctx, t, first, "size=40 100")
+
+	// A second viewer attaches to the same shell and sees its scrollback.
+	second, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.CloseNow()
+	readTerminal(ctx, t, second, "size=40 100")
+	resize, _ := json.Marshal(api.TerminalMessage{Type: api.TerminalMessageResize, Cols: 120, Rows: 30})
+	if err := second.Write(ctx, websocket.MessageText, resize); err != nil {
+		t.Fatal(err)
+	}
+	input, _ := json.Marshal(api.TerminalMessage{Type: api.TerminalMessageInput, Data: "echo resized=$(stty size)\n"})
+	if err := second.Write(ctx, websocket.MessageText, input); err != nil {
+		t.Fatal(err)
+	}
+	readTerminal(ctx, t, first, "resized=30 120")
+	readTerminal(ctx, t, second, "resized=30 120")
+	if got := server.terminals.Context("ses_terminal"); !strings.Contains(got, "resized=30 120") {
+		t.Fatalf("agent context = %q", got)
+	}
+
+	if err := first.Write(ctx, websocket.MessageBinary, []byte("exit 4\n")); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		typ, data, err := second.Read(ctx)
+		if err != nil {
+			t.Fatalf("read exit message: %v", err)
+		}
+		if typ != websocket.MessageText {
+			continue
+		}
+		var msg api.TerminalMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type != api.TerminalMessageExit || msg.ExitCode == nil || *msg.ExitCode != 4 {
+			t.Fatalf("exit message = %s", data)
+		}
+		break
+	}
+}
+
+func TestSessionTerminalRespectsPermissions(t *testing.T) {
+	server := newTerminalTestServer(t, permission.Ruleset{{Action: "bash", Resource: "*", Effect: permission.EffectDeny}})
+	response := httptest.NewRecorder()
+	server.router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/sessions/ses_terminal/terminal", nil))
+	if response.Code != http.StatusForbidden || !strings.Contains(response.Body.String(), "config permission rules") {
+		t.Fatalf("terminal = %d: %s", response.Code, response.Body.String())
+	}
+
+	response = httptest.NewRecorder()
+	server.router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/sessions/ses_terminal/terminal?cols=0", nil))
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("invalid size = %d: %s", response.Code, response.Body.String())
+	}
+}
+
+func TestSessionTerminalTreatsAskAsForbidden(t *testing.T) {
+	server := newTerminalTestServer(t, permission.Ruleset{{Action: "bash", Resource: "*", Effect: permission.EffectAsk}})
+	response := httptest.NewRecorder()
+	server.router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/sessions/ses_terminal/terminal", nil))
+	if response.Code != http.StatusForbidden || !strings.Contains(response.Body.String(), "(ask)") {
+		t.Fatalf("terminal = %d: %s", response.Code, response.Body.String())
+	}
+}
+
+func TestSessionTerminalDefaultsToSessionAgentAndClosesOnMove(t *testing.T) {
+	server := newTerminalTestServer(t, nil)
+	ctx := context.Background()
+	agent := &store.Agent{Name: "locked", Permissions: permission.Ruleset{{Action: "bash", Resource: "*", Effect: permission.EffectDeny}}}
+	if err := server.store.CreateAgent(agent); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.store.AdmitSessionRun(ctx, store.SessionRun{ID: "run_terminal", SessionID: "ses_terminal", Agent: *agent}); err != nil {
+		t.Fatal(err)
+	}
+	response := httptest.NewRecorder()
+	server.router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/sessions/ses_terminal/terminal", nil))
+	if response.Code != http.StatusForbidden || !strings.Contains(response.Body.String(), "agent permission rules") {
+		t.Fatalf("terminal = %d: %s", response.Code, response.Body.String())
+	}
+
+	// Naming a more permissive agent does not bypass the session's own.
+	open := &store.Agent{Name: "open"}
+	if err := server.store.CreateAgent(open); err != nil {
+		t.Fatal(err)
+	}
+	response = httptest.NewRecorder()
+	server.router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/sessions/ses_terminal/terminal?agent_id="+open.ID, nil))
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("terminal with another agent = %d: %s", response.Code, response.Body.String())
+	}
+
+	sess, err := server.store.GetSession("ses_terminal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	term, err := server.terminals.Open(terminal.Options{SessionID: sess.ID, WorkDir: sess.WorkDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"working_directory":` + strconv.Quote(t.TempDir()) + `,"expected_version":` + strconv.FormatInt(sess.AggregateVersion, 10) + `}`
+	response = httptest.NewRecorder()
+	server.router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/sessions/ses_terminal/move", strings.NewReader(body)))
+	if response.Code != http.StatusOK {
+		t.Fatalf("move = %d: %s", response.Code, response.Body.String())
+	}
+	select {
+	case <-term.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("moving the session left its terminal running")
+	}
+}
@@ -56,6 +56,21 @@ func (s *Server) toolCatalog(scope *execution.Scope) (*tool.Registry, []toolCata
 	for _, native := range execution.BuiltinTools() {
 		add(native, catalogItem(native, "native"))
 	}
+	if scope != nil && scope.Processes() != nil {
+		for _, t := range scope.Processes().Tools() {
+			add(t, catalogItem(t, "native"))
+		}
+	}
+	if scope != nil && scope.Symbols() != nil {
+		for _, t := range scope.Symbols().Tools() {
+			add(t, catalogItem(t, "native"))
+		}
+	}
+	if scope != nil && scope.Search() != nil {
+		for _, t := range scope.Search().Tools() {
+			add(t, catalogItem(t, "native"))
+		}
+	}
 
 	if scope != nil && scope.Plugins() != nil {
 		owners := map[string]string{}
@@ -120,7 +120,11 @@ func (s *Server) handleCreateWorkspace(w http.ResponseWriter, r *http.Request) {
 		s.writeError(w, http.StatusBadRequest, "name is required when no directory is set")
 		return
 	}
-	workspace := &store.Workspace{Name: name, Path: path}
+	if err := s.validateCredentialSelection(req.Credentials); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	workspace := &store.Workspace{Name: name, Path: path, Credentials: req.Credentials}
 	clientID, err := s.resolveClientID(r)
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err.Error())
@@ -197,6 +201,16 @@ func (s *Server) handleUpdateWorkspace(w http.ResponseWriter, r *http.Request) {
 		}
 		workspace.Path = path
 	}
+	if req.Credentials != nil {
+		if err := s.validateCredentialSelection(req.Credentials); err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		workspace.Credentials = req.Credentials
+		if len(workspace.Credentials) == 0 {
+			workspace.Credentials = nil
+		}
+	}
 	if workspace.Name == "" {
 		s.writeError(w, http.StatusBadRequest, "name is required")
 		return
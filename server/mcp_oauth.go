@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/chaserensberger/wingman/api"
+	wingmcp "github.com/chaserensberger/wingman/mcp"
+	"github.com/chaserensberger/wingman/store"
+)
+
+// MCPTokenStore adapts the OAuth credentials stored for remote MCP servers to
+// the token store MCP managers refresh before connecting. It returns nil
+// without a store.
+func MCPTokenStore(data store.Store) wingmcp.TokenStore {
+	if data == nil {
+		return nil
+	}
+	return mcpTokenStore{store: data}
+}
+
+type mcpTokenStore struct {
+	store store.Store
+}
+
+func (t mcpTokenStore) LoadToken(server string) (wingmcp.Token, bool, error) {
+	auth, err := t.store.GetAuth()
+	if err != nil {
+		return wingmcp.Token{}, false, err
+	}
+	credential, ok := auth.MCP[server]
+	if !ok {
+		return wingmcp.Token{}, false, nil
+	}
+	return wingmcp.Token{
+		AccessToken: credential.Access, RefreshToken: credential.Refresh, ExpiresAt: credential.ExpiresAt,
+		ClientID: credential.ClientID, ClientSecret: credential.ClientSecret, TokenURL: credential.TokenURL,
+		Resource: credential.Resource, Scope: credential.Scope,
+	}, true, nil
+}
+
+func (t mcpTokenStore) SaveToken(server string, token wingmcp.Token) error {
+	return t.store.SetMCPCredential(server, mcpCredential(token))
+}
+
+func mcpCredential(token wingmcp.Token) store.AuthCredential {
+	return store.AuthCredential{
+		Type: "oauth", Access: token.AccessToken, Refresh: token.RefreshToken, ExpiresAt: token.ExpiresAt,
+		ClientID: token.ClientID, ClientSecret: token.ClientSecret, TokenURL: token.TokenURL,
+		Resource: token.Resource, Scope: token.Scope,
+	}
+}
+
+// startMCP begins a browser authorization for a remote MCP server. Its
+// loopback callback listens on an ephemeral port that is registered with the
+// server's authorization server for this attempt only.
+func (m *oauthManager) startMCP(servers *wingmcp.Manager, name string, connected func()) (oauthAttemptDTO, error) {
+	if err := m.open(); err != nil {
+		return oauthAttemptDTO{}, err
+	}
+	ctx, cancel := context.WithTimeout(m.root, 5*time.Minute)
+	attempt := &oauthAttempt{id: randomValue(18), provider: "mcp/" + name, method: "browser", status: "pending", cancel: cancel}
+	m.mu.Lock()
+	for _, existing := range m.attempts {
+		if existing.provider == attempt.provider && existing.status == "pending" {
+			m.mu.Unlock()
+			cancel()
+			return oauthAttemptDTO{}, fmt.Errorf("an OAuth attempt for MCP server %s is already in progress", name)
+		}
+	}
+	m.attempts[attempt.id] = attempt
+	m.mu.Unlock()
+
+	if err := m.startMCPBrowser(ctx, attempt, servers, name, connected); err != nil {
+		m.finish(attempt.id, "failed", err)
+		return oauthAttemptDTO{}, err
+	}
+	return m.status(attempt.id)
+}
+
+func (m *oauthManager) startMCPBrowser(ctx context.Context, attempt *oauthAttempt, servers *wingmcp.Manager, name string, connected func()) error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("start OAuth callback listener: %w", err)
+	}
+	redirectURI := "http://" + listener.Addr().String() + "/callback"
+	authorization, err := servers.Authorize(ctx, name, redirectURI)
+	if err != nil {
+		_ = listener.Close()
+		return err
+	}
+	verifier := randomValue(48)
+	state := randomValue(32)
+
+	m.mu.Lock()
+	attempt.verifier = verifier
+	attempt.state = state
+	attempt.url = authorization.AuthCodeURL(state, verifier)
+	attempt.instructions = "Complete authorization in your browser. This window will close automatically."
+	m.mu.Unlock()
+
+	h := m.callbackHandler(attempt, "/callback", state, name, func(ctx context.Context, code string) (func() error, error) {
+		token, err := authorization.Exchange(ctx, code, verifier)
+		if err != nil {
+			return nil, err
+		}
+		return func() error { return m.store.SetMCPCredential(name, mcpCredential(token)) }, nil
+	}, connected)
+	return m.serveCallback(ctx, attempt, listener, h)
+}
+
+func (s *Server) handleAuthMCP(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	manager, release, ok := s.mcpManager(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+	name := chi.URLParam(r, "name")
+	attempt, err := s.oauth.startMCP(manager, name, func() { s.reconnectMCP(name) })
+	if err != nil {
+		s.writeError(w, mcpErrorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, attempt)
+}
+
+func (s *Server) handleMCPAuthStatus(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "attempt")
+	attempt, err := s.oauth.status(id)
+	if err != nil || !s.oauth.owns(id, "mcp/"+chi.URLParam(r, "name")) {
+		s.writeError(w, http.StatusNotFound, "OAuth attempt not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, attempt)
+}
+
+func (s *Server) handleMCPAuthCancel(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "attempt")
+	if !s.oauth.owns(id, "mcp/"+chi.URLParam(r, "name")) || s.oauth.cancel(id) != nil {
+		s.writeError(w, http.StatusNotFound, "OAuth attempt not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, api.StatusResponse{Status: "cancelled"})
+}
+
+// handleLogoutMCP forgets a server's stored token and disconnects it so no
+// session keeps using the revoked authorization.
+func (s *Server) handleLogoutMCP(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	manager, release, ok := s.mcpManager(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+	name := chi.URLParam(r, "name")
+	if err := manager.Disconnect(name); err != nil {
+		s.writeError(w, mcpErrorStatus(err), err.Error())
+		return
+	}
+	if err := s.store.DeleteMCPCredential(name); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, api.StatusResponse{Status: "deleted"})
+}
+
+// reconnectMCP reconnects a server in the directoryless scope once its new
+// token is saved. Other scopes use the token on their next connection.
+func (s *Server) reconnectMCP(name string) {
+	ctx, cancel := context.WithTimeout(s.oauth.root, time.Minute)
+	defer cancel()
+	scope, release, err := s.executionScope(ctx, "")
+	if err != nil {
+		s.logger.Warn("reconnect MCP server after authorization", "server", name, "error", err)
+		return
+	}
+	defer release()
+	if scope == nil || scope.MCP() == nil {
+		return
+	}
+	if err := scope.MCP().Connect(ctx, name); err != nil {
+		s.logger.Warn("reconnect MCP server after authorization", "server", name, "error", err)
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/chaserensberger/wingman/execution"
+	wingmcp "github.com/chaserensberger/wingman/mcp"
+	provider "github.com/chaserensberger/wingman/models/providers"
+	"github.com/chaserensberger/wingman/store/memory"
+)
+
+func TestMCPAuthStoresTokenAndReconnects(t *testing.T) {
+	// The remote server has no discovery metadata, so it acts as its own
+	// authorization server at the default endpoints.
+	protected := mcpsdk.NewStreamableHTTPHandler(func(*http.Request) *mcpsdk.Server {
+		return mcpsdk.NewServer(&mcpsdk.Implementation{Name: "docs", Version: "test"}, nil)
+	}, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer docs-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		protected.ServeHTTP(w, r)
+	})
+	mux.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"client_id":"wingman-client"}`)
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		redirect, _ := url.Parse(r.URL.Query().Get("redirect_uri"))
+		redirect.RawQuery = url.Values{"code": {"code"}, "state": {r.URL.Query().Get("state")}}.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("code") != "code" || r.Form.Get("code_verifier") == "" {
+			http.Error(w, "bad code", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		_, _ = io.WriteString(w, `{"access_token":"docs-token","refresh_token":"refresh","expires_in":3600}`)
+	})
+	remote := httptest.NewServer(mux)
+	defer remote.Close()
+
+	data := memory.NewStore()
+	registry, err := provider.NewRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scopes, err := execution.NewManager(execution.Config{
+		Providers:      registry,
+		DisablePlugins: true,
+		MCP:            map[string]wingmcp.ServerConfig{"docs": {Type: "remote", URL: remote.URL + "/mcp"}},
+		MCPTokens:      MCPTokenStore(data),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = scopes.Close() })
+	server := New(Config{Store: data, Scopes: scopes})
+
+	response := httptest.NewRecorder()
+	server.router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/mcp/docs/auth", nil))
+	if response.Code != http.StatusAccepted {
+		t.Fatalf("authorize status = %d: %s", response.Code, response.Body.String())
+	}
+	var attempt oauthAttemptDTO
+	if err := json.Unmarshal(response.Body.Bytes(), &attempt); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(attempt.URL, remote.URL+"/authorize?") {
+		t.Fatalf("attempt = %#v", attempt)
+	}
+
+	// Following the authorization URL plays the browser through the callback.
+	page, err := http.Get(attempt.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(page.Body)
+	page.Body.Close()
+	if page.StatusCode != http.StatusOK || !strings.Contains(string(body), "docs connected") {
+		t.Fatalf("callback page = %d: %s", page.StatusCode, body)
+	}
+	auth, err := data.GetAuth()
+	if err != nil || auth.MCP["docs"].Access != "docs-token" || auth.MCP["docs"].ClientID != "wingman-client" {
+		t.Fatalf("auth = %#v, error = %v", auth, err)
+	}
+
+	connected := false
+	for deadline := time.Now().Add(5 * time.Second); !connected && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		response = httptest.NewRecorder()
+		server.router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/mcp", nil))
+		connected = strings.Contains(response.Body.String(), `"status":"connected"`)
+	}
+	if !connected {
+		t.Fatalf("server did not reconnect: %s", response.Body.String())
+	}
+
+	response = httptest.NewRecorder()
+	server.router.ServeHTTP(response, httptest.NewRequest(http.MethodDelete, "/mcp/docs/auth", nil))
+	if response.Code != http.StatusOK {
+		t.Fatalf("logout status = %d: %s", response.Code, response.Body.String())
+	}
+	if auth, err := data.GetAuth(); err != nil || len(auth.MCP) != 0 {
+		t.Fatalf("auth after logout = %#v, error = %v", auth, err)
+	}
+}
@@ -0,0 +1,381 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/chaserensberger/wingman/agent/session"
+	"github.com/chaserensberger/wingman/api"
+	"github.com/chaserensberger/wingman/models"
+	"github.com/chaserensberger/wingman/store"
+)
+
+const (
+	mcpServerPath = "/mcp-server"
+	// mcpSessionURIPrefix names Wingman sessions exposed as MCP resources.
+	mcpSessionURIPrefix = "wingman://sessions/"
+	// mcpRunPollInterval bounds how long an agent tool call relies on live
+	// events alone before re-reading its run.
+	mcpRunPollInterval = time.Second
+)
+
+type mcpClientContextKey struct{}
+
+type mcpAskInput struct {
+	Message          string `json:"message"`
+	SessionID        string `json:"session_id,omitempty"`
+	WorkingDirectory string `json:"working_directory,omitempty"`
+}
+
+type mcpAskOutput struct {
+	SessionID string `json:"session_id"`
+	RunID     string `json:"run_id"`
+	Status    string `json:"status"`
+}
+
+// handleMCPServer serves Wingman itself over the MCP streamable HTTP
+// transport. The MCP session is bound to the client resolved when it
+// initializes.
+func (s *Server) handleMCPServer(w http.ResponseWriter, r *http.Request) {
+	if s.Ephemeral() {
+		s.ephemeralNotImplemented(w)
+		return
+	}
+	clientID, err := s.resolveClientID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mcpHTTP.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), mcpClientContextKey{}, clientID)))
+}
+
+func (s *Server) mcpServerForRequest(r *http.Request) *mcpsdk.Server {
+	clientID, _ := r.Context().Value(mcpClientContextKey{}).(string)
+	server, err := s.newMCPServer(clientID)
+	if err != nil {
+		s.logger.Error("build MCP server", "client_id", clientID, "error", err)
+		return nil
+	}
+	return server
+}
+
+// newMCPServer snapshots the stored agents into tools and prompts. Agents
+// created later appear when the MCP client reconnects.
+func (s *Server) newMCPServer(clientID string) (*mcpsdk.Server, error) {
+	agents, err := s.store.ListAgents()
+	if err != nil {
+		return nil, fmt.Errorf("list agents: %w", err)
+	}
+	sessions, err := s.store.ListSessionsByClient(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	version := s.version
+	if version == "" {
+		version = "dev"
+	}
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "wingman", Version: version}, nil)
+
+	names := make(map[string]bool, len(agents))
+	for _, agent := range agents {
+		name := mcpAgentName(agent, names)
+		server.AddTool(&mcpsdk.Tool{
+			Name:        "ask_" + name,
+			Title:       "Ask " + agent.Name,
+			Description: fmt.Sprintf("Ask the %s agent to work on a task in a Wingman session and return its reply. Pass session_id to continue an earlier conversation.", agent.Name),
+			InputSchema: mcpAskInputSchema,
+		}, func(ctx context.Context, req *mcpsdk.CallToolRequest) (*mcpsdk.CallToolResult, error) {
+			return s.callMCPAgentTool(ctx, server, req, clientID, agent), nil
+		})
+		server.AddPrompt(&mcpsdk.Prompt{
+			Name:        name,
+			Title:       agent.Name,
+			Description: fmt.Sprintf("Instructions for the %s agent.", agent.Name),
+			Arguments:   []*mcpsdk.PromptArgument{{Name: "task", Description: "Task to append to the instructions"}},
+		}, func(_ context.Context, req *mcpsdk.GetPromptRequest) (*mcpsdk.GetPromptResult, error) {
+			text := agent.Instructions
+			if task := strings.TrimSpace(req.Params.Arguments["task"]); task != "" {
+				text = strings.TrimSpace(text + "\n\nTask: " + task)
+			}
+			return &mcpsdk.GetPromptResult{
+				Description: fmt.Sprintf("Instructions for the %s agent.", agent.Name),
+				Messages:    []*mcpsdk.PromptMessage{{Role: "user", Content: &mcpsdk.TextContent{Text: text}}},
+			}, nil
+		})
+	}
+
+	read := func(ctx context.Context, req *mcpsdk.ReadResourceRequest) (*mcpsdk.ReadResourceResult, error) {
+		return s.readMCPSessionResource(ctx, clientID, req.Params.URI)
+	}
+	for _, sess := range sessions {
+		server.AddResource(mcpSessionResource(sess), read)
+	}
+	server.AddResourceTemplate(&mcpsdk.ResourceTemplate{
+		URITemplate: mcpSessionURIPrefix + "{id}",
+		Name:        "session",
+		Description: "A Wingman session with its message history",
+		MIMEType:    "application/json",
+	}, read)
+	return server, nil
+}
+
+var mcpAskInputSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"message":           map[string]any{"type": "string", "description": "Task or message for the agent"},
+		"session_id":        map[string]any{"type": "string", "description": "Existing session to continue; omit to start a new session"},
+		"working_directory": map[string]any{"type": "string", "description": "Working directory for a new session"},
+	},
+	"required": []string{"message"},
+}
+
+// mcpAgentName derives a unique MCP-safe name from an agent's display name.
+func mcpAgentName(agent *store.Agent, taken map[string]bool) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(agent.Name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "_"):
+			b.WriteByte('_')
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "_")
+	if name == "" || taken[name] {
+		name = strings.ToLower(agent.ID)
+	}
+	taken[name] = true
+	return name
+}
+
+func mcpSessionResource(sess *store.Session) *mcpsdk.Resource {
+	return &mcpsdk.Resource{
+		URI:         mcpSessionURIPrefix + sess.ID,
+		Name:        sess.ID,
+		Title:       sess.Title,
+		Description: sess.WorkDir,
+		MIMEType:    "application/json",
+	}
+}
+
+func (s *Server) readMCPSessionResource(ctx context.Context, clientID, uri string) (*mcpsdk.ReadResourceResult, error) {
+	id, ok := strings.CutPrefix(uri, mcpSessionURIPrefix)
+	if !ok || id == "" {
+		return nil, mcpsdk.ResourceNotFoundError(uri)
+	}
+	sess, err := s.store.GetSession(id)
+	if err != nil || sess.ClientID != clientID {
+		return nil, mcpsdk.ResourceNotFoundError(uri)
+	}
+	history, err := s.sessionHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	latestCall, err := s.store.LatestModelCall(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(apiSessionDetail(sess, history, latestCall))
+	if err != nil {
+		return nil, err
+	}
+	return &mcpsdk.ReadResourceResult{Contents: []*mcpsdk.ResourceContents{{URI: uri, MIMEType: "application/json", Text: string(data)}}}, nil
+}
+
+// callMCPAgentTool admits the message through the session run queue and
+// waits for the run to settle. Failures are tool results rather than
+// protocol errors so the calling model can read them.
+func (s *Server) callMCPAgentTool(ctx context.Context, server *mcpsdk.Server, req *mcpsdk.CallToolRequest, clientID string, agent *store.Agent) *mcpsdk.CallToolResult {
+	var input mcpAskInput
+	if len(req.Params.Arguments) > 0 {
+		if err := json.Unmarshal(req.Params.Arguments, &input); err != nil {
+			return mcpToolError(fmt.Errorf("invalid arguments: %w", err))
+		}
+	}
+	if strings.TrimSpace(input.Message) == "" {
+		return mcpToolError(errors.New("message is required"))
+	}
+
+	var sess *store.Session
+	if input.SessionID != "" {
+		existing, err := s.store.GetSession(input.SessionID)
+		if err != nil || existing.ClientID != clientID {
+			return mcpToolError(fmt.Errorf("session not found: %s", input.SessionID))
+		}
+		sess = existing
+	} else {
+		workDir, _, err := s.resolveSessionLocation(clientID, input.WorkingDirectory, "")
+		if err != nil {
+			return mcpToolError(err)
+		}
+		sess = &store.Session{Title: defaultSessionTitle, WorkDir: workDir, ClientID: clientID}
+		if err := s.store.CreateSession(sess); err != nil {
+			return mcpToolError(err)
+		}
+		server.AddResource(mcpSessionResource(sess), func(ctx context.Context, req *mcpsdk.ReadResourceRequest) (*mcpsdk.ReadResourceResult, error) {
+			return s.readMCPSessionResource(ctx, clientID, req.Params.URI)
+		})
+	}
+
+	admission, _, err := s.admitSessionMessage(ctx, sess, api.MessageSessionRequest{Message: input.Message, AgentID: agent.ID})
+	if err != nil {
+		return mcpToolError(err)
+	}
+	run, err := s.awaitMCPRun(ctx, req.Session, agent, sess.ID, admission.Run.ID)
+	if err != nil {
+		return mcpToolError(err)
+	}
+	output := mcpAskOutput{SessionID: sess.ID, RunID: run.ID, Status: run.Status}
+	if run.Status != store.SessionRunStatusCompleted {
+		message := fmt.Sprintf("run %s %s", run.ID, run.Status)
+		if run.ErrorMessage != "" {
+			message += ": " + run.ErrorMessage
+		}
+		result := mcpToolError(errors.New(message))
+		result.StructuredContent = output
+		return result
+	}
+	reply, err := s.runReply(ctx, sess.ID, run.ID)
+	if err != nil {
+		return mcpToolError(err)
+	}
+	return &mcpsdk.CallToolResult{
+		Content:           []mcpsdk.Content{&mcpsdk.TextContent{Text: reply}},
+		StructuredContent: output,
+	}
+}
+
+func mcpToolError(err error) *mcpsdk.CallToolResult {
+	return &mcpsdk.CallToolResult{IsError: true, Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: err.Error()}}}
+}
+
+// awaitMCPRun waits for a run to reach a terminal status. Permission requests
+// raised by the run are forwarded to the MCP client as elicitations when it
+// supports them; otherwise they wait for a reply from another client.
+func (s *Server) awaitMCPRun(ctx context.Context, mcpSession *mcpsdk.ServerSession, agent *store.Agent, sessionID, runID string) (*store.SessionRun, error) {
+	sub, unsubscribe := s.events.subscribe(sessionID)
+	defer unsubscribe()
+	ticker := time.NewTicker(mcpRunPollInterval)
+	defer ticker.Stop()
+	overflow := sub.overflow
+	elicited := map[string]bool{}
+	for {
+		current, err := s.store.GetSessionRun(ctx, sessionID, runID)
+		if err != nil {
+			return nil, err
+		}
+		switch current.Status {
+		case store.SessionRunStatusCompleted, store.SessionRunStatusFailed, store.SessionRunStatusAborted:
+			return current, nil
+		}
+		if mcpSupportsElicitation(mcpSession) {
+			if err := s.elicitMCPPermissions(ctx, mcpSession, agent, sessionID, runID, elicited); err != nil {
+				return nil, err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-sub.done:
+			return nil, fmt.Errorf("session closed: %s", sessionID)
+		case <-overflow:
+			overflow = nil
+		case <-sub.events:
+		case <-ticker.C:
+		}
+	}
+}
+
+func mcpSupportsElicitation(ss *mcpsdk.ServerSession) bool {
+	if ss == nil {
+		return false
+	}
+	params := ss.InitializeParams()
+	return params != nil && params.Capabilities != nil && params.Capabilities.Elicitation != nil
+}
+
+var mcpPermissionSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"response": map[string]any{"type": "string", "enum": []string{store.PermissionResponseOnce, store.PermissionResponseAlways, store.PermissionResponseReject}, "description": "Allow once, always allow in this session, or reject"},
+		"message":  map[string]any{"type": "string", "description": "Explanation returned to the agent when rejecting"},
+	},
+	"required": []string{"response"},
+}
+
+// elicitMCPPermissions asks the MCP client about each pending request of the
+// run once. A cancelled elicitation leaves the request pending.
+func (s *Server) elicitMCPPermissions(ctx context.Context, ss *mcpsdk.ServerSession, agent *store.Agent, sessionID, runID string, elicited map[string]bool) error {
+	requests, err := s.store.ListPermissionRequests(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	for _, request := range requests {
+		if request.RunID != runID || request.Status != store.PermissionRequestStatusPending || elicited[request.ID] {
+			continue
+		}
+		elicited[request.ID] = true
+		result, err := ss.Elicit(ctx, &mcpsdk.ElicitParams{
+			Message:         fmt.Sprintf("The %s agent wants to %s %s.", agent.Name, request.Action, strings.Join(request.Resources, ", ")),
+			RequestedSchema: mcpPermissionSchema,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.logger.Warn("elicit MCP permission", "session_id", sessionID, "request_id", request.ID, "error", err)
+			continue
+		}
+		var reply api.PermissionReplyRequest
+		switch result.Action {
+		case "accept":
+			reply.Response, _ = result.Content["response"].(string)
+			if reply.Response == store.PermissionResponseReject {
+				reply.Message, _ = result.Content["message"].(string)
+			}
+		case "decline":
+			reply.Response = store.PermissionResponseReject
+		default:
+			continue
+		}
+		if _, status, err := s.replyPermissionRequest(ctx, sessionID, request.ID, reply); err != nil && status != http.StatusConflict {
+			s.logger.Warn("reply MCP permission", "session_id", sessionID, "request_id", request.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// runReply returns the text of the last assistant message the run produced.
+func (s *Server) runReply(ctx context.Context, sessionID, runID string) (string, error) {
+	stored, err := s.store.ListMessages(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+	var reply string
+	for _, sm := range stored {
+		if sm.RunID != runID || sm.Role != string(models.RoleAssistant) {
+			continue
+		}
+		msg, err := session.StoredMessageToModel(sm)
+		if err != nil {
+			return "", fmt.Errorf("unmarshal message: %w", err)
+		}
+		var b strings.Builder
+		for _, part := range msg.Content {
+			if text, ok := part.(models.TextPart); ok {
+				b.WriteString(text.Text)
+			}
+		}
+		if b.Len() > 0 {
+			reply = b.String()
+		}
+	}
+	return reply, nil
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/chaserensberger/wingman/agent/run"
+	"github.com/chaserensberger/wingman/models"
+	"github.com/chaserensberger/wingman/store"
+	"github.com/chaserensberger/wingman/store/memory"
+)
+
+func TestMCPServerAgentToolRunsThroughSessionQueue(t *testing.T) {
+	data := memory.NewStore()
+	owner, err := data.EnsureDefaultClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := &store.Agent{
+		ID:           "agt_mcp_build",
+		Name:         "Build",
+		Instructions: "Build things.",
+		ModelRef:     "test/model",
+		Options: map[string]any{agentOptionModelRoute: models.ModelInfo{
+			Provider: "test",
+			ID:       "model",
+			API:      models.APIOpenAICompatible,
+			BaseURL:  "http://127.0.0.1:1",
+		}},
+	}
+	if err := data.CreateAgent(agent); err != nil {
+		t.Fatal(err)
+	}
+	// The admission store never claims runs, so the test plays the worker.
+	server := New(Config{Store: &admissionTestStore{Store: data}})
+	mcpServer, err := server.newMCPServer(owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientTransport, serverTransport := mcpsdk.NewInMemoryTransports()
+	if _, err := mcpServer.Connect(context.Background(), serverTransport, nil); err != nil {
+		t.Fatal(err)
+	}
+	elicitations := make(chan string, 1)
+	client := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "editor", Version: "test"}, &mcpsdk.ClientOptions{
+		ElicitationHandler: func(_ context.Context, req *mcpsdk.ElicitRequest) (*mcpsdk.ElicitResult, error) {
+			elicitations <- req.Params.Message
+			return &mcpsdk.ElicitResult{Action: "accept", Content: map[string]any{"response": "once"}}, nil
+		},
+	})
+	cs, err := client.Connect(context.Background(), clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	tools, err := cs.ListTools(context.Background(), nil)
+	if err != nil || len(tools.Tools) != 1 || tools.Tools[0].Name != "ask_build" {
+		t.Fatalf("tools = %#v, error = %v", tools, err)
+	}
+	prompt, err := cs.GetPrompt(context.Background(), &mcpsdk.GetPromptParams{Name: "build", Arguments: map[string]string{"task": "ship it"}})
+	if err != nil || len(prompt.Messages) != 1 || prompt.Messages[0].Content.(*mcpsdk.TextContent).Text != "Build things.\n\nTask: ship it" {
+		t.Fatalf("prompt = %#v, error = %v", prompt, err)
+	}
+
+	results := make(chan *mcpsdk.CallToolResult, 1)
+	go func() {
+		result, err := cs.CallTool(context.Background(), &mcpsdk.CallToolParams{Name: "ask_build", Arguments: map[string]any{"message": "compile"}})
+		if err != nil {
+			t.Error(err)
+		}
+		results <- result
+	}()
+
+	var sess *store.Session
+	deadline := time.Now().Add(time.Second)
+	for sess == nil && time.Now().Before(deadline) {
+		if sessions, _ := data.ListSessionsByClient(owner.ID); len(sessions) == 1 {
+			sess = sessions[0]
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if sess == nil {
+		t.Fatal("agent tool did not create a session")
+	}
+	var claimed store.SessionRunTransition
+	for claimed.Run.ID == "" && time.Now().Before(deadline) {
+		if claimed, err = data.ClaimNextSessionRun(context.Background(), sess.ID); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if claimed.Run.ID == "" || claimed.Run.Message != "compile" || claimed.Run.Agent.ID != agent.ID {
+		t.Fatalf("claimed = %#v", claimed.Run)
+	}
+	reply, err := server.permissionRequests.prompter(sess.ID, claimed.Run.ID).Request(context.Background(), run.PermissionRequestInfo{Action: "bash", Resources: []string{"make"}})
+	if err != nil || reply.Response != run.PermissionResponseOnce {
+		t.Fatalf("permission reply = %#v, error = %v", reply, err)
+	}
+	if message := <-elicitations; message != "The Build agent wants to bash make." {
+		t.Fatalf("elicitation = %q", message)
+	}
+	if err := data.SaveMessage(context.Background(), store.StoredMessage{
+		ID: "msg_mcp_reply", SessionID: sess.ID, RunID: claimed.Run.ID, Role: "assistant", Revision: 1, State: "completed",
+		Parts: []store.StoredPart{{ID: "part_mcp_reply", MessageID: "msg_mcp_reply", Kind: "text", PayloadJSON: []byte(`{"type":"text","id":"part_mcp_reply","text":"built"}`)}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := data.SettleSessionRun(context.Background(), store.SessionRunSettlement{ID: claimed.Run.ID, ExpectedStatus: store.SessionRunStatusRunning, Status: store.SessionRunStatusCompleted}); err != nil {
+		t.Fatal(err)
+	}
+
+	var result *mcpsdk.CallToolResult
+	select {
+	case result = <-results:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent tool did not return")
+	}
+	if result == nil || result.IsError || result.Content[0].(*mcpsdk.TextContent).Text != "built" {
+		t.Fatalf("result = %#v", result)
+	}
+	output, _ := json.Marshal(result.StructuredContent)
+	if !strings.Contains(string(output), `"session_id":"`+sess.ID+`"`) {
+		t.Fatalf("structured output = %s", output)
+	}
+
+	read, err := cs.ReadResource(context.Background(), &mcpsdk.ReadResourceParams{URI: mcpSessionURIPrefix + sess.ID})
+	if err != nil || len(read.Contents) != 1 || !strings.Contains(read.Contents[0].Text, "built") {
+		t.Fatalf("session resource = %#v, error = %v", read, err)
+	}
+	if _, err := cs.ReadResource(context.Background(), &mcpsdk.ReadResourceParams{URI: mcpSessionURIPrefix + "missing"}); err == nil {
+		t.Fatal("missing session resource was readable")
+	}
+}
+
+func TestMCPServerEndpointRejectsUnknownClient(t *testing.T) {
+	server := New(Config{Store: memory.NewStore()})
+	request := httptest.NewRequest(http.MethodPost, mcpServerPath, strings.NewReader(`{}`))
+	request.Header.Set("X-Wingman-Client", "cli_missing")
+	response := httptest.NewRecorder()
+	server.router.ServeHTTP(response, request)
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d: %s", response.Code, response.Body.String())
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/danielgtaylor/huma/v2/adapters/humachi"
 
 	"github.com/chaserensberger/wingman/api"
+	wingmcp "github.com/chaserensberger/wingman/mcp"
 	"github.com/chaserensberger/wingman/models"
 )
 
@@ -139,6 +140,93 @@ func (s *Server) registerSessionEvents() {
 	s.registerOperation(op, s.handleSessionEvents)
 }
 
+// registerSessionTerminal documents the terminal WebSocket. The protocol is
+// described in the operation; api.TerminalMessage is the text frame schema.
+func (s *Server) registerSessionTerminal() {
+	op := &huma.Operation{
+		Method:      http.MethodGet,
+		Path:        "/sessions/{id}/terminal",
+		OperationID: "openSessionTerminal",
+		Summary:     "Attach to the session terminal",
+		Description: "Upgrades to a WebSocket attached to an interactive shell in the session's working directory. Binary frames carry raw terminal input and output; text frames carry TerminalMessage control messages.",
+		Parameters: append(operationParameters("/sessions/{id}/terminal"),
+			queryParameter("cols", huma.TypeInteger, "Terminal width for a new terminal"),
+			queryParameter("rows", huma.TypeInteger, "Terminal height for a new terminal"),
+			queryParameter("share_with_agent", huma.TypeBoolean, "Share recent terminal output with the agent"),
+			queryParameter("agent_id", huma.TypeString, "Agent whose permission rules apply"),
+		),
+		Responses: map[string]*huma.Response{
+			"101":     {Description: "Switching to the terminal WebSocket", Content: map[string]*huma.MediaType{"application/json": {Schema: schemaFor(s.protocol, api.TerminalMessage{})}}},
+			"default": jsonResponse("Request failed", schemaFor(s.protocol, api.ErrorResponse{})),
+		},
+	}
+	setOperationSecurity(op)
+	s.registerOperation(op, s.handleSessionTerminal)
+}
+
+func (s *Server) registerMCPResourceSubscription() {
+	op := &huma.Operation{
+		Method:      http.MethodGet,
+		Path:        "/mcp/{name}/resources/subscribe",
+		OperationID: "subscribeMCPResource",
+		Summary:     "Stream MCP resource update notifications",
+		Parameters:  append(operationParameters("/mcp/{name}/resources/subscribe"), requiredQueryParameter("uri", "Resource URI")),
+		Responses: map[string]*huma.Response{
+			"200":     streamResponse("MCP resource update stream", schemaFor(s.protocol, wingmcp.ResourceUpdate{})),
+			"default": jsonResponse("Request failed", schemaFor(s.protocol, api.ErrorResponse{})),
+		},
+	}
+	setOperationSecurity(op)
+	s.registerOperation(op, s.handleSubscribeMCPResource)
+}
+
+// registerMCPServer documents the MCP streamable HTTP transport. Bodies are
+// MCP JSON-RPC messages, so the operations describe only the transport.
+func (s *Server) registerMCPServer() {
+	message := &huma.Schema{Type: huma.TypeObject, Description: "MCP JSON-RPC message"}
+	failed := jsonResponse("Request failed", schemaFor(s.protocol, api.ErrorResponse{}))
+	operations := []*huma.Operation{
+		{
+			Method:      http.MethodPost,
+			OperationID: "postMCPServerMessage",
+			Summary:     "Send a message to the Wingman MCP server",
+			RequestBody: &huma.RequestBody{Required: true, Content: map[string]*huma.MediaType{"application/json": {Schema: message}}},
+			Responses: map[string]*huma.Response{
+				"200": {Description: "MCP response", Content: map[string]*huma.MediaType{
+					"application/json":  {Schema: message},
+					"text/event-stream": {Schema: message},
+				}},
+				"202":     {Description: "Notification or response accepted"},
+				"default": failed,
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			OperationID: "streamMCPServerMessages",
+			Summary:     "Stream Wingman MCP server messages",
+			Responses: map[string]*huma.Response{
+				"200":     streamResponse("MCP message stream", message),
+				"default": failed,
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			OperationID: "closeMCPServerSession",
+			Summary:     "Close a Wingman MCP server session",
+			Responses: map[string]*huma.Response{
+				"204":     {Description: "Session closed"},
+				"default": failed,
+			},
+		},
+	}
+	for _, op := range operations {
+		op.Path = mcpServerPath
+		op.Parameters = append(operationParameters(mcpServerPath), &huma.Param{Name: "Mcp-Session-Id", In: "header", Description: "MCP session ID returned by initialize", Schema: &huma.Schema{Type: huma.TypeString}})
+		setOperationSecurity(op)
+		s.registerOperation(op, s.handleMCPServer)
+	}
+}
+
 func (s *Server) registerRunStream() {
 	op := &huma.Operation{
 		Method:      http.MethodPost,
@@ -205,6 +293,10 @@ func queryParameter(name, typ, description string) *huma.Param {
 	return &huma.Param{Name: name, In: "query", Description: description, Schema: &huma.Schema{Type: typ}}
 }
 
+func requiredQueryParameter(name, description string) *huma.Param {
+	return &huma.Param{Name: name, In: "query", Description: description, Required: true, Schema: &huma.Schema{Type: huma.TypeString}}
+}
+
 type eventVariant struct {
 	types []string
 	data  any
@@ -220,6 +312,7 @@ func sessionEventSchema(registry huma.Registry) *huma.Schema {
 		{[]string{"session.tool.called", "session.tool.updated", "session.tool.progress", "session.tool.completed", "session.tool.failed"}, api.ToolEventData{}},
 		{[]string{"session.permission.requested", "session.permission.resolved"}, api.PermissionEventData{}},
 		{[]string{"session.structured_output.completed"}, api.StructuredOutputEventData{}},
+		{[]string{"session.instructions.loaded"}, api.InstructionsEventData{}},
 		{[]string{"session.events.synchronized"}, api.EventsSynchronizedEventData{}},
 		{[]string{"session.events.resync_required"}, api.EventsResyncRequiredEventData{}},
 	}, true)
@@ -239,6 +332,7 @@ func runStreamEventSchema(registry huma.Registry) *huma.Schema {
 		{[]string{"compaction", "context_transformed"}, api.RunContextTransformedEventData{}},
 		{[]string{"error"}, api.RunErrorEventData{}},
 		{[]string{"structured_output"}, api.RunStructuredOutputEventData{}},
+		{[]string{"instructions"}, api.RunInstructionsEventData{}},
 		{[]string{"done"}, api.RunDoneEventData{}},
 	}, false)
 }
@@ -1,9 +1,11 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"sync"
 	"time"
@@ -25,14 +27,21 @@ type permissionRequestManager struct {
 	timeout           time.Duration
 	resolutionTimeout time.Duration
 	mu                sync.Mutex
-	waiters           map[string]chan store.PermissionRequest
+	waiters           map[string]permissionWaiter
+}
+
+// permissionWaiter is a prompt blocked on a reply. validateInput checks
+// reviewer edits against the waiting tool's input schema.
+type permissionWaiter struct {
+	resolved      chan store.PermissionRequest
+	validateInput func(map[string]any) error
 }
 
 func newPermissionRequestManager(server *Server, timeout time.Duration) *permissionRequestManager {
 	if timeout <= 0 {
 		timeout = defaultPermissionTimeout
 	}
-	return &permissionRequestManager{server: server, timeout: timeout, resolutionTimeout: defaultPermissionResolutionTimeout, waiters: make(map[string]chan store.PermissionRequest)}
+	return &permissionRequestManager{server: server, timeout: timeout, resolutionTimeout: defaultPermissionResolutionTimeout, waiters: make(map[string]permissionWaiter)}
 }
 
 func (m *permissionRequestManager) prompter(sessionID, runID string) run.PermissionPrompter {
@@ -44,10 +53,10 @@ type permissionPrompter struct {
 	sessionID, runID string
 }
 
-func (p permissionPrompter) Request(ctx context.Context, info run.PermissionRequestInfo) (run.PermissionResponse, error) {
+func (p permissionPrompter) Request(ctx context.Context, info run.PermissionRequestInfo) (run.PermissionReply, error) {
 	grants, err := p.manager.server.store.ListPermissionGrants(ctx, p.sessionID)
 	if err != nil {
-		return "", err
+		return run.PermissionReply{}, err
 	}
 	granted := make(map[string]bool, len(grants))
 	for _, grant := range grants {
@@ -63,13 +72,13 @@ func (p permissionPrompter) Request(ctx context.Context, info run.PermissionRequ
 		}
 	}
 	if allGranted {
-		return run.PermissionResponseAlways, nil
+		return run.PermissionReply{Response: run.PermissionResponseAlways}, nil
 	}
 
 	requestID := store.NewID(store.PrefixPermissionRequest)
 	waiter := make(chan store.PermissionRequest, 1)
 	p.manager.mu.Lock()
-	p.manager.waiters[requestID] = waiter
+	p.manager.waiters[requestID] = permissionWaiter{resolved: waiter, validateInput: info.ValidateInput}
 	p.manager.mu.Unlock()
 	defer p.manager.removeWaiter(requestID)
 
@@ -78,12 +87,24 @@ func (p permissionPrompter) Request(ctx context.Context, info run.PermissionRequ
 		Action: info.Action, Resources: append([]string(nil), info.Resources...),
 	})
 	if err != nil {
-		return "", err
+		return run.PermissionReply{}, err
 	}
 	if transition.Changed {
 		p.manager.server.events.publish(transition.Event)
 	}
 
+	// A run waiting on a person gives its slot to runs that can proceed.
+	resume := p.manager.server.scheduler.suspend(p.runID)
+	reply, err := p.await(ctx, requestID, waiter)
+	if resumeErr := resume(ctx); resumeErr != nil && err == nil {
+		return run.PermissionReply{}, resumeErr
+	}
+	return reply, err
+}
+
+// await waits for a reply to requestID and records a timeout or an
+// interruption as the request's outcome.
+func (p permissionPrompter) await(ctx context.Context, requestID string, waiter <-chan store.PermissionRequest) (run.PermissionReply, error) {
 	waitCtx, cancel := context.WithTimeout(ctx, p.manager.timeout)
 	defer cancel()
 	select {
@@ -98,7 +119,7 @@ func (p permissionPrompter) Request(ctx context.Context, info run.PermissionRequ
 		request, err := p.manager.resolve(resolveCtx, p.sessionID, requestID, status, "", errorType, errorMessage)
 		resolveCancel()
 		if err != nil {
-			return "", err
+			return run.PermissionReply{}, err
 		}
 		return permissionOutcome(request)
 	case <-p.manager.server.ShutdownCtx().Done():
@@ -106,7 +127,7 @@ func (p permissionPrompter) Request(ctx context.Context, info run.PermissionRequ
 		request, err := p.manager.resolve(resolveCtx, p.sessionID, requestID, store.PermissionRequestStatusInterrupted, "", "permission_interrupted", "permission request interrupted")
 		resolveCancel()
 		if err != nil {
-			return "", err
+			return run.PermissionReply{}, err
 		}
 		return permissionOutcome(request)
 	}
@@ -141,28 +162,47 @@ func (m *permissionRequestManager) resolve(ctx context.Context, sessionID, reque
 
 func (m *permissionRequestManager) notify(request store.PermissionRequest) {
 	m.mu.Lock()
-	waiter := m.waiters[request.ID]
+	waiter, ok := m.waiters[request.ID]
 	m.mu.Unlock()
-	if waiter != nil {
+	if ok {
 		select {
-		case waiter <- request:
+		case waiter.resolved <- request:
 		default:
 		}
 	}
 }
 
-func permissionOutcome(request store.PermissionRequest) (run.PermissionResponse, error) {
+// validateEditedInput checks reviewer edits against the waiting tool's
+// schema. Requests without a live waiter are left to the run, which
+// re-validates edits before execution.
+func (m *permissionRequestManager) validateEditedInput(requestID string, input map[string]any) error {
+	m.mu.Lock()
+	waiter, ok := m.waiters[requestID]
+	m.mu.Unlock()
+	if !ok || waiter.validateInput == nil {
+		return nil
+	}
+	return waiter.validateInput(input)
+}
+
+func permissionOutcome(request store.PermissionRequest) (run.PermissionReply, error) {
 	switch request.Status {
 	case store.PermissionRequestStatusApproved:
-		return run.PermissionResponse(request.Response), nil
+		reply := run.PermissionReply{Response: run.PermissionResponse(request.Response)}
+		if len(request.EditedInput) > 0 {
+			if err := json.Unmarshal(request.EditedInput, &reply.Input); err != nil {
+				return run.PermissionReply{}, fmt.Errorf("decode edited permission input: %w", err)
+			}
+		}
+		return reply, nil
 	case store.PermissionRequestStatusRejected:
-		return run.PermissionResponseReject, nil
+		return run.PermissionReply{Response: run.PermissionResponseReject, Message: request.Message}, nil
 	case store.PermissionRequestStatusTimedOut:
-		return "", context.DeadlineExceeded
+		return run.PermissionReply{}, context.DeadlineExceeded
 	case store.PermissionRequestStatusInterrupted:
-		return "", context.Canceled
+		return run.PermissionReply{}, context.Canceled
 	default:
-		return "", errors.New("permission request is not resolved")
+		return run.PermissionReply{}, errors.New("permission request is not resolved")
 	}
 }
 
@@ -214,37 +254,61 @@ func (s *Server) handleReplyPermissionRequest(w http.ResponseWriter, r *http.Req
 		s.writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
+	resolved, status, err := s.replyPermissionRequest(r.Context(), sessionID, requestID, reply)
+	if err != nil {
+		s.writeError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, apiPermissionRequest(resolved))
+}
+
+// replyPermissionRequest validates and records a reply, then wakes the waiting
+// run. Identical retries succeed. On failure it returns the HTTP status to
+// report.
+func (s *Server) replyPermissionRequest(ctx context.Context, sessionID, requestID string, reply api.PermissionReplyRequest) (store.PermissionRequest, int, error) {
 	status := store.PermissionRequestStatusApproved
 	if reply.Response == store.PermissionResponseReject {
 		status = store.PermissionRequestStatusRejected
 	}
 	if reply.Response != store.PermissionResponseOnce && reply.Response != store.PermissionResponseAlways && reply.Response != store.PermissionResponseReject {
-		s.writeError(w, http.StatusBadRequest, "response must be once, always, or reject")
-		return
+		return store.PermissionRequest{}, http.StatusBadRequest, errors.New("response must be once, always, or reject")
+	}
+	if reply.Message != "" && reply.Response != store.PermissionResponseReject {
+		return store.PermissionRequest{}, http.StatusBadRequest, errors.New("message is only valid with response reject")
 	}
-	transition, err := s.store.ResolvePermissionRequest(r.Context(), store.PermissionRequestResolution{SessionID: sessionID, RequestID: requestID, Status: status, Response: reply.Response})
+	var editedInput json.RawMessage
+	if reply.Input != nil {
+		// An "always" grant remembers the requested resources, which no
+		// longer describe an edited call.
+		if reply.Response != store.PermissionResponseOnce {
+			return store.PermissionRequest{}, http.StatusBadRequest, errors.New("input is only valid with response once")
+		}
+		if err := s.permissionRequests.validateEditedInput(requestID, reply.Input); err != nil {
+			return store.PermissionRequest{}, http.StatusBadRequest, err
+		}
+		var err error
+		if editedInput, err = json.Marshal(reply.Input); err != nil {
+			return store.PermissionRequest{}, http.StatusBadRequest, errors.New("invalid input")
+		}
+	}
+	transition, err := s.store.ResolvePermissionRequest(ctx, store.PermissionRequestResolution{SessionID: sessionID, RequestID: requestID, Status: status, Response: reply.Response, EditedInput: editedInput, Message: reply.Message})
 	if errors.Is(err, store.ErrPermissionRequestNotFound) {
-		s.writeError(w, http.StatusNotFound, err.Error())
-		return
+		return store.PermissionRequest{}, http.StatusNotFound, err
 	}
 	if errors.Is(err, store.ErrPermissionRequestTransitionConflict) {
-		s.writeError(w, http.StatusConflict, err.Error())
-		return
+		return store.PermissionRequest{}, http.StatusConflict, err
 	}
 	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, err.Error())
-		return
+		return store.PermissionRequest{}, http.StatusInternalServerError, err
 	}
 	if !transition.Changed {
-		if transition.Request.Status == status && transition.Request.Response == reply.Response {
+		if transition.Request.Status == status && transition.Request.Response == reply.Response && bytes.Equal(transition.Request.EditedInput, editedInput) && transition.Request.Message == reply.Message {
 			s.permissionRequests.notify(transition.Request)
-			writeJSON(w, http.StatusOK, apiPermissionRequest(transition.Request))
-			return
+			return transition.Request, http.StatusOK, nil
 		}
-		s.writeError(w, http.StatusConflict, "permission request is already resolved")
-		return
+		return store.PermissionRequest{}, http.StatusConflict, errors.New("permission request is already resolved")
 	}
 	s.events.publish(transition.Event)
 	s.permissionRequests.notify(transition.Request)
-	writeJSON(w, http.StatusOK, apiPermissionRequest(transition.Request))
+	return transition.Request, http.StatusOK, nil
 }
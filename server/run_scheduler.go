@@ -0,0 +1,261 @@
+package server
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/chaserensberger/wingman/api"
+)
+
+const (
+	// diagnosticsQueueLimit bounds the waiting runs /diagnostics lists.
+	diagnosticsQueueLimit = 50
+	// maxRunPriority bounds a run's priority either way.
+	maxRunPriority = 100
+)
+
+// SchedulerConfig bounds the session runs a server executes at once. Zero
+// leaves a limit unset.
+type SchedulerConfig struct {
+	// MaxConcurrentRuns bounds runs across all sessions.
+	MaxConcurrentRuns int
+	// MaxRunsPerClient bounds the runs of any one API client.
+	MaxRunsPerClient int
+	// MaxRunsPerProvider bounds runs by the provider of their model, keyed
+	// by provider ID.
+	MaxRunsPerProvider map[string]int
+}
+
+// runScheduler shares run slots among session workers. A worker waits for a
+// slot before it claims its session's next run. Waiting runs start by
+// priority, then round robin across clients, then in arrival order; a run
+// whose client or provider is at its limit is passed over until a slot of
+// that kind frees. A run gives its slot up while it waits for a permission
+// reply and goes ahead of runs that have not started when it takes one back.
+type runScheduler struct {
+	limits SchedulerConfig
+
+	mu         sync.Mutex
+	running    int
+	byClient   map[string]int
+	byProvider map[string]int
+	waiting    []*runTicket
+	// held maps the run ID of each ticket holding a slot to its ticket.
+	held map[string]*runTicket
+	// served records the dispatch turn each client last started a run on,
+	// so clients that waited longest go first among equal priorities.
+	served   map[string]uint64
+	turn     uint64
+	arrivals uint64
+}
+
+// runTicket is one session's claim on a run slot for its next queued run.
+type runTicket struct {
+	sessionID string
+	runID     string
+	clientID  string
+	provider  string
+	priority  int
+	queuedAt  time.Time
+
+	arrival uint64
+	ready   chan struct{}
+	granted bool
+	// resuming marks a started run waiting to take its slot back.
+	resuming bool
+}
+
+func newRunScheduler(limits SchedulerConfig) *runScheduler {
+	limits.MaxRunsPerProvider = maps.Clone(limits.MaxRunsPerProvider)
+	return &runScheduler{
+		limits:     limits,
+		byClient:   map[string]int{},
+		byProvider: map[string]int{},
+		held:       map[string]*runTicket{},
+		served:     map[string]uint64{},
+	}
+}
+
+// acquire waits until ticket may start and returns the function that frees
+// its slot. It fails only when ctx ends first.
+func (s *runScheduler) acquire(ctx context.Context, ticket *runTicket) (release func(), err error) {
+	if err := s.wait(ctx, ticket); err != nil {
+		return nil, err
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.dropLocked(ticket)
+			s.mu.Unlock()
+		})
+	}, nil
+}
+
+// suspend frees the slot runID holds and returns the function that waits
+// to take it back. A run without a slot gets a resume that returns at once.
+func (s *runScheduler) suspend(runID string) (resume func(context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ticket := s.held[runID]
+	if ticket == nil {
+		return func(context.Context) error { return nil }
+	}
+	s.freeLocked(ticket)
+	return func(ctx context.Context) error {
+		s.mu.Lock()
+		ticket.resuming = true
+		s.mu.Unlock()
+		return s.wait(ctx, ticket)
+	}
+}
+
+func (s *runScheduler) wait(ctx context.Context, ticket *runTicket) error {
+	s.mu.Lock()
+	if ticket.arrival == 0 {
+		s.arrivals++
+		ticket.arrival = s.arrivals
+	}
+	ticket.ready = make(chan struct{})
+	s.waiting = append(s.waiting, ticket)
+	s.dispatchLocked()
+	s.mu.Unlock()
+
+	select {
+	case <-ticket.ready:
+		return nil
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropLocked(ticket)
+	return ctx.Err()
+}
+
+// dropLocked frees ticket's slot or removes it from the queue.
+func (s *runScheduler) dropLocked(ticket *runTicket) {
+	if ticket.granted {
+		s.freeLocked(ticket)
+		return
+	}
+	s.waiting = slices.DeleteFunc(s.waiting, func(t *runTicket) bool { return t == ticket })
+}
+
+func (s *runScheduler) freeLocked(ticket *runTicket) {
+	ticket.granted = false
+	delete(s.held, ticket.runID)
+	s.running--
+	decrement(s.byClient, ticket.clientID)
+	decrement(s.byProvider, ticket.provider)
+	s.dispatchLocked()
+}
+
+func (s *runScheduler) dispatchLocked() {
+	for s.limits.MaxConcurrentRuns <= 0 || s.running < s.limits.MaxConcurrentRuns {
+		var next *runTicket
+		for _, ticket := range s.waiting {
+			if s.eligibleLocked(ticket) && (next == nil || s.beforeLocked(ticket, next)) {
+				next = ticket
+			}
+		}
+		if next == nil {
+			return
+		}
+		s.waiting = slices.DeleteFunc(s.waiting, func(t *runTicket) bool { return t == next })
+		s.running++
+		s.byClient[next.clientID]++
+		s.byProvider[next.provider]++
+		s.turn++
+		s.served[next.clientID] = s.turn
+		next.granted, next.resuming = true, false
+		s.held[next.runID] = next
+		close(next.ready)
+	}
+}
+
+func (s *runScheduler) eligibleLocked(ticket *runTicket) bool {
+	if limit := s.limits.MaxRunsPerClient; limit > 0 && s.byClient[ticket.clientID] >= limit {
+		return false
+	}
+	if limit := s.limits.MaxRunsPerProvider[ticket.provider]; limit > 0 && s.byProvider[ticket.provider] >= limit {
+		return false
+	}
+	return true
+}
+
+// beforeLocked reports whether a starts before b.
+func (s *runScheduler) beforeLocked(a, b *runTicket) bool {
+	if a.resuming != b.resuming {
+		return a.resuming
+	}
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	if s.served[a.clientID] != s.served[b.clientID] {
+		return s.served[a.clientID] < s.served[b.clientID]
+	}
+	return a.arrival < b.arrival
+}
+
+// queueLocked returns the waiting tickets in the order they would start if
+// no limit held them back.
+func (s *runScheduler) queueLocked() []*runTicket {
+	queue := slices.Clone(s.waiting)
+	slices.SortFunc(queue, func(a, b *runTicket) int {
+		if s.beforeLocked(a, b) {
+			return -1
+		}
+		if s.beforeLocked(b, a) {
+			return 1
+		}
+		return 0
+	})
+	return queue
+}
+
+// positions returns the 1-based queue position of each waiting run by run ID.
+func (s *runScheduler) positions() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	positions := make(map[string]int, len(s.waiting))
+	for i, ticket := range s.queueLocked() {
+		positions[ticket.runID] = i + 1
+	}
+	return positions
+}
+
+func (s *runScheduler) diagnostics() api.RunSchedulerDiagnostics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := api.RunSchedulerDiagnostics{
+		MaxConcurrentRuns:  s.limits.MaxConcurrentRuns,
+		MaxRunsPerClient:   s.limits.MaxRunsPerClient,
+		MaxRunsPerProvider: maps.Clone(s.limits.MaxRunsPerProvider),
+		RunningRuns:        s.running,
+		RunningByClient:    maps.Clone(s.byClient),
+		RunningByProvider:  maps.Clone(s.byProvider),
+		WaitingRuns:        len(s.waiting),
+		Queue:              []api.RunQueueEntry{},
+	}
+	for i, ticket := range s.queueLocked() {
+		if i == diagnosticsQueueLimit {
+			break
+		}
+		result.Queue = append(result.Queue, api.RunQueueEntry{
+			Position: i + 1, SessionID: ticket.sessionID, RunID: ticket.runID, ClientID: ticket.clientID,
+			Provider: ticket.provider, Priority: ticket.priority, QueuedAt: ticket.queuedAt,
+		})
+	}
+	return result
+}
+
+func decrement(counts map[string]int, key string) {
+	if counts[key] <= 1 {
+		delete(counts, key)
+		return
+	}
+	counts[key]--
+}
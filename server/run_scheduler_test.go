@@ -0,0 +1,283 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chaserensberger/wingman/api"
+	_ "github.com/chaserensberger/wingman/models/providers/mock"
+	"github.com/chaserensberger/wingman/store"
+	"github.com/chaserensberger/wingman/store/memory"
+)
+
+func TestSessionRunWaitsForRunSlotWithQueuePosition(t *testing.T) {
+	data := memory.NewStore()
+	client, err := data.EnsureDefaultClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"ses_slot_busy", "ses_slot_waiting"} {
+		if err := data.CreateSession(&store.Session{ID: id, ClientID: client.ID, WorkDir: t.TempDir()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := data.CreateAgent(&store.Agent{ID: "agt_slot", Name: "Slot", ModelRef: "mock/scripted"}); err != nil {
+		t.Fatal(err)
+	}
+	s := New(Config{Store: data, Scheduler: SchedulerConfig{MaxConcurrentRuns: 1}})
+	t.Cleanup(func() { _ = s.Close(context.Background()) })
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		s.router.ServeHTTP(response, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return response
+	}
+
+	if response := serve(http.MethodPost, "/sessions/ses_slot_busy/message", `{"agent_id":"agt_slot","message":"@mock delay 500ms\n@mock text done"}`); response.Code != http.StatusAccepted {
+		t.Fatalf("busy message = %d: %s", response.Code, response.Body.String())
+	}
+	// The busy run must hold the only slot before the second run queues.
+	deadline := time.Now().Add(time.Second)
+	for scheduler := s.scheduler.diagnostics(); scheduler.RunningRuns != 1; scheduler = s.scheduler.diagnostics() {
+		if time.Now().After(deadline) {
+			t.Fatalf("busy run did not start: %#v", scheduler)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if response := serve(http.MethodPost, "/sessions/ses_slot_waiting/message", `{"agent_id":"agt_slot","message":"hello","priority":101}`); response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "priority must be between -100 and 100") {
+		t.Fatalf("out of range priority = %d: %s", response.Code, response.Body.String())
+	}
+	response := serve(http.MethodPost, "/sessions/ses_slot_waiting/message", `{"agent_id":"agt_slot","message":"hello","priority":2}`)
+	if response.Code != http.StatusAccepted {
+		t.Fatalf("waiting message = %d: %s", response.Code, response.Body.String())
+	}
+	var admitted api.MessageSessionResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &admitted); err != nil {
+		t.Fatal(err)
+	}
+	waitForWaitingRuns(t, s.scheduler, 1)
+
+	var runs []api.SessionRun
+	if err := json.Unmarshal(serve(http.MethodGet, "/sessions/ses_slot_waiting/runs", "").Body.Bytes(), &runs); err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].QueuePosition != 1 || runs[0].Priority != 2 || runs[0].Status != store.SessionRunStatusQueued {
+		t.Fatalf("runs = %#v", runs)
+	}
+	var diagnostics api.DiagnosticsResponse
+	if err := json.Unmarshal(serve(http.MethodGet, "/diagnostics", "").Body.Bytes(), &diagnostics); err != nil {
+		t.Fatal(err)
+	}
+	scheduler := diagnostics.Scheduler
+	if scheduler.MaxConcurrentRuns != 1 || scheduler.RunningRuns != 1 || scheduler.RunningByProvider["mock"] != 1 || scheduler.WaitingRuns != 1 || len(scheduler.Queue) != 1 || scheduler.Queue[0].RunID != admitted.RunID || scheduler.Queue[0].Provider != "mock" {
+		t.Fatalf("scheduler diagnostics = %#v", scheduler)
+	}
+
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		run, err := data.GetSessionRun(context.Background(), "ses_slot_waiting", admitted.RunID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.Status == store.SessionRunStatusCompleted {
+			break
+		}
+		if run.Status == store.SessionRunStatusFailed || time.Now().After(deadline) {
+			t.Fatalf("waiting run = %#v", run)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if reply, err := s.runReply(context.Background(), "ses_slot_waiting", admitted.RunID); err != nil || reply != "You said: hello" {
+		t.Fatalf("reply = %q, error = %v", reply, err)
+	}
+}
+
+func TestRunSchedulerStartsByPriorityThenRoundRobinAcrossClients(t *testing.T) {
+	scheduler := newRunScheduler(SchedulerConfig{MaxConcurrentRuns: 1})
+	ctx := context.Background()
+	release, err := scheduler.acquire(ctx, &runTicket{runID: "run_first", clientID: "cli_a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan string, 4)
+	releases := make(chan func(), 4)
+	for i, ticket := range []*runTicket{
+		{runID: "run_x1", clientID: "cli_x"},
+		{runID: "run_x2", clientID: "cli_x"},
+		{runID: "run_y1", clientID: "cli_y"},
+		{runID: "run_urgent", clientID: "cli_x", priority: 5},
+	} {
+		go func() {
+			release, err := scheduler.acquire(ctx, ticket)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			started <- ticket.runID
+			releases <- release
+		}()
+		waitForWaitingRuns(t, scheduler, i+1)
+	}
+	positions := scheduler.positions()
+	if positions["run_urgent"] != 1 || positions["run_x1"] != 2 || positions["run_x2"] != 3 || positions["run_y1"] != 4 {
+		t.Fatalf("positions = %#v", positions)
+	}
+	diagnostics := scheduler.diagnostics()
+	if diagnostics.RunningRuns != 1 || diagnostics.WaitingRuns != 4 || diagnostics.RunningByClient["cli_a"] != 1 || diagnostics.Queue[0].RunID != "run_urgent" || diagnostics.Queue[0].Position != 1 {
+		t.Fatalf("diagnostics = %#v", diagnostics)
+	}
+
+	// After cli_x starts a run, cli_y's waiting run goes before cli_x's
+	// second one.
+	release()
+	for _, want := range []string{"run_urgent", "run_y1", "run_x1", "run_x2"} {
+		select {
+		case got := <-started:
+			if got != want {
+				t.Fatalf("started %s, want %s", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s did not start", want)
+		}
+		(<-releases)()
+	}
+	if diagnostics := scheduler.diagnostics(); diagnostics.RunningRuns != 0 || diagnostics.WaitingRuns != 0 || len(diagnostics.RunningByClient) != 0 {
+		t.Fatalf("drained diagnostics = %#v", diagnostics)
+	}
+}
+
+func TestRunSchedulerAppliesClientAndProviderLimits(t *testing.T) {
+	scheduler := newRunScheduler(SchedulerConfig{MaxRunsPerClient: 1, MaxRunsPerProvider: map[string]int{"openai": 1}})
+	ctx := context.Background()
+	releaseOpenAI, err := scheduler.acquire(ctx, &runTicket{runID: "run_openai", clientID: "cli_x", provider: "openai"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scheduler.acquire(ctx, &runTicket{runID: "run_anthropic", clientID: "cli_a", provider: "anthropic"}); err != nil {
+		t.Fatal(err)
+	}
+
+	granted := make(chan string, 2)
+	for i, ticket := range []*runTicket{
+		{runID: "run_client_limited", clientID: "cli_a", provider: "anthropic"},
+		{runID: "run_provider_limited", clientID: "cli_b", provider: "openai"},
+	} {
+		go func() {
+			if _, err := scheduler.acquire(ctx, ticket); err == nil {
+				granted <- ticket.runID
+			}
+		}()
+		waitForWaitingRuns(t, scheduler, i+1)
+	}
+	if _, err := scheduler.acquire(ctx, &runTicket{runID: "run_other", clientID: "cli_c", provider: "anthropic"}); err != nil {
+		t.Fatalf("run under every limit waited: %v", err)
+	}
+
+	releaseOpenAI()
+	select {
+	case got := <-granted:
+		if got != "run_provider_limited" {
+			t.Fatalf("started %s, want run_provider_limited", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("provider slot was not handed on")
+	}
+	if positions := scheduler.positions(); len(positions) != 1 || positions["run_client_limited"] != 1 {
+		t.Fatalf("positions = %#v", positions)
+	}
+	if diagnostics := scheduler.diagnostics(); diagnostics.RunningByProvider["openai"] != 1 || diagnostics.RunningByProvider["anthropic"] != 2 || diagnostics.RunningRuns != 3 {
+		t.Fatalf("diagnostics = %#v", diagnostics)
+	}
+}
+
+func TestRunSchedulerCancelledWaitLeavesQueue(t *testing.T) {
+	scheduler := newRunScheduler(SchedulerConfig{MaxConcurrentRuns: 1})
+	release, err := scheduler.acquire(context.Background(), &runTicket{runID: "run_first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := scheduler.acquire(ctx, &runTicket{runID: "run_cancelled"})
+		done <- err
+	}()
+	waitForWaitingRuns(t, scheduler, 1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("acquire error = %v, want canceled", err)
+	}
+	release()
+	if diagnostics := scheduler.diagnostics(); diagnostics.RunningRuns != 0 || diagnostics.WaitingRuns != 0 {
+		t.Fatalf("diagnostics = %#v", diagnostics)
+	}
+}
+
+func TestRunSchedulerSuspendedRunResumesAheadOfWaitingRuns(t *testing.T) {
+	scheduler := newRunScheduler(SchedulerConfig{MaxConcurrentRuns: 1})
+	ctx := context.Background()
+	release, err := scheduler.acquire(ctx, &runTicket{runID: "run_prompting"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan func(), 2)
+	go func() {
+		releaseWaiting, err := scheduler.acquire(ctx, &runTicket{runID: "run_waiting", priority: 5})
+		if err != nil {
+			t.Error(err)
+		}
+		started <- releaseWaiting
+	}()
+	waitForWaitingRuns(t, scheduler, 1)
+
+	resume := scheduler.suspend("run_prompting")
+	releaseWaiting := <-started
+	go func() {
+		waitForWaitingRuns(t, scheduler, 2)
+		releaseWaiting()
+	}()
+	go func() {
+		_, err := scheduler.acquire(ctx, &runTicket{runID: "run_later", priority: 5})
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+	waitForWaitingRuns(t, scheduler, 1)
+	if err := resume(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if diagnostics := scheduler.diagnostics(); diagnostics.RunningRuns != 1 || diagnostics.WaitingRuns != 1 || diagnostics.Queue[0].RunID != "run_later" {
+		t.Fatalf("diagnostics = %#v", diagnostics)
+	}
+	release()
+	release()
+	if diagnostics := scheduler.diagnostics(); diagnostics.RunningRuns != 1 {
+		t.Fatalf("diagnostics after release = %#v", diagnostics)
+	}
+	if resume := scheduler.suspend("run_unknown"); resume(ctx) != nil {
+		t.Fatal("resume of a run without a slot failed")
+	}
+}
+
+func countWaiting(scheduler *runScheduler) int {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	return len(scheduler.waiting)
+}
+
+func waitForWaitingRuns(t *testing.T, scheduler *runScheduler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for countWaiting(scheduler) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("waiting runs = %d, want %d", countWaiting(scheduler), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
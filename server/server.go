@@ -21,14 +21,17 @@ import (
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
 
 	"github.com/chaserensberger/wingman/agent/session"
 	"github.com/chaserensberger/wingman/api"
 	"github.com/chaserensberger/wingman/execution"
 	"github.com/chaserensberger/wingman/internal/observability"
+	wingmcp "github.com/chaserensberger/wingman/mcp"
 	provider "github.com/chaserensberger/wingman/models/providers"
 	"github.com/chaserensberger/wingman/permission"
 	"github.com/chaserensberger/wingman/store"
+	"github.com/chaserensberger/wingman/terminal"
 	consoleui "github.com/chaserensberger/wingman/web/apps/console"
 )
 
@@ -37,8 +40,10 @@ type Server struct {
 	router             *chi.Mux
 	protocol           huma.API
 	runs               *sessionRunManager
+	scheduler          *runScheduler
 	permissionRequests *permissionRequestManager
 	events             *sessionEventBroker
+	mcpHTTP            http.Handler
 	consoleDevURL      string
 	logger             *slog.Logger
 	logs               *observability.LogBuffer
@@ -46,6 +51,7 @@ type Server struct {
 	providers          *provider.Registry
 	permissions        permission.Ruleset
 	agentPermissions   map[string]permission.Ruleset
+	maxToolOutputBytes int
 	oauth              *oauthManager
 	password           string
 	username           string
@@ -66,6 +72,12 @@ type Server struct {
 	inflightMu     sync.Mutex
 	inflightClosed bool
 	inflight       sync.WaitGroup
+
+	// terminals holds the interactive shells opened for remote clients.
+	terminals *terminal.Manager
+
+	// batchPollInterval spaces checks of in-progress provider batches.
+	batchPollInterval time.Duration
 }
 
 type Config struct {
@@ -86,6 +98,14 @@ type Config struct {
 	Username          string
 	InstanceID        string
 	Version           string
+
+	// MaxToolOutputBytes bounds the tool output kept inline in session
+	// transcripts. Larger output is stored as a session artifact. Zero keeps
+	// all output inline.
+	MaxToolOutputBytes int
+	// Scheduler bounds the session runs executing at once. The zero value
+	// sets no limits.
+	Scheduler SchedulerConfig
 }
 
 func New(cfg Config) *Server {
@@ -133,8 +153,13 @@ func New(cfg Config) *Server {
 		shutdownCtx:      ctx,
 		shutdownCancel:   cancel,
 	}
+	s.maxToolOutputBytes = cfg.MaxToolOutputBytes
+	s.batchPollInterval = defaultBatchPollInterval
+	s.terminals = terminal.NewManager()
+	s.scheduler = newRunScheduler(cfg.Scheduler)
 	s.runs = newSessionRunManager(s)
 	s.permissionRequests = newPermissionRequestManager(s, cfg.PermissionTimeout)
+	s.mcpHTTP = mcpsdk.NewStreamableHTTPHandler(s.mcpServerForRequest, nil)
 
 	s.setupMiddleware()
 	s.setupOpenAPI()
@@ -284,6 +309,15 @@ func shouldBypassTimeout(r *http.Request) bool {
 	if path == "/run" {
 		return true
 	}
+	if strings.HasPrefix(path, "/sessions/") && strings.HasSuffix(path, "/terminal") {
+		return true
+	}
+	if strings.HasPrefix(path, "/mcp/") && strings.HasSuffix(path, "/resources/subscribe") {
+		return true
+	}
+	if path == mcpServerPath {
+		return true
+	}
 	return false
 }
 
@@ -298,8 +332,16 @@ func (s *Server) setupRoutes() {
 	s.registerJSON(http.MethodGet, "/mcp", "listMCPServers", "List MCP server status", nil, http.StatusOK, mcpResponse{}, s.handleListMCP)
 	s.registerJSON(http.MethodPost, "/mcp/{name}/connect", "connectMCPServer", "Connect an MCP server", nil, http.StatusOK, mcpResponse{}, s.handleConnectMCP)
 	s.registerJSON(http.MethodPost, "/mcp/{name}/disconnect", "disconnectMCPServer", "Disconnect an MCP server", nil, http.StatusOK, mcpResponse{}, s.handleDisconnectMCP)
-	s.registerErrorOnly(http.MethodPost, "/mcp/{name}/auth", "authorizeMCPServer", "Authorize an MCP server", s.handleAuthMCP)
-	s.registerErrorOnly(http.MethodDelete, "/mcp/{name}/auth", "logoutMCPServer", "Remove MCP authorization", s.handleLogoutMCP)
+	s.registerJSON(http.MethodGet, "/mcp/{name}/resources", "listMCPResources", "List MCP server resources", nil, http.StatusOK, wingmcp.ResourceList{}, s.handleListMCPResources)
+	s.registerJSONWithParameters(http.MethodGet, "/mcp/{name}/resources/read", "readMCPResource", "Read an MCP resource", nil, http.StatusOK, wingmcp.ResourceRead{}, []*huma.Param{requiredQueryParameter("uri", "Resource URI")}, s.handleReadMCPResource)
+	s.registerMCPResourceSubscription()
+	s.registerJSON(http.MethodGet, "/mcp/{name}/prompts", "listMCPPrompts", "List MCP server prompts", nil, http.StatusOK, []wingmcp.Prompt{}, s.handleListMCPPrompts)
+	s.registerJSON(http.MethodPost, "/mcp/{name}/prompts/{prompt}/render", "renderMCPPrompt", "Render an MCP prompt", mcpPromptRenderRequest{}, http.StatusOK, wingmcp.RenderedPrompt{}, s.handleRenderMCPPrompt)
+	s.registerMCPServer()
+	s.registerJSON(http.MethodPost, "/mcp/{name}/auth", "authorizeMCPServer", "Authorize an MCP server", nil, http.StatusAccepted, oauthAttemptDTO{}, s.handleAuthMCP)
+	s.registerJSON(http.MethodDelete, "/mcp/{name}/auth", "logoutMCPServer", "Remove MCP authorization", nil, http.StatusOK, api.StatusResponse{}, s.handleLogoutMCP)
+	s.registerJSON(http.MethodGet, "/mcp/{name}/auth/{attempt}", "getMCPAuthAttempt", "Get MCP OAuth status", nil, http.StatusOK, oauthAttemptDTO{}, s.handleMCPAuthStatus)
+	s.registerJSON(http.MethodDelete, "/mcp/{name}/auth/{attempt}", "cancelMCPAuthAttempt", "Cancel MCP OAuth", nil, http.StatusOK, api.StatusResponse{}, s.handleMCPAuthCancel)
 	s.registerJSON(http.MethodGet, "/tools", "listTools", "List available tools", nil, http.StatusOK, toolCatalogResponse{}, s.handleListTools)
 	s.registerJSON(http.MethodGet, "/catalog", "getModelCatalog", "Get the model catalog", nil, http.StatusOK, CatalogDTO{}, s.handleCatalog)
 	s.registerBinary(http.MethodGet, "/catalog/labs/{id}/logo", "getCatalogLabLogo", "Get a catalog lab logo", "image/svg+xml", s.handleCatalogLabLogo)
@@ -308,6 +350,9 @@ func (s *Server) setupRoutes() {
 	s.registerJSON(http.MethodGet, "/provider/auth", "getProviderAuth", "Get provider credential status", nil, http.StatusOK, ProvidersAuthResponse{}, s.handleGetProvidersAuth)
 	s.registerJSON(http.MethodPut, "/provider/auth", "setProviderAuth", "Set provider credentials", SetProvidersAuthRequest{}, http.StatusOK, api.StatusResponse{}, s.handleSetProvidersAuth)
 	s.registerJSON(http.MethodDelete, "/provider/auth/{provider}", "deleteProviderAuth", "Delete provider credentials", nil, http.StatusOK, api.StatusResponse{}, s.handleDeleteProviderAuth)
+	s.registerJSON(http.MethodGet, "/provider/{name}/credentials", "listProviderCredentials", "List a provider's credentials", nil, http.StatusOK, ProviderCredentialsResponse{}, s.handleListProviderCredentials)
+	s.registerJSON(http.MethodPut, "/provider/{name}/credentials/{credential}", "setProviderCredential", "Set a named provider credential", store.AuthCredential{}, http.StatusOK, api.StatusResponse{}, s.handleSetProviderCredential)
+	s.registerJSON(http.MethodDelete, "/provider/{name}/credentials/{credential}", "deleteProviderCredential", "Delete a named provider credential", nil, http.StatusOK, api.StatusResponse{}, s.handleDeleteProviderCredential)
 	s.registerJSON(http.MethodPost, "/provider/{name}/oauth/authorize", "authorizeProviderOAuth", "Start provider OAuth", providerOAuthRequest{}, http.StatusAccepted, oauthAttemptDTO{}, s.handleProviderOAuthAuthorize)
 	s.registerJSON(http.MethodGet, "/provider/{name}/oauth/{attempt}", "getProviderOAuthAttempt", "Get provider OAuth status", nil, http.StatusOK, oauthAttemptDTO{}, s.handleProviderOAuthStatus)
 	s.registerJSON(http.MethodDelete, "/provider/{name}/oauth/{attempt}", "cancelProviderOAuthAttempt", "Cancel provider OAuth", nil, http.StatusOK, api.StatusResponse{}, s.handleProviderOAuthCancel)
@@ -315,11 +360,26 @@ func (s *Server) setupRoutes() {
 	s.registerJSON(http.MethodGet, "/provider/{name}/models", "listProviderModels", "List provider models", nil, http.StatusOK, map[string]ModelDTO{}, s.handleListProviderModels)
 	s.registerJSON(http.MethodGet, "/provider/{name}/models/{model}", "getProviderModel", "Get a provider model", nil, http.StatusOK, ModelDTO{}, s.handleGetProviderModel)
 
-	s.registerJSON(http.MethodGet, "/agents", "listAgents", "List agents", nil, http.StatusOK, []api.Agent{}, s.handleListAgents)
+	s.registerJSONWithParameters(http.MethodGet, "/agents", "listAgents", "List agents", nil, http.StatusOK, []api.Agent{}, []*huma.Param{queryParameter("working_directory", huma.TypeString, "Project directory whose agent files are listed with stored agents")}, s.handleListAgents)
+	s.registerJSONWithParameters(http.MethodGet, "/agents/files", "listAgentFiles", "List agent files and load errors", nil, http.StatusOK, api.AgentFileList{}, []*huma.Param{requiredQueryParameter("working_directory", "Project directory whose agent files are listed")}, s.handleListAgentFiles)
 	s.registerJSON(http.MethodPost, "/agents", "createAgent", "Create an agent", api.CreateAgentRequest{}, http.StatusCreated, api.Agent{}, s.handleCreateAgent)
-	s.registerJSON(http.MethodGet, "/agents/{id}", "getAgent", "Get an agent", nil, http.StatusOK, api.Agent{}, s.handleGetAgent)
+	s.registerJSONWithParameters(http.MethodGet, "/agents/{id}", "getAgent", "Get an agent", nil, http.StatusOK, api.Agent{}, []*huma.Param{queryParameter("working_directory", huma.TypeString, "Project directory used to resolve file: agent IDs")}, s.handleGetAgent)
 	s.registerJSON(http.MethodPut, "/agents/{id}", "updateAgent", "Update an agent", api.UpdateAgentRequest{}, http.StatusOK, api.Agent{}, s.handleUpdateAgent)
 	s.registerJSON(http.MethodDelete, "/agents/{id}", "deleteAgent", "Delete an agent", nil, http.StatusOK, api.StatusResponse{}, s.handleDeleteAgent)
+	s.registerJSON(http.MethodGet, "/agents/{id}/revisions", "listAgentRevisions", "List agent revisions", nil, http.StatusOK, []api.AgentRevision{}, s.handleListAgentRevisions)
+	s.registerJSON(http.MethodGet, "/agents/{id}/revisions/{revision}", "getAgentRevision", "Get an agent revision", nil, http.StatusOK, api.AgentRevision{}, s.handleGetAgentRevision)
+	s.registerJSON(http.MethodPost, "/agents/{id}/revisions/{revision}/rollback", "rollbackAgent", "Restore an agent revision as the next revision", nil, http.StatusOK, api.Agent{}, s.handleRollbackAgent)
+	s.registerJSON(http.MethodGet, "/agents/{id}/permissions", "getAgentPermissions", "Get persisted agent permission rules", nil, http.StatusOK, api.PermissionRuleset{}, s.handleGetAgentPermissions)
+	s.registerJSON(http.MethodPut, "/agents/{id}/permissions", "updateAgentPermissions", "Replace persisted agent permission rules", api.PermissionRulesetUpdateRequest{}, http.StatusOK, api.PermissionRuleset{}, s.handleUpdateAgentPermissions)
+	s.registerJSON(http.MethodDelete, "/agents/{id}/permissions", "deleteAgentPermissions", "Clear persisted agent permission rules", nil, http.StatusOK, api.PermissionRuleset{}, s.handleDeleteAgentPermissions)
+	s.registerJSON(http.MethodGet, "/agents/{id}/permissions/history", "listAgentPermissionHistory", "List agent permission rule changes", nil, http.StatusOK, []api.PermissionRuleChange{}, s.handleListAgentPermissionHistory)
+
+	s.registerJSONWithParameters(http.MethodGet, "/commands", "listCommands", "List commands", nil, http.StatusOK, []api.Command{}, []*huma.Param{queryParameter("working_directory", huma.TypeString, "Project directory whose command files are listed with stored commands")}, s.handleListCommands)
+	s.registerJSONWithParameters(http.MethodGet, "/commands/files", "listCommandFiles", "List command files and load errors", nil, http.StatusOK, api.CommandFileList{}, []*huma.Param{requiredQueryParameter("working_directory", "Project directory whose command files are listed")}, s.handleListCommandFiles)
+	s.registerJSON(http.MethodPost, "/commands", "createCommand", "Create a command", api.CreateCommandRequest{}, http.StatusCreated, api.Command{}, s.handleCreateCommand)
+	s.registerJSONWithParameters(http.MethodGet, "/commands/{id}", "getCommand", "Get a command", nil, http.StatusOK, api.Command{}, []*huma.Param{queryParameter("working_directory", huma.TypeString, "Project directory used to resolve file: command IDs")}, s.handleGetCommand)
+	s.registerJSON(http.MethodPut, "/commands/{id}", "updateCommand", "Update a command", api.UpdateCommandRequest{}, http.StatusOK, api.Command{}, s.handleUpdateCommand)
+	s.registerJSON(http.MethodDelete, "/commands/{id}", "deleteCommand", "Delete a command", nil, http.StatusOK, api.StatusResponse{}, s.handleDeleteCommand)
 
 	s.registerJSON(http.MethodGet, "/client", "getCurrentClient", "Get the current API client", nil, http.StatusOK, api.Client{}, s.handleGetCurrentClient)
 	s.registerJSON(http.MethodGet, "/clients", "listClients", "List API clients", nil, http.StatusOK, []api.Client{}, s.handleListClients)
@@ -332,6 +392,11 @@ func (s *Server) setupRoutes() {
 	s.registerJSON(http.MethodPut, "/workspaces/{id}", "updateWorkspace", "Update a Workspace", api.UpdateWorkspaceRequest{}, http.StatusOK, api.Workspace{}, s.handleUpdateWorkspace)
 	s.registerJSON(http.MethodDelete, "/workspaces/{id}", "deleteWorkspace", "Delete a Workspace", nil, http.StatusOK, api.StatusResponse{}, s.handleDeleteWorkspace)
 	s.registerJSON(http.MethodGet, "/workspaces/{id}/sessions", "listWorkspaceSessions", "List Workspace sessions", nil, http.StatusOK, []api.Session{}, s.handleListWorkspaceSessions)
+	s.registerJSON(http.MethodGet, "/workspaces/{id}/permissions", "getWorkspacePermissions", "Get persisted Workspace permission rules", nil, http.StatusOK, api.PermissionRuleset{}, s.handleGetWorkspacePermissions)
+	s.registerJSON(http.MethodPut, "/workspaces/{id}/permissions", "updateWorkspacePermissions", "Replace persisted Workspace permission rules", api.PermissionRulesetUpdateRequest{}, http.StatusOK, api.PermissionRuleset{}, s.handleUpdateWorkspacePermissions)
+	s.registerJSON(http.MethodDelete, "/workspaces/{id}/permissions", "deleteWorkspacePermissions", "Clear persisted Workspace permission rules", nil, http.StatusOK, api.PermissionRuleset{}, s.handleDeleteWorkspacePermissions)
+	s.registerJSON(http.MethodGet, "/workspaces/{id}/permissions/history", "listWorkspacePermissionHistory", "List Workspace permission rule changes", nil, http.StatusOK, []api.PermissionRuleChange{}, s.handleListWorkspacePermissionHistory)
+	s.registerJSON(http.MethodPost, "/permissions/evaluate", "evaluatePermission", "Evaluate a permission without prompting", api.PermissionEvaluateRequest{}, http.StatusOK, api.PermissionEvaluation{}, s.handleEvaluatePermission)
 	s.registerJSONWithParameters(http.MethodGet, "/filesystem/directories", "listDirectories", "List filesystem directories", nil, http.StatusOK, directoryListing{}, []*huma.Param{queryParameter("path", huma.TypeString, "Directory to list")}, s.handleListDirectories)
 
 	s.registerJSON(http.MethodPost, "/sessions", "createSession", "Create a session", api.CreateSessionRequest{}, http.StatusCreated, api.Session{}, s.handleCreateSession)
@@ -341,6 +406,7 @@ func (s *Server) setupRoutes() {
 	s.registerJSON(http.MethodGet, "/sessions/{id}/tool-uses", "listSessionToolUses", "List session tool uses", nil, http.StatusOK, []api.ToolUse{}, s.handleListSessionToolUses)
 	s.registerJSON(http.MethodGet, "/sessions/{id}/permission-requests", "listPermissionRequests", "List session permission requests", nil, http.StatusOK, []api.PermissionRequest{}, s.handleListPermissionRequests)
 	s.registerJSON(http.MethodGet, "/sessions/{id}/permission-grants", "listPermissionGrants", "List session permission grants", nil, http.StatusOK, []api.PermissionGrant{}, s.handleListPermissionGrants)
+	s.registerJSON(http.MethodPost, "/sessions/{id}/permission-grants/{grantID}/promote", "promotePermissionGrant", "Promote a session permission grant", api.PermissionGrantPromoteRequest{}, http.StatusOK, api.PermissionRuleset{}, s.handlePromotePermissionGrant)
 	s.registerJSON(http.MethodPost, "/sessions/{id}/permission-requests/{requestID}/reply", "replyPermissionRequest", "Reply to a permission request", api.PermissionReplyRequest{}, http.StatusOK, api.PermissionRequest{}, s.handleReplyPermissionRequest)
 	s.registerJSON(http.MethodPost, "/sessions/{id}/rename", "renameSession", "Rename a session", api.RenameSessionRequest{}, http.StatusOK, api.Session{}, s.handleRenameSession)
 	s.registerJSON(http.MethodPost, "/sessions/{id}/move", "moveSession", "Move a session", api.MoveSessionRequest{}, http.StatusOK, api.Session{}, s.handleMoveSession)
@@ -348,11 +414,22 @@ func (s *Server) setupRoutes() {
 	s.registerSessionEvents()
 	s.registerJSONWithParameters(http.MethodGet, "/sessions/{id}/events/history", "listSessionEvents", "List durable session events", nil, http.StatusOK, api.SessionEventPage{}, []*huma.Param{queryParameter("after", huma.TypeInteger, "Exclusive durable event cursor"), queryParameter("limit", huma.TypeInteger, "Maximum page size")}, s.handleSessionEventsHistory)
 	s.registerJSON(http.MethodPost, "/sessions/{id}/message", "messageSession", "Admit a session message", api.MessageSessionRequest{}, http.StatusAccepted, api.MessageSessionResponse{}, s.handleMessageSession)
+	s.registerJSON(http.MethodPost, "/sessions/{id}/command", "commandSession", "Render a command and admit it as a session message", api.CommandSessionRequest{}, http.StatusAccepted, api.MessageSessionResponse{}, s.handleCommandSession)
+	s.registerJSON(http.MethodGet, "/sessions/{id}/artifacts/{artifactID}", "getSessionArtifact", "Get a session artifact", nil, http.StatusOK, api.Artifact{}, s.handleGetSessionArtifact)
+	s.registerSessionTerminal()
+	s.registerJSON(http.MethodGet, "/sessions/{id}/processes", "listSessionProcesses", "List session background processes", nil, http.StatusOK, []api.Process{}, s.handleListSessionProcesses)
 	s.registerJSON(http.MethodPost, "/sessions/{id}/abort", "abortSession", "Abort active session runs", nil, http.StatusOK, api.AbortSessionResponse{}, s.handleAbortSession)
 	s.registerJSON(http.MethodGet, "/sessions/{id}/runs", "listSessionRuns", "List session runs", nil, http.StatusOK, []api.SessionRun{}, s.handleListSessionRuns)
 	s.registerJSON(http.MethodGet, "/sessions/{id}/runs/{runID}", "getSessionRun", "Get a session run", nil, http.StatusOK, api.SessionRun{}, s.handleGetSessionRun)
 	s.registerJSONStatuses(http.MethodPost, "/sessions/{id}/runs/{runID}/abort", "abortSessionRun", "Abort a session run", nil, map[int]any{http.StatusOK: api.SessionRun{}, http.StatusAccepted: api.SessionRun{}}, s.handleAbortSessionRun)
 
+	s.registerJSON(http.MethodPost, "/batches", "createBatch", "Submit prompts as a provider batch", api.CreateBatchRequest{}, http.StatusCreated, api.Batch{}, s.handleCreateBatch)
+	s.registerJSON(http.MethodGet, "/batches", "listBatches", "List batches", nil, http.StatusOK, []api.Batch{}, s.handleListBatches)
+	s.registerJSON(http.MethodGet, "/batches/{id}", "getBatch", "Get a batch and its items", nil, http.StatusOK, api.Batch{}, s.handleGetBatch)
+	s.registerJSON(http.MethodPost, "/evals", "createEval", "Run an agent over a dataset of eval cases", api.CreateEvalRequest{}, http.StatusAccepted, api.Eval{}, s.handleCreateEval)
+	s.registerJSONWithParameters(http.MethodGet, "/evals", "listEvals", "List evals", nil, http.StatusOK, []api.Eval{}, []*huma.Param{queryParameter("agent_id", huma.TypeString, "Only list evals of this agent")}, s.handleListEvals)
+	s.registerJSON(http.MethodGet, "/evals/{id}", "getEval", "Get an eval and its case results", nil, http.StatusOK, api.Eval{}, s.handleGetEval)
+
 	s.registerRunStream()
 	s.router.NotFound(func(w http.ResponseWriter, _ *http.Request) {
 		s.writeError(w, http.StatusNotFound, "route not found")
@@ -502,10 +579,17 @@ func (s *Server) recoverStartup(ctx context.Context) error {
 			s.events.publish(transition.Event)
 		}
 	}
+	if err := s.failInterruptedEvals(ctx); err != nil {
+		return fmt.Errorf("fail interrupted evals: %w", err)
+	}
+	if err := s.failInterruptedBatches(ctx); err != nil {
+		return fmt.Errorf("fail interrupted batches: %w", err)
+	}
 	if err := s.runs.resumeQueued(ctx); err != nil {
 		return fmt.Errorf("resume queued session runs: %w", err)
 	}
 	s.runs.startReconciler()
+	s.startBatchPoller()
 	return nil
 }
 
@@ -530,6 +614,7 @@ func (s *Server) Close(ctx context.Context) error {
 	if s.oauth != nil {
 		errs = append(errs, s.oauth.Close(ctx))
 	}
+	errs = append(errs, s.terminals.CloseContext(ctx))
 	errs = append(errs, s.waitInflight(ctx))
 	return errors.Join(errs...)
 }
@@ -726,6 +811,25 @@ func (s *Server) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	response.EventSubscribers, response.SubscriberBacklog, response.SubscriberMaxBacklog, response.SubscriberOverflows, response.SubscriberClosures = s.events.diagnostics()
+	response.Scheduler = s.scheduler.diagnostics()
+	response.RateLimits = []api.ProviderRateLimitDiagnostics{}
+	for _, limit := range s.providers.RateLimits() {
+		response.RateLimits = append(response.RateLimits, api.ProviderRateLimitDiagnostics{
+			Provider: limit.Provider, Credential: limit.Credential, RequestsPerMinute: limit.RequestsPerMinute, TokensPerMinute: limit.TokensPerMinute,
+			RemainingRequests: limit.RemainingRequests, RemainingTokens: limit.RemainingTokens,
+			BlockedUntil: limit.BlockedUntil, WaitingRequests: limit.Waiting,
+			ThrottledRequests: limit.ThrottledRequests, ThrottledMS: limit.Throttled.Milliseconds(),
+			RateLimitedResponses: limit.RateLimitedResponses,
+		})
+	}
+	response.Embeddings = []api.EmbeddingUsageDiagnostics{}
+	for _, usage := range s.providers.EmbeddingUsage() {
+		response.Embeddings = append(response.Embeddings, api.EmbeddingUsageDiagnostics{
+			Provider: usage.Provider, Model: usage.Model, Requests: usage.Requests, Inputs: usage.Inputs,
+			Failures: usage.Failures, InputTokens: usage.InputTokens, TotalTokens: usage.TotalTokens,
+			Cost: usage.Cost, LastUsedAt: usage.LastUsed,
+		})
+	}
 	writeJSON(w, http.StatusOK, response)
 }
 
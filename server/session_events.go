@@ -422,6 +422,8 @@ func (s *Server) authorizeSessionForRequest(w http.ResponseWriter, r *http.Reque
 
 func (s *Server) forwardRunEvent(ctx context.Context, sessionID, runID string, e session.StreamEvent) {
 	switch v := e.Data.(type) {
+	case session.InstructionsLoadedEvent:
+		s.persistRunEvent(ctx, sessionID, string(api.SessionEventInstructionsLoaded), api.InstructionsEventData{RunID: runID, Files: apiInstructionFiles(v.Files)})
 	case run.IterationStartEvent:
 		s.persistRunEvent(ctx, sessionID, string(api.SessionEventStepStarted), api.StepEventData{RunID: runID, Step: v.Step})
 	case run.IterationEndEvent:
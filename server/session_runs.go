@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -78,6 +80,31 @@ func (m *sessionRunManager) wake(sessionID string) {
 	go m.drain(sessionID, ctx)
 }
 
+// hold reserves sessionID for a caller that admits, claims, and settles its
+// own run, such as an imported batch result, so no worker drains the run
+// meanwhile. It fails when the session already has a worker.
+func (m *sessionRunManager) hold(sessionID string) (release func(), ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return nil, false
+	}
+	if _, busy := m.active[sessionID]; busy {
+		return nil, false
+	}
+	m.active[sessionID] = func() {}
+	m.done[sessionID] = make(chan struct{})
+	return func() {
+		m.mu.Lock()
+		pending := m.pending[sessionID]
+		m.finishLocked(sessionID)
+		m.mu.Unlock()
+		if pending {
+			m.wake(sessionID)
+		}
+	}, true
+}
+
 func (m *sessionRunManager) reconcile() {
 	defer m.wg.Done()
 	ticker := time.NewTicker(m.reconcileInterval)
@@ -97,8 +124,17 @@ func (m *sessionRunManager) reconcile() {
 func (m *sessionRunManager) drain(sessionID string, ctx context.Context) {
 	defer m.wg.Done()
 	for {
+		release, err := m.schedule(ctx, sessionID)
+		if err != nil {
+			if ctx.Err() == nil {
+				m.server.logger.Error("schedule session run", "session_id", sessionID, "error", err)
+			}
+			m.finish(sessionID)
+			return
+		}
 		transition, err := m.claim(ctx, sessionID)
 		if err != nil {
+			release()
 			m.server.logger.Error("claim session run", "session_id", sessionID, "error", err)
 			m.finish(sessionID)
 			return
@@ -107,6 +143,7 @@ func (m *sessionRunManager) drain(sessionID string, ctx context.Context) {
 			m.server.events.publish(transition.Event)
 		}
 		if transition.Run.ID == "" {
+			release()
 			m.mu.Lock()
 			if m.pending[sessionID] {
 				delete(m.pending, sessionID)
@@ -118,6 +155,7 @@ func (m *sessionRunManager) drain(sessionID string, ctx context.Context) {
 			return
 		}
 		m.execute(ctx, &transition.Run)
+		release()
 		if ctx.Err() != nil {
 			m.finish(sessionID)
 			return
@@ -125,6 +163,26 @@ func (m *sessionRunManager) drain(sessionID string, ctx context.Context) {
 	}
 }
 
+// schedule waits for a run slot for the session's next queued run. With no
+// queued run it returns at once, so the claim that follows finds the queue
+// empty and the worker exits.
+func (m *sessionRunManager) schedule(ctx context.Context, sessionID string) (release func(), err error) {
+	runs, err := m.server.store.ListSessionRuns(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(runs, func(run store.SessionRun) bool { return run.Status == store.SessionRunStatusQueued })
+	if i < 0 {
+		return func() {}, nil
+	}
+	next := runs[i]
+	ticket := &runTicket{sessionID: sessionID, runID: next.ID, clientID: next.ClientID, priority: next.Priority, queuedAt: next.CreatedAt}
+	if ref, ok := models.ParseModelRef(next.Agent.ModelRef); ok {
+		ticket.provider = ref.Provider
+	}
+	return m.server.scheduler.acquire(ctx, ticket)
+}
+
 func (m *sessionRunManager) claim(ctx context.Context, sessionID string) (store.SessionRunTransition, error) {
 	var lastErr error
 	for attempt := 0; attempt < claimRetryLimit; attempt++ {
@@ -219,6 +277,15 @@ func (m *sessionRunManager) execute(workerCtx context.Context, queued *store.Ses
 						err = runCtx.Err()
 					} else {
 						result := stream.Result()
+						if result.StopReason.IsLimit() {
+							// A budget stop is a clean run result, but the request did
+							// not finish, so clients see it as a distinct failure.
+							message := fmt.Sprintf("run stopped at its %s limit", result.StopReason)
+							if m.settle(workerCtx, store.SessionRunSettlement{ID: queued.ID, ExpectedStatus: store.SessionRunStatusRunning, Status: store.SessionRunStatusFailed, ErrorType: "limit_exceeded", ErrorMessage: message, EventData: map[string]any{"error_type": "limit_exceeded", "error_message": message, "stop_reason": result.StopReason, "usage": result.Usage, "steps": result.Steps}}) {
+								m.server.logger.Info("session run stopped at limit", "session_id", queued.SessionID, "run_id", queued.ID, "agent_id", queued.Agent.ID, "stop_reason", result.StopReason, "steps", result.Steps)
+							}
+							return
+						}
 						if m.settle(workerCtx, store.SessionRunSettlement{ID: queued.ID, ExpectedStatus: store.SessionRunStatusRunning, Status: store.SessionRunStatusCompleted, EventData: map[string]any{"usage": result.Usage, "steps": result.Steps}}) {
 							m.server.logger.Info("session run completed", "session_id", queued.SessionID, "run_id", queued.ID, "agent_id", queued.Agent.ID, "steps", result.Steps)
 						}
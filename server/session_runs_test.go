@@ -35,11 +35,11 @@ func TestPermissionRequestManagerResolvesAndRemembersGrant(t *testing.T) {
 		err      error
 	}, 1)
 	go func() {
-		response, err := prompter.Request(context.Background(), run.PermissionRequestInfo{CallID: "call_one", Action: "edit", Resources: []string{"a.go"}})
+		reply, err := prompter.Request(context.Background(), run.PermissionRequestInfo{CallID: "call_one", Action: "edit", Resources: []string{"a.go"}})
 		responses <- struct {
 			response run.PermissionResponse
 			err      error
-		}{response, err}
+		}{reply.Response, err}
 	}()
 	request := waitForPermissionRequest(t, data, "ses_permission")
 	if request.CallID != "call_one" {
@@ -59,9 +59,9 @@ func TestPermissionRequestManagerResolvesAndRemembersGrant(t *testing.T) {
 	if waiters := permissionWaiterCount(server.permissionRequests); waiters != 0 {
 		t.Fatalf("waiters = %d, want 0", waiters)
 	}
-	response, err := prompter.Request(context.Background(), run.PermissionRequestInfo{Action: "edit", Resources: []string{"a.go"}})
-	if err != nil || response != run.PermissionResponseAlways {
-		t.Fatalf("remembered response = %q, %v", response, err)
+	reply, err := prompter.Request(context.Background(), run.PermissionRequestInfo{Action: "edit", Resources: []string{"a.go"}})
+	if err != nil || reply.Response != run.PermissionResponseAlways {
+		t.Fatalf("remembered response = %q, %v", reply.Response, err)
 	}
 	requests, err := data.ListPermissionRequests(context.Background(), "ses_permission")
 	if err != nil || len(requests) != 1 {
@@ -145,11 +145,11 @@ func TestPermissionRequestManagerOnceRejectAndCancellation(t *testing.T) {
 				err      error
 			}, 1)
 			go func() {
-				response, err := server.permissionRequests.prompter("ses_"+test.name, "").Request(context.Background(), run.PermissionRequestInfo{Action: "edit", Resources: []string{"a.go"}})
+				reply, err := server.permissionRequests.prompter("ses_"+test.name, "").Request(context.Background(), run.PermissionRequestInfo{Action: "edit", Resources: []string{"a.go"}})
 				result <- struct {
 					response run.PermissionResponse
 					err      error
-				}{response, err}
+				}{reply.Response, err}
 			}()
 			request := waitForPermissionRequest(t, data, "ses_"+test.name)
 			if _, err := server.permissionRequests.resolve(context.Background(), request.SessionID, request.ID, test.status, test.response, "", ""); err != nil {
@@ -234,11 +234,11 @@ func TestPermissionRequestManagerReplyCancellationRaceHasOneTerminalEvent(t *tes
 		err      error
 	}, 1)
 	go func() {
-		response, err := server.permissionRequests.prompter("ses_cancel_race", "").Request(ctx, run.PermissionRequestInfo{Action: "edit", Resources: []string{"a.go"}})
+		reply, err := server.permissionRequests.prompter("ses_cancel_race", "").Request(ctx, run.PermissionRequestInfo{Action: "edit", Resources: []string{"a.go"}})
 		result <- struct {
 			response run.PermissionResponse
 			err      error
-		}{response, err}
+		}{reply.Response, err}
 	}()
 	request := waitForPermissionRequest(t, data, "ses_cancel_race")
 	done := make(chan struct{})
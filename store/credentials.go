@@ -0,0 +1,138 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// ErrCredentialKeyNotFound reports sealed credentials whose key is not in
+// the configured keyset.
+var ErrCredentialKeyNotFound = errors.New("credential key not found")
+
+// ErrCredentialsSealed reports sealed credentials read without keys.
+var ErrCredentialsSealed = errors.New("stored credentials are encrypted and no credential key is configured")
+
+// sealedPrefix marks an encrypted auth column. Plain columns hold JSON
+// objects, so they never start with it.
+const sealedPrefix = "sealed:v1:"
+
+// CredentialKeys supplies the AES-256 keys that seal stored credentials.
+// New writes use the current key; reads use whichever key sealed the value,
+// so keys can rotate without rewriting the database first.
+type CredentialKeys interface {
+	// Current returns the ID and 32-byte key for new seals.
+	Current() (id string, key []byte, err error)
+	// Lookup returns the key with id, or an error wrapping
+	// ErrCredentialKeyNotFound.
+	Lookup(id string) ([]byte, error)
+}
+
+// sealCredentials encrypts plain under the current key. The column name is
+// authenticated so a sealed value cannot be moved to another column.
+func sealCredentials(keys CredentialKeys, column string, plain []byte) (string, error) {
+	if keys == nil {
+		return string(plain), nil
+	}
+	id, key, err := keys.Current()
+	if err != nil {
+		return "", fmt.Errorf("credential key: %w", err)
+	}
+	if strings.Contains(id, ":") || id == "" {
+		return "", fmt.Errorf("credential key ID %q is invalid", id)
+	}
+	aead, err := credentialAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plain, []byte(column))
+	return sealedPrefix + id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// openCredentials decrypts a column written by sealCredentials and returns
+// the ID of the key that sealed it. Plain JSON is returned as is with an
+// empty key ID.
+func openCredentials(keys CredentialKeys, column, value string) ([]byte, string, error) {
+	rest, ok := strings.CutPrefix(value, sealedPrefix)
+	if !ok {
+		return []byte(value), "", nil
+	}
+	if keys == nil {
+		return nil, "", ErrCredentialsSealed
+	}
+	id, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return nil, "", fmt.Errorf("auth %s: malformed sealed value", column)
+	}
+	key, err := keys.Lookup(id)
+	if err != nil {
+		return nil, "", fmt.Errorf("auth %s: %w", column, err)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, "", fmt.Errorf("auth %s: malformed sealed value: %w", column, err)
+	}
+	aead, err := credentialAEAD(key)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, "", fmt.Errorf("auth %s: malformed sealed value", column)
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(column))
+	if err != nil {
+		return nil, "", fmt.Errorf("auth %s: decrypt with key %s: %w", column, id, err)
+	}
+	return plain, id, nil
+}
+
+func credentialAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("credential key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+const redacted = "[REDACTED]"
+
+// LogValue redacts secrets so a credential passed to slog never leaks them.
+func (c AuthCredential) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("type", c.Type)}
+	for _, secret := range []struct{ name, value string }{
+		{"key", c.Key}, {"access", c.Access}, {"refresh", c.Refresh}, {"client_secret", c.ClientSecret},
+	} {
+		if secret.value != "" {
+			attrs = append(attrs, slog.String(secret.name, redacted))
+		}
+	}
+	if c.AccountID != "" {
+		attrs = append(attrs, slog.String("account_id", c.AccountID))
+	}
+	if c.ClientID != "" {
+		attrs = append(attrs, slog.String("client_id", c.ClientID))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// String redacts secrets so formatting a credential never leaks them.
+func (c AuthCredential) String() string {
+	return c.LogValue().String()
+}
+
+// GoString redacts secrets from %#v.
+func (c AuthCredential) GoString() string {
+	return "store.AuthCredential" + c.String()
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testCredentialKeys struct {
+	current string
+	keys    map[string][]byte
+}
+
+func newTestCredentialKeys(ids ...string) *testCredentialKeys {
+	keys := &testCredentialKeys{current: ids[0], keys: map[string][]byte{}}
+	for _, id := range ids {
+		key := sha256.Sum256([]byte(id))
+		keys.keys[id] = key[:]
+	}
+	return keys
+}
+
+func (k *testCredentialKeys) Current() (string, []byte, error) {
+	return k.current, k.keys[k.current], nil
+}
+
+func (k *testCredentialKeys) Lookup(id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCredentialKeyNotFound, id)
+	}
+	return key, nil
+}
+
+func rawAuthColumns(t *testing.T, data *SQLiteStore) (string, string) {
+	t.Helper()
+	var providers, mcp string
+	err := data.db.QueryRow(`SELECT providers_json, mcp_json FROM auth WHERE id = 1`).Scan(&providers, &mcp)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		t.Fatal(err)
+	}
+	return providers, mcp
+}
+
+func TestSQLiteCredentialsEncryptAtRestAndRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wingman.db")
+	data, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	if err := data.SetAuth(&Auth{Providers: map[string]AuthCredential{"openai": {Type: "api", Key: "sk-plain-secret"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.SetMCPCredential("docs", AuthCredential{Type: "oauth", Refresh: "refresh-secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := newTestCredentialKeys("k1")
+	if resealed, err := data.UseCredentialKeys(keys); err != nil || !resealed {
+		t.Fatalf("migrate plain credentials: resealed = %v, error = %v", resealed, err)
+	}
+	providers, mcp := rawAuthColumns(t, data)
+	for _, column := range []string{providers, mcp} {
+		if !strings.HasPrefix(column, sealedPrefix+"k1:") || strings.Contains(column, "secret") {
+			t.Fatalf("stored column = %q", column)
+		}
+	}
+	if resealed, err := data.UseCredentialKeys(keys); err != nil || resealed {
+		t.Fatalf("second migration: resealed = %v, error = %v", resealed, err)
+	}
+
+	rotated := newTestCredentialKeys("k2", "k1")
+	if err := data.SetAuth(&Auth{Providers: map[string]AuthCredential{"openai": {Type: "api", Key: "sk-rotated"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if resealed, err := data.UseCredentialKeys(rotated); err != nil || !resealed {
+		t.Fatalf("rotate: resealed = %v, error = %v", resealed, err)
+	}
+	providers, mcp = rawAuthColumns(t, data)
+	if !strings.HasPrefix(providers, sealedPrefix+"k2:") || !strings.HasPrefix(mcp, sealedPrefix+"k2:") {
+		t.Fatalf("rotated columns = %q, %q", providers, mcp)
+	}
+	auth, err := data.GetAuth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Providers["openai"].Key != "sk-rotated" || auth.MCP["docs"].Refresh != "refresh-secret" {
+		t.Fatalf("auth = %#v", auth)
+	}
+
+	reopened, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if _, err := reopened.GetAuth(); !errors.Is(err, ErrCredentialsSealed) {
+		t.Fatalf("read without keys: error = %v", err)
+	}
+	if _, err := reopened.UseCredentialKeys(newTestCredentialKeys("k1")); !errors.Is(err, ErrCredentialKeyNotFound) {
+		t.Fatalf("read with a keyset missing the current key: error = %v", err)
+	}
+}
+
+func TestSealedCredentialsAreBoundToTheirColumn(t *testing.T) {
+	keys := newTestCredentialKeys("k1")
+	sealed, err := sealCredentials(keys, "providers_json", []byte(`{"openai":{"type":"api","key":"sk"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := openCredentials(keys, "mcp_json", sealed); err == nil {
+		t.Fatal("credentials sealed for one column opened from another")
+	}
+	plain, id, err := openCredentials(keys, "providers_json", sealed)
+	if err != nil || id != "k1" || !strings.Contains(string(plain), `"sk"`) {
+		t.Fatalf("open = %s, %q, %v", plain, id, err)
+	}
+}
+
+func TestAuthCredentialRedactsSecrets(t *testing.T) {
+	credential := AuthCredential{Type: "oauth", Key: "hidden-key", Access: "hidden-access", Refresh: "hidden-refresh", ClientID: "client", ClientSecret: "hidden-client"}
+	var logs bytes.Buffer
+	slog.New(slog.NewJSONHandler(&logs, nil)).Info("credential", "credential", credential)
+	for _, out := range []string{
+		logs.String(),
+		fmt.Sprint(credential),
+		fmt.Sprintf("%v %+v %#v", credential, credential, credential),
+		fmt.Sprintf("%v", map[string]AuthCredential{"openai": credential}),
+		fmt.Sprintf("%+v", Auth{Providers: map[string]AuthCredential{"openai": credential}}),
+	} {
+		if strings.Contains(out, "hidden") || !strings.Contains(out, redacted) {
+			t.Fatalf("formatted credential leaked or lost its redaction: %s", out)
+		}
+	}
+}
+
+func TestSQLiteNamedProviderCredentialsRoundTripSealed(t *testing.T) {
+	data, err := NewSQLiteStore(filepath.Join(t.TempDir(), "wingman.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	if _, err := data.UseCredentialKeys(newTestCredentialKeys("k1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.SetAuth(&Auth{
+		Providers:           map[string]AuthCredential{"openai": {Type: "api", Key: "sk-default-secret"}},
+		ProviderCredentials: map[string]map[string]AuthCredential{"openai": {"team-b": {Type: "api", Key: "sk-team-secret"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var named string
+	if err := data.db.QueryRow(`SELECT provider_credentials_json FROM auth WHERE id = 1`).Scan(&named); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(named, sealedPrefix+"k1:") || strings.Contains(named, "secret") {
+		t.Fatalf("stored named credentials = %q", named)
+	}
+	auth, err := data.GetAuth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Providers["openai"].Key != "sk-default-secret" || auth.ProviderCredentials["openai"]["team-b"].Key != "sk-team-secret" {
+		t.Fatalf("auth = %#v", auth)
+	}
+
+	workspace := &Workspace{Name: "team", Credentials: map[string]string{"openai": "team-b"}}
+	if err := data.CreateWorkspace(workspace); err != nil {
+		t.Fatal(err)
+	}
+	got, err := data.GetWorkspace(workspace.ID)
+	if err != nil || got.Credentials["openai"] != "team-b" {
+		t.Fatalf("workspace = %#v, %v", got, err)
+	}
+	got.Credentials = nil
+	if err := data.UpdateWorkspace(got); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = data.GetWorkspace(workspace.ID); err != nil || got.Credentials != nil {
+		t.Fatalf("cleared workspace = %#v, %v", got, err)
+	}
+}
@@ -8,17 +8,17 @@ func DefaultAgents() []*Agent {
 		{
 			Name:         "Build",
 			Instructions: buildAgentInstructions,
-			Tools:        []string{"read", "grep", "glob", "write", "edit", "bash", "webfetch", "websearch"},
+			Tools:        []string{"read", "grep", "glob", "write", "edit", "bash", "process_start", "process_list", "process_read", "process_write", "process_signal", "git_status", "git_diff", "git_log", "git_commit", "git_branch", "symbol_outline", "symbol_definition", "symbol_references", "webfetch", "websearch", "read_artifact"},
 		},
 		{
 			Name:         "Plan",
 			Instructions: planAgentInstructions,
-			Tools:        []string{"read", "grep", "glob", "webfetch", "websearch"},
+			Tools:        []string{"read", "grep", "glob", "git_status", "git_diff", "git_log", "symbol_outline", "symbol_definition", "symbol_references", "webfetch", "websearch", "read_artifact"},
 		},
 		{
 			Name:         "Wingston",
 			Instructions: wingstonAgentInstructions,
-			Tools:        []string{"webfetch", "websearch"},
+			Tools:        []string{"webfetch", "websearch", "read_artifact"},
 		},
 	}
 }
@@ -86,7 +86,7 @@ For medical, legal, and financial questions, give the person the substantive inf
 
 const planAgentInstructions = `You are Wingman's planning agent.
 
-Your job is to understand the user's goal, inspect relevant context, and produce a clear plan. You must not modify files or make system changes. Use only read-only tools such as read, grep, glob, webfetch, and websearch.
+Your job is to understand the user's goal, inspect relevant context, and produce a clear plan. You must not modify files or make system changes. Use only read-only tools such as read, grep, glob, git_status, git_diff, git_log, the symbol tools, webfetch, and websearch.
 
 Before planning, gather enough context to avoid guessing. Surface assumptions and tradeoffs. Ask a concise clarifying question when the right plan depends on information you cannot infer safely.
 
@@ -98,6 +98,6 @@ You are a pragmatic software engineer. Inspect the relevant code first, then mak
 
 You may be in a dirty worktree. Never revert, overwrite, or modify changes you did not make unless the user explicitly asks. If unrelated changes exist, ignore them. If they directly conflict with the task, stop and ask how to proceed.
 
-Use tools deliberately. Prefer read, grep, and glob for codebase inspection. Use write and edit for file changes. Use bash for commands such as builds, tests, package scripts, and git inspection. Avoid destructive commands unless explicitly requested.
+Use tools deliberately. Prefer read, grep, and glob for codebase inspection, and the symbol tools to outline files and find where code is defined and used. Use write and edit for file changes. Use the git tools to inspect changes, commit, and manage branches, and bash for builds, tests, package scripts, and other commands. Use process_start for dev servers, watchers, and other commands that keep running, and stop them with process_signal when you are done. Avoid destructive commands unless explicitly requested.
 
 Verify meaningful changes when feasible. Report what changed, what you ran, and anything that could not be verified.`
@@ -8,12 +8,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/chaserensberger/wingman/permission"
 	"github.com/chaserensberger/wingman/store"
 )
 
@@ -22,6 +24,8 @@ import (
 type Store struct {
 	mu                 sync.RWMutex
 	agents             map[string]*store.Agent
+	agentRevisions     map[string][]store.AgentRevision
+	commands           map[string]*store.Command
 	sessions           map[string]*store.Session
 	clients            map[string]*store.Client
 	workspaces         map[string]*store.Workspace
@@ -29,8 +33,13 @@ type Store struct {
 	parts              map[string]*store.StoredPart
 	modelCalls         map[string]*store.ModelCall
 	toolUses           map[string]*store.ToolUse
+	artifacts          map[string]*store.Artifact
+	batches            map[string]*store.Batch
+	evals              map[string]*store.Eval
 	permissionRequests map[string]*store.PermissionRequest
 	permissionGrants   map[string]*store.PermissionGrant
+	permissionRulesets map[permissionScopeKey]store.PermissionRuleset
+	permissionChanges  map[permissionScopeKey][]store.PermissionRuleChange
 	events             map[string]*store.SessionEvent
 	aggregates         map[store.AggregateRef][]store.AggregateEvent
 	globalSeq          int64
@@ -42,6 +51,8 @@ type Store struct {
 func NewStore() *Store {
 	return &Store{
 		agents:             make(map[string]*store.Agent),
+		agentRevisions:     make(map[string][]store.AgentRevision),
+		commands:           make(map[string]*store.Command),
 		sessions:           make(map[string]*store.Session),
 		clients:            make(map[string]*store.Client),
 		workspaces:         make(map[string]*store.Workspace),
@@ -49,8 +60,13 @@ func NewStore() *Store {
 		parts:              make(map[string]*store.StoredPart),
 		modelCalls:         make(map[string]*store.ModelCall),
 		toolUses:           make(map[string]*store.ToolUse),
+		artifacts:          make(map[string]*store.Artifact),
+		batches:            make(map[string]*store.Batch),
+		evals:              make(map[string]*store.Eval),
 		permissionRequests: make(map[string]*store.PermissionRequest),
 		permissionGrants:   make(map[string]*store.PermissionGrant),
+		permissionRulesets: make(map[permissionScopeKey]store.PermissionRuleset),
+		permissionChanges:  make(map[permissionScopeKey][]store.PermissionRuleChange),
 		events:             make(map[string]*store.SessionEvent),
 		aggregates:         make(map[store.AggregateRef][]store.AggregateEvent),
 		runs:               make(map[string]*store.SessionRun),
@@ -366,6 +382,10 @@ func copyAgent(a *store.Agent) *store.Agent {
 	copy(cp.Tools, a.Tools)
 	cp.Options = deepCopyMap(a.Options)
 	cp.OutputSchema = deepCopyMap(a.OutputSchema)
+	if a.Limits != nil {
+		limits := *a.Limits
+		cp.Limits = &limits
+	}
 	return &cp
 }
 
@@ -397,6 +417,7 @@ func copyWorkspace(workspace *store.Workspace) *store.Workspace {
 		return nil
 	}
 	cp := *workspace
+	cp.Credentials = maps.Clone(workspace.Credentials)
 	return &cp
 }
 
@@ -450,11 +471,23 @@ func copyToolUse(use *store.ToolUse) store.ToolUse {
 func copyPermissionRequest(request *store.PermissionRequest) store.PermissionRequest {
 	cp := *request
 	cp.Resources = append([]string(nil), request.Resources...)
+	cp.EditedInput = append(json.RawMessage(nil), request.EditedInput...)
 	return cp
 }
 
 func copyPermissionGrant(grant *store.PermissionGrant) store.PermissionGrant { return *grant }
 
+func copyPermissionRuleset(ruleset store.PermissionRuleset) store.PermissionRuleset {
+	ruleset.Rules = append(permission.Ruleset{}, ruleset.Rules...)
+	return ruleset
+}
+
+func copyPermissionRuleChange(change store.PermissionRuleChange) store.PermissionRuleChange {
+	change.Before = append(permission.Ruleset{}, change.Before...)
+	change.After = append(permission.Ruleset{}, change.After...)
+	return change
+}
+
 func copySessionEvent(e *store.SessionEvent) store.SessionEvent {
 	cp := *e
 	if e.DataJSON != nil {
@@ -481,6 +514,21 @@ func copyAuth(a *store.Auth) *store.Auth {
 	for k, v := range a.Providers {
 		cp.Providers[k] = v
 	}
+	if len(a.MCP) > 0 {
+		cp.MCP = make(map[string]store.AuthCredential, len(a.MCP))
+		for k, v := range a.MCP {
+			cp.MCP[k] = v
+		}
+	}
+	for provider, named := range a.ProviderCredentials {
+		if len(named) == 0 {
+			continue
+		}
+		if cp.ProviderCredentials == nil {
+			cp.ProviderCredentials = make(map[string]map[string]store.AuthCredential, len(a.ProviderCredentials))
+		}
+		cp.ProviderCredentials[provider] = maps.Clone(named)
+	}
 	return cp
 }
 
@@ -496,8 +544,10 @@ func (s *Store) CreateAgent(agent *store.Agent) error {
 	now := store.Now()
 	agent.CreatedAt = now
 	agent.UpdatedAt = now
+	agent.Revision = 1
 
 	s.agents[agent.ID] = copyAgent(agent)
+	s.appendAgentRevisionLocked(agent)
 	return nil
 }
 
@@ -537,10 +587,47 @@ func (s *Store) UpdateAgent(agent *store.Agent) error {
 
 	agent.UpdatedAt = store.Now()
 	agent.CreatedAt = existing.CreatedAt
+	agent.Revision = existing.Revision + 1
 	s.agents[agent.ID] = copyAgent(agent)
+	s.appendAgentRevisionLocked(agent)
 	return nil
 }
 
+func (s *Store) appendAgentRevisionLocked(agent *store.Agent) {
+	s.agentRevisions[agent.ID] = append(s.agentRevisions[agent.ID], store.AgentRevision{
+		AgentID: agent.ID, Revision: agent.Revision, Agent: *copyAgent(agent), CreatedAt: agent.UpdatedAt,
+	})
+}
+
+func (s *Store) ListAgentRevisions(id string) ([]store.AgentRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	revisions, ok := s.agentRevisions[id]
+	if !ok {
+		return nil, fmt.Errorf("agent not found: %s", id)
+	}
+	out := make([]store.AgentRevision, len(revisions))
+	for i, revision := range revisions {
+		revision.Agent = *copyAgent(&revision.Agent)
+		out[i] = revision
+	}
+	return out, nil
+}
+
+func (s *Store) GetAgentRevision(id string, revision int64) (*store.AgentRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, candidate := range s.agentRevisions[id] {
+		if candidate.Revision == revision {
+			candidate.Agent = *copyAgent(&candidate.Agent)
+			return &candidate, nil
+		}
+	}
+	return nil, fmt.Errorf("agent revision not found: %s@%d", id, revision)
+}
+
 func (s *Store) DeleteAgent(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -549,6 +636,83 @@ func (s *Store) DeleteAgent(id string) error {
 		return fmt.Errorf("agent not found: %s", id)
 	}
 	delete(s.agents, id)
+	delete(s.agentRevisions, id)
+	delete(s.permissionRulesets, permissionScopeKey{store.PermissionScopeAgent, id})
+	return nil
+}
+
+// ---- commands ------------------------------------------------------------
+
+func copyCommand(c *store.Command) *store.Command {
+	cp := *c
+	cp.Arguments = append([]store.CommandArgument(nil), c.Arguments...)
+	return &cp
+}
+
+func (s *Store) CreateCommand(command *store.Command) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if command.ID == "" {
+		command.ID = store.NewID(store.PrefixCommand)
+	}
+	now := store.Now()
+	command.CreatedAt = now
+	command.UpdatedAt = now
+	s.commands[command.ID] = copyCommand(command)
+	return nil
+}
+
+func (s *Store) GetCommand(id string) (*store.Command, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	command, ok := s.commands[id]
+	if !ok {
+		return nil, fmt.Errorf("command not found: %s", id)
+	}
+	return copyCommand(command), nil
+}
+
+func (s *Store) ListCommands() ([]*store.Command, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*store.Command, 0, len(s.commands))
+	for _, command := range s.commands {
+		out = append(out, copyCommand(command))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func (s *Store) UpdateCommand(command *store.Command) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.commands[command.ID]
+	if !ok {
+		return fmt.Errorf("command not found: %s", command.ID)
+	}
+	command.UpdatedAt = store.Now()
+	command.CreatedAt = existing.CreatedAt
+	s.commands[command.ID] = copyCommand(command)
+	return nil
+}
+
+func (s *Store) DeleteCommand(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.commands[id]; !ok {
+		return fmt.Errorf("command not found: %s", id)
+	}
+	delete(s.commands, id)
 	return nil
 }
 
@@ -735,6 +899,7 @@ func (s *Store) DeleteWorkspace(id string) error {
 		return fmt.Errorf("workspace not found: %s", id)
 	}
 	delete(s.workspaces, id)
+	delete(s.permissionRulesets, permissionScopeKey{store.PermissionScopeWorkspace, id})
 	for _, sess := range s.sessions {
 		if sess.WorkspaceID == id {
 			sess.WorkspaceID = ""
@@ -1070,6 +1235,11 @@ func (s *Store) PurgeSession(_ context.Context, id string, expectedVersion int64
 			delete(s.toolUses, useID)
 		}
 	}
+	for artifactID, artifact := range s.artifacts {
+		if artifact.SessionID == id {
+			delete(s.artifacts, artifactID)
+		}
+	}
 	for requestID, request := range s.permissionRequests {
 		if request.SessionID == id {
 			delete(s.permissionRequests, requestID)
@@ -1586,7 +1756,8 @@ func (s *Store) ResolvePermissionRequest(ctx context.Context, resolution store.P
 		return store.PermissionRequestTransition{}, &store.PermissionRequestNotFound{SessionID: resolution.SessionID, RequestID: resolution.RequestID}
 	}
 	if request.Status != store.PermissionRequestStatusPending {
-		if request.Status == resolution.Status && request.Response == resolution.Response && request.ErrorType == resolution.ErrorType && request.ErrorMessage == resolution.ErrorMessage {
+		if request.Status == resolution.Status && request.Response == resolution.Response && request.ErrorType == resolution.ErrorType && request.ErrorMessage == resolution.ErrorMessage &&
+			bytes.Equal(request.EditedInput, resolution.EditedInput) && request.Message == resolution.Message {
 			return store.PermissionRequestTransition{Request: copyPermissionRequest(request)}, nil
 		}
 		return store.PermissionRequestTransition{}, &store.PermissionRequestTransitionConflict{SessionID: resolution.SessionID, RequestID: resolution.RequestID}
@@ -1594,6 +1765,7 @@ func (s *Store) ResolvePermissionRequest(ctx context.Context, resolution store.P
 	now := time.Now().UTC()
 	updated := copyPermissionRequest(request)
 	updated.Status, updated.Response, updated.ErrorType, updated.ErrorMessage = resolution.Status, resolution.Response, resolution.ErrorType, resolution.ErrorMessage
+	updated.EditedInput, updated.Message = append(json.RawMessage(nil), resolution.EditedInput...), resolution.Message
 	updated.ResolvedAt, updated.UpdatedAt = now, now
 	event, err := s.newPermissionEventLocked(&updated, "session.permission.resolved", now)
 	if err != nil {
@@ -1789,6 +1961,56 @@ func (s *Store) appendPermissionAggregateCommitLocked(event store.AggregateEvent
 	s.sessions[event.Aggregate.ID] = copySession(projected)
 }
 
+// ---- permission rulesets ------------------------------------------------
+
+type permissionScopeKey struct{ scope, scopeID string }
+
+func (s *Store) GetPermissionRuleset(ctx context.Context, scope, scopeID string) (*store.PermissionRuleset, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ruleset := s.permissionRulesetLocked(scope, scopeID)
+	return &ruleset, nil
+}
+
+func (s *Store) UpdatePermissionRuleset(ctx context.Context, update store.PermissionRulesetUpdate) (store.PermissionRulesetTransition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.permissionRulesetLocked(update.Scope, update.ScopeID)
+	next, change, err := store.NextPermissionRuleset(current, update, time.Now().UTC())
+	if err != nil {
+		return store.PermissionRulesetTransition{}, err
+	}
+	if slices.Equal(current.Rules, next.Rules) {
+		return store.PermissionRulesetTransition{Ruleset: current}, nil
+	}
+	key := permissionScopeKey{update.Scope, update.ScopeID}
+	s.permissionRulesets[key] = copyPermissionRuleset(next)
+	s.permissionChanges[key] = append(s.permissionChanges[key], copyPermissionRuleChange(change))
+	return store.PermissionRulesetTransition{Ruleset: next, Change: change, Changed: true}, nil
+}
+
+func (s *Store) ListPermissionRuleChanges(ctx context.Context, scope, scopeID string) ([]store.PermissionRuleChange, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	changes := s.permissionChanges[permissionScopeKey{scope, scopeID}]
+	out := make([]store.PermissionRuleChange, 0, len(changes))
+	for _, change := range changes {
+		out = append(out, copyPermissionRuleChange(change))
+	}
+	return out, nil
+}
+
+func (s *Store) permissionRulesetLocked(scope, scopeID string) store.PermissionRuleset {
+	ruleset, ok := s.permissionRulesets[permissionScopeKey{scope, scopeID}]
+	if !ok {
+		return store.PermissionRuleset{Scope: scope, ScopeID: scopeID, Rules: permission.Ruleset{}}
+	}
+	return copyPermissionRuleset(ruleset)
+}
+
 func (s *Store) SaveToolUse(ctx context.Context, use store.ToolUse) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -1951,6 +2173,254 @@ func (s *Store) InterruptActiveToolUses(ctx context.Context) error {
 	return nil
 }
 
+// SaveArtifact stores an immutable session artifact.
+func (s *Store) SaveArtifact(_ context.Context, artifact *store.Artifact) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sessions[artifact.SessionID]; !ok {
+		return store.ErrSessionNotFound
+	}
+	if artifact.ID == "" {
+		artifact.ID = store.NewID(store.PrefixArtifact)
+	}
+	if artifact.MediaType == "" {
+		artifact.MediaType = "text/plain; charset=utf-8"
+	}
+	if artifact.CreatedAt.IsZero() {
+		artifact.CreatedAt = time.Now().UTC()
+	}
+	artifact.Size = int64(len(artifact.Content))
+	stored := *artifact
+	stored.Content = bytes.Clone(artifact.Content)
+	s.artifacts[stored.ID] = &stored
+	return nil
+}
+
+// GetArtifact returns one artifact of a session with its content.
+func (s *Store) GetArtifact(_ context.Context, sessionID, id string) (*store.Artifact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	artifact, ok := s.artifacts[id]
+	if !ok || artifact.SessionID != sessionID {
+		return nil, fmt.Errorf("%w: %s", store.ErrArtifactNotFound, id)
+	}
+	copied := *artifact
+	copied.Content = bytes.Clone(artifact.Content)
+	return &copied, nil
+}
+
+// CreateBatch stores a provider batch and its items.
+func (s *Store) CreateBatch(_ context.Context, batch *store.Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if batch.ID == "" {
+		batch.ID = store.NewID(store.PrefixBatch)
+	}
+	if batch.Status == "" {
+		batch.Status = store.BatchStatusInProgress
+	}
+	if batch.CreatedAt.IsZero() {
+		batch.CreatedAt = time.Now().UTC()
+	}
+	batch.UpdatedAt = batch.CreatedAt
+	for i := range batch.Items {
+		batch.Items[i].Index = i
+		if batch.Items[i].Status == "" {
+			batch.Items[i].Status = store.BatchItemStatusPending
+		}
+	}
+	batch.Counts = store.CountBatchItems(batch.Items)
+	stored := *batch
+	stored.Items = slices.Clone(batch.Items)
+	s.batches[stored.ID] = &stored
+	return nil
+}
+
+// GetBatch returns a batch with its items in index order.
+func (s *Store) GetBatch(_ context.Context, id string) (*store.Batch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	batch, ok := s.batches[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", store.ErrBatchNotFound, id)
+	}
+	copied := *batch
+	copied.Items = slices.Clone(batch.Items)
+	copied.Counts = store.CountBatchItems(copied.Items)
+	return &copied, nil
+}
+
+// ListBatches returns batches newest first without their items.
+func (s *Store) ListBatches(_ context.Context) ([]*store.Batch, error) {
+	return s.listBatches(func(*store.Batch) bool { return true }), nil
+}
+
+// ListBatchesByClient returns one client's batches newest first without
+// their items.
+func (s *Store) ListBatchesByClient(_ context.Context, clientID string) ([]*store.Batch, error) {
+	return s.listBatches(func(batch *store.Batch) bool { return batch.ClientID == clientID }), nil
+}
+
+// ListBatchesByStatus returns the batches in status newest first without
+// their items.
+func (s *Store) ListBatchesByStatus(_ context.Context, status string) ([]*store.Batch, error) {
+	return s.listBatches(func(batch *store.Batch) bool { return batch.Status == status }), nil
+}
+
+func (s *Store) listBatches(match func(*store.Batch) bool) []*store.Batch {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	batches := []*store.Batch{}
+	for _, batch := range s.batches {
+		if !match(batch) {
+			continue
+		}
+		copied := *batch
+		copied.Counts = store.CountBatchItems(batch.Items)
+		copied.Items = nil
+		batches = append(batches, &copied)
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		if !batches[i].CreatedAt.Equal(batches[j].CreatedAt) {
+			return batches[i].CreatedAt.After(batches[j].CreatedAt)
+		}
+		return batches[i].ID > batches[j].ID
+	})
+	return batches
+}
+
+// UpdateBatch writes a batch's provider batch ID, status, and error.
+func (s *Store) UpdateBatch(_ context.Context, batch *store.Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.batches[batch.ID]
+	if !ok {
+		return fmt.Errorf("%w: %s", store.ErrBatchNotFound, batch.ID)
+	}
+	batch.UpdatedAt = time.Now().UTC()
+	if store.IsBatchTerminal(batch.Status) && batch.CompletedAt.IsZero() {
+		batch.CompletedAt = batch.UpdatedAt
+	}
+	stored.ProviderBatchID, stored.Status, stored.ErrorMessage = batch.ProviderBatchID, batch.Status, batch.ErrorMessage
+	stored.UpdatedAt, stored.CompletedAt = batch.UpdatedAt, batch.CompletedAt
+	return nil
+}
+
+// UpdateBatchItem records the session, run, and outcome of one item.
+func (s *Store) UpdateBatchItem(_ context.Context, batchID string, item store.BatchItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch, ok := s.batches[batchID]
+	if !ok || item.Index < 0 || item.Index >= len(batch.Items) {
+		return fmt.Errorf("%w: %s item %d", store.ErrBatchNotFound, batchID, item.Index)
+	}
+	stored := &batch.Items[item.Index]
+	stored.SessionID, stored.RunID, stored.Status, stored.ErrorMessage = item.SessionID, item.RunID, item.Status, item.ErrorMessage
+	return nil
+}
+
+// CreateEval stores an eval and its cases.
+func (s *Store) CreateEval(_ context.Context, eval *store.Eval) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if eval.ID == "" {
+		eval.ID = store.NewID(store.PrefixEval)
+	}
+	if eval.Status == "" {
+		eval.Status = store.EvalStatusRunning
+	}
+	if eval.CreatedAt.IsZero() {
+		eval.CreatedAt = time.Now().UTC()
+	}
+	eval.UpdatedAt = eval.CreatedAt
+	for i := range eval.Cases {
+		eval.Cases[i].Index = i
+		if eval.Cases[i].Status == "" {
+			eval.Cases[i].Status = store.EvalCaseStatusPending
+		}
+	}
+	eval.Summary = store.SummarizeEvalCases(eval.Cases)
+	stored := *eval
+	stored.Cases = cloneEvalCases(eval.Cases)
+	s.evals[stored.ID] = &stored
+	return nil
+}
+
+// GetEval returns an eval with its cases in index order.
+func (s *Store) GetEval(_ context.Context, id string) (*store.Eval, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	eval, ok := s.evals[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", store.ErrEvalNotFound, id)
+	}
+	copied := *eval
+	copied.Cases = cloneEvalCases(eval.Cases)
+	copied.Summary = store.SummarizeEvalCases(copied.Cases)
+	return &copied, nil
+}
+
+// ListEvals returns evals newest first without their cases.
+func (s *Store) ListEvals(_ context.Context) ([]*store.Eval, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	evals := make([]*store.Eval, 0, len(s.evals))
+	for _, eval := range s.evals {
+		copied := *eval
+		copied.Summary = store.SummarizeEvalCases(eval.Cases)
+		copied.Cases = nil
+		evals = append(evals, &copied)
+	}
+	sort.Slice(evals, func(i, j int) bool {
+		if !evals[i].CreatedAt.Equal(evals[j].CreatedAt) {
+			return evals[i].CreatedAt.After(evals[j].CreatedAt)
+		}
+		return evals[i].ID > evals[j].ID
+	})
+	return evals, nil
+}
+
+// UpdateEval writes an eval's status and error.
+func (s *Store) UpdateEval(_ context.Context, eval *store.Eval) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.evals[eval.ID]
+	if !ok {
+		return fmt.Errorf("%w: %s", store.ErrEvalNotFound, eval.ID)
+	}
+	eval.UpdatedAt = time.Now().UTC()
+	if eval.Status != store.EvalStatusRunning && eval.CompletedAt.IsZero() {
+		eval.CompletedAt = eval.UpdatedAt
+	}
+	stored.Status, stored.ErrorMessage = eval.Status, eval.ErrorMessage
+	stored.UpdatedAt, stored.CompletedAt = eval.UpdatedAt, eval.CompletedAt
+	return nil
+}
+
+// UpdateEvalCase records the session, run, and outcome of one case.
+func (s *Store) UpdateEvalCase(_ context.Context, evalID string, c store.EvalCase) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	eval, ok := s.evals[evalID]
+	if !ok || c.Index < 0 || c.Index >= len(eval.Cases) {
+		return fmt.Errorf("%w: %s case %d", store.ErrEvalNotFound, evalID, c.Index)
+	}
+	stored := &eval.Cases[c.Index]
+	stored.SessionID, stored.RunID, stored.Status, stored.Score = c.SessionID, c.RunID, c.Status, c.Score
+	stored.ResultsJSON, stored.ErrorMessage = bytes.Clone(c.ResultsJSON), c.ErrorMessage
+	stored.TotalTokens, stored.Cost = c.TotalTokens, c.Cost
+	return nil
+}
+
+func cloneEvalCases(cases []store.EvalCase) []store.EvalCase {
+	cloned := slices.Clone(cases)
+	for i := range cloned {
+		cloned[i].DefinitionJSON = bytes.Clone(cases[i].DefinitionJSON)
+		cloned[i].ResultsJSON = bytes.Clone(cases[i].ResultsJSON)
+	}
+	return cloned
+}
+
 func sameToolUseIdentityMemory(a, b store.ToolUse) bool {
 	return a.SessionID == b.SessionID && a.RunID == b.RunID && a.ModelCallID == b.ModelCallID && a.AssistantMessageID == b.AssistantMessageID && a.PartID == b.PartID && a.Step == b.Step && a.Ordinal == b.Ordinal && a.CallID == b.CallID && a.Name == b.Name
 }
@@ -2062,6 +2532,33 @@ func (s *Store) SetAuth(auth *store.Auth) error {
 	defer s.mu.Unlock()
 
 	auth.UpdatedAt = store.Now()
+	mcp := copyAuth(s.auth).MCP
 	s.auth = copyAuth(auth)
+	s.auth.MCP = mcp
+	return nil
+}
+
+func (s *Store) SetMCPCredential(server string, credential store.AuthCredential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := copyAuth(s.auth)
+	if next.MCP == nil {
+		next.MCP = make(map[string]store.AuthCredential)
+	}
+	next.MCP[server] = credential
+	next.UpdatedAt = store.Now()
+	s.auth = next
+	return nil
+}
+
+func (s *Store) DeleteMCPCredential(server string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := copyAuth(s.auth)
+	delete(next.MCP, server)
+	next.UpdatedAt = store.Now()
+	s.auth = next
 	return nil
 }
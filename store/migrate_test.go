@@ -17,8 +17,8 @@ func TestRunMigrationsCreatesCanonicalSchema(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(migrations) != 1 || migrations[0].version != 1 || migrations[0].name != "init" {
-		t.Fatalf("migrations = %#v, want 0001_init", migrations)
+	if len(migrations) == 0 || migrations[0].version != 1 || migrations[0].name != "init" {
+		t.Fatalf("migrations = %#v, want 0001_init first", migrations)
 	}
 	var count int
 	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = 1 AND name = 'init' AND checksum <> ''`).Scan(&count); err != nil {
@@ -28,7 +28,7 @@ func TestRunMigrationsCreatesCanonicalSchema(t *testing.T) {
 		t.Fatalf("recorded initial migration = %d, want 1", count)
 	}
 	for table, columns := range map[string][]string{
-		"agents":              {"permissions_json"},
+		"agents":              {"permissions_json", "limits_json", "revision"},
 		"sessions":            {"aggregate_version"},
 		"messages":            {"run_id"},
 		"session_runs":        {"request_id", "request_hash", "admitted_version", "work_dir", "workspace_id", "client_id", "error_type"},
@@ -45,7 +45,7 @@ func TestRunMigrationsCreatesCanonicalSchema(t *testing.T) {
 			}
 		}
 	}
-	for _, table := range []string{"agents", "clients", "workspaces", "sessions", "messages", "parts", "session_runs", "model_calls", "tool_uses", "permission_requests", "permission_grants", "session_events", "aggregate_events", "auth"} {
+	for _, table := range []string{"agents", "agent_revisions", "commands", "clients", "workspaces", "sessions", "messages", "parts", "session_runs", "model_calls", "tool_uses", "session_artifacts", "permission_requests", "permission_grants", "permission_rulesets", "permission_rule_changes", "session_events", "aggregate_events", "auth", "batches", "batch_items", "evals", "eval_cases"} {
 		if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&count); err != nil || count != 1 {
 			t.Fatalf("table %s count=%d error=%v", table, count, err)
 		}
@@ -63,6 +63,7 @@ func TestRunMigrationsCreatesCanonicalSchema(t *testing.T) {
 		"idx_tool_uses_run_step_ordinal",
 		"idx_permission_requests_session_created",
 		"idx_permission_grants_session",
+		"idx_permission_rule_changes_scope",
 		"idx_aggregate_events_stream",
 		"idx_session_events_session_seq",
 	} {
@@ -149,7 +150,11 @@ func TestRunMigrationsRejectsUnknownHistory(t *testing.T) {
 	if err := runMigrations(db); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (3, 'removed', 'checksum', ?)`, Now()); err != nil {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, 'removed', 'checksum', ?)`, len(migrations)+1, Now()); err != nil {
 		t.Fatal(err)
 	}
 	if err := runMigrations(db); err == nil || !strings.Contains(err.Error(), "unknown version") {
@@ -189,6 +194,39 @@ CREATE TABLE schema_migrations (
 	}
 }
 
+func TestAgentRevisionsMigrationBackfillsExistingAgents(t *testing.T) {
+	db := testMigrationDB(t)
+	if _, err := db.Exec(migrationsTable); err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range migrations[:5] {
+		if err := applyMigration(db, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec(`
+		INSERT INTO agents (id, name, instructions, tools_json, permissions_json, model_ref, options_json, output_schema_json, limits_json, created_at, updated_at)
+		VALUES ('agt_old', 'old', 'be brief', '["read"]', NULL, '', '', NULL, '{"max_steps":2}', '2025-01-01T00:00:00Z', '2025-01-02T00:00:00Z')
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if err := runMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+	revision, err := scanAgentRevision(db.QueryRow(`SELECT agent_id, revision, agent_json, created_at FROM agent_revisions WHERE agent_id = 'agt_old'`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := revision.Agent
+	if revision.Revision != 1 || agent.Revision != 1 || agent.Instructions != "be brief" || len(agent.Tools) != 1 || agent.Options != nil || agent.Limits.MaxSteps != 2 || agent.UpdatedAt != revision.CreatedAt {
+		t.Fatalf("revision = %#v", revision)
+	}
+}
+
 func TestApplyMigrationRollsBackOnFailure(t *testing.T) {
 	db := testMigrationDB(t)
 	if _, err := db.Exec(migrationsTable); err != nil {
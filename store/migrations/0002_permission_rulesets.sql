@@ -0,0 +1,26 @@
+-- 0002_permission_rulesets.sql: persisted workspace and agent permission rules.
+
+CREATE TABLE permission_rulesets (
+    scope      TEXT NOT NULL CHECK (scope IN ('workspace', 'agent')),
+    scope_id   TEXT NOT NULL,
+    rules_json TEXT NOT NULL CHECK (json_valid(rules_json) AND json_type(rules_json) = 'array'),
+    version    INTEGER NOT NULL CHECK (version > 0),
+    updated_at TEXT NOT NULL,
+    PRIMARY KEY (scope, scope_id)
+);
+
+CREATE TABLE permission_rule_changes (
+    id          TEXT PRIMARY KEY,
+    scope       TEXT NOT NULL CHECK (scope IN ('workspace', 'agent')),
+    scope_id    TEXT NOT NULL,
+    version     INTEGER NOT NULL CHECK (version > 0),
+    operation   TEXT NOT NULL CHECK (operation IN ('replace', 'delete', 'promote')),
+    client_id   TEXT NOT NULL DEFAULT '',
+    session_id  TEXT NOT NULL DEFAULT '',
+    grant_id    TEXT NOT NULL DEFAULT '',
+    before_json TEXT NOT NULL CHECK (json_valid(before_json)),
+    after_json  TEXT NOT NULL CHECK (json_valid(after_json)),
+    created_at  TEXT NOT NULL
+);
+
+CREATE INDEX idx_permission_rule_changes_scope ON permission_rule_changes(scope, scope_id, created_at, version);
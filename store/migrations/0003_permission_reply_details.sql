@@ -0,0 +1,4 @@
+-- 0003_permission_reply_details.sql: reviewer edits and explanations on permission replies.
+
+ALTER TABLE permission_requests ADD COLUMN edited_input_json TEXT CHECK (edited_input_json IS NULL OR (json_valid(edited_input_json) AND json_type(edited_input_json) = 'object'));
+ALTER TABLE permission_requests ADD COLUMN message TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- 0004_mcp_auth.sql: OAuth credentials for remote MCP servers.
+
+ALTER TABLE auth ADD COLUMN mcp_json TEXT NOT NULL DEFAULT '{}';
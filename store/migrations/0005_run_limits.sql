@@ -0,0 +1,3 @@
+-- 0005_run_limits.sql: default per-run limits on agent definitions.
+
+ALTER TABLE agents ADD COLUMN limits_json TEXT;
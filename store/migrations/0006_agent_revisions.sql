@@ -0,0 +1,29 @@
+-- 0006_agent_revisions.sql: immutable agent revisions. Every create and
+-- update appends a snapshot; existing agents start at revision 1.
+
+ALTER TABLE agents ADD COLUMN revision INTEGER NOT NULL DEFAULT 1 CHECK (revision > 0);
+
+CREATE TABLE agent_revisions (
+    agent_id   TEXT NOT NULL,
+    revision   INTEGER NOT NULL CHECK (revision > 0),
+    agent_json TEXT NOT NULL CHECK (json_valid(agent_json)),
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (agent_id, revision)
+);
+
+INSERT INTO agent_revisions (agent_id, revision, agent_json, created_at)
+SELECT id, 1, json_object(
+    'id', id,
+    'name', name,
+    'instructions', instructions,
+    'tools', json(NULLIF(tools_json, '')),
+    'permissions', json(NULLIF(permissions_json, '')),
+    'model_ref', model_ref,
+    'options', json(NULLIF(options_json, '')),
+    'output_schema', json(NULLIF(output_schema_json, '')),
+    'limits', json(NULLIF(limits_json, '')),
+    'revision', 1,
+    'created_at', created_at,
+    'updated_at', updated_at
+), updated_at
+FROM agents;
@@ -0,0 +1,13 @@
+-- 0007_commands.sql: saved prompt commands rendered into session messages.
+
+CREATE TABLE commands (
+    id             TEXT PRIMARY KEY,
+    name           TEXT NOT NULL,
+    description    TEXT NOT NULL DEFAULT '',
+    template       TEXT NOT NULL,
+    arguments_json TEXT CHECK (arguments_json IS NULL OR json_valid(arguments_json)),
+    agent_id       TEXT NOT NULL DEFAULT '',
+    model_ref      TEXT NOT NULL DEFAULT '',
+    created_at     TEXT NOT NULL,
+    updated_at     TEXT NOT NULL
+);
@@ -0,0 +1,16 @@
+-- 0008_session_artifacts.sql: tool outputs too large to keep in the transcript.
+
+CREATE TABLE session_artifacts (
+    id          TEXT PRIMARY KEY,
+    session_id  TEXT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    run_id      TEXT REFERENCES session_runs(id) ON DELETE CASCADE,
+    tool_use_id TEXT REFERENCES tool_uses(id) ON DELETE SET NULL,
+    call_id     TEXT NOT NULL DEFAULT '',
+    name        TEXT NOT NULL DEFAULT '',
+    media_type  TEXT NOT NULL,
+    content     BLOB NOT NULL,
+    size        INTEGER NOT NULL CHECK (size >= 0),
+    created_at  TEXT NOT NULL
+);
+
+CREATE INDEX idx_session_artifacts_session_id ON session_artifacts(session_id, created_at);
@@ -0,0 +1,27 @@
+-- 0009_batches.sql: prompts submitted to provider batch endpoints. Each item
+-- becomes a session with one run when its result is imported.
+
+CREATE TABLE batches (
+    id                TEXT PRIMARY KEY,
+    client_id         TEXT NOT NULL DEFAULT '',
+    agent_json        TEXT NOT NULL CHECK (json_valid(agent_json)),
+    provider_batch_id TEXT NOT NULL,
+    status            TEXT NOT NULL,
+    error_message     TEXT NOT NULL DEFAULT '',
+    created_at        TEXT NOT NULL,
+    updated_at        TEXT NOT NULL,
+    completed_at      TEXT
+);
+
+CREATE INDEX idx_batches_status ON batches(status);
+
+CREATE TABLE batch_items (
+    batch_id      TEXT NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    idx           INTEGER NOT NULL CHECK (idx >= 0),
+    prompt        TEXT NOT NULL,
+    session_id    TEXT REFERENCES sessions(id) ON DELETE SET NULL,
+    run_id        TEXT REFERENCES session_runs(id) ON DELETE SET NULL,
+    status        TEXT NOT NULL,
+    error_message TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (batch_id, idx)
+);
@@ -0,0 +1,34 @@
+-- 0010_evals.sql: agent evaluations. Each case runs in its own session; the
+-- case row keeps its definition, check results, and score.
+
+CREATE TABLE evals (
+    id              TEXT PRIMARY KEY,
+    client_id       TEXT NOT NULL DEFAULT '',
+    name            TEXT NOT NULL DEFAULT '',
+    agent_json      TEXT NOT NULL CHECK (json_valid(agent_json)),
+    judge_model_ref TEXT NOT NULL DEFAULT '',
+    status          TEXT NOT NULL,
+    error_message   TEXT NOT NULL DEFAULT '',
+    created_at      TEXT NOT NULL,
+    updated_at      TEXT NOT NULL,
+    completed_at    TEXT
+);
+
+CREATE INDEX idx_evals_created ON evals(created_at);
+
+CREATE TABLE eval_cases (
+    eval_id         TEXT NOT NULL REFERENCES evals(id) ON DELETE CASCADE,
+    idx             INTEGER NOT NULL CHECK (idx >= 0),
+    case_id         TEXT NOT NULL,
+    definition_json TEXT NOT NULL CHECK (json_valid(definition_json)),
+    session_id      TEXT REFERENCES sessions(id) ON DELETE SET NULL,
+    run_id          TEXT REFERENCES session_runs(id) ON DELETE SET NULL,
+    status          TEXT NOT NULL,
+    score           REAL NOT NULL DEFAULT 0,
+    results_json    TEXT CHECK (results_json IS NULL OR json_valid(results_json)),
+    error_message   TEXT NOT NULL DEFAULT '',
+    total_tokens    INTEGER NOT NULL DEFAULT 0,
+    cost            REAL NOT NULL DEFAULT 0,
+    PRIMARY KEY (eval_id, idx),
+    UNIQUE (eval_id, case_id)
+);
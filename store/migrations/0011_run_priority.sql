@@ -0,0 +1,3 @@
+-- 0011_run_priority.sql: scheduling priority of queued session runs.
+
+ALTER TABLE session_runs ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
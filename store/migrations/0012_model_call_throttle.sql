@@ -0,0 +1,3 @@
+-- 0012_model_call_throttle.sql: time a model call waited on the provider rate limiter.
+
+ALTER TABLE model_calls ADD COLUMN throttled_ms INTEGER NOT NULL DEFAULT 0;
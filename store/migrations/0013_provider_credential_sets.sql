@@ -0,0 +1,6 @@
+-- 0013_provider_credential_sets.sql: named provider credentials, their
+-- selection per workspace, and the credential that served each model call.
+
+ALTER TABLE auth ADD COLUMN provider_credentials_json TEXT NOT NULL DEFAULT '{}';
+ALTER TABLE workspaces ADD COLUMN credentials_json TEXT;
+ALTER TABLE model_calls ADD COLUMN credential TEXT NOT NULL DEFAULT '';
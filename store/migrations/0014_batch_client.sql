@@ -0,0 +1,3 @@
+-- 0014_batch_client.sql: lists a client's batches without scanning them all.
+
+CREATE INDEX idx_batches_client ON batches(client_id, created_at);
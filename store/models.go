@@ -16,8 +16,95 @@ type Agent struct {
 	ModelRef     string             `json:"model_ref,omitempty"`
 	Options      map[string]any     `json:"options,omitempty"`
 	OutputSchema map[string]any     `json:"output_schema,omitempty"`
-	CreatedAt    string             `json:"created_at"`
-	UpdatedAt    string             `json:"updated_at"`
+	Limits       *RunLimits         `json:"limits,omitempty"`
+	// Revision numbers the agent's immutable revisions, starting at 1.
+	// Session runs snapshot the agent, so a run's Agent.Revision records
+	// the revision it executed with.
+	Revision int64 `json:"revision,omitempty"`
+	// Source is AgentSourceFile for agents loaded from a project's agent
+	// files and empty for agents persisted in the store.
+	Source string `json:"source,omitempty"`
+	// Path is the agent file a file agent was loaded from.
+	Path      string `json:"path,omitempty"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// AgentRevision is one immutable snapshot of a stored agent. Every create
+// and update appends a revision.
+type AgentRevision struct {
+	AgentID   string `json:"agent_id"`
+	Revision  int64  `json:"revision"`
+	Agent     Agent  `json:"agent"`
+	CreatedAt string `json:"created_at"`
+}
+
+// Command is a saved prompt template. Rendering it with named arguments
+// produces the message of an ordinary session run.
+type Command struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Template    string            `json:"template"`
+	Arguments   []CommandArgument `json:"arguments,omitempty"`
+	// AgentID and ModelRef bind the run's agent and model unless the
+	// request overrides them.
+	AgentID  string `json:"agent_id,omitempty"`
+	ModelRef string `json:"model_ref,omitempty"`
+	// Source and Path mirror Agent: file commands come from a project's
+	// .wingman/commands directory.
+	Source    string `json:"source,omitempty"`
+	Path      string `json:"path,omitempty"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// CommandArgument declares one named {{argument}} of a command template.
+type CommandArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Default     string `json:"default,omitempty"`
+}
+
+// Agent sources reported by the API. Commands report the same sources.
+const (
+	AgentSourceStore = "store"
+	AgentSourceFile  = "file"
+)
+
+// RunLimits bounds one run. Zero fields are unlimited.
+type RunLimits struct {
+	MaxSteps       int   `json:"max_steps,omitempty"`
+	MaxTotalTokens int   `json:"max_total_tokens,omitempty"`
+	MaxDurationMS  int64 `json:"max_duration_ms,omitempty"`
+	MaxToolCalls   int   `json:"max_tool_calls,omitempty"`
+}
+
+// Tighten returns the strictest combination of l and other: each field takes
+// the smaller non-zero value. It returns nil when neither sets a limit.
+func (l *RunLimits) Tighten(other *RunLimits) *RunLimits {
+	var out RunLimits
+	for _, limits := range []*RunLimits{l, other} {
+		if limits == nil {
+			continue
+		}
+		out.MaxSteps = tighter(out.MaxSteps, limits.MaxSteps)
+		out.MaxTotalTokens = tighter(out.MaxTotalTokens, limits.MaxTotalTokens)
+		out.MaxDurationMS = tighter(out.MaxDurationMS, limits.MaxDurationMS)
+		out.MaxToolCalls = tighter(out.MaxToolCalls, limits.MaxToolCalls)
+	}
+	if out == (RunLimits{}) {
+		return nil
+	}
+	return &out
+}
+
+func tighter[T int | int64](current, next T) T {
+	if next > 0 && (current == 0 || next < current) {
+		return next
+	}
+	return current
 }
 
 type Session struct {
@@ -65,20 +152,26 @@ const (
 
 // PermissionRequest records one interactive authorization decision.
 type PermissionRequest struct {
-	ID           string    `json:"id"`
-	SessionID    string    `json:"session_id"`
-	RunID        string    `json:"run_id,omitempty"`
-	ToolUseID    string    `json:"tool_use_id,omitempty"`
-	CallID       string    `json:"call_id,omitempty"`
-	Action       string    `json:"action"`
-	Resources    []string  `json:"resources"`
-	Status       string    `json:"status"`
-	Response     string    `json:"response,omitempty"`
-	ErrorType    string    `json:"error_type,omitempty"`
-	ErrorMessage string    `json:"error_message,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	ResolvedAt   time.Time `json:"resolved_at,omitempty"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           string   `json:"id"`
+	SessionID    string   `json:"session_id"`
+	RunID        string   `json:"run_id,omitempty"`
+	ToolUseID    string   `json:"tool_use_id,omitempty"`
+	CallID       string   `json:"call_id,omitempty"`
+	Action       string   `json:"action"`
+	Resources    []string `json:"resources"`
+	Status       string   `json:"status"`
+	Response     string   `json:"response,omitempty"`
+	ErrorType    string   `json:"error_type,omitempty"`
+	ErrorMessage string   `json:"error_message,omitempty"`
+	// EditedInput is reviewer-edited tool input approved in place of the
+	// model's proposal.
+	EditedInput json.RawMessage `json:"edited_input,omitempty"`
+	// Message is the reviewer's explanation, returned to the model when the
+	// request is rejected.
+	Message    string    `json:"message,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	ResolvedAt time.Time `json:"resolved_at,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
 
 // PermissionGrant is a session-scoped authorization remembered by an
@@ -107,6 +200,79 @@ type PermissionRequestResolution struct {
 	Response       string
 	ErrorType      string
 	ErrorMessage   string
+	EditedInput    json.RawMessage
+	Message        string
+}
+
+const (
+	PermissionScopeWorkspace = "workspace"
+	PermissionScopeAgent     = "agent"
+)
+
+const (
+	PermissionRuleChangeReplace = "replace"
+	PermissionRuleChangeDelete  = "delete"
+	PermissionRuleChangePromote = "promote"
+)
+
+// PermissionRuleset is a persisted ruleset owned by a workspace or agent.
+// Version is zero until the first change is recorded.
+type PermissionRuleset struct {
+	Scope     string             `json:"scope"`
+	ScopeID   string             `json:"scope_id"`
+	Rules     permission.Ruleset `json:"rules"`
+	Version   int64              `json:"version"`
+	UpdatedAt time.Time          `json:"updated_at,omitempty"`
+}
+
+// PermissionRuleChange is one immutable audit entry for a ruleset change.
+type PermissionRuleChange struct {
+	ID        string             `json:"id"`
+	Scope     string             `json:"scope"`
+	ScopeID   string             `json:"scope_id"`
+	Version   int64              `json:"version"`
+	Operation string             `json:"operation"`
+	ClientID  string             `json:"client_id,omitempty"`
+	SessionID string             `json:"session_id,omitempty"`
+	GrantID   string             `json:"grant_id,omitempty"`
+	Before    permission.Ruleset `json:"before"`
+	After     permission.Ruleset `json:"after"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// PermissionRulesetUpdate replaces a ruleset and records who changed it.
+// A nil ExpectedVersion skips the optimistic concurrency check.
+type PermissionRulesetUpdate struct {
+	Scope           string
+	ScopeID         string
+	ExpectedVersion *int64
+	Rules           permission.Ruleset
+	Operation       string
+	ClientID        string
+	SessionID       string
+	GrantID         string
+}
+
+// PermissionRulesetTransition is an atomic ruleset change and its audit entry.
+type PermissionRulesetTransition struct {
+	Ruleset PermissionRuleset
+	Change  PermissionRuleChange
+	Changed bool
+}
+
+// Artifact is a blob stored with a session, such as tool output too large
+// to keep inline in the transcript.
+type Artifact struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"session_id"`
+	RunID     string    `json:"run_id,omitempty"`
+	ToolUseID string    `json:"tool_use_id,omitempty"`
+	CallID    string    `json:"call_id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	MediaType string    `json:"media_type"`
+	Content   []byte    `json:"-"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // ToolUse records one durable tool invocation lifecycle.
@@ -155,15 +321,18 @@ func (u ToolUse) MarshalJSON() ([]byte, error) {
 // SessionRun is a durably admitted prompt and its immutable effective agent
 // configuration. Runs are claimed in sequence order per session.
 type SessionRun struct {
-	ID               string    `json:"id"`
-	SessionID        string    `json:"session_id"`
-	RequestID        string    `json:"request_id,omitempty"`
-	RequestHash      string    `json:"-"`
-	AdmittedVersion  int64     `json:"admitted_version"`
-	WorkDir          string    `json:"work_dir,omitempty"`
-	WorkspaceID      string    `json:"workspace_id,omitempty"`
-	ClientID         string    `json:"client_id,omitempty"`
-	Sequence         int       `json:"sequence"`
+	ID              string `json:"id"`
+	SessionID       string `json:"session_id"`
+	RequestID       string `json:"request_id,omitempty"`
+	RequestHash     string `json:"-"`
+	AdmittedVersion int64  `json:"admitted_version"`
+	WorkDir         string `json:"work_dir,omitempty"`
+	WorkspaceID     string `json:"workspace_id,omitempty"`
+	ClientID        string `json:"client_id,omitempty"`
+	Sequence        int    `json:"sequence"`
+	// Priority orders the run against other sessions' runs waiting for a
+	// run slot. Higher runs first.
+	Priority         int       `json:"priority,omitempty"`
 	Status           string    `json:"status"`
 	Message          string    `json:"message"`
 	Agent            Agent     `json:"agent"`
@@ -201,13 +370,172 @@ type SessionRunSettlement struct {
 	EventData      map[string]any
 }
 
+const (
+	// BatchStatusSubmitting marks a batch stored before its provider
+	// submission returns, so a submitted batch always has a record.
+	BatchStatusSubmitting = "submitting"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusCompleted  = "completed"
+	BatchStatusFailed     = "failed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelled  = "cancelled"
+)
+
+const (
+	BatchItemStatusPending   = "pending"
+	BatchItemStatusCompleted = "completed"
+	BatchItemStatusFailed    = "failed"
+)
+
+// Batch is a set of prompts submitted to a provider batch endpoint against
+// one agent snapshot. Each item becomes a session with one run once the
+// provider returns its result; the batch stays in progress until every
+// result is imported.
+type Batch struct {
+	ID              string      `json:"id"`
+	ClientID        string      `json:"client_id,omitempty"`
+	Agent           Agent       `json:"agent"`
+	ProviderBatchID string      `json:"provider_batch_id"`
+	Status          string      `json:"status"`
+	ErrorMessage    string      `json:"error_message,omitempty"`
+	Counts          BatchCounts `json:"counts"`
+	Items           []BatchItem `json:"items,omitempty"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+	CompletedAt     time.Time   `json:"completed_at,omitempty"`
+}
+
+// BatchCounts tallies a batch's items by status.
+type BatchCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// BatchItem is one prompt of a batch. SessionID and RunID are set when its
+// result is imported.
+type BatchItem struct {
+	Index        int    `json:"index"`
+	Prompt       string `json:"prompt"`
+	SessionID    string `json:"session_id,omitempty"`
+	RunID        string `json:"run_id,omitempty"`
+	Status       string `json:"status"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// CountBatchItems tallies items by status.
+func CountBatchItems(items []BatchItem) BatchCounts {
+	counts := BatchCounts{Total: len(items)}
+	for _, item := range items {
+		switch item.Status {
+		case BatchItemStatusCompleted:
+			counts.Completed++
+		case BatchItemStatusFailed:
+			counts.Failed++
+		}
+	}
+	return counts
+}
+
+// IsBatchTerminal reports whether a batch status is final.
+func IsBatchTerminal(status string) bool {
+	return status != BatchStatusSubmitting && status != BatchStatusInProgress
+}
+
+const (
+	EvalStatusRunning   = "running"
+	EvalStatusCompleted = "completed"
+	EvalStatusFailed    = "failed"
+)
+
+const (
+	EvalCaseStatusPending = "pending"
+	EvalCaseStatusRunning = "running"
+	EvalCaseStatusPassed  = "passed"
+	EvalCaseStatusFailed  = "failed"
+	// EvalCaseStatusError marks a case whose run did not complete, so its
+	// answer could not be scored.
+	EvalCaseStatusError = "error"
+)
+
+// Eval runs one agent snapshot over a dataset. Each case runs in its own
+// session and keeps its check results so evals of different agent
+// revisions and models can be compared.
+type Eval struct {
+	ID            string      `json:"id"`
+	ClientID      string      `json:"client_id,omitempty"`
+	Name          string      `json:"name,omitempty"`
+	Agent         Agent       `json:"agent"`
+	JudgeModelRef string      `json:"judge_model_ref,omitempty"`
+	Status        string      `json:"status"`
+	ErrorMessage  string      `json:"error_message,omitempty"`
+	Summary       EvalSummary `json:"summary"`
+	Cases         []EvalCase  `json:"cases,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	CompletedAt   time.Time   `json:"completed_at,omitempty"`
+}
+
+// EvalSummary aggregates an eval's cases. Score is the mean case score,
+// counting unscored cases as zero.
+type EvalSummary struct {
+	Total       int     `json:"total"`
+	Passed      int     `json:"passed"`
+	Failed      int     `json:"failed"`
+	Errored     int     `json:"errored"`
+	Score       float64 `json:"score"`
+	TotalTokens int     `json:"total_tokens"`
+	Cost        float64 `json:"cost"`
+}
+
+// EvalCase is one dataset case of an eval. DefinitionJSON holds the case as
+// submitted and ResultsJSON its check results.
+type EvalCase struct {
+	Index          int             `json:"index"`
+	CaseID         string          `json:"case_id"`
+	DefinitionJSON json.RawMessage `json:"definition_json"`
+	SessionID      string          `json:"session_id,omitempty"`
+	RunID          string          `json:"run_id,omitempty"`
+	Status         string          `json:"status"`
+	Score          float64         `json:"score"`
+	ResultsJSON    json.RawMessage `json:"results_json,omitempty"`
+	ErrorMessage   string          `json:"error_message,omitempty"`
+	TotalTokens    int             `json:"total_tokens"`
+	Cost           float64         `json:"cost"`
+}
+
+// SummarizeEvalCases aggregates cases by status.
+func SummarizeEvalCases(cases []EvalCase) EvalSummary {
+	summary := EvalSummary{Total: len(cases)}
+	for _, c := range cases {
+		switch c.Status {
+		case EvalCaseStatusPassed:
+			summary.Passed++
+		case EvalCaseStatusFailed:
+			summary.Failed++
+		case EvalCaseStatusError:
+			summary.Errored++
+		}
+		summary.Score += c.Score
+		summary.TotalTokens += c.TotalTokens
+		summary.Cost += c.Cost
+	}
+	if summary.Total > 0 {
+		summary.Score /= float64(summary.Total)
+	}
+	return summary
+}
+
 type Workspace struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Path      string `json:"path"`
-	ClientID  string `json:"client_id,omitempty"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	ClientID string `json:"client_id,omitempty"`
+	// Credentials select a named credential by provider ID for sessions in
+	// the workspace.
+	Credentials map[string]string `json:"credentials,omitempty"`
+	CreatedAt   string            `json:"created_at"`
+	UpdatedAt   string            `json:"updated_at"`
 }
 
 // StoredMessage is a single message row for a session.
@@ -288,33 +616,38 @@ const (
 // the durable source of model provenance, finish state, token usage, and
 // context-window fullness for assistant turns.
 type ModelCall struct {
-	ID                   string          `json:"id"`
-	SessionID            string          `json:"session_id"`
-	RunID                string          `json:"run_id,omitempty"`
-	AssistantMessageID   string          `json:"assistant_message_id,omitempty"`
-	Step                 int             `json:"step"`
-	Attempt              int             `json:"attempt"`
-	Status               string          `json:"status"`
-	AgentID              string          `json:"agent_id,omitempty"`
-	ModelRef             string          `json:"model_ref,omitempty"`
-	Provider             string          `json:"provider,omitempty"`
-	ProviderRequestID    string          `json:"provider_request_id,omitempty"`
-	API                  string          `json:"api,omitempty"`
-	ModelID              string          `json:"model_id,omitempty"`
-	FinishReason         string          `json:"finish_reason,omitempty"`
-	StopReason           string          `json:"stop_reason,omitempty"`
-	ErrorType            string          `json:"error_type,omitempty"`
-	ErrorMessage         string          `json:"error_message,omitempty"`
-	InputTokens          int             `json:"input_tokens"`
-	OutputTokens         int             `json:"output_tokens"`
-	ReasoningTokens      int             `json:"reasoning_tokens,omitempty"`
-	CachedInputTokens    int             `json:"cached_input_tokens,omitempty"`
-	CacheWriteTokens     int             `json:"cache_write_tokens,omitempty"`
-	TotalTokens          int             `json:"total_tokens"`
-	ContextTokens        int             `json:"context_tokens"`
-	ContextWindow        int             `json:"context_window,omitempty"`
-	ContextPercent       float64         `json:"context_percent,omitempty"`
-	Cost                 *float64        `json:"cost,omitempty"`
+	ID                 string   `json:"id"`
+	SessionID          string   `json:"session_id"`
+	RunID              string   `json:"run_id,omitempty"`
+	AssistantMessageID string   `json:"assistant_message_id,omitempty"`
+	Step               int      `json:"step"`
+	Attempt            int      `json:"attempt"`
+	Status             string   `json:"status"`
+	AgentID            string   `json:"agent_id,omitempty"`
+	ModelRef           string   `json:"model_ref,omitempty"`
+	Provider           string   `json:"provider,omitempty"`
+	ProviderRequestID  string   `json:"provider_request_id,omitempty"`
+	API                string   `json:"api,omitempty"`
+	ModelID            string   `json:"model_id,omitempty"`
+	FinishReason       string   `json:"finish_reason,omitempty"`
+	StopReason         string   `json:"stop_reason,omitempty"`
+	ErrorType          string   `json:"error_type,omitempty"`
+	ErrorMessage       string   `json:"error_message,omitempty"`
+	InputTokens        int      `json:"input_tokens"`
+	OutputTokens       int      `json:"output_tokens"`
+	ReasoningTokens    int      `json:"reasoning_tokens,omitempty"`
+	CachedInputTokens  int      `json:"cached_input_tokens,omitempty"`
+	CacheWriteTokens   int      `json:"cache_write_tokens,omitempty"`
+	TotalTokens        int      `json:"total_tokens"`
+	ContextTokens      int      `json:"context_tokens"`
+	ContextWindow      int      `json:"context_window,omitempty"`
+	ContextPercent     float64  `json:"context_percent,omitempty"`
+	Cost               *float64 `json:"cost,omitempty"`
+	// ThrottledMS is how long the provider rate limiter held the call back.
+	ThrottledMS int64 `json:"throttled_ms,omitempty"`
+	// Credential names the provider credential that served the call when
+	// the provider has several.
+	Credential           string          `json:"credential,omitempty"`
 	StructuredOutputJSON []byte          `json:"-"`
 	MetadataJSON         []byte          `json:"-"`
 	Trace                json.RawMessage `json:"trace,omitempty"`
@@ -361,9 +694,25 @@ type AuthCredential struct {
 	Refresh   string `json:"refresh,omitempty"`
 	ExpiresAt int64  `json:"expires_at,omitempty"`
 	AccountID string `json:"account_id,omitempty"`
+
+	// MCP OAuth credentials also record the dynamically registered client
+	// and the token endpoint needed to refresh them.
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	TokenURL     string `json:"token_url,omitempty"`
+	Resource     string `json:"resource,omitempty"`
+	Scope        string `json:"scope,omitempty"`
 }
 
+// Auth holds stored credentials. SetAuth writes Providers only; MCP server
+// credentials are written one server at a time with SetMCPCredential so that
+// token refreshes never race provider logins.
 type Auth struct {
 	Providers map[string]AuthCredential `json:"providers"`
-	UpdatedAt string                    `json:"updated_at"`
+	// ProviderCredentials holds named credentials keyed by provider ID and
+	// then name, beside each provider's single credential in Providers.
+	// SetAuth writes both.
+	ProviderCredentials map[string]map[string]AuthCredential `json:"provider_credentials,omitempty"`
+	MCP                 map[string]AuthCredential            `json:"mcp,omitempty"`
+	UpdatedAt           string                               `json:"updated_at"`
 }
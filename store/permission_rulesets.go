@@ -0,0 +1,53 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chaserensberger/wingman/permission"
+)
+
+// PermissionRulesetVersionConflict reports a stale expected ruleset version.
+type PermissionRulesetVersionConflict struct {
+	Scope, ScopeID    string
+	Expected, Current int64
+}
+
+func (e *PermissionRulesetVersionConflict) Error() string {
+	return fmt.Sprintf("%s %s: %s: expected version %d, current %d", e.Scope, e.ScopeID, ErrPermissionRulesetVersionConflict.Error(), e.Expected, e.Current)
+}
+
+func (e *PermissionRulesetVersionConflict) Unwrap() error { return ErrPermissionRulesetVersionConflict }
+
+// ValidPermissionScope reports whether scope owns persisted rulesets.
+func ValidPermissionScope(scope string) bool {
+	return scope == PermissionScopeWorkspace || scope == PermissionScopeAgent
+}
+
+// NextPermissionRuleset applies update to current and returns the new
+// ruleset with its audit entry. Both store implementations share it so the
+// versioning and validation contract cannot drift.
+func NextPermissionRuleset(current PermissionRuleset, update PermissionRulesetUpdate, now time.Time) (PermissionRuleset, PermissionRuleChange, error) {
+	if !ValidPermissionScope(update.Scope) {
+		return PermissionRuleset{}, PermissionRuleChange{}, fmt.Errorf("unknown permission scope %q", update.Scope)
+	}
+	if update.ScopeID == "" {
+		return PermissionRuleset{}, PermissionRuleChange{}, fmt.Errorf("permission scope ID is required")
+	}
+	switch update.Operation {
+	case PermissionRuleChangeReplace, PermissionRuleChangeDelete, PermissionRuleChangePromote:
+	default:
+		return PermissionRuleset{}, PermissionRuleChange{}, fmt.Errorf("unknown permission rule change operation %q", update.Operation)
+	}
+	if update.ExpectedVersion != nil && *update.ExpectedVersion != current.Version {
+		return PermissionRuleset{}, PermissionRuleChange{}, &PermissionRulesetVersionConflict{Scope: update.Scope, ScopeID: update.ScopeID, Expected: *update.ExpectedVersion, Current: current.Version}
+	}
+	rules := append(permission.Ruleset{}, update.Rules...)
+	next := PermissionRuleset{Scope: update.Scope, ScopeID: update.ScopeID, Rules: rules, Version: current.Version + 1, UpdatedAt: now}
+	change := PermissionRuleChange{
+		ID: NewID(PrefixPermissionChange), Scope: update.Scope, ScopeID: update.ScopeID, Version: next.Version,
+		Operation: update.Operation, ClientID: update.ClientID, SessionID: update.SessionID, GrantID: update.GrantID,
+		Before: append(permission.Ruleset{}, current.Rules...), After: append(permission.Ruleset{}, rules...), CreatedAt: now,
+	}
+	return next, change, nil
+}
@@ -0,0 +1,84 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/chaserensberger/wingman/permission"
+	"github.com/chaserensberger/wingman/store"
+	"github.com/chaserensberger/wingman/store/memory"
+)
+
+func TestPermissionRulesetParity(t *testing.T) {
+	for _, open := range []struct {
+		name string
+		open func(*testing.T) store.Store
+	}{
+		{"sqlite", func(t *testing.T) store.Store {
+			data, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "wingman.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = data.Close() })
+			return data
+		}},
+		{"memory", func(t *testing.T) store.Store { return memory.NewStore() }},
+	} {
+		t.Run(open.name, func(t *testing.T) {
+			ctx, data := context.Background(), open.open(t)
+			workspace := &store.Workspace{Name: "rules", Path: t.TempDir()}
+			if err := data.CreateWorkspace(workspace); err != nil {
+				t.Fatal(err)
+			}
+			empty, err := data.GetPermissionRuleset(ctx, store.PermissionScopeWorkspace, workspace.ID)
+			if err != nil || empty.Version != 0 || len(empty.Rules) != 0 {
+				t.Fatalf("empty ruleset = %#v, %v", empty, err)
+			}
+			rules := permission.Ruleset{{Action: "shell.exec", Resource: "git *", Effect: permission.EffectAllow}}
+			zero := int64(0)
+			first, err := data.UpdatePermissionRuleset(ctx, store.PermissionRulesetUpdate{Scope: store.PermissionScopeWorkspace, ScopeID: workspace.ID, ExpectedVersion: &zero, Rules: rules, Operation: store.PermissionRuleChangeReplace, ClientID: "cli_one"})
+			if err != nil || !first.Changed || first.Ruleset.Version != 1 || first.Change.Version != 1 || len(first.Change.Before) != 0 || len(first.Change.After) != 1 {
+				t.Fatalf("first update = %#v, %v", first, err)
+			}
+			if _, err := data.UpdatePermissionRuleset(ctx, store.PermissionRulesetUpdate{Scope: store.PermissionScopeWorkspace, ScopeID: workspace.ID, ExpectedVersion: &zero, Operation: store.PermissionRuleChangeDelete}); !errors.Is(err, store.ErrPermissionRulesetVersionConflict) {
+				t.Fatalf("stale update error = %v", err)
+			}
+			if _, err := data.UpdatePermissionRuleset(ctx, store.PermissionRulesetUpdate{Scope: "session", ScopeID: "ses_one", Operation: store.PermissionRuleChangeReplace}); err == nil {
+				t.Fatal("unknown scope update succeeded")
+			}
+			same, err := data.UpdatePermissionRuleset(ctx, store.PermissionRulesetUpdate{Scope: store.PermissionScopeWorkspace, ScopeID: workspace.ID, Rules: rules, Operation: store.PermissionRuleChangeReplace})
+			if err != nil || same.Changed || same.Ruleset.Version != 1 {
+				t.Fatalf("unchanged update = %#v, %v", same, err)
+			}
+			second, err := data.UpdatePermissionRuleset(ctx, store.PermissionRulesetUpdate{Scope: store.PermissionScopeWorkspace, ScopeID: workspace.ID, Rules: append(rules, permission.Rule{Action: "filesystem.write", Resource: "*", Effect: permission.EffectAllow}), Operation: store.PermissionRuleChangePromote, SessionID: "ses_one", GrantID: "pgr_one"})
+			if err != nil || second.Ruleset.Version != 2 || len(second.Change.Before) != 1 || len(second.Change.After) != 2 {
+				t.Fatalf("second update = %#v, %v", second, err)
+			}
+			current, err := data.GetPermissionRuleset(ctx, store.PermissionScopeWorkspace, workspace.ID)
+			if err != nil || current.Version != 2 || len(current.Rules) != 2 || current.Rules[1].Action != "filesystem.write" {
+				t.Fatalf("current ruleset = %#v, %v", current, err)
+			}
+			other, err := data.GetPermissionRuleset(ctx, store.PermissionScopeAgent, workspace.ID)
+			if err != nil || other.Version != 0 {
+				t.Fatalf("agent ruleset with workspace ID = %#v, %v", other, err)
+			}
+
+			if err := data.DeleteWorkspace(workspace.ID); err != nil {
+				t.Fatal(err)
+			}
+			deleted, err := data.GetPermissionRuleset(ctx, store.PermissionScopeWorkspace, workspace.ID)
+			if err != nil || deleted.Version != 0 || len(deleted.Rules) != 0 {
+				t.Fatalf("ruleset after workspace delete = %#v, %v", deleted, err)
+			}
+			history, err := data.ListPermissionRuleChanges(ctx, store.PermissionScopeWorkspace, workspace.ID)
+			if err != nil || len(history) != 2 {
+				t.Fatalf("history = %#v, %v", history, err)
+			}
+			if history[0].ClientID != "cli_one" || history[0].Operation != store.PermissionRuleChangeReplace || history[1].GrantID != "pgr_one" || history[1].SessionID != "ses_one" {
+				t.Fatalf("history = %#v", history)
+			}
+		})
+	}
+}
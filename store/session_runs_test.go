@@ -347,3 +347,29 @@ func TestAdmitSessionRunSnapshotsPlacement(t *testing.T) {
 		t.Fatalf("claimed run = %#v, want original placement", claimed)
 	}
 }
+
+func TestAdmitSessionRunKeepsPriority(t *testing.T) {
+	data := newTestSQLiteStore(t)
+	ctx := context.Background()
+	if err := data.CreateSession(&Session{ID: "ses_priority"}); err != nil {
+		t.Fatal(err)
+	}
+	admission, err := data.AdmitSessionRun(ctx, SessionRun{SessionID: "ses_priority", RequestID: "request", Message: "hello", Priority: 3, Agent: Agent{ID: "agt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := data.AdmitSessionRun(ctx, SessionRun{SessionID: "ses_priority", RequestID: "request", Message: "hello", Priority: 1, Agent: Agent{ID: "agt"}}); !errors.Is(err, ErrSessionRunAdmissionConflict) {
+		t.Fatalf("retry with another priority error = %v, want admission conflict", err)
+	}
+	run, err := data.GetSessionRun(ctx, "ses_priority", admission.Run.ID)
+	if err != nil || run.Priority != 3 {
+		t.Fatalf("run = %#v, error = %v", run, err)
+	}
+	events, err := data.ListAggregateEvents(ctx, AggregateRef{Type: AggregateSession, ID: "ses_priority"}, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if projected, err := ProjectSessionRunAdmission(events[1]); err != nil || projected.Priority != 3 {
+		t.Fatalf("projected run = %#v, error = %v", projected, err)
+	}
+}
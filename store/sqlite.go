@@ -25,6 +25,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/chaserensberger/wingman/permission"
 	_ "modernc.org/sqlite"
 )
 
@@ -32,6 +33,8 @@ import (
 // NewSQLiteStore; share a single instance across the process.
 type SQLiteStore struct {
 	db *sql.DB
+	// credentialKeys seals the auth row when set; see UseCredentialKeys.
+	credentialKeys CredentialKeys
 }
 
 type immediateTx struct {
@@ -311,7 +314,7 @@ func replaceSQLiteSessionProjection(ctx context.Context, tx *immediateTx, projec
 		}
 	}
 	for _, call := range projection.ModelCalls {
-		if _, err := tx.ExecContext(ctx, `INSERT INTO model_calls (id, session_id, run_id, assistant_message_id, step, attempt, status, agent_id, model_ref, provider, provider_request_id, api, model_id, finish_reason, stop_reason, error_type, error_message, input_tokens, output_tokens, reasoning_tokens, cached_input_tokens, cache_write_tokens, total_tokens, context_tokens, context_window, context_percent, cost, structured_output_json, metadata_json, started_at, completed_at, created_at, updated_at) VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, call.ID, call.SessionID, call.RunID, call.AssistantMessageID, call.Step, call.Attempt, call.Status, call.AgentID, call.ModelRef, call.Provider, call.ProviderRequestID, call.API, call.ModelID, call.FinishReason, call.StopReason, call.ErrorType, call.ErrorMessage, call.InputTokens, call.OutputTokens, call.ReasoningTokens, call.CachedInputTokens, call.CacheWriteTokens, call.TotalTokens, call.ContextTokens, call.ContextWindow, call.ContextPercent, call.Cost, nullableBytes(call.StructuredOutputJSON), nullableBytes(call.MetadataJSON), formatTime(call.StartedAt), nullableTime(call.CompletedAt), formatTime(call.CreatedAt), formatTime(call.UpdatedAt)); err != nil {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO model_calls (id, session_id, run_id, assistant_message_id, step, attempt, status, agent_id, model_ref, provider, provider_request_id, api, model_id, finish_reason, stop_reason, error_type, error_message, input_tokens, output_tokens, reasoning_tokens, cached_input_tokens, cache_write_tokens, total_tokens, context_tokens, context_window, context_percent, cost, throttled_ms, credential, structured_output_json, metadata_json, started_at, completed_at, created_at, updated_at) VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, call.ID, call.SessionID, call.RunID, call.AssistantMessageID, call.Step, call.Attempt, call.Status, call.AgentID, call.ModelRef, call.Provider, call.ProviderRequestID, call.API, call.ModelID, call.FinishReason, call.StopReason, call.ErrorType, call.ErrorMessage, call.InputTokens, call.OutputTokens, call.ReasoningTokens, call.CachedInputTokens, call.CacheWriteTokens, call.TotalTokens, call.ContextTokens, call.ContextWindow, call.ContextPercent, call.Cost, call.ThrottledMS, call.Credential, nullableBytes(call.StructuredOutputJSON), nullableBytes(call.MetadataJSON), formatTime(call.StartedAt), nullableTime(call.CompletedAt), formatTime(call.CreatedAt), formatTime(call.UpdatedAt)); err != nil {
 			return fmt.Errorf("insert model call: %w", err)
 		}
 	}
@@ -325,7 +328,7 @@ func replaceSQLiteSessionProjection(ctx context.Context, tx *immediateTx, projec
 		if err != nil {
 			return err
 		}
-		if _, err := tx.ExecContext(ctx, `INSERT INTO permission_requests (id, session_id, run_id, tool_use_id, call_id, action, resources_json, status, response, error_type, error_message, created_at, resolved_at, updated_at) VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, request.ID, request.SessionID, request.RunID, request.ToolUseID, request.CallID, request.Action, string(resources), request.Status, request.Response, request.ErrorType, request.ErrorMessage, formatTime(request.CreatedAt), nullableTime(request.ResolvedAt), formatTime(request.UpdatedAt)); err != nil {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO permission_requests (id, session_id, run_id, tool_use_id, call_id, action, resources_json, status, response, error_type, error_message, edited_input_json, message, created_at, resolved_at, updated_at) VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, request.ID, request.SessionID, request.RunID, request.ToolUseID, request.CallID, request.Action, string(resources), request.Status, request.Response, request.ErrorType, request.ErrorMessage, nullableBytes(request.EditedInput), request.Message, formatTime(request.CreatedAt), nullableTime(request.ResolvedAt), formatTime(request.UpdatedAt)); err != nil {
 			return fmt.Errorf("insert permission request: %w", err)
 		}
 	}
@@ -490,21 +493,36 @@ func (s *SQLiteStore) CreateAgent(agent *Agent) error {
 	if err != nil {
 		return err
 	}
+	limitsJSON, err := marshalLimits(agent.Limits)
+	if err != nil {
+		return err
+	}
 
-	_, err = s.db.Exec(`
-		INSERT INTO agents (id, name, instructions, tools_json, permissions_json, model_ref, options_json, output_schema_json, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, agent.ID, agent.Name, agent.Instructions, string(tools), permissionsJSON, agent.ModelRef, optionsJSON, outputSchemaJSON, agent.CreatedAt, agent.UpdatedAt)
+	agent.Revision = 1
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO agents (id, name, instructions, tools_json, permissions_json, model_ref, options_json, output_schema_json, limits_json, revision, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, agent.ID, agent.Name, agent.Instructions, string(tools), permissionsJSON, agent.ModelRef, optionsJSON, outputSchemaJSON, limitsJSON, agent.Revision, agent.CreatedAt, agent.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("insert agent: %w", err)
 	}
-	return nil
+	if err := insertAgentRevision(tx, agent); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // GetAgent returns the agent with the given ID, or an error if not found.
 func (s *SQLiteStore) GetAgent(id string) (*Agent, error) {
 	row := s.db.QueryRow(`
-		SELECT id, name, instructions, tools_json, permissions_json, model_ref, options_json, output_schema_json, created_at, updated_at
+		SELECT id, name, instructions, tools_json, permissions_json, model_ref, options_json, output_schema_json, limits_json, revision, created_at, updated_at
 		FROM agents WHERE id = ?
 	`, id)
 	a, err := scanAgent(row)
@@ -517,7 +535,7 @@ func (s *SQLiteStore) GetAgent(id string) (*Agent, error) {
 // ListAgents returns every agent, newest first by created_at.
 func (s *SQLiteStore) ListAgents() ([]*Agent, error) {
 	rows, err := s.db.Query(`
-		SELECT id, name, instructions, tools_json, permissions_json, model_ref, options_json, output_schema_json, created_at, updated_at
+		SELECT id, name, instructions, tools_json, permissions_json, model_ref, options_json, output_schema_json, limits_json, revision, created_at, updated_at
 		FROM agents ORDER BY created_at DESC
 	`)
 	if err != nil {
@@ -557,28 +575,98 @@ func (s *SQLiteStore) UpdateAgent(agent *Agent) error {
 	if err != nil {
 		return err
 	}
+	limitsJSON, err := marshalLimits(agent.Limits)
+	if err != nil {
+		return err
+	}
 
-	res, err := s.db.Exec(`
-		UPDATE agents SET name = ?, instructions = ?, tools_json = ?, permissions_json = ?, model_ref = ?, options_json = ?, output_schema_json = ?, updated_at = ?
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		UPDATE agents SET name = ?, instructions = ?, tools_json = ?, permissions_json = ?, model_ref = ?, options_json = ?, output_schema_json = ?, limits_json = ?, revision = revision + 1, updated_at = ?
 		WHERE id = ?
-	`, agent.Name, agent.Instructions, string(tools), permissionsJSON, agent.ModelRef, optionsJSON, outputSchemaJSON, agent.UpdatedAt, agent.ID)
+		RETURNING revision, created_at
+	`, agent.Name, agent.Instructions, string(tools), permissionsJSON, agent.ModelRef, optionsJSON, outputSchemaJSON, limitsJSON, agent.UpdatedAt, agent.ID).Scan(&agent.Revision, &agent.CreatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("agent not found: %s", agent.ID)
+	}
 	if err != nil {
 		return err
 	}
-	n, err := res.RowsAffected()
+	if err := insertAgentRevision(tx, agent); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertAgentRevision(tx *sql.Tx, agent *Agent) error {
+	snapshot, err := json.Marshal(agent)
 	if err != nil {
 		return err
 	}
-	if n == 0 {
-		return fmt.Errorf("agent not found: %s", agent.ID)
+	if _, err := tx.Exec(`
+		INSERT INTO agent_revisions (agent_id, revision, agent_json, created_at) VALUES (?, ?, ?, ?)
+	`, agent.ID, agent.Revision, string(snapshot), agent.UpdatedAt); err != nil {
+		return fmt.Errorf("insert agent revision: %w", err)
 	}
 	return nil
 }
 
+// ListAgentRevisions returns an agent's revisions oldest first.
+func (s *SQLiteStore) ListAgentRevisions(id string) ([]AgentRevision, error) {
+	rows, err := s.db.Query(`
+		SELECT agent_id, revision, agent_json, created_at FROM agent_revisions
+		WHERE agent_id = ? ORDER BY revision
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AgentRevision
+	for rows.Next() {
+		revision, err := scanAgentRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("agent not found: %s", id)
+	}
+	return out, nil
+}
+
+// GetAgentRevision returns one immutable agent revision.
+func (s *SQLiteStore) GetAgentRevision(id string, revision int64) (*AgentRevision, error) {
+	row := s.db.QueryRow(`
+		SELECT agent_id, revision, agent_json, created_at FROM agent_revisions
+		WHERE agent_id = ? AND revision = ?
+	`, id, revision)
+	r, err := scanAgentRevision(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("agent revision not found: %s@%d", id, revision)
+	}
+	return r, err
+}
+
 // DeleteAgent removes the agent. Returns an error if not found. Does NOT
 // cascade to sessions (sessions reference agents only at runtime).
 func (s *SQLiteStore) DeleteAgent(id string) error {
-	res, err := s.db.Exec(`DELETE FROM agents WHERE id = ?`, id)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM agents WHERE id = ?`, id)
 	if err != nil {
 		return err
 	}
@@ -589,9 +677,141 @@ func (s *SQLiteStore) DeleteAgent(id string) error {
 	if n == 0 {
 		return fmt.Errorf("agent not found: %s", id)
 	}
+	if _, err := tx.Exec(`DELETE FROM agent_revisions WHERE agent_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM permission_rulesets WHERE scope = ? AND scope_id = ?`, PermissionScopeAgent, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ---- commands ------------------------------------------------------------
+
+// CreateCommand inserts a new command row. If command.ID is empty, a fresh
+// KSUID is minted. CreatedAt/UpdatedAt are always overwritten with Now().
+func (s *SQLiteStore) CreateCommand(command *Command) error {
+	if command.ID == "" {
+		command.ID = NewID(PrefixCommand)
+	}
+	now := Now()
+	command.CreatedAt = now
+	command.UpdatedAt = now
+
+	argumentsJSON, err := marshalCommandArguments(command.Arguments)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`
+		INSERT INTO commands (id, name, description, template, arguments_json, agent_id, model_ref, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, command.ID, command.Name, command.Description, command.Template, argumentsJSON, command.AgentID, command.ModelRef, command.CreatedAt, command.UpdatedAt); err != nil {
+		return fmt.Errorf("insert command: %w", err)
+	}
 	return nil
 }
 
+// GetCommand returns the command with the given ID, or an error if not found.
+func (s *SQLiteStore) GetCommand(id string) (*Command, error) {
+	row := s.db.QueryRow(`
+		SELECT id, name, description, template, arguments_json, agent_id, model_ref, created_at, updated_at
+		FROM commands WHERE id = ?
+	`, id)
+	command, err := scanCommand(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("command not found: %s", id)
+	}
+	return command, err
+}
+
+// ListCommands returns every command sorted by name.
+func (s *SQLiteStore) ListCommands() ([]*Command, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, description, template, arguments_json, agent_id, model_ref, created_at, updated_at
+		FROM commands ORDER BY name, id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Command
+	for rows.Next() {
+		command, err := scanCommand(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, command)
+	}
+	return out, rows.Err()
+}
+
+// UpdateCommand overwrites the command's mutable fields. Returns an error if
+// the row does not exist.
+func (s *SQLiteStore) UpdateCommand(command *Command) error {
+	command.UpdatedAt = Now()
+	argumentsJSON, err := marshalCommandArguments(command.Arguments)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec(`
+		UPDATE commands SET name = ?, description = ?, template = ?, arguments_json = ?, agent_id = ?, model_ref = ?, updated_at = ?
+		WHERE id = ?
+	`, command.Name, command.Description, command.Template, argumentsJSON, command.AgentID, command.ModelRef, command.UpdatedAt, command.ID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("command not found: %s", command.ID)
+	}
+	return nil
+}
+
+// DeleteCommand removes the command. Returns an error if not found.
+func (s *SQLiteStore) DeleteCommand(id string) error {
+	res, err := s.db.Exec(`DELETE FROM commands WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("command not found: %s", id)
+	}
+	return nil
+}
+
+func marshalCommandArguments(arguments []CommandArgument) (*string, error) {
+	if len(arguments) == 0 {
+		return nil, nil
+	}
+	return marshalNullable(arguments)
+}
+
+// scanCommand reads one command row from any rowScanner.
+func scanCommand(r rowScanner) (*Command, error) {
+	var command Command
+	var argumentsJSON sql.NullString
+	if err := r.Scan(
+		&command.ID, &command.Name, &command.Description, &command.Template, &argumentsJSON,
+		&command.AgentID, &command.ModelRef, &command.CreatedAt, &command.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if argumentsJSON.Valid && argumentsJSON.String != "" {
+		if err := json.Unmarshal([]byte(argumentsJSON.String), &command.Arguments); err != nil {
+			return nil, err
+		}
+	}
+	return &command, nil
+}
+
 // ---- clients -------------------------------------------------------------
 
 // CreateClient inserts a new Wingman API client row with a fresh KSUID and the
@@ -726,10 +946,14 @@ func (s *SQLiteStore) CreateWorkspace(workspace *Workspace) error {
 	if workspace.ClientID != "" {
 		clientIDPtr = &workspace.ClientID
 	}
+	credentialsJSON, err := marshalWorkspaceCredentials(workspace.Credentials)
+	if err != nil {
+		return err
+	}
 	if _, err := tx.Exec(`
-		INSERT INTO workspaces (id, name, path, client_id, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, workspace.ID, workspace.Name, workspace.Path, clientIDPtr, workspace.CreatedAt, workspace.UpdatedAt); err != nil {
+		INSERT INTO workspaces (id, name, path, client_id, credentials_json, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, workspace.ID, workspace.Name, workspace.Path, clientIDPtr, credentialsJSON, workspace.CreatedAt, workspace.UpdatedAt); err != nil {
 		return fmt.Errorf("insert workspace: %w", err)
 	}
 
@@ -738,25 +962,22 @@ func (s *SQLiteStore) CreateWorkspace(workspace *Workspace) error {
 
 // GetWorkspace returns the workspace with the given ID, or an error if not found.
 func (s *SQLiteStore) GetWorkspace(id string) (*Workspace, error) {
-	var workspace Workspace
-	var clientID sql.NullString
-	err := s.db.QueryRow(`
-		SELECT id, name, path, client_id, created_at, updated_at FROM workspaces WHERE id = ?
-	`, id).Scan(&workspace.ID, &workspace.Name, &workspace.Path, &clientID, &workspace.CreatedAt, &workspace.UpdatedAt)
+	workspace, err := scanWorkspace(s.db.QueryRow(`
+		SELECT `+workspaceColumns+` FROM workspaces WHERE id = ?
+	`, id))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("workspace not found: %s", id)
 	}
 	if err != nil {
 		return nil, err
 	}
-	workspace.ClientID = clientID.String
-	return &workspace, nil
+	return workspace, nil
 }
 
 // ListWorkspaces returns every workspace, newest first by created_at.
 func (s *SQLiteStore) ListWorkspaces() ([]*Workspace, error) {
 	rows, err := s.db.Query(`
-		SELECT id, name, path, client_id, created_at, updated_at FROM workspaces ORDER BY created_at DESC
+		SELECT ` + workspaceColumns + ` FROM workspaces ORDER BY created_at DESC
 	`)
 	if err != nil {
 		return nil, err
@@ -768,7 +989,7 @@ func (s *SQLiteStore) ListWorkspaces() ([]*Workspace, error) {
 // ListWorkspacesByClient returns every workspace attributed to a specific client.
 func (s *SQLiteStore) ListWorkspacesByClient(clientID string) ([]*Workspace, error) {
 	rows, err := s.db.Query(`
-		SELECT id, name, path, client_id, created_at, updated_at FROM workspaces WHERE client_id = ? ORDER BY created_at DESC
+		SELECT `+workspaceColumns+` FROM workspaces WHERE client_id = ? ORDER BY created_at DESC
 	`, clientID)
 	if err != nil {
 		return nil, err
@@ -777,16 +998,31 @@ func (s *SQLiteStore) ListWorkspacesByClient(clientID string) ([]*Workspace, err
 	return scanWorkspaces(rows)
 }
 
+const workspaceColumns = `id, name, path, client_id, credentials_json, created_at, updated_at`
+
+func scanWorkspace(r rowScanner) (*Workspace, error) {
+	var workspace Workspace
+	var clientID, credentialsJSON sql.NullString
+	if err := r.Scan(&workspace.ID, &workspace.Name, &workspace.Path, &clientID, &credentialsJSON, &workspace.CreatedAt, &workspace.UpdatedAt); err != nil {
+		return nil, err
+	}
+	workspace.ClientID = clientID.String
+	if credentialsJSON.Valid && credentialsJSON.String != "" {
+		if err := json.Unmarshal([]byte(credentialsJSON.String), &workspace.Credentials); err != nil {
+			return nil, err
+		}
+	}
+	return &workspace, nil
+}
+
 func scanWorkspaces(rows *sql.Rows) ([]*Workspace, error) {
 	var out []*Workspace
 	for rows.Next() {
-		var workspace Workspace
-		var clientID sql.NullString
-		if err := rows.Scan(&workspace.ID, &workspace.Name, &workspace.Path, &clientID, &workspace.CreatedAt, &workspace.UpdatedAt); err != nil {
+		workspace, err := scanWorkspace(rows)
+		if err != nil {
 			return nil, err
 		}
-		workspace.ClientID = clientID.String
-		out = append(out, &workspace)
+		out = append(out, workspace)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -804,10 +1040,14 @@ func (s *SQLiteStore) UpdateWorkspace(workspace *Workspace) error {
 	if workspace.ClientID != "" {
 		clientIDPtr = &workspace.ClientID
 	}
+	credentialsJSON, err := marshalWorkspaceCredentials(workspace.Credentials)
+	if err != nil {
+		return err
+	}
 
 	res, err := s.db.Exec(`
-		UPDATE workspaces SET name = ?, path = ?, client_id = ?, updated_at = ? WHERE id = ?
-	`, workspace.Name, workspace.Path, clientIDPtr, workspace.UpdatedAt, workspace.ID)
+		UPDATE workspaces SET name = ?, path = ?, client_id = ?, credentials_json = ?, updated_at = ? WHERE id = ?
+	`, workspace.Name, workspace.Path, clientIDPtr, credentialsJSON, workspace.UpdatedAt, workspace.ID)
 	if err != nil {
 		return err
 	}
@@ -845,7 +1085,13 @@ func verifyWorkspaceNameAvailable(q queryer, clientID, name, excludeID string) e
 // DeleteWorkspace removes the workspace. Linked sessions keep their work_dir and
 // have workspace_id set to NULL by the foreign key.
 func (s *SQLiteStore) DeleteWorkspace(id string) error {
-	res, err := s.db.Exec(`DELETE FROM workspaces WHERE id = ?`, id)
+	ctx := context.Background()
+	tx, err := s.beginImmediate(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, `DELETE FROM workspaces WHERE id = ?`, id)
 	if err != nil {
 		return err
 	}
@@ -856,7 +1102,10 @@ func (s *SQLiteStore) DeleteWorkspace(id string) error {
 	if n == 0 {
 		return fmt.Errorf("workspace not found: %s", id)
 	}
-	return nil
+	if _, err := tx.ExecContext(ctx, `DELETE FROM permission_rulesets WHERE scope = ? AND scope_id = ?`, PermissionScopeWorkspace, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 // ---- sessions ------------------------------------------------------------
@@ -1211,7 +1460,7 @@ func (s *SQLiteStore) CreatePermissionRequest(ctx context.Context, request Permi
 	if err != nil {
 		return PermissionRequestTransition{}, err
 	}
-	if existing, err := scanPermissionRequest(tx.QueryRowContext(ctx, `SELECT id, session_id, COALESCE(run_id, ''), COALESCE(tool_use_id, ''), call_id, action, resources_json, status, response, error_type, error_message, created_at, resolved_at, updated_at FROM permission_requests WHERE id = ?`, request.ID)); err == nil {
+	if existing, err := scanPermissionRequest(tx.QueryRowContext(ctx, `SELECT `+permissionRequestColumns+` FROM permission_requests WHERE id = ?`, request.ID)); err == nil {
 		if samePendingPermissionRequest(existing, request) {
 			if err := tx.Commit(ctx); err != nil {
 				return PermissionRequestTransition{}, err
@@ -1254,7 +1503,7 @@ func (s *SQLiteStore) GetPermissionRequest(ctx context.Context, sessionID, reque
 	if err := s.sessionExists(ctx, sessionID); err != nil {
 		return nil, err
 	}
-	request, err := scanPermissionRequest(s.db.QueryRowContext(ctx, `SELECT id, session_id, COALESCE(run_id, ''), COALESCE(tool_use_id, ''), call_id, action, resources_json, status, response, error_type, error_message, created_at, resolved_at, updated_at FROM permission_requests WHERE id = ? AND session_id = ?`, requestID, sessionID))
+	request, err := scanPermissionRequest(s.db.QueryRowContext(ctx, `SELECT `+permissionRequestColumns+` FROM permission_requests WHERE id = ? AND session_id = ?`, requestID, sessionID))
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, &PermissionRequestNotFound{SessionID: sessionID, RequestID: requestID}
 	}
@@ -1268,7 +1517,7 @@ func (s *SQLiteStore) ListPermissionRequests(ctx context.Context, sessionID stri
 	if err := s.sessionExists(ctx, sessionID); err != nil {
 		return nil, err
 	}
-	rows, err := s.db.QueryContext(ctx, `SELECT id, session_id, COALESCE(run_id, ''), COALESCE(tool_use_id, ''), call_id, action, resources_json, status, response, error_type, error_message, created_at, resolved_at, updated_at FROM permission_requests WHERE session_id = ? ORDER BY created_at, id`, sessionID)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+permissionRequestColumns+` FROM permission_requests WHERE session_id = ? ORDER BY created_at, id`, sessionID)
 	if err != nil {
 		return nil, err
 	}
@@ -1300,7 +1549,7 @@ func (s *SQLiteStore) ResolvePermissionRequest(ctx context.Context, resolution P
 	if err != nil {
 		return PermissionRequestTransition{}, err
 	}
-	request, err := scanPermissionRequest(tx.QueryRowContext(ctx, `SELECT id, session_id, COALESCE(run_id, ''), COALESCE(tool_use_id, ''), call_id, action, resources_json, status, response, error_type, error_message, created_at, resolved_at, updated_at FROM permission_requests WHERE id = ? AND session_id = ?`, resolution.RequestID, resolution.SessionID))
+	request, err := scanPermissionRequest(tx.QueryRowContext(ctx, `SELECT `+permissionRequestColumns+` FROM permission_requests WHERE id = ? AND session_id = ?`, resolution.RequestID, resolution.SessionID))
 	if errors.Is(err, sql.ErrNoRows) {
 		return PermissionRequestTransition{}, &PermissionRequestNotFound{SessionID: resolution.SessionID, RequestID: resolution.RequestID}
 	}
@@ -1315,8 +1564,9 @@ func (s *SQLiteStore) ResolvePermissionRequest(ctx context.Context, resolution P
 	}
 	now := time.Now().UTC()
 	request.Status, request.Response, request.ErrorType, request.ErrorMessage = resolution.Status, resolution.Response, resolution.ErrorType, resolution.ErrorMessage
+	request.EditedInput, request.Message = resolution.EditedInput, resolution.Message
 	request.ResolvedAt, request.UpdatedAt = now, now
-	if _, err := tx.ExecContext(ctx, `UPDATE permission_requests SET status = ?, response = ?, error_type = ?, error_message = ?, resolved_at = ?, updated_at = ? WHERE id = ? AND session_id = ? AND status = ?`, request.Status, request.Response, request.ErrorType, request.ErrorMessage, formatTime(now), formatTime(now), request.ID, request.SessionID, PermissionRequestStatusPending); err != nil {
+	if _, err := tx.ExecContext(ctx, `UPDATE permission_requests SET status = ?, response = ?, error_type = ?, error_message = ?, edited_input_json = ?, message = ?, resolved_at = ?, updated_at = ? WHERE id = ? AND session_id = ? AND status = ?`, request.Status, request.Response, request.ErrorType, request.ErrorMessage, nullableBytes(request.EditedInput), request.Message, formatTime(now), formatTime(now), request.ID, request.SessionID, PermissionRequestStatusPending); err != nil {
 		return PermissionRequestTransition{}, err
 	}
 	event, err := appendPermissionEventTx(ctx, tx, request, "session.permission.resolved", now)
@@ -1382,7 +1632,7 @@ func (s *SQLiteStore) InterruptPendingPermissionRequests(ctx context.Context) ([
 		return nil, err
 	}
 	defer tx.Rollback()
-	rows, err := tx.QueryContext(ctx, `SELECT id, session_id, COALESCE(run_id, ''), COALESCE(tool_use_id, ''), call_id, action, resources_json, status, response, error_type, error_message, created_at, resolved_at, updated_at FROM permission_requests WHERE status = ? ORDER BY created_at, id`, PermissionRequestStatusPending)
+	rows, err := tx.QueryContext(ctx, `SELECT `+permissionRequestColumns+` FROM permission_requests WHERE status = ? ORDER BY created_at, id`, PermissionRequestStatusPending)
 	if err != nil {
 		return nil, err
 	}
@@ -1432,6 +1682,103 @@ func (s *SQLiteStore) InterruptPendingPermissionRequests(ctx context.Context) ([
 	return transitions, nil
 }
 
+// ---- permission rulesets ------------------------------------------------
+
+// GetPermissionRuleset returns the persisted ruleset for scope/scopeID.
+func (s *SQLiteStore) GetPermissionRuleset(ctx context.Context, scope, scopeID string) (*PermissionRuleset, error) {
+	ruleset, err := getPermissionRuleset(ctx, s.db, scope, scopeID)
+	if err != nil {
+		return nil, err
+	}
+	return &ruleset, nil
+}
+
+// UpdatePermissionRuleset replaces the ruleset and appends its audit entry.
+func (s *SQLiteStore) UpdatePermissionRuleset(ctx context.Context, update PermissionRulesetUpdate) (PermissionRulesetTransition, error) {
+	tx, err := s.beginImmediate(ctx)
+	if err != nil {
+		return PermissionRulesetTransition{}, err
+	}
+	defer tx.Rollback()
+	current, err := getPermissionRuleset(ctx, tx, update.Scope, update.ScopeID)
+	if err != nil {
+		return PermissionRulesetTransition{}, err
+	}
+	next, change, err := NextPermissionRuleset(current, update, time.Now().UTC())
+	if err != nil {
+		return PermissionRulesetTransition{}, err
+	}
+	if slices.Equal(current.Rules, next.Rules) {
+		return PermissionRulesetTransition{Ruleset: current}, nil
+	}
+	rules, err := json.Marshal(next.Rules)
+	if err != nil {
+		return PermissionRulesetTransition{}, err
+	}
+	before, err := json.Marshal(change.Before)
+	if err != nil {
+		return PermissionRulesetTransition{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO permission_rulesets (scope, scope_id, rules_json, version, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(scope, scope_id) DO UPDATE SET rules_json = excluded.rules_json, version = excluded.version, updated_at = excluded.updated_at
+	`, next.Scope, next.ScopeID, string(rules), next.Version, formatTime(next.UpdatedAt)); err != nil {
+		return PermissionRulesetTransition{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO permission_rule_changes (id, scope, scope_id, version, operation, client_id, session_id, grant_id, before_json, after_json, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, change.ID, change.Scope, change.ScopeID, change.Version, change.Operation, change.ClientID, change.SessionID, change.GrantID, string(before), string(rules), formatTime(change.CreatedAt)); err != nil {
+		return PermissionRulesetTransition{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return PermissionRulesetTransition{}, err
+	}
+	return PermissionRulesetTransition{Ruleset: next, Change: change, Changed: true}, nil
+}
+
+// ListPermissionRuleChanges returns the audit history for scope/scopeID.
+func (s *SQLiteStore) ListPermissionRuleChanges(ctx context.Context, scope, scopeID string) ([]PermissionRuleChange, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, scope, scope_id, version, operation, client_id, session_id, grant_id, before_json, after_json, created_at FROM permission_rule_changes WHERE scope = ? AND scope_id = ? ORDER BY created_at, version`, scope, scopeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []PermissionRuleChange{}
+	for rows.Next() {
+		var change PermissionRuleChange
+		var before, after, created string
+		if err := rows.Scan(&change.ID, &change.Scope, &change.ScopeID, &change.Version, &change.Operation, &change.ClientID, &change.SessionID, &change.GrantID, &before, &after, &created); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(before), &change.Before); err != nil {
+			return nil, fmt.Errorf("decode permission rule change %s: %w", change.ID, err)
+		}
+		if err := json.Unmarshal([]byte(after), &change.After); err != nil {
+			return nil, fmt.Errorf("decode permission rule change %s: %w", change.ID, err)
+		}
+		change.CreatedAt, _ = time.Parse(time.RFC3339Nano, created)
+		out = append(out, change)
+	}
+	return out, rows.Err()
+}
+
+func getPermissionRuleset(ctx context.Context, q interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}, scope, scopeID string) (PermissionRuleset, error) {
+	ruleset := PermissionRuleset{Scope: scope, ScopeID: scopeID, Rules: permission.Ruleset{}}
+	var rules, updated string
+	err := q.QueryRowContext(ctx, `SELECT rules_json, version, updated_at FROM permission_rulesets WHERE scope = ? AND scope_id = ?`, scope, scopeID).Scan(&rules, &ruleset.Version, &updated)
+	if err == sql.ErrNoRows {
+		return ruleset, nil
+	}
+	if err != nil {
+		return PermissionRuleset{}, err
+	}
+	if err := json.Unmarshal([]byte(rules), &ruleset.Rules); err != nil {
+		return PermissionRuleset{}, fmt.Errorf("decode %s %s permission rules: %w", scope, scopeID, err)
+	}
+	ruleset.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updated)
+	return ruleset, nil
+}
+
 // SaveMessage atomically stores a complete authoritative message revision.
 func (s *SQLiteStore) SaveMessage(ctx context.Context, msg StoredMessage) error {
 	if msg.Revision == 0 {
@@ -1854,10 +2201,10 @@ func (s *SQLiteStore) UpsertModelCall(ctx context.Context, call ModelCall) error
 			agent_id, model_ref, provider, provider_request_id, api, model_id,
 			finish_reason, stop_reason, error_type, error_message,
 			input_tokens, output_tokens, reasoning_tokens, cached_input_tokens, cache_write_tokens, total_tokens,
-			context_tokens, context_window, context_percent, cost,
+			context_tokens, context_window, context_percent, cost, throttled_ms, credential,
 			structured_output_json, metadata_json, started_at, completed_at, created_at, updated_at
 		)
-		VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			assistant_message_id = excluded.assistant_message_id,
 			status = excluded.status,
@@ -1881,6 +2228,8 @@ func (s *SQLiteStore) UpsertModelCall(ctx context.Context, call ModelCall) error
 			context_window = excluded.context_window,
 			context_percent = excluded.context_percent,
 			cost = excluded.cost,
+			throttled_ms = excluded.throttled_ms,
+			credential = excluded.credential,
 			structured_output_json = excluded.structured_output_json,
 			metadata_json = excluded.metadata_json,
 			completed_at = excluded.completed_at,
@@ -1889,7 +2238,7 @@ func (s *SQLiteStore) UpsertModelCall(ctx context.Context, call ModelCall) error
 		call.AgentID, call.ModelRef, call.Provider, call.ProviderRequestID, call.API, call.ModelID,
 		call.FinishReason, call.StopReason, call.ErrorType, call.ErrorMessage,
 		call.InputTokens, call.OutputTokens, call.ReasoningTokens, call.CachedInputTokens, call.CacheWriteTokens, call.TotalTokens,
-		call.ContextTokens, call.ContextWindow, call.ContextPercent, call.Cost,
+		call.ContextTokens, call.ContextWindow, call.ContextPercent, call.Cost, call.ThrottledMS, call.Credential,
 		nullableBytes(call.StructuredOutputJSON), nullableBytes(call.MetadataJSON), startedAt, completedAt, createdAt, updatedAt)
 	if err != nil {
 		if call.RunID != "" {
@@ -2220,6 +2569,45 @@ func (s *SQLiteStore) InterruptActiveToolUses(ctx context.Context) error {
 	return tx.Commit(ctx)
 }
 
+// SaveArtifact stores an immutable session artifact.
+func (s *SQLiteStore) SaveArtifact(ctx context.Context, artifact *Artifact) error {
+	if artifact.ID == "" {
+		artifact.ID = NewID(PrefixArtifact)
+	}
+	if artifact.MediaType == "" {
+		artifact.MediaType = "text/plain; charset=utf-8"
+	}
+	if artifact.CreatedAt.IsZero() {
+		artifact.CreatedAt = time.Now().UTC()
+	}
+	artifact.Size = int64(len(artifact.Content))
+	if err := s.sessionExists(ctx, artifact.SessionID); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO session_artifacts (id, session_id, run_id, tool_use_id, call_id, name, media_type, content, size, created_at) VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, ?)`,
+		artifact.ID, artifact.SessionID, artifact.RunID, artifact.ToolUseID, artifact.CallID, artifact.Name, artifact.MediaType, artifact.Content, artifact.Size, formatTime(artifact.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("insert artifact: %w", err)
+	}
+	return nil
+}
+
+// GetArtifact returns one artifact of a session with its content.
+func (s *SQLiteStore) GetArtifact(ctx context.Context, sessionID, id string) (*Artifact, error) {
+	artifact := Artifact{ID: id, SessionID: sessionID}
+	var created string
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(run_id, ''), COALESCE(tool_use_id, ''), call_id, name, media_type, content, size, created_at FROM session_artifacts WHERE session_id = ? AND id = ?`, sessionID, id).
+		Scan(&artifact.RunID, &artifact.ToolUseID, &artifact.CallID, &artifact.Name, &artifact.MediaType, &artifact.Content, &artifact.Size, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrArtifactNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read artifact: %w", err)
+	}
+	artifact.CreatedAt, _ = time.Parse(time.RFC3339Nano, created)
+	return &artifact, nil
+}
+
 func (s *SQLiteStore) AdmitSessionRun(ctx context.Context, run SessionRun) (SessionRunAdmission, error) {
 	tx, err := s.beginImmediate(ctx)
 	if err != nil {
@@ -2271,9 +2659,9 @@ func (s *SQLiteStore) AdmitSessionRun(ctx context.Context, run SessionRun) (Sess
 		return SessionRunAdmission{}, err
 	}
 	if _, err := tx.ExecContext(ctx, `
-		INSERT INTO session_runs (id, session_id, request_id, request_hash, admitted_version, work_dir, workspace_id, client_id, sequence, status, message, agent_json, output_schema_json, error_type, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, NULL, ?, ?)
-	`, run.ID, run.SessionID, run.RequestID, run.RequestHash, run.AdmittedVersion, run.WorkDir, run.WorkspaceID, run.ClientID, run.Sequence, run.Status, run.Message, string(agentJSON), nullableJSON(run.OutputSchemaJSON), now.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano)); err != nil {
+		INSERT INTO session_runs (id, session_id, request_id, request_hash, admitted_version, work_dir, workspace_id, client_id, sequence, priority, status, message, agent_json, output_schema_json, error_type, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, ?, NULL, ?, ?)
+	`, run.ID, run.SessionID, run.RequestID, run.RequestHash, run.AdmittedVersion, run.WorkDir, run.WorkspaceID, run.ClientID, run.Sequence, run.Priority, run.Status, run.Message, string(agentJSON), nullableJSON(run.OutputSchemaJSON), now.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano)); err != nil {
 		return SessionRunAdmission{}, fmt.Errorf("insert session run: %w", err)
 	}
 	if _, err := tx.ExecContext(ctx, `UPDATE sessions SET aggregate_version = ? WHERE id = ?`, run.AdmittedVersion, run.SessionID); err != nil {
@@ -2545,7 +2933,7 @@ func scanSessionRun(row rowScanner) (SessionRun, error) {
 	var agentJSON string
 	var workDir, workspaceID, clientID, schema, errorType, errorMessage, started, completed sql.NullString
 	var created, updated string
-	if err := row.Scan(&run.ID, &run.SessionID, &run.RequestID, &run.RequestHash, &run.AdmittedVersion, &workDir, &workspaceID, &clientID, &run.Sequence, &run.Status, &run.Message, &agentJSON, &schema, &errorType, &errorMessage, &created, &started, &completed, &updated); err != nil {
+	if err := row.Scan(&run.ID, &run.SessionID, &run.RequestID, &run.RequestHash, &run.AdmittedVersion, &workDir, &workspaceID, &clientID, &run.Sequence, &run.Priority, &run.Status, &run.Message, &agentJSON, &schema, &errorType, &errorMessage, &created, &started, &completed, &updated); err != nil {
 		return SessionRun{}, err
 	}
 	if err := json.Unmarshal([]byte(agentJSON), &run.Agent); err != nil {
@@ -2704,7 +3092,8 @@ func legalPermissionResolution(status, response string) bool {
 }
 
 func samePermissionResolution(request PermissionRequest, resolution PermissionRequestResolution) bool {
-	return request.Status == resolution.Status && request.Response == resolution.Response && request.ErrorType == resolution.ErrorType && request.ErrorMessage == resolution.ErrorMessage
+	return request.Status == resolution.Status && request.Response == resolution.Response && request.ErrorType == resolution.ErrorType && request.ErrorMessage == resolution.ErrorMessage &&
+		bytes.Equal(request.EditedInput, resolution.EditedInput) && request.Message == resolution.Message
 }
 
 func samePendingPermissionRequest(existing, request PermissionRequest) bool {
@@ -2765,41 +3154,501 @@ func appendPermissionEventTx(ctx context.Context, tx *immediateTx, request Permi
 	return event, nil
 }
 
+// ---- batches -------------------------------------------------------------
+
+const batchColumns = `
+	b.id, b.client_id, b.agent_json, b.provider_batch_id, b.status, b.error_message, b.created_at, b.updated_at, b.completed_at,
+	(SELECT COUNT(*) FROM batch_items i WHERE i.batch_id = b.id),
+	(SELECT COUNT(*) FROM batch_items i WHERE i.batch_id = b.id AND i.status = 'completed'),
+	(SELECT COUNT(*) FROM batch_items i WHERE i.batch_id = b.id AND i.status = 'failed')`
+
+// CreateBatch stores a provider batch and its items.
+func (s *SQLiteStore) CreateBatch(ctx context.Context, batch *Batch) error {
+	if batch.ID == "" {
+		batch.ID = NewID(PrefixBatch)
+	}
+	if batch.Status == "" {
+		batch.Status = BatchStatusInProgress
+	}
+	now := time.Now().UTC()
+	if batch.CreatedAt.IsZero() {
+		batch.CreatedAt = now
+	}
+	batch.UpdatedAt = batch.CreatedAt
+	agent, err := json.Marshal(batch.Agent)
+	if err != nil {
+		return fmt.Errorf("encode batch agent: %w", err)
+	}
+	tx, err := s.beginImmediate(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO batches (id, client_id, agent_json, provider_batch_id, status, error_message, created_at, updated_at, completed_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		batch.ID, batch.ClientID, string(agent), batch.ProviderBatchID, batch.Status, batch.ErrorMessage, formatTime(batch.CreatedAt), formatTime(batch.UpdatedAt), nullableTime(batch.CompletedAt)); err != nil {
+		return fmt.Errorf("insert batch: %w", err)
+	}
+	for i := range batch.Items {
+		item := &batch.Items[i]
+		item.Index = i
+		if item.Status == "" {
+			item.Status = BatchItemStatusPending
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO batch_items (batch_id, idx, prompt, session_id, run_id, status, error_message) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			batch.ID, item.Index, item.Prompt, nullableString(item.SessionID), nullableString(item.RunID), item.Status, item.ErrorMessage); err != nil {
+			return fmt.Errorf("insert batch item: %w", err)
+		}
+	}
+	batch.Counts = CountBatchItems(batch.Items)
+	return tx.Commit(ctx)
+}
+
+// GetBatch returns a batch with its items in index order.
+func (s *SQLiteStore) GetBatch(ctx context.Context, id string) (*Batch, error) {
+	batch, err := scanBatch(s.db.QueryRowContext(ctx, `SELECT `+batchColumns+` FROM batches b WHERE b.id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrBatchNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read batch: %w", err)
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT idx, prompt, COALESCE(session_id, ''), COALESCE(run_id, ''), status, error_message FROM batch_items WHERE batch_id = ? ORDER BY idx`, id)
+	if err != nil {
+		return nil, fmt.Errorf("list batch items: %w", err)
+	}
+	defer rows.Close()
+	batch.Items = []BatchItem{}
+	for rows.Next() {
+		var item BatchItem
+		if err := rows.Scan(&item.Index, &item.Prompt, &item.SessionID, &item.RunID, &item.Status, &item.ErrorMessage); err != nil {
+			return nil, err
+		}
+		batch.Items = append(batch.Items, item)
+	}
+	return batch, rows.Err()
+}
+
+// ListBatches returns batches newest first without their items.
+func (s *SQLiteStore) ListBatches(ctx context.Context) ([]*Batch, error) {
+	return s.listBatches(ctx, "")
+}
+
+// ListBatchesByClient returns one client's batches newest first without
+// their items.
+func (s *SQLiteStore) ListBatchesByClient(ctx context.Context, clientID string) ([]*Batch, error) {
+	return s.listBatches(ctx, "WHERE b.client_id = ?", clientID)
+}
+
+// ListBatchesByStatus returns the batches in status newest first without
+// their items.
+func (s *SQLiteStore) ListBatchesByStatus(ctx context.Context, status string) ([]*Batch, error) {
+	return s.listBatches(ctx, "WHERE b.status = ?", status)
+}
+
+func (s *SQLiteStore) listBatches(ctx context.Context, where string, args ...any) ([]*Batch, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+batchColumns+` FROM batches b `+where+` ORDER BY b.created_at DESC, b.id DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list batches: %w", err)
+	}
+	defer rows.Close()
+	batches := []*Batch{}
+	for rows.Next() {
+		batch, err := scanBatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, batch)
+	}
+	return batches, rows.Err()
+}
+
+// UpdateBatch writes a batch's provider batch ID, status, and error.
+func (s *SQLiteStore) UpdateBatch(ctx context.Context, batch *Batch) error {
+	batch.UpdatedAt = time.Now().UTC()
+	if IsBatchTerminal(batch.Status) && batch.CompletedAt.IsZero() {
+		batch.CompletedAt = batch.UpdatedAt
+	}
+	result, err := s.db.ExecContext(ctx, `UPDATE batches SET provider_batch_id = ?, status = ?, error_message = ?, updated_at = ?, completed_at = ? WHERE id = ?`,
+		batch.ProviderBatchID, batch.Status, batch.ErrorMessage, formatTime(batch.UpdatedAt), nullableTime(batch.CompletedAt), batch.ID)
+	if err != nil {
+		return fmt.Errorf("update batch: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", ErrBatchNotFound, batch.ID)
+	}
+	return nil
+}
+
+// UpdateBatchItem records the session, run, and outcome of one item.
+func (s *SQLiteStore) UpdateBatchItem(ctx context.Context, batchID string, item BatchItem) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE batch_items SET session_id = ?, run_id = ?, status = ?, error_message = ? WHERE batch_id = ? AND idx = ?`,
+		nullableString(item.SessionID), nullableString(item.RunID), item.Status, item.ErrorMessage, batchID, item.Index)
+	if err != nil {
+		return fmt.Errorf("update batch item: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s item %d", ErrBatchNotFound, batchID, item.Index)
+	}
+	return nil
+}
+
+func scanBatch(r rowScanner) (*Batch, error) {
+	var batch Batch
+	var agent, created, updated string
+	var completed sql.NullString
+	if err := r.Scan(&batch.ID, &batch.ClientID, &agent, &batch.ProviderBatchID, &batch.Status, &batch.ErrorMessage, &created, &updated, &completed,
+		&batch.Counts.Total, &batch.Counts.Completed, &batch.Counts.Failed); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(agent), &batch.Agent); err != nil {
+		return nil, fmt.Errorf("decode batch agent: %w", err)
+	}
+	batch.CreatedAt, _ = time.Parse(time.RFC3339Nano, created)
+	batch.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updated)
+	if completed.Valid {
+		batch.CompletedAt, _ = time.Parse(time.RFC3339Nano, completed.String)
+	}
+	return &batch, nil
+}
+
+// ---- evals ---------------------------------------------------------------
+
+const evalColumns = `
+	e.id, e.client_id, e.name, e.agent_json, e.judge_model_ref, e.status, e.error_message, e.created_at, e.updated_at, e.completed_at,
+	(SELECT COUNT(*) FROM eval_cases c WHERE c.eval_id = e.id),
+	(SELECT COUNT(*) FROM eval_cases c WHERE c.eval_id = e.id AND c.status = 'passed'),
+	(SELECT COUNT(*) FROM eval_cases c WHERE c.eval_id = e.id AND c.status = 'failed'),
+	(SELECT COUNT(*) FROM eval_cases c WHERE c.eval_id = e.id AND c.status = 'error'),
+	COALESCE((SELECT AVG(c.score) FROM eval_cases c WHERE c.eval_id = e.id), 0),
+	COALESCE((SELECT SUM(c.total_tokens) FROM eval_cases c WHERE c.eval_id = e.id), 0),
+	COALESCE((SELECT SUM(c.cost) FROM eval_cases c WHERE c.eval_id = e.id), 0)`
+
+const evalCaseColumns = `idx, case_id, definition_json, COALESCE(session_id, ''), COALESCE(run_id, ''), status, score, results_json, error_message, total_tokens, cost`
+
+// CreateEval stores an eval and its cases.
+func (s *SQLiteStore) CreateEval(ctx context.Context, eval *Eval) error {
+	if eval.ID == "" {
+		eval.ID = NewID(PrefixEval)
+	}
+	if eval.Status == "" {
+		eval.Status = EvalStatusRunning
+	}
+	if eval.CreatedAt.IsZero() {
+		eval.CreatedAt = time.Now().UTC()
+	}
+	eval.UpdatedAt = eval.CreatedAt
+	agent, err := json.Marshal(eval.Agent)
+	if err != nil {
+		return fmt.Errorf("encode eval agent: %w", err)
+	}
+	tx, err := s.beginImmediate(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO evals (id, client_id, name, agent_json, judge_model_ref, status, error_message, created_at, updated_at, completed_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		eval.ID, eval.ClientID, eval.Name, string(agent), eval.JudgeModelRef, eval.Status, eval.ErrorMessage, formatTime(eval.CreatedAt), formatTime(eval.UpdatedAt), nullableTime(eval.CompletedAt)); err != nil {
+		return fmt.Errorf("insert eval: %w", err)
+	}
+	for i := range eval.Cases {
+		c := &eval.Cases[i]
+		c.Index = i
+		if c.Status == "" {
+			c.Status = EvalCaseStatusPending
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO eval_cases (eval_id, idx, case_id, definition_json, session_id, run_id, status, score, results_json, error_message, total_tokens, cost) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			eval.ID, c.Index, c.CaseID, string(c.DefinitionJSON), nullableString(c.SessionID), nullableString(c.RunID), c.Status, c.Score, nullableBytes(c.ResultsJSON), c.ErrorMessage, c.TotalTokens, c.Cost); err != nil {
+			return fmt.Errorf("insert eval case: %w", err)
+		}
+	}
+	eval.Summary = SummarizeEvalCases(eval.Cases)
+	return tx.Commit(ctx)
+}
+
+// GetEval returns an eval with its cases in index order.
+func (s *SQLiteStore) GetEval(ctx context.Context, id string) (*Eval, error) {
+	eval, err := scanEval(s.db.QueryRowContext(ctx, `SELECT `+evalColumns+` FROM evals e WHERE e.id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrEvalNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read eval: %w", err)
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT `+evalCaseColumns+` FROM eval_cases WHERE eval_id = ? ORDER BY idx`, id)
+	if err != nil {
+		return nil, fmt.Errorf("list eval cases: %w", err)
+	}
+	defer rows.Close()
+	eval.Cases = []EvalCase{}
+	for rows.Next() {
+		var c EvalCase
+		var definition string
+		var results sql.NullString
+		if err := rows.Scan(&c.Index, &c.CaseID, &definition, &c.SessionID, &c.RunID, &c.Status, &c.Score, &results, &c.ErrorMessage, &c.TotalTokens, &c.Cost); err != nil {
+			return nil, err
+		}
+		c.DefinitionJSON = json.RawMessage(definition)
+		c.ResultsJSON = nullableStringBytes(results)
+		eval.Cases = append(eval.Cases, c)
+	}
+	return eval, rows.Err()
+}
+
+// ListEvals returns evals newest first without their cases.
+func (s *SQLiteStore) ListEvals(ctx context.Context) ([]*Eval, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+evalColumns+` FROM evals e ORDER BY e.created_at DESC, e.id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("list evals: %w", err)
+	}
+	defer rows.Close()
+	evals := []*Eval{}
+	for rows.Next() {
+		eval, err := scanEval(rows)
+		if err != nil {
+			return nil, err
+		}
+		evals = append(evals, eval)
+	}
+	return evals, rows.Err()
+}
+
+// UpdateEval writes an eval's status and error.
+func (s *SQLiteStore) UpdateEval(ctx context.Context, eval *Eval) error {
+	eval.UpdatedAt = time.Now().UTC()
+	if eval.Status != EvalStatusRunning && eval.CompletedAt.IsZero() {
+		eval.CompletedAt = eval.UpdatedAt
+	}
+	result, err := s.db.ExecContext(ctx, `UPDATE evals SET status = ?, error_message = ?, updated_at = ?, completed_at = ? WHERE id = ?`,
+		eval.Status, eval.ErrorMessage, formatTime(eval.UpdatedAt), nullableTime(eval.CompletedAt), eval.ID)
+	if err != nil {
+		return fmt.Errorf("update eval: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", ErrEvalNotFound, eval.ID)
+	}
+	return nil
+}
+
+// UpdateEvalCase records the session, run, and outcome of one case.
+func (s *SQLiteStore) UpdateEvalCase(ctx context.Context, evalID string, c EvalCase) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE eval_cases SET session_id = ?, run_id = ?, status = ?, score = ?, results_json = ?, error_message = ?, total_tokens = ?, cost = ? WHERE eval_id = ? AND idx = ?`,
+		nullableString(c.SessionID), nullableString(c.RunID), c.Status, c.Score, nullableBytes(c.ResultsJSON), c.ErrorMessage, c.TotalTokens, c.Cost, evalID, c.Index)
+	if err != nil {
+		return fmt.Errorf("update eval case: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s case %d", ErrEvalNotFound, evalID, c.Index)
+	}
+	return nil
+}
+
+func scanEval(r rowScanner) (*Eval, error) {
+	var eval Eval
+	var agent, created, updated string
+	var completed sql.NullString
+	summary := &eval.Summary
+	if err := r.Scan(&eval.ID, &eval.ClientID, &eval.Name, &agent, &eval.JudgeModelRef, &eval.Status, &eval.ErrorMessage, &created, &updated, &completed,
+		&summary.Total, &summary.Passed, &summary.Failed, &summary.Errored, &summary.Score, &summary.TotalTokens, &summary.Cost); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(agent), &eval.Agent); err != nil {
+		return nil, fmt.Errorf("decode eval agent: %w", err)
+	}
+	eval.CreatedAt, _ = time.Parse(time.RFC3339Nano, created)
+	eval.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updated)
+	if completed.Valid {
+		eval.CompletedAt, _ = time.Parse(time.RFC3339Nano, completed.String)
+	}
+	return &eval, nil
+}
+
 // ---- auth ----------------------------------------------------------------
 
 // GetAuth returns the singleton auth row, or an empty Auth if unset.
 func (s *SQLiteStore) GetAuth() (*Auth, error) {
 	var auth Auth
-	var providersJSON string
+	var providersJSON, mcpJSON, providerCredentialsJSON string
 
-	err := s.db.QueryRow(`SELECT providers_json, updated_at FROM auth WHERE id = 1`).
-		Scan(&providersJSON, &auth.UpdatedAt)
+	err := s.db.QueryRow(`SELECT providers_json, mcp_json, provider_credentials_json, updated_at FROM auth WHERE id = 1`).
+		Scan(&providersJSON, &mcpJSON, &providerCredentialsJSON, &auth.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return &Auth{Providers: make(map[string]AuthCredential)}, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	if err := json.Unmarshal([]byte(providersJSON), &auth.Providers); err != nil {
+	if err := s.decodeCredentials("providers_json", providersJSON, &auth.Providers); err != nil {
+		return nil, err
+	}
+	if err := s.decodeCredentials("mcp_json", mcpJSON, &auth.MCP); err != nil {
 		return nil, err
 	}
+	if err := s.decodeCredentials("provider_credentials_json", providerCredentialsJSON, &auth.ProviderCredentials); err != nil {
+		return nil, err
+	}
+	if len(auth.ProviderCredentials) == 0 {
+		auth.ProviderCredentials = nil
+	}
+	if auth.Providers == nil {
+		auth.Providers = make(map[string]AuthCredential)
+	}
 	return &auth, nil
 }
 
-// SetAuth writes the singleton auth row, upserting on the fixed id=1.
+// SetAuth writes the provider credentials of the singleton auth row,
+// single and named, upserting on the fixed id=1. MCP credentials are left
+// untouched.
 func (s *SQLiteStore) SetAuth(auth *Auth) error {
 	auth.UpdatedAt = Now()
-	providers, err := json.Marshal(auth.Providers)
+	providers, err := s.encodeCredentials("providers_json", auth.Providers)
+	if err != nil {
+		return err
+	}
+	named := auth.ProviderCredentials
+	if named == nil {
+		named = map[string]map[string]AuthCredential{}
+	}
+	providerCredentials, err := s.encodeCredentials("provider_credentials_json", named)
+	if err != nil {
+		return err
+	}
+	empty, err := s.encodeCredentials("mcp_json", map[string]AuthCredential{})
 	if err != nil {
 		return err
 	}
 	_, err = s.db.Exec(`
-		INSERT INTO auth (id, providers_json, updated_at) VALUES (1, ?, ?)
-		ON CONFLICT(id) DO UPDATE SET providers_json = ?, updated_at = ?
-	`, string(providers), auth.UpdatedAt, string(providers), auth.UpdatedAt)
+		INSERT INTO auth (id, providers_json, provider_credentials_json, mcp_json, updated_at) VALUES (1, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET providers_json = excluded.providers_json,
+			provider_credentials_json = excluded.provider_credentials_json, updated_at = excluded.updated_at
+	`, providers, providerCredentials, empty, auth.UpdatedAt)
 	return err
 }
 
+// SetMCPCredential stores the OAuth credential for one remote MCP server.
+func (s *SQLiteStore) SetMCPCredential(server string, credential AuthCredential) error {
+	return s.updateMCPCredentials(func(credentials map[string]AuthCredential) {
+		credentials[server] = credential
+	})
+}
+
+// DeleteMCPCredential removes the OAuth credential for one remote MCP server.
+func (s *SQLiteStore) DeleteMCPCredential(server string) error {
+	return s.updateMCPCredentials(func(credentials map[string]AuthCredential) {
+		delete(credentials, server)
+	})
+}
+
+func (s *SQLiteStore) updateMCPCredentials(update func(map[string]AuthCredential)) error {
+	ctx := context.Background()
+	tx, err := s.beginImmediate(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	mcpJSON := "{}"
+	if err := tx.QueryRowContext(ctx, `SELECT mcp_json FROM auth WHERE id = 1`).Scan(&mcpJSON); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	credentials := make(map[string]AuthCredential)
+	if err := s.decodeCredentials("mcp_json", mcpJSON, &credentials); err != nil {
+		return err
+	}
+	if credentials == nil {
+		credentials = make(map[string]AuthCredential)
+	}
+	update(credentials)
+	encoded, err := s.encodeCredentials("mcp_json", credentials)
+	if err != nil {
+		return err
+	}
+	empty, err := s.encodeCredentials("providers_json", map[string]AuthCredential{})
+	if err != nil {
+		return err
+	}
+	emptyNamed, err := s.encodeCredentials("provider_credentials_json", map[string]map[string]AuthCredential{})
+	if err != nil {
+		return err
+	}
+	now := Now()
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO auth (id, providers_json, provider_credentials_json, mcp_json, updated_at) VALUES (1, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET mcp_json = excluded.mcp_json, updated_at = excluded.updated_at
+	`, empty, emptyNamed, encoded, now); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UseCredentialKeys encrypts stored credentials at rest with keys. Plain
+// credentials written before, and credentials sealed with a key other than
+// the current one, are resealed with the current key; resealed reports
+// whether that rewrote the auth row. Call it before the store is shared.
+func (s *SQLiteStore) UseCredentialKeys(keys CredentialKeys) (resealed bool, err error) {
+	s.credentialKeys = keys
+	if keys == nil {
+		return false, nil
+	}
+	current, _, err := keys.Current()
+	if err != nil {
+		return false, fmt.Errorf("credential key: %w", err)
+	}
+	ctx := context.Background()
+	tx, err := s.beginImmediate(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	var providersJSON, mcpJSON, providerCredentialsJSON string
+	err = tx.QueryRowContext(ctx, `SELECT providers_json, mcp_json, provider_credentials_json FROM auth WHERE id = 1`).
+		Scan(&providersJSON, &mcpJSON, &providerCredentialsJSON)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	columns := map[string]string{"providers_json": providersJSON, "mcp_json": mcpJSON, "provider_credentials_json": providerCredentialsJSON}
+	updates := map[string]string{}
+	for column, value := range columns {
+		plain, keyID, err := openCredentials(keys, column, value)
+		if err != nil {
+			return false, err
+		}
+		if keyID == current {
+			continue
+		}
+		if updates[column], err = sealCredentials(keys, column, plain); err != nil {
+			return false, err
+		}
+	}
+	if len(updates) == 0 {
+		return false, nil
+	}
+	for column, value := range updates {
+		if _, err := tx.ExecContext(ctx, `UPDATE auth SET `+column+` = ? WHERE id = 1`, value); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (s *SQLiteStore) decodeCredentials(column, value string, into any) error {
+	plain, _, err := openCredentials(s.credentialKeys, column, value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plain, into)
+}
+
+func (s *SQLiteStore) encodeCredentials(column string, credentials any) (string, error) {
+	plain, err := json.Marshal(credentials)
+	if err != nil {
+		return "", err
+	}
+	return sealCredentials(s.credentialKeys, column, plain)
+}
+
 // ---- helpers -------------------------------------------------------------
 
 const modelCallColumns = `
@@ -2807,7 +3656,7 @@ const modelCallColumns = `
 	COALESCE(agent_id, ''), COALESCE(model_ref, ''), COALESCE(provider, ''), COALESCE(provider_request_id, ''), COALESCE(api, ''), COALESCE(model_id, ''),
 	COALESCE(finish_reason, ''), COALESCE(stop_reason, ''), COALESCE(error_type, ''), COALESCE(error_message, ''),
 	input_tokens, output_tokens, reasoning_tokens, cached_input_tokens, cache_write_tokens, total_tokens,
-	context_tokens, context_window, COALESCE(context_percent, 0), cost,
+	context_tokens, context_window, COALESCE(context_percent, 0), cost, throttled_ms, credential,
 	structured_output_json, metadata_json, started_at, completed_at, created_at, updated_at`
 
 const toolUseColumns = `
@@ -2815,9 +3664,13 @@ const toolUseColumns = `
 	step, ordinal, call_id, name, status, input_json, output, structured_json, metadata_json, error_type, error_message,
 	proposed_at, authorized_at, started_at, completed_at, created_at, updated_at`
 
+const permissionRequestColumns = `
+	id, session_id, COALESCE(run_id, ''), COALESCE(tool_use_id, ''), call_id, action, resources_json,
+	status, response, error_type, error_message, edited_input_json, message, created_at, resolved_at, updated_at`
+
 const sessionRunColumns = `
 	id, session_id, request_id, request_hash, admitted_version,
-	work_dir, workspace_id, client_id, sequence, status, message, agent_json,
+	work_dir, workspace_id, client_id, sequence, priority, status, message, agent_json,
 	output_schema_json, error_type, error_message, created_at, started_at, completed_at, updated_at`
 
 // SessionRunRequestHash returns the canonical hash for an admission request.
@@ -2837,7 +3690,8 @@ func SessionRunRequestHash(run SessionRun) (string, error) {
 		ClientID     string `json:"client_id"`
 		WorkDir      string `json:"work_dir"`
 		WorkspaceID  string `json:"workspace_id"`
-	}{run.Message, agent, schema, run.ClientID, run.WorkDir, run.WorkspaceID})
+		Priority     int    `json:"priority,omitempty"`
+	}{run.Message, agent, schema, run.ClientID, run.WorkDir, run.WorkspaceID, run.Priority})
 	if err != nil {
 		return "", fmt.Errorf("marshal run request: %w", err)
 	}
@@ -2866,11 +3720,14 @@ func formatTime(t time.Time) string { return t.UTC().Format(time.RFC3339Nano) }
 
 func scanPermissionRequest(r rowScanner) (PermissionRequest, error) {
 	var request PermissionRequest
-	var resources, resolvedAt sql.NullString
+	var resources, editedInput, resolvedAt sql.NullString
 	var createdAt, updatedAt string
-	if err := r.Scan(&request.ID, &request.SessionID, &request.RunID, &request.ToolUseID, &request.CallID, &request.Action, &resources, &request.Status, &request.Response, &request.ErrorType, &request.ErrorMessage, &createdAt, &resolvedAt, &updatedAt); err != nil {
+	if err := r.Scan(&request.ID, &request.SessionID, &request.RunID, &request.ToolUseID, &request.CallID, &request.Action, &resources, &request.Status, &request.Response, &request.ErrorType, &request.ErrorMessage, &editedInput, &request.Message, &createdAt, &resolvedAt, &updatedAt); err != nil {
 		return PermissionRequest{}, err
 	}
+	if editedInput.Valid {
+		request.EditedInput = json.RawMessage(editedInput.String)
+	}
 	if err := json.Unmarshal([]byte(resources.String), &request.Resources); err != nil {
 		return PermissionRequest{}, fmt.Errorf("unmarshal permission resources: %w", err)
 	}
@@ -2891,7 +3748,7 @@ func scanModelCall(r rowScanner) (ModelCall, error) {
 		&call.AgentID, &call.ModelRef, &call.Provider, &call.ProviderRequestID, &call.API, &call.ModelID,
 		&call.FinishReason, &call.StopReason, &call.ErrorType, &call.ErrorMessage,
 		&call.InputTokens, &call.OutputTokens, &call.ReasoningTokens, &call.CachedInputTokens, &call.CacheWriteTokens, &call.TotalTokens,
-		&call.ContextTokens, &call.ContextWindow, &call.ContextPercent, &call.Cost,
+		&call.ContextTokens, &call.ContextWindow, &call.ContextPercent, &call.Cost, &call.ThrottledMS, &call.Credential,
 		&structuredOutputJSON, &metadataJSON, &startedAt, &completedAt, &createdAt, &updatedAt,
 	); err != nil {
 		return ModelCall{}, err
@@ -3032,11 +3889,12 @@ func scanAgent(r rowScanner) (*Agent, error) {
 	var permissionsJSON sql.NullString
 	var optionsJSON sql.NullString
 	var outputSchemaJSON sql.NullString
+	var limitsJSON sql.NullString
 
 	if err := r.Scan(
 		&a.ID, &a.Name, &a.Instructions, &toolsJSON, &permissionsJSON,
-		&a.ModelRef, &optionsJSON, &outputSchemaJSON,
-		&a.CreatedAt, &a.UpdatedAt,
+		&a.ModelRef, &optionsJSON, &outputSchemaJSON, &limitsJSON,
+		&a.Revision, &a.CreatedAt, &a.UpdatedAt,
 	); err != nil {
 		return nil, err
 	}
@@ -3060,9 +3918,45 @@ func scanAgent(r rowScanner) (*Agent, error) {
 			return nil, err
 		}
 	}
+	if limitsJSON.Valid && limitsJSON.String != "" {
+		if err := json.Unmarshal([]byte(limitsJSON.String), &a.Limits); err != nil {
+			return nil, err
+		}
+	}
 	return &a, nil
 }
 
+// scanAgentRevision reads one agent revision row from any rowScanner.
+func scanAgentRevision(r rowScanner) (*AgentRevision, error) {
+	var revision AgentRevision
+	var agentJSON string
+	if err := r.Scan(&revision.AgentID, &revision.Revision, &agentJSON, &revision.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(agentJSON), &revision.Agent); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// marshalLimits encodes run limits for a nullable column. Unset limits are
+// stored as NULL.
+func marshalLimits(limits *RunLimits) (*string, error) {
+	if limits == nil || *limits == (RunLimits{}) {
+		return nil, nil
+	}
+	return marshalNullable(limits)
+}
+
+// marshalWorkspaceCredentials encodes workspace credential selections for a
+// nullable column. No selections are stored as NULL.
+func marshalWorkspaceCredentials(credentials map[string]string) (*string, error) {
+	if len(credentials) == 0 {
+		return nil, nil
+	}
+	return marshalNullable(credentials)
+}
+
 // marshalNullable returns a *string for use as a nullable SQL column:
 // nil if v is nil/empty, else a pointer to the JSON encoding.
 func marshalNullable(v any) (*string, error) {
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"reflect"
 	"runtime"
 	"slices"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -120,7 +122,7 @@ func TestSQLiteModelCallUpsertUsesStableID(t *testing.T) {
 	if err := data.UpsertModelCall(ctx, ModelCall{ID: "mcl_test", SessionID: "ses_test", RunID: run.Run.ID, Step: 1, Status: ModelCallStatusStarted, StartedAt: startedAt, CreatedAt: createdAt}); err != nil {
 		t.Fatal(err)
 	}
-	if err := data.UpsertModelCall(ctx, ModelCall{ID: "mcl_test", SessionID: "ses_other", RunID: "run_other", Step: 9, Attempt: 2, Status: ModelCallStatusCompleted, ProviderRequestID: "request_123", CompletedAt: startedAt.Add(time.Minute), StartedAt: startedAt.Add(time.Hour), CreatedAt: createdAt.Add(time.Hour)}); err != nil {
+	if err := data.UpsertModelCall(ctx, ModelCall{ID: "mcl_test", SessionID: "ses_other", RunID: "run_other", Step: 9, Attempt: 2, Status: ModelCallStatusCompleted, ProviderRequestID: "request_123", Credential: "team-b", ThrottledMS: 250, CompletedAt: startedAt.Add(time.Minute), StartedAt: startedAt.Add(time.Hour), CreatedAt: createdAt.Add(time.Hour)}); err != nil {
 		t.Fatal(err)
 	}
 	if err := data.UpsertModelCall(ctx, ModelCall{ID: "mcl_conflict", SessionID: "ses_test", RunID: run.Run.ID, Step: 1, Status: ModelCallStatusFailed}); !errors.Is(err, ErrModelCallAttemptConflict) {
@@ -137,7 +139,7 @@ func TestSQLiteModelCallUpsertUsesStableID(t *testing.T) {
 	if call.RunID != run.Run.ID || call.Step != 1 || call.Attempt != 1 || !call.StartedAt.Equal(startedAt) || !call.CreatedAt.Equal(createdAt) {
 		t.Fatalf("immutable identity = %#v", call)
 	}
-	if call.Status != ModelCallStatusCompleted || call.ProviderRequestID != "request_123" || call.CompletedAt.IsZero() {
+	if call.Status != ModelCallStatusCompleted || call.ProviderRequestID != "request_123" || call.Credential != "team-b" || call.ThrottledMS != 250 || call.CompletedAt.IsZero() {
 		t.Fatalf("terminal fields = %#v", call)
 	}
 }
@@ -467,7 +469,7 @@ func TestSQLiteSeedsWingstonAgent(t *testing.T) {
 		if agent.Instructions != wingstonAgentInstructions {
 			t.Fatal("Wingston instructions do not match the default")
 		}
-		if got, want := agent.Tools, []string{"webfetch", "websearch"}; !slices.Equal(got, want) {
+		if got, want := agent.Tools, []string{"webfetch", "websearch", "read_artifact"}; !slices.Equal(got, want) {
 			t.Fatalf("Wingston tools = %v, want %v", got, want)
 		}
 		return
@@ -475,6 +477,232 @@ func TestSQLiteSeedsWingstonAgent(t *testing.T) {
 	t.Fatal("Wingston agent was not seeded")
 }
 
+func TestSQLiteAgentRevisions(t *testing.T) {
+	data, err := NewSQLiteStore(filepath.Join(t.TempDir(), "wingman.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = data.Close() })
+
+	agent := &Agent{Name: "writer", Instructions: "v1", Tools: []string{"read"}}
+	if err := data.CreateAgent(agent); err != nil {
+		t.Fatal(err)
+	}
+	agent.Instructions = "v2"
+	agent.Limits = &RunLimits{MaxSteps: 3}
+	if err := data.UpdateAgent(agent); err != nil {
+		t.Fatal(err)
+	}
+	if agent.Revision != 2 {
+		t.Fatalf("revision after update = %d, want 2", agent.Revision)
+	}
+	current, err := data.GetAgent(agent.ID)
+	if err != nil || current.Revision != 2 {
+		t.Fatalf("current = %#v, error = %v", current, err)
+	}
+	revisions, err := data.ListAgentRevisions(agent.ID)
+	if err != nil || len(revisions) != 2 {
+		t.Fatalf("revisions = %#v, error = %v", revisions, err)
+	}
+	if revisions[0].Agent.Instructions != "v1" || revisions[0].Agent.Limits != nil || revisions[1].Agent.Instructions != "v2" || revisions[1].Agent.Limits.MaxSteps != 3 {
+		t.Fatalf("revisions = %#v", revisions)
+	}
+	first, err := data.GetAgentRevision(agent.ID, 1)
+	if err != nil || first.Agent.Revision != 1 || !slices.Equal(first.Agent.Tools, []string{"read"}) {
+		t.Fatalf("first = %#v, error = %v", first, err)
+	}
+	if _, err := data.GetAgentRevision(agent.ID, 3); err == nil {
+		t.Fatal("missing revision returned no error")
+	}
+
+	// Seeded agents were created through the same path and start at 1.
+	agents, err := data.ListAgents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, seeded := range agents {
+		if seeded.ID != agent.ID && seeded.Revision != 1 {
+			t.Fatalf("seeded %s revision = %d", seeded.Name, seeded.Revision)
+		}
+	}
+	if err := data.DeleteAgent(agent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := data.ListAgentRevisions(agent.ID); err == nil {
+		t.Fatal("revisions survived agent deletion")
+	}
+}
+
+func TestSQLiteCommandRoundTrip(t *testing.T) {
+	data, err := NewSQLiteStore(filepath.Join(t.TempDir(), "wingman.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = data.Close() })
+
+	cmd := &Command{Name: "review", Template: "Review {{focus}}.", Arguments: []CommandArgument{{Name: "focus", Default: "tests"}}, ModelRef: "test/model"}
+	if err := data.CreateCommand(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(cmd.ID, PrefixCommand) {
+		t.Fatalf("id = %q", cmd.ID)
+	}
+	cmd.Arguments = append(cmd.Arguments, CommandArgument{Name: "path", Required: true})
+	if err := data.UpdateCommand(cmd); err != nil {
+		t.Fatal(err)
+	}
+	got, err := data.GetCommand(cmd.ID)
+	if err != nil || len(got.Arguments) != 2 || got.Arguments[0].Default != "tests" || !got.Arguments[1].Required || got.ModelRef != "test/model" {
+		t.Fatalf("command = %#v, error = %v", got, err)
+	}
+	if commands, err := data.ListCommands(); err != nil || len(commands) != 1 {
+		t.Fatalf("commands = %#v, error = %v", commands, err)
+	}
+	if err := data.DeleteCommand(cmd.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := data.GetCommand(cmd.ID); err == nil {
+		t.Fatal("deleted command returned no error")
+	}
+}
+
+func TestSQLiteArtifactRoundTrip(t *testing.T) {
+	data := newTestSQLiteStore(t)
+	ctx := context.Background()
+	for _, id := range []string{"ses_artifact", "ses_other"} {
+		if err := data.CreateSession(&Session{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run, err := data.AdmitSessionRun(ctx, SessionRun{ID: "run_artifact", SessionID: "ses_artifact"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifact := &Artifact{SessionID: "ses_artifact", RunID: run.Run.ID, CallID: "call_1", Name: "bash", Content: []byte("large output")}
+	if err := data.SaveArtifact(ctx, artifact); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(artifact.ID, PrefixArtifact) || artifact.Size != 12 {
+		t.Fatalf("artifact = %#v", artifact)
+	}
+	got, err := data.GetArtifact(ctx, "ses_artifact", artifact.ID)
+	if err != nil || string(got.Content) != "large output" || got.RunID != run.Run.ID || got.ToolUseID != "" || got.MediaType == "" {
+		t.Fatalf("artifact = %#v, error = %v", got, err)
+	}
+	if _, err := data.GetArtifact(ctx, "ses_other", artifact.ID); !errors.Is(err, ErrArtifactNotFound) {
+		t.Fatalf("other session error = %v", err)
+	}
+	if err := data.SaveArtifact(ctx, &Artifact{SessionID: "ses_missing", Content: []byte("x")}); err == nil {
+		t.Fatal("artifact saved for a missing session")
+	}
+}
+
+func TestSQLiteBatchRoundTrip(t *testing.T) {
+	data := newTestSQLiteStore(t)
+	ctx := context.Background()
+	if err := data.CreateSession(&Session{ID: "ses_batch"}); err != nil {
+		t.Fatal(err)
+	}
+	batch := &Batch{ClientID: "cli_batch", Agent: Agent{ID: "agt_batch", Name: "labeler"}, ProviderBatchID: "msgbatch_1", Items: []BatchItem{{Prompt: "first"}, {Prompt: "second"}}}
+	if err := data.CreateBatch(ctx, batch); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(batch.ID, PrefixBatch) || batch.Status != BatchStatusInProgress || batch.Items[1].Index != 1 || batch.Items[1].Status != BatchItemStatusPending {
+		t.Fatalf("batch = %#v", batch)
+	}
+	if err := data.UpdateBatchItem(ctx, batch.ID, BatchItem{Index: 0, SessionID: "ses_batch", Status: BatchItemStatusCompleted}); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.UpdateBatchItem(ctx, batch.ID, BatchItem{Index: 1, Status: BatchItemStatusFailed, ErrorMessage: "expired"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.UpdateBatchItem(ctx, batch.ID, BatchItem{Index: 2, Status: BatchItemStatusFailed}); !errors.Is(err, ErrBatchNotFound) {
+		t.Fatalf("missing item error = %v", err)
+	}
+	batch.Status = BatchStatusCompleted
+	if err := data.UpdateBatch(ctx, batch); err != nil || batch.CompletedAt.IsZero() {
+		t.Fatalf("update = %#v, error = %v", batch, err)
+	}
+	got, err := data.GetBatch(ctx, batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Agent.Name != "labeler" || got.ProviderBatchID != "msgbatch_1" || got.Status != BatchStatusCompleted || got.CompletedAt.IsZero() {
+		t.Fatalf("batch = %#v", got)
+	}
+	if got.Counts != (BatchCounts{Total: 2, Completed: 1, Failed: 1}) || got.Items[0].SessionID != "ses_batch" || got.Items[1].ErrorMessage != "expired" {
+		t.Fatalf("items = %#v, counts = %#v", got.Items, got.Counts)
+	}
+	list, err := data.ListBatches(ctx)
+	if err != nil || len(list) != 1 || list[0].Items != nil || list[0].Counts.Total != 2 {
+		t.Fatalf("list = %#v, error = %v", list, err)
+	}
+	if err := data.CreateBatch(ctx, &Batch{ClientID: "cli_other", Items: []BatchItem{{Prompt: "other"}}}); err != nil {
+		t.Fatal(err)
+	}
+	list, err = data.ListBatchesByClient(ctx, "cli_batch")
+	if err != nil || len(list) != 1 || list[0].ID != batch.ID || list[0].Counts.Total != 2 {
+		t.Fatalf("client list = %#v, error = %v", list, err)
+	}
+	list, err = data.ListBatchesByStatus(ctx, BatchStatusInProgress)
+	if err != nil || len(list) != 1 || list[0].ClientID != "cli_other" {
+		t.Fatalf("status list = %#v, error = %v", list, err)
+	}
+	if _, err := data.GetBatch(ctx, "bat_missing"); !errors.Is(err, ErrBatchNotFound) {
+		t.Fatalf("missing batch error = %v", err)
+	}
+}
+
+func TestSQLiteEvalRoundTrip(t *testing.T) {
+	data := newTestSQLiteStore(t)
+	ctx := context.Background()
+	if err := data.CreateSession(&Session{ID: "ses_eval"}); err != nil {
+		t.Fatal(err)
+	}
+	eval := &Eval{ClientID: "cli_eval", Name: "smoke", Agent: Agent{ID: "agt_eval", Revision: 2}, Cases: []EvalCase{
+		{CaseID: "add", DefinitionJSON: json.RawMessage(`{"input":"1+1"}`)},
+		{CaseID: "sub", DefinitionJSON: json.RawMessage(`{"input":"2-1"}`)},
+	}}
+	if err := data.CreateEval(ctx, eval); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(eval.ID, PrefixEval) || eval.Status != EvalStatusRunning || eval.Cases[1].Index != 1 || eval.Cases[1].Status != EvalCaseStatusPending {
+		t.Fatalf("eval = %#v", eval)
+	}
+	passed := EvalCase{Index: 0, SessionID: "ses_eval", Status: EvalCaseStatusPassed, Score: 1, ResultsJSON: json.RawMessage(`[{"type":"exact","passed":true,"score":1}]`), TotalTokens: 30, Cost: 0.25}
+	if err := data.UpdateEvalCase(ctx, eval.ID, passed); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.UpdateEvalCase(ctx, eval.ID, EvalCase{Index: 1, Status: EvalCaseStatusError, ErrorMessage: "run failed", TotalTokens: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := data.UpdateEvalCase(ctx, eval.ID, EvalCase{Index: 2}); !errors.Is(err, ErrEvalNotFound) {
+		t.Fatalf("missing case error = %v", err)
+	}
+	eval.Status = EvalStatusCompleted
+	if err := data.UpdateEval(ctx, eval); err != nil || eval.CompletedAt.IsZero() {
+		t.Fatalf("update = %#v, error = %v", eval, err)
+	}
+	got, err := data.GetEval(ctx, eval.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := EvalSummary{Total: 2, Passed: 1, Errored: 1, Score: 0.5, TotalTokens: 40, Cost: 0.25}
+	if got.Agent.Revision != 2 || got.Name != "smoke" || got.Status != EvalStatusCompleted || got.Summary != want {
+		t.Fatalf("eval = %#v", got)
+	}
+	if string(got.Cases[0].ResultsJSON) != string(passed.ResultsJSON) || got.Cases[0].SessionID != "ses_eval" || got.Cases[1].ResultsJSON != nil || string(got.Cases[1].DefinitionJSON) != `{"input":"2-1"}` {
+		t.Fatalf("cases = %#v", got.Cases)
+	}
+	list, err := data.ListEvals(ctx)
+	if err != nil || len(list) != 1 || list[0].Cases != nil || list[0].Summary != want {
+		t.Fatalf("list = %#v, error = %v", list, err)
+	}
+	if _, err := data.GetEval(ctx, "evl_missing"); !errors.Is(err, ErrEvalNotFound) {
+		t.Fatalf("missing eval error = %v", err)
+	}
+}
+
 func TestSQLiteSaveMessageRevisionedAndRollback(t *testing.T) {
 	data, err := NewSQLiteStore(filepath.Join(t.TempDir(), "wingman.db"))
 	if err != nil {
@@ -669,3 +897,41 @@ func TestSQLiteSaveMessageRevisionRaceAcrossHandles(t *testing.T) {
 		t.Fatalf("messages=%#v err=%v", messages, err)
 	}
 }
+
+func TestSQLiteMCPCredentialsSurviveProviderWrites(t *testing.T) {
+	data, err := NewSQLiteStore(filepath.Join(t.TempDir(), "wingman.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = data.Close() })
+
+	if err := data.SetMCPCredential("docs", AuthCredential{Type: "oauth", Access: "access", Refresh: "refresh", ClientID: "client", TokenURL: "https://auth.example.com/token"}); err != nil {
+		t.Fatal(err)
+	}
+	auth, err := data.GetAuth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.Providers["openai"] = AuthCredential{Type: "api", Key: "sk-test"}
+	auth.MCP = nil
+	if err := data.SetAuth(auth); err != nil {
+		t.Fatal(err)
+	}
+	auth, err = data.GetAuth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Providers["openai"].Key != "sk-test" || auth.MCP["docs"].ClientID != "client" || auth.MCP["docs"].Refresh != "refresh" {
+		t.Fatalf("auth = %#v", auth)
+	}
+	if err := data.DeleteMCPCredential("docs"); err != nil {
+		t.Fatal(err)
+	}
+	auth, err = data.GetAuth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := auth.MCP["docs"]; ok || auth.Providers["openai"].Key != "sk-test" {
+		t.Fatalf("auth after delete = %#v", auth)
+	}
+}
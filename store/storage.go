@@ -19,6 +19,10 @@ var ErrMessageRevisionStale = errors.New("message revision stale")
 var ErrMessageRevisionConflict = errors.New("message revision conflict")
 var ErrPermissionRequestNotFound = errors.New("permission request not found")
 var ErrPermissionRequestTransitionConflict = errors.New("permission request transition conflict")
+var ErrArtifactNotFound = errors.New("artifact not found")
+var ErrPermissionRulesetVersionConflict = errors.New("permission ruleset version conflict")
+var ErrBatchNotFound = errors.New("batch not found")
+var ErrEvalNotFound = errors.New("eval not found")
 
 // PermissionRequestNotFound identifies a request absent from a session.
 type PermissionRequestNotFound struct{ SessionID, RequestID string }
@@ -54,8 +58,19 @@ type Store interface {
 	CreateAgent(agent *Agent) error
 	GetAgent(id string) (*Agent, error)
 	ListAgents() ([]*Agent, error)
+	// UpdateAgent overwrites an agent and appends its next revision.
 	UpdateAgent(agent *Agent) error
 	DeleteAgent(id string) error
+	// ListAgentRevisions returns an agent's revisions oldest first.
+	ListAgentRevisions(id string) ([]AgentRevision, error)
+	// GetAgentRevision returns one immutable agent revision.
+	GetAgentRevision(id string, revision int64) (*AgentRevision, error)
+
+	CreateCommand(command *Command) error
+	GetCommand(id string) (*Command, error)
+	ListCommands() ([]*Command, error)
+	UpdateCommand(command *Command) error
+	DeleteCommand(id string) error
 
 	CreateSession(session *Session) error
 	GetSession(id string) (*Session, error)
@@ -78,6 +93,14 @@ type Store interface {
 	ResolvePermissionRequest(ctx context.Context, resolution PermissionRequestResolution) (PermissionRequestTransition, error)
 	ListPermissionGrants(ctx context.Context, sessionID string) ([]PermissionGrant, error)
 	InterruptPendingPermissionRequests(ctx context.Context) ([]PermissionRequestTransition, error)
+	// GetPermissionRuleset returns the persisted ruleset for a scope, or an
+	// empty version-zero ruleset when none has been written.
+	GetPermissionRuleset(ctx context.Context, scope, scopeID string) (*PermissionRuleset, error)
+	// UpdatePermissionRuleset replaces a scope's ruleset and appends an audit
+	// entry in one transaction.
+	UpdatePermissionRuleset(ctx context.Context, update PermissionRulesetUpdate) (PermissionRulesetTransition, error)
+	// ListPermissionRuleChanges returns a scope's audit history oldest first.
+	ListPermissionRuleChanges(ctx context.Context, scope, scopeID string) ([]PermissionRuleChange, error)
 
 	// SaveMessage atomically stores a complete authoritative message revision.
 	SaveMessage(ctx context.Context, msg StoredMessage) error
@@ -96,6 +119,11 @@ type Store interface {
 	SaveToolUse(ctx context.Context, use ToolUse) error
 	ListToolUses(ctx context.Context, sessionID string) ([]ToolUse, error)
 	InterruptActiveToolUses(ctx context.Context) error
+	// SaveArtifact stores an immutable session artifact, assigning its ID,
+	// size, and creation time when unset.
+	SaveArtifact(ctx context.Context, artifact *Artifact) error
+	// GetArtifact returns one artifact of a session with its content.
+	GetArtifact(ctx context.Context, sessionID, id string) (*Artifact, error)
 	// AppendSessionEvent stores one durable session event and assigns its
 	// session-scoped sequence.
 	AppendSessionEvent(ctx context.Context, event SessionEvent) (SessionEvent, error)
@@ -105,6 +133,39 @@ type Store interface {
 	// or zero when the existing session has no events.
 	SessionEventWatermark(ctx context.Context, sessionID string) (int64, error)
 
+	// CreateBatch stores a provider batch and its items, assigning the
+	// batch ID and timestamps when unset.
+	CreateBatch(ctx context.Context, batch *Batch) error
+	// GetBatch returns a batch with its items in index order.
+	GetBatch(ctx context.Context, id string) (*Batch, error)
+	// ListBatches returns batches newest first with item counts but
+	// without items.
+	ListBatches(ctx context.Context) ([]*Batch, error)
+	// ListBatchesByClient returns one client's batches like ListBatches.
+	ListBatchesByClient(ctx context.Context, clientID string) ([]*Batch, error)
+	// ListBatchesByStatus returns the batches in status like ListBatches.
+	ListBatchesByStatus(ctx context.Context, status string) ([]*Batch, error)
+	// UpdateBatch writes a batch's provider batch ID, status, and error.
+	// A terminal status
+	// records the completion time.
+	UpdateBatch(ctx context.Context, batch *Batch) error
+	// UpdateBatchItem records the session, run, and outcome of one item.
+	UpdateBatchItem(ctx context.Context, batchID string, item BatchItem) error
+
+	// CreateEval stores an eval and its cases, assigning the eval ID and
+	// timestamps when unset.
+	CreateEval(ctx context.Context, eval *Eval) error
+	// GetEval returns an eval with its cases in index order.
+	GetEval(ctx context.Context, id string) (*Eval, error)
+	// ListEvals returns evals newest first with summaries but without
+	// cases.
+	ListEvals(ctx context.Context) ([]*Eval, error)
+	// UpdateEval writes an eval's status and error. A status other than
+	// running records the completion time.
+	UpdateEval(ctx context.Context, eval *Eval) error
+	// UpdateEvalCase records the session, run, and outcome of one case.
+	UpdateEvalCase(ctx context.Context, evalID string, c EvalCase) error
+
 	// CreateClient registers a Wingman API consumer identity.
 	CreateClient(name string) (*Client, error)
 	CreateClientWithID(id, name string) (*Client, error)
@@ -122,6 +183,8 @@ type Store interface {
 
 	GetAuth() (*Auth, error)
 	SetAuth(auth *Auth) error
+	SetMCPCredential(server string, credential AuthCredential) error
+	DeleteMCPCredential(server string) error
 
 	Close() error
 }
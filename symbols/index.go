@@ -0,0 +1,503 @@
+// Package symbols maintains a tree-sitter symbol index of a working
+// directory and exposes outline, definition, and reference lookups as tools.
+package symbols
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	ts "github.com/odvcencio/gotreesitter"
+	"github.com/odvcencio/gotreesitter/grammars"
+
+	"github.com/chaserensberger/wingman/tool"
+)
+
+const (
+	// maxFileSize skips generated or vendored files too large to be useful.
+	maxFileSize = 1 << 20
+	// maxFiles bounds how many source files one index tracks.
+	maxFiles = 20000
+	// maxReferences bounds one reference lookup.
+	maxReferences = 500
+	// rescanInterval is how long a workspace walk is trusted before lookups
+	// walk again to pick up files changed outside the file tools.
+	rescanInterval = 5 * time.Second
+)
+
+// ErrNoWorkDir is returned by lookups on an index without a root.
+var ErrNoWorkDir = errors.New("symbol index requires a working directory")
+
+// extensions lists the file types the index parses. Each must have a
+// grammar with a tags query.
+var extensions = map[string]bool{
+	".go":   true,
+	".py":   true,
+	".pyi":  true,
+	".ts":   true,
+	".mts":  true,
+	".cts":  true,
+	".tsx":  true,
+	".js":   true,
+	".mjs":  true,
+	".cjs":  true,
+	".jsx":  true,
+	".rs":   true,
+	".java": true,
+	".c":    true,
+	".h":    true,
+	".cc":   true,
+	".cpp":  true,
+	".hpp":  true,
+}
+
+// Symbol is one definition found in a source file.
+type Symbol struct {
+	Name string `json:"name"`
+	// Kind is the tags query kind without its "definition." prefix, such as
+	// function, method, class, interface, type, or constant.
+	Kind string `json:"kind"`
+	// Path is relative to the index root, with forward slashes.
+	Path string `json:"path"`
+	// Line and Column locate the name, 1-based.
+	Line    int `json:"line"`
+	Column  int `json:"column"`
+	EndLine int `json:"end_line"`
+	// Container names the enclosing definition, if any.
+	Container string `json:"container,omitempty"`
+
+	start, end uint32
+}
+
+// Reference is one use of a name in a source file. Comments and string
+// literals never match.
+type Reference struct {
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Text   string `json:"text"`
+}
+
+type fileEntry struct {
+	modTime time.Time
+	size    int64
+	symbols []Symbol
+}
+
+// Index is a lazily built symbol index of one working directory. Lookups
+// refresh files changed since they were parsed; Update re-indexes files as
+// soon as a tool reports changing them. An Index is safe for concurrent use.
+type Index struct {
+	root string
+
+	// mu guards files and scanned. Files are read and parsed without it,
+	// so a rescan does not block lookups and updates.
+	mu      sync.Mutex
+	files   map[string]*fileEntry
+	scanned time.Time
+
+	// scanMu serializes rescans.
+	scanMu sync.Mutex
+
+	// tagMu guards taggers, which reuse parse buffers.
+	tagMu   sync.Mutex
+	taggers map[string]*ts.Tagger
+}
+
+// NewIndex returns an empty index rooted at root. Nothing is parsed until
+// the first lookup.
+func NewIndex(root string) *Index {
+	return &Index{root: root, files: map[string]*fileEntry{}, taggers: map[string]*ts.Tagger{}}
+}
+
+// Root returns the indexed directory.
+func (ix *Index) Root() string { return ix.root }
+
+// Supported reports whether path has a file type the index parses.
+func Supported(path string) bool {
+	return extensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// Outline returns the definitions in one file in source order. path may be
+// absolute or relative to the index root.
+func (ix *Index) Outline(ctx context.Context, path string) ([]Symbol, error) {
+	abs, rel, err := ix.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	if !Supported(rel) {
+		return nil, fmt.Errorf("unsupported file type: %s", rel)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", rel)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	entry, err := ix.load(abs, rel, info)
+	if err != nil {
+		return nil, err
+	}
+	return append([]Symbol(nil), entry.symbols...), nil
+}
+
+// Definitions returns every definition of name, optionally restricted to
+// one kind, ordered by path and line.
+func (ix *Index) Definitions(ctx context.Context, name, kind string) ([]Symbol, error) {
+	if err := ix.refresh(ctx); err != nil {
+		return nil, err
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	var found []Symbol
+	for _, rel := range ix.paths("") {
+		for _, sym := range ix.files[rel].symbols {
+			if sym.Name == name && (kind == "" || sym.Kind == kind) {
+				found = append(found, sym)
+			}
+		}
+	}
+	return found, nil
+}
+
+// References returns the identifiers spelled name outside their
+// definitions, in files under dir (relative to the root; empty for all).
+// truncated reports whether the limit cut the results short.
+func (ix *Index) References(ctx context.Context, name, dir string) (refs []Reference, truncated bool, err error) {
+	prefix := ""
+	if dir != "" {
+		_, rel, err := ix.resolve(dir)
+		if err != nil {
+			return nil, false, err
+		}
+		if rel != "." {
+			prefix = rel
+		}
+	}
+	if err := ix.refresh(ctx); err != nil {
+		return nil, false, err
+	}
+	ix.mu.Lock()
+	paths := ix.paths(prefix)
+	defs := make(map[string][]Symbol, len(paths))
+	for _, rel := range paths {
+		defs[rel] = ix.files[rel].symbols
+	}
+	ix.mu.Unlock()
+	needle := []byte(name)
+	for _, rel := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		src, err := os.ReadFile(filepath.Join(ix.root, filepath.FromSlash(rel)))
+		if err != nil || !bytes.Contains(src, needle) {
+			continue
+		}
+		found, err := references(rel, src, name, defs[rel])
+		if err != nil {
+			continue
+		}
+		for _, ref := range found {
+			if len(refs) == maxReferences {
+				return refs, true, nil
+			}
+			refs = append(refs, ref)
+		}
+	}
+	return refs, false, nil
+}
+
+// Update re-indexes the given files, dropping those that no longer exist.
+// Paths may be absolute or relative to the index root; paths outside it and
+// unsupported file types are ignored.
+func (ix *Index) Update(paths ...string) {
+	for _, path := range paths {
+		abs, rel, err := ix.resolve(path)
+		if err != nil || !Supported(rel) {
+			continue
+		}
+		info, err := os.Stat(abs)
+		if err != nil || info.IsDir() {
+			ix.mu.Lock()
+			delete(ix.files, rel)
+			ix.mu.Unlock()
+			continue
+		}
+		if _, err := ix.load(abs, rel, info); err != nil {
+			ix.mu.Lock()
+			delete(ix.files, rel)
+			ix.mu.Unlock()
+		}
+	}
+}
+
+// resolve returns the absolute path and the slash-separated path relative to
+// the root, rejecting paths that leave it.
+func (ix *Index) resolve(path string) (string, string, error) {
+	if ix.root == "" {
+		return "", "", ErrNoWorkDir
+	}
+	abs := path
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(ix.root, abs)
+	}
+	abs = filepath.Clean(abs)
+	rel, err := filepath.Rel(ix.root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("path %q is outside the working directory", path)
+	}
+	return abs, filepath.ToSlash(rel), nil
+}
+
+// paths returns the indexed paths under prefix in sorted order. The caller
+// holds ix.mu.
+func (ix *Index) paths(prefix string) []string {
+	paths := make([]string, 0, len(ix.files))
+	for rel := range ix.files {
+		if prefix == "" || rel == prefix || strings.HasPrefix(rel, prefix+"/") {
+			paths = append(paths, rel)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// refresh walks the root when the last walk is older than rescanInterval,
+// parsing new and changed files and dropping deleted ones. The walk runs
+// without ix.mu and its result is swapped in at the end; files Update
+// changed meanwhile keep their newer entries.
+func (ix *Index) refresh(ctx context.Context) error {
+	if ix.root == "" {
+		return ErrNoWorkDir
+	}
+	ix.scanMu.Lock()
+	defer ix.scanMu.Unlock()
+	ix.mu.Lock()
+	if !ix.scanned.IsZero() && time.Since(ix.scanned) < rescanInterval {
+		ix.mu.Unlock()
+		return nil
+	}
+	known := maps.Clone(ix.files)
+	ix.mu.Unlock()
+
+	files := make(map[string]*fileEntry, len(known))
+	err := filepath.WalkDir(ix.root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != ix.root && tool.SkipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !Supported(path) {
+			return nil
+		}
+		if len(files) == maxFiles {
+			return filepath.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(ix.root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if entry := known[rel]; entry.current(info) {
+			files[rel] = entry
+		} else if entry, err := ix.parse(path, rel, info); err == nil {
+			files[rel] = entry
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for rel, entry := range ix.files {
+		if entry != known[rel] {
+			files[rel] = entry
+		}
+	}
+	for rel := range known {
+		if _, ok := ix.files[rel]; !ok {
+			delete(files, rel)
+		}
+	}
+	ix.files = files
+	ix.scanned = time.Now()
+	return nil
+}
+
+// current reports whether the entry was parsed from the file as it is now.
+func (e *fileEntry) current(info os.FileInfo) bool {
+	return e != nil && e.modTime.Equal(info.ModTime()) && e.size == info.Size()
+}
+
+// load returns the entry for a file, parsing it when it changed since it
+// was last indexed.
+func (ix *Index) load(abs, rel string, info os.FileInfo) (*fileEntry, error) {
+	ix.mu.Lock()
+	entry := ix.files[rel]
+	ix.mu.Unlock()
+	if entry.current(info) {
+		return entry, nil
+	}
+	entry, err := ix.parse(abs, rel, info)
+	if err != nil {
+		return nil, err
+	}
+	ix.mu.Lock()
+	ix.files[rel] = entry
+	ix.mu.Unlock()
+	return entry, nil
+}
+
+// parse reads and tags one file without touching the index.
+func (ix *Index) parse(abs, rel string, info os.FileInfo) (*fileEntry, error) {
+	if info.Size() > maxFileSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", rel, maxFileSize)
+	}
+	src, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	ix.tagMu.Lock()
+	defer ix.tagMu.Unlock()
+	tagger, err := ix.tagger(rel)
+	if err != nil {
+		return nil, err
+	}
+	return &fileEntry{modTime: info.ModTime(), size: info.Size(), symbols: definitions(rel, tagger.Tag(src))}, nil
+}
+
+// tagger returns the cached tagger for the file's language. Taggers reuse
+// parse buffers, so callers hold ix.tagMu.
+func (ix *Index) tagger(path string) (*ts.Tagger, error) {
+	entry := grammars.DetectLanguage(path)
+	if entry == nil {
+		return nil, fmt.Errorf("unsupported file type: %s", path)
+	}
+	if tagger, ok := ix.taggers[entry.Name]; ok {
+		if tagger == nil {
+			return nil, fmt.Errorf("no symbol query for %s", entry.Name)
+		}
+		return tagger, nil
+	}
+	query := grammars.ResolveTagsQuery(*entry)
+	lang := entry.Language()
+	var opts []ts.TaggerOption
+	if factory := entry.TokenSourceFactory; factory != nil {
+		opts = append(opts, ts.WithTaggerTokenSourceFactory(func(src []byte) ts.TokenSource { return factory(src, lang) }))
+	}
+	var tagger *ts.Tagger
+	if query != "" {
+		tagger, _ = ts.NewTagger(lang, query, opts...)
+	}
+	ix.taggers[entry.Name] = tagger
+	if tagger == nil {
+		return nil, fmt.Errorf("no symbol query for %s", entry.Name)
+	}
+	return tagger, nil
+}
+
+// definitions converts definition tags to symbols in source order, naming
+// each symbol's innermost enclosing definition.
+func definitions(rel string, tags []ts.Tag) []Symbol {
+	symbols := make([]Symbol, 0, len(tags))
+	seen := map[uint32]bool{}
+	for _, tag := range tags {
+		kind, ok := strings.CutPrefix(tag.Kind, "definition.")
+		if !ok || tag.Name == "" || seen[tag.NameRange.StartByte] {
+			continue
+		}
+		seen[tag.NameRange.StartByte] = true
+		symbols = append(symbols, Symbol{
+			Name:    tag.Name,
+			Kind:    kind,
+			Path:    rel,
+			Line:    int(tag.NameRange.StartPoint.Row) + 1,
+			Column:  int(tag.NameRange.StartPoint.Column) + 1,
+			EndLine: int(tag.Range.EndPoint.Row) + 1,
+			start:   tag.Range.StartByte,
+			end:     tag.Range.EndByte,
+		})
+	}
+	sort.SliceStable(symbols, func(i, j int) bool {
+		if symbols[i].start != symbols[j].start {
+			return symbols[i].start < symbols[j].start
+		}
+		return symbols[i].end > symbols[j].end
+	})
+	var open []int
+	for i := range symbols {
+		for len(open) > 0 && symbols[open[len(open)-1]].end <= symbols[i].start {
+			open = open[:len(open)-1]
+		}
+		if len(open) > 0 {
+			symbols[i].Container = symbols[open[len(open)-1]].Name
+		}
+		open = append(open, i)
+	}
+	return symbols
+}
+
+// references parses src and returns the identifier leaves spelled name,
+// skipping the definitions' own names.
+func references(rel string, src []byte, name string, defs []Symbol) ([]Reference, error) {
+	tree, err := grammars.ParseFilePooled(rel, src)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Release()
+	defined := map[[2]int]bool{}
+	for _, sym := range defs {
+		defined[[2]int{sym.Line, sym.Column}] = true
+	}
+	lines := bytes.Split(src, []byte("\n"))
+	var refs []Reference
+	var walk func(*ts.Node)
+	walk = func(n *ts.Node) {
+		if n.ChildCount() > 0 {
+			for _, child := range n.Children() {
+				walk(child)
+			}
+			return
+		}
+		if !strings.Contains(tree.NodeType(n), "identifier") || tree.NodeText(n) != name {
+			return
+		}
+		point := n.StartPoint()
+		line, column := int(point.Row)+1, int(point.Column)+1
+		if defined[[2]int{line, column}] {
+			return
+		}
+		text := ""
+		if int(point.Row) < len(lines) {
+			text = strings.TrimSpace(string(lines[point.Row]))
+		}
+		refs = append(refs, Reference{Path: rel, Line: line, Column: column, Text: text})
+	}
+	walk(tree.RootNode())
+	return refs, nil
+}
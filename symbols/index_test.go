@@ -0,0 +1,123 @@
+package symbols
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chaserensberger/wingman/agent/run"
+	"github.com/chaserensberger/wingman/tool"
+)
+
+func writeSource(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestIndexFindsDefinitionsAcrossLanguages(t *testing.T) {
+	dir := t.TempDir()
+	writeSource(t, dir, "server/server.go", "package server\n\ntype Server struct{}\n\nfunc (s *Server) Serve() error {\n\treturn nil\n}\n")
+	writeSource(t, dir, "app/models.py", "class Store:\n    def save(self):\n        pass\n")
+	writeSource(t, dir, "web/client.ts", "export interface Options { url: string }\nexport class Client {\n  fetch() {}\n}\n")
+	writeSource(t, dir, "core/lib.rs", "pub struct Store;\n\nfn main() {}\n")
+	writeSource(t, dir, "node_modules/dep/index.js", "function Store() {}\n")
+	ix := NewIndex(dir)
+	ctx := context.Background()
+
+	stores, err := ix.Definitions(ctx, "Store", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stores) != 2 || stores[0].Path != "app/models.py" || stores[0].Kind != "class" || stores[1].Path != "core/lib.rs" || stores[1].Line != 1 {
+		t.Fatalf("Store definitions = %+v", stores)
+	}
+	if methods, _ := ix.Definitions(ctx, "save", "class"); len(methods) != 0 {
+		t.Fatalf("kind filter = %+v", methods)
+	}
+
+	outline, err := ix.Outline(ctx, "web/client.ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outline) != 3 || outline[0].Name != "Options" || outline[0].Kind != "interface" || outline[2].Name != "fetch" || outline[2].Container != "Client" {
+		t.Fatalf("outline = %+v", outline)
+	}
+	result, err := (&outlineTool{index: ix}).Execute(ctx, tool.Invocation{WorkDir: dir, Input: map[string]any{"path": filepath.Join(dir, "server/server.go")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Text != "type Server (lines 3-3)\nmethod Serve (lines 5-7)" || result.Structured.(Outline).Path != "server/server.go" {
+		t.Fatalf("outline tool = %q, %+v", result.Text, result.Structured)
+	}
+	if _, err := ix.Outline(ctx, "../outside.go"); err == nil {
+		t.Fatal("path outside the root was accepted")
+	}
+}
+
+func TestReferencesSkipCommentsStringsAndDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	writeSource(t, dir, "main.go", "package main\n\n// Run starts the app.\nfunc Run() {}\n\nfunc main() {\n\tRun()\n\tprintln(\"Run\")\n}\n")
+	writeSource(t, dir, "pkg/other.py", "from main import Run\n\nRun()  # Run again\n")
+	ix := NewIndex(dir)
+
+	refs, truncated, err := ix.References(context.Background(), "Run", "")
+	if err != nil || truncated {
+		t.Fatalf("references = %v, %v", truncated, err)
+	}
+	if len(refs) != 3 || refs[0].Path != "main.go" || refs[0].Line != 7 || refs[0].Text != "Run()" || refs[1].Path != "pkg/other.py" || refs[2].Line != 3 {
+		t.Fatalf("references = %+v", refs)
+	}
+	if scoped, _, _ := ix.References(context.Background(), "Run", "pkg"); len(scoped) != 2 {
+		t.Fatalf("scoped references = %+v", scoped)
+	}
+}
+
+func TestPluginReindexesChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := writeSource(t, dir, "main.go", "package main\n\nfunc oldName() {}\n")
+	ix := NewIndex(dir)
+	ctx := context.Background()
+	if found, _ := ix.Definitions(ctx, "oldName", ""); len(found) != 1 {
+		t.Fatalf("initial definitions = %+v", found)
+	}
+
+	// A write within the rescan interval is only visible through Update.
+	writeSource(t, dir, "main.go", "package main\n\nfunc newName() {}\n")
+	writeSource(t, dir, "added.go", "package main\n\nfunc helper() {}\n")
+	hook := ix.Plugin().(indexPlugin).afterToolCall
+	metadata := map[string]any{"files": []any{
+		map[string]any{"filePath": path, "type": "update"},
+		map[string]any{"filePath": filepath.Join(dir, "gone.go"), "movePath": "added.go", "type": "move"},
+	}}
+	if _, err := hook(ctx, run.ToolCall{Name: "apply_patch"}, run.ToolResult{Name: "apply_patch", Metadata: metadata}); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := ix.Definitions(ctx, "oldName", ""); len(found) != 0 {
+		t.Fatalf("stale definitions = %+v", found)
+	}
+	for _, name := range []string{"newName", "helper"} {
+		if found, _ := ix.Definitions(ctx, name, ""); len(found) != 1 {
+			t.Fatalf("%s definitions = %+v", name, found)
+		}
+	}
+	result, err := (&definitionTool{index: ix}).Execute(ctx, tool.Invocation{WorkDir: dir, Input: map[string]any{"name": "helper"}})
+	if err != nil || !strings.HasPrefix(result.Text, "added.go:3:6: function helper") {
+		t.Fatalf("definition tool = %q, %v", result.Text, err)
+	}
+}
+
+func TestReferencesToolChecksPath(t *testing.T) {
+	check, declared, err := tool.PermissionFor(context.Background(), &referencesTool{}, tool.Invocation{Input: map[string]any{"name": "Run", "path": "secrets"}})
+	if err != nil || !declared || check.Action != "grep" || strings.Join(check.Resources, ",") != "Run,secrets" {
+		t.Fatalf("check = %#v, declared = %v, error = %v", check, declared, err)
+	}
+}
@@ -0,0 +1,56 @@
+package symbols
+
+import (
+	"context"
+
+	"github.com/chaserensberger/wingman/agent/plugin"
+	"github.com/chaserensberger/wingman/agent/run"
+)
+
+// Plugin returns an agent plugin that re-indexes the files a tool call
+// reports changing in its "files" metadata, the shape write, edit, and
+// apply_patch produce, so the next lookup sees the edit without a rescan.
+func (ix *Index) Plugin() plugin.Plugin { return indexPlugin{index: ix} }
+
+type indexPlugin struct{ index *Index }
+
+func (p indexPlugin) Name() string { return "symbols" }
+
+func (p indexPlugin) Activate(r *plugin.Registry) (plugin.Cleanup, error) {
+	return nil, r.RegisterAfterToolCall(p.afterToolCall)
+}
+
+func (p indexPlugin) afterToolCall(_ context.Context, _ run.ToolCall, result run.ToolResult) (run.ToolResult, error) {
+	if result.IsError {
+		return result, nil
+	}
+	if paths := changedFiles(result.Metadata); len(paths) > 0 {
+		p.index.Update(paths...)
+	}
+	return result, nil
+}
+
+// changedFiles reads the paths from file-diff metadata, whether it holds
+// the tool's own values or a decoded JSON copy.
+func changedFiles(metadata map[string]any) []string {
+	var files []map[string]any
+	switch raw := metadata["files"].(type) {
+	case []map[string]any:
+		files = raw
+	case []any:
+		for _, item := range raw {
+			if file, ok := item.(map[string]any); ok {
+				files = append(files, file)
+			}
+		}
+	}
+	var paths []string
+	for _, file := range files {
+		for _, key := range []string{"filePath", "movePath"} {
+			if path, ok := file[key].(string); ok && path != "" {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
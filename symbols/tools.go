@@ -0,0 +1,222 @@
+package symbols
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chaserensberger/wingman/tool"
+)
+
+// Tool names.
+const (
+	OutlineToolName    = "symbol_outline"
+	DefinitionToolName = "symbol_definition"
+	ReferencesToolName = "symbol_references"
+)
+
+// Outline is the structured result of symbol_outline.
+type Outline struct {
+	Path    string   `json:"path"`
+	Symbols []Symbol `json:"symbols"`
+}
+
+// Definitions is the structured result of symbol_definition.
+type Definitions struct {
+	Name    string   `json:"name"`
+	Symbols []Symbol `json:"symbols"`
+}
+
+// References is the structured result of symbol_references.
+type References struct {
+	Name       string      `json:"name"`
+	References []Reference `json:"references"`
+	Truncated  bool        `json:"truncated,omitempty"`
+}
+
+// Tools returns the navigation tools bound to ix.
+func (ix *Index) Tools() []tool.Tool {
+	return []tool.Tool{
+		&definitionTool{index: ix},
+		&outlineTool{index: ix},
+		&referencesTool{index: ix},
+	}
+}
+
+type outlineTool struct{ index *Index }
+
+func (t *outlineTool) Name() string { return OutlineToolName }
+
+func (t *outlineTool) Description() string {
+	return "List the functions, types, methods, and other definitions in a source file with their line ranges. Supports Go, Python, TypeScript, JavaScript, Rust, Java, C, and C++."
+}
+
+func (t *outlineTool) Definition() tool.Definition {
+	return tool.Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: tool.InputSchema{
+			Type: "object",
+			Properties: map[string]tool.Property{
+				"path": {
+					Type:        "string",
+					Description: "The source file to outline",
+				},
+			},
+			Required: []string{"path"},
+		},
+		Permission: &tool.PermissionTarget{Action: "read", ResourceFields: []string{"path"}},
+	}
+}
+
+func (t *outlineTool) DirectoryScoped() {}
+
+func (t *outlineTool) Execute(ctx context.Context, inv tool.Invocation) (tool.Result, error) {
+	path, _ := inv.Input["path"].(string)
+	if path == "" {
+		return tool.Result{}, fmt.Errorf("path is required")
+	}
+	symbols, err := t.index.Outline(ctx, path)
+	if err != nil {
+		return tool.Result{}, err
+	}
+	_, rel, _ := t.index.resolve(path)
+	outline := Outline{Path: rel, Symbols: nonNil(symbols)}
+	if len(symbols) == 0 {
+		return tool.Result{Text: "No definitions found in " + rel + ".", Structured: outline}, nil
+	}
+	depth := map[string]int{}
+	var b strings.Builder
+	for _, sym := range symbols {
+		level := 0
+		if sym.Container != "" {
+			level = depth[sym.Container] + 1
+		}
+		depth[sym.Name] = level
+		fmt.Fprintf(&b, "%s%s %s (lines %d-%d)\n", strings.Repeat("  ", level), sym.Kind, sym.Name, sym.Line, sym.EndLine)
+	}
+	return tool.Result{Text: strings.TrimSuffix(b.String(), "\n"), Structured: outline, Metadata: map[string]any{"count": len(symbols)}}, nil
+}
+
+type definitionTool struct{ index *Index }
+
+func (t *definitionTool) Name() string { return DefinitionToolName }
+
+func (t *definitionTool) Description() string {
+	return "Find where a symbol is defined in the working directory by exact name. Returns each definition's file, line, and kind."
+}
+
+func (t *definitionTool) Definition() tool.Definition {
+	return tool.Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: tool.InputSchema{
+			Type: "object",
+			Properties: map[string]tool.Property{
+				"name": {
+					Type:        "string",
+					Description: "The exact symbol name, without a package or receiver qualifier",
+				},
+				"kind": {
+					Type:        "string",
+					Description: "Only return definitions of this kind (e.g., 'function', 'method', 'class', 'type', 'interface')",
+				},
+			},
+			Required: []string{"name"},
+		},
+		Permission: &tool.PermissionTarget{Action: "grep", ResourceFields: []string{"name"}},
+	}
+}
+
+func (t *definitionTool) DirectoryScoped() {}
+
+func (t *definitionTool) Execute(ctx context.Context, inv tool.Invocation) (tool.Result, error) {
+	name, _ := inv.Input["name"].(string)
+	if name == "" {
+		return tool.Result{}, fmt.Errorf("name is required")
+	}
+	kind, _ := inv.Input["kind"].(string)
+	symbols, err := t.index.Definitions(ctx, name, kind)
+	if err != nil {
+		return tool.Result{}, err
+	}
+	found := Definitions{Name: name, Symbols: nonNil(symbols)}
+	if len(symbols) == 0 {
+		return tool.Result{Text: fmt.Sprintf("No definitions of %s found.", name), Structured: found, Metadata: map[string]any{"count": 0}}, nil
+	}
+	var b strings.Builder
+	for _, sym := range symbols {
+		fmt.Fprintf(&b, "%s:%d:%d: %s %s", sym.Path, sym.Line, sym.Column, sym.Kind, sym.Name)
+		if sym.Container != "" {
+			fmt.Fprintf(&b, " (in %s)", sym.Container)
+		}
+		b.WriteByte('\n')
+	}
+	return tool.Result{Text: strings.TrimSuffix(b.String(), "\n"), Structured: found, Metadata: map[string]any{"count": len(symbols)}}, nil
+}
+
+type referencesTool struct{ index *Index }
+
+func (t *referencesTool) Name() string { return ReferencesToolName }
+
+func (t *referencesTool) Description() string {
+	return fmt.Sprintf("Find uses of a symbol name in the working directory's source files. Matches identifiers only, so comments and strings are skipped, but unrelated symbols with the same name also match. Returns at most %d references.", maxReferences)
+}
+
+func (t *referencesTool) Definition() tool.Definition {
+	return tool.Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: tool.InputSchema{
+			Type: "object",
+			Properties: map[string]tool.Property{
+				"name": {
+					Type:        "string",
+					Description: "The exact symbol name",
+				},
+				"path": {
+					Type:        "string",
+					Description: "Only search files under this directory or in this file (optional, defaults to working directory)",
+				},
+			},
+			Required: []string{"name"},
+		},
+		Permission: &tool.PermissionTarget{Action: "grep", ResourceFields: []string{"name", "path"}},
+	}
+}
+
+func (t *referencesTool) DirectoryScoped() {}
+
+func (t *referencesTool) BoundedOutput() {}
+
+func (t *referencesTool) Execute(ctx context.Context, inv tool.Invocation) (tool.Result, error) {
+	name, _ := inv.Input["name"].(string)
+	if name == "" {
+		return tool.Result{}, fmt.Errorf("name is required")
+	}
+	path, _ := inv.Input["path"].(string)
+	refs, truncated, err := t.index.References(ctx, name, path)
+	if err != nil {
+		return tool.Result{}, err
+	}
+	found := References{Name: name, References: nonNil(refs), Truncated: truncated}
+	if len(refs) == 0 {
+		return tool.Result{Text: fmt.Sprintf("No references to %s found.", name), Structured: found, Metadata: map[string]any{"count": 0}}, nil
+	}
+	var b strings.Builder
+	for _, ref := range refs {
+		fmt.Fprintf(&b, "%s:%d:%d: %s\n", ref.Path, ref.Line, ref.Column, ref.Text)
+	}
+	if truncated {
+		fmt.Fprintf(&b, "(results truncated to %d references; narrow the search with path)\n", maxReferences)
+	}
+	return tool.Result{Text: strings.TrimSuffix(b.String(), "\n"), Structured: found, Metadata: map[string]any{"count": len(refs), "truncated": truncated}}, nil
+}
+
+// nonNil keeps empty results encoding as [] rather than null.
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,398 @@
+// Package terminal runs interactive shells on pseudo-terminals for remote
+// clients. Each session has at most one terminal; any number of viewers can
+// attach to it, and it keeps running between viewers until the shell exits or
+// the manager closes.
+package terminal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/creack/pty"
+)
+
+// Default terminal size used when a client does not send one.
+const (
+	DefaultCols = 80
+	DefaultRows = 24
+)
+
+// maxScrollback is the output replayed to a viewer that attaches to a running
+// terminal.
+const maxScrollback = 64 << 10
+
+// maxSharedContext bounds the terminal output shared with the agent.
+const maxSharedContext = 8 << 10
+
+// outputDrainDelay bounds how long the terminal keeps reading after the
+// shell exits, for background jobs that still hold the pseudo-terminal open.
+const outputDrainDelay = time.Second
+
+// viewerBuffer is the number of output chunks queued for a viewer. A viewer
+// that falls further behind is detached so it cannot stall the shell.
+const viewerBuffer = 256
+
+// ErrClosed reports a manager that no longer opens terminals.
+var ErrClosed = errors.New("terminal manager is closed")
+
+// Options describes the terminal to open for a session.
+type Options struct {
+	SessionID string
+	WorkDir   string
+	// Shell defaults to $SHELL, or bash when it is unset.
+	Shell string
+	Cols  uint16
+	Rows  uint16
+}
+
+// Manager owns the terminals opened for sessions.
+type Manager struct {
+	mu     sync.Mutex
+	terms  map[string]*Terminal
+	closed bool
+}
+
+// NewManager returns an empty manager.
+func NewManager() *Manager {
+	return &Manager{terms: map[string]*Terminal{}}
+}
+
+// Terminal is one shell running on a pseudo-terminal.
+type Terminal struct {
+	sessionID string
+	cmd       *exec.Cmd
+	pty       *os.File
+	done      chan struct{}
+
+	mu         sync.Mutex
+	scrollback []byte
+	viewers    map[*Viewer]struct{}
+	shared     bool
+	exitCode   int
+}
+
+// Viewer receives a terminal's output from the moment it attached.
+type Viewer struct {
+	term   *Terminal
+	output chan []byte
+}
+
+// Open returns the session's running terminal, or starts one in opts.WorkDir
+// when the session has none. The size in opts only applies to a new terminal.
+func (m *Manager) Open(opts Options) (*Terminal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if t := m.terms[opts.SessionID]; t != nil && t.Running() {
+		return t, nil
+	}
+	t, err := start(opts)
+	if err != nil {
+		return nil, err
+	}
+	m.terms[opts.SessionID] = t
+	go func() {
+		<-t.done
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.terms[opts.SessionID] == t {
+			delete(m.terms, opts.SessionID)
+		}
+	}()
+	return t, nil
+}
+
+// Get returns the session's running terminal.
+func (m *Manager) Get(sessionID string) (*Terminal, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.terms[sessionID]
+	return t, t != nil && t.Running()
+}
+
+// Context returns the system prompt section describing the session's
+// terminal, or "" when the session has no terminal shared with the agent.
+func (m *Manager) Context(sessionID string) string {
+	t, ok := m.Get(sessionID)
+	if !ok {
+		return ""
+	}
+	return t.Context()
+}
+
+// CloseSession kills the session's terminal, if it has one.
+func (m *Manager) CloseSession(sessionID string) {
+	m.mu.Lock()
+	t := m.terms[sessionID]
+	delete(m.terms, sessionID)
+	m.mu.Unlock()
+	if t != nil {
+		t.Close()
+	}
+}
+
+// CloseContext kills every terminal and waits for the shells to exit until ctx
+// is done. Later opens fail with ErrClosed.
+func (m *Manager) CloseContext(ctx context.Context) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	terms := make([]*Terminal, 0, len(m.terms))
+	for _, t := range m.terms {
+		terms = append(terms, t)
+	}
+	m.terms = map[string]*Terminal{}
+	m.mu.Unlock()
+	var errs []error
+	for _, t := range terms {
+		t.Close()
+		select {
+		case <-t.done:
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("terminal for session %s did not exit: %w", t.sessionID, ctx.Err()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func start(opts Options) (*Terminal, error) {
+	shell := opts.Shell
+	if shell == "" {
+		shell = os.Getenv("SHELL")
+	}
+	if shell == "" {
+		shell = "bash"
+	}
+	cols, rows := opts.Cols, opts.Rows
+	if cols == 0 {
+		cols = DefaultCols
+	}
+	if rows == 0 {
+		rows = DefaultRows
+	}
+	cmd := exec.Command(shell)
+	cmd.Dir = opts.WorkDir
+	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+	f, err := pty.StartWithSize(cmd, &pty.Winsize{Cols: cols, Rows: rows})
+	if err != nil {
+		return nil, fmt.Errorf("start terminal: %w", err)
+	}
+	t := &Terminal{
+		sessionID: opts.SessionID,
+		cmd:       cmd,
+		pty:       f,
+		done:      make(chan struct{}),
+		viewers:   map[*Viewer]struct{}{},
+	}
+	go t.wait()
+	return t, nil
+}
+
+// Running reports whether the shell has not exited.
+func (t *Terminal) Running() bool {
+	select {
+	case <-t.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Done is closed when the shell exits and every viewer has been detached.
+func (t *Terminal) Done() <-chan struct{} { return t.done }
+
+// ExitCode returns the shell's exit code once Done is closed. It is -1 for a
+// shell terminated by a signal.
+func (t *Terminal) ExitCode() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.exitCode
+}
+
+// Attach registers a viewer and returns it with the scrollback it missed.
+// The viewer's output channel is closed when the terminal exits or the viewer
+// falls too far behind.
+func (t *Terminal) Attach() (*Viewer, []byte) {
+	v := &Viewer{term: t, output: make(chan []byte, viewerBuffer)}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.Running() {
+		close(v.output)
+	} else {
+		t.viewers[v] = struct{}{}
+	}
+	return v, append([]byte(nil), t.scrollback...)
+}
+
+// Output delivers the terminal's output in the order it was written.
+func (v *Viewer) Output() <-chan []byte { return v.output }
+
+// Detach stops delivering output to the viewer. The terminal keeps running.
+func (v *Viewer) Detach() {
+	t := v.term
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.viewers[v]; ok {
+		delete(t.viewers, v)
+		close(v.output)
+	}
+}
+
+// Write sends input to the shell.
+func (t *Terminal) Write(input []byte) error {
+	if !t.Running() {
+		return fmt.Errorf("terminal has exited")
+	}
+	if _, err := t.pty.Write(input); err != nil {
+		return fmt.Errorf("write to terminal: %w", err)
+	}
+	return nil
+}
+
+// Resize changes the terminal size shared by every viewer.
+func (t *Terminal) Resize(cols, rows uint16) error {
+	if cols == 0 || rows == 0 {
+		return fmt.Errorf("terminal size must be positive, got %dx%d", cols, rows)
+	}
+	if err := pty.Setsize(t.pty, &pty.Winsize{Cols: cols, Rows: rows}); err != nil {
+		return fmt.Errorf("resize terminal: %w", err)
+	}
+	return nil
+}
+
+// SetShared controls whether the terminal's recent output is included in the
+// agent's context for the session.
+func (t *Terminal) SetShared(shared bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.shared = shared
+}
+
+// Shared reports whether the terminal is shared with the agent.
+func (t *Terminal) Shared() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.shared
+}
+
+// Context returns the system prompt section for a terminal shared with the
+// agent, or "" when it is not shared. The section holds the end of the
+// scrollback with escape sequences removed.
+func (t *Terminal) Context() string {
+	t.mu.Lock()
+	shared := t.shared
+	scrollback := string(t.scrollback)
+	t.mu.Unlock()
+	if !shared {
+		return ""
+	}
+	text := PlainText(scrollback)
+	if len(text) > maxSharedContext {
+		start := len(text) - maxSharedContext
+		for start < len(text) && !utf8.RuneStart(text[start]) {
+			start++
+		}
+		text = text[start:]
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			text = text[i+1:]
+		}
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		text = "(no output yet)"
+	}
+	return "The user shares an interactive terminal running in this session's working directory. Its most recent output is:\n<terminal>\n" + text + "\n</terminal>"
+}
+
+// Close kills the shell. Done is closed once it has exited.
+func (t *Terminal) Close() {
+	if t.Running() {
+		_ = t.cmd.Process.Kill()
+	}
+}
+
+func (t *Terminal) wait() {
+	copied := make(chan struct{})
+	go t.copyOutput(copied)
+	_ = t.cmd.Wait()
+	select {
+	case <-copied:
+	case <-time.After(outputDrainDelay):
+	}
+	// Closing the master unblocks the copy and hangs up any job left behind.
+	_ = t.pty.Close()
+	<-copied
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.exitCode = t.cmd.ProcessState.ExitCode()
+	for v := range t.viewers {
+		close(v.output)
+	}
+	t.viewers = map[*Viewer]struct{}{}
+	close(t.done)
+}
+
+func (t *Terminal) copyOutput(copied chan<- struct{}) {
+	defer close(copied)
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := t.pty.Read(buf)
+		if n > 0 {
+			t.broadcast(append([]byte(nil), buf[:n]...))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (t *Terminal) broadcast(chunk []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scrollback = append(t.scrollback, chunk...)
+	if len(t.scrollback) > 2*maxScrollback {
+		t.scrollback = append(t.scrollback[:0:0], t.scrollback[len(t.scrollback)-maxScrollback:]...)
+	}
+	for v := range t.viewers {
+		select {
+		case v.output <- chunk:
+		default:
+			delete(t.viewers, v)
+			close(v.output)
+		}
+	}
+}
+
+var escapeSequence = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[@-Z\\-_])`)
+
+// PlainText removes terminal escape sequences and carriage-return overwrites
+// from terminal output.
+func PlainText(output string) string {
+	output = escapeSequence.ReplaceAllString(output, "")
+	lines := strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if j := strings.LastIndexByte(line, '\r'); j >= 0 {
+			line = line[j+1:]
+		}
+		lines[i] = strings.Map(func(r rune) rune {
+			if r < ' ' && r != '\t' {
+				return -1
+			}
+			return r
+		}, line)
+	}
+	return strings.Join(lines, "\n")
+}
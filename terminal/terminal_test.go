@@ -0,0 +1,120 @@
+package terminal
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// readUntil collects viewer output until it contains want.
+func readUntil(t *testing.T, v *Viewer, want string) string {
+	t.Helper()
+	var got strings.Builder
+	timeout := time.After(10 * time.Second)
+	for !strings.Contains(got.String(), want) {
+		select {
+		case chunk, ok := <-v.Output():
+			if !ok {
+				t.Fatalf("viewer closed before %q; got %q", want, got.String())
+			}
+			got.Write(chunk)
+		case <-timeout:
+			t.Fatalf("timed out waiting for %q; got %q", want, got.String())
+		}
+	}
+	return got.String()
+}
+
+func TestTerminalSharesOutputBetweenViewers(t *testing.T) {
+	m := NewManager()
+	t.Cleanup(func() { _ = m.CloseContext(context.Background()) })
+	workDir := t.TempDir()
+	term, err := m.Open(Options{SessionID: "ses_term", WorkDir: workDir, Shell: "sh", Cols: 100, Rows: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := term.Attach()
+	defer first.Detach()
+	if err := term.Write([]byte("echo size=$(stty size) dir=$(pwd)\n")); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, first, "size=40 100 dir="+workDir)
+
+	// A second open attaches to the same shell and replays what it missed.
+	again, err := m.Open(Options{SessionID: "ses_term", WorkDir: workDir, Shell: "sh"})
+	if err != nil || again != term {
+		t.Fatalf("open again = %p, %v; want %p", again, err, term)
+	}
+	second, scrollback := again.Attach()
+	defer second.Detach()
+	if !strings.Contains(string(scrollback), "size=40 100") {
+		t.Fatalf("scrollback = %q", scrollback)
+	}
+	if err := term.Resize(120, 30); err != nil {
+		t.Fatal(err)
+	}
+	if err := term.Write([]byte("echo resized=$(stty size)\n")); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, first, "resized=30 120")
+	readUntil(t, second, "resized=30 120")
+
+	if got := m.Context("ses_term"); got != "" {
+		t.Fatalf("unshared context = %q", got)
+	}
+	term.SetShared(true)
+	if got := m.Context("ses_term"); !strings.Contains(got, "resized=30 120") || strings.Contains(got, "\x1b") {
+		t.Fatalf("shared context = %q", got)
+	}
+
+	if err := term.Write([]byte("exit 3\n")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-term.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("terminal did not exit")
+	}
+	if term.ExitCode() != 3 {
+		t.Fatalf("exit code = %d", term.ExitCode())
+	}
+	if _, ok := m.Get("ses_term"); ok {
+		t.Fatal("exited terminal is still registered")
+	}
+}
+
+func TestCloseKillsTerminals(t *testing.T) {
+	m := NewManager()
+	term, err := m.Open(Options{SessionID: "ses_term", WorkDir: t.TempDir(), Shell: "sh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := m.CloseContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if term.Running() {
+		t.Fatal("terminal still running")
+	}
+	if _, err := m.Open(Options{SessionID: "ses_term", WorkDir: t.TempDir(), Shell: "sh"}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("open after close error = %v", err)
+	}
+}
+
+func TestPlainTextRemovesEscapes(t *testing.T) {
+	got := PlainText("\x1b]0;title\x07\x1b[1;32mok\x1b[0m\r\nprogress 10%\rprogress 100%\n")
+	if got != "ok\nprogress 100%\n" {
+		t.Fatalf("plain text = %q", got)
+	}
+}
+
+func TestContextCutsOnRuneBoundary(t *testing.T) {
+	term := &Terminal{shared: true, scrollback: []byte("é" + strings.Repeat("x", maxSharedContext-1))}
+	if got := term.Context(); !utf8.ValidString(got) || strings.Contains(got, "é") {
+		t.Fatalf("context = %q", got)
+	}
+}
@@ -60,7 +60,7 @@ func (t *ApplyPatchTool) Definition() Definition {
 
 func (t *ApplyPatchTool) DirectoryScoped() {}
 
-func (t *ApplyPatchTool) Permission(inv Invocation) (PermissionCheck, error) {
+func (t *ApplyPatchTool) Permission(_ context.Context, inv Invocation) (PermissionCheck, error) {
 	patchText, ok := inv.Input["patchText"].(string)
 	if !ok || strings.TrimSpace(patchText) == "" {
 		return PermissionCheck{}, fmt.Errorf("patchText is required")
@@ -61,15 +61,15 @@ func TestDefinitionTraitsRespectOptionalInterfaces(t *testing.T) {
 	if !IsSequential(declared) || !IsDirectoryScoped(declared) {
 		t.Fatal("declared execution traits were not applied")
 	}
-	check, declaredPermission, err := PermissionFor(declared, Invocation{Input: map[string]any{"path": "one", "paths": []string{"two", "three"}}})
+	check, declaredPermission, err := PermissionFor(context.Background(), declared, Invocation{Input: map[string]any{"path": "one", "paths": []string{"two", "three"}}})
 	if err != nil || !declaredPermission || check.Action != "read" || len(check.Resources) != 3 {
 		t.Fatalf("PermissionFor() = %#v, %v, %v", check, declaredPermission, err)
 	}
-	_, _, err = PermissionFor(declared, Invocation{Input: map[string]any{}})
+	_, _, err = PermissionFor(context.Background(), declared, Invocation{Input: map[string]any{}})
 	if err != nil {
 		t.Fatal(err)
 	}
-	check, _, _ = PermissionFor(declared, Invocation{Input: map[string]any{}})
+	check, _, _ = PermissionFor(context.Background(), declared, Invocation{Input: map[string]any{}})
 	if len(check.Resources) != 1 || check.Resources[0] != "*" {
 		t.Fatalf("fallback resources = %#v", check.Resources)
 	}
@@ -78,7 +78,7 @@ func TestDefinitionTraitsRespectOptionalInterfaces(t *testing.T) {
 	if IsSequential(override) || !IsDirectoryScoped(override) {
 		t.Fatal("optional execution traits did not take precedence")
 	}
-	check, declaredPermission, err = PermissionFor(override, Invocation{})
+	check, declaredPermission, err = PermissionFor(context.Background(), override, Invocation{})
 	if err != nil || !declaredPermission || check.Action != "override" || len(check.Resources) != 1 || check.Resources[0] != "resource" {
 		t.Fatalf("override PermissionFor() = %#v, %v, %v", check, declaredPermission, err)
 	}
@@ -88,6 +88,6 @@ type traitOverrideTool struct{ Tool }
 
 func (traitOverrideTool) Sequential() bool { return false }
 func (traitOverrideTool) DirectoryScoped() {}
-func (traitOverrideTool) Permission(Invocation) (PermissionCheck, error) {
+func (traitOverrideTool) Permission(context.Context, Invocation) (PermissionCheck, error) {
 	return PermissionCheck{Action: "override", Resources: []string{"resource"}}, nil
 }
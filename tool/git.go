@@ -0,0 +1,921 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitTimeout bounds one git command.
+const gitTimeout = 2 * time.Minute
+
+// Default and maximum number of commits returned by git_log.
+const (
+	defaultGitLogLimit = 20
+	maxGitLogLimit     = 200
+)
+
+// GitStatus is the structured result of git_status. Paths are relative to
+// the repository root.
+type GitStatus struct {
+	Branch   string          `json:"branch,omitempty"`
+	Commit   string          `json:"commit,omitempty"`
+	Upstream string          `json:"upstream,omitempty"`
+	Ahead    int             `json:"ahead"`
+	Behind   int             `json:"behind"`
+	Files    []GitStatusFile `json:"files"`
+}
+
+// GitStatusFile is one changed path. Index and WorkTree hold git's one-letter
+// status codes, empty when that side is unchanged.
+type GitStatusFile struct {
+	Path       string `json:"path"`
+	OrigPath   string `json:"orig_path,omitempty"`
+	Index      string `json:"index,omitempty"`
+	WorkTree   string `json:"work_tree,omitempty"`
+	Untracked  bool   `json:"untracked,omitempty"`
+	Conflicted bool   `json:"conflicted,omitempty"`
+}
+
+// GitDiff is the structured result of git_diff.
+type GitDiff struct {
+	Files     []GitDiffFile `json:"files"`
+	Additions int           `json:"additions"`
+	Deletions int           `json:"deletions"`
+}
+
+// GitDiffFile summarizes one file in a diff. Type is add, update, delete, or
+// move, as in the edit tools' diff metadata.
+type GitDiffFile struct {
+	Path      string `json:"path"`
+	OldPath   string `json:"old_path,omitempty"`
+	Type      string `json:"type"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary,omitempty"`
+}
+
+// GitLog is the structured result of git_log.
+type GitLog struct {
+	Commits []GitCommit `json:"commits"`
+}
+
+// GitCommit is one commit in a log.
+type GitCommit struct {
+	Hash      string    `json:"hash"`
+	ShortHash string    `json:"short_hash"`
+	Author    string    `json:"author"`
+	Email     string    `json:"email"`
+	Date      time.Time `json:"date"`
+	Subject   string    `json:"subject"`
+}
+
+// GitCommitResult is the structured result of git_commit.
+type GitCommitResult struct {
+	Hash      string        `json:"hash"`
+	ShortHash string        `json:"short_hash"`
+	Branch    string        `json:"branch"`
+	Subject   string        `json:"subject"`
+	Files     []GitDiffFile `json:"files"`
+	Additions int           `json:"additions"`
+	Deletions int           `json:"deletions"`
+}
+
+// GitBranches is the structured result of git_branch.
+type GitBranches struct {
+	Current  string      `json:"current,omitempty"`
+	Branches []GitBranch `json:"branches"`
+}
+
+// GitBranch is one local branch.
+type GitBranch struct {
+	Name     string `json:"name"`
+	Current  bool   `json:"current"`
+	Commit   string `json:"commit"`
+	Upstream string `json:"upstream,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+}
+
+// GitStatusTool reports the working tree status.
+type GitStatusTool struct{}
+
+func NewGitStatusTool() *GitStatusTool { return &GitStatusTool{} }
+
+func (t *GitStatusTool) Name() string { return "git_status" }
+
+func (t *GitStatusTool) Description() string {
+	return "Show the current branch, its upstream, and staged, unstaged, untracked, and conflicted files in the git repository containing the working directory."
+}
+
+func (t *GitStatusTool) Definition() Definition {
+	return Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: InputSchema{Type: "object", Properties: map[string]Property{}},
+		Permission:  &PermissionTarget{Action: "git.status"},
+	}
+}
+
+func (t *GitStatusTool) DirectoryScoped() {}
+
+func (t *GitStatusTool) Execute(ctx context.Context, inv Invocation) (Result, error) {
+	if inv.WorkDir == "" {
+		return Result{}, fmt.Errorf("workDir is required for git_status tool")
+	}
+	out, err := runGit(ctx, inv.WorkDir, "status", "--porcelain=v2", "--branch", "-z")
+	if err != nil {
+		return Result{}, err
+	}
+	status := parseGitStatus(out)
+	return Result{Text: formatGitStatus(status), Structured: status}, nil
+}
+
+// GitDiffTool shows changes as a unified diff.
+type GitDiffTool struct{}
+
+func NewGitDiffTool() *GitDiffTool { return &GitDiffTool{} }
+
+func (t *GitDiffTool) Name() string { return "git_diff" }
+
+func (t *GitDiffTool) Description() string {
+	return "Show changes in the git repository as a unified diff. By default shows unstaged changes; set staged to show what will be committed, or ref to compare against a commit or range. Returns diff metadata for UI rendering."
+}
+
+func (t *GitDiffTool) Definition() Definition {
+	return Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]Property{
+				"staged": {
+					Type:        "boolean",
+					Description: "Show staged changes instead of unstaged changes",
+				},
+				"ref": {
+					Type:        "string",
+					Description: "Commit, branch, or range to diff against, such as HEAD~1 or main...HEAD",
+				},
+				"path": {
+					Type:        "string",
+					Description: "Limit the diff to this file or directory",
+				},
+			},
+		},
+		Permission: &PermissionTarget{Action: "git.diff", ResourceFields: []string{"path"}},
+	}
+}
+
+func (t *GitDiffTool) DirectoryScoped() {}
+
+func (t *GitDiffTool) Execute(ctx context.Context, inv Invocation) (Result, error) {
+	if inv.WorkDir == "" {
+		return Result{}, fmt.Errorf("workDir is required for git_diff tool")
+	}
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--no-textconv", "--find-renames"}
+	if staged, _ := inv.Input["staged"].(bool); staged {
+		args = append(args, "--cached")
+	}
+	if ref, _ := inv.Input["ref"].(string); ref != "" {
+		if err := validateGitArg("ref", ref); err != nil {
+			return Result{}, err
+		}
+		args = append(args, ref)
+	}
+	pathspec, err := gitPathspec(inv)
+	if err != nil {
+		return Result{}, err
+	}
+	args = append(args, pathspec...)
+	root, err := gitRoot(ctx, inv.WorkDir)
+	if err != nil {
+		return Result{}, err
+	}
+	out, err := runGit(ctx, inv.WorkDir, args...)
+	if err != nil {
+		return Result{}, err
+	}
+	diff, files := parseGitDiff(out, root, inv.WorkDir)
+	text := out
+	if text == "" {
+		text = "No changes."
+	}
+	return Result{Text: text, Structured: diff, Metadata: map[string]any{"files": files}}, nil
+}
+
+// GitLogTool lists commits.
+type GitLogTool struct{}
+
+func NewGitLogTool() *GitLogTool { return &GitLogTool{} }
+
+func (t *GitLogTool) Name() string { return "git_log" }
+
+func (t *GitLogTool) Description() string {
+	return "List recent commits, newest first, optionally starting from a ref or limited to a path."
+}
+
+func (t *GitLogTool) Definition() Definition {
+	return Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]Property{
+				"ref": {
+					Type:        "string",
+					Description: "Commit, branch, or range to list. Defaults to HEAD",
+				},
+				"path": {
+					Type:        "string",
+					Description: "Only list commits that touch this file or directory",
+				},
+				"limit": {
+					Type:        "integer",
+					Description: fmt.Sprintf("Maximum number of commits (default %d, max %d)", defaultGitLogLimit, maxGitLogLimit),
+				},
+			},
+		},
+		Permission: &PermissionTarget{Action: "git.log", ResourceFields: []string{"path"}},
+	}
+}
+
+func (t *GitLogTool) DirectoryScoped() {}
+
+func (t *GitLogTool) Execute(ctx context.Context, inv Invocation) (Result, error) {
+	if inv.WorkDir == "" {
+		return Result{}, fmt.Errorf("workDir is required for git_log tool")
+	}
+	limit := defaultGitLogLimit
+	if raw, ok := inv.Input["limit"].(float64); ok && raw > 0 {
+		limit = min(int(raw), maxGitLogLimit)
+	} else if raw, ok := inv.Input["limit"].(int); ok && raw > 0 {
+		limit = min(raw, maxGitLogLimit)
+	}
+	args := []string{"log", "--no-color", "-n", strconv.Itoa(limit), "--format=%H%x1f%h%x1f%an%x1f%ae%x1f%aI%x1f%s%x1e"}
+	if ref, _ := inv.Input["ref"].(string); ref != "" {
+		if err := validateGitArg("ref", ref); err != nil {
+			return Result{}, err
+		}
+		args = append(args, ref)
+	}
+	pathspec, err := gitPathspec(inv)
+	if err != nil {
+		return Result{}, err
+	}
+	args = append(args, pathspec...)
+	out, err := runGit(ctx, inv.WorkDir, args...)
+	if err != nil {
+		return Result{}, err
+	}
+	log := GitLog{Commits: parseGitLog(out)}
+	if len(log.Commits) == 0 {
+		return Result{Text: "No commits.", Structured: log}, nil
+	}
+	var b strings.Builder
+	for _, commit := range log.Commits {
+		fmt.Fprintf(&b, "%s %s %s: %s\n", commit.ShortHash, commit.Date.Format(time.DateOnly), commit.Author, commit.Subject)
+	}
+	return Result{Text: strings.TrimSuffix(b.String(), "\n"), Structured: log}, nil
+}
+
+// GitCommitTool records staged changes as a commit on the current branch.
+type GitCommitTool struct{}
+
+func NewGitCommitTool() *GitCommitTool { return &GitCommitTool{} }
+
+func (t *GitCommitTool) Name() string { return "git_commit" }
+
+func (t *GitCommitTool) Description() string {
+	return "Create a git commit on the current branch. Commits what is staged; set path to stage a file or directory first, or all to stage every change to tracked files. Commit hooks do not run. Returns diff metadata for UI rendering."
+}
+
+func (t *GitCommitTool) Definition() Definition {
+	return Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]Property{
+				"message": {
+					Type:        "string",
+					Description: "The commit message",
+				},
+				"path": {
+					Type:        "string",
+					Description: "Stage this file or directory, including new and deleted files, before committing",
+				},
+				"all": {
+					Type:        "boolean",
+					Description: "Stage all modified and deleted tracked files before committing",
+				},
+			},
+			Required: []string{"message"},
+		},
+		Sequential: true,
+	}
+}
+
+func (t *GitCommitTool) DirectoryScoped() {}
+
+// Permission targets the branch the commit will land on, or HEAD when the
+// repository is in detached HEAD state. A path to stage is also checked as
+// an edit of that path.
+func (t *GitCommitTool) Permission(ctx context.Context, inv Invocation) (PermissionCheck, error) {
+	if inv.WorkDir == "" {
+		return PermissionCheck{}, fmt.Errorf("workDir is required for git_commit tool")
+	}
+	branch, err := gitCurrentBranch(ctx, inv.WorkDir)
+	if err != nil {
+		return PermissionCheck{}, err
+	}
+	check := PermissionCheck{Action: "git.commit", Resources: []string{branch}}
+	pathspec, err := gitPathspec(inv)
+	if err != nil {
+		return PermissionCheck{}, err
+	}
+	if len(pathspec) > 0 {
+		check.Also = []PermissionCheck{{Action: "edit", Resources: pathspec[1:]}}
+	}
+	return check, nil
+}
+
+func (t *GitCommitTool) Execute(ctx context.Context, inv Invocation) (Result, error) {
+	message, _ := inv.Input["message"].(string)
+	if strings.TrimSpace(message) == "" {
+		return Result{}, fmt.Errorf("message is required")
+	}
+	if inv.WorkDir == "" {
+		return Result{}, fmt.Errorf("workDir is required for git_commit tool")
+	}
+	root, err := gitRoot(ctx, inv.WorkDir)
+	if err != nil {
+		return Result{}, err
+	}
+	pathspec, err := gitPathspec(inv)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(pathspec) > 0 {
+		if _, err := runGit(ctx, inv.WorkDir, append([]string{"add", "-A"}, pathspec...)...); err != nil {
+			return Result{}, err
+		}
+	}
+	// Hooks are repository-controlled programs the bash rules never see.
+	args := []string{"commit", "-q", "--no-verify", "-m", message}
+	if all, _ := inv.Input["all"].(bool); all {
+		args = append(args, "-a")
+	}
+	if _, err := runGit(ctx, inv.WorkDir, args...); err != nil {
+		return Result{}, err
+	}
+
+	out, err := runGit(ctx, inv.WorkDir, "log", "-1", "--format=%H%x1f%h%x1f%an%x1f%ae%x1f%aI%x1f%s%x1e")
+	if err != nil {
+		return Result{}, err
+	}
+	commits := parseGitLog(out)
+	if len(commits) == 0 {
+		return Result{}, fmt.Errorf("read new commit: no commit found")
+	}
+	branch, err := gitCurrentBranch(ctx, inv.WorkDir)
+	if err != nil {
+		return Result{}, err
+	}
+	show, err := runGit(ctx, inv.WorkDir, "show", "--format=", "--no-color", "--no-ext-diff", "--no-textconv", "--find-renames", commits[0].Hash)
+	if err != nil {
+		return Result{}, err
+	}
+	diff, files := parseGitDiff(show, root, inv.WorkDir)
+	committed := GitCommitResult{
+		Hash:      commits[0].Hash,
+		ShortHash: commits[0].ShortHash,
+		Branch:    branch,
+		Subject:   commits[0].Subject,
+		Files:     diff.Files,
+		Additions: diff.Additions,
+		Deletions: diff.Deletions,
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Committed %s on %s: %s", committed.ShortHash, branch, committed.Subject)
+	for _, file := range diff.Files {
+		fmt.Fprintf(&b, "\n%s %s (+%d -%d)", patchSummaryPrefix(file.Type), gitDisplayPath(file), file.Additions, file.Deletions)
+	}
+	return Result{Text: b.String(), Structured: committed, Metadata: map[string]any{"files": files}}, nil
+}
+
+// Branch operations accepted by git_branch.
+const (
+	gitBranchList   = "list"
+	gitBranchCreate = "create"
+	gitBranchSwitch = "switch"
+	gitBranchDelete = "delete"
+)
+
+// GitBranchTool lists, creates, switches, and deletes local branches.
+type GitBranchTool struct{}
+
+func NewGitBranchTool() *GitBranchTool { return &GitBranchTool{} }
+
+func (t *GitBranchTool) Name() string { return "git_branch" }
+
+func (t *GitBranchTool) Description() string {
+	return "List, create, switch, or delete local git branches. Every operation returns the resulting branch list."
+}
+
+func (t *GitBranchTool) Definition() Definition {
+	return Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]Property{
+				"operation": {
+					Type:        "string",
+					Description: "The branch operation. Defaults to list",
+					Enum:        []string{gitBranchList, gitBranchCreate, gitBranchSwitch, gitBranchDelete},
+				},
+				"name": {
+					Type:        "string",
+					Description: "The branch to create, switch to, or delete",
+				},
+				"start_point": {
+					Type:        "string",
+					Description: "Commit or branch a new branch starts from. Defaults to HEAD",
+				},
+				"force": {
+					Type:        "boolean",
+					Description: "Delete the branch even if it is not merged",
+				},
+			},
+		},
+		Sequential: true,
+	}
+}
+
+func (t *GitBranchTool) DirectoryScoped() {}
+
+// Permission targets "list" or "<operation> <name>", so rules can allow
+// creating and switching branches while asking before deletes.
+func (t *GitBranchTool) Permission(_ context.Context, inv Invocation) (PermissionCheck, error) {
+	operation, name, err := gitBranchInput(inv)
+	if err != nil {
+		return PermissionCheck{}, err
+	}
+	resource := operation
+	if name != "" {
+		resource += " " + name
+	}
+	return PermissionCheck{Action: "git.branch", Resources: []string{resource}}, nil
+}
+
+func (t *GitBranchTool) Execute(ctx context.Context, inv Invocation) (Result, error) {
+	operation, name, err := gitBranchInput(inv)
+	if err != nil {
+		return Result{}, err
+	}
+	if inv.WorkDir == "" {
+		return Result{}, fmt.Errorf("workDir is required for git_branch tool")
+	}
+	if name != "" {
+		if _, err := runGit(ctx, inv.WorkDir, "check-ref-format", "--branch", name); err != nil {
+			return Result{}, fmt.Errorf("invalid branch name %q", name)
+		}
+	}
+	var summary string
+	switch operation {
+	case gitBranchCreate:
+		args := []string{"branch", name}
+		if start, _ := inv.Input["start_point"].(string); start != "" {
+			if err := validateGitArg("start_point", start); err != nil {
+				return Result{}, err
+			}
+			args = append(args, start)
+		}
+		if _, err := runGit(ctx, inv.WorkDir, args...); err != nil {
+			return Result{}, err
+		}
+		summary = fmt.Sprintf("Created branch %s.", name)
+	case gitBranchSwitch:
+		if _, err := runGit(ctx, inv.WorkDir, "switch", "-q", name); err != nil {
+			return Result{}, err
+		}
+		summary = fmt.Sprintf("Switched to branch %s.", name)
+	case gitBranchDelete:
+		flag := "-d"
+		if force, _ := inv.Input["force"].(bool); force {
+			flag = "-D"
+		}
+		if _, err := runGit(ctx, inv.WorkDir, "branch", flag, name); err != nil {
+			return Result{}, err
+		}
+		summary = fmt.Sprintf("Deleted branch %s.", name)
+	}
+
+	out, err := runGit(ctx, inv.WorkDir, "branch", "--no-color", "--format=%(HEAD)%1f%(refname:short)%1f%(objectname:short)%1f%(upstream:short)%1f%(contents:subject)")
+	if err != nil {
+		return Result{}, err
+	}
+	branches := parseGitBranches(out)
+	var b strings.Builder
+	if summary != "" {
+		b.WriteString(summary + "\n")
+	}
+	if len(branches.Branches) == 0 {
+		b.WriteString("No branches.")
+	}
+	for _, branch := range branches.Branches {
+		marker := " "
+		if branch.Current {
+			marker = "*"
+		}
+		fmt.Fprintf(&b, "%s %s %s", marker, branch.Name, branch.Commit)
+		if branch.Upstream != "" {
+			fmt.Fprintf(&b, " [%s]", branch.Upstream)
+		}
+		if branch.Subject != "" {
+			b.WriteString(" " + branch.Subject)
+		}
+		b.WriteString("\n")
+	}
+	return Result{
+		Text:       strings.TrimSuffix(b.String(), "\n"),
+		Structured: branches,
+		Metadata:   map[string]any{"operation": operation, "branch": name},
+	}, nil
+}
+
+func gitBranchInput(inv Invocation) (string, string, error) {
+	operation, _ := inv.Input["operation"].(string)
+	if operation == "" {
+		operation = gitBranchList
+	}
+	name, _ := inv.Input["name"].(string)
+	switch operation {
+	case gitBranchList:
+		return operation, "", nil
+	case gitBranchCreate, gitBranchSwitch, gitBranchDelete:
+		if name == "" {
+			return "", "", fmt.Errorf("name is required to %s a branch", operation)
+		}
+		if err := validateGitArg("name", name); err != nil {
+			return "", "", err
+		}
+		return operation, name, nil
+	default:
+		return "", "", fmt.Errorf("unsupported branch operation %q", operation)
+	}
+}
+
+// runGit runs git in workDir and returns its standard output. Failures carry
+// git's own error message.
+func runGit(ctx context.Context, workDir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, gitTimeout)
+	defer cancel()
+	// No hook runs: hooks, like textconv and external diff drivers, are
+	// programs the repository configures rather than ones the agent's bash
+	// rules were checked against.
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-c", "core.quotepath=off", "-c", "core.hooksPath=" + os.DevNull}, args...)...)
+	cmd.Dir = workDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("git %s timed out after %v", args[0], gitTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s failed: %s", args[0], msg)
+		}
+		if msg := strings.TrimSpace(stdout.String()); msg != "" {
+			return "", fmt.Errorf("git %s failed: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// gitRoot returns the repository's top-level directory, expressed from
+// workDir so it compares cleanly with paths under workDir.
+func gitRoot(ctx context.Context, workDir string) (string, error) {
+	cdup, err := runGit(ctx, workDir, "rev-parse", "--show-cdup")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(workDir, strings.TrimSpace(cdup)), nil
+}
+
+func gitCurrentBranch(ctx context.Context, workDir string) (string, error) {
+	out, err := runGit(ctx, workDir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		// A repository without commits has no HEAD commit yet.
+		out, err = runGit(ctx, workDir, "symbolic-ref", "--short", "HEAD")
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// gitPathspec returns the pathspec for the optional path input, relative to
+// the working directory it must stay inside.
+func gitPathspec(inv Invocation) ([]string, error) {
+	raw, _ := inv.Input["path"].(string)
+	if raw == "" {
+		return nil, nil
+	}
+	_, rel, err := resolveWorkPath(inv.WorkDir, raw)
+	if err != nil {
+		return nil, err
+	}
+	return []string{"--", rel}, nil
+}
+
+// validateGitArg rejects values git would parse as options.
+func validateGitArg(name, value string) error {
+	if strings.HasPrefix(value, "-") {
+		return fmt.Errorf("%s must not start with '-': %s", name, value)
+	}
+	return nil
+}
+
+func parseGitStatus(out string) GitStatus {
+	status := GitStatus{Files: []GitStatusFile{}}
+	records := strings.Split(out, "\x00")
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		switch {
+		case strings.HasPrefix(record, "# branch.oid "):
+			if oid := strings.TrimPrefix(record, "# branch.oid "); oid != "(initial)" {
+				status.Commit = oid
+			}
+		case strings.HasPrefix(record, "# branch.head "):
+			if head := strings.TrimPrefix(record, "# branch.head "); head != "(detached)" {
+				status.Branch = head
+			}
+		case strings.HasPrefix(record, "# branch.upstream "):
+			status.Upstream = strings.TrimPrefix(record, "# branch.upstream ")
+		case strings.HasPrefix(record, "# branch.ab "):
+			fmt.Sscanf(strings.TrimPrefix(record, "# branch.ab "), "+%d -%d", &status.Ahead, &status.Behind)
+		case strings.HasPrefix(record, "1 "):
+			if fields := strings.SplitN(record, " ", 9); len(fields) == 9 {
+				status.Files = append(status.Files, gitStatusFile(fields[1], fields[8]))
+			}
+		case strings.HasPrefix(record, "2 "):
+			if fields := strings.SplitN(record, " ", 10); len(fields) == 10 {
+				file := gitStatusFile(fields[1], fields[9])
+				if i+1 < len(records) {
+					i++
+					file.OrigPath = records[i]
+				}
+				status.Files = append(status.Files, file)
+			}
+		case strings.HasPrefix(record, "u "):
+			if fields := strings.SplitN(record, " ", 11); len(fields) == 11 {
+				file := gitStatusFile(fields[1], fields[10])
+				file.Conflicted = true
+				status.Files = append(status.Files, file)
+			}
+		case strings.HasPrefix(record, "? "):
+			status.Files = append(status.Files, GitStatusFile{Path: record[2:], Untracked: true})
+		}
+	}
+	return status
+}
+
+func gitStatusFile(xy, path string) GitStatusFile {
+	file := GitStatusFile{Path: path}
+	if len(xy) == 2 {
+		file.Index = strings.TrimPrefix(xy[:1], ".")
+		file.WorkTree = strings.TrimPrefix(xy[1:], ".")
+	}
+	return file
+}
+
+// formatGitStatus renders status like git status --short --branch.
+func formatGitStatus(status GitStatus) string {
+	var b strings.Builder
+	b.WriteString("## ")
+	switch {
+	case status.Branch != "":
+		b.WriteString(status.Branch)
+	default:
+		b.WriteString("HEAD (detached)")
+	}
+	if status.Upstream != "" {
+		b.WriteString("..." + status.Upstream)
+		var counts []string
+		if status.Ahead > 0 {
+			counts = append(counts, fmt.Sprintf("ahead %d", status.Ahead))
+		}
+		if status.Behind > 0 {
+			counts = append(counts, fmt.Sprintf("behind %d", status.Behind))
+		}
+		if len(counts) > 0 {
+			b.WriteString(" [" + strings.Join(counts, ", ") + "]")
+		}
+	}
+	if len(status.Files) == 0 {
+		b.WriteString("\nNothing to commit, working tree clean.")
+	}
+	for _, file := range status.Files {
+		code := "??"
+		if !file.Untracked {
+			code = gitStatusCode(file.Index) + gitStatusCode(file.WorkTree)
+		}
+		b.WriteString("\n" + code + " " + file.Path)
+		if file.OrigPath != "" {
+			b.WriteString(" <- " + file.OrigPath)
+		}
+	}
+	return b.String()
+}
+
+func gitStatusCode(code string) string {
+	if code == "" {
+		return " "
+	}
+	return code
+}
+
+// parseGitDiff summarizes a unified diff from git. It also returns the
+// per-file metadata the edit tools produce, so clients render both alike.
+func parseGitDiff(out, root, workDir string) (GitDiff, []map[string]any) {
+	diff := GitDiff{Files: []GitDiffFile{}}
+	files := []map[string]any{}
+	for _, patch := range splitGitDiff(out) {
+		file := parseGitDiffFile(patch)
+		diff.Files = append(diff.Files, file)
+		diff.Additions += file.Additions
+		diff.Deletions += file.Deletions
+
+		path := file.Path
+		if file.OldPath != "" {
+			path = file.OldPath
+		}
+		abs := filepath.Join(root, filepath.FromSlash(path))
+		metadata := map[string]any{
+			"filePath":     abs,
+			"relativePath": gitRelativePath(workDir, abs),
+			"type":         file.Type,
+			"patch":        patch,
+			"additions":    file.Additions,
+			"deletions":    file.Deletions,
+		}
+		if file.Type == "move" {
+			metadata["movePath"] = gitRelativePath(workDir, filepath.Join(root, filepath.FromSlash(file.Path)))
+		}
+		files = append(files, metadata)
+	}
+	return diff, files
+}
+
+func gitRelativePath(workDir, abs string) string {
+	rel, err := filepath.Rel(workDir, abs)
+	if err != nil {
+		return filepath.ToSlash(abs)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func splitGitDiff(out string) []string {
+	var patches []string
+	start := -1
+	offset := 0
+	for _, line := range strings.SplitAfter(out, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			if start >= 0 {
+				patches = append(patches, out[start:offset])
+			}
+			start = offset
+		}
+		offset += len(line)
+	}
+	if start >= 0 {
+		patches = append(patches, out[start:])
+	}
+	return patches
+}
+
+func parseGitDiffFile(patch string) GitDiffFile {
+	file := GitDiffFile{Type: "update"}
+	var oldPath, newPath string
+	inHunk := false
+	for _, line := range strings.Split(patch, "\n") {
+		if inHunk {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				file.Additions++
+			case strings.HasPrefix(line, "-"):
+				file.Deletions++
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case strings.HasPrefix(line, "diff --git "):
+			// Only a fallback: the a/ and b/ names are ambiguous with spaces.
+			header := strings.TrimPrefix(line, "diff --git ")
+			if i := strings.Index(header, " b/"); strings.HasPrefix(header, "a/") && i >= 0 {
+				oldPath, newPath = header[2:i], header[i+3:]
+			}
+		case strings.HasPrefix(line, "new file mode"):
+			file.Type = "add"
+		case strings.HasPrefix(line, "deleted file mode"):
+			file.Type = "delete"
+		case strings.HasPrefix(line, "rename from "):
+			file.Type = "move"
+			oldPath = strings.TrimPrefix(line, "rename from ")
+		case strings.HasPrefix(line, "rename to "):
+			newPath = strings.TrimPrefix(line, "rename to ")
+		case strings.HasPrefix(line, "--- "):
+			if name := strings.TrimPrefix(line, "--- "); name != "/dev/null" {
+				oldPath = strings.TrimPrefix(unquoteGitPath(name), "a/")
+			}
+		case strings.HasPrefix(line, "+++ "):
+			if name := strings.TrimPrefix(line, "+++ "); name != "/dev/null" {
+				newPath = strings.TrimPrefix(unquoteGitPath(name), "b/")
+			}
+		case strings.HasPrefix(line, "Binary files "):
+			file.Binary = true
+		}
+	}
+	switch file.Type {
+	case "delete":
+		file.Path = oldPath
+	case "move":
+		file.Path, file.OldPath = newPath, oldPath
+	default:
+		file.Path = newPath
+	}
+	if file.Path == "" {
+		file.Path = oldPath
+	}
+	return file
+}
+
+func unquoteGitPath(name string) string {
+	if unquoted, err := strconv.Unquote(name); err == nil {
+		return unquoted
+	}
+	return name
+}
+
+func gitDisplayPath(file GitDiffFile) string {
+	if file.OldPath != "" {
+		return file.OldPath + " -> " + file.Path
+	}
+	return file.Path
+}
+
+func parseGitLog(out string) []GitCommit {
+	commits := []GitCommit{}
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.Split(strings.TrimLeft(record, "\n"), "\x1f")
+		if len(fields) != 6 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[4])
+		commits = append(commits, GitCommit{
+			Hash:      fields[0],
+			ShortHash: fields[1],
+			Author:    fields[2],
+			Email:     fields[3],
+			Date:      date,
+			Subject:   fields[5],
+		})
+	}
+	return commits
+}
+
+func parseGitBranches(out string) GitBranches {
+	branches := GitBranches{Branches: []GitBranch{}}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 5 {
+			continue
+		}
+		branch := GitBranch{
+			Name:     fields[1],
+			Current:  fields[0] == "*",
+			Commit:   fields[2],
+			Upstream: fields[3],
+			Subject:  fields[4],
+		}
+		// A detached HEAD is listed as a pseudo-branch.
+		if strings.HasPrefix(branch.Name, "(") {
+			continue
+		}
+		if branch.Current {
+			branches.Current = branch.Name
+		}
+		branches.Branches = append(branches.Branches, branch)
+	}
+	return branches
+}
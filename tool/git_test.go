@@ -0,0 +1,196 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Tester")
+	t.Setenv("GIT_AUTHOR_EMAIL", "tester@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Tester")
+	t.Setenv("GIT_COMMITTER_EMAIL", "tester@example.com")
+	dir := t.TempDir()
+	for _, args := range [][]string{{"init", "-q", "-b", "main"}, {"commit", "-q", "--allow-empty", "-m", "root"}} {
+		if _, err := runGit(context.Background(), dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func writeRepoFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitToolsCommitAndInspectChanges(t *testing.T) {
+	dir := newGitRepo(t)
+	ctx := context.Background()
+	writeRepoFile(t, dir, "app/main.go", "package main\n")
+	writeRepoFile(t, dir, "notes.txt", "draft\n")
+
+	commit := NewGitCommitTool()
+	check, err := commit.Permission(ctx, Invocation{WorkDir: dir, Input: map[string]any{"message": "Add app"}})
+	if err != nil || check.Action != "git.commit" || check.Resources[0] != "main" {
+		t.Fatalf("permission = %#v, %v", check, err)
+	}
+	check, err = commit.Permission(ctx, Invocation{WorkDir: dir, Input: map[string]any{"message": "Add app", "path": "app"}})
+	if err != nil || len(check.Also) != 1 || check.Also[0].Action != "edit" || !slices.Equal(check.Also[0].Resources, []string{"app"}) {
+		t.Fatalf("permission with path = %#v, %v", check, err)
+	}
+	// Repository hooks are programs the bash rules never see, so they do
+	// not run.
+	writeRepoFile(t, dir, ".git/hooks/pre-commit", "#!/bin/sh\nexit 1\n")
+	if err := os.Chmod(filepath.Join(dir, ".git", "hooks", "pre-commit"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	result, err := commit.Execute(ctx, Invocation{WorkDir: dir, Input: map[string]any{"message": "Add app", "path": "app"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	committed := result.Structured.(GitCommitResult)
+	if committed.Branch != "main" || committed.Subject != "Add app" || len(committed.Files) != 1 || committed.Files[0].Type != "add" || committed.Additions != 1 {
+		t.Fatalf("commit = %#v", committed)
+	}
+	files := result.Metadata["files"].([]map[string]any)
+	if files[0]["relativePath"] != "app/main.go" || !strings.Contains(files[0]["patch"].(string), "+package main") {
+		t.Fatalf("commit metadata = %#v", files)
+	}
+
+	writeRepoFile(t, dir, "app/main.go", "package main\n\nfunc main() {}\n")
+	status, err := NewGitStatusTool().Execute(ctx, Invocation{WorkDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := status.Structured.(GitStatus)
+	if got.Branch != "main" || len(got.Files) != 2 || got.Files[0].Path != "app/main.go" || got.Files[0].WorkTree != "M" || got.Files[0].Index != "" || !got.Files[1].Untracked {
+		t.Fatalf("status = %#v", got)
+	}
+	if !strings.Contains(status.Text, "## main\n M app/main.go\n?? notes.txt") {
+		t.Fatalf("status text = %q", status.Text)
+	}
+
+	// Paths in metadata are relative to the working directory, even when it is
+	// below the repository root.
+	diff, err := NewGitDiffTool().Execute(ctx, Invocation{WorkDir: filepath.Join(dir, "app")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary := diff.Structured.(GitDiff); len(summary.Files) != 1 || summary.Files[0].Path != "app/main.go" || summary.Additions != 2 {
+		t.Fatalf("diff = %#v", summary)
+	}
+	if files := diff.Metadata["files"].([]map[string]any); files[0]["relativePath"] != "main.go" || files[0]["type"] != "update" {
+		t.Fatalf("diff metadata = %#v", files)
+	}
+	staged, err := NewGitDiffTool().Execute(ctx, Invocation{WorkDir: dir, Input: map[string]any{"staged": true}})
+	if err != nil || staged.Text != "No changes." {
+		t.Fatalf("staged diff = %#v, %v", staged, err)
+	}
+	if _, err := NewGitDiffTool().Execute(ctx, Invocation{WorkDir: dir, Input: map[string]any{"ref": "--output=/tmp/x"}}); err == nil {
+		t.Fatal("option-like ref was accepted")
+	}
+
+	if _, err := commit.Execute(ctx, Invocation{WorkDir: dir, Input: map[string]any{"message": "Add main func", "all": true}}); err != nil {
+		t.Fatal(err)
+	}
+	log, err := NewGitLogTool().Execute(ctx, Invocation{WorkDir: dir, Input: map[string]any{"limit": float64(2)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits := log.Structured.(GitLog).Commits
+	if len(commits) != 2 || commits[0].Subject != "Add main func" || commits[1].Subject != "Add app" || commits[0].Author != "Tester" {
+		t.Fatalf("log = %#v", commits)
+	}
+}
+
+func TestGitBranchOperations(t *testing.T) {
+	dir := newGitRepo(t)
+	ctx := context.Background()
+	branch := NewGitBranchTool()
+
+	check, err := branch.Permission(context.Background(), Invocation{WorkDir: dir, Input: map[string]any{"operation": "delete", "name": "old"}})
+	if err != nil || check.Action != "git.branch" || check.Resources[0] != "delete old" {
+		t.Fatalf("permission = %#v, %v", check, err)
+	}
+	if check, _ := branch.Permission(context.Background(), Invocation{WorkDir: dir}); check.Resources[0] != "list" {
+		t.Fatalf("list permission = %#v", check)
+	}
+	if _, err := branch.Permission(context.Background(), Invocation{WorkDir: dir, Input: map[string]any{"operation": "create"}}); err == nil {
+		t.Fatal("create without a name was accepted")
+	}
+
+	for _, input := range []map[string]any{
+		{"operation": "create", "name": "feature"},
+		{"operation": "switch", "name": "feature"},
+	} {
+		if _, err := branch.Execute(ctx, Invocation{WorkDir: dir, Input: input}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	listed, err := branch.Execute(ctx, Invocation{WorkDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	branches := listed.Structured.(GitBranches)
+	if branches.Current != "feature" || len(branches.Branches) != 2 || !strings.Contains(listed.Text, "* feature") {
+		t.Fatalf("branches = %#v, text = %q", branches, listed.Text)
+	}
+	if _, err := branch.Execute(ctx, Invocation{WorkDir: dir, Input: map[string]any{"operation": "create", "name": "bad..name"}}); err == nil {
+		t.Fatal("invalid branch name was accepted")
+	}
+
+	if _, err := branch.Execute(ctx, Invocation{WorkDir: dir, Input: map[string]any{"operation": "switch", "name": "main"}}); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := branch.Execute(ctx, Invocation{WorkDir: dir, Input: map[string]any{"operation": "delete", "name": "feature"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := deleted.Structured.(GitBranches); got.Current != "main" || len(got.Branches) != 1 || !strings.HasPrefix(deleted.Text, "Deleted branch feature.") {
+		t.Fatalf("after delete = %#v, text = %q", got, deleted.Text)
+	}
+}
+
+func TestParseGitStatusRenamesAndConflicts(t *testing.T) {
+	out := strings.Join([]string{
+		"# branch.oid 0123456789abcdef",
+		"# branch.head main",
+		"# branch.upstream origin/main",
+		"# branch.ab +2 -1",
+		"2 R. N... 100644 100644 100644 aaa bbb R100 new name.go",
+		"old name.go",
+		"u UU N... 100644 100644 100644 100644 aaa bbb ccc conflict.go",
+		"",
+	}, "\x00")
+	status := parseGitStatus(out)
+	if status.Upstream != "origin/main" || status.Ahead != 2 || status.Behind != 1 || len(status.Files) != 2 {
+		t.Fatalf("status = %#v", status)
+	}
+	if renamed := status.Files[0]; renamed.Path != "new name.go" || renamed.OrigPath != "old name.go" || renamed.Index != "R" {
+		t.Fatalf("renamed = %#v", renamed)
+	}
+	if !status.Files[1].Conflicted {
+		t.Fatalf("conflict = %#v", status.Files[1])
+	}
+	if text := formatGitStatus(status); !strings.HasPrefix(text, "## main...origin/main [ahead 2, behind 1]\nR  new name.go <- old name.go\nUU conflict.go") {
+		t.Fatalf("text = %q", text)
+	}
+}
@@ -99,7 +99,7 @@ func (t *GrepTool) Execute(ctx context.Context, inv Invocation) (Result, error)
 				return nil
 			}
 			if d.IsDir() {
-				if shouldSkipDir(d.Name()) {
+				if SkipDir(d.Name()) {
 					return filepath.SkipDir
 				}
 				return nil
@@ -112,7 +112,7 @@ func (t *GrepTool) Execute(ctx context.Context, inv Invocation) (Result, error)
 				}
 			}
 
-			if !isTextFile(d.Name()) {
+			if !IsTextFile(d.Name()) {
 				return nil
 			}
 
@@ -180,7 +180,9 @@ func searchFile(path string, re *regexp.Regexp) ([]grepMatch, error) {
 	return matches, scanner.Err()
 }
 
-func shouldSkipDir(name string) bool {
+// SkipDir reports whether directory walks skip a directory with this name,
+// such as version control metadata and dependency or build output.
+func SkipDir(name string) bool {
 	skipDirs := []string{".git", "node_modules", "vendor", ".idea", ".vscode", "__pycache__", ".next", "dist", "build"}
 	for _, skip := range skipDirs {
 		if name == skip {
@@ -190,7 +192,9 @@ func shouldSkipDir(name string) bool {
 	return false
 }
 
-func isTextFile(name string) bool {
+// IsTextFile reports whether a file name looks like source or text that
+// content searches read.
+func IsTextFile(name string) bool {
 	textExtensions := []string{
 		".go", ".js", ".ts", ".jsx", ".tsx", ".py", ".rb", ".java", ".c", ".cpp", ".h",
 		".rs", ".swift", ".kt", ".scala", ".php", ".pl", ".pm", ".sh", ".bash", ".zsh",
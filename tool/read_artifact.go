@@ -0,0 +1,99 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+)
+
+const (
+	defaultReadArtifactLimit = 16 * 1024
+	maxReadArtifactLimit     = 64 * 1024
+)
+
+// ReadArtifactTool pages through a session artifact, such as tool output
+// that was too large to keep inline.
+type ReadArtifactTool struct{}
+
+func NewReadArtifactTool() *ReadArtifactTool {
+	return &ReadArtifactTool{}
+}
+
+func (t *ReadArtifactTool) Name() string {
+	return "read_artifact"
+}
+
+func (t *ReadArtifactTool) Description() string {
+	return "Read a page of a session artifact, such as tool output that was truncated because it was too large. Pages are byte ranges; use the next offset reported at the end of each page to continue."
+}
+
+func (t *ReadArtifactTool) Definition() Definition {
+	return Definition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]Property{
+				"artifact_id": {
+					Type:        "string",
+					Description: "The artifact ID named in the truncated tool output",
+				},
+				"offset": {
+					Type:        "number",
+					Description: "The byte offset to start reading from (defaults to 0)",
+				},
+				"limit": {
+					Type:        "number",
+					Description: "The maximum number of bytes to read (defaults to 16384, at most 65536)",
+				},
+			},
+			Required: []string{"artifact_id"},
+		},
+	}
+}
+
+func (t *ReadArtifactTool) BoundedOutput() {}
+
+func (t *ReadArtifactTool) Execute(ctx context.Context, inv Invocation) (Result, error) {
+	id, _ := inv.Input["artifact_id"].(string)
+	if id == "" {
+		return Result{}, fmt.Errorf("artifact_id is required")
+	}
+	if inv.Artifacts == nil {
+		return Result{}, fmt.Errorf("this session does not store artifacts")
+	}
+	offset := intParam(inv.Input["offset"], 0)
+	limit := intParam(inv.Input["limit"], defaultReadArtifactLimit)
+	if offset < 0 {
+		return Result{}, fmt.Errorf("offset must be >= 0")
+	}
+	if limit < 1 {
+		return Result{}, fmt.Errorf("limit must be >= 1")
+	}
+	limit = min(limit, maxReadArtifactLimit)
+
+	content, err := inv.Artifacts.ReadArtifact(ctx, id)
+	if err != nil {
+		return Result{}, err
+	}
+	if offset > len(content) {
+		return Result{}, fmt.Errorf("offset %d is out of range for this artifact (%d bytes)", offset, len(content))
+	}
+	// Align both ends to UTF-8 boundaries so pages never split a character.
+	start := offset
+	for start < len(content) && !utf8.RuneStart(content[start]) {
+		start++
+	}
+	end := min(start+limit, len(content))
+	for end < len(content) && end > start && !utf8.RuneStart(content[end]) {
+		end--
+	}
+	output := fmt.Sprintf("<artifact>%s</artifact>\n<content>\n%s", id, content[start:end])
+	if end < len(content) {
+		output += fmt.Sprintf("\n\n(Showing bytes %d-%d of %d. Use offset=%d to continue.)", start, end, len(content), end)
+	} else {
+		output += fmt.Sprintf("\n\n(End of artifact - total %d bytes)", len(content))
+	}
+	output += "\n</content>"
+	return Result{Text: output, Metadata: map[string]any{"artifact_id": id, "offset": start, "end": end, "bytes": len(content), "truncated": end < len(content)}}, nil
+}
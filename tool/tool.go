@@ -100,6 +100,18 @@ type Invocation struct {
 	PartID      string
 	ModelCallID string
 	Progress    *Progress
+	// Artifacts reads artifacts stored with the invoking session. It is nil
+	// when the session does not persist artifacts.
+	Artifacts ArtifactReader
+	// Allowed reports whether the run's permission rules allow action on
+	// resource without asking, for tools that return content beyond their
+	// own permission check. It is nil when the run has no rules.
+	Allowed func(action, resource string) bool
+}
+
+// ArtifactReader reads the content of session artifacts by ID.
+type ArtifactReader interface {
+	ReadArtifact(ctx context.Context, id string) ([]byte, error)
 }
 
 // SequentialTool is an optional interface a Tool can implement to force
@@ -118,13 +130,17 @@ type PermissionCheck struct {
 	Action    string
 	Resources []string
 	Save      []string
+	// Also lists further checks the call must pass under their own actions,
+	// such as the files git_commit stages. A deny in any check denies the
+	// call.
+	Also []PermissionCheck
 }
 
 // PermissionedTool is an optional interface for tools that can describe their
 // own permission target from validated input parameters.
 type PermissionedTool interface {
 	Tool
-	Permission(inv Invocation) (PermissionCheck, error)
+	Permission(ctx context.Context, inv Invocation) (PermissionCheck, error)
 }
 
 // DirectoryScopedTool is a marker interface for tools that operate on the
@@ -135,6 +151,14 @@ type DirectoryScopedTool interface {
 	DirectoryScoped()
 }
 
+// BoundedOutputTool is a marker interface for tools that bound their own
+// output size. The loop never moves their results to artifacts, so a tool
+// that pages through an artifact cannot spill its own pages.
+type BoundedOutputTool interface {
+	Tool
+	BoundedOutput()
+}
+
 // PermissionTarget declares the permission action and input fields that
 // identify resources for a tool invocation.
 type PermissionTarget struct {
@@ -190,9 +214,9 @@ func IsDirectoryScoped(t Tool) bool {
 // PermissionFor derives a permission check from t and inv. It reports false
 // when neither the optional interface nor Definition declares a target.
 // PermissionedTool takes precedence over Definition.Permission.
-func PermissionFor(t Tool, inv Invocation) (PermissionCheck, bool, error) {
+func PermissionFor(ctx context.Context, t Tool, inv Invocation) (PermissionCheck, bool, error) {
 	if permissioned, ok := t.(PermissionedTool); ok {
-		check, err := permissioned.Permission(inv)
+		check, err := permissioned.Permission(ctx, inv)
 		return check, true, err
 	}
 	target := t.Definition().Permission
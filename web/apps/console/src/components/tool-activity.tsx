@@ -1,5 +1,5 @@
 import { useEffect, useState, type ReactNode } from "react";
-import { CheckCircleIcon, CircleNotchIcon, CodeIcon, FileTextIcon, GlobeIcon, MagnifyingGlassIcon, TerminalIcon, WarningCircleIcon, WrenchIcon } from "@phosphor-icons/react";
+import { CheckCircleIcon, CircleNotchIcon, CodeIcon, FileTextIcon, GitBranchIcon, GlobeIcon, MagnifyingGlassIcon, TerminalIcon, WarningCircleIcon, WrenchIcon } from "@phosphor-icons/react";
 
 import { ToolDiff } from "@/components/tool-diff";
 import { collapseOutput, compactInput, formatDuration, humanizeToolName, parseReadOutput, patchFiles, stripAnsi, toolSummary, toolText } from "@/lib/tool-display";
@@ -47,7 +47,7 @@ function normalizeTool({ call, result, activity }: Props): ToolView {
 export function ToolActivityItem({ compact = false, ...props }: Props & { compact?: boolean }) {
 	const view = normalizeTool(props);
 	if (view.call.name === "bash") return <BashTool view={view} />;
-	if (["apply_patch", "edit", "write"].includes(view.call.name)) return <FileMutationTool view={view} />;
+	if (["apply_patch", "edit", "write", "git_diff", "git_commit"].includes(view.call.name)) return <FileMutationTool view={view} />;
 	if (["read", "grep", "glob", "webfetch", "websearch"].includes(view.call.name)) return <TimelineTool view={view} compact={compact} />;
 	return <CompactTool view={view} />;
 }
@@ -74,9 +74,10 @@ function StatusIcon({ status }: { status: ToolView["status"] }) {
 function toolIcon(name: string) {
 	if (name === "bash") return <TerminalIcon className="size-4" />;
 	if (name === "read") return <FileTextIcon className="size-4" />;
-	if (name === "grep" || name === "glob") return <MagnifyingGlassIcon className="size-4" />;
+	if (name === "grep" || name === "glob" || name.startsWith("symbol_") || name === "codebase_search") return <MagnifyingGlassIcon className="size-4" />;
 	if (name === "webfetch" || name === "websearch") return <GlobeIcon className="size-4" />;
 	if (name === "apply_patch" || name === "edit" || name === "write") return <CodeIcon className="size-4" />;
+	if (name.startsWith("git_")) return <GitBranchIcon className="size-4" />;
 	return <WrenchIcon className="size-4" />;
 }
 
@@ -66,6 +66,13 @@ export function toolSummary(call: ToolCallPart) {
 	if (call.name === "write") return `Write ${filename(stringInput(call, "filePath")) || "file"}`;
 	if (call.name === "edit") return `Edit ${filename(stringInput(call, "filePath")) || "file"}`;
 	if (call.name === "apply_patch") return "Apply patch";
+	if (call.name === "git_diff") return `Diff${inPath(stringInput(call, "path"))}`;
+	if (call.name === "git_commit") return `Commit ${quote(stringInput(call, "message").split("\n")[0])}`;
+	if (call.name === "git_branch") return `${humanizeToolName(stringInput(call, "operation") || "list")} branch ${stringInput(call, "name")}`.trim();
+	if (call.name === "symbol_outline") return `Outline ${filename(stringInput(call, "path")) || "file"}`;
+	if (call.name === "symbol_definition") return `Find definition of ${stringInput(call, "name")}`;
+	if (call.name === "symbol_references") return `Find references to ${stringInput(call, "name")}${inPath(stringInput(call, "path"))}`;
+	if (call.name === "codebase_search") return `Search code for ${quote(stringInput(call, "query"))}${inPath(stringInput(call, "path"))}`;
 	if (call.name === "websearch") return `Search ${quote(stringInput(call, "query"))}`;
 	if (call.name === "webfetch") return `Fetch ${stringInput(call, "url") || "URL"}`;
 	return humanizeToolName(call.name);
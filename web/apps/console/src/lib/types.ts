@@ -69,6 +69,11 @@ export interface ProviderModel {
   structured_output: boolean;
   input_cost_per_mtok?: number;
   output_cost_per_mtok?: number;
+  embedding?: {
+    dimensions: number;
+    max_input_tokens?: number;
+    max_batch_size?: number;
+  };
 }
 
 export interface ProviderAuthResponse {
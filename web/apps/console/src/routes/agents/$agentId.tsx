@@ -108,7 +108,7 @@ function AgentDetailPage() {
         selectableProviders.map(async (provider) => {
           try {
             const data = await client.providers.models.list(provider.id) as Record<string, ProviderModel>;
-            return [provider.id, Object.values(data).sort((a, b) => a.id.localeCompare(b.id))] as const;
+            return [provider.id, Object.values(data).filter((model) => !model.embedding).sort((a, b) => a.id.localeCompare(b.id))] as const;
           } catch {
             return [provider.id, []] as const;
           }
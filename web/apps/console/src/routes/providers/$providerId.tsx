@@ -253,6 +253,7 @@ function ProviderDetailPage() {
                       {model.images && <Badge variant="outline">images</Badge>}
                       {model.reasoning && <Badge variant="outline">reasoning</Badge>}
                       {model.structured_output && <Badge variant="outline">structured</Badge>}
+                      {model.embedding && <Badge variant="outline">embedding · {model.embedding.dimensions}d</Badge>}
                     </div>
                   </TableCell>
                 </TableRow>
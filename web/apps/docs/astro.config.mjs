@@ -40,6 +40,7 @@ export default defineConfig({
           items: [
             { label: "Run the Server", slug: "use-wingman/run-server" },
             { label: "Use the Console", slug: "use-wingman/web-ui" },
+            { label: "Evaluate Agents", slug: "use-wingman/evaluate-agents" },
           ],
         },
         {